@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/coder/websocket"
+	"github.com/loppo-llc/kojo/internal/server"
+	"golang.org/x/term"
+)
+
+// detachKey leaves an attached session without stopping it: Ctrl-],
+// the same escape telnet uses, which no supported tool binds.
+const detachKey = 0x1d
+
+// attachSession bridges the local terminal to a session's PTY over
+// /api/v1/ws until the session exits, the connection drops, or the
+// user presses the detach key. The session keeps running on detach.
+func attachSession(c *apiClient, id string) int {
+	u, err := url.Parse(c.baseURL() + "/api/v1/ws")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "attach: %v\n", err)
+		return 1
+	}
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	default:
+		u.Scheme = "ws"
+	}
+	u.RawQuery = url.Values{"session": {id}}.Encode()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hdr := http.Header{}
+	if *c.token != "" {
+		hdr.Set("Authorization", "Bearer "+*c.token)
+	}
+	conn, resp, err := websocket.Dial(ctx, u.String(), &websocket.DialOptions{
+		HTTPClient:      c.http,
+		HTTPHeader:      hdr,
+		CompressionMode: websocket.CompressionNoContextTakeover,
+	})
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			err = fmt.Errorf("session %s not found", id)
+		}
+		fmt.Fprintf(os.Stderr, "attach: %v\n", err)
+		return 1
+	}
+	defer conn.CloseNow()
+	// Scrollback for a long-running session can exceed the 32KB default.
+	conn.SetReadLimit(-1)
+
+	stdin := int(os.Stdin.Fd())
+	if term.IsTerminal(stdin) {
+		old, err := term.MakeRaw(stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "attach: %v\n", err)
+			return 1
+		}
+		defer term.Restore(stdin, old)
+	}
+
+	sendResize := func() {
+		cols, rows, err := term.GetSize(int(os.Stdout.Fd()))
+		if err != nil {
+			return
+		}
+		writeWSJSON(ctx, conn, server.WSResizeMsg{Type: "resize", Cols: cols, Rows: rows})
+	}
+	sendResize()
+	go watchResize(ctx, sendResize)
+
+	inputErr := make(chan error, 1)
+	go func() { inputErr <- pumpStdin(ctx, conn) }()
+
+	outputErr := make(chan error, 1)
+	exitCode := 0
+	go func() { outputErr <- pumpOutput(ctx, conn, &exitCode) }()
+
+	select {
+	case err = <-inputErr:
+	case err = <-outputErr:
+	}
+	cancel()
+	conn.Close(websocket.StatusNormalClosure, "")
+
+	switch {
+	case errors.Is(err, errDetached):
+		fmt.Fprintf(os.Stderr, "\r\ndetached from %s\r\n", id)
+		return 0
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "\r\nattach: %v\r\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "\r\n%s exited (%d)\r\n", id, exitCode)
+	return 0
+}
+
+// pumpStdin forwards keystrokes as input messages until stdin closes or
+// the detach key is read; bytes typed before the key are still sent.
+func pumpStdin(ctx context.Context, conn *websocket.Conn) error {
+	buf := make([]byte, 4096)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if n > 0 {
+			chunk := buf[:n]
+			i := bytes.IndexByte(chunk, detachKey)
+			if i >= 0 {
+				chunk = chunk[:i]
+			}
+			if len(chunk) > 0 {
+				msg := server.WSInputMsg{Type: "input", Data: base64.StdEncoding.EncodeToString(chunk)}
+				if err := writeWSJSON(ctx, conn, msg); err != nil {
+					return err
+				}
+			}
+			if i >= 0 {
+				return errDetached
+			}
+		}
+		if err != nil {
+			// EOF on a piped stdin: stop sending, keep watching output.
+			<-ctx.Done()
+			return nil
+		}
+	}
+}
+
+// pumpOutput writes scrollback and output to stdout. It returns nil on
+// the session's exit message, recording the code.
+func pumpOutput(ctx context.Context, conn *websocket.Conn, exitCode *int) error {
+	for {
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			if websocket.CloseStatus(err) == websocket.StatusNormalClosure {
+				return nil
+			}
+			return err
+		}
+		var msg struct {
+			Type     string `json:"type"`
+			Data     string `json:"data"`
+			ExitCode int    `json:"exitCode"`
+		}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+		switch msg.Type {
+		case "scrollback", "output":
+			raw, err := base64.StdEncoding.DecodeString(msg.Data)
+			if err != nil {
+				continue
+			}
+			if _, err := os.Stdout.Write(raw); err != nil {
+				return err
+			}
+		case "desync":
+			// Output was dropped; clear the screen and redraw from
+			// the server's snapshot.
+			raw, err := base64.StdEncoding.DecodeString(msg.Data)
+			if err != nil {
+				continue
+			}
+			if _, err := os.Stdout.Write(append([]byte("\x1bc"), raw...)); err != nil {
+				return err
+			}
+		case "draining":
+			fmt.Fprint(os.Stderr, "\r\n[kojo: server is draining for a restart; new attaches are refused]\r\n")
+		case "exit":
+			*exitCode = msg.ExitCode
+			return nil
+		}
+	}
+}
+
+func writeWSJSON(ctx context.Context, conn *websocket.Conn, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return conn.Write(ctx, websocket.MessageText, data)
+}
@@ -0,0 +1,9 @@
+//go:build windows
+
+package main
+
+import "context"
+
+// watchResize is a no-op on Windows, which has no resize signal; the
+// session keeps the size sent when attaching.
+func watchResize(ctx context.Context, fn func()) {}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/loppo-llc/kojo/internal/configdir"
+	"github.com/loppo-llc/kojo/internal/session"
+)
+
+// clientCommands are the `kojo <cmd>` subcommands that drive an
+// already-running server through its HTTP API instead of starting one.
+// Like `kojo update` they are intercepted before flag.Parse.
+var clientCommands = map[string]func(c *apiClient, fs *flag.FlagSet, args []string) int{
+	"ls":     runListCommand,
+	"new":    runNewCommand,
+	"stop":   runStopCommand,
+	"attach": runAttachCommand,
+}
+
+// defaultServerURL is where the client looks for the server when the
+// control socket is unavailable and neither -server nor $KOJO_SERVER
+// is given: the --local listener on the default port.
+const defaultServerURL = "http://127.0.0.1:8080"
+
+// socketBaseURL stands in for the server URL when requests go over the
+// control socket; the host part is never resolved.
+const socketBaseURL = "http://kojo"
+
+// runClientCommand parses the connection flags shared by every client
+// subcommand, then hands the remaining flag set to the subcommand to
+// finish. Exit codes: 0 on success, 1 on any error, 2 on usage errors.
+func runClientCommand(name string, args []string) int {
+	run := clientCommands[name]
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	server := fs.String("server", envOr("KOJO_SERVER", defaultServerURL), "kojo server URL (also via KOJO_SERVER)")
+	token := fs.String("token", os.Getenv("KOJO_OWNER_TOKEN"), "Owner token (also via KOJO_OWNER_TOKEN)")
+	socket := fs.String("socket", envOr("KOJO_SOCKET", filepath.Join(configdir.Path(), controlSocketName)), "control socket, preferred when -server is not set (also via KOJO_SOCKET)")
+	// The subcommand registers its own flags and parses; the client
+	// reads the connection flags through the pointers afterwards.
+	c := &apiClient{
+		fs:     fs,
+		server: server,
+		token:  token,
+		socket: socket,
+	}
+	return run(c, fs, args)
+}
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// apiClient is a minimal Owner-authenticated client for the session API.
+type apiClient struct {
+	fs     *flag.FlagSet
+	server *string
+	token  *string
+	socket *string
+
+	base string
+	http *http.Client
+}
+
+// connect picks the transport once flags are parsed. The control
+// socket wins unless a server URL was asked for explicitly: it needs no
+// token and works whatever port the server ended up on.
+func (c *apiClient) connect() {
+	if c.http != nil {
+		return
+	}
+	explicit := os.Getenv("KOJO_SERVER") != ""
+	c.fs.Visit(func(f *flag.Flag) {
+		if f.Name == "server" {
+			explicit = true
+		}
+	})
+	if path := *c.socket; !explicit && controlSocketSupported && path != "" {
+		if fi, err := os.Stat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			c.base = socketBaseURL
+			c.http = &http.Client{Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", path)
+				},
+			}}
+			return
+		}
+	}
+	c.base = strings.TrimRight(*c.server, "/")
+	c.http = &http.Client{}
+}
+
+func (c *apiClient) baseURL() string {
+	c.connect()
+	return c.base
+}
+
+// describe names the endpoint in connection errors.
+func (c *apiClient) describe() string {
+	if c.baseURL() == socketBaseURL {
+		return *c.socket
+	}
+	return c.base
+}
+
+// apiError is the server's `{"error":{"code","message"}}` body.
+type apiError struct {
+	Status  int
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *apiError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("server returned %d", e.Status)
+	}
+	return fmt.Sprintf("%s (%d %s)", e.Message, e.Status, e.Code)
+}
+
+// do sends a JSON request and decodes a 2xx response into out (when
+// non-nil). Non-2xx answers come back as *apiError.
+func (c *apiClient) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL()+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if *c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+*c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot reach kojo at %s: %w", c.describe(), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		var wrapped struct {
+			Error apiError `json:"error"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&wrapped)
+		wrapped.Error.Status = resp.StatusCode
+		if resp.StatusCode == http.StatusUnauthorized && *c.token == "" {
+			// Only reachable over TCP; the control socket needs no token.
+			wrapped.Error.Message = "unauthorized; pass -token or set KOJO_OWNER_TOKEN"
+		}
+		return &wrapped.Error
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// clientSession is the subset of session.SessionInfo the CLI shows.
+type clientSession struct {
+	ID        string   `json:"id"`
+	Tool      string   `json:"tool"`
+	WorkDir   string   `json:"workDir"`
+	Args      []string `json:"args"`
+	Status    string   `json:"status"`
+	ExitCode  *int     `json:"exitCode"`
+	CreatedAt string   `json:"createdAt"`
+	Internal  bool     `json:"internal"`
+}
+
+func runListCommand(c *apiClient, fs *flag.FlagSet, args []string) int {
+	asJSON := fs.Bool("json", false, "print the raw session list as JSON")
+	all := fs.Bool("a", false, "include internal sessions")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	var resp struct {
+		Sessions []clientSession `json:"sessions"`
+	}
+	if err := c.do(context.Background(), http.MethodGet, "/api/v1/sessions", nil, &resp); err != nil {
+		fmt.Fprintf(os.Stderr, "ls: %v\n", err)
+		return 1
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(resp.Sessions)
+		return 0
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTOOL\tSTATUS\tCREATED\tWORKDIR")
+	for _, s := range resp.Sessions {
+		if s.Internal && !*all {
+			continue
+		}
+		status := s.Status
+		if s.ExitCode != nil {
+			status = fmt.Sprintf("%s (%d)", status, *s.ExitCode)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", s.ID, s.Tool, status, formatCreated(s.CreatedAt), s.WorkDir)
+	}
+	_ = tw.Flush()
+	return 0
+}
+
+// formatCreated renders an RFC 3339 timestamp in local time, falling
+// back to the raw string.
+func formatCreated(ts string) string {
+	t, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		return ts
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func runNewCommand(c *apiClient, fs *flag.FlagSet, args []string) int {
+	tool := fs.String("tool", "claude", "tool to run: "+strings.Join(append(session.UserTools(), session.ShellToolName()), ", "))
+	dir := fs.String("dir", ".", "working directory on the server host")
+	yolo := fs.Bool("yolo", false, "start in yolo mode")
+	worktree := fs.String("worktree", "", "run on this branch in its own git worktree")
+	attach := fs.Bool("attach", false, "attach to the session once it starts")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: kojo new [flags] [-- tool args...]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	workDir, err := filepath.Abs(*dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "new: %v\n", err)
+		return 1
+	}
+	req := map[string]any{
+		"tool":     *tool,
+		"workDir":  workDir,
+		"args":     fs.Args(),
+		"yoloMode": *yolo,
+		"worktree": *worktree,
+	}
+	var sess clientSession
+	if err := c.do(context.Background(), http.MethodPost, "/api/v1/sessions", req, &sess); err != nil {
+		fmt.Fprintf(os.Stderr, "new: %v\n", err)
+		return 1
+	}
+	if !*attach {
+		fmt.Println(sess.ID)
+		return 0
+	}
+	return attachSession(c, sess.ID)
+}
+
+func runStopCommand(c *apiClient, fs *flag.FlagSet, args []string) int {
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: kojo stop [flags] <session-id>...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	code := 0
+	for _, id := range fs.Args() {
+		if err := stopSession(c, id); err != nil {
+			fmt.Fprintf(os.Stderr, "stop %s: %v\n", id, err)
+			code = 1
+		}
+	}
+	return code
+}
+
+// stopSession stops a running session. DELETE on an exited session
+// removes its record instead, so the status is checked first: stopping
+// something that already exited is a no-op, not a delete.
+func stopSession(c *apiClient, id string) error {
+	ctx := context.Background()
+	path := "/api/v1/sessions/" + url.PathEscape(id)
+	var sess clientSession
+	if err := c.do(ctx, http.MethodGet, path, nil, &sess); err != nil {
+		return err
+	}
+	if sess.Status != "running" && sess.Status != "paused" {
+		fmt.Fprintf(os.Stderr, "%s is already %s\n", id, sess.Status)
+		return nil
+	}
+	return c.do(ctx, http.MethodDelete, path, nil, nil)
+}
+
+func runAttachCommand(c *apiClient, fs *flag.FlagSet, args []string) int {
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: kojo attach [flags] <session-id>")
+		fmt.Fprintln(fs.Output(), "detach with Ctrl-]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	return attachSession(c, fs.Arg(0))
+}
+
+// errDetached ends an attach the user left with the detach key.
+var errDetached = errors.New("detached")
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestClient(t *testing.T, h http.HandlerFunc) *apiClient {
+	t.Helper()
+	ts := httptest.NewServer(h)
+	t.Cleanup(ts.Close)
+	token := "tok"
+	return &apiClient{token: &token, base: ts.URL, http: ts.Client()}
+}
+
+func TestAPIClientDecodesServerErrors(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer tok" {
+			t.Errorf("Authorization = %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":{"code":"not_found","message":"session not found: s1"}}`))
+	})
+	err := c.do(t.Context(), http.MethodGet, "/api/v1/sessions/s1", nil, nil)
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("err = %v, want *apiError", err)
+	}
+	if apiErr.Status != http.StatusNotFound || apiErr.Code != "not_found" || apiErr.Message != "session not found: s1" {
+		t.Errorf("apiError = %+v", apiErr)
+	}
+}
+
+func TestStopSessionSkipsExitedSession(t *testing.T) {
+	var deletes int
+	status := "exited"
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.Write([]byte(`{"id":"s1","status":"` + status + `"}`))
+		case http.MethodDelete:
+			deletes++
+			w.Write([]byte(`{"ok":true}`))
+		}
+	})
+	if err := stopSession(c, "s1"); err != nil {
+		t.Fatal(err)
+	}
+	if deletes != 0 {
+		t.Fatalf("exited session got %d DELETEs, want 0", deletes)
+	}
+	status = "running"
+	if err := stopSession(c, "s1"); err != nil {
+		t.Fatal(err)
+	}
+	if deletes != 1 {
+		t.Fatalf("running session got %d DELETEs, want 1", deletes)
+	}
+}
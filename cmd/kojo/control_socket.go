@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// controlSocketName is the control socket's file name in the config
+// directory.
+const controlSocketName = "kojo.sock"
+
+// controlSocketPath resolves the config's Socket setting: empty means
+// the default inside configDir, "off" disables the socket ("" result).
+func controlSocketPath(setting, configDir string) string {
+	switch setting {
+	case "off":
+		return ""
+	case "":
+		return filepath.Join(configDir, controlSocketName)
+	}
+	return setting
+}
+
+// listenControlSocket binds the control socket at path with mode 0600,
+// so only the user running kojo can connect. A leftover socket from a
+// crashed process is replaced; one that still answers belongs to
+// another running kojo and is left alone.
+func listenControlSocket(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if c, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			c.Close()
+			return nil, fmt.Errorf("%s is in use by another process", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	// net.Listen creates the file under the process umask; tighten it
+	// before the listener is served.
+	if err := os.Chmod(path, 0o600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
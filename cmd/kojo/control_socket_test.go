@@ -0,0 +1,71 @@
+//go:build unix
+
+package main
+
+import (
+	"flag"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListenControlSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), controlSocketName)
+
+	// A socket file nobody listens on is left over from a crash.
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	ln, err := listenControlSocket(path)
+	if err != nil {
+		t.Fatalf("stale socket not replaced: %v", err)
+	}
+	defer ln.Close()
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0o600 {
+		t.Errorf("socket mode = %o, want 600", perm)
+	}
+
+	if _, err := listenControlSocket(path); err == nil {
+		t.Error("second listener took over a live socket")
+	}
+}
+
+func TestClientPrefersControlSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), controlSocketName)
+	ln, err := listenControlSocket(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Error("token sent over the control socket")
+		}
+		w.Write([]byte(`{"sessions":[{"id":"s1","status":"running"}]}`))
+	})}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+
+	t.Setenv("KOJO_SERVER", "")
+	fs := flag.NewFlagSet("ls", flag.ContinueOnError)
+	server, token := "http://127.0.0.1:1", ""
+	c := &apiClient{fs: fs, server: &server, token: &token, socket: &path}
+	var resp struct {
+		Sessions []clientSession `json:"sessions"`
+	}
+	if err := c.do(t.Context(), http.MethodGet, "/api/v1/sessions", nil, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Sessions) != 1 || resp.Sessions[0].ID != "s1" {
+		t.Errorf("sessions = %+v", resp.Sessions)
+	}
+}
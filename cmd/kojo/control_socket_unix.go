@@ -0,0 +1,7 @@
+//go:build unix
+
+package main
+
+// controlSocketSupported gates the control socket in main. Its only
+// auth is the socket file's mode, which needs unix permissions.
+const controlSocketSupported = true
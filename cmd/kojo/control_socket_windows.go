@@ -0,0 +1,8 @@
+//go:build windows
+
+package main
+
+// controlSocketSupported is false on Windows: AF_UNIX sockets exist
+// but file modes don't restrict who may connect, so the socket would
+// be an unauthenticated Owner endpoint. Use the TCP listener there.
+const controlSocketSupported = false
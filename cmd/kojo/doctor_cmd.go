@@ -0,0 +1,60 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+
+	"github.com/loppo-llc/kojo/internal/session"
+)
+
+// runDoctorCommand implements `kojo doctor`: it checks the programs
+// sessions run on — tmux and the agent CLIs — on this machine, without
+// a server. Exit codes: 0 when nothing needs attention, 1 otherwise.
+func runDoctorCommand(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "doctor: %v\n", err)
+		return 1
+	}
+
+	ok := true
+	if runtime.GOOS != "windows" {
+		switch v := session.TmuxVersion(); {
+		case v == "":
+			fmt.Println("tmux:   not found; sessions end when kojo exits")
+			ok = false
+		case !session.TmuxSupported():
+			fmt.Printf("tmux:   %s is unsupported, need %s or later\n", v, session.MinTmuxVersion)
+			ok = false
+		default:
+			fmt.Printf("tmux:   %s\n", v)
+		}
+	}
+
+	tools := session.ToolAvailability()
+	names := make([]string, 0, len(tools))
+	for name := range tools {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		t := tools[name]
+		switch {
+		case !t.Available:
+			fmt.Printf("%-7s not found\n", name+":")
+		case !t.Supported:
+			fmt.Printf("%-7s %s at %s is unsupported, need %s or later\n", name+":", t.Version, t.Path, t.MinVersion)
+			ok = false
+		default:
+			fmt.Printf("%-7s %s at %s\n", name+":", cmp.Or(t.Version, "unknown version"), t.Path)
+		}
+	}
+	if !ok {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestListenWithFallback(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	port := busy.Addr().(*net.TCPAddr).Port
+
+	// --strict-port: a busy port is an error naming the flag.
+	if ln, err := listenWithFallback("127.0.0.1", port, 1, slog.Default()); err == nil {
+		ln.Close()
+		t.Fatal("strict listen on a busy port succeeded")
+	} else if !strings.Contains(err.Error(), "--strict-port") {
+		t.Errorf("strict error = %v", err)
+	}
+
+	// Otherwise the next free port in the range is used.
+	ln, err := listenWithFallback("127.0.0.1", port, 10, slog.Default())
+	if err != nil {
+		t.Skipf("no free port after %d: %v", port, err)
+	}
+	defer ln.Close()
+	if got := ln.Addr().(*net.TCPAddr).Port; got <= port || got >= port+10 {
+		t.Errorf("fallback port = %d, want in (%d, %d)", got, port, port+10)
+	}
+}
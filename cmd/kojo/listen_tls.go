@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/loppo-llc/kojo/internal/config"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// listenTLSConfig builds the TLS config for the --listen listeners:
+// a user-provided certificate, or one obtained from Let's Encrypt for
+// the configured domains. nil means plain HTTP. The returned handler,
+// when non-nil, answers ACME HTTP-01 challenges and should be served
+// on cfg.ACMEHTTPAddr; without it certificates are issued over
+// TLS-ALPN-01 on the TLS port itself.
+func listenTLSConfig(cfg config.Config, configDir string) (*tls.Config, http.Handler, error) {
+	// config.Validate has already rejected conflicting settings.
+	switch {
+	case cfg.TLSCert != "":
+		kp := &keyPairReloader{certFile: cfg.TLSCert, keyFile: cfg.TLSKey}
+		if _, err := kp.get(); err != nil {
+			return nil, nil, err
+		}
+		return &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+				return kp.get()
+			},
+		}, nil, nil
+	case len(cfg.ACMEDomains) > 0:
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.ACMEDomains...),
+			Cache:      autocert.DirCache(filepath.Join(configDir, "acme")),
+			Email:      cfg.ACMEEmail,
+		}
+		var challenge http.Handler
+		if cfg.ACMEHTTPAddr != "" {
+			challenge = m.HTTPHandler(nil)
+		}
+		tc := m.TLSConfig()
+		tc.MinVersion = tls.VersionTLS12
+		return tc, challenge, nil
+	}
+	return nil, nil, nil
+}
+
+// keyPairReloader serves a certificate from disk and picks up a
+// renewed one (certbot, a cron job) without a restart. The files are
+// re-checked at most once a minute.
+type keyPairReloader struct {
+	certFile, keyFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+func (k *keyPairReloader) get() (*tls.Certificate, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.cert != nil && time.Since(k.checked) < time.Minute {
+		return k.cert, nil
+	}
+	k.checked = time.Now()
+	fi, err := os.Stat(k.certFile)
+	if err != nil {
+		if k.cert != nil {
+			return k.cert, nil // keep serving the last good pair
+		}
+		return nil, err
+	}
+	if k.cert != nil && fi.ModTime().Equal(k.modTime) {
+		return k.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(k.certFile, k.keyFile)
+	if err != nil {
+		if k.cert != nil {
+			return k.cert, nil
+		}
+		return nil, fmt.Errorf("load TLS key pair: %w", err)
+	}
+	k.cert, k.modTime = &cert, fi.ModTime()
+	return k.cert, nil
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/fs"
 	"log/slog"
 	"net"
@@ -17,6 +18,7 @@ import (
 	"os/signal"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -26,16 +28,26 @@ import (
 	"github.com/loppo-llc/kojo/internal/agent"
 	"github.com/loppo-llc/kojo/internal/auth"
 	"github.com/loppo-llc/kojo/internal/blob"
+	"github.com/loppo-llc/kojo/internal/config"
 	"github.com/loppo-llc/kojo/internal/configdir"
+	"github.com/loppo-llc/kojo/internal/dirdefaults"
 	"github.com/loppo-llc/kojo/internal/eventbus"
+	"github.com/loppo-llc/kojo/internal/logfile"
+	"github.com/loppo-llc/kojo/internal/logging"
+	"github.com/loppo-llc/kojo/internal/macro"
 	"github.com/loppo-llc/kojo/internal/notify"
 	"github.com/loppo-llc/kojo/internal/peer"
+	"github.com/loppo-llc/kojo/internal/prefs"
+	"github.com/loppo-llc/kojo/internal/qrcode"
+	"github.com/loppo-llc/kojo/internal/schedule"
 	"github.com/loppo-llc/kojo/internal/selfupdate"
 	"github.com/loppo-llc/kojo/internal/server"
 	"github.com/loppo-llc/kojo/internal/session"
+	"github.com/loppo-llc/kojo/internal/snippet"
 	"github.com/loppo-llc/kojo/internal/store"
 	"github.com/loppo-llc/kojo/internal/store/secretcrypto"
 	"github.com/loppo-llc/kojo/web"
+	"golang.org/x/term"
 	localTailscale "tailscale.com/client/local"
 	"tailscale.com/ipn/ipnstate"
 	"tailscale.com/tsnet"
@@ -44,11 +56,54 @@ import (
 var version = "0.110.0"
 
 // newCLILogger builds the stderr text logger used by every subcommand and
-// the main boot path, at the given level.
-func newCLILogger(level slog.Level) *slog.Logger {
+// the main boot path, at the given level. The boot path passes a
+// *slog.LevelVar so a config reload can change the level in place.
+func newCLILogger(level slog.Leveler) *slog.Logger {
 	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
 }
 
+// newServerLogger builds the daemon's per-component loggers from cfg's
+// log format and file, leveled by level and the component overrides.
+// Without a log file they write to stderr like newCLILogger; with one,
+// the returned func closes it.
+func newServerLogger(cfg config.Config, level *slog.LevelVar) (*logging.Levels, func(), error) {
+	var w io.Writer = os.Stderr
+	closeLog := func() {}
+	if cfg.LogFile != "" {
+		f, err := logfile.Open(cfg.LogFile, int64(cfg.LogFileMaxMB)<<20, cfg.LogFileBackups)
+		if err != nil {
+			return nil, nil, fmt.Errorf("--log-file: %w", err)
+		}
+		w, closeLog = f, func() { _ = f.Close() }
+	}
+	// logging.Levels does the leveling; the handler passes everything.
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+	var h slog.Handler = slog.NewTextHandler(w, opts)
+	if cfg.LogFormat == "json" {
+		h = slog.NewJSONHandler(w, opts)
+	}
+	levels := logging.NewLevels(h, level)
+	if err := levels.Replace(componentLevels(cfg)); err != nil {
+		closeLog()
+		return nil, nil, err
+	}
+	return levels, closeLog, nil
+}
+
+// componentLevels parses cfg's per-component log levels, already
+// checked by Validate. In dev mode tsnet stays at info unless set,
+// since its debug output buries everything else.
+func componentLevels(cfg config.Config) map[string]slog.Level {
+	out := map[string]slog.Level{}
+	if cfg.Dev {
+		out["tsnet"] = slog.LevelInfo
+	}
+	for name, s := range cfg.LogLevels {
+		out[name], _ = config.ParseLogLevel(s)
+	}
+	return out
+}
+
 // applyConfigDirFlag applies the --config-dir override when set. A no-op for
 // the empty default so configdir keeps its platform-default resolution.
 func applyConfigDirFlag(configDir string) {
@@ -57,6 +112,89 @@ func applyConfigDirFlag(configDir string) {
 	}
 }
 
+// loadConfig reads the config file and overlays the KOJO_* environment.
+// An empty path means <configDir>/config.json, which may be absent; an
+// explicit --config path must exist.
+func loadConfig(path, configDir string) (config.Config, error) {
+	if path == "" {
+		path = config.Path(configDir)
+	} else if _, err := os.Stat(path); err != nil {
+		return config.Config{}, fmt.Errorf("--config: %w", err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return cfg, err
+	}
+	if err := cfg.ApplyEnv(os.LookupEnv); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// rateLimits maps cfg's per-client limits onto the server's.
+func rateLimits(cfg config.Config) server.RateLimits {
+	return server.RateLimits{
+		SessionCreate:       cfg.SessionCreateRate,
+		GitExec:             cfg.GitExecRate,
+		Upload:              cfg.UploadRate,
+		WebSocketsPerClient: cfg.WebSocketsPerClient,
+	}
+}
+
+// wsLimits maps cfg's WebSocket limits onto the server's.
+func wsLimits(cfg config.Config) server.WSLimits {
+	return server.WSLimits{
+		MaxLifetime: time.Duration(cfg.WebSocketLifetimeMinutes) * time.Minute,
+		IdleTimeout: time.Duration(cfg.WebSocketIdleMinutes) * time.Minute,
+		MaxConns:    cfg.MaxWebSockets,
+		Evict:       cfg.WebSocketEvict,
+	}
+}
+
+func remoteHosts(cfg config.Config) []session.RemoteHost {
+	hosts := make([]session.RemoteHost, len(cfg.RemoteHosts))
+	for i, h := range cfg.RemoteHosts {
+		hosts[i] = session.RemoteHost(h)
+	}
+	return hosts
+}
+
+func sandboxes(cfg config.Config) []session.Sandbox {
+	out := make([]session.Sandbox, len(cfg.Sandboxes))
+	for i, sb := range cfg.Sandboxes {
+		out[i] = session.Sandbox(sb)
+	}
+	return out
+}
+
+// resolveLogLevel returns cfg's log level: LogLevel when set, else
+// debug in dev mode and info otherwise. On an invalid LogLevel it
+// returns the fallback along with the error.
+func resolveLogLevel(cfg config.Config) (slog.Level, error) {
+	fallback := slog.LevelInfo
+	if cfg.Dev {
+		fallback = slog.LevelDebug
+	}
+	if cfg.LogLevel == "" {
+		return fallback, nil
+	}
+	lvl, err := config.ParseLogLevel(cfg.LogLevel)
+	if err != nil {
+		return fallback, err
+	}
+	return lvl, nil
+}
+
+// lockHolder names the process holding a lock Acquire refused, as
+// " (pid N)", or "" when it isn't known.
+func lockHolder(err error) string {
+	var locked *configdir.LockedError
+	if errors.As(err, &locked) && locked.PID > 0 {
+		return fmt.Sprintf(" (pid %d)", locked.PID)
+	}
+	return ""
+}
+
 func main() {
 	// Subcommands are intercepted before flag.Parse. Today every other
 	// mode is a flag; positional args were silently ignored, so claiming
@@ -64,15 +202,48 @@ func main() {
 	if len(os.Args) > 1 && os.Args[1] == "update" {
 		os.Exit(runUpdateCommand(os.Args[2:]))
 	}
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		os.Exit(runDoctorCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "service" {
+		os.Exit(runServiceCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && clientCommands[os.Args[1]] != nil {
+		os.Exit(runClientCommand(os.Args[1], os.Args[2:]))
+	}
 
-	port := flag.Int("port", 8080, "port number (auto-increments if busy)")
+	port := flag.Int("port", 8080, "port number; when busy the next free one within --port-range is used (also via KOJO_PORT)")
+	portRange := flag.Int("port-range", 10, "how many ports from --port to try when it is busy (also via KOJO_PORT_RANGE)")
+	strictPort := flag.Bool("strict-port", false, "fail instead of moving to another port when --port is busy, so bookmarked URLs keep working (also via KOJO_STRICT_PORT)")
+	bindAddr := flag.String("bind", "", "address the --local / --dev listener binds to, e.g. 0.0.0.0 for the LAN (default 127.0.0.1; also via KOJO_BIND)")
 	dev := flag.Bool("dev", false, "enable dev mode (proxy to Vite)")
+	viteURL := flag.String("vite-url", "", "Vite dev server --dev proxies the web UI to (default "+server.DefaultViteURL+"; also via KOJO_VITE_URL)")
+	spawnVite := flag.Bool("spawn-vite", false, "with --dev, run `npm run dev` in ./web and restart it if it exits (also via KOJO_SPAWN_VITE)")
 	local := flag.Bool("local", false, "listen on localhost only (no Tailscale)")
 	hostname := flag.String("hostname", "kojo", "tsnet machine name (becomes <name>.<tailnet>.ts.net). Ignored in --local / --dev mode")
+	basePath := flag.String("base-path", "", "URL prefix when served behind a reverse proxy, e.g. /kojo (also via KOJO_BASE_PATH)")
+	listenAddrs := flag.String("listen", "", "extra comma-separated host:port listeners beside the mode's own, e.g. 127.0.0.1:8080 with Tailscale; token auth required (also via KOJO_LISTEN)")
+	tlsCert := flag.String("tls-cert", "", "PEM certificate for serving the --listen addresses over HTTPS; reloaded when the file changes (also via KOJO_TLS_CERT)")
+	tlsKey := flag.String("tls-key", "", "PEM private key for --tls-cert (also via KOJO_TLS_KEY)")
+	acmeDomains := flag.String("acme-domain", "", "comma-separated domains to get Let's Encrypt certificates for on the --listen addresses (also via KOJO_ACME_DOMAINS)")
+	acmeEmail := flag.String("acme-email", "", "contact email for the ACME account (also via KOJO_ACME_EMAIL)")
+	acmeHTTPAddr := flag.String("acme-http-addr", "", "answer ACME HTTP-01 challenges on this address, e.g. :80; default is TLS-ALPN-01 on the --listen port (also via KOJO_ACME_HTTP_ADDR)")
+	accessLog := flag.String("access-log", "", "append one JSON line per HTTP request to this file; requests are otherwise logged at debug level only (also via KOJO_ACCESS_LOG)")
+	debugEndpoints := flag.Bool("debug-endpoints", false, "serve /debug/pprof/ and /api/v1/debug/goroutines to the owner (also via KOJO_DEBUG_ENDPOINTS)")
+	readOnly := flag.Bool("read-only", false, "let the web UI view sessions, agents and files but not create, type into or change anything (also via KOJO_READ_ONLY)")
+	funnel := flag.Bool("funnel", false, "also publish kojo on the internet via Tailscale Funnel; Bearer auth is always required there (also via KOJO_FUNNEL)")
+	funnelPort := flag.Int("funnel-port", 443, "Funnel port: 443, 8443 or 10000 (also via KOJO_FUNNEL_PORT)")
+	stateDir := flag.String("state-dir", "", "tsnet state directory; give each instance on one machine its own (also via KOJO_STATE_DIR)")
+	tsAuthKey := flag.String("ts-authkey", "", "Tailscale auth key for joining the tailnet without the login URL (also via KOJO_TS_AUTHKEY)")
 	configDir := flag.String("config-dir", "", "override config directory (default: ~/.config/kojo-v1)")
+	configFile := flag.String("config", "", "JSON config file (default: <config-dir>/config.json). Precedence: defaults < config file < KOJO_* env < flags")
+	socketFlag := flag.String("socket", "", "control socket path (default <config-dir>/kojo.sock; \"off\" disables; also via KOJO_SOCKET)")
+	logLevelFlag := flag.String("log-level", "", "log level: debug|info|warn|error (default info, debug with --dev; also via KOJO_LOG_LEVEL)")
+	logFormatFlag := flag.String("log-format", "", "log format: text|json (default text; also via KOJO_LOG_FORMAT)")
+	logFileFlag := flag.String("log-file", "", "write the log to this file instead of stderr, rotated by size (logFileMaxMB, default 10) keeping logFileBackups old files (default 5); also via KOJO_LOG_FILE")
 	showVersion := flag.Bool("version", false, "show version")
 	noAuth := flag.Bool("no-auth", false, "disable agent-facing auth listener (--local/--dev only)")
-	noUpdateCheck := flag.Bool("no-update-check", false, "disable the periodic GitHub release update check (also via KOJO_NO_UPDATE_CHECK=1)")
+	noUpdateCheck := flag.Bool("no-update-check", false, "disable the periodic GitHub release update check (also via KOJO_NO_UPDATE_CHECK=1 or noUpdateCheck in config.json)")
 	noPeerAutoUpdate := flag.Bool("no-peer-autoupdate", false, "with --peer: do not auto-update this peer's binary from a newer Hub (also via KOJO_NO_PEER_AUTOUPDATE=1)")
 
 	// Upgrade-migration flags: import the legacy kojo/ config dir
@@ -164,28 +335,103 @@ func main() {
 		}))
 	}
 
-	logLevel := slog.LevelInfo
-	if *dev {
-		logLevel = slog.LevelDebug
-	}
-	if lvl := os.Getenv("KOJO_LOG_LEVEL"); lvl != "" {
-		switch strings.ToLower(lvl) {
-		case "debug":
-			logLevel = slog.LevelDebug
-		case "info":
-			logLevel = slog.LevelInfo
-		case "warn", "warning":
-			logLevel = slog.LevelWarn
-		case "error":
-			logLevel = slog.LevelError
-		default:
-			// Defer logging the invalid value until the logger exists, so
-			// operators notice the misconfiguration instead of silently
-			// getting the default level.
-			fmt.Fprintf(os.Stderr, "kojo: ignoring invalid KOJO_LOG_LEVEL=%q (valid: debug|info|warn|warning|error)\n", lvl)
-		}
+	// Layered settings: defaults < config file < KOJO_* env < flags.
+	// The config file lives in the config directory, so resolve that
+	// first; it stays flag-only for the same reason.
+	applyConfigDirFlag(*configDir)
+	cfg, err := loadConfig(*configFile, configdir.Path())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "kojo: %v\n", err)
+		os.Exit(1)
+	}
+	// overlayFlags re-applies the flags given on the command line; they
+	// outrank the file and env on boot and on every reload.
+	overlayFlags := func(c *config.Config) {
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "port":
+				c.Port = *port
+			case "port-range":
+				c.PortRange = *portRange
+			case "strict-port":
+				c.StrictPort = *strictPort
+			case "bind":
+				c.Bind = *bindAddr
+			case "hostname":
+				c.Hostname = *hostname
+			case "base-path":
+				c.BasePath = *basePath
+			case "listen":
+				c.Listen = strings.FieldsFunc(*listenAddrs, func(r rune) bool { return r == ',' || r == ' ' })
+			case "tls-cert":
+				c.TLSCert = *tlsCert
+			case "tls-key":
+				c.TLSKey = *tlsKey
+			case "acme-domain":
+				c.ACMEDomains = strings.FieldsFunc(*acmeDomains, func(r rune) bool { return r == ',' || r == ' ' })
+			case "acme-email":
+				c.ACMEEmail = *acmeEmail
+			case "acme-http-addr":
+				c.ACMEHTTPAddr = *acmeHTTPAddr
+			case "debug-endpoints":
+				c.DebugEndpoints = *debugEndpoints
+			case "access-log":
+				c.AccessLog = *accessLog
+			case "read-only":
+				c.ReadOnly = *readOnly
+			case "funnel":
+				c.Funnel = *funnel
+			case "funnel-port":
+				c.FunnelPort = *funnelPort
+			case "state-dir":
+				c.StateDir = *stateDir
+			case "ts-authkey":
+				c.TSAuthKey = *tsAuthKey
+			case "dev":
+				c.Dev = *dev
+			case "vite-url":
+				c.ViteURL = *viteURL
+			case "spawn-vite":
+				c.SpawnVite = *spawnVite
+			case "local":
+				c.Local = *local
+			case "log-level":
+				c.LogLevel = *logLevelFlag
+			case "log-format":
+				c.LogFormat = *logFormatFlag
+			case "log-file":
+				c.LogFile = *logFileFlag
+			case "socket":
+				c.Socket = *socketFlag
+			case "no-update-check":
+				c.NoUpdateCheck = *noUpdateCheck
+			}
+		})
+	}
+	overlayFlags(&cfg)
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "kojo: %v\n", err)
+		os.Exit(1)
+	}
+	// The rest of main reads these through the flag pointers.
+	*port, *hostname, *dev, *local = cfg.Port, cfg.Hostname, cfg.Dev, cfg.Local
+
+	logLevel := new(slog.LevelVar)
+	lvl, err := resolveLogLevel(cfg)
+	if err != nil {
+		// Report the invalid value instead of refusing to boot, so
+		// operators notice the misconfiguration without losing the
+		// server to a typo.
+		fmt.Fprintf(os.Stderr, "kojo: ignoring %v\n", err)
+	}
+	logLevel.Set(lvl)
+	logLevels, closeLog, err := newServerLogger(cfg, logLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "kojo: %v\n", err)
+		os.Exit(1)
 	}
-	logger := newCLILogger(logLevel)
+	defer closeLog()
+	logger := logLevels.Logger("")
 
 	// --peer mode mutual exclusion. The Hub-side network shape
 	// (tsnet listener, Owner-trusted UI proxy) and the peer-side
@@ -194,6 +440,13 @@ func main() {
 	// half-peer that listens for Owner traffic with no UI to
 	// answer, or claims --peer while booting tsnet. Refuse early
 	// so the operator picks one.
+	// Funnel rides on the tsnet listener, which only the default
+	// (Tailscale) mode starts.
+	if cfg.Funnel && (*peerMode || *dev || *local) {
+		fmt.Fprintln(os.Stderr, "kojo: --funnel requires Tailscale mode (not --peer, --dev or --local)")
+		os.Exit(1)
+	}
+
 	if *peerMode {
 		switch {
 		case *dev:
@@ -229,10 +482,6 @@ func main() {
 		}
 	}
 
-	// Resolve the config directory before any subsystem reads it.
-	if *configDir != "" {
-		configdir.Set(*configDir)
-	}
 	resolvedDir := configdir.Path()
 	logger.Info("config directory", "path", resolvedDir)
 
@@ -294,16 +543,37 @@ func main() {
 	lock, err := configdir.Acquire(resolvedDir)
 	if err != nil {
 		logger.Error("could not lock config directory — another kojo instance may be running", "dir", resolvedDir, "err", err)
-		fmt.Fprintf(os.Stderr, "\nAnother kojo instance is already using %s.\n", resolvedDir)
+		fmt.Fprintf(os.Stderr, "\nAnother kojo instance%s is already using %s.\n", lockHolder(err), resolvedDir)
 		fmt.Fprintf(os.Stderr, "Use --config-dir to point this instance at a different directory.\n\n")
 		os.Exit(1)
 	}
 	defer lock.Release()
 
-	// tmux is required for user tool sessions on Unix
-	if runtime.GOOS != "windows" {
+	// Without tmux, user tool sessions fall back to a direct PTY
+	// (see sessionBackend) and don't survive a kojo restart.
+	if runtime.GOOS != "windows" && cfg.SessionBackend != "pty" {
 		if _, err := exec.LookPath("tmux"); err != nil {
-			logger.Warn("tmux not found in PATH; user tool sessions (claude, codex, grok) will not work")
+			if cfg.SessionBackend == "tmux" {
+				logger.Warn("tmux not found in PATH; user tool sessions (claude, codex, grok) will not work")
+			} else {
+				logger.Warn("tmux not found in PATH; user tool sessions run on a direct PTY and end when kojo exits")
+			}
+		} else {
+			// One instance per user on the tmux server, whatever its
+			// config directory: see session.TmuxLockPath.
+			tmuxLockPath, err := session.TmuxLockPath()
+			if err != nil {
+				logger.Error("could not create the tmux backend's runtime directory", "err", err)
+				os.Exit(1)
+			}
+			tmuxLock, err := configdir.AcquireFile(tmuxLockPath)
+			if err != nil {
+				logger.Error("could not lock the tmux backend — another kojo instance is running sessions in tmux", "err", err)
+				fmt.Fprintf(os.Stderr, "\nAnother kojo instance%s is running its sessions on this user's tmux server.\n", lockHolder(err))
+				fmt.Fprintf(os.Stderr, "Stop it, or start this instance with KOJO_SESSION_BACKEND=pty (or \"sessionBackend\": \"pty\") so its sessions run apart.\n\n")
+				os.Exit(1)
+			}
+			defer tmuxLock.Release()
 		}
 	}
 
@@ -339,20 +609,21 @@ func main() {
 	// notifications still work, the private key just isn't encrypted.
 	var notifyMgr *notify.Manager
 	if vapidStore := buildVAPIDStore(agentMgr, resolvedDir, logger); vapidStore != nil {
-		nm, err := notify.NewManagerWithVAPIDStore(logger, vapidStore)
+		nm, err := notify.NewManagerWithVAPIDStore(logLevels.Logger("notify"), vapidStore)
 		if err != nil {
 			logger.Warn("web push notifications disabled (kv path)", "err", err)
 		} else {
 			notifyMgr = nm
 		}
 	} else {
-		nm, err := notify.NewManager(logger)
+		nm, err := notify.NewManager(logLevels.Logger("notify"))
 		if err != nil {
 			logger.Warn("web push notifications disabled", "err", err)
 		} else {
 			notifyMgr = nm
 		}
 	}
+	seedPushSubscriptions(notifyMgr, agentMgr.Store(), logger)
 	groupDMMgr := agent.NewGroupDMManager(agentMgr, logger)
 	agentMgr.SetGroupDMManager(groupDMMgr)
 
@@ -362,6 +633,20 @@ func main() {
 		fmt.Fprintln(os.Stderr, "kojo: --no-auth requires --local or --dev")
 		os.Exit(1)
 	}
+	// --bind moves the --local / --dev listener; the other modes pick
+	// their own interfaces. Without auth it must stay on this machine.
+	localBind := "127.0.0.1"
+	if cfg.Bind != "" {
+		switch {
+		case !*local && !*dev:
+			fmt.Fprintln(os.Stderr, "kojo: --bind requires --local or --dev")
+			os.Exit(1)
+		case *noAuth && !isLoopbackHost(cfg.Bind):
+			fmt.Fprintln(os.Stderr, "kojo: --no-auth refuses a non-loopback --bind address")
+			os.Exit(1)
+		}
+		localBind = cfg.Bind
+	}
 
 	// Token store. Owner / per-agent hashes live in kv (namespace=
 	// "auth", scope=global) per Phase 2c-2 slice 17; the
@@ -988,10 +1273,19 @@ func main() {
 	srv := server.New(server.Config{
 		Addr:           fmt.Sprintf(":%d", *port),
 		DevMode:        *dev,
+		ViteURL:        cfg.ViteURL,
+		SpawnVite:      cfg.SpawnVite,
+		BasePath:       server.NormalizeBasePath(cfg.BasePath),
 		Logger:         logger,
+		LogLevels:      logLevels,
 		StaticFS:       staticFS,
 		Version:        version,
 		NotifyManager:  notifyMgr,
+		Snippets:       snippet.NewStore(logger),
+		Macros:         macro.NewStore(logger),
+		Prefs:          prefs.NewStore(logger),
+		DirDefaults:    dirdefaults.NewStore(logger, session.IsUserTool),
+		Schedules:      schedule.NewStore(logger, session.IsUserTool),
 		AgentManager:   agentMgr,
 		GroupDMManager: groupDMMgr,
 		BlobStore:      blobStore,
@@ -1009,6 +1303,26 @@ func main() {
 		// RepoDir enables POST /api/v1/system/rebuild (`make build` +
 		// in-place binary swap). Empty disables the endpoint.
 		RepoDir:        os.Getenv("KOJO_REPO_DIR"),
+		FileRoots:      cfg.FileRoots,
+		GitExecAllow:   cfg.GitExecAllow,
+		GitExecDeny:    cfg.GitExecDeny,
+		GitAuditLog:    filepath.Join(configdir.Path(), "git-exec-audit.jsonl"),
+		AuditLog:       filepath.Join(configdir.Path(), "audit.jsonl"),
+		RateLimits:     rateLimits(cfg),
+		WSLimits:       wsLimits(cfg),
+		UploadTTL:      time.Duration(cfg.UploadTTLHours) * time.Hour,
+		UploadQuota:    int64(cfg.UploadQuotaMB) << 20,
+		SessionBackend: cfg.SessionBackend,
+		RemoteHosts:    remoteHosts(cfg),
+		Sandboxes:      sandboxes(cfg),
+		YoloSandbox:    cfg.YoloSandbox,
+		NoRedact:       cfg.NoRedact,
+		RedactPatterns: cfg.RedactPatterns,
+		ProtectedPaths: cfg.ProtectedPaths,
+		NoYoloPaths:    cfg.NoYoloPaths,
+		AccessLog:      cfg.AccessLog,
+		DebugEndpoints: cfg.DebugEndpoints,
+		ReadOnly:       cfg.ReadOnly,
 		V0LegacyDir:    sessionV0LegacyDir,
 		PeerOnly:       *peerMode,
 		PendingSyncKEK: pendingSyncKEK,
@@ -1325,6 +1639,57 @@ func main() {
 		srv.SetRestartTrigger(requestRestart)
 	}
 
+	// Config reload (SIGHUP / POST /api/v1/system/reload): re-read the
+	// file and env, re-apply the command-line flags, and swap in the
+	// settings that can change without a restart. Live sessions are
+	// untouched; listener settings still need a restart.
+	var reloadMu sync.Mutex
+	reloadConfig := func() error {
+		reloadMu.Lock()
+		defer reloadMu.Unlock()
+		next, err := loadConfig(*configFile, resolvedDir)
+		if err != nil {
+			return err
+		}
+		overlayFlags(&next)
+		if err := next.Validate(); err != nil {
+			return err
+		}
+		lvl, err := resolveLogLevel(next)
+		if err != nil {
+			return err
+		}
+		if next.Port != cfg.Port || next.Hostname != cfg.Hostname || next.StateDir != cfg.StateDir ||
+			next.Dev != cfg.Dev || next.ViteURL != cfg.ViteURL || next.SpawnVite != cfg.SpawnVite ||
+			next.Local != cfg.Local || next.Socket != cfg.Socket ||
+			next.Funnel != cfg.Funnel || next.FunnelPort != cfg.FunnelPort || !slices.Equal(next.Listen, cfg.Listen) ||
+			next.BasePath != cfg.BasePath || next.TLSCert != cfg.TLSCert || next.TLSKey != cfg.TLSKey ||
+			!slices.Equal(next.ACMEDomains, cfg.ACMEDomains) || next.ACMEHTTPAddr != cfg.ACMEHTTPAddr ||
+			next.AccessLog != cfg.AccessLog || next.DebugEndpoints != cfg.DebugEndpoints ||
+			next.ReadOnly != cfg.ReadOnly || next.PortRange != cfg.PortRange || next.StrictPort != cfg.StrictPort ||
+			next.Bind != cfg.Bind || next.LogFormat != cfg.LogFormat || next.LogFile != cfg.LogFile ||
+			next.LogFileMaxMB != cfg.LogFileMaxMB || next.LogFileBackups != cfg.LogFileBackups {
+			logger.Warn("config reload: listener settings (port, portRange, strictPort, bind, hostname, stateDir, dev, viteURL, spawnVite, local, listen, socket, funnel, basePath, tls, acme, accessLog, debugEndpoints, readOnly, log format and file) take effect after a restart")
+		}
+		logLevel.Set(lvl)
+		if err := logLevels.Replace(componentLevels(next)); err != nil {
+			return err
+		}
+		srv.ApplySettings(server.Settings{
+			FileRoots:    next.FileRoots,
+			GitExecAllow: next.GitExecAllow,
+			GitExecDeny:  next.GitExecDeny,
+			RateLimits:   rateLimits(next),
+			WSLimits:     wsLimits(next),
+			UploadTTL:    time.Duration(next.UploadTTLHours) * time.Hour,
+			UploadQuota:  int64(next.UploadQuotaMB) << 20,
+		})
+		logger.Info("config reloaded", "logLevel", lvl.String(), "fileRoots", next.FileRoots)
+		return nil
+	}
+	srv.SetReloadTrigger(reloadConfig)
+	go reloadOnSignal(ctx, reloadConfig, logger)
+
 	// Background sweep of expired idempotency_keys rows (3.5). The
 	// dedup window is 24 h; sweeping once an hour keeps the table
 	// from growing unbounded without competing with hot writes. The
@@ -1336,8 +1701,8 @@ func main() {
 	// stamps (never auto-notify against a dirty describe), operator
 	// opt-out, or env kill-switch. The checker itself is still on
 	// server.Config so the HTTP endpoints can CheckNow/Apply on demand.
-	if *noUpdateCheck || os.Getenv("KOJO_NO_UPDATE_CHECK") == "1" {
-		logger.Info("selfupdate: periodic check disabled by flag or KOJO_NO_UPDATE_CHECK")
+	if cfg.NoUpdateCheck {
+		logger.Info("selfupdate: periodic check disabled by flag, config or KOJO_NO_UPDATE_CHECK")
 	} else if _, err := selfupdate.ParseVersion(version); err != nil {
 		logger.Debug("selfupdate: periodic check skipped (unparseable version)",
 			"version", version, "err", err)
@@ -1369,7 +1734,7 @@ func main() {
 			fmt.Fprintln(os.Stderr, "kojo: --tailnet-only set but Tailscale interface is unavailable; refusing to bind to 0.0.0.0")
 			os.Exit(1)
 		}
-		ln, err := listenWithFallback(bindHost, *port, 10, logger)
+		ln, err := listenWithFallback(bindHost, *port, cfg.PortAttempts(), logger)
 		if err != nil {
 			logger.Error("failed to listen", "err", err)
 			os.Exit(1)
@@ -1383,6 +1748,7 @@ func main() {
 		if tcp, ok := ln.Addr().(*net.TCPAddr); ok {
 			listenPort = tcp.Port
 		}
+		srv.SetListenPort(listenPort)
 		fmt.Fprintf(os.Stderr, "\n  kojo v%s (peer mode) running at:\n\n    http://%s\n\n", version, actualAddr)
 
 		// Stamp the peer_registry self-row with an address the Hub /
@@ -1519,7 +1885,7 @@ func main() {
 		// mode uses below; agentAPIBase is stamped into the PTY's
 		// $KOJO_API_BASE env so the CLI's curl examples target
 		// the loopback listener with a Bearer token.
-		authLn, err := listenWithFallback("127.0.0.1", *port+1, 10, logger)
+		authLn, err := listenWithFallback("127.0.0.1", *port+1, cfg.PortAttempts(), logger)
 		if err != nil {
 			logger.Error("failed to listen on agent loopback auth port", "err", err)
 			os.Exit(1)
@@ -1535,12 +1901,20 @@ func main() {
 			}
 		}()
 	} else if *local || *dev {
-		ln, err := listenWithFallback("127.0.0.1", *port, 10, logger)
+		ln, err := listenWithFallback(localBind, *port, cfg.PortAttempts(), logger)
 		if err != nil {
 			logger.Error("failed to listen", "err", err)
 			os.Exit(1)
 		}
 		actualAddr := ln.Addr().String()
+		// Agents on this host reach a wildcard --bind through loopback.
+		apiAddr := actualAddr
+		if tcp, ok := ln.Addr().(*net.TCPAddr); ok {
+			srv.SetListenPort(tcp.Port)
+			if tcp.IP.IsUnspecified() {
+				apiAddr = net.JoinHostPort("127.0.0.1", strconv.Itoa(tcp.Port))
+			}
+		}
 
 		if *noAuth {
 			// --no-auth (--local/--dev only): the loopback listener is
@@ -1549,7 +1923,7 @@ func main() {
 			// the full API as Owner. Group DM curl examples stay
 			// pointed at this listener.
 			fmt.Fprintf(os.Stderr, "\n  kojo v%s running at:\n\n    http://%s\n\n", version, actualAddr)
-			groupDMMgr.SetAPIBase("http://" + actualAddr)
+			groupDMMgr.SetAPIBase("http://" + apiAddr)
 			logger.Warn("agent auth listener disabled via --no-auth — agents can read full API as Owner")
 			go func() {
 				if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
@@ -1563,7 +1937,7 @@ func main() {
 			// present (Owner vs per-agent). On first visit the UI
 			// follows the printed URL whose ?token= query param
 			// bootstraps the Owner token into localStorage.
-			agentAPIBase := "http://" + actualAddr
+			agentAPIBase := "http://" + apiAddr
 			agent.SetKojoAPIBase(agentAPIBase)
 			groupDMMgr.SetAPIBase(agentAPIBase)
 			// KOJO_OWNER_TOKEN may be a custom string; URL-escape so
@@ -1590,9 +1964,12 @@ func main() {
 		if tsHost == "" {
 			tsHost = "kojo"
 		}
+		tsLogger := logLevels.Logger("tsnet")
 		tsServer := &tsnet.Server{
 			Hostname: tsHost,
-			Logf:     func(format string, args ...any) { logger.Debug(fmt.Sprintf(format, args...)) },
+			Dir:      cfg.StateDir,
+			AuthKey:  cfg.TSAuthKey,
+			Logf:     func(format string, args ...any) { tsLogger.Debug(fmt.Sprintf(format, args...)) },
 		}
 
 		ln, err := tsServer.ListenTLS("tcp", fmt.Sprintf(":%d", *port))
@@ -1600,6 +1977,7 @@ func main() {
 			logger.Error("failed to listen on tailscale", "err", err)
 			os.Exit(1)
 		}
+		srv.SetListenPort(*port)
 
 		// get tailscale addresses for display. Display only — the agent
 		// API base is wired further below to the local auth listener so
@@ -1617,9 +1995,16 @@ func main() {
 			// peer_registry self-row once the FQDN is known.
 			if status, err := lc.Status(ctx); err == nil {
 				printTailscaleAddrs(status, *port)
+				if u := tailscaleURL(status, *port); u != "" {
+					srv.SetAccessURL(u)
+					printAccessQR(u, logger)
+				} else {
+					go publishAccessURL(ctx, lc, srv, *port, logger)
+				}
 			} else {
 				logger.Warn("could not get tailscale status", "err", err)
 				fmt.Fprintf(os.Stderr, "    https://%s.<tailnet>.ts.net:%d  (getting status...)\n", tsHost, *port)
+				go publishAccessURL(ctx, lc, srv, *port, logger)
 			}
 			// Wire the WhoIs-backed identity resolver into the
 			// Server now that LocalClient is ready. The closure
@@ -1683,7 +2068,7 @@ func main() {
 		// running in PTY sessions reach this via $KOJO_API_BASE; the
 		// Tailscale listener above stays open for the user UI without
 		// any token requirement, preserving the original UX.
-		authLn, err := listenWithFallback("127.0.0.1", *port+1, 10, logger)
+		authLn, err := listenWithFallback("127.0.0.1", *port+1, cfg.PortAttempts(), logger)
 		if err != nil {
 			logger.Error("failed to listen on auth port", "err", err)
 			os.Exit(1)
@@ -1703,10 +2088,110 @@ func main() {
 			}
 		}()
 
+		// Funnel: the same auth chain as the loopback listener above,
+		// reachable from the internet. FunnelOnly keeps tailnet
+		// clients on the token-free listener; anything arriving here
+		// must present the Owner or an agent token.
+		if cfg.Funnel {
+			funnelLn, err := tsServer.ListenFunnel("tcp", fmt.Sprintf(":%d", cfg.FunnelPort), tsnet.FunnelOnly())
+			if err != nil {
+				logger.Error("failed to start tailscale funnel", "err", err)
+				os.Exit(1)
+			}
+			funnelURL := "https://" + tsHost + ".<tailnet>.ts.net"
+			if lc != nil {
+				if st, err := lc.StatusWithoutPeers(ctx); err == nil && st.Self != nil && st.Self.DNSName != "" {
+					funnelURL = "https://" + strings.TrimSuffix(st.Self.DNSName, ".")
+				}
+			}
+			if cfg.FunnelPort != 443 {
+				funnelURL += fmt.Sprintf(":%d", cfg.FunnelPort)
+			}
+			fmt.Fprintf(os.Stderr, "    public (Funnel): %s  (Bearer required)\n", funnelURL)
+			if tok := tokens.OwnerToken(); tok != "" {
+				fmt.Fprintf(os.Stderr, "    open once to authorize a browser: %s/?token=%s\n", funnelURL, url.QueryEscape(tok))
+			} else {
+				// Only a hash is kept after the first boot.
+				fmt.Fprintln(os.Stderr, "    authorize a browser with ?token=<owner token> (set KOJO_OWNER_TOKEN to choose it)")
+			}
+			fmt.Fprintln(os.Stderr)
+			go func() {
+				if err := srv.ServeAuth(funnelLn, resolver); err != nil && err != http.ErrServerClosed {
+					logger.Error("funnel listener error", "err", err)
+					stop()
+				}
+			}()
+		}
+
 		tsShutdown = sync.OnceFunc(func() { _ = tsServer.Close() })
 		defer tsShutdown()
 	}
 
+	// Extra listeners (--listen): the same auth chain as the loopback
+	// agent listener, so the UI bootstraps with ?token= and agents use
+	// their Bearer. Lets one process serve tsnet and localhost (or a
+	// LAN address) with shared session state. Peer mode binds its own.
+	// With --tls-cert or --acme-domain they serve HTTPS.
+	if !*peerMode {
+		tlsCfg, acmeChallenge, err := listenTLSConfig(cfg, resolvedDir)
+		if err != nil {
+			logger.Error("failed to set up TLS", "err", err)
+			os.Exit(1)
+		}
+		if acmeChallenge != nil {
+			challengeSrv := &http.Server{Addr: cfg.ACMEHTTPAddr, Handler: acmeChallenge, ReadHeaderTimeout: 10 * time.Second}
+			go func() {
+				if err := challengeSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					logger.Error("ACME challenge listener error", "addr", cfg.ACMEHTTPAddr, "err", err)
+				}
+			}()
+			defer challengeSrv.Close()
+		}
+		scheme := "http"
+		if tlsCfg != nil {
+			scheme = "https"
+		}
+		for _, addr := range cfg.Listen {
+			extraLn, err := net.Listen("tcp", addr)
+			if err != nil {
+				logger.Error("failed to listen", "addr", addr, "err", err)
+				os.Exit(1)
+			}
+			if tlsCfg != nil {
+				extraLn = tls.NewListener(extraLn, tlsCfg)
+			} else if host, _, _ := net.SplitHostPort(addr); !isLoopbackHost(host) {
+				logger.Warn("extra listener is plain HTTP; tokens cross the network unencrypted", "addr", addr)
+			}
+			fmt.Fprintf(os.Stderr, "    also at: %s://%s  (token required)\n\n", scheme, extraLn.Addr())
+			go func() {
+				if err := srv.ServeAuth(extraLn, resolver); err != nil && err != http.ErrServerClosed {
+					logger.Error("extra listener error", "addr", addr, "err", err)
+					stop()
+				}
+			}()
+		}
+	} else if len(cfg.Listen) > 0 {
+		logger.Warn("--listen is ignored in --peer mode")
+	}
+
+	// Control socket: the full API as Owner for local CLI tooling and
+	// scripts, with the socket file's mode as the only gate. Served in
+	// every mode alongside the TCP listeners; failure to bind is not
+	// fatal since the TCP API still works.
+	if sockPath := controlSocketPath(cfg.Socket, resolvedDir); controlSocketSupported && sockPath != "" {
+		sockLn, err := listenControlSocket(sockPath)
+		if err != nil {
+			logger.Warn("control socket disabled", "path", sockPath, "err", err)
+		} else {
+			fmt.Fprintf(os.Stderr, "    control socket: %s\n\n", sockPath)
+			go func() {
+				if err := srv.ServeUnix(sockLn, resolver); err != nil && err != http.ErrServerClosed {
+					logger.Error("control socket error", "err", err)
+				}
+			}()
+		}
+	}
+
 	// Consume a restart-wake marker if the previous process armed one
 	// (POST /api/v1/system/restart {"wake":true}) — fires ONE chat turn
 	// for the marked agent so it can verify its own deploy. Placed after
@@ -1796,21 +2281,72 @@ func printTailscaleAddrs(status *ipnstate.Status, port int) {
 	if status == nil {
 		return
 	}
-	if status.Self != nil {
-		dnsName := strings.TrimSuffix(status.Self.DNSName, ".")
-		if dnsName != "" {
-			if port == 443 {
-				fmt.Fprintf(os.Stderr, "    https://%s\n", dnsName)
-			} else {
-				fmt.Fprintf(os.Stderr, "    https://%s:%d\n", dnsName, port)
-			}
-		}
+	if u := tailscaleURL(status, port); u != "" {
+		fmt.Fprintf(os.Stderr, "    %s\n", u)
 	}
 	for _, ip := range status.TailscaleIPs {
 		fmt.Fprintf(os.Stderr, "    https://%s\n", net.JoinHostPort(ip.String(), strconv.Itoa(port)))
 	}
 }
 
+// tailscaleURL is the MagicDNS https URL Status reports for this
+// node, "" while the name is not known yet.
+func tailscaleURL(status *ipnstate.Status, port int) string {
+	if status == nil || status.Self == nil {
+		return ""
+	}
+	dnsName := strings.TrimSuffix(status.Self.DNSName, ".")
+	switch {
+	case dnsName == "":
+		return ""
+	case port == 443:
+		return "https://" + dnsName
+	default:
+		return fmt.Sprintf("https://%s:%d", dnsName, port)
+	}
+}
+
+// printAccessQR prints a QR code of u under the startup banner so a
+// phone can open it by scanning. Skipped when stderr is not a
+// terminal (launchd, systemd, redirected logs).
+func printAccessQR(u string, logger *slog.Logger) {
+	if !term.IsTerminal(int(os.Stderr.Fd())) {
+		return
+	}
+	code, err := qrcode.Terminal(u)
+	if err != nil {
+		logger.Warn("could not render QR code", "err", err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\n%s\n", code)
+}
+
+// publishAccessURL waits for tsnet to report the MagicDNS name when it
+// wasn't known at startup, then records the access URL for
+// GET /api/v1/qr and prints it with its QR code.
+func publishAccessURL(ctx context.Context, lc tailscaleLocalClient, srv *server.Server, port int, logger *slog.Logger) {
+	const maxAttempts = 30
+	for i := 0; i < maxAttempts; i++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(2 * time.Second):
+		}
+		statusCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		st, err := lc.Status(statusCtx)
+		cancel()
+		if err != nil {
+			continue
+		}
+		if u := tailscaleURL(st, port); u != "" {
+			srv.SetAccessURL(u)
+			fmt.Fprintf(os.Stderr, "\n  kojo is reachable at %s\n", u)
+			printAccessQR(u, logger)
+			return
+		}
+	}
+}
+
 // printHubPairingSpecOnce waits for tsnet to report a stable
 // DNSName, then prints the Hub's pairing spec (deviceID|name|url|
 // publicKey) to stderr exactly once. Mirrors the `--peer` mode's
@@ -1933,6 +2469,7 @@ func captureSelfNodeKeyFromOSTailscale(ctx context.Context, srv *server.Server,
 			nk := st.Self.PublicKey.String()
 			if nk != "" {
 				srv.SetSelfNodeKey(nk)
+				srv.SetTailscaleName(strings.TrimSuffix(st.Self.DNSName, "."))
 				if reg != nil {
 					reg.SetSelfNodeKey(nk)
 					refreshCtx, refreshCancel := context.WithTimeout(ctx, 5*time.Second)
@@ -2108,6 +2645,7 @@ func captureSelfNodeKeyFromTailscale(ctx context.Context, lc tailscaleLocalClien
 			nk := st.Self.PublicKey.String()
 			if nk != "" {
 				srv.SetSelfNodeKey(nk)
+				srv.SetTailscaleName(strings.TrimSuffix(st.Self.DNSName, "."))
 				if reg != nil {
 					reg.SetSelfNodeKey(nk)
 					refreshCtx, refreshCancel := context.WithTimeout(ctx, 5*time.Second)
@@ -2255,14 +2793,27 @@ func peerBindAndAdvertise(parent context.Context, logger *slog.Logger) (bindHost
 	return ip, ip
 }
 
-func listenWithFallback(host string, startPort, maxAttempts int, logger *slog.Logger) (net.Listener, error) {
-	for i := range maxAttempts {
+// isLoopbackHost reports whether a listen host stays on this machine.
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// listenWithFallback listens on host:startPort, or on the next free
+// port when it is busy, trying attempts ports in all. A move is logged
+// as a warning since it breaks bookmarked URLs; --strict-port
+// (attempts == 1) makes it an error instead.
+func listenWithFallback(host string, startPort, attempts int, logger *slog.Logger) (net.Listener, error) {
+	for i := range attempts {
 		port := startPort + i
 		addr := net.JoinHostPort(host, strconv.Itoa(port))
 		ln, err := net.Listen("tcp", addr)
 		if err == nil {
 			if i > 0 {
-				logger.Info("port was busy, using fallback", "requested", startPort, "actual", port)
+				logger.Warn("port was busy, listening on another one", "requested", startPort, "actual", port)
 			}
 			return ln, nil
 		}
@@ -2270,5 +2821,8 @@ func listenWithFallback(host string, startPort, maxAttempts int, logger *slog.Lo
 			return nil, err
 		}
 	}
-	return nil, fmt.Errorf("all ports %d-%d are in use", startPort, startPort+maxAttempts-1)
+	if attempts == 1 {
+		return nil, fmt.Errorf("port %d on %s is in use (--strict-port); stop whatever holds it or pick another --port", startPort, host)
+	}
+	return nil, fmt.Errorf("ports %d-%d on %s are all in use; raise --port-range or pick another --port", startPort, startPort+attempts-1, host)
 }
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+)
+
+// reloadOnSignal runs reload on every reloadSignals delivery until ctx
+// is cancelled. A failed reload keeps the previous settings.
+func reloadOnSignal(ctx context.Context, reload func() error, logger *slog.Logger) {
+	if len(reloadSignals) == 0 {
+		return
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, reloadSignals...)
+	defer signal.Stop(ch)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-ch:
+			logger.Info("config reload requested", "signal", sig.String())
+			if err := reload(); err != nil {
+				logger.Error("config reload failed; keeping previous settings", "err", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,12 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// reloadSignals re-read the config file. SIGHUP is the daemon
+// convention (and otherwise kills the process when its terminal closes).
+var reloadSignals = []os.Signal{syscall.SIGHUP}
@@ -0,0 +1,9 @@
+//go:build windows
+
+package main
+
+import "os"
+
+// reloadSignals is empty: windows has no SIGHUP, so a reload goes
+// through POST /api/v1/system/reload only.
+var reloadSignals []os.Signal
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const (
+	// launchdLabel names the launchd job and its plist.
+	launchdLabel = "com.loppo.kojo"
+	// systemdUnitName is the systemd user unit's file name.
+	systemdUnitName = "kojo.service"
+)
+
+// serviceSpec is everything a service definition needs, resolved at
+// install time so the unit doesn't depend on the installing shell.
+type serviceSpec struct {
+	Exe  string   // absolute path, symlinks resolved
+	Args []string // daemon flags, e.g. --local --port 9090
+	// Path is copied into the unit's environment: launchd and systemd
+	// start jobs with a minimal PATH that won't find claude, codex
+	// or git installed under the user's home.
+	Path    string
+	LogPath string
+}
+
+// serviceManager is the per-OS half of `kojo service`.
+type serviceManager struct {
+	unitPath string
+	render   func(serviceSpec) string
+	install  [][]string // commands run after the unit is written
+	remove   [][]string // commands run before the unit is deleted
+	status   []string
+	hint     string // printed after install
+}
+
+// runServiceCommand implements `kojo service install|uninstall|status`.
+// Like `kojo update` it is intercepted before flag.Parse. Flags after
+// `--` on install become the daemon's flags:
+//
+//	kojo service install -- --local --port 9090
+func runServiceCommand(args []string) int {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "usage: kojo service install [-log <file>] [-- kojo flags...]")
+		fmt.Fprintln(os.Stderr, "       kojo service uninstall")
+		fmt.Fprintln(os.Stderr, "       kojo service status")
+	}
+	if len(args) == 0 {
+		usage()
+		return 2
+	}
+	mgr, err := newServiceManager()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "service: %v\n", err)
+		return 1
+	}
+
+	switch args[0] {
+	case "install":
+		fs := flag.NewFlagSet("service install", flag.ContinueOnError)
+		logPath := fs.String("log", defaultServiceLogPath(), "file the daemon's stdout and stderr are appended to")
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2
+		}
+		spec, err := newServiceSpec(fs.Args(), *logPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "service install: %v\n", err)
+			return 1
+		}
+		if err := mgr.installUnit(spec); err != nil {
+			fmt.Fprintf(os.Stderr, "service install: %v\n", err)
+			return 1
+		}
+		fmt.Printf("installed %s\nlogs: %s\n", mgr.unitPath, spec.LogPath)
+		if mgr.hint != "" {
+			fmt.Println(mgr.hint)
+		}
+		return 0
+	case "uninstall":
+		if err := mgr.uninstallUnit(); err != nil {
+			fmt.Fprintf(os.Stderr, "service uninstall: %v\n", err)
+			return 1
+		}
+		fmt.Printf("removed %s\n", mgr.unitPath)
+		return 0
+	case "status":
+		if _, err := os.Stat(mgr.unitPath); errors.Is(err, os.ErrNotExist) {
+			fmt.Println("not installed")
+			return 1
+		}
+		fmt.Printf("unit: %s\n", mgr.unitPath)
+		cmd := exec.Command(mgr.status[0], mgr.status[1:]...)
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			return 1
+		}
+		return 0
+	}
+	usage()
+	return 2
+}
+
+func newServiceSpec(args []string, logPath string) (serviceSpec, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return serviceSpec{}, err
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	if logPath, err = filepath.Abs(logPath); err != nil {
+		return serviceSpec{}, err
+	}
+	return serviceSpec{Exe: exe, Args: args, Path: os.Getenv("PATH"), LogPath: logPath}, nil
+}
+
+// defaultServiceLogPath follows each platform's per-user log location.
+func defaultServiceLogPath() string {
+	home, _ := os.UserHomeDir()
+	if runtime.GOOS == "darwin" {
+		return filepath.Join(home, "Library", "Logs", "kojo", "kojo.log")
+	}
+	if state := os.Getenv("XDG_STATE_HOME"); filepath.IsAbs(state) {
+		return filepath.Join(state, "kojo", "kojo.log")
+	}
+	return filepath.Join(home, ".local", "state", "kojo", "kojo.log")
+}
+
+func newServiceManager() (*serviceManager, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		domain := "gui/" + strconv.Itoa(os.Getuid())
+		plist := filepath.Join(home, "Library", "LaunchAgents", launchdLabel+".plist")
+		return &serviceManager{
+			unitPath: plist,
+			render:   launchdPlist,
+			// bootout first so a reinstall picks up the new plist; its
+			// failure when nothing is loaded is ignored.
+			install: [][]string{
+				{"-launchctl", "bootout", domain + "/" + launchdLabel},
+				{"launchctl", "bootstrap", domain, plist},
+			},
+			remove: [][]string{{"-launchctl", "bootout", domain + "/" + launchdLabel}},
+			status: []string{"launchctl", "print", domain + "/" + launchdLabel},
+		}, nil
+	case "linux":
+		dir := filepath.Join(home, ".config")
+		if xdg := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(xdg) {
+			dir = xdg
+		}
+		return &serviceManager{
+			unitPath: filepath.Join(dir, "systemd", "user", systemdUnitName),
+			render:   systemdUnit,
+			install: [][]string{
+				{"systemctl", "--user", "daemon-reload"},
+				{"systemctl", "--user", "enable", systemdUnitName},
+				// restart rather than start so a reinstall over a
+				// running unit picks up the new definition.
+				{"systemctl", "--user", "restart", systemdUnitName},
+			},
+			remove: [][]string{{"-systemctl", "--user", "disable", "--now", systemdUnitName}},
+			status: []string{"systemctl", "--user", "status", "--no-pager", systemdUnitName},
+			hint:   "to keep kojo running after you log out: loginctl enable-linger " + os.Getenv("USER"),
+		}, nil
+	}
+	return nil, fmt.Errorf("not supported on %s", runtime.GOOS)
+}
+
+func (m *serviceManager) installUnit(spec serviceSpec) error {
+	if err := os.MkdirAll(filepath.Dir(spec.LogPath), 0o755); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.unitPath), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(m.unitPath, []byte(m.render(spec)), 0o644); err != nil {
+		return err
+	}
+	return runServiceSteps(m.install)
+}
+
+func (m *serviceManager) uninstallUnit() error {
+	if _, err := os.Stat(m.unitPath); errors.Is(err, os.ErrNotExist) {
+		return errors.New("not installed")
+	}
+	if err := runServiceSteps(m.remove); err != nil {
+		return err
+	}
+	if err := os.Remove(m.unitPath); err != nil {
+		return err
+	}
+	if runtime.GOOS == "linux" {
+		return runServiceSteps([][]string{{"systemctl", "--user", "daemon-reload"}})
+	}
+	return nil
+}
+
+// runServiceSteps runs each command in order, stopping at the first
+// failure. A leading "-" on the program name (make's convention) marks
+// a step whose failure is expected and ignored.
+func runServiceSteps(steps [][]string) error {
+	for _, step := range steps {
+		name, optional := strings.CutPrefix(step[0], "-")
+		out, err := exec.Command(name, step[1:]...).CombinedOutput()
+		if err != nil && !optional {
+			return fmt.Errorf("%s: %w: %s", strings.Join(append([]string{name}, step[1:]...), " "), err, bytes.TrimSpace(out))
+		}
+	}
+	return nil
+}
+
+// launchdPlist renders a LaunchAgent that starts at login and is
+// restarted when it exits abnormally. A clean exit (kojo shutting down
+// on SIGTERM) stays down.
+func launchdPlist(spec serviceSpec) string {
+	esc := func(s string) string {
+		var b strings.Builder
+		_ = xml.EscapeText(&b, []byte(s))
+		return b.String()
+	}
+	var args strings.Builder
+	for _, a := range append([]string{spec.Exe}, spec.Args...) {
+		fmt.Fprintf(&args, "\t\t<string>%s</string>\n", esc(a))
+	}
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>` + launchdLabel + `</string>
+	<key>ProgramArguments</key>
+	<array>
+` + args.String() + `	</array>
+	<key>EnvironmentVariables</key>
+	<dict>
+		<key>PATH</key>
+		<string>` + esc(spec.Path) + `</string>
+	</dict>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<dict>
+		<key>SuccessfulExit</key>
+		<false/>
+	</dict>
+	<key>ThrottleInterval</key>
+	<integer>10</integer>
+	<key>StandardOutPath</key>
+	<string>` + esc(spec.LogPath) + `</string>
+	<key>StandardErrorPath</key>
+	<string>` + esc(spec.LogPath) + `</string>
+</dict>
+</plist>
+`
+}
+
+// systemdUnit renders a user unit restarted on failure, with output
+// appended to the log file (systemd 240+) rather than only the journal.
+func systemdUnit(spec serviceSpec) string {
+	words := make([]string, 0, len(spec.Args)+1)
+	for _, a := range append([]string{spec.Exe}, spec.Args...) {
+		words = append(words, systemdQuote(a))
+	}
+	return `[Unit]
+Description=kojo
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+ExecStart=` + strings.Join(words, " ") + `
+Environment=` + systemdQuote("PATH="+spec.Path) + `
+Restart=on-failure
+RestartSec=5
+StandardOutput=append:` + systemdEscape(spec.LogPath) + `
+StandardError=append:` + systemdEscape(spec.LogPath) + `
+
+[Install]
+WantedBy=default.target
+`
+}
+
+// systemdQuote double-quotes a word for ExecStart= / Environment= when
+// it needs it.
+func systemdQuote(s string) string {
+	s = systemdEscape(s)
+	if s != "" && !strings.ContainsAny(s, " \t\"'\\;") {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// systemdEscape doubles the specifier and variable characters systemd
+// would otherwise expand.
+func systemdEscape(s string) string {
+	return strings.NewReplacer("%", "%%", "$", "$$").Replace(s)
+}
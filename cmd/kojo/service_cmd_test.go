@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSystemdUnit(t *testing.T) {
+	unit := systemdUnit(serviceSpec{
+		Exe:     "/opt/my apps/kojo",
+		Args:    []string{"--local", "--port", "9090"},
+		Path:    "/usr/bin:/home/u/.local/bin",
+		LogPath: "/home/u/.local/state/kojo/kojo.log",
+	})
+	for _, want := range []string{
+		`ExecStart="/opt/my apps/kojo" --local --port 9090`,
+		`Environment=PATH=/usr/bin:/home/u/.local/bin`,
+		"Restart=on-failure",
+		"StandardOutput=append:/home/u/.local/state/kojo/kojo.log",
+		"WantedBy=default.target",
+	} {
+		if !strings.Contains(unit, want) {
+			t.Errorf("unit missing %q:\n%s", want, unit)
+		}
+	}
+}
+
+func TestSystemdQuote(t *testing.T) {
+	for in, want := range map[string]string{
+		"plain":    "plain",
+		"a b":      `"a b"`,
+		`say "hi"`: `"say \"hi\""`,
+		"50%":      "50%%",
+		"$HOME":    "$$HOME",
+		"":         `""`,
+	} {
+		if got := systemdQuote(in); got != want {
+			t.Errorf("systemdQuote(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestLaunchdPlistEscapes(t *testing.T) {
+	plist := launchdPlist(serviceSpec{
+		Exe:     "/Applications/kojo",
+		Args:    []string{"--hostname", "a&b"},
+		Path:    "/usr/bin",
+		LogPath: "/Users/u/Library/Logs/kojo/kojo.log",
+	})
+	for _, want := range []string{
+		"<string>/Applications/kojo</string>",
+		"<string>a&amp;b</string>",
+		"<key>SuccessfulExit</key>\n\t\t<false/>",
+		"<string>/Users/u/Library/Logs/kojo/kojo.log</string>",
+	} {
+		if !strings.Contains(plist, want) {
+			t.Errorf("plist missing %q:\n%s", want, plist)
+		}
+	}
+}
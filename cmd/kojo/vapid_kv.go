@@ -8,6 +8,8 @@ import (
 	"path/filepath"
 	"time"
 
+	webpush "github.com/SherClockHolmes/webpush-go"
+
 	"github.com/loppo-llc/kojo/internal/agent"
 	"github.com/loppo-llc/kojo/internal/notify"
 	"github.com/loppo-llc/kojo/internal/store"
@@ -44,6 +46,35 @@ func buildVAPIDStore(agentMgr *agent.Manager, configDir string, logger *slog.Log
 	return vs
 }
 
+// seedPushSubscriptions hands the push subscriptions the v0→v1
+// importer put in the database to nm, which otherwise only reads its
+// own file and would leave every migrated browser unsubscribed. Rows
+// signed for a different VAPID key can't be delivered to and are
+// skipped.
+func seedPushSubscriptions(nm *notify.Manager, st *store.Store, logger *slog.Logger) {
+	if nm == nil || st == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), vapidKVOpTimeout)
+	defer cancel()
+	recs, err := st.ListActivePushSubscriptions(ctx)
+	if err != nil {
+		logger.Warn("could not read stored push subscriptions", "err", err)
+		return
+	}
+	var subs []*webpush.Subscription
+	for _, rec := range recs {
+		if rec.VAPIDPublicKey != "" && rec.VAPIDPublicKey != nm.VAPIDPublicKey() {
+			continue
+		}
+		subs = append(subs, &webpush.Subscription{
+			Endpoint: rec.Endpoint,
+			Keys:     webpush.Keys{Auth: rec.Auth, P256dh: rec.P256dh},
+		})
+	}
+	nm.SeedSubscriptions(subs)
+}
+
 // vapidKVStore implements notify.VAPIDStore on top of the kv table.
 //
 // Layout:
@@ -0,0 +1,402 @@
+//go:build !windows
+
+// Package apitest runs a real kojo Server against a fake tool, so the
+// session state machine can be tested end to end through the HTTP and
+// WebSocket API: create, attach, type, exit, restart.
+//
+// Start puts a shell script named after a user tool (FakeTool) first
+// on PATH and gives tmux its own socket directory, so tests neither
+// need the real CLIs nor touch the tmux server the developer's own
+// kojo runs in. The fake tool prints FakeToolReady, echoes every line
+// it reads as "echo: <line>", and exits with status N on "exit N";
+// Shift+Tab cycles the permission mode in its footer, as in claude.
+// Under tmux, output printed before kojo has hooked up the pane's
+// output pipe never reaches a client, so tests should wait for the
+// echo of a line (Terminal.Line) rather than for the banner.
+package apitest
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+
+	"github.com/loppo-llc/kojo/internal/server"
+	"github.com/loppo-llc/kojo/internal/session"
+)
+
+// FakeTool is the tool name the fake is installed as; create sessions
+// with "tool": FakeTool.
+const FakeTool = "claude"
+
+// FakeToolReady is the first line the fake tool prints.
+const FakeToolReady = "fake tool ready"
+
+// The fake reads a character at a time, as claude does, so it sees
+// Shift+Tab (ESC [ Z) without a newline; it cycles the permission
+// mode shown in a claude-style footer line.
+const fakeToolScript = `#!/bin/bash
+if [ "$1" = "--version" ]; then
+	echo "fake 1.0.0"
+	exit 0
+fi
+mode=default
+while [ $# -gt 0 ]; do
+	[ "$1" = "--permission-mode" ] && mode=$2
+	shift
+done
+footer() {
+	case "$mode" in
+	default) echo "  ? for shortcuts" ;;
+	acceptEdits) echo "  accept edits on (shift+tab to cycle)" ;;
+	plan) echo "  plan mode on (shift+tab to cycle)" ;;
+	esac
+}
+echo "` + FakeToolReady + `"
+footer
+line=
+while IFS= read -rn1 c; do
+	case "$c" in
+	"")
+		case "$line" in
+		exit*) exit ${line#exit} ;;
+		esac
+		echo "echo: $line"
+		footer
+		line= ;;
+	$'\e')
+		IFS= read -rn2 c
+		[ "$c" = "[Z" ] || continue
+		case "$mode" in
+		default) mode=acceptEdits ;;
+		acceptEdits) mode=plan ;;
+		*) mode=default ;;
+		esac
+		footer ;;
+	*) line="$line$c" ;;
+	esac
+done
+`
+
+// Timeout bounds every wait in the harness.
+const Timeout = 10 * time.Second
+
+// Options configure Start.
+type Options struct {
+	// Backend is session.BackendTmux or session.BackendPTY; empty
+	// picks tmux when it is installed.
+	Backend string
+}
+
+// Harness is a running Server with its API at URL.
+type Harness struct {
+	t      testing.TB
+	URL    string
+	Server *server.Server
+}
+
+// Start starts a Server for the test and stops it, with every session
+// and the private tmux server, when the test ends. It calls t.Setenv,
+// so tests using it can't run in parallel.
+func Start(t testing.TB, opts Options) *Harness {
+	t.Helper()
+	if opts.Backend == session.BackendTmux {
+		if _, err := exec.LookPath("tmux"); err != nil {
+			t.Skip("tmux not installed")
+		}
+	}
+
+	home := t.TempDir()
+	bin := filepath.Join(home, "bin")
+	if err := os.Mkdir(bin, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(bin, FakeTool), []byte(fakeToolScript), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// tmux's socket path has to stay short, so not under t.TempDir.
+	tmuxDir, err := os.MkdirTemp("", "kojo-apitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, ".cache"))
+	t.Setenv("TMUX_TMPDIR", tmuxDir)
+	t.Setenv("TMUX", "")
+	t.Cleanup(func() {
+		_ = exec.Command("tmux", "-L", "kojo", "kill-server").Run()
+		os.RemoveAll(tmuxDir)
+	})
+
+	srv := server.New(server.Config{
+		Logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Version:        "test",
+		SessionBackend: opts.Backend,
+		// Every caller is Owner, as with --no-auth.
+		Unsafe: true,
+	})
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(func() {
+		ts.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+	})
+	return &Harness{t: t, URL: ts.URL, Server: srv}
+}
+
+// Do sends a JSON request and decodes the response into out, if not
+// nil. It returns the status code.
+func (h *Harness) Do(method, path string, body, out any) int {
+	h.t.Helper()
+	var rd io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			h.t.Fatal(err)
+		}
+		rd = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, h.URL+path, rd)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		h.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			h.t.Fatalf("%s %s: decoding %d response: %v", method, path, resp.StatusCode, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// MustDo is Do failing the test unless the status is want.
+func (h *Harness) MustDo(method, path string, body, out any, want int) {
+	h.t.Helper()
+	var raw json.RawMessage
+	if got := h.Do(method, path, body, &raw); got != want {
+		h.t.Fatalf("%s %s = %d, want %d: %s", method, path, got, want, raw)
+	}
+	if out != nil {
+		if err := json.Unmarshal(raw, out); err != nil {
+			h.t.Fatal(err)
+		}
+	}
+}
+
+// CreateSession starts a FakeTool session in workDir (empty for the
+// fake home directory); extra fields go into the request as they are.
+func (h *Harness) CreateSession(workDir string, extra map[string]any) session.SessionInfo {
+	h.t.Helper()
+	req := map[string]any{"tool": FakeTool, "workDir": workDir}
+	for k, v := range extra {
+		req[k] = v
+	}
+	var info session.SessionInfo
+	h.MustDo(http.MethodPost, "/api/v1/sessions", req, &info, http.StatusOK)
+	return info
+}
+
+// Session fetches a session.
+func (h *Harness) Session(id string) session.SessionInfo {
+	h.t.Helper()
+	var info session.SessionInfo
+	h.MustDo(http.MethodGet, "/api/v1/sessions/"+id, nil, &info, http.StatusOK)
+	return info
+}
+
+// WaitStatus polls the session until its status is want.
+func (h *Harness) WaitStatus(id string, want session.Status) session.SessionInfo {
+	h.t.Helper()
+	deadline := time.Now().Add(Timeout)
+	for {
+		info := h.Session(id)
+		if info.Status == want {
+			return info
+		}
+		if time.Now().After(deadline) {
+			h.t.Fatalf("session %s is %s after %s, want %s", id, info.Status, Timeout, want)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// Terminal is a WebSocket attached to a session's terminal.
+type Terminal struct {
+	t    testing.TB
+	conn *websocket.Conn
+
+	mu      sync.Mutex
+	out     bytes.Buffer
+	msgs    []map[string]any
+	exit    *int
+	closed  bool
+	changed chan struct{}
+}
+
+// Attach opens the session's terminal WebSocket.
+func (h *Harness) Attach(id string) *Terminal {
+	h.t.Helper()
+	url := "ws" + strings.TrimPrefix(h.URL, "http") + "/api/v1/ws?session=" + id
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, url, nil)
+	if err != nil {
+		h.t.Fatalf("attaching to %s: %v", id, err)
+	}
+	conn.SetReadLimit(4 << 20)
+	term := &Terminal{t: h.t, conn: conn, changed: make(chan struct{}, 1)}
+	go term.read()
+	h.t.Cleanup(term.Close)
+	return term
+}
+
+func (c *Terminal) read() {
+	defer c.update(func() { c.closed = true })
+	for {
+		_, data, err := c.conn.Read(context.Background())
+		if err != nil {
+			return
+		}
+		var msg map[string]any
+		if json.Unmarshal(data, &msg) != nil {
+			continue
+		}
+		c.update(func() {
+			c.msgs = append(c.msgs, msg)
+			switch msg["type"] {
+			case "output", "scrollback", "desync":
+				s, _ := msg["data"].(string)
+				b, _ := base64.StdEncoding.DecodeString(s)
+				c.out.Write(b)
+			case "exit":
+				code, _ := msg["exitCode"].(float64)
+				n := int(code)
+				c.exit = &n
+			}
+		})
+	}
+}
+
+func (c *Terminal) update(f func()) {
+	c.mu.Lock()
+	f()
+	c.mu.Unlock()
+	select {
+	case c.changed <- struct{}{}:
+	default:
+	}
+}
+
+// wait waits until cond, called with c.mu held, is true.
+func (c *Terminal) wait(what string, cond func() bool) {
+	c.t.Helper()
+	timeout := time.After(Timeout)
+	for {
+		c.mu.Lock()
+		ok, closed := cond(), c.closed
+		c.mu.Unlock()
+		if ok {
+			return
+		}
+		if closed {
+			c.t.Fatalf("terminal closed waiting for %s; output:\n%s", what, c.Output())
+		}
+		select {
+		case <-c.changed:
+		case <-timeout:
+			c.t.Fatalf("no %s after %s; output:\n%s", what, Timeout, c.Output())
+		}
+	}
+}
+
+// Send writes a WebSocket message.
+func (c *Terminal) Send(msg any) {
+	c.t.Helper()
+	b, err := json.Marshal(msg)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	if err := c.conn.Write(ctx, websocket.MessageText, b); err != nil {
+		c.t.Fatalf("sending %s: %v", b, err)
+	}
+}
+
+// Type sends text as terminal input.
+func (c *Terminal) Type(text string) {
+	c.t.Helper()
+	c.Send(map[string]string{"type": "input", "data": base64.StdEncoding.EncodeToString([]byte(text))})
+}
+
+// Line types a line and waits for the fake tool's echo of it.
+func (c *Terminal) Line(line string) {
+	c.t.Helper()
+	c.Type(line + "\r")
+	c.WaitOutput("echo: " + line)
+}
+
+// Output is everything the terminal has received, escape sequences
+// included.
+func (c *Terminal) Output() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.out.String()
+}
+
+// WaitOutput waits for substr to appear in the output.
+func (c *Terminal) WaitOutput(substr string) {
+	c.t.Helper()
+	c.wait(fmt.Sprintf("%q", substr), func() bool { return strings.Contains(c.out.String(), substr) })
+}
+
+// WaitMessage waits for a message of type typ and returns the first.
+func (c *Terminal) WaitMessage(typ string) map[string]any {
+	c.t.Helper()
+	var found map[string]any
+	c.wait(typ+" message", func() bool {
+		for _, m := range c.msgs {
+			if m["type"] == typ {
+				found = m
+				return true
+			}
+		}
+		return false
+	})
+	return found
+}
+
+// WaitExit waits for the session's exit message and returns its code.
+func (c *Terminal) WaitExit() int {
+	c.t.Helper()
+	c.wait("exit message", func() bool { return c.exit != nil })
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return *c.exit
+}
+
+// Close closes the WebSocket.
+func (c *Terminal) Close() {
+	c.conn.Close(websocket.StatusNormalClosure, "")
+}
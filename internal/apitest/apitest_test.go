@@ -0,0 +1,106 @@
+//go:build !windows
+
+package apitest
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/loppo-llc/kojo/internal/session"
+)
+
+func TestSessionLifecycle(t *testing.T) {
+	for _, backend := range []string{session.BackendTmux, session.BackendPTY} {
+		t.Run(backend, func(t *testing.T) {
+			h := Start(t, Options{Backend: backend})
+
+			info := h.CreateSession(t.TempDir(), nil)
+			if info.Status != session.StatusRunning {
+				t.Fatalf("new session is %s", info.Status)
+			}
+			term := h.Attach(info.ID)
+			term.Line("hello")
+			if got := h.Session(info.ID); got.Viewers != 1 {
+				t.Errorf("viewers = %d, want 1", got.Viewers)
+			}
+
+			term.Type("exit 3\r")
+			if code := term.WaitExit(); code != 3 {
+				t.Errorf("exit message code = %d, want 3", code)
+			}
+			exited := h.WaitStatus(info.ID, session.StatusExited)
+			if exited.ExitCode == nil || *exited.ExitCode != 3 {
+				t.Errorf("exit code = %v, want 3", exited.ExitCode)
+			}
+
+			// A restart relaunches the tool in the same session.
+			h.MustDo(http.MethodPost, "/api/v1/sessions/"+info.ID+"/restart", nil, nil, http.StatusOK)
+			term = h.Attach(info.ID)
+			term.Line("again")
+
+			// DELETE stops a running session, then removes it.
+			h.MustDo(http.MethodDelete, "/api/v1/sessions/"+info.ID, nil, nil, http.StatusOK)
+			h.WaitStatus(info.ID, session.StatusExited)
+			h.MustDo(http.MethodDelete, "/api/v1/sessions/"+info.ID, nil, nil, http.StatusOK)
+			h.MustDo(http.MethodGet, "/api/v1/sessions/"+info.ID, nil, nil, http.StatusNotFound)
+		})
+	}
+}
+
+func TestCreateErrors(t *testing.T) {
+	h := Start(t, Options{Backend: session.BackendPTY})
+	var body struct {
+		Error struct{ Code string }
+	}
+	if got := h.Do(http.MethodPost, "/api/v1/sessions", map[string]any{"tool": "nope"}, &body); got != http.StatusBadRequest || body.Error.Code != "unsupported_tool" {
+		t.Errorf("unknown tool: %d %q", got, body.Error.Code)
+	}
+	if got := h.Do(http.MethodPost, "/api/v1/sessions", map[string]any{"tool": FakeTool, "workDir": "/does/not/exist"}, &body); got != http.StatusBadRequest || body.Error.Code != "work_dir_not_found" {
+		t.Errorf("missing workDir: %d %q", got, body.Error.Code)
+	}
+}
+
+func TestSessionAction(t *testing.T) {
+	h := Start(t, Options{Backend: session.BackendPTY})
+	info := h.CreateSession(t.TempDir(), nil)
+
+	var list struct{ Actions []session.ToolAction }
+	h.MustDo(http.MethodGet, "/api/v1/sessions/"+info.ID+"/actions", nil, &list, http.StatusOK)
+	if len(list.Actions) == 0 || list.Actions[0].Name != "compact" {
+		t.Fatalf("actions = %+v", list.Actions)
+	}
+
+	var resp struct{ Output []string }
+	h.MustDo(http.MethodPost, "/api/v1/sessions/"+info.ID+"/actions/compact", map[string]any{"captureMs": 500}, &resp, http.StatusOK)
+	if !slices.Contains(resp.Output, "echo: /compact") {
+		t.Errorf("output = %q, want the fake tool's echo of /compact", resp.Output)
+	}
+	h.MustDo(http.MethodPost, "/api/v1/sessions/"+info.ID+"/actions/nope", nil, nil, http.StatusNotFound)
+}
+
+func TestSessionControls(t *testing.T) {
+	h := Start(t, Options{Backend: session.BackendPTY})
+	info := h.CreateSession(t.TempDir(), nil)
+	if info.Model != "" || info.PermissionMode != "default" {
+		t.Fatalf("new session controls: %q, %q", info.Model, info.PermissionMode)
+	}
+	term := h.Attach(info.ID)
+	// kojo reads the current mode off the screen before switching it.
+	term.WaitOutput("? for shortcuts")
+
+	h.MustDo(http.MethodPatch, "/api/v1/sessions/"+info.ID, map[string]any{"model": "opus", "permissionMode": "plan"}, &info, http.StatusOK)
+	if info.Model != "opus" || info.PermissionMode != "plan" {
+		t.Errorf("patched controls: %q, %q", info.Model, info.PermissionMode)
+	}
+	term.WaitOutput("echo: /model opus")
+	h.MustDo(http.MethodPatch, "/api/v1/sessions/"+info.ID, map[string]any{"permissionMode": "yolo"}, nil, http.StatusBadRequest)
+
+	// A restart keeps them.
+	term.Type("exit 0\r")
+	h.WaitStatus(info.ID, session.StatusExited)
+	h.MustDo(http.MethodPost, "/api/v1/sessions/"+info.ID+"/restart", nil, &info, http.StatusOK)
+	if info.Model != "opus" || info.PermissionMode != "plan" {
+		t.Errorf("controls after restart: %q, %q", info.Model, info.PermissionMode)
+	}
+}
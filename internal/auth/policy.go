@@ -30,17 +30,23 @@ func writeJSONError(w http.ResponseWriter, status int, code, message string) {
 //
 //	GET    /api/v1/sessions                           list
 //	POST   /api/v1/sessions                           create
+//	DELETE /api/v1/sessions                           ?status=exited clear exited
+//	GET    /api/v1/sessions/events                    list changes (WebSocket; matches the {id} GET)
 //	GET    /api/v1/sessions/{id}                      info
-//	DELETE /api/v1/sessions/{id}                      stop
+//	DELETE /api/v1/sessions/{id}                      stop, or remove once exited
 //	PATCH  /api/v1/sessions/{id}                      yolo toggle / patch
 //	POST   /api/v1/sessions/{id}/restart
+//	POST   /api/v1/sessions/{id}/clone
 //	POST   /api/v1/sessions/{id}/tmux
+//	POST   /api/v1/sessions/{id}/signal
+//	POST   /api/v1/sessions/{id}/clipboard
 //	GET    /api/v1/sessions/{id}/terminal
 //	GET    /api/v1/sessions/{id}/attachments
 //	DELETE /api/v1/sessions/{id}/attachments          ?path=
+//	GET    /api/v1/sessions/{id}/images/{image}
 func allowPeerSessionPath(method, path string) bool {
 	if path == "/api/v1/sessions" {
-		return method == http.MethodGet || method == http.MethodPost
+		return method == http.MethodGet || method == http.MethodPost || method == http.MethodDelete
 	}
 	const prefix = "/api/v1/sessions/"
 	if !strings.HasPrefix(path, prefix) {
@@ -60,13 +66,16 @@ func allowPeerSessionPath(method, path string) bool {
 		case http.MethodGet, http.MethodPatch, http.MethodDelete:
 			return true
 		}
-	case "/restart", "/tmux":
+	case "/restart", "/clone", "/tmux", "/signal", "/clipboard":
 		return method == http.MethodPost
 	case "/terminal":
 		return method == http.MethodGet
 	case "/attachments":
 		return method == http.MethodGet || method == http.MethodDelete
 	}
+	if strings.HasPrefix(sub, "/images/") && !strings.Contains(sub[len("/images/"):], "/") {
+		return method == http.MethodGet
+	}
 	return false
 }
 
@@ -230,14 +239,32 @@ func AllowNonOwner(p Principal, method, path string) bool {
 		if method == http.MethodPost && path == "/api/v1/upload" {
 			return true
 		}
+		if method == http.MethodGet && path == "/api/v1/uploads" {
+			return true
+		}
+		if method == http.MethodDelete && strings.HasPrefix(path, "/api/v1/uploads/") &&
+			!strings.Contains(path[len("/api/v1/uploads/"):], "/") {
+			return true
+		}
 		// Git surface used by the Git tab. Read-only routes admit
 		// GET; the exec endpoint runs whitelisted operations
-		// inside handler-side guards.
+		// inside handler-side guards, and stage / unstage /
+		// discard / commit validate their paths and message.
 		if method == http.MethodGet && (path == "/api/v1/git/status" ||
-			path == "/api/v1/git/log" || path == "/api/v1/git/diff") {
+			path == "/api/v1/git/log" || path == "/api/v1/git/diff" ||
+			path == "/api/v1/git/show" || path == "/api/v1/git/file" ||
+			path == "/api/v1/git/prs" || path == "/api/v1/git/pr-checks" ||
+			path == "/api/v1/git/repos") {
+			return true
+		}
+		if path == "/api/v1/git/worktrees" && (method == http.MethodGet ||
+			method == http.MethodPost || method == http.MethodDelete) {
 			return true
 		}
-		if method == http.MethodPost && path == "/api/v1/git/exec" {
+		if method == http.MethodPost && (path == "/api/v1/git/exec" ||
+			path == "/api/v1/git/stage" || path == "/api/v1/git/unstage" ||
+			path == "/api/v1/git/discard" || path == "/api/v1/git/commit" ||
+			path == "/api/v1/git/prs") {
 			return true
 		}
 		return false
@@ -247,6 +274,10 @@ func AllowNonOwner(p Principal, method, path string) bool {
 	if method == http.MethodGet && path == "/api/v1/info" {
 		return true
 	}
+	// The error-code catalog is static documentation.
+	if method == http.MethodGet && path == "/api/v1/error-codes" {
+		return true
+	}
 	// Peer list: agents need this to discover handoff targets
 	// by Tailscale machine name. The wire shape carries no
 	// identity-sensitive fields, so Owner and Agent see the same
@@ -0,0 +1,523 @@
+// Package config holds kojo's layered runtime settings. A value
+// resolves as built-in default < config file < KOJO_* environment
+// variable < command-line flag; this package implements the first three
+// layers and cmd/kojo applies the flags it was given on top.
+//
+// The config file is JSON at <configdir>/config.json unless --config
+// points elsewhere. It is optional; a missing file leaves the defaults
+// in place. Unknown keys are rejected so a typo fails loudly at boot
+// instead of being silently ignored.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/loppo-llc/kojo/internal/logging"
+)
+
+// FileName is the config file's name inside the config directory.
+const FileName = "config.json"
+
+// FunnelPorts are the ports Tailscale Funnel can publish.
+var FunnelPorts = []int{443, 8443, 10000}
+
+// Config is the union of every setting that can come from the config
+// file or the environment. JSON keys are the field names in lowerCamel.
+type Config struct {
+	// Port is the main listener port. When it is busy the next free
+	// one within PortRange ports is used, unless StrictPort is set.
+	Port       int  `json:"port,omitempty"`
+	PortRange  int  `json:"portRange,omitempty"`
+	StrictPort bool `json:"strictPort,omitempty"`
+	// Bind is the address the --local / --dev listener binds to.
+	// Empty means 127.0.0.1.
+	Bind string `json:"bind,omitempty"`
+	// Hostname is the tsnet machine name.
+	Hostname string `json:"hostname,omitempty"`
+	// StateDir holds tsnet's node state. Empty leaves tsnet's default,
+	// which is shared by every kojo run as the same user.
+	StateDir string `json:"stateDir,omitempty"`
+	// TSAuthKey joins the tailnet without the interactive login URL.
+	// Only read on first start; the node key is kept in StateDir.
+	TSAuthKey string `json:"tsAuthKey,omitempty"`
+	Dev       bool   `json:"dev,omitempty"`
+	// ViteURL is the Vite dev server --dev proxies the web UI to.
+	// Empty means http://localhost:5173.
+	ViteURL string `json:"viteURL,omitempty"`
+	// SpawnVite makes --dev run the Vite dev server (npm run dev in
+	// ./web) itself.
+	SpawnVite bool `json:"spawnVite,omitempty"`
+	Local     bool `json:"local,omitempty"`
+	// Funnel also publishes kojo on the internet through Tailscale
+	// Funnel, on FunnelPort, behind the Bearer-token auth chain.
+	Funnel     bool `json:"funnel,omitempty"`
+	FunnelPort int  `json:"funnelPort,omitempty"`
+	// BasePath serves kojo under a URL prefix ("/kojo") behind a
+	// reverse proxy that forwards the prefix.
+	BasePath string `json:"basePath,omitempty"`
+	// Listen adds auth-required HTTP listeners (host:port) next to the
+	// mode's own, e.g. 127.0.0.1:8080 beside tsnet or a LAN address.
+	Listen []string `json:"listen,omitempty"`
+	// TLSCert / TLSKey serve the Listen listeners over HTTPS with a
+	// PEM certificate and key; a renewed pair is picked up in place.
+	TLSCert string `json:"tlsCert,omitempty"`
+	TLSKey  string `json:"tlsKey,omitempty"`
+	// ACMEDomains instead obtains certificates from Let's Encrypt.
+	// Challenges use TLS-ALPN-01 on the listener's own port (so it must
+	// be reachable on 443) or HTTP-01 when ACMEHTTPAddr is set.
+	ACMEDomains  []string `json:"acmeDomains,omitempty"`
+	ACMEEmail    string   `json:"acmeEmail,omitempty"`
+	ACMEHTTPAddr string   `json:"acmeHttpAddr,omitempty"`
+	// DebugEndpoints serves /debug/pprof/ and /api/v1/debug/goroutines
+	// to the Owner.
+	DebugEndpoints bool `json:"debugEndpoints,omitempty"`
+	// ReadOnly lets the UI view sessions, agents and files but not
+	// start, type into or change anything.
+	ReadOnly bool `json:"readOnly,omitempty"`
+	// AccessLog is a file that gets one JSON line per HTTP request.
+	// Without it requests are only logged at debug level.
+	AccessLog string `json:"accessLog,omitempty"`
+	// LogLevel is debug|info|warn|error. Empty means info, or debug
+	// in dev mode.
+	LogLevel string `json:"logLevel,omitempty"`
+	// LogLevels overrides LogLevel for single components (see
+	// logging.Components), e.g. {"tsnet": "warn", "session": "debug"}.
+	LogLevels map[string]string `json:"logLevels,omitempty"`
+	// LogFormat is text (the default) or json.
+	LogFormat string `json:"logFormat,omitempty"`
+	// LogFile sends the log to a file instead of stderr, rotated once
+	// it reaches LogFileMaxMB with LogFileBackups old files kept.
+	LogFile        string `json:"logFile,omitempty"`
+	LogFileMaxMB   int    `json:"logFileMaxMB,omitempty"`
+	LogFileBackups int    `json:"logFileBackups,omitempty"`
+	// Socket is the control socket path. Empty means
+	// <configdir>/kojo.sock; "off" disables it.
+	Socket string `json:"socket,omitempty"`
+
+	// NoUpdateCheck turns off the periodic GitHub release check;
+	// `kojo update` and the update endpoints still work on demand.
+	NoUpdateCheck bool `json:"noUpdateCheck,omitempty"`
+
+	// FileRoots are extra directories the file browser may read.
+	FileRoots []string `json:"fileRoots,omitempty"`
+	// GitExecAllow / GitExecDeny feed git.Options; see there for the
+	// rule syntax. A nil GitExecDeny keeps the default deny rules, an
+	// empty list denies nothing.
+	GitExecAllow []string `json:"gitExecAllow,omitempty"`
+	GitExecDeny  []string `json:"gitExecDeny"`
+
+	// Per-client limits (a client is a principal at a remote address).
+	// The rates are requests per minute; 0 disables a limit.
+	SessionCreateRate   int `json:"sessionCreateRate"`
+	GitExecRate         int `json:"gitExecRate"`
+	UploadRate          int `json:"uploadRate"`
+	WebSocketsPerClient int `json:"webSocketsPerClient"`
+
+	// Limits on all WebSockets together: at most MaxWebSockets open,
+	// making room by closing the one idle the longest or, with
+	// WebSocketEvict "reject", refusing new ones; each closed
+	// WebSocketIdleMinutes after its last message or output and
+	// WebSocketLifetimeMinutes after it opened. 0 disables a limit.
+	MaxWebSockets            int    `json:"maxWebSockets"`
+	WebSocketEvict           string `json:"webSocketEvict,omitempty"`
+	WebSocketIdleMinutes     int    `json:"webSocketIdleMinutes"`
+	WebSocketLifetimeMinutes int    `json:"webSocketLifetimeMinutes"`
+
+	// Uploaded files are deleted UploadTTLHours after upload, and new
+	// uploads are refused once the kept ones total UploadQuotaMB; 0
+	// disables either.
+	UploadTTLHours int `json:"uploadTTLHours"`
+	UploadQuotaMB  int `json:"uploadQuotaMB"`
+
+	// SessionBackend is how user tool sessions run on Unix: "tmux",
+	// "pty" (a direct PTY: no crash resilience, sessions end with
+	// kojo) or "auto"/empty (tmux when installed, else pty).
+	SessionBackend string `json:"sessionBackend,omitempty"`
+
+	// RemoteHosts are machines sessions can be started on over SSH.
+	RemoteHosts []RemoteHost `json:"remoteHosts,omitempty"`
+
+	// Sandboxes are restriction profiles a session can be started in;
+	// YoloSandbox names the one yolo sessions get unless they pick
+	// another.
+	Sandboxes   []Sandbox `json:"sandboxes,omitempty"`
+	YoloSandbox string    `json:"yoloSandbox,omitempty"`
+
+	// Session output is scrubbed of credentials before it is stored
+	// or sent (see session.DefaultRedactPatterns). RedactPatterns are
+	// extra regexps to mask; NoRedact turns redaction off.
+	RedactPatterns []string `json:"redactPatterns,omitempty"`
+	NoRedact       bool     `json:"noRedact,omitempty"`
+
+	// ProtectedPaths are directories sessions can't start in, and
+	// NoYoloPaths ones they start in with yolo mode off. An entry
+	// ("~/Documents", "/") matches that directory; with "/**" at the
+	// end ("~/.ssh/**") it also matches everything below. nil keeps
+	// session.DefaultProtectedPaths / DefaultNoYoloPaths, an empty
+	// list protects nothing.
+	ProtectedPaths []string `json:"protectedPaths"`
+	NoYoloPaths    []string `json:"noYoloPaths"`
+}
+
+// RemoteHost is an SSH target sessions can run on; see
+// session.RemoteHost.
+type RemoteHost struct {
+	Name    string   `json:"name"`
+	Target  string   `json:"target"`
+	ToolDir string   `json:"toolDir,omitempty"`
+	SSHArgs []string `json:"sshArgs,omitempty"`
+}
+
+// Sandbox is a session restriction profile; see session.Sandbox.
+type Sandbox struct {
+	Name       string `json:"name"`
+	User       string `json:"user,omitempty"`
+	MemoryMB   int    `json:"memoryMB,omitempty"`
+	CPUPercent int    `json:"cpuPercent,omitempty"`
+	NoNetwork  bool   `json:"noNetwork,omitempty"`
+	MacProfile string `json:"macProfile,omitempty"`
+}
+
+// Defaults returns the built-in values every other layer overrides.
+func Defaults() Config {
+	return Config{
+		Port:       8080,
+		PortRange:  10,
+		Hostname:   "kojo",
+		FunnelPort: 443,
+
+		SessionCreateRate:   30,
+		GitExecRate:         60,
+		UploadRate:          60,
+		WebSocketsPerClient: 64,
+		MaxWebSockets:       256,
+		UploadTTLHours:      24,
+		UploadQuotaMB:       20 << 10,
+		LogFileMaxMB:        10,
+		LogFileBackups:      5,
+	}
+}
+
+// Path returns the default config file location inside configDir.
+func Path(configDir string) string {
+	return filepath.Join(configDir, FileName)
+}
+
+// Load returns Defaults overlaid with the config file at path. A
+// missing file is not an error.
+func Load(path string) (Config, error) {
+	cfg := Defaults()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("read config: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return cfg, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// ApplyEnv overlays the KOJO_* variables that are set (lookup is
+// os.LookupEnv outside tests). List variables are split like the
+// values they replace: KOJO_FILE_ROOTS like PATH, the git exec lists
+// on commas.
+func (c *Config) ApplyEnv(lookup func(string) (string, bool)) error {
+	for name, dst := range map[string]*int{
+		"KOJO_PORT":                       &c.Port,
+		"KOJO_PORT_RANGE":                 &c.PortRange,
+		"KOJO_FUNNEL_PORT":                &c.FunnelPort,
+		"KOJO_SESSION_CREATE_RATE":        &c.SessionCreateRate,
+		"KOJO_GIT_EXEC_RATE":              &c.GitExecRate,
+		"KOJO_UPLOAD_RATE":                &c.UploadRate,
+		"KOJO_WEBSOCKETS_PER_CLIENT":      &c.WebSocketsPerClient,
+		"KOJO_MAX_WEBSOCKETS":             &c.MaxWebSockets,
+		"KOJO_WEBSOCKET_IDLE_MINUTES":     &c.WebSocketIdleMinutes,
+		"KOJO_WEBSOCKET_LIFETIME_MINUTES": &c.WebSocketLifetimeMinutes,
+		"KOJO_UPLOAD_TTL_HOURS":           &c.UploadTTLHours,
+		"KOJO_UPLOAD_QUOTA_MB":            &c.UploadQuotaMB,
+		"KOJO_LOG_FILE_MAX_MB":            &c.LogFileMaxMB,
+		"KOJO_LOG_FILE_BACKUPS":           &c.LogFileBackups,
+	} {
+		if v, ok := lookup(name); ok && v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("%s=%q: not a number", name, v)
+			}
+			*dst = n
+		}
+	}
+	if v, ok := lookup("KOJO_HOSTNAME"); ok && v != "" {
+		c.Hostname = v
+	}
+	if v, ok := lookup("KOJO_STATE_DIR"); ok && v != "" {
+		c.StateDir = v
+	}
+	if v, ok := lookup("KOJO_TS_AUTHKEY"); ok && v != "" {
+		c.TSAuthKey = v
+	}
+	if v, ok := lookup("KOJO_BIND"); ok && v != "" {
+		c.Bind = v
+	}
+	for name, dst := range map[string]*bool{
+		"KOJO_DEV":             &c.Dev,
+		"KOJO_SPAWN_VITE":      &c.SpawnVite,
+		"KOJO_LOCAL":           &c.Local,
+		"KOJO_FUNNEL":          &c.Funnel,
+		"KOJO_NO_UPDATE_CHECK": &c.NoUpdateCheck,
+		"KOJO_DEBUG_ENDPOINTS": &c.DebugEndpoints,
+		"KOJO_NO_REDACT":       &c.NoRedact,
+		"KOJO_READ_ONLY":       &c.ReadOnly,
+		"KOJO_STRICT_PORT":     &c.StrictPort,
+	} {
+		if v, ok := lookup(name); ok && v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("%s=%q: not a boolean", name, v)
+			}
+			*dst = b
+		}
+	}
+	if v, ok := lookup("KOJO_LOG_LEVEL"); ok && v != "" {
+		c.LogLevel = v
+	}
+	if v, ok := lookup("KOJO_LOG_LEVELS"); ok && v != "" {
+		c.LogLevels = map[string]string{}
+		for _, kv := range splitComma(v) {
+			name, lvl, ok := strings.Cut(kv, "=")
+			if !ok {
+				return fmt.Errorf("KOJO_LOG_LEVELS=%q: want component=level pairs", v)
+			}
+			c.LogLevels[strings.TrimSpace(name)] = strings.TrimSpace(lvl)
+		}
+	}
+	if v, ok := lookup("KOJO_LOG_FORMAT"); ok && v != "" {
+		c.LogFormat = v
+	}
+	if v, ok := lookup("KOJO_LOG_FILE"); ok && v != "" {
+		c.LogFile = v
+	}
+	if v, ok := lookup("KOJO_SOCKET"); ok && v != "" {
+		c.Socket = v
+	}
+	if v, ok := lookup("KOJO_BASE_PATH"); ok && v != "" {
+		c.BasePath = v
+	}
+	if v, ok := lookup("KOJO_LISTEN"); ok {
+		c.Listen = splitComma(v)
+	}
+	for name, dst := range map[string]*string{
+		"KOJO_TLS_CERT":        &c.TLSCert,
+		"KOJO_TLS_KEY":         &c.TLSKey,
+		"KOJO_ACME_EMAIL":      &c.ACMEEmail,
+		"KOJO_ACME_HTTP_ADDR":  &c.ACMEHTTPAddr,
+		"KOJO_ACCESS_LOG":      &c.AccessLog,
+		"KOJO_SESSION_BACKEND": &c.SessionBackend,
+		"KOJO_WEBSOCKET_EVICT": &c.WebSocketEvict,
+		"KOJO_VITE_URL":        &c.ViteURL,
+	} {
+		if v, ok := lookup(name); ok && v != "" {
+			*dst = v
+		}
+	}
+	if v, ok := lookup("KOJO_ACME_DOMAINS"); ok {
+		c.ACMEDomains = splitComma(v)
+	}
+	if v, ok := lookup("KOJO_FILE_ROOTS"); ok {
+		c.FileRoots = filepath.SplitList(v)
+	}
+	if v, ok := lookup("KOJO_GIT_EXEC_ALLOW"); ok {
+		c.GitExecAllow = splitComma(v)
+	}
+	if v, ok := lookup("KOJO_GIT_EXEC_DENY"); ok {
+		c.GitExecDeny = splitComma(v)
+	}
+	return c.Validate()
+}
+
+// PortAttempts is how many ports from Port the listeners may try.
+func (c *Config) PortAttempts() int {
+	if c.StrictPort {
+		return 1
+	}
+	return c.PortRange
+}
+
+// Validate reports values no layer may set. The log level is checked
+// separately by ParseLogLevel so an invalid one can degrade to the
+// default with a warning instead of refusing to boot.
+func (c *Config) Validate() error {
+	if c.Port < 1 || c.Port > 65535 {
+		return fmt.Errorf("port %d out of range", c.Port)
+	}
+	if c.PortRange < 1 || c.Port+c.PortRange-1 > 65535 {
+		return fmt.Errorf("port range %d out of range for port %d", c.PortRange, c.Port)
+	}
+	if c.Bind != "" && c.Bind != "localhost" && net.ParseIP(c.Bind) == nil {
+		return fmt.Errorf("bind address %q is not an IP address", c.Bind)
+	}
+	if strings.TrimSpace(c.Hostname) == "" {
+		return errors.New("hostname must not be empty")
+	}
+	for name, n := range map[string]int{
+		"sessionCreateRate":        c.SessionCreateRate,
+		"gitExecRate":              c.GitExecRate,
+		"uploadRate":               c.UploadRate,
+		"webSocketsPerClient":      c.WebSocketsPerClient,
+		"maxWebSockets":            c.MaxWebSockets,
+		"webSocketIdleMinutes":     c.WebSocketIdleMinutes,
+		"webSocketLifetimeMinutes": c.WebSocketLifetimeMinutes,
+		"uploadTTLHours":           c.UploadTTLHours,
+		"uploadQuotaMB":            c.UploadQuotaMB,
+		"logFileBackups":           c.LogFileBackups,
+	} {
+		if n < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+	for name, lvl := range c.LogLevels {
+		if !slices.Contains(logging.Components, name) {
+			return fmt.Errorf("logLevels: unknown component %q (want one of %v)", name, logging.Components)
+		}
+		if _, err := ParseLogLevel(lvl); err != nil {
+			return fmt.Errorf("logLevels.%s: %w", name, err)
+		}
+	}
+	switch c.LogFormat {
+	case "", "text", "json":
+	default:
+		return fmt.Errorf("logFormat %q: want text or json", c.LogFormat)
+	}
+	if c.LogFileMaxMB < 1 {
+		return errors.New("logFileMaxMB must be at least 1")
+	}
+	if c.ViteURL != "" {
+		u, err := url.Parse(c.ViteURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("viteURL %q: want an http(s)://host:port URL", c.ViteURL)
+		}
+		if c.SpawnVite && u.Port() == "" {
+			return fmt.Errorf("viteURL %q: spawnVite needs an explicit port", c.ViteURL)
+		}
+	}
+	switch c.WebSocketEvict {
+	case "", "idle", "reject":
+	default:
+		return fmt.Errorf("webSocketEvict %q: want idle or reject", c.WebSocketEvict)
+	}
+	switch c.SessionBackend {
+	case "", "auto", "tmux", "pty":
+	default:
+		return fmt.Errorf("sessionBackend %q: want auto, tmux or pty", c.SessionBackend)
+	}
+	seen := make(map[string]bool)
+	for _, h := range c.RemoteHosts {
+		switch {
+		case strings.TrimSpace(h.Name) == "":
+			return errors.New("remoteHosts: name must not be empty")
+		case seen[h.Name]:
+			return fmt.Errorf("remoteHosts: duplicate name %q", h.Name)
+		case h.Target == "" || strings.HasPrefix(h.Target, "-"):
+			return fmt.Errorf("remoteHosts %q: target %q: want [user@]host", h.Name, h.Target)
+		}
+		seen[h.Name] = true
+	}
+	sandboxes := make(map[string]bool)
+	for _, sb := range c.Sandboxes {
+		switch {
+		case strings.TrimSpace(sb.Name) == "":
+			return errors.New("sandboxes: name must not be empty")
+		case sandboxes[sb.Name]:
+			return fmt.Errorf("sandboxes: duplicate name %q", sb.Name)
+		case sb.MemoryMB < 0 || sb.CPUPercent < 0:
+			return fmt.Errorf("sandboxes %q: limits must not be negative", sb.Name)
+		case strings.HasPrefix(sb.User, "-"):
+			return fmt.Errorf("sandboxes %q: invalid user %q", sb.Name, sb.User)
+		}
+		sandboxes[sb.Name] = true
+	}
+	for _, p := range slices.Concat(c.ProtectedPaths, c.NoYoloPaths) {
+		if dir := strings.TrimSuffix(p, "/**"); !strings.HasPrefix(dir, "~") && !strings.HasPrefix(dir, "/") && !filepath.IsAbs(dir) && dir != "" {
+			return fmt.Errorf("protected path %q: want an absolute path or one starting with ~", p)
+		}
+	}
+	for _, p := range c.RedactPatterns {
+		if _, err := regexp.Compile(p); err != nil {
+			return fmt.Errorf("redactPatterns %q: %v", p, err)
+		}
+	}
+	if c.YoloSandbox != "" && !sandboxes[c.YoloSandbox] {
+		return fmt.Errorf("yoloSandbox %q is not in sandboxes", c.YoloSandbox)
+	}
+	for _, addr := range c.Listen {
+		if _, port, err := net.SplitHostPort(addr); err != nil || port == "" {
+			return fmt.Errorf("listen address %q: want host:port", addr)
+		}
+	}
+	switch {
+	case c.TLSCert != "" && len(c.ACMEDomains) > 0:
+		return errors.New("tlsCert and acmeDomains are mutually exclusive")
+	case (c.TLSCert == "") != (c.TLSKey == ""):
+		return errors.New("tlsCert and tlsKey must be set together")
+	case (c.TLSCert != "" || len(c.ACMEDomains) > 0) && len(c.Listen) == 0:
+		return errors.New("TLS applies to listen addresses; set listen too")
+	case c.ACMEHTTPAddr != "" && len(c.ACMEDomains) == 0:
+		return errors.New("acmeHttpAddr requires acmeDomains")
+	}
+	if c.Funnel {
+		if !slices.Contains(FunnelPorts, c.FunnelPort) {
+			return fmt.Errorf("funnel port %d not supported (Tailscale Funnel allows %v)", c.FunnelPort, FunnelPorts)
+		}
+		if c.FunnelPort == c.Port {
+			return fmt.Errorf("funnel port %d collides with the tailnet listener port", c.FunnelPort)
+		}
+	}
+	return nil
+}
+
+// ParseLogLevel maps a LogLevel string onto slog. The empty string is
+// info.
+func ParseLogLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info", "":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("invalid log level %q (valid: debug|info|warn|warning|error)", s)
+}
+
+// splitComma splits a comma-separated list, dropping blanks. The
+// result is non-nil so an empty-but-set variable reads as "no rules".
+func splitComma(v string) []string {
+	out := []string{}
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
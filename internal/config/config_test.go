@@ -0,0 +1,125 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadMissingFileUsesDefaults(t *testing.T) {
+	cfg, err := Load(filepath.Join(t.TempDir(), FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg, Defaults()) {
+		t.Errorf("Load = %+v, want defaults", cfg)
+	}
+}
+
+func TestLoadOverlaysFile(t *testing.T) {
+	cfg, err := Load(writeConfig(t, `{"port": 9090, "local": true, "gitExecDeny": []}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 9090 || !cfg.Local || cfg.Hostname != "kojo" {
+		t.Errorf("Load = %+v", cfg)
+	}
+	if cfg.GitExecDeny == nil || len(cfg.GitExecDeny) != 0 {
+		t.Errorf("GitExecDeny = %#v, want empty non-nil", cfg.GitExecDeny)
+	}
+}
+
+func TestLoadRejectsUnknownKeysAndBadValues(t *testing.T) {
+	for _, body := range []string{
+		`{"prot": 9090}`,
+		`{"port": 0}`,
+		`{"portRange": 0}`,
+		`{"logFormat": "xml"}`,
+		`{"logLevels": {"tmux": "loud"}}`,
+		`{"logLevels": {"agent": "debug"}}`,
+		`{"logFileMaxMB": -1}`,
+		`{"port": 65530, "portRange": 10}`,
+		`{"bind": "0.0.0.0:8080"}`,
+		`{"hostname": " "}`,
+		`{"funnel": true, "funnelPort": 8080}`,
+		`{"funnel": true, "port": 443}`,
+		`{"listen": ["8080"]}`,
+		`{"gitExecRate": -1}`,
+		`{"sessionBackend": "screen"}`,
+		`{"viteURL": "localhost:5173"}`,
+		`{"remoteHosts": [{"name": "vm"}]}`,
+		`{"sandboxes": [{"name": "a", "memoryMB": -1}]}`,
+		`{"redactPatterns": ["("]}`,
+		`{"protectedPaths": ["src/app"]}`,
+		`{"sandboxes": [{"name": "a"}], "yoloSandbox": "b"}`,
+		`{"remoteHosts": [{"name": "vm", "target": "-oProxyCommand=x"}]}`,
+		`{"remoteHosts": [{"name": "vm", "target": "a"}, {"name": "vm", "target": "b"}]}`,
+		`{"listen": [":443"], "tlsCert": "c.pem"}`,
+		`{"tlsCert": "c.pem", "tlsKey": "k.pem"}`,
+		`{"listen": [":443"], "tlsCert": "c.pem", "tlsKey": "k.pem", "acmeDomains": ["a.example"]}`,
+		`{`,
+	} {
+		if _, err := Load(writeConfig(t, body)); err == nil {
+			t.Errorf("Load(%s) succeeded, want error", body)
+		}
+	}
+}
+
+func TestApplyEnvOverridesFile(t *testing.T) {
+	cfg, err := Load(writeConfig(t, `{"port": 9090, "hostname": "file", "fileRoots": ["/a"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{
+		"KOJO_PORT":           "7070",
+		"KOJO_DEV":            "1",
+		"KOJO_STATE_DIR":      "/var/lib/kojo-work",
+		"KOJO_FILE_ROOTS":     strings.Join([]string{"/x", "/y"}, string(os.PathListSeparator)),
+		"KOJO_GIT_EXEC_ALLOW": "status, log,,",
+		"KOJO_UPLOAD_RATE":    "0",
+		"KOJO_VITE_URL":       "http://127.0.0.1:5174",
+	}
+	lookup := func(k string) (string, bool) { v, ok := env[k]; return v, ok }
+	if err := cfg.ApplyEnv(lookup); err != nil {
+		t.Fatal(err)
+	}
+	want := Config{
+		Port:         7070,
+		PortRange:    10,
+		Hostname:     "file",
+		FunnelPort:   443,
+		Dev:          true,
+		ViteURL:      "http://127.0.0.1:5174",
+		StateDir:     "/var/lib/kojo-work",
+		FileRoots:    []string{"/x", "/y"},
+		GitExecAllow: []string{"status", "log"},
+
+		SessionCreateRate:   30,
+		GitExecRate:         60,
+		WebSocketsPerClient: 64,
+		MaxWebSockets:       256,
+		UploadTTLHours:      24,
+		UploadQuotaMB:       20 << 10,
+		LogFileMaxMB:        10,
+		LogFileBackups:      5,
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("ApplyEnv = %+v, want %+v", cfg, want)
+	}
+
+	env = map[string]string{"KOJO_PORT": "eighty"}
+	if err := cfg.ApplyEnv(lookup); err == nil {
+		t.Error("ApplyEnv accepted a non-numeric KOJO_PORT")
+	}
+}
@@ -2,8 +2,11 @@ package configdir
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 )
 
 const lockFileName = "kojo.lock"
@@ -23,21 +26,62 @@ func (l *Lock) Release() error {
 	return l.f.Close()
 }
 
+// LockedError is returned by Acquire and AcquireFile when another
+// process holds the lock.
+type LockedError struct {
+	Path string
+	// PID is the holder's process ID as it recorded it in the lock
+	// file; 0 when unknown (the file can't be read while locked on
+	// Windows).
+	PID int
+	Err error
+}
+
+func (e *LockedError) Error() string {
+	if e.PID > 0 {
+		return fmt.Sprintf("%s is held by another kojo instance (pid %d): %v", e.Path, e.PID, e.Err)
+	}
+	return fmt.Sprintf("%s is held by another kojo instance: %v", e.Path, e.Err)
+}
+
+func (e *LockedError) Unwrap() error { return e.Err }
+
 // Acquire takes an exclusive, non-blocking advisory lock on <dir>/kojo.lock.
-// Returns an error if another process currently holds the lock. The caller
-// must call Release on shutdown.
+// Returns a *LockedError if another process currently holds the lock. The
+// caller must call Release on shutdown.
 func Acquire(dir string) (*Lock, error) {
 	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return nil, fmt.Errorf("create config dir: %w", err)
 	}
-	path := filepath.Join(dir, lockFileName)
+	return AcquireFile(filepath.Join(dir, lockFileName))
+}
+
+// AcquireFile takes an exclusive, non-blocking advisory lock on the
+// file at path, creating it, and records this process's ID in it.
+// Returns a *LockedError if another process currently holds the lock.
+func AcquireFile(path string) (*Lock, error) {
 	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
 	if err != nil {
 		return nil, fmt.Errorf("open lock file: %w", err)
 	}
 	if err := lockFile(f); err != nil {
+		pid := readPID(f)
 		f.Close()
-		return nil, fmt.Errorf("another kojo instance is using %s: %w", dir, err)
+		return nil, &LockedError{Path: path, PID: pid, Err: err}
+	}
+	// Best effort: the PID only makes the error above clearer.
+	if err := f.Truncate(0); err == nil {
+		_, _ = f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
 	}
 	return &Lock{f: f}, nil
 }
+
+// readPID returns the process ID recorded in a lock file, 0 if none.
+func readPID(f *os.File) int {
+	b, err := io.ReadAll(io.NewSectionReader(f, 0, 32))
+	if err != nil {
+		return 0
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(b)))
+	return pid
+}
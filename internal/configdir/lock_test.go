@@ -1,6 +1,9 @@
 package configdir
 
 import (
+	"errors"
+	"os"
+	"runtime"
 	"testing"
 )
 
@@ -33,3 +36,21 @@ func TestReleaseNilSafe(t *testing.T) {
 		t.Fatalf("Release on nil Lock: %v", err)
 	}
 }
+
+func TestAcquireRecordsPID(t *testing.T) {
+	dir := t.TempDir()
+	first, err := Acquire(dir)
+	if err != nil {
+		t.Fatalf("first Acquire: %v", err)
+	}
+	defer first.Release()
+
+	_, err = Acquire(dir)
+	var locked *LockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("second Acquire = %v, want a *LockedError", err)
+	}
+	if runtime.GOOS != "windows" && locked.PID != os.Getpid() {
+		t.Errorf("PID = %d, want %d", locked.PID, os.Getpid())
+	}
+}
@@ -0,0 +1,200 @@
+// Package dirdefaults keeps the settings new sessions get by default
+// in a directory — tool, args, yolo mode, sandbox, limits — on the
+// server, so a repository behaves the same whichever device starts a
+// session in it. They are persisted as dir-defaults.json in the config
+// directory.
+package dirdefaults
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loppo-llc/kojo/internal/atomicfile"
+	"github.com/loppo-llc/kojo/internal/configdir"
+)
+
+const (
+	fileName = "dir-defaults.json"
+	// Limits on what the API accepts.
+	maxEntries = 200
+	maxDirLen  = 4096
+	maxArgs    = 64
+	maxArgLen  = 4096
+)
+
+var (
+	ErrNotFound = errors.New("no defaults for directory")
+	ErrInvalid  = errors.New("invalid directory defaults")
+)
+
+// Defaults are the session settings for Dir and the directories below
+// it. A zero field sets nothing and leaves the request's value, or the
+// usual default, in place.
+type Defaults struct {
+	Dir      string   `json:"dir"`
+	Tool     string   `json:"tool,omitempty"`
+	Args     []string `json:"args,omitempty"`
+	YoloMode *bool    `json:"yoloMode,omitempty"`
+	Sandbox  string   `json:"sandbox,omitempty"`
+	// TimeLimitMinutes, TokenBudget and CheckpointMinutes are as on
+	// POST /api/v1/sessions.
+	TimeLimitMinutes  int       `json:"timeLimitMinutes,omitempty"`
+	TokenBudget       int64     `json:"tokenBudget,omitempty"`
+	CheckpointMinutes int       `json:"checkpointMinutes,omitempty"`
+	UpdatedAt         time.Time `json:"updatedAt,omitzero"`
+}
+
+// Store holds the defaults, sorted by directory, and writes every
+// change through to disk.
+type Store struct {
+	mu        sync.Mutex
+	path      string
+	entries   []Defaults
+	validTool func(string) bool
+	logger    *slog.Logger
+}
+
+// NewStore loads the defaults saved in the config directory. validTool
+// reports whether a tool name is one sessions can be started with. A
+// missing or unreadable file means no defaults; the latter is logged
+// and only replaced by the next change.
+func NewStore(logger *slog.Logger, validTool func(string) bool) *Store {
+	return newStore(logger, filepath.Join(configdir.Path(), fileName), validTool)
+}
+
+func newStore(logger *slog.Logger, path string, validTool func(string) bool) *Store {
+	st := &Store{path: path, validTool: validTool, logger: logger}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warn("failed to read directory defaults", "err", err)
+		}
+		return st
+	}
+	if err := json.Unmarshal(data, &st.entries); err != nil {
+		logger.Warn("invalid directory defaults file, ignoring", "path", path, "err", err)
+		st.entries = nil
+	}
+	return st
+}
+
+// List returns every directory's defaults, sorted by directory.
+func (st *Store) List() []Defaults {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return slices.Clone(st.entries)
+}
+
+// Set saves d, replacing any defaults already set for d.Dir, and
+// returns it as saved.
+func (st *Store) Set(d Defaults) (Defaults, error) {
+	d.Dir = cleanDir(d.Dir)
+	if err := st.validate(d); err != nil {
+		return Defaults{}, err
+	}
+	d.Args = slices.Clone(d.Args)
+	d.UpdatedAt = time.Now().UTC()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if i := st.index(d.Dir); i >= 0 {
+		st.entries[i] = d
+	} else {
+		if len(st.entries) >= maxEntries {
+			return Defaults{}, fmt.Errorf("%w: at most %d directories", ErrInvalid, maxEntries)
+		}
+		st.entries = append(st.entries, d)
+	}
+	return d, st.saveLocked()
+}
+
+// Delete removes the defaults set for dir.
+func (st *Store) Delete(dir string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	i := st.index(cleanDir(dir))
+	if i < 0 {
+		return ErrNotFound
+	}
+	st.entries = slices.Delete(st.entries, i, i+1)
+	return st.saveLocked()
+}
+
+// Lookup returns the defaults for a session in workDir: those of the
+// closest directory at or above it. ok is false when none apply.
+func (st *Store) Lookup(workDir string) (d Defaults, ok bool) {
+	workDir = cleanDir(workDir)
+	if !filepath.IsAbs(workDir) {
+		return Defaults{}, false
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for _, e := range st.entries {
+		if within(workDir, e.Dir) && len(e.Dir) > len(d.Dir) {
+			d, ok = e, true
+		}
+	}
+	d.Args = slices.Clone(d.Args)
+	return d, ok
+}
+
+// within reports whether path is dir or below it.
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func (st *Store) index(dir string) int {
+	return slices.IndexFunc(st.entries, func(e Defaults) bool { return e.Dir == dir })
+}
+
+// saveLocked sorts the entries and writes them out. Caller holds mu.
+func (st *Store) saveLocked() error {
+	slices.SortFunc(st.entries, func(a, b Defaults) int { return strings.Compare(a.Dir, b.Dir) })
+	if err := os.MkdirAll(filepath.Dir(st.path), 0o755); err != nil {
+		return err
+	}
+	return atomicfile.WriteJSON(st.path, st.entries, 0o600)
+}
+
+func (st *Store) validate(d Defaults) error {
+	switch {
+	case d.Dir == "" || !filepath.IsAbs(d.Dir):
+		return fmt.Errorf("%w: dir must be an absolute path", ErrInvalid)
+	case len(d.Dir) > maxDirLen:
+		return fmt.Errorf("%w: dir longer than %d bytes", ErrInvalid, maxDirLen)
+	case d.Tool != "" && st.validTool != nil && !st.validTool(d.Tool):
+		return fmt.Errorf("%w: unknown tool %q", ErrInvalid, d.Tool)
+	case len(d.Args) > maxArgs:
+		return fmt.Errorf("%w: more than %d args", ErrInvalid, maxArgs)
+	case d.TimeLimitMinutes < 0 || d.TokenBudget < 0 || d.CheckpointMinutes < 0:
+		return fmt.Errorf("%w: limits must not be negative", ErrInvalid)
+	}
+	for _, a := range d.Args {
+		if len(a) > maxArgLen {
+			return fmt.Errorf("%w: arg longer than %d bytes", ErrInvalid, maxArgLen)
+		}
+	}
+	return nil
+}
+
+// cleanDir expands a leading "~" to the home directory and cleans the
+// path; relative paths are left for validate to refuse.
+func cleanDir(dir string) string {
+	if rest, ok := strings.CutPrefix(dir, "~"); ok && (rest == "" || rest[0] == '/' || rest[0] == filepath.Separator) {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = home + rest
+		}
+	}
+	if dir == "" {
+		return ""
+	}
+	return filepath.Clean(dir)
+}
@@ -0,0 +1,85 @@
+package dirdefaults
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, fileName)
+	st := newStore(slog.Default(), path, func(tool string) bool { return tool == "claude" || tool == "codex" })
+
+	src := filepath.Join(dir, "src")
+	app := filepath.Join(src, "app")
+	if _, err := st.Set(Defaults{Dir: src, Tool: "codex"}); err != nil {
+		t.Fatal(err)
+	}
+	d, err := st.Set(Defaults{Dir: app + "/", Tool: "claude", Args: []string{"--model", "opus"}, YoloMode: ptr(false)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Dir != app || d.UpdatedAt.IsZero() {
+		t.Errorf("saved = %+v", d)
+	}
+
+	// The closest directory at or above the session's wins.
+	for workDir, want := range map[string]string{
+		app:                             "claude",
+		filepath.Join(app, "web"):       "claude",
+		filepath.Join(src, "other"):     "codex",
+		filepath.Join(src + "-old"):     "",
+		filepath.Join(dir, "elsewhere"): "",
+		"relative/app":                  "",
+	} {
+		got, ok := st.Lookup(workDir)
+		if got.Tool != want || ok != (want != "") {
+			t.Errorf("Lookup(%q) = %q, %v; want %q", workDir, got.Tool, ok, want)
+		}
+	}
+	if got, _ := st.Lookup(app); !slices.Equal(got.Args, []string{"--model", "opus"}) || got.YoloMode == nil || *got.YoloMode {
+		t.Errorf("app defaults = %+v", got)
+	}
+
+	for name, d := range map[string]Defaults{
+		"relative": {Dir: "src/app"},
+		"tool":     {Dir: app, Tool: "vim"},
+		"limit":    {Dir: app, TimeLimitMinutes: -1},
+	} {
+		if _, err := st.Set(d); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+
+	if err := st.Delete(src); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Delete(src); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second delete: %v", err)
+	}
+
+	// A reload sees what was saved.
+	re := newStore(slog.Default(), path, nil)
+	if got := re.List(); len(got) != 1 || got[0].Dir != app || got[0].Tool != "claude" {
+		t.Errorf("reloaded = %+v", got)
+	}
+}
+
+func TestCleanDir(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip(err)
+	}
+	if got := cleanDir("~/src/app/"); got != filepath.Join(home, "src/app") {
+		t.Errorf("cleanDir = %q", got)
+	}
+	if got := cleanDir("~bob/src"); got != "~bob/src" {
+		t.Errorf("cleanDir(~bob) = %q", got)
+	}
+}
@@ -0,0 +1,389 @@
+package filebrowser
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/loppo-llc/kojo/internal/thumbnail"
+)
+
+const (
+	// maxArchiveEntries caps how many members an archive listing reads,
+	// so a pathological archive can't pin the server building an index.
+	maxArchiveEntries = 100_000
+	// maxArchiveRawSize caps a member served through ServeRaw. Members
+	// are decompressed into memory (tar.gz can't seek), so this bounds
+	// per-request RAM rather than mirroring maxFileSize.
+	maxArchiveRawSize = 32 * 1024 * 1024
+)
+
+// errArchiveEntryNotFound is returned when the path inside an archive
+// names no member.
+var errArchiveEntryNotFound = fmt.Errorf("file %w in archive", ErrNotFound)
+
+// archiveKind reports how name is read as an archive: "zip" (.zip, .jar),
+// "tgz" (.tar.gz, .tgz), "tar", or "" when it is not a browsable archive.
+func archiveKind(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"), strings.HasSuffix(lower, ".jar"):
+		return "zip"
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "tgz"
+	case strings.HasSuffix(lower, ".tar"):
+		return "tar"
+	}
+	return ""
+}
+
+// archivePath splits a virtual path like /home/u/build.zip/lib/a.txt into
+// the archive on disk and the slash-separated member path inside it
+// ("lib/a.txt"). A path naming the archive file itself yields inner "".
+// archive is "" when path does not go through an archive; the archive
+// file is validated against the allowed roots before it is returned.
+// Archives nested inside archives are not descended into.
+func (b *Browser) archivePath(p string) (archive, inner string, err error) {
+	p, err = expandHome(p)
+	if err != nil {
+		return "", "", err
+	}
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %w", ErrInvalidPath, err)
+	}
+	// Walk up to the deepest component that exists on disk (stat of
+	// x.zip/lib fails with ENOTDIR). Only when that is a regular archive
+	// file is the remainder a member path.
+	cur := abs
+	for {
+		info, statErr := os.Stat(cur)
+		if statErr == nil {
+			if !info.Mode().IsRegular() || archiveKind(cur) == "" {
+				return "", "", nil
+			}
+			break
+		}
+		parent := filepath.Dir(cur)
+		if parent == cur {
+			return "", "", nil
+		}
+		cur = parent
+	}
+	if err := b.validatePath(cur); err != nil {
+		return "", "", err
+	}
+	rel, err := filepath.Rel(cur, abs)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %w", ErrInvalidPath, err)
+	}
+	if rel == "." {
+		rel = ""
+	}
+	return cur, filepath.ToSlash(rel), nil
+}
+
+// archiveEntry is one member of an archive, with a cleaned
+// slash-separated name relative to the archive root.
+type archiveEntry struct {
+	name    string
+	isDir   bool
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// cleanMemberName normalizes a member name to a slash path relative to
+// the archive root. Leading "/" and ".." segments are clamped to the root
+// ("../x" → "x", "/etc/passwd" → "etc/passwd"); "" means no usable name.
+func cleanMemberName(name string) string {
+	name = path.Clean("/" + strings.ReplaceAll(name, "\\", "/"))
+	name = strings.TrimPrefix(name, "/")
+	if name == "" || name == "." {
+		return ""
+	}
+	return name
+}
+
+// walkArchive calls fn for each member of the archive in order. open
+// returns the member's content and is only valid during the call.
+// Walking stops early when fn returns errStopWalk.
+func walkArchive(archive string, fn func(e archiveEntry, open func() (io.ReadCloser, error)) error) error {
+	count := 0
+	visit := func(e archiveEntry, open func() (io.ReadCloser, error)) error {
+		if e.name == "" {
+			return nil
+		}
+		if count++; count > maxArchiveEntries {
+			return fmt.Errorf("%w: more than %d archive entries", ErrFileTooLarge, maxArchiveEntries)
+		}
+		return fn(e, open)
+	}
+
+	var err error
+	switch archiveKind(archive) {
+	case "zip":
+		err = walkZip(archive, visit)
+	case "tgz", "tar":
+		err = walkTar(archive, visit)
+	default:
+		return fmt.Errorf("%w: not an archive", ErrUnsupportedFile)
+	}
+	if errors.Is(err, errStopWalk) {
+		return nil
+	}
+	return err
+}
+
+var errStopWalk = errors.New("stop walk")
+
+func walkZip(archive string, visit func(archiveEntry, func() (io.ReadCloser, error)) error) error {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return fmt.Errorf("cannot read archive: %w", err)
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		info := f.FileInfo()
+		e := archiveEntry{
+			name:    cleanMemberName(f.Name),
+			isDir:   info.IsDir(),
+			size:    int64(f.UncompressedSize64),
+			mode:    info.Mode(),
+			modTime: f.Modified,
+		}
+		if err := visit(e, f.Open); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkTar(archive string, visit func(archiveEntry, func() (io.ReadCloser, error)) error) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return fmt.Errorf("cannot read archive: %w", err)
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if archiveKind(archive) == "tgz" {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("cannot read archive: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("cannot read archive: %w", err)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeReg, tar.TypeDir, tar.TypeSymlink:
+		default:
+			continue // pax headers, hard links, devices
+		}
+		e := archiveEntry{
+			name:    cleanMemberName(hdr.Name),
+			isDir:   hdr.Typeflag == tar.TypeDir,
+			mode:    hdr.FileInfo().Mode(),
+			modTime: hdr.ModTime,
+		}
+		if !e.isDir {
+			e.size = hdr.Size
+		}
+		open := func() (io.ReadCloser, error) { return io.NopCloser(tr), nil }
+		if err := visit(e, open); err != nil {
+			return err
+		}
+	}
+}
+
+// listArchive lists the direct children of inner inside archive as a
+// virtual directory. Directories implied only by member paths (common
+// in zips without explicit dir entries) are synthesized.
+func (b *Browser) listArchive(archive, inner string, opts ListOptions) (*ListResult, error) {
+	prefix := ""
+	if inner != "" {
+		prefix = inner + "/"
+	}
+	children := make(map[string]DirEntry)
+	found := inner == ""
+	err := walkArchive(archive, func(e archiveEntry, _ func() (io.ReadCloser, error)) error {
+		if e.name == inner {
+			if !e.isDir {
+				return ErrNotDirectory
+			}
+			found = true
+			return nil
+		}
+		rest, ok := strings.CutPrefix(e.name, prefix)
+		if !ok {
+			return nil
+		}
+		found = true
+		name, sub, nested := strings.Cut(rest, "/")
+		if nested && sub != "" {
+			if _, seen := children[name]; !seen {
+				children[name] = DirEntry{Name: name, Type: "dir", Mode: (fs.ModeDir | 0o755).String()}
+			}
+			return nil
+		}
+		entry := DirEntry{
+			Name:    name,
+			Type:    "file",
+			Mode:    e.mode.String(),
+			modTime: e.modTime,
+		}
+		if e.isDir {
+			entry.Type = "dir"
+		} else {
+			entry.Size = e.size
+		}
+		children[name] = entry
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errArchiveEntryNotFound
+	}
+
+	all := make([]DirEntry, 0, len(children))
+	for _, e := range children {
+		if !opts.Hidden && strings.HasPrefix(e.Name, ".") {
+			continue
+		}
+		e.ModTime = e.modTime.Format(time.RFC3339)
+		all = append(all, e)
+	}
+	sortEntries(all, opts.Sort, opts.Desc)
+
+	virtual := archive
+	if inner != "" {
+		virtual = filepath.Join(archive, filepath.FromSlash(inner))
+	}
+	result := &ListResult{Path: virtual, Total: len(all)}
+	start := min(max(opts.Offset, 0), len(all))
+	end := len(all)
+	if opts.Limit > 0 && start+opts.Limit < end {
+		end = start + opts.Limit
+		result.HasMore = true
+	}
+	result.Entries = all[start:end]
+	return result, nil
+}
+
+// readArchiveMember returns the content of the member named inner,
+// refusing members larger than limit.
+func readArchiveMember(archive, inner string, limit int64) (archiveEntry, []byte, error) {
+	var (
+		found   archiveEntry
+		content []byte
+	)
+	err := walkArchive(archive, func(e archiveEntry, open func() (io.ReadCloser, error)) error {
+		if e.name != inner {
+			return nil
+		}
+		if e.isDir {
+			return ErrIsDirectory
+		}
+		if e.size > limit {
+			return fmt.Errorf("%w: %d bytes (max %d)", ErrFileTooLarge, e.size, limit)
+		}
+		rc, err := open()
+		if err != nil {
+			return fmt.Errorf("cannot read archive entry: %w", err)
+		}
+		defer rc.Close()
+		// The header size can lie; never read past limit regardless.
+		content, err = io.ReadAll(io.LimitReader(rc, limit+1))
+		if err != nil {
+			return fmt.Errorf("cannot read archive entry: %w", err)
+		}
+		if int64(len(content)) > limit {
+			return fmt.Errorf("%w: more than %d bytes", ErrFileTooLarge, limit)
+		}
+		found = e
+		return errStopWalk
+	})
+	if err != nil {
+		return archiveEntry{}, nil, err
+	}
+	if content == nil {
+		return archiveEntry{}, nil, errArchiveEntryNotFound
+	}
+	return found, content, nil
+}
+
+// viewArchive is View for a member inside an archive. Images and media
+// point at the raw endpoint (which also understands archive paths);
+// everything else is shown as text. Structured previews are not built
+// for archive members.
+func (b *Browser) viewArchive(archive, inner string) (*FileView, error) {
+	virtual := filepath.Join(archive, filepath.FromSlash(inner))
+	ext := strings.ToLower(path.Ext(inner))
+	if mime := rawMime(ext); mime != "" {
+		e, _, err := readArchiveMember(archive, inner, maxArchiveRawSize)
+		if err != nil {
+			return nil, err
+		}
+		return &FileView{
+			Path: virtual,
+			Type: viewType(mime),
+			Mime: mime,
+			Size: e.size,
+			URL:  "/api/v1/files/raw?path=" + url.QueryEscape(virtual),
+		}, nil
+	}
+
+	e, content, err := readArchiveMember(archive, inner, maxFileSize)
+	if err != nil {
+		return nil, err
+	}
+	if isBinary(content) {
+		return nil, fmt.Errorf("%w: binary", ErrUnsupportedFile)
+	}
+	return &FileView{
+		Path:     virtual,
+		Type:     "text",
+		Content:  string(content),
+		Language: langExts[ext],
+		Size:     e.size,
+	}, nil
+}
+
+// serveArchive is ServeRaw for a member inside an archive.
+func (b *Browser) serveArchive(w http.ResponseWriter, r *http.Request, archive, inner string) error {
+	e, content, err := readArchiveMember(archive, inner, maxArchiveRawSize)
+	switch {
+	case errors.Is(err, errArchiveEntryNotFound):
+		return thumbnail.NewHTTPError(http.StatusNotFound, err.Error(), err)
+	case errors.Is(err, ErrFileTooLarge):
+		return thumbnail.NewHTTPError(http.StatusRequestEntityTooLarge, err.Error(), err)
+	case err != nil:
+		return thumbnail.NewHTTPError(http.StatusBadRequest, err.Error(), err)
+	}
+	ext := strings.ToLower(path.Ext(inner))
+	setRawHeaders(w, ext)
+	http.ServeContent(w, r, path.Base(inner), e.modTime, bytes.NewReader(content))
+	return nil
+}
@@ -0,0 +1,166 @@
+package filebrowser
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var archiveFiles = map[string]string{
+	"README.md":      "# hi\n",
+	"lib/a.go":       "package lib\n",
+	"lib/deep/b.txt": "b\n",
+	"img/dot.png":    "\x89PNG fake",
+}
+
+func writeZip(t *testing.T, path string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for name, body := range archiveFiles {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTarGz(t *testing.T, path string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: "lib/", Typeflag: tar.TypeDir, Mode: 0o755}); err != nil {
+		t.Fatal(err)
+	}
+	for name, body := range archiveFiles {
+		if err := tw.WriteHeader(&tar.Header{Name: "./" + name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(body))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestArchiveBrowsing(t *testing.T) {
+	dir := t.TempDir()
+	writeZip(t, filepath.Join(dir, "out.zip"))
+	writeTarGz(t, filepath.Join(dir, "out.tar.gz"))
+	b := New(slog.Default(), Options{})
+
+	res, err := b.List(dir, ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range res.Entries {
+		if !e.IsArchive {
+			t.Errorf("%s not flagged as archive", e.Name)
+		}
+	}
+
+	for _, name := range []string{"out.zip", "out.tar.gz"} {
+		archive := filepath.Join(dir, name)
+		res, err := b.List(archive, ListOptions{})
+		if err != nil {
+			t.Fatalf("List(%s): %v", name, err)
+		}
+		if got, want := entryNames(res.Entries), []string{"README.md", "img", "lib"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("List(%s) = %v, want %v", name, got, want)
+		}
+
+		res, err = b.List(filepath.Join(archive, "lib"), ListOptions{})
+		if err != nil {
+			t.Fatalf("List(%s/lib): %v", name, err)
+		}
+		if got, want := entryNames(res.Entries), []string{"a.go", "deep"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("List(%s/lib) = %v, want %v", name, got, want)
+		}
+		if res.Entries[1].Type != "dir" || res.Entries[0].Size != int64(len("package lib\n")) {
+			t.Errorf("List(%s/lib) entries = %+v", name, res.Entries)
+		}
+
+		v, err := b.View(filepath.Join(archive, "lib", "a.go"))
+		if err != nil {
+			t.Fatalf("View(%s/lib/a.go): %v", name, err)
+		}
+		if v.Type != "text" || v.Content != "package lib\n" || v.Language != "go" {
+			t.Errorf("View(%s/lib/a.go) = %+v", name, v)
+		}
+
+		if _, err := b.View(filepath.Join(archive, "missing.txt")); err == nil {
+			t.Errorf("View(%s/missing.txt) succeeded", name)
+		}
+		if _, err := b.List(filepath.Join(archive, "README.md"), ListOptions{}); err == nil {
+			t.Errorf("List(%s/README.md) succeeded on a file member", name)
+		}
+	}
+}
+
+func TestArchiveServeRaw(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "out.zip")
+	writeZip(t, archive)
+	b := New(slog.Default(), Options{})
+
+	member := filepath.Join(archive, "img", "dot.png")
+	v, err := b.View(member)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Type != "image" || v.URL == "" {
+		t.Fatalf("View = %+v, want image with url", v)
+	}
+
+	rec := httptest.NewRecorder()
+	if err := b.ServeRaw(rec, httptest.NewRequest(http.MethodGet, "/api/v1/files/raw", nil), member); err != nil {
+		t.Fatalf("ServeRaw: %v", err)
+	}
+	if rec.Body.String() != archiveFiles["img/dot.png"] || rec.Header().Get("Content-Type") != "image/png" {
+		t.Errorf("ServeRaw body %q, type %q", rec.Body.String(), rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestArchiveScopeEnforced(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "out.zip")
+	writeZip(t, archive)
+	work := filepath.Join(dir, "work")
+	if err := os.Mkdir(work, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	scoped, err := New(slog.Default(), Options{}).Scoped(work)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := scoped.View(filepath.Join(archive, "README.md")); err == nil {
+		t.Error("archive outside scope was readable")
+	}
+}
@@ -1,6 +1,7 @@
 package filebrowser
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -9,16 +10,25 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/loppo-llc/kojo/internal/thumbnail"
 )
 
-// Sentinel errors for file browser operations.
+// Sentinel errors for file browser operations. Errors returned by the
+// Browser wrap one of these where it applies, so callers can tell them
+// apart with errors.Is; the server maps them to error codes.
 var (
 	ErrUnsupportedFile = errors.New("unsupported file type")
 	ErrFileTooLarge    = errors.New("file too large")
+	ErrAccessDenied    = errors.New("access denied")
+	ErrInvalidPath     = errors.New("invalid path")
+	ErrNotFound        = errors.New("not found")
+	ErrIsDirectory     = errors.New("path is a directory")
+	ErrNotDirectory    = errors.New("path is not a directory")
 )
 
 const maxFileSize = 1024 * 1024 // 1MB
@@ -29,6 +39,51 @@ var imageExts = map[string]string{
 	".jpeg": "image/jpeg",
 	".gif":  "image/gif",
 	".webp": "image/webp",
+	".svg":  "image/svg+xml",
+}
+
+// mediaExts maps non-image formats the client can render from the raw
+// endpoint (PDF viewer, <video>, <audio>) to their MIME type. View
+// returns a URL for these instead of reading the content, and ServeRaw
+// pins Content-Type from here so playback doesn't depend on the host's
+// MIME database.
+var mediaExts = map[string]string{
+	".pdf":  "application/pdf",
+	".mp4":  "video/mp4",
+	".m4v":  "video/x-m4v",
+	".webm": "video/webm",
+	".mov":  "video/quicktime",
+	".ogv":  "video/ogg",
+	".mp3":  "audio/mpeg",
+	".m4a":  "audio/mp4",
+	".aac":  "audio/aac",
+	".wav":  "audio/wav",
+	".ogg":  "audio/ogg",
+	".oga":  "audio/ogg",
+	".opus": "audio/ogg",
+	".flac": "audio/flac",
+}
+
+// viewType returns the FileView type for a media MIME type.
+func viewType(mime string) string {
+	switch {
+	case mime == "application/pdf":
+		return "pdf"
+	case strings.HasPrefix(mime, "video/"):
+		return "video"
+	case strings.HasPrefix(mime, "audio/"):
+		return "audio"
+	}
+	return "image"
+}
+
+// rawMime returns the Content-Type ServeRaw pins for ext, or "" to let
+// http.ServeFile sniff it.
+func rawMime(ext string) string {
+	if m, ok := imageExts[ext]; ok {
+		return m
+	}
+	return mediaExts[ext]
 }
 
 var langExts = map[string]string{
@@ -59,15 +114,94 @@ var langExts = map[string]string{
 	".swift": "swift",
 	".kt":    "kotlin",
 	".mod":   "go",
+	".ipynb": "json",
 	".sum":   "text",
 }
 
 type Browser struct {
 	logger *slog.Logger
+	// roots are extra allowed directories on top of home and temp.
+	// Swapped wholesale by SetRoots on a config reload.
+	roots atomic.Pointer[[]string]
+	// scope, when set, replaces every allowed root with this single
+	// (symlink-resolved) directory. See Scoped.
+	scope string
+	// watches is shared with scoped copies so every caller watching a
+	// directory reuses one fsnotify watch.
+	watches *watchHub
+}
+
+// Options configures a Browser.
+type Options struct {
+	// Roots lists extra directories the browser may read, in addition
+	// to the user's home and the temp directory — e.g. a mounted work
+	// disk like /Volumes/Projects. Entries may use a leading "~/".
+	// Missing or unresolvable entries are logged and skipped.
+	Roots []string
+}
+
+func New(logger *slog.Logger, opts Options) *Browser {
+	b := &Browser{logger: logger, watches: newWatchHub(logger)}
+	b.SetRoots(opts.Roots)
+	return b
+}
+
+// SetRoots replaces the extra root directories (same rules as
+// Options.Roots). Requests already past validation finish under the old
+// set; scoped browsers are unaffected.
+func (b *Browser) SetRoots(roots []string) {
+	var out []string
+	for _, r := range roots {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+		expanded, err := expandHome(r)
+		if err == nil {
+			expanded, err = filepath.Abs(expanded)
+		}
+		if err != nil {
+			b.logger.Warn("file browser: ignoring root", "root", r, "err", err)
+			continue
+		}
+		out = append(out, expanded)
+	}
+	b.roots.Store(&out)
 }
 
-func New(logger *slog.Logger) *Browser {
-	return &Browser{logger: logger}
+// configuredRoots returns the current SetRoots list (nil for a scoped
+// browser).
+func (b *Browser) configuredRoots() []string {
+	if p := b.roots.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// Roots returns the directories the browser may read, symlink-resolved.
+// For a scoped browser this is the scope alone.
+func (b *Browser) Roots() []string {
+	roots, _ := b.allowedRoots()
+	out := make([]string, len(roots))
+	for i, r := range roots {
+		out[i] = strings.TrimSuffix(r, string(filepath.Separator))
+	}
+	return out
+}
+
+// Scoped returns a browser restricted to the dir subtree, used to keep a
+// session's file browsing inside its workDir. dir itself must be
+// readable by b; the scoped browser shares b's logger and watches.
+func (b *Browser) Scoped(dir string) (*Browser, error) {
+	dir, err := b.resolveValidated(dir)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve path: %w", err)
+	}
+	return &Browser{logger: b.logger, scope: resolved, watches: b.watches}, nil
 }
 
 type DirEntry struct {
@@ -75,11 +209,44 @@ type DirEntry struct {
 	Type    string `json:"type"` // "dir" or "file"
 	Size    int64  `json:"size"` // 0 for directories
 	ModTime string `json:"modTime"`
+	Mode    string `json:"mode"` // ls-style permission string, e.g. "-rw-r--r--"
+	// SymlinkTarget is the raw link text when the entry is a symlink.
+	// Type then describes the link's target ("dir" for a link to a
+	// directory) so the UI can navigate through it.
+	SymlinkTarget string `json:"symlinkTarget,omitempty"`
+	// IsGitIgnored is set when the entry sits in a git work tree and is
+	// matched by its ignore rules. Only computed for the returned page.
+	IsGitIgnored bool `json:"isGitIgnored,omitempty"`
+	// IsArchive marks a .zip / .jar / .tar / .tar.gz file that List and
+	// View can descend into as a read-only virtual directory.
+	IsArchive bool `json:"isArchive,omitempty"`
+
+	modTime time.Time
 }
 
 type ListResult struct {
 	Path    string     `json:"path"`
 	Entries []DirEntry `json:"entries"`
+	// Total is the number of entries before pagination.
+	Total   int  `json:"total"`
+	HasMore bool `json:"hasMore"`
+}
+
+// Sort keys accepted by ListOptions.Sort.
+const (
+	SortName  = "name"
+	SortMtime = "mtime"
+	SortSize  = "size"
+)
+
+// ListOptions controls filtering, ordering and pagination for List.
+// The zero value lists every non-hidden entry sorted by name.
+type ListOptions struct {
+	Hidden bool
+	Sort   string // SortName (default), SortMtime or SortSize
+	Desc   bool
+	Offset int
+	Limit  int // 0 = no limit
 }
 
 // expandHome replaces a leading "~/" (or "~" alone) with the user's home
@@ -90,7 +257,7 @@ func expandHome(path string) (string, error) {
 		return path, nil
 	}
 	if path != "~" && path[1] != '/' && path[1] != filepath.Separator {
-		return "", fmt.Errorf("invalid path: ~user/ form is not supported")
+		return "", fmt.Errorf("%w: ~user/ form is not supported", ErrInvalidPath)
 	}
 	home, err := os.UserHomeDir()
 	if err != nil || home == "" {
@@ -102,10 +269,23 @@ func expandHome(path string) (string, error) {
 	return filepath.Join(home, path[2:]), nil
 }
 
-func (b *Browser) List(dir string, hidden bool) (*ListResult, error) {
+// List returns the entries of dir. A dir that is (or lies inside) a
+// zip/tar archive is listed read-only from the archive's members, e.g.
+// List("~/out/build.zip/lib").
+func (b *Browser) List(dir string, opts ListOptions) (*ListResult, error) {
 	if dir == "" {
-		home, _ := os.UserHomeDir()
-		dir = home
+		if b.scope != "" {
+			dir = b.scope
+		} else {
+			home, _ := os.UserHomeDir()
+			dir = home
+		}
+	}
+
+	if archive, inner, err := b.archivePath(dir); err != nil {
+		return nil, err
+	} else if archive != "" {
+		return b.listArchive(archive, inner, opts)
 	}
 
 	dir, err := b.resolveValidated(dir)
@@ -118,50 +298,105 @@ func (b *Browser) List(dir string, hidden bool) (*ListResult, error) {
 		return nil, fmt.Errorf("cannot read directory: %w", err)
 	}
 
-	result := &ListResult{
-		Path:    dir,
-		Entries: make([]DirEntry, 0, len(entries)),
-	}
-
+	all := make([]DirEntry, 0, len(entries))
 	for _, e := range entries {
-		if !hidden && strings.HasPrefix(e.Name(), ".") {
+		if !opts.Hidden && strings.HasPrefix(e.Name(), ".") {
 			continue
 		}
-		entryType := "file"
-		if e.IsDir() {
-			entryType = "dir"
-		}
-		info, _ := e.Info()
-		modTime := time.Time{}
-		var size int64
-		if info != nil {
-			modTime = info.ModTime()
-			if !info.IsDir() {
-				size = info.Size()
-			}
-		}
-		result.Entries = append(result.Entries, DirEntry{
-			Name:    e.Name(),
-			Type:    entryType,
-			Size:    size,
-			ModTime: modTime.Format(time.RFC3339),
-		})
+		all = append(all, dirEntry(dir, e))
+	}
+	sortEntries(all, opts.Sort, opts.Desc)
+
+	result := &ListResult{
+		Path:  dir,
+		Total: len(all),
+	}
+	start := min(max(opts.Offset, 0), len(all))
+	end := len(all)
+	if opts.Limit > 0 && start+opts.Limit < end {
+		end = start + opts.Limit
+		result.HasMore = true
 	}
+	result.Entries = all[start:end]
+	markGitIgnored(dir, result.Entries)
 
 	return result, nil
 }
 
+// dirEntry builds the listing row for e. Symlinks are followed for Type
+// and Size so a link to a directory can be navigated like one; a
+// dangling link is reported as a file.
+func dirEntry(dir string, e os.DirEntry) DirEntry {
+	entry := DirEntry{Name: e.Name(), Type: "file"}
+	info, _ := e.Info()
+	if info == nil {
+		entry.ModTime = time.Time{}.Format(time.RFC3339)
+		return entry
+	}
+	entry.Mode = info.Mode().String()
+	if info.Mode()&os.ModeSymlink != 0 {
+		full := filepath.Join(dir, e.Name())
+		entry.SymlinkTarget, _ = os.Readlink(full)
+		if target, err := os.Stat(full); err == nil {
+			info = target
+		}
+	}
+	if info.IsDir() {
+		entry.Type = "dir"
+	} else {
+		entry.Size = info.Size()
+		entry.IsArchive = info.Mode().IsRegular() && archiveKind(e.Name()) != ""
+	}
+	entry.modTime = info.ModTime()
+	entry.ModTime = entry.modTime.Format(time.RFC3339)
+	return entry
+}
+
+// sortEntries orders entries by key, falling back to name so the order
+// (and therefore pagination) is stable across calls.
+func sortEntries(entries []DirEntry, key string, desc bool) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		var c int
+		switch key {
+		case SortMtime:
+			c = a.modTime.Compare(b.modTime)
+		case SortSize:
+			c = cmp.Compare(a.Size, b.Size)
+		}
+		if c == 0 {
+			c = strings.Compare(a.Name, b.Name)
+		}
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+}
+
 type FileView struct {
 	Path     string `json:"path"`
-	Type     string `json:"type"` // "text" or "image"
+	Type     string `json:"type"` // "text", "image", "pdf", "video", "audio", "notebook" or "table"
 	Content  string `json:"content,omitempty"`
 	Language string `json:"language,omitempty"`
 	Mime     string `json:"mime,omitempty"`
 	Size     int64  `json:"size"`
 	URL      string `json:"url,omitempty"`
+	// Notebook is set for Type "notebook" (.ipynb).
+	Notebook *NotebookPreview `json:"notebook,omitempty"`
+	// Table is set for Type "table" (.csv / .tsv).
+	Table *TablePreview `json:"table,omitempty"`
 }
 
+// View returns a preview of the file at path. Paths inside a zip/tar
+// archive (see List) are previewed from the archive member.
 func (b *Browser) View(path string) (*FileView, error) {
+	if archive, inner, err := b.archivePath(path); err != nil {
+		return nil, err
+	} else if inner != "" {
+		return b.viewArchive(archive, inner)
+	}
+
 	path, err := b.resolveValidated(path)
 	if err != nil {
 		return nil, err
@@ -169,26 +404,47 @@ func (b *Browser) View(path string) (*FileView, error) {
 
 	info, err := os.Stat(path)
 	if err != nil {
-		return nil, fmt.Errorf("file not found: %w", err)
+		return nil, fmt.Errorf("file %w: %w", ErrNotFound, err)
 	}
 
 	if info.IsDir() {
-		return nil, fmt.Errorf("path is a directory")
+		return nil, ErrIsDirectory
 	}
 
 	ext := strings.ToLower(filepath.Ext(path))
 
-	// image
-	if mime, ok := imageExts[ext]; ok {
+	// image / media: the client renders these from the raw endpoint
+	if mime := rawMime(ext); mime != "" {
 		return &FileView{
 			Path: path,
-			Type: "image",
+			Type: viewType(mime),
 			Mime: mime,
 			Size: info.Size(),
 			URL:  "/api/v1/files/raw?path=" + url.QueryEscape(path),
 		}, nil
 	}
 
+	// structured previews; a file that doesn't parse falls through to
+	// the plain-text view below
+	switch ext {
+	case ".ipynb":
+		nb, err := readNotebook(path, info.Size())
+		if err == nil {
+			return &FileView{Path: path, Type: "notebook", Size: info.Size(), Notebook: nb}, nil
+		}
+		if !errors.Is(err, errMalformedPreview) {
+			return nil, err
+		}
+	case ".csv", ".tsv":
+		table, err := readTable(path, ext == ".tsv")
+		if err == nil {
+			return &FileView{Path: path, Type: "table", Size: info.Size(), Table: table}, nil
+		}
+		if !errors.Is(err, errMalformedPreview) {
+			return nil, err
+		}
+	}
+
 	// text
 	if info.Size() > maxFileSize {
 		return nil, fmt.Errorf("%w: %d bytes (max %d)", ErrFileTooLarge, info.Size(), maxFileSize)
@@ -229,13 +485,36 @@ func (b *Browser) ServeRaw(w http.ResponseWriter, r *http.Request, path string)
 	if err != nil {
 		return thumbnail.NewHTTPError(http.StatusBadRequest, "invalid path", err)
 	}
+	if archive, inner, err := b.archivePath(absPath); err != nil {
+		return thumbnail.NewHTTPError(http.StatusForbidden, err.Error(), err)
+	} else if inner != "" {
+		return b.serveArchive(w, r, archive, inner)
+	}
 	if err := b.validatePath(absPath); err != nil {
 		return thumbnail.NewHTTPError(http.StatusForbidden, err.Error(), err)
 	}
+	setRawHeaders(w, strings.ToLower(filepath.Ext(absPath)))
+	// http.ServeFile answers Range / If-Range requests (206 Partial
+	// Content), which mobile <video> / <audio> players require for
+	// seeking and often for starting playback at all.
 	http.ServeFile(w, r, absPath)
 	return nil
 }
 
+// setRawHeaders sets the content headers ServeRaw sends for a file with
+// extension ext.
+func setRawHeaders(w http.ResponseWriter, ext string) {
+	if mime := rawMime(ext); mime != "" {
+		w.Header().Set("Content-Type", mime)
+	}
+	if ext == ".svg" {
+		// SVG can carry script; served from our own origin it would run
+		// with the user's session. Sandbox it so it only renders.
+		w.Header().Set("Content-Security-Policy", "sandbox")
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+}
+
 // ServeThumb serves a low-resolution JPEG thumbnail of the image at path.
 // Path is validated against the allowed roots just like ServeRaw. Non-image
 // extensions return 415 immediately so unsupported types don't hit the
@@ -301,10 +580,11 @@ func (b *Browser) ValidatePath(path string) error {
 }
 
 // resolveValidated expands a leading ~, makes the path absolute, and checks
-// it against the allowed roots (home / temp), returning the resolved
-// absolute path. It is the shared preamble for the browser's read paths;
-// validatePath (the containment check) and its error values are unchanged,
-// so callers observe identical errors to the inline form.
+// it against the allowed roots (home / temp / configured roots, or the
+// scope), returning the resolved absolute path. It is the shared preamble
+// for the browser's read paths; validatePath (the containment check) and
+// its error values are unchanged, so callers observe identical errors to
+// the inline form.
 func (b *Browser) resolveValidated(path string) (string, error) {
 	path, err := expandHome(path)
 	if err != nil {
@@ -312,7 +592,7 @@ func (b *Browser) resolveValidated(path string) (string, error) {
 	}
 	absPath, err := filepath.Abs(path)
 	if err != nil {
-		return "", fmt.Errorf("invalid path: %w", err)
+		return "", fmt.Errorf("%w: %w", ErrInvalidPath, err)
 	}
 	if err := b.validatePath(absPath); err != nil {
 		return "", err
@@ -325,26 +605,52 @@ func (b *Browser) validatePath(path string) error {
 	resolved, err := filepath.EvalSymlinks(path)
 	if err != nil {
 		if !os.IsNotExist(err) {
-			return fmt.Errorf("access denied: cannot resolve path: %w", err)
+			return fmt.Errorf("%w: cannot resolve path: %w", ErrAccessDenied, err)
 		}
 		// if file doesn't exist yet, resolve the parent
 		resolved, err = filepath.EvalSymlinks(filepath.Dir(path))
 		if err != nil {
-			return fmt.Errorf("access denied: cannot resolve path")
+			return fmt.Errorf("%w: cannot resolve path", ErrAccessDenied)
 		}
 		resolved = filepath.Join(resolved, filepath.Base(path))
 	}
 
+	allowedRoots, err := b.allowedRoots()
+	if err != nil {
+		return err
+	}
+	for _, root := range allowedRoots {
+		if strings.HasPrefix(resolved+string(filepath.Separator), root) {
+			return nil
+		}
+	}
+
+	if b.scope != "" {
+		return fmt.Errorf("%w: path must be under %s", ErrAccessDenied, b.scope)
+	}
+	if len(b.configuredRoots()) > 0 {
+		return fmt.Errorf("%w: path must be under home, temp or a configured root directory", ErrAccessDenied)
+	}
+	return fmt.Errorf("%w: path must be under home or temp directory", ErrAccessDenied)
+}
+
+// allowedRoots returns the symlink-resolved roots a path must fall under,
+// each with a trailing separator so /Users/loppo-evil can't match
+// /Users/loppo.
+func (b *Browser) allowedRoots() ([]string, error) {
+	if b.scope != "" {
+		return []string{strings.TrimSuffix(b.scope, string(filepath.Separator)) + string(filepath.Separator)}, nil
+	}
+
 	home, err := os.UserHomeDir()
 	if err != nil || home == "" {
-		return fmt.Errorf("access denied: cannot determine home directory")
+		return nil, fmt.Errorf("%w: cannot determine home directory", ErrAccessDenied)
 	}
 	homeResolved, err := filepath.EvalSymlinks(home)
 	if err != nil || homeResolved == "" {
-		return fmt.Errorf("access denied: cannot resolve home directory")
+		return nil, fmt.Errorf("%w: cannot resolve home directory", ErrAccessDenied)
 	}
 
-	// use path separator suffix to prevent /Users/loppo-evil matching /Users/loppo
 	allowedRoots := []string{
 		homeResolved + string(filepath.Separator),
 	}
@@ -360,14 +666,14 @@ func (b *Browser) validatePath(path string) error {
 			allowedRoots = append(allowedRoots, tmpResolved+string(filepath.Separator))
 		}
 	}
-
-	for _, root := range allowedRoots {
-		if strings.HasPrefix(resolved+string(filepath.Separator), root) {
-			return nil
+	// configured roots are resolved per call: a volume mounted after
+	// startup becomes browsable without a restart
+	for _, r := range b.configuredRoots() {
+		if rootResolved, err := filepath.EvalSymlinks(r); err == nil && rootResolved != "" {
+			allowedRoots = append(allowedRoots, strings.TrimSuffix(rootResolved, string(filepath.Separator))+string(filepath.Separator))
 		}
 	}
-
-	return fmt.Errorf("access denied: path must be under home or temp directory")
+	return allowedRoots, nil
 }
 
 func isBinary(data []byte) bool {
@@ -0,0 +1,218 @@
+package filebrowser
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func entryNames(entries []DirEntry) []string {
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.Name
+	}
+	return names
+}
+
+func TestListSortAndPaginate(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for i, f := range []struct {
+		name string
+		size int
+	}{
+		{"b.txt", 30},
+		{"a.txt", 10},
+		{"c.txt", 20},
+	} {
+		p := filepath.Join(dir, f.name)
+		if err := os.WriteFile(p, make([]byte, f.size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		mt := now.Add(time.Duration(i) * time.Hour)
+		if err := os.Chtimes(p, mt, mt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".hidden"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	b := New(slog.Default(), Options{})
+
+	cases := []struct {
+		opts ListOptions
+		want []string
+		more bool
+	}{
+		{ListOptions{}, []string{"a.txt", "b.txt", "c.txt"}, false},
+		{ListOptions{Sort: SortSize}, []string{"a.txt", "c.txt", "b.txt"}, false},
+		{ListOptions{Sort: SortMtime, Desc: true}, []string{"c.txt", "a.txt", "b.txt"}, false},
+		{ListOptions{Limit: 2}, []string{"a.txt", "b.txt"}, true},
+		{ListOptions{Offset: 2, Limit: 2}, []string{"c.txt"}, false},
+		{ListOptions{Offset: 10}, []string{}, false},
+		{ListOptions{Hidden: true, Limit: 1}, []string{".hidden"}, true},
+	}
+	for _, tc := range cases {
+		res, err := b.List(dir, tc.opts)
+		if err != nil {
+			t.Fatalf("List(%+v): %v", tc.opts, err)
+		}
+		got := entryNames(res.Entries)
+		if len(got) != len(tc.want) {
+			t.Errorf("List(%+v) = %v, want %v", tc.opts, got, tc.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("List(%+v) = %v, want %v", tc.opts, got, tc.want)
+				break
+			}
+		}
+		if res.HasMore != tc.more {
+			t.Errorf("List(%+v).HasMore = %v, want %v", tc.opts, res.HasMore, tc.more)
+		}
+	}
+}
+
+func TestListSymlinkMetadata(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "real"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("real", filepath.Join(dir, "link")); err != nil {
+		t.Skipf("symlink unsupported: %v", err)
+	}
+	b := New(slog.Default(), Options{})
+	res, err := b.List(dir, ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var link *DirEntry
+	for i := range res.Entries {
+		if res.Entries[i].Name == "link" {
+			link = &res.Entries[i]
+		}
+	}
+	if link == nil {
+		t.Fatal("link entry missing")
+	}
+	if link.Type != "dir" || link.SymlinkTarget != "real" {
+		t.Errorf("link = %+v, want type dir, target real", *link)
+	}
+	if link.Mode == "" || link.Mode[0] != 'L' {
+		t.Errorf("link mode = %q, want symlink mode", link.Mode)
+	}
+}
+
+func TestViewMediaTypes(t *testing.T) {
+	dir := t.TempDir()
+	b := New(slog.Default(), Options{})
+	for name, want := range map[string]string{
+		"doc.pdf":  "pdf",
+		"clip.mp4": "video",
+		"song.mp3": "audio",
+		"icon.svg": "image",
+	} {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		v, err := b.View(p)
+		if err != nil {
+			t.Fatalf("View(%s): %v", name, err)
+		}
+		if v.Type != want || v.URL == "" || v.Mime == "" {
+			t.Errorf("View(%s) = type %q url %q mime %q, want type %q with url", name, v.Type, v.URL, v.Mime, want)
+		}
+	}
+}
+
+func TestServeRawRange(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "clip.webm")
+	if err := os.WriteFile(p, []byte("0123456789"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	b := New(slog.Default(), Options{})
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/raw", nil)
+	req.Header.Set("Range", "bytes=2-5")
+	rec := httptest.NewRecorder()
+	if err := b.ServeRaw(rec, req, p); err != nil {
+		t.Fatalf("ServeRaw: %v", err)
+	}
+	if rec.Code != http.StatusPartialContent {
+		t.Fatalf("status = %d, want 206", rec.Code)
+	}
+	if got := rec.Body.String(); got != "2345" {
+		t.Errorf("body = %q, want %q", got, "2345")
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "video/webm" {
+		t.Errorf("Content-Type = %q, want video/webm", ct)
+	}
+}
+
+func TestScopedBrowser(t *testing.T) {
+	dir := t.TempDir()
+	work := filepath.Join(dir, "work")
+	if err := os.MkdirAll(filepath.Join(work, "src"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "outside.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	scoped, err := New(slog.Default(), Options{}).Scoped(work)
+	if err != nil {
+		t.Fatalf("Scoped: %v", err)
+	}
+	res, err := scoped.List("", ListOptions{})
+	if err != nil {
+		t.Fatalf("List scope root: %v", err)
+	}
+	if got := entryNames(res.Entries); len(got) != 1 || got[0] != "src" {
+		t.Errorf("scope root entries = %v, want [src]", got)
+	}
+	if err := scoped.ValidatePath(filepath.Join(work, "src")); err != nil {
+		t.Errorf("path inside scope rejected: %v", err)
+	}
+	if err := scoped.ValidatePath(filepath.Join(dir, "outside.txt")); err == nil {
+		t.Error("path outside scope accepted")
+	}
+	if err := scoped.ValidatePath(filepath.Join(work, "..", "outside.txt")); err == nil {
+		t.Error("dot-dot escape from scope accepted")
+	}
+}
+
+func TestConfiguredRoots(t *testing.T) {
+	root := t.TempDir()
+	b := New(slog.Default(), Options{Roots: []string{"", root}})
+	found := false
+	want, _ := filepath.EvalSymlinks(root)
+	for _, r := range b.Roots() {
+		if r == want {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Roots() = %v, missing %s", b.Roots(), want)
+	}
+}
+
+func TestSetRootsReplacesConfiguredRoots(t *testing.T) {
+	root := t.TempDir()
+	want, _ := filepath.EvalSymlinks(root)
+	b := New(slog.Default(), Options{Roots: []string{root}})
+	b.SetRoots(nil)
+	for _, r := range b.Roots() {
+		if r == want {
+			t.Fatalf("Roots() = %v, still has %s after SetRoots(nil)", b.Roots(), want)
+		}
+	}
+	b.SetRoots([]string{root})
+	if !slices.Contains(b.Roots(), want) {
+		t.Errorf("Roots() = %v, missing %s", b.Roots(), want)
+	}
+}
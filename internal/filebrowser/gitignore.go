@@ -0,0 +1,51 @@
+package filebrowser
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// gitCheckIgnoreTimeout bounds the `git check-ignore` call made per
+// listing. The lookup is a decoration; a slow or wedged git must not
+// stall the directory listing.
+const gitCheckIgnoreTimeout = 3 * time.Second
+
+// markGitIgnored sets IsGitIgnored on the entries of dir matched by the
+// enclosing work tree's ignore rules. Outside a work tree, without a git
+// binary, or on any git failure the entries are left untouched.
+func markGitIgnored(dir string, entries []DirEntry) {
+	if len(entries) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), gitCheckIgnoreTimeout)
+	defer cancel()
+
+	var stdin bytes.Buffer
+	for _, e := range entries {
+		stdin.WriteString(e.Name)
+		stdin.WriteByte(0)
+	}
+	cmd := exec.CommandContext(ctx, "git", "check-ignore", "--stdin", "-z")
+	cmd.Dir = dir
+	cmd.Stdin = &stdin
+	// Exit status 1 means "nothing ignored" and 128 "not a repository";
+	// both leave stdout empty, so the output alone decides.
+	out, _ := cmd.Output()
+	if len(out) == 0 {
+		return
+	}
+	ignored := make(map[string]bool)
+	for _, name := range strings.Split(string(out), "\x00") {
+		if name != "" {
+			ignored[name] = true
+		}
+	}
+	for i := range entries {
+		if ignored[entries[i].Name] {
+			entries[i].IsGitIgnored = true
+		}
+	}
+}
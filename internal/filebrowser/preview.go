@@ -0,0 +1,256 @@
+package filebrowser
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// errMalformedPreview signals that a file with a previewable extension
+// did not parse. View falls back to the plain-text view in that case.
+var errMalformedPreview = errors.New("malformed preview source")
+
+const (
+	// maxNotebookSize caps the .ipynb files parsed for a structured
+	// preview. Notebooks embed base64 plot images in their outputs, so
+	// they outgrow maxFileSize long before they stop being useful.
+	maxNotebookSize = 16 * 1024 * 1024
+	// maxNotebookOutputText truncates a single text output (a cell that
+	// printed a whole dataframe, a long traceback).
+	maxNotebookOutputText = 64 * 1024
+	// maxTableRows is the row cap for .csv / .tsv previews; the header
+	// row counts toward it.
+	maxTableRows = 1000
+	// maxTableCellBytes truncates individual cells so one huge field
+	// can't dominate the response.
+	maxTableCellBytes = 4 * 1024
+)
+
+// notebookMimes lists the rich output representations forwarded to the
+// client, in preference order. Everything else (widget state, vendor
+// JSON) is dropped.
+var notebookMimes = []string{
+	"text/html",
+	"image/png",
+	"image/jpeg",
+	"image/svg+xml",
+	"text/markdown",
+	"text/latex",
+	"application/json",
+	"text/plain",
+}
+
+// NotebookPreview is the structured form of a Jupyter notebook.
+type NotebookPreview struct {
+	Language string         `json:"language,omitempty"`
+	Cells    []NotebookCell `json:"cells"`
+}
+
+type NotebookCell struct {
+	Type           string           `json:"type"` // "code", "markdown" or "raw"
+	Source         string           `json:"source"`
+	ExecutionCount *int             `json:"executionCount,omitempty"`
+	Outputs        []NotebookOutput `json:"outputs,omitempty"`
+}
+
+// NotebookOutput is one cell output. Stream output carries Name and
+// Text; execute_result / display_data carry Data keyed by MIME type
+// (images base64-encoded as stored in the notebook); error carries
+// EName, EValue and Traceback (which may contain ANSI escapes).
+type NotebookOutput struct {
+	Type      string            `json:"type"`
+	Name      string            `json:"name,omitempty"`
+	Text      string            `json:"text,omitempty"`
+	Data      map[string]string `json:"data,omitempty"`
+	EName     string            `json:"ename,omitempty"`
+	EValue    string            `json:"evalue,omitempty"`
+	Traceback []string          `json:"traceback,omitempty"`
+}
+
+// multiline is nbformat's "string or list of strings" field.
+type multiline string
+
+func (m *multiline) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*m = multiline(s)
+		return nil
+	}
+	var parts []string
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return err
+	}
+	*m = multiline(strings.Join(parts, ""))
+	return nil
+}
+
+type rawNotebook struct {
+	Metadata struct {
+		Kernelspec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+	Cells []struct {
+		CellType       string    `json:"cell_type"`
+		Source         multiline `json:"source"`
+		ExecutionCount *int      `json:"execution_count"`
+		Outputs        []struct {
+			OutputType string                     `json:"output_type"`
+			Name       string                     `json:"name"`
+			Text       multiline                  `json:"text"`
+			Data       map[string]json.RawMessage `json:"data"`
+			EName      string                     `json:"ename"`
+			EValue     string                     `json:"evalue"`
+			Traceback  []string                   `json:"traceback"`
+		} `json:"outputs"`
+	} `json:"cells"`
+}
+
+// readNotebook parses an nbformat 4 notebook. A file that isn't valid
+// notebook JSON yields errMalformedPreview.
+func readNotebook(path string, size int64) (*NotebookPreview, error) {
+	if size > maxNotebookSize {
+		return nil, fmt.Errorf("%w: %d bytes (max %d)", ErrFileTooLarge, size, maxNotebookSize)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read file: %w", err)
+	}
+	var raw rawNotebook
+	if err := json.Unmarshal(data, &raw); err != nil || raw.Cells == nil {
+		return nil, errMalformedPreview
+	}
+
+	nb := &NotebookPreview{
+		Language: raw.Metadata.LanguageInfo.Name,
+		Cells:    make([]NotebookCell, 0, len(raw.Cells)),
+	}
+	if nb.Language == "" {
+		nb.Language = raw.Metadata.Kernelspec.Language
+	}
+	for _, c := range raw.Cells {
+		cell := NotebookCell{
+			Type:   c.CellType,
+			Source: string(c.Source),
+		}
+		if c.CellType == "code" {
+			cell.ExecutionCount = c.ExecutionCount
+		}
+		for _, o := range c.Outputs {
+			out := NotebookOutput{
+				Type:      o.OutputType,
+				Name:      o.Name,
+				Text:      truncateText(string(o.Text), maxNotebookOutputText),
+				EName:     o.EName,
+				EValue:    o.EValue,
+				Traceback: o.Traceback,
+			}
+			for _, mime := range notebookMimes {
+				rawVal, ok := o.Data[mime]
+				if !ok {
+					continue
+				}
+				var val string
+				if mime == "application/json" {
+					val = string(rawVal)
+				} else {
+					var m multiline
+					if json.Unmarshal(rawVal, &m) != nil {
+						continue
+					}
+					val = string(m)
+				}
+				if !strings.HasPrefix(mime, "image/") || mime == "image/svg+xml" {
+					val = truncateText(val, maxNotebookOutputText)
+				}
+				if out.Data == nil {
+					out.Data = make(map[string]string)
+				}
+				out.Data[mime] = val
+			}
+			cell.Outputs = append(cell.Outputs, out)
+		}
+		nb.Cells = append(nb.Cells, cell)
+	}
+	return nb, nil
+}
+
+// TablePreview is the parsed head of a delimited text file.
+type TablePreview struct {
+	Delimiter string     `json:"delimiter"`
+	Rows      [][]string `json:"rows"`
+	// Truncated is set when the file has more rows than were returned.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// readTable parses up to maxTableRows records of a CSV (or, with tsv,
+// tab-separated) file. Only the rows returned are read, so a multi-GB
+// export previews as cheaply as a small one. Binary content or a
+// syntax error before the first record yields errMalformedPreview.
+func readTable(path string, tsv bool) (*TablePreview, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read file: %w", err)
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	if head, _ := br.Peek(512); isBinary(head) {
+		return nil, fmt.Errorf("%w: binary", ErrUnsupportedFile)
+	}
+
+	r := csv.NewReader(br)
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	r.ReuseRecord = true
+	table := &TablePreview{Delimiter: ",", Rows: [][]string{}}
+	if tsv {
+		r.Comma = '\t'
+		table.Delimiter = "\t"
+	}
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if len(table.Rows) == 0 {
+				return nil, errMalformedPreview
+			}
+			// Show what parsed; the broken tail is cut like a row cap.
+			table.Truncated = true
+			break
+		}
+		if len(table.Rows) == maxTableRows {
+			table.Truncated = true
+			break
+		}
+		row := make([]string, len(rec))
+		for i, cell := range rec {
+			row[i] = truncateText(cell, maxTableCellBytes)
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table, nil
+}
+
+// truncateText cuts s to at most n bytes without splitting a UTF-8
+// sequence.
+func truncateText(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	cut := n
+	for cut > 0 && cut < len(s) && s[cut]&0xC0 == 0x80 {
+		cut--
+	}
+	return s[:cut]
+}
@@ -0,0 +1,112 @@
+package filebrowser
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sampleNotebook = `{
+ "cells": [
+  {"cell_type": "markdown", "metadata": {}, "source": ["# Title\n", "text"]},
+  {"cell_type": "code", "execution_count": 3, "metadata": {}, "source": "print(1)",
+   "outputs": [
+    {"output_type": "stream", "name": "stdout", "text": ["1\n"]},
+    {"output_type": "execute_result", "execution_count": 3, "metadata": {},
+     "data": {"text/plain": ["2"], "image/png": "iVBORw0KGgo=", "application/vnd.jupyter.widget-view+json": {"model_id": "x"}}},
+    {"output_type": "error", "ename": "ValueError", "evalue": "bad", "traceback": ["line 1"]}
+   ]}
+ ],
+ "metadata": {"kernelspec": {"language": "python"}},
+ "nbformat": 4, "nbformat_minor": 5
+}`
+
+func TestViewNotebook(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nb.ipynb")
+	if err := os.WriteFile(path, []byte(sampleNotebook), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	v, err := New(slog.Default(), Options{}).View(path)
+	if err != nil {
+		t.Fatalf("View: %v", err)
+	}
+	if v.Type != "notebook" || v.Notebook == nil {
+		t.Fatalf("type = %q, notebook = %v", v.Type, v.Notebook)
+	}
+	nb := v.Notebook
+	if nb.Language != "python" || len(nb.Cells) != 2 {
+		t.Fatalf("language = %q, cells = %d", nb.Language, len(nb.Cells))
+	}
+	if nb.Cells[0].Source != "# Title\ntext" {
+		t.Errorf("markdown source = %q", nb.Cells[0].Source)
+	}
+	code := nb.Cells[1]
+	if code.ExecutionCount == nil || *code.ExecutionCount != 3 || len(code.Outputs) != 3 {
+		t.Fatalf("code cell = %+v", code)
+	}
+	if o := code.Outputs[0]; o.Name != "stdout" || o.Text != "1\n" {
+		t.Errorf("stream output = %+v", o)
+	}
+	data := code.Outputs[1].Data
+	if data["text/plain"] != "2" || data["image/png"] != "iVBORw0KGgo=" || len(data) != 2 {
+		t.Errorf("result data = %v", data)
+	}
+	if o := code.Outputs[2]; o.EName != "ValueError" || len(o.Traceback) != 1 {
+		t.Errorf("error output = %+v", o)
+	}
+}
+
+func TestViewMalformedNotebookFallsBackToText(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.ipynb")
+	if err := os.WriteFile(path, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	v, err := New(slog.Default(), Options{}).View(path)
+	if err != nil {
+		t.Fatalf("View: %v", err)
+	}
+	if v.Type != "text" || v.Content != "{not json" {
+		t.Errorf("view = %+v, want text fallback", v)
+	}
+}
+
+func TestViewTable(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "data.csv")
+	if err := os.WriteFile(csvPath, []byte("name,note\nalice,\"a, b\"\nbob\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	b := New(slog.Default(), Options{})
+	v, err := b.View(csvPath)
+	if err != nil {
+		t.Fatalf("View: %v", err)
+	}
+	if v.Type != "table" || v.Table == nil {
+		t.Fatalf("type = %q", v.Type)
+	}
+	if got := fmt.Sprint(v.Table.Rows); got != "[[name note] [alice a, b] [bob]]" {
+		t.Errorf("rows = %s", got)
+	}
+	if v.Table.Truncated {
+		t.Error("small table reported truncated")
+	}
+
+	var sb strings.Builder
+	for i := 0; i < maxTableRows+5; i++ {
+		fmt.Fprintf(&sb, "%d\tx\n", i)
+	}
+	tsvPath := filepath.Join(dir, "big.tsv")
+	if err := os.WriteFile(tsvPath, []byte(sb.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	v, err = b.View(tsvPath)
+	if err != nil {
+		t.Fatalf("View: %v", err)
+	}
+	if len(v.Table.Rows) != maxTableRows || !v.Table.Truncated || v.Table.Delimiter != "\t" {
+		t.Errorf("rows = %d, truncated = %v, delimiter = %q", len(v.Table.Rows), v.Table.Truncated, v.Table.Delimiter)
+	}
+}
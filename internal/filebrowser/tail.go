@@ -0,0 +1,306 @@
+package filebrowser
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	// defaultTailLines is the backlog size returned when the caller
+	// does not ask for a specific count.
+	defaultTailLines = 100
+	// maxTailLines caps the requested backlog so a "lines=1e9" query
+	// can't turn the initial read into a full-file dump.
+	maxTailLines = 5000
+	// maxTailBacklogBytes bounds how far back from EOF the initial read
+	// scans for the requested lines. Same budget as View.
+	maxTailBacklogBytes = maxFileSize
+	// maxTailLineBytes caps a single unterminated line held in the
+	// partial-line buffer. A writer that never emits '\n' (progress bars,
+	// minified output) is flushed in pieces instead of growing forever.
+	maxTailLineBytes = 64 * 1024
+	// tailReadChunk is the per-read size while following appended data.
+	tailReadChunk = 64 * 1024
+	// tailKeepalive is how often an idle follow emits an empty event.
+	// The caller uses it as a heartbeat so a dead client is noticed
+	// (the write fails) and proxies don't reap the idle stream. The
+	// tick also re-checks the file, covering filesystems where
+	// inotify/kqueue events are unreliable (network mounts).
+	tailKeepalive = 15 * time.Second
+)
+
+// TailEvent is one batch of lines delivered by Tail. The first event
+// carries the backlog; later events carry appended lines. An event with
+// no Lines and Reset unset is a keepalive.
+type TailEvent struct {
+	Lines []string `json:"lines"`
+	// Offset is the byte offset in the file just past the last line
+	// delivered so far.
+	Offset int64 `json:"offset"`
+	// Reset is set when the file was truncated or replaced (log
+	// rotation); Lines then start from the top of the new file.
+	Reset bool `json:"reset,omitempty"`
+}
+
+// Tail delivers the last n lines of the file at path to fn and, when
+// follow is set, keeps delivering appended lines until ctx is cancelled
+// or fn returns an error. Appends are detected with fsnotify (inotify /
+// kqueue / ReadDirectoryChangesW) on the parent directory so rotation by
+// rename+create is picked up as well as in-place writes.
+//
+// Validation failures (path outside the allowed roots, missing file,
+// directory) are returned before fn is first called, so the caller can
+// still answer with a plain error response. n <= 0 selects the default
+// backlog size.
+func (b *Browser) Tail(ctx context.Context, path string, n int, follow bool, fn func(TailEvent) error) error {
+	path, err := b.resolveValidated(path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("file %w: %w", ErrNotFound, err)
+	}
+	if info.IsDir() {
+		return ErrIsDirectory
+	}
+	if n <= 0 {
+		n = defaultTailLines
+	}
+	if n > maxTailLines {
+		n = maxTailLines
+	}
+
+	t := &tailer{path: path}
+	defer t.close()
+
+	var watcher *fsnotify.Watcher
+	if follow {
+		// Register the watch before the backlog read so a write landing
+		// between the two is not lost.
+		watcher, err = fsnotify.NewWatcher()
+		if err != nil {
+			return fmt.Errorf("cannot watch file: %w", err)
+		}
+		defer watcher.Close()
+		if err := watcher.Add(filepath.Dir(path)); err != nil {
+			return fmt.Errorf("cannot watch file: %w", err)
+		}
+	}
+
+	lines, err := t.backlog(n)
+	if err != nil {
+		return err
+	}
+	if err := fn(TailEvent{Lines: lines, Offset: t.offset}); err != nil {
+		return err
+	}
+	if !follow {
+		return nil
+	}
+
+	ticker := time.NewTicker(tailKeepalive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(ev.Name) != path {
+				continue
+			}
+			if _, err := t.drain(fn); err != nil {
+				return err
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			b.logger.Debug("tail watcher error", "path", path, "err", err)
+		case <-ticker.C:
+			sent, err := t.drain(fn)
+			if err != nil {
+				return err
+			}
+			if sent == 0 {
+				if err := fn(TailEvent{Offset: t.offset - int64(len(t.partial))}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// tailer tracks the read position in a followed file across appends,
+// truncation and replacement.
+type tailer struct {
+	path    string
+	f       *os.File
+	offset  int64
+	partial []byte
+}
+
+func (t *tailer) close() {
+	if t.f != nil {
+		t.f.Close()
+		t.f = nil
+	}
+}
+
+// backlog opens the file, returns up to n trailing complete lines and
+// positions the read offset for the follow loop.
+func (t *tailer) backlog(n int) ([]string, error) {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read file: %w", err)
+	}
+	t.f = f
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read file: %w", err)
+	}
+	size := info.Size()
+	start := size - maxTailBacklogBytes
+	if start < 0 {
+		start = 0
+	}
+	buf := make([]byte, size-start)
+	if _, err := f.ReadAt(buf, start); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("cannot read file: %w", err)
+	}
+	if isBinary(buf) {
+		return nil, fmt.Errorf("%w: binary", ErrUnsupportedFile)
+	}
+	// Starting mid-file: the first line is almost certainly cut.
+	if start > 0 {
+		if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+			buf = buf[i+1:]
+		}
+	}
+	// Hold back an unterminated last line: rewinding the offset makes
+	// the next read pick it up whole once the writer finishes it,
+	// instead of emitting it now and again (completed) later.
+	t.offset = size
+	if i := bytes.LastIndexByte(buf, '\n'); i < len(buf)-1 {
+		t.offset -= int64(len(buf) - (i + 1))
+		buf = buf[:i+1]
+	}
+	lines := splitLines(buf)
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, nil
+}
+
+// drain delivers everything appended since the last read and reports
+// how many events it sent, so the keepalive tick knows whether the
+// stream was idle.
+func (t *tailer) drain(fn func(TailEvent) error) (int, error) {
+	sent := 0
+	reset := t.checkReplaced()
+	buf := make([]byte, tailReadChunk)
+	for {
+		if t.f == nil {
+			return sent, nil
+		}
+		nr, err := t.f.ReadAt(buf, t.offset)
+		if nr > 0 {
+			t.offset += int64(nr)
+			lines := t.feed(buf[:nr])
+			if len(lines) > 0 || reset {
+				if err := fn(TailEvent{Lines: lines, Offset: t.offset - int64(len(t.partial)), Reset: reset}); err != nil {
+					return sent, err
+				}
+				sent++
+				reset = false
+			}
+		}
+		if err != nil || nr < len(buf) {
+			if reset {
+				// Truncated to empty: still tell the caller.
+				if err := fn(TailEvent{Offset: t.offset, Reset: true}); err != nil {
+					return sent, err
+				}
+				sent++
+			}
+			return sent, nil
+		}
+	}
+}
+
+// checkReplaced detects truncation (size shrank below our offset) and
+// replacement (the path now names a different file, e.g. after
+// logrotate's rename+create). Either way reading restarts at offset 0.
+// A missing path is left alone: a rotator usually recreates it shortly
+// and the next event re-checks.
+func (t *tailer) checkReplaced() bool {
+	info, err := os.Stat(t.path)
+	if err != nil {
+		return false
+	}
+	if t.f != nil {
+		if cur, err := t.f.Stat(); err == nil && os.SameFile(cur, info) {
+			if info.Size() >= t.offset {
+				return false
+			}
+			t.offset = 0
+			t.partial = t.partial[:0]
+			return true
+		}
+	}
+	f, err := os.Open(t.path)
+	if err != nil {
+		return false
+	}
+	t.close()
+	t.f = f
+	t.offset = 0
+	t.partial = t.partial[:0]
+	return true
+}
+
+// feed appends data to the partial-line buffer and returns the complete
+// lines it now holds. An over-long unterminated line is emitted as-is
+// once it reaches maxTailLineBytes.
+func (t *tailer) feed(data []byte) []string {
+	t.partial = append(t.partial, data...)
+	i := bytes.LastIndexByte(t.partial, '\n')
+	if i < 0 {
+		if len(t.partial) < maxTailLineBytes {
+			return nil
+		}
+		line := string(t.partial)
+		t.partial = t.partial[:0]
+		return []string{line}
+	}
+	lines := splitLines(t.partial[:i+1])
+	rest := copy(t.partial, t.partial[i+1:])
+	t.partial = t.partial[:rest]
+	return lines
+}
+
+// splitLines splits newline-terminated data into lines without their
+// terminators (a trailing "\r" from CRLF files is dropped too).
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return []string{}
+	}
+	data = bytes.TrimSuffix(data, []byte{'\n'})
+	parts := bytes.Split(data, []byte{'\n'})
+	lines := make([]string, len(parts))
+	for i, p := range parts {
+		lines[i] = string(bytes.TrimSuffix(p, []byte{'\r'}))
+	}
+	return lines
+}
@@ -0,0 +1,123 @@
+package filebrowser
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTailBacklog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("a\nb\r\nc\nd\npartial"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	b := New(slog.Default(), Options{})
+
+	var got []TailEvent
+	err := b.Tail(context.Background(), path, 2, false, func(ev TailEvent) error {
+		got = append(got, ev)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Tail: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("events = %d, want 1", len(got))
+	}
+	if want := []string{"c", "d"}; !reflect.DeepEqual(got[0].Lines, want) {
+		t.Errorf("lines = %q, want %q", got[0].Lines, want)
+	}
+	// The unterminated "partial" is held back, not delivered.
+	if want := int64(len("a\nb\r\nc\nd\n")); got[0].Offset != want {
+		t.Errorf("offset = %d, want %d", got[0].Offset, want)
+	}
+}
+
+func TestTailRejectsDirectory(t *testing.T) {
+	b := New(slog.Default(), Options{})
+	err := b.Tail(context.Background(), t.TempDir(), 0, false, func(TailEvent) error {
+		t.Fatal("fn called for a directory")
+		return nil
+	})
+	if err == nil {
+		t.Fatal("expected error for directory")
+	}
+}
+
+func TestTailFollow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	b := New(slog.Default(), Options{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	events := make(chan TailEvent, 16)
+	done := make(chan error, 1)
+	go func() {
+		done <- b.Tail(ctx, path, 0, true, func(ev TailEvent) error {
+			events <- ev
+			return nil
+		})
+	}()
+
+	next := func() TailEvent {
+		t.Helper()
+		select {
+		case ev := <-events:
+			return ev
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for tail event")
+		}
+		return TailEvent{}
+	}
+
+	if ev := next(); !reflect.DeepEqual(ev.Lines, []string{"old"}) {
+		t.Fatalf("backlog = %q, want [old]", ev.Lines)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Split a line across two writes: it must arrive once, whole.
+	if _, err := f.WriteString("new "); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if _, err := f.WriteString("line\n"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if ev := next(); !reflect.DeepEqual(ev.Lines, []string{"new line"}) {
+		t.Fatalf("appended = %q, want [new line]", ev.Lines)
+	}
+
+	if err := os.WriteFile(path, []byte("fresh\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		ev := next()
+		if ev.Reset {
+			// Truncate and write may be observed as one or two events.
+			if len(ev.Lines) == 0 {
+				ev = next()
+			}
+			if !reflect.DeepEqual(ev.Lines, []string{"fresh"}) {
+				t.Fatalf("after reset = %q, want [fresh]", ev.Lines)
+			}
+			break
+		}
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Tail returned %v, want context.Canceled", err)
+	}
+}
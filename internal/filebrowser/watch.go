@@ -0,0 +1,325 @@
+package filebrowser
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	// watchDebounce coalesces a burst of filesystem events (an agent
+	// rewriting a dozen files, an editor's tmp+rename save) into one
+	// batch per subscriber.
+	watchDebounce = 300 * time.Millisecond
+	// maxWatchDirs caps how many directories one tree watch registers.
+	// inotify watches are a per-user kernel resource; a workDir with a
+	// huge vendored tree must not exhaust them. Directories past the
+	// cap are simply not watched.
+	maxWatchDirs = 4096
+	// watchSubBuffer is the per-subscriber batch backlog. A subscriber
+	// that falls further behind gets a single ChangeRescan instead of
+	// the batches it missed.
+	watchSubBuffer = 16
+)
+
+// Change ops reported in FileChange.Op.
+const (
+	ChangeCreated  = "created"
+	ChangeModified = "modified"
+	ChangeDeleted  = "deleted"
+	// ChangeRescan tells the subscriber that changes were dropped (it
+	// fell behind) and it should reload instead of patching its view.
+	ChangeRescan = "rescan"
+)
+
+// watchSkipDirs are never descended into: churny, huge, and not what a
+// user watching their project cares about.
+var watchSkipDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+}
+
+// FileChange is one debounced change under a watched directory. Path is
+// slash-separated and relative to the watched directory.
+type FileChange struct {
+	Path string `json:"path"`
+	Op   string `json:"op"`
+}
+
+// watchHub shares one recursive fsnotify watch per directory between
+// every subscriber, so several tabs on the same workDir cost one set of
+// kernel watches.
+type watchHub struct {
+	logger *slog.Logger
+	mu     sync.Mutex
+	dirs   map[string]*dirWatch
+}
+
+func newWatchHub(logger *slog.Logger) *watchHub {
+	return &watchHub{logger: logger, dirs: make(map[string]*dirWatch)}
+}
+
+// Watch delivers debounced change batches for the directory tree at dir
+// to fn until ctx is cancelled or fn returns an error. fn is called with
+// an empty batch once the watch is established and then every
+// tailKeepalive, so the caller can open its stream and send heartbeats.
+// Validation failures are returned before fn is first called.
+func (b *Browser) Watch(ctx context.Context, dir string, fn func([]FileChange) error) error {
+	dir, err := b.resolveValidated(dir)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("directory %w: %w", ErrNotFound, err)
+	}
+	if !info.IsDir() {
+		return ErrNotDirectory
+	}
+
+	ch, unsubscribe, err := b.watches.subscribe(dir)
+	if err != nil {
+		return err
+	}
+	defer unsubscribe()
+	if err := fn(nil); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(tailKeepalive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case batch := <-ch:
+			if err := fn(batch); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			if err := fn(nil); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (h *watchHub) subscribe(dir string) (<-chan []FileChange, func(), error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	dw, ok := h.dirs[dir]
+	if !ok {
+		var err error
+		dw, err = newDirWatch(dir, h)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot watch directory: %w", err)
+		}
+		h.dirs[dir] = dw
+		go dw.run()
+	}
+	ch := make(chan []FileChange, watchSubBuffer)
+	dw.subs[ch] = &subState{}
+	unsubscribe := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(dw.subs, ch)
+		if len(dw.subs) == 0 {
+			delete(h.dirs, dir)
+			dw.close()
+		}
+	}
+	return ch, unsubscribe, nil
+}
+
+type subState struct {
+	overflowed bool
+}
+
+// dirWatch is one recursive watch rooted at root. subs is guarded by
+// the owning hub's mu; pending by dirWatch.mu.
+type dirWatch struct {
+	root   string
+	logger *slog.Logger
+	w      *fsnotify.Watcher
+	hub    *watchHub
+	subs   map[chan []FileChange]*subState
+	nDirs  int
+
+	mu      sync.Mutex
+	pending map[string]string
+	timer   *time.Timer
+	closed  bool
+}
+
+func newDirWatch(root string, hub *watchHub) (*dirWatch, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	dw := &dirWatch{
+		root:    root,
+		logger:  hub.logger,
+		w:       w,
+		hub:     hub,
+		subs:    make(map[chan []FileChange]*subState),
+		pending: make(map[string]string),
+	}
+	if err := w.Add(root); err != nil {
+		w.Close()
+		return nil, err
+	}
+	dw.nDirs = 1
+	dw.addTree(root)
+	return dw, nil
+}
+
+// addTree registers every subdirectory below dir (dir itself is already
+// watched), skipping watchSkipDirs and stopping at maxWatchDirs.
+func (dw *dirWatch) addTree(dir string) {
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() || path == dir {
+			return nil // best-effort: skip unreadable entries
+		}
+		if watchSkipDirs[d.Name()] {
+			return filepath.SkipDir
+		}
+		if dw.nDirs >= maxWatchDirs {
+			return filepath.SkipAll
+		}
+		if err := dw.w.Add(path); err != nil {
+			dw.logger.Debug("file watch: add dir failed", "path", path, "err", err)
+			return filepath.SkipDir
+		}
+		dw.nDirs++
+		return nil
+	})
+}
+
+func (dw *dirWatch) close() {
+	dw.mu.Lock()
+	dw.closed = true
+	if dw.timer != nil {
+		dw.timer.Stop()
+	}
+	dw.mu.Unlock()
+	dw.w.Close()
+}
+
+// run is the event loop; it exits when close() shuts the watcher.
+func (dw *dirWatch) run() {
+	for {
+		select {
+		case ev, ok := <-dw.w.Events:
+			if !ok {
+				return
+			}
+			dw.handle(ev)
+		case err, ok := <-dw.w.Errors:
+			if !ok {
+				return
+			}
+			dw.logger.Debug("file watch: watcher error", "root", dw.root, "err", err)
+		}
+	}
+}
+
+func (dw *dirWatch) handle(ev fsnotify.Event) {
+	rel, err := filepath.Rel(dw.root, ev.Name)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return
+	}
+	for _, seg := range strings.Split(rel, string(filepath.Separator)) {
+		if watchSkipDirs[seg] {
+			return
+		}
+	}
+	var op string
+	switch {
+	case ev.Op&fsnotify.Create != 0:
+		op = ChangeCreated
+		// fsnotify is non-recursive: a new directory must be added so
+		// changes inside it are seen.
+		if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+			if dw.nDirs < maxWatchDirs && dw.w.Add(ev.Name) == nil {
+				dw.nDirs++
+				dw.addTree(ev.Name)
+			}
+		}
+	case ev.Op&(fsnotify.Remove|fsnotify.Rename) != 0:
+		op = ChangeDeleted
+	case ev.Op&(fsnotify.Write|fsnotify.Chmod) != 0:
+		op = ChangeModified
+	default:
+		return
+	}
+	dw.record(filepath.ToSlash(rel), op)
+}
+
+// record merges op into the pending batch for path and (re)arms the
+// debounce timer.
+func (dw *dirWatch) record(path, op string) {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+	if dw.closed {
+		return
+	}
+	switch prev := dw.pending[path]; {
+	case prev == ChangeCreated && op == ChangeDeleted:
+		// Created and gone within one window (tmp file): nothing to say.
+		delete(dw.pending, path)
+	case prev == ChangeCreated && op == ChangeModified:
+		// Still a creation from the subscriber's point of view.
+	case prev == ChangeDeleted && op == ChangeCreated:
+		// Replaced (atomic save): the path still exists, with new content.
+		dw.pending[path] = ChangeModified
+	default:
+		dw.pending[path] = op
+	}
+	if dw.timer == nil {
+		dw.timer = time.AfterFunc(watchDebounce, dw.flush)
+	} else {
+		dw.timer.Reset(watchDebounce)
+	}
+}
+
+// flush hands the pending batch to every subscriber. A subscriber whose
+// buffer is full is marked overflowed; once it drains it receives a
+// single ChangeRescan in place of the batches it missed.
+func (dw *dirWatch) flush() {
+	dw.mu.Lock()
+	if dw.closed || len(dw.pending) == 0 {
+		dw.mu.Unlock()
+		return
+	}
+	batch := make([]FileChange, 0, len(dw.pending))
+	for p, op := range dw.pending {
+		batch = append(batch, FileChange{Path: p, Op: op})
+	}
+	dw.pending = make(map[string]string)
+	dw.mu.Unlock()
+	sort.Slice(batch, func(i, j int) bool { return batch[i].Path < batch[j].Path })
+
+	dw.hub.mu.Lock()
+	defer dw.hub.mu.Unlock()
+	for ch, st := range dw.subs {
+		send := batch
+		if st.overflowed {
+			send = []FileChange{{Op: ChangeRescan}}
+		}
+		select {
+		case ch <- send:
+			st.overflowed = false
+		default:
+			st.overflowed = true
+		}
+	}
+}
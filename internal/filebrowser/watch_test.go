@@ -0,0 +1,115 @@
+package filebrowser
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "existing.txt"), []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	b := New(slog.Default(), Options{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	batches := make(chan []FileChange, 16)
+	done := make(chan error, 1)
+	go func() {
+		done <- b.Watch(ctx, dir, func(changes []FileChange) error {
+			batches <- changes
+			return nil
+		})
+	}()
+
+	// collect merges batches until want paths have all been reported.
+	collect := func(want map[string]string) {
+		t.Helper()
+		got := make(map[string]string)
+		for len(got) < len(want) {
+			select {
+			case batch := <-batches:
+				for _, c := range batch {
+					got[c.Path] = c.Op
+				}
+			case <-ctx.Done():
+				t.Fatalf("timed out: got %v, want %v", got, want)
+			}
+		}
+		for p, op := range want {
+			if got[p] != op {
+				t.Errorf("change %s = %q, want %q (all: %v)", p, got[p], op, got)
+			}
+		}
+	}
+
+	// The first callback is the empty "watch established" batch.
+	select {
+	case batch := <-batches:
+		if len(batch) != 0 {
+			t.Fatalf("first batch = %v, want empty", batch)
+		}
+	case <-ctx.Done():
+		t.Fatal("watch never established")
+	}
+
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "new.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Created and removed within the debounce window: never reported.
+	tmp := filepath.Join(dir, "tmp.swp")
+	if err := os.WriteFile(tmp, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(tmp); err != nil {
+		t.Fatal(err)
+	}
+	collect(map[string]string{"sub": ChangeCreated, "new.txt": ChangeCreated})
+
+	// The new subdirectory is watched too.
+	if err := os.WriteFile(filepath.Join(dir, "sub", "inner.txt"), []byte("y"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "existing.txt"), []byte("b"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "new.txt")); err != nil {
+		t.Fatal(err)
+	}
+	collect(map[string]string{
+		"sub/inner.txt": ChangeCreated,
+		"existing.txt":  ChangeModified,
+		"new.txt":       ChangeDeleted,
+	})
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Watch returned %v, want context.Canceled", err)
+	}
+	if n := len(b.watches.dirs); n != 0 {
+		t.Errorf("hub still holds %d watches after the last subscriber left", n)
+	}
+}
+
+func TestWatchRejectsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "f.txt")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err := New(slog.Default(), Options{}).Watch(context.Background(), path, func([]FileChange) error {
+		t.Fatal("fn called for a file")
+		return nil
+	})
+	if err == nil {
+		t.Fatal("expected error for a file")
+	}
+}
@@ -0,0 +1,180 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Sentinel errors for the staging / commit operations. Callers map them
+// to client errors; anything else is a git failure.
+var (
+	ErrInvalidPath     = errors.New("invalid path")
+	ErrEmptyMessage    = errors.New("commit message is required")
+	ErrNothingToCommit = errors.New("nothing to commit")
+)
+
+// Stage adds the given paths (files or directories, relative to workDir)
+// to the index. Deleted files are staged as deletions.
+func (m *Manager) Stage(workDir string, paths []string) error {
+	paths, err := cleanPaths(workDir, paths)
+	if err != nil {
+		return err
+	}
+	_, err = m.run(workDir, append([]string{"add", "-A", "--"}, paths...)...)
+	return err
+}
+
+// Unstage removes the given paths from the index, leaving the working
+// tree untouched. Before the first commit there is no HEAD to reset
+// to, so the paths are dropped from the index instead.
+func (m *Manager) Unstage(workDir string, paths []string) error {
+	paths, err := cleanPaths(workDir, paths)
+	if err != nil {
+		return err
+	}
+	if m.hasHead(workDir) {
+		_, err = m.run(workDir, append([]string{"reset", "-q", "HEAD", "--"}, paths...)...)
+	} else {
+		_, err = m.run(workDir, append([]string{"rm", "-r", "-q", "--cached", "--ignore-unmatch", "--"}, paths...)...)
+	}
+	return err
+}
+
+// Discard throws away unstaged changes to the given paths: tracked files
+// are restored from the index and untracked files under them are
+// deleted. Staged changes are kept. This cannot be undone.
+func (m *Manager) Discard(workDir string, paths []string) error {
+	paths, err := cleanPaths(workDir, paths)
+	if err != nil {
+		return err
+	}
+	tracked, err := m.run(workDir, append([]string{"ls-files", "-z", "--"}, paths...)...)
+	if err != nil {
+		return err
+	}
+	// Restore only the tracked files: checkout rejects a pathspec that
+	// matches nothing in the index (a purely untracked path).
+	if tracked != "" {
+		files := strings.Split(strings.TrimSuffix(tracked, "\x00"), "\x00")
+		if _, err := m.run(workDir, append([]string{"checkout", "-q", "--"}, files...)...); err != nil {
+			return err
+		}
+	}
+	// -d covers untracked directories; ignored files are left alone.
+	_, err = m.run(workDir, append([]string{"clean", "-f", "-d", "-q", "--"}, paths...)...)
+	return err
+}
+
+type CommitResult struct {
+	Hash    string `json:"hash"`
+	Message string `json:"message"` // subject line
+}
+
+// Commit records the index as a new commit. With amend it replaces the
+// HEAD commit instead; an empty message then keeps HEAD's message.
+func (m *Manager) Commit(workDir, message string, amend bool) (*CommitResult, error) {
+	if workDir == "" {
+		return nil, errors.New("workDir is required")
+	}
+	message = strings.TrimSpace(message)
+	if message == "" && !amend {
+		return nil, ErrEmptyMessage
+	}
+	if amend && !m.hasHead(workDir) {
+		return nil, fmt.Errorf("%w: no commit to amend", ErrNothingToCommit)
+	}
+	if !amend {
+		staged, err := m.hasStagedChanges(workDir)
+		if err != nil {
+			return nil, err
+		}
+		if !staged {
+			return nil, ErrNothingToCommit
+		}
+	}
+
+	args := []string{"commit", "-q"}
+	if amend {
+		args = append(args, "--amend")
+		if message == "" {
+			args = append(args, "--no-edit")
+		}
+	}
+	if message != "" {
+		args = append(args, "-m", message)
+	}
+	if _, err := m.run(workDir, args...); err != nil {
+		return nil, err
+	}
+
+	out, err := m.run(workDir, "log", "-1", "--format=%H%n%s")
+	if err != nil {
+		return nil, err
+	}
+	hash, subject, _ := strings.Cut(strings.TrimSpace(out), "\n")
+	return &CommitResult{Hash: hash, Message: subject}, nil
+}
+
+func (m *Manager) hasHead(workDir string) bool {
+	_, err := m.run(workDir, "rev-parse", "-q", "--verify", "HEAD")
+	return err == nil
+}
+
+// hasStagedChanges reports whether the index differs from HEAD (or, on
+// an unborn branch, whether anything is staged at all).
+func (m *Manager) hasStagedChanges(workDir string) (bool, error) {
+	if !m.hasHead(workDir) {
+		out, err := m.run(workDir, "ls-files", "--cached")
+		if err != nil {
+			return false, err
+		}
+		return strings.TrimSpace(out) != "", nil
+	}
+	cmd := exec.Command("git", "diff", "--cached", "--quiet")
+	cmd.Dir = workDir
+	err := cmd.Run()
+	if err == nil {
+		return false, nil
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		return true, nil
+	}
+	return false, fmt.Errorf("git diff: %w", err)
+}
+
+// cleanPaths validates pathspecs for the mutating operations: at least
+// one, each inside workDir, and none that git would read as an option
+// or pathspec magic. Absolute paths are made relative to workDir.
+func cleanPaths(workDir string, paths []string) ([]string, error) {
+	if workDir == "" {
+		return nil, errors.New("workDir is required")
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("%w: at least one path is required", ErrInvalidPath)
+	}
+	out := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if p == "" || strings.ContainsRune(p, 0) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPath, p)
+		}
+		if strings.HasPrefix(p, ":") {
+			return nil, fmt.Errorf("%w: pathspec magic is not allowed: %s", ErrInvalidPath, p)
+		}
+		rel := p
+		if filepath.IsAbs(p) {
+			var err error
+			if rel, err = filepath.Rel(workDir, p); err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidPath, p)
+			}
+		}
+		rel = filepath.Clean(rel)
+		if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel) {
+			return nil, fmt.Errorf("%w: %s is outside the work tree", ErrInvalidPath, p)
+		}
+		out = append(out, filepath.ToSlash(rel))
+	}
+	return out, nil
+}
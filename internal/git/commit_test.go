@@ -0,0 +1,90 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStageCommitAmend(t *testing.T) {
+	r := newTestRepo(t)
+	m := New(Options{})
+
+	if _, err := m.Commit(r.dir, "empty", false); !errors.Is(err, ErrNothingToCommit) {
+		t.Fatalf("commit on empty index: %v, want ErrNothingToCommit", err)
+	}
+
+	r.write("a.txt", "a\n")
+	r.write("b.txt", "b\n")
+	if err := m.Stage(r.dir, []string{"a.txt", filepath.Join(r.dir, "b.txt")}); err != nil {
+		t.Fatalf("Stage: %v", err)
+	}
+	if err := m.Unstage(r.dir, []string{"b.txt"}); err != nil {
+		t.Fatalf("Unstage before first commit: %v", err)
+	}
+	if _, err := m.Commit(r.dir, "  ", false); !errors.Is(err, ErrEmptyMessage) {
+		t.Fatalf("blank message: %v, want ErrEmptyMessage", err)
+	}
+	res, err := m.Commit(r.dir, "first", false)
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if res.Message != "first" || len(res.Hash) != 40 {
+		t.Errorf("commit result = %+v", res)
+	}
+	if files := r.git("show", "--name-only", "--format=", "HEAD"); strings.TrimSpace(files) != "a.txt" {
+		t.Errorf("committed files = %q, want a.txt only", files)
+	}
+
+	if err := m.Stage(r.dir, []string{"b.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	amended, err := m.Commit(r.dir, "", true)
+	if err != nil {
+		t.Fatalf("amend: %v", err)
+	}
+	if amended.Message != "first" || amended.Hash == res.Hash {
+		t.Errorf("amend result = %+v (original %s)", amended, res.Hash)
+	}
+	if n := strings.TrimSpace(r.git("rev-list", "--count", "HEAD")); n != "1" {
+		t.Errorf("commit count after amend = %s, want 1", n)
+	}
+}
+
+func TestDiscard(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("tracked.txt", "orig\n")
+	r.git("add", ".")
+	r.git("commit", "-qm", "init")
+
+	r.write("tracked.txt", "changed\n")
+	r.write("scratch.txt", "tmp\n")
+	m := New(Options{})
+	if err := m.Discard(r.dir, []string{"tracked.txt", "scratch.txt"}); err != nil {
+		t.Fatalf("Discard: %v", err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(r.dir, "tracked.txt")); string(b) != "orig\n" {
+		t.Errorf("tracked.txt = %q, want restored", b)
+	}
+	if _, err := os.Stat(filepath.Join(r.dir, "scratch.txt")); !os.IsNotExist(err) {
+		t.Errorf("untracked scratch.txt not removed: %v", err)
+	}
+}
+
+func TestCleanPathsRejectsEscapes(t *testing.T) {
+	dir := t.TempDir()
+	for _, p := range []string{"../x", "/etc/passwd", ":(top)x", ""} {
+		if _, err := cleanPaths(dir, []string{p}); !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("cleanPaths(%q) = %v, want ErrInvalidPath", p, err)
+		}
+	}
+	if _, err := cleanPaths(dir, nil); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("no paths: %v, want ErrInvalidPath", err)
+	}
+	got, err := cleanPaths(dir, []string{"sub/../a.txt", "-n"})
+	if err != nil || got[0] != "a.txt" || got[1] != "-n" {
+		t.Errorf("cleanPaths = %v, %v", got, err)
+	}
+}
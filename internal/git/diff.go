@@ -0,0 +1,437 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DiffOptions selects what StructuredDiff compares.
+type DiffOptions struct {
+	// Ref, when set, is a commit hash whose changes against its first
+	// parent are shown (like `git show`). Staged is ignored then.
+	Ref string
+	// Staged diffs the index against HEAD (`git diff --staged`)
+	// instead of the working tree against the index.
+	Staged bool
+	// Base, when set, is a commit hash the working tree is diffed
+	// against (`git diff <base>`), with untracked files shown as
+	// added. Ref and Staged are ignored then.
+	Base string
+	// Paths limits the diff to these pathspecs (relative to workDir).
+	Paths []string
+}
+
+// Diff line types reported in DiffLine.Type.
+const (
+	LineContext = "context"
+	LineAdd     = "add"
+	LineDelete  = "delete"
+)
+
+// File statuses reported in DiffFile.Status.
+const (
+	FileAdded    = "added"
+	FileDeleted  = "deleted"
+	FileModified = "modified"
+	FileRenamed  = "renamed"
+	FileCopied   = "copied"
+)
+
+type DiffLine struct {
+	Type    string `json:"type"`
+	Content string `json:"content"` // without the leading +/-/space marker
+	// OldLine / NewLine are 1-based line numbers on each side; 0 when
+	// the line doesn't exist on that side.
+	OldLine int `json:"oldLine,omitempty"`
+	NewLine int `json:"newLine,omitempty"`
+	// NoNewline marks the last line of a side that lacks a trailing
+	// newline ("\ No newline at end of file").
+	NoNewline bool `json:"noNewline,omitempty"`
+}
+
+type DiffHunk struct {
+	OldStart int `json:"oldStart"`
+	OldLines int `json:"oldLines"`
+	NewStart int `json:"newStart"`
+	NewLines int `json:"newLines"`
+	// Header is the function/section context git prints after the
+	// second @@, if any.
+	Header string     `json:"header,omitempty"`
+	Lines  []DiffLine `json:"lines"`
+}
+
+type DiffFile struct {
+	OldPath string `json:"oldPath"` // "" for added files
+	NewPath string `json:"newPath"` // "" for deleted files
+	Status  string `json:"status"`
+	// Similarity is the rename/copy similarity index (0-100).
+	Similarity int        `json:"similarity,omitempty"`
+	Binary     bool       `json:"binary,omitempty"`
+	OldMode    string     `json:"oldMode,omitempty"`
+	NewMode    string     `json:"newMode,omitempty"`
+	Additions  int        `json:"additions"`
+	Deletions  int        `json:"deletions"`
+	Hunks      []DiffHunk `json:"hunks"`
+}
+
+type StructuredDiffResult struct {
+	Files     []DiffFile `json:"files"`
+	Additions int        `json:"additions"`
+	Deletions int        `json:"deletions"`
+}
+
+// StructuredDiff runs git diff with rename detection and parses the
+// output into files, hunks and typed lines.
+func (m *Manager) StructuredDiff(workDir string, opts DiffOptions) (*StructuredDiffResult, error) {
+	if workDir == "" {
+		return nil, errors.New("workDir is required")
+	}
+	if opts.Ref != "" && !isHexString(opts.Ref) {
+		return nil, fmt.Errorf("invalid ref: %s", opts.Ref)
+	}
+	if opts.Base != "" && !isHexString(opts.Base) {
+		return nil, fmt.Errorf("invalid base: %s", opts.Base)
+	}
+
+	// core.quotePath=false keeps non-ASCII paths readable; paths with
+	// quotes or control characters are still C-quoted and unquoted by
+	// the parser.
+	args := []string{"-c", "core.quotePath=false"}
+	switch {
+	case opts.Base != "":
+		args = append(args, "diff", opts.Base)
+	case opts.Ref != "":
+		// -m --first-parent: a merge is shown against its first parent
+		// as a plain diff, not the combined (@@@) format.
+		args = append(args, "show", "--format=", "-m", "--first-parent", opts.Ref)
+	default:
+		args = append(args, "diff")
+		if opts.Staged {
+			args = append(args, "--staged")
+		}
+	}
+	args = append(args, "-M", "--no-color", "--no-ext-diff", "--")
+	args = append(args, opts.Paths...)
+
+	out, err := m.run(workDir, args...)
+	if err != nil {
+		return nil, err
+	}
+	result, err := ParseDiff(out)
+	if err != nil || opts.Base == "" {
+		return result, err
+	}
+	untracked, err := m.untrackedFiles(workDir, opts.Paths)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range untracked {
+		result.Additions += f.Additions
+		result.Files = append(result.Files, f)
+	}
+	return result, nil
+}
+
+// maxUntrackedFiles and maxUntrackedSize bound how much of untracked
+// files a Base diff reads; past them a file is listed without lines.
+const (
+	maxUntrackedFiles = 200
+	maxUntrackedSize  = 256 << 10
+)
+
+// untrackedFiles returns the files git doesn't track and doesn't
+// ignore as added DiffFiles, paths relative to the repository root like
+// git diff's.
+func (m *Manager) untrackedFiles(workDir string, paths []string) ([]DiffFile, error) {
+	top, err := m.run(workDir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	top = strings.TrimSpace(top)
+	if len(paths) == 0 {
+		paths = []string{":/"} // the whole repository, not just workDir
+	}
+	out, err := m.run(workDir, append([]string{"ls-files", "--others", "--exclude-standard", "--full-name", "-z", "--"}, paths...)...)
+	if err != nil {
+		return nil, err
+	}
+	var files []DiffFile
+	for _, p := range strings.Split(out, "\x00") {
+		if p == "" {
+			continue
+		}
+		f := DiffFile{NewPath: p, Status: FileAdded, Hunks: []DiffHunk{}}
+		if len(files) < maxUntrackedFiles {
+			addFileLines(&f, filepath.Join(top, filepath.FromSlash(p)))
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// addFileLines fills in f's one hunk with path's lines, all added,
+// or marks it binary. Files over maxUntrackedSize are left alone.
+func addFileLines(f *DiffFile, path string) {
+	info, err := os.Lstat(path)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > maxUntrackedSize {
+		return
+	}
+	content, err := os.ReadFile(path)
+	if err != nil || len(content) == 0 {
+		return
+	}
+	if bytes.IndexByte(content[:min(len(content), 8000)], 0) >= 0 {
+		f.Binary = true
+		return
+	}
+	text := string(content)
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	h := DiffHunk{NewStart: 1, NewLines: len(lines), Lines: make([]DiffLine, len(lines))}
+	for i, l := range lines {
+		h.Lines[i] = DiffLine{Type: LineAdd, Content: l, NewLine: i + 1}
+	}
+	h.Lines[len(lines)-1].NoNewline = !strings.HasSuffix(text, "\n")
+	f.Hunks = []DiffHunk{h}
+	f.Additions = len(lines)
+}
+
+// ParseDiff parses `git diff` unified output (with a/ b/ prefixes).
+func ParseDiff(diff string) (*StructuredDiffResult, error) {
+	result := &StructuredDiffResult{Files: []DiffFile{}}
+	var (
+		file             *DiffFile
+		hunk             *DiffHunk
+		oldNo, newNo     int
+		oldLeft, newLeft int
+	)
+	finish := func() {
+		if file == nil {
+			return
+		}
+		if file.Status == "" {
+			file.Status = FileModified
+		}
+		result.Additions += file.Additions
+		result.Deletions += file.Deletions
+		result.Files = append(result.Files, *file)
+		file, hunk = nil, nil
+	}
+
+	sc := bufio.NewScanner(strings.NewReader(diff))
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+
+		// Inside a hunk every line is content until the counts run out.
+		if hunk != nil && (oldLeft > 0 || newLeft > 0) {
+			if line == "" {
+				// Some tools strip the trailing space of empty context lines.
+				line = " "
+			}
+			dl := DiffLine{Content: line[1:]}
+			switch line[0] {
+			case ' ':
+				dl.Type, dl.OldLine, dl.NewLine = LineContext, oldNo, newNo
+				oldNo++
+				newNo++
+				oldLeft--
+				newLeft--
+			case '-':
+				dl.Type, dl.OldLine = LineDelete, oldNo
+				oldNo++
+				oldLeft--
+				file.Deletions++
+			case '+':
+				dl.Type, dl.NewLine = LineAdd, newNo
+				newNo++
+				newLeft--
+				file.Additions++
+			case '\\':
+				markNoNewline(hunk)
+				continue
+			default:
+				return nil, fmt.Errorf("malformed diff: unexpected hunk line %q", line)
+			}
+			hunk.Lines = append(hunk.Lines, dl)
+			continue
+		}
+		if strings.HasPrefix(line, `\`) && hunk != nil {
+			markNoNewline(hunk)
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			finish()
+			file = &DiffFile{Hunks: []DiffHunk{}}
+			file.OldPath, file.NewPath = splitDiffGitPaths(strings.TrimPrefix(line, "diff --git "))
+		case file == nil:
+			// Preamble (e.g. commit header): ignore.
+		case strings.HasPrefix(line, "@@ "):
+			h, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, err
+			}
+			file.Hunks = append(file.Hunks, h)
+			hunk = &file.Hunks[len(file.Hunks)-1]
+			oldNo, newNo = h.OldStart, h.NewStart
+			oldLeft, newLeft = h.OldLines, h.NewLines
+		case strings.HasPrefix(line, "--- "):
+			if p := headerPath(line[4:]); p != "" {
+				file.OldPath = p
+			}
+		case strings.HasPrefix(line, "+++ "):
+			if p := headerPath(line[4:]); p != "" {
+				file.NewPath = p
+			}
+		case strings.HasPrefix(line, "new file mode "):
+			file.Status = FileAdded
+			file.NewMode = strings.TrimPrefix(line, "new file mode ")
+		case strings.HasPrefix(line, "deleted file mode "):
+			file.Status = FileDeleted
+			file.OldMode = strings.TrimPrefix(line, "deleted file mode ")
+		case strings.HasPrefix(line, "old mode "):
+			file.OldMode = strings.TrimPrefix(line, "old mode ")
+		case strings.HasPrefix(line, "new mode "):
+			file.NewMode = strings.TrimPrefix(line, "new mode ")
+		case strings.HasPrefix(line, "rename from "):
+			file.Status = FileRenamed
+			file.OldPath = unquotePath(strings.TrimPrefix(line, "rename from "))
+		case strings.HasPrefix(line, "rename to "):
+			file.NewPath = unquotePath(strings.TrimPrefix(line, "rename to "))
+		case strings.HasPrefix(line, "copy from "):
+			file.Status = FileCopied
+			file.OldPath = unquotePath(strings.TrimPrefix(line, "copy from "))
+		case strings.HasPrefix(line, "copy to "):
+			file.NewPath = unquotePath(strings.TrimPrefix(line, "copy to "))
+		case strings.HasPrefix(line, "similarity index "):
+			file.Similarity, _ = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(line, "similarity index "), "%"))
+		case strings.HasPrefix(line, "Binary files "), line == "GIT binary patch":
+			file.Binary = true
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read diff: %w", err)
+	}
+	finish()
+
+	for i := range result.Files {
+		f := &result.Files[i]
+		switch f.Status {
+		case FileAdded:
+			f.OldPath = ""
+		case FileDeleted:
+			f.NewPath = ""
+		}
+	}
+	return result, nil
+}
+
+// markNoNewline flags the hunk's last line as lacking a trailing newline.
+func markNoNewline(h *DiffHunk) {
+	if n := len(h.Lines); n > 0 {
+		h.Lines[n-1].NoNewline = true
+	}
+}
+
+// parseHunkHeader parses "@@ -a,b +c,d @@ header". Omitted counts are 1.
+func parseHunkHeader(line string) (DiffHunk, error) {
+	rest := strings.TrimPrefix(line, "@@ ")
+	ranges, header, ok := strings.Cut(rest, " @@")
+	if !ok {
+		return DiffHunk{}, fmt.Errorf("malformed hunk header: %q", line)
+	}
+	oldR, newR, ok := strings.Cut(ranges, " ")
+	if !ok || !strings.HasPrefix(oldR, "-") || !strings.HasPrefix(newR, "+") {
+		return DiffHunk{}, fmt.Errorf("malformed hunk header: %q", line)
+	}
+	h := DiffHunk{Header: strings.TrimSpace(header), Lines: []DiffLine{}}
+	var err error
+	if h.OldStart, h.OldLines, err = parseRange(oldR[1:]); err != nil {
+		return DiffHunk{}, fmt.Errorf("malformed hunk header: %q", line)
+	}
+	if h.NewStart, h.NewLines, err = parseRange(newR[1:]); err != nil {
+		return DiffHunk{}, fmt.Errorf("malformed hunk header: %q", line)
+	}
+	return h, nil
+}
+
+func parseRange(s string) (start, count int, err error) {
+	startS, countS, hasCount := strings.Cut(s, ",")
+	if start, err = strconv.Atoi(startS); err != nil {
+		return 0, 0, err
+	}
+	count = 1
+	if hasCount {
+		if count, err = strconv.Atoi(countS); err != nil {
+			return 0, 0, err
+		}
+	}
+	return start, count, nil
+}
+
+// headerPath extracts the path from a ---/+++ header value, dropping the
+// a/ or b/ prefix. /dev/null yields "".
+func headerPath(s string) string {
+	// git appends a tab when the path contains a space.
+	s = strings.TrimSuffix(s, "\t")
+	s = unquotePath(s)
+	if s == "/dev/null" {
+		return ""
+	}
+	if strings.HasPrefix(s, "a/") || strings.HasPrefix(s, "b/") {
+		return s[2:]
+	}
+	return s
+}
+
+// splitDiffGitPaths splits the "a/old b/new" part of a diff --git line.
+// It is only authoritative when the paths are equal (mode-only changes,
+// binary files); rename and ---/+++ headers override it otherwise.
+func splitDiffGitPaths(s string) (oldPath, newPath string) {
+	if strings.HasPrefix(s, `"`) {
+		// Quoted: `"a/x y" "b/x y"` or mixed forms.
+		if end := closingQuote(s); end > 0 {
+			return headerPath(s[:end+1]), headerPath(strings.TrimSpace(s[end+1:]))
+		}
+	}
+	// Unquoted with equal names: "a/P b/P" — split in the middle.
+	if n := len(s); n%2 == 1 {
+		mid := n / 2
+		if s[mid] == ' ' && strings.HasPrefix(s, "a/") && s[2:mid] == strings.TrimPrefix(s[mid+1:], "b/") {
+			return s[2:mid], s[2:mid]
+		}
+	}
+	if i := strings.Index(s, " b/"); i > 0 {
+		return headerPath(s[:i]), headerPath(s[i+1:])
+	}
+	return s, s
+}
+
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// unquotePath undoes git's C-style quoting of unusual paths.
+func unquotePath(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		if u, err := strconv.Unquote(s); err == nil {
+			return u
+		}
+	}
+	return s
+}
@@ -0,0 +1,224 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sampleDiff = `diff --git a/main.go b/main.go
+index 83db48f..bf269f4 100644
+--- a/main.go
++++ b/main.go
+@@ -1,4 +1,4 @@ package main
+ package main
+ 
+-func old() {}
++func renamed() {}
+ // end
+\ No newline at end of file
+diff --git a/old name.txt b/new name.txt
+similarity index 90%
+rename from old name.txt
+rename to new name.txt
+index 1111111..2222222 100644
+--- a/old name.txt	
++++ b/new name.txt	
+@@ -1 +1,2 @@
+ same
++added
+diff --git a/gone.txt b/gone.txt
+deleted file mode 100644
+index 3333333..0000000
+--- a/gone.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+diff --git a/img.png b/img.png
+new file mode 100644
+index 0000000..4444444
+Binary files /dev/null and b/img.png differ
+diff --git a/run.sh b/run.sh
+old mode 100644
+new mode 100755
+`
+
+func TestParseDiff(t *testing.T) {
+	res, err := ParseDiff(sampleDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Files) != 5 {
+		t.Fatalf("files = %d, want 5", len(res.Files))
+	}
+	if res.Additions != 2 || res.Deletions != 2 {
+		t.Errorf("totals = +%d -%d, want +2 -2", res.Additions, res.Deletions)
+	}
+
+	mod := res.Files[0]
+	if mod.Status != FileModified || mod.OldPath != "main.go" || mod.NewPath != "main.go" || len(mod.Hunks) != 1 {
+		t.Fatalf("main.go = %+v", mod)
+	}
+	h := mod.Hunks[0]
+	if h.OldStart != 1 || h.OldLines != 4 || h.NewStart != 1 || h.NewLines != 4 || h.Header != "package main" {
+		t.Errorf("hunk header = %+v", h)
+	}
+	if len(h.Lines) != 5 {
+		t.Fatalf("hunk lines = %d, want 5", len(h.Lines))
+	}
+	if l := h.Lines[1]; l.Type != LineContext || l.Content != "" || l.OldLine != 2 || l.NewLine != 2 {
+		t.Errorf("blank context line = %+v", l)
+	}
+	if l := h.Lines[2]; l.Type != LineDelete || l.OldLine != 3 || l.NewLine != 0 {
+		t.Errorf("delete line = %+v", l)
+	}
+	if l := h.Lines[3]; l.Type != LineAdd || l.Content != "func renamed() {}" || l.NewLine != 3 {
+		t.Errorf("add line = %+v", l)
+	}
+	if l := h.Lines[4]; !l.NoNewline {
+		t.Errorf("last line should be flagged NoNewline: %+v", l)
+	}
+
+	ren := res.Files[1]
+	if ren.Status != FileRenamed || ren.OldPath != "old name.txt" || ren.NewPath != "new name.txt" || ren.Similarity != 90 || ren.Additions != 1 {
+		t.Errorf("rename = %+v", ren)
+	}
+	if del := res.Files[2]; del.Status != FileDeleted || del.OldPath != "gone.txt" || del.NewPath != "" || del.Deletions != 1 {
+		t.Errorf("delete = %+v", del)
+	}
+	if bin := res.Files[3]; bin.Status != FileAdded || !bin.Binary || bin.NewPath != "img.png" || bin.OldPath != "" {
+		t.Errorf("binary = %+v", bin)
+	}
+	if mode := res.Files[4]; mode.Status != FileModified || mode.NewPath != "run.sh" || mode.OldMode != "100644" || mode.NewMode != "100755" {
+		t.Errorf("mode change = %+v", mode)
+	}
+}
+
+func TestParseDiffQuotedPath(t *testing.T) {
+	res, err := ParseDiff("diff --git \"a/tab\\there\" \"b/tab\\there\"\nnew file mode 100644\nindex 0000000..e69de29\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Files) != 1 || res.Files[0].NewPath != "tab\there" {
+		t.Errorf("files = %+v", res.Files)
+	}
+}
+
+// testRepo is a throwaway repository for tests that shell out to git.
+type testRepo struct {
+	t   *testing.T
+	dir string
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	r := &testRepo{t: t, dir: t.TempDir()}
+	r.git("init", "-q")
+	// Manager runs git without the test env, so commits it makes need
+	// an identity in the repo config.
+	r.git("config", "user.name", "t")
+	r.git("config", "user.email", "t@example.com")
+	return r
+}
+
+func (r *testRepo) git(args ...string) string {
+	r.t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@example.com",
+		"GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@example.com")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return string(out)
+}
+
+func (r *testRepo) write(name, body string) {
+	r.t.Helper()
+	if err := os.WriteFile(filepath.Join(r.dir, name), []byte(body), 0o644); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+func TestStructuredDiffStagedAndRename(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("a.txt", "one\ntwo\nthree\nfour\nfive\n")
+	r.write("b.txt", "b\n")
+	r.git("add", ".")
+	r.git("commit", "-qm", "init")
+
+	r.git("mv", "a.txt", "moved.txt")
+	r.write("b.txt", "b\nmore\n")
+
+	m := New(Options{})
+	staged, err := m.StructuredDiff(r.dir, DiffOptions{Staged: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(staged.Files) != 1 || staged.Files[0].Status != FileRenamed || staged.Files[0].NewPath != "moved.txt" {
+		t.Errorf("staged = %+v", staged.Files)
+	}
+
+	unstaged, err := m.StructuredDiff(r.dir, DiffOptions{Paths: []string{"b.txt"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unstaged.Files) != 1 || unstaged.Files[0].NewPath != "b.txt" || unstaged.Additions != 1 {
+		t.Errorf("unstaged = %+v", unstaged.Files)
+	}
+
+	if _, err := m.StructuredDiff(r.dir, DiffOptions{Ref: "--output=x"}); err == nil {
+		t.Error("option-like ref accepted")
+	}
+}
+
+func TestStructuredDiffBase(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("a.txt", "one\n")
+	r.git("add", ".")
+	r.git("commit", "-qm", "init")
+	base := strings.TrimSpace(r.git("rev-parse", "HEAD"))
+
+	// Committed and uncommitted work since base both count.
+	r.write("a.txt", "one\ntwo\n")
+	r.git("commit", "-qam", "two")
+	r.write("a.txt", "one\ntwo\nthree\n")
+	if err := os.Mkdir(filepath.Join(r.dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	r.write("sub/new.txt", "x\ny")
+	r.write("sub/blob.bin", "\x00\x01")
+
+	m := New(Options{})
+	got, err := m.StructuredDiff(filepath.Join(r.dir, "sub"), DiffOptions{Base: base})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byPath := map[string]DiffFile{}
+	for _, f := range got.Files {
+		byPath[f.NewPath] = f
+	}
+	if len(got.Files) != 3 || got.Additions != 4 {
+		t.Fatalf("files = %+v, additions = %d", got.Files, got.Additions)
+	}
+	if f := byPath["a.txt"]; f.Additions != 2 {
+		t.Errorf("a.txt = %+v", f)
+	}
+	if f := byPath["sub/new.txt"]; f.Status != FileAdded || len(f.Hunks) != 1 || len(f.Hunks[0].Lines) != 2 || !f.Hunks[0].Lines[1].NoNewline {
+		t.Errorf("sub/new.txt = %+v", f)
+	}
+	if f := byPath["sub/blob.bin"]; !f.Binary {
+		t.Errorf("sub/blob.bin = %+v", f)
+	}
+
+	if _, err := m.StructuredDiff(r.dir, DiffOptions{Base: "HEAD~1"}); err == nil {
+		t.Error("non-hash base accepted")
+	}
+}
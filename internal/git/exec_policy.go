@@ -0,0 +1,231 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrExecDenied is returned by Exec when the policy rejects a command.
+var ErrExecDenied = errors.New("git command not allowed")
+
+// DefaultExecAllow is the subcommand allowlist used when Options leaves
+// ExecAllow empty: the routine flows the Git tab and agents drive.
+// Plumbing that runs external programs or rewrites history wholesale
+// (filter-branch, config, daemon, …) and user aliases are not on it.
+var DefaultExecAllow = []string{
+	"add", "blame", "branch", "checkout", "cherry-pick", "commit",
+	"describe", "diff", "fetch", "grep", "log", "ls-files", "merge",
+	"mv", "pull", "push", "rebase", "reflog", "remote", "reset",
+	"restore", "rev-parse", "revert", "rm", "shortlog", "show",
+	"stash", "status", "switch", "tag", "worktree",
+}
+
+// DefaultExecDeny blocks force pushes even though push is allowed.
+var DefaultExecDeny = []string{"push --force", "push -f", "push --mirror"}
+
+// execInjectionFlags make git run an arbitrary program or write to an
+// arbitrary file, whatever the subcommand. They are always rejected,
+// abbreviated too: git takes any unambiguous prefix of a long option.
+var execInjectionFlags = []string{
+	"--exec-path", "--upload-pack", "--receive-pack", "--exec",
+	"--config-env", "--output", "--git-dir", "--work-tree",
+	"--open-files-in-pager",
+}
+
+// execInjectionShort are the short forms of execInjectionFlags, by the
+// subcommands that take them.
+var execInjectionShort = map[string][]string{
+	"rebase": {"-x"}, // --exec
+	"grep":   {"-O"}, // --open-files-in-pager
+	"fetch":  {"-u"}, // --upload-pack
+	"pull":   {"-u"},
+}
+
+// execFlagAllow lists the only flags some subcommands run with, as
+// their options that run programs are too many, or too easily
+// abbreviated, to deny one by one. Long flags must be spelled out; a
+// value goes after "=" or in the next argument. Short flags may be
+// clustered; those in the subcommand's execFlagArgs take the rest of
+// the cluster as their value.
+var execFlagAllow = map[string][]string{
+	"rebase": {
+		"--continue", "--abort", "--skip", "--quit", "--onto", "--keep-base",
+		"--autostash", "--no-autostash", "--autosquash", "--no-autosquash",
+		"--update-refs", "--no-update-refs", "--rebase-merges", "--no-rebase-merges",
+		"--root", "--fork-point", "--no-fork-point", "--signoff",
+		"--committer-date-is-author-date", "--reset-author-date",
+		"--keep-empty", "--no-keep-empty", "--empty", "--stat", "--no-stat",
+		"--quiet", "--verbose", "--strategy", "--strategy-option",
+		"--force-rebase", "--no-ff", "--apply", "--merge", "--ignore-whitespace",
+		"--whitespace", "--no-verify", "--verify",
+		"-q", "-v", "-n", "-m", "-f", "-r", "-s", "-X",
+	},
+	"grep": {
+		"--ignore-case", "--word-regexp", "--invert-match", "--full-name",
+		"--extended-regexp", "--basic-regexp", "--fixed-strings", "--perl-regexp",
+		"--line-number", "--column", "--files-with-matches", "--name-only",
+		"--files-without-match", "--null", "--only-matching", "--count",
+		"--color", "--no-color", "--break", "--heading", "--show-function",
+		"--function-context", "--after-context", "--before-context", "--context",
+		"--and", "--or", "--not", "--all-match", "--quiet", "--cached",
+		"--untracked", "--no-index", "--recurse-submodules", "--text",
+		"--textconv", "--no-textconv", "--recursive", "--no-recursive",
+		"--max-depth", "--max-count", "--threads", "--exclude-standard",
+		"--no-exclude-standard",
+		"-i", "-I", "-w", "-v", "-h", "-H", "-E", "-G", "-F", "-P", "-n", "-l",
+		"-L", "-z", "-o", "-c", "-p", "-W", "-q", "-a", "-r",
+		"-A", "-B", "-C", "-e", "-f", "-m",
+	},
+}
+
+// execFlagArgs are the short flags in execFlagAllow that take a value,
+// by subcommand: rebase -f and -m are switches where grep's take one.
+var execFlagArgs = map[string]map[string]bool{
+	"rebase": {"-s": true, "-X": true},
+	"grep":   {"-A": true, "-B": true, "-C": true, "-e": true, "-f": true, "-m": true},
+}
+
+// execPolicy decides which `git <args>` invocations Exec runs.
+type execPolicy struct {
+	allowAll bool
+	allow    map[string]bool
+	// deny maps a subcommand to denied flags; a nil slice denies the
+	// subcommand outright.
+	deny map[string][]string
+}
+
+// newExecPolicy builds a policy from allow ("*" = any subcommand) and
+// deny rules. A deny rule is "<subcommand>" or "<subcommand> <flag>".
+func newExecPolicy(allow, deny []string) execPolicy {
+	if len(allow) == 0 {
+		allow = DefaultExecAllow
+	}
+	if deny == nil {
+		deny = DefaultExecDeny
+	}
+	p := execPolicy{allow: map[string]bool{}, deny: map[string][]string{}}
+	for _, a := range allow {
+		a = strings.TrimSpace(a)
+		if a == "*" {
+			p.allowAll = true
+		} else if a != "" {
+			p.allow[a] = true
+		}
+	}
+	for _, d := range deny {
+		sub, flag, hasFlag := strings.Cut(strings.TrimSpace(d), " ")
+		if sub == "" {
+			continue
+		}
+		if !hasFlag {
+			p.deny[sub] = nil
+			continue
+		}
+		if flags, ok := p.deny[sub]; ok && flags == nil {
+			continue // already denied outright
+		}
+		p.deny[sub] = append(p.deny[sub], strings.TrimSpace(flag))
+	}
+	return p
+}
+
+// check returns an ErrExecDenied-wrapped reason when args may not run.
+func (p execPolicy) check(args []string) error {
+	sub := args[0]
+	// Global options (-c, -C, --exec-path, --git-dir, …) go before the
+	// subcommand and can redirect git anywhere; require the subcommand
+	// first.
+	if strings.HasPrefix(sub, "-") {
+		return fmt.Errorf("%w: global option %s", ErrExecDenied, sub)
+	}
+	if !p.allowAll && !p.allow[sub] {
+		return fmt.Errorf("%w: subcommand %q", ErrExecDenied, sub)
+	}
+	flags, denied := p.deny[sub]
+	if denied && flags == nil {
+		return fmt.Errorf("%w: subcommand %q", ErrExecDenied, sub)
+	}
+	for _, a := range args[1:] {
+		if a == "--" {
+			break
+		}
+		if name, _, _ := strings.Cut(a, "="); strings.HasPrefix(name, "--") && len(name) > 2 {
+			for _, f := range execInjectionFlags {
+				if strings.HasPrefix(f, name) {
+					return fmt.Errorf("%w: flag %s", ErrExecDenied, f)
+				}
+			}
+		}
+		for _, f := range execInjectionShort[sub] {
+			if flagMatches(a, f) {
+				return fmt.Errorf("%w: %s %s", ErrExecDenied, sub, f)
+			}
+		}
+		if allowed, ok := execFlagAllow[sub]; ok && !flagAllowed(a, allowed, execFlagArgs[sub]) {
+			return fmt.Errorf("%w: %s flag %s", ErrExecDenied, sub, a)
+		}
+		for _, f := range flags {
+			if flagMatches(a, f) {
+				return fmt.Errorf("%w: %s %s", ErrExecDenied, sub, f)
+			}
+		}
+		// `push origin +main` is a force push without the flag.
+		if sub == "push" && strings.HasPrefix(a, "+") && p.deniesFlag("push", "--force") {
+			return fmt.Errorf("%w: push +refspec (force)", ErrExecDenied)
+		}
+	}
+	return nil
+}
+
+// flagAllowed reports whether arg is an operand or uses only flags in
+// allowed, of which those in takesArg end a cluster; see execFlagAllow.
+func flagAllowed(arg string, allowed []string, takesArg map[string]bool) bool {
+	if !strings.HasPrefix(arg, "-") || arg == "-" {
+		return true
+	}
+	if strings.HasPrefix(arg, "--") {
+		name, _, _ := strings.Cut(arg, "=")
+		return slices.Contains(allowed, name)
+	}
+	for _, c := range arg[1:] {
+		f := "-" + string(c)
+		if c >= '0' && c <= '9' && slices.Contains(allowed, "-C") {
+			continue // grep -3: context lines
+		}
+		if !slices.Contains(allowed, f) {
+			return false
+		}
+		if takesArg[f] {
+			return true
+		}
+	}
+	return true
+}
+
+func (p execPolicy) deniesFlag(sub, flag string) bool {
+	for _, f := range p.deny[sub] {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// flagMatches reports whether arg uses flag. Long flags match by prefix
+// so "--force" also covers --force-with-lease, and arg matches as an
+// abbreviation ("--mirr" is --mirror): git takes any unambiguous prefix
+// and refuses an ambiguous one, so denying every prefix lets nothing
+// through that git would run. A short flag matches inside a cluster
+// ("-fu" uses -f).
+func flagMatches(arg, flag string) bool {
+	if strings.HasPrefix(flag, "--") {
+		name, _, _ := strings.Cut(arg, "=")
+		return strings.HasPrefix(arg, flag) || len(name) > 2 && strings.HasPrefix(flag, name)
+	}
+	if len(flag) == 2 && flag[0] == '-' && len(arg) >= 2 && arg[0] == '-' && arg[1] != '-' {
+		return strings.ContainsRune(arg[1:], rune(flag[1]))
+	}
+	return arg == flag
+}
@@ -0,0 +1,86 @@
+package git
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExecPolicyDefaults(t *testing.T) {
+	p := newExecPolicy(nil, nil)
+	allowed := [][]string{
+		{"status"},
+		{"push", "origin", "main"},
+		{"commit", "-m", "msg"},
+		{"checkout", "--", "--output"}, // after --, a path not a flag
+		{"rebase", "--onto", "main", "topic"},
+		{"rebase", "-Xours", "main"},
+		{"rebase", "-fm", "main"},
+		{"grep", "-n3i", "-e", "foo", "--", "*.go"},
+		{"fetch", "--unshallow", "origin"},
+	}
+	for _, args := range allowed {
+		if err := p.check(args); err != nil {
+			t.Errorf("check(%q) = %v, want allowed", args, err)
+		}
+	}
+	denied := [][]string{
+		{"-c", "core.pager=sh", "log"},
+		{"--exec-path=/tmp", "status"},
+		{"config", "alias.x", "!sh"},
+		{"my-alias"},
+		{"push", "--force"},
+		{"push", "--force-with-lease"},
+		{"push", "--forc"},
+		{"push", "--mirr", "origin"},
+		{"push", "-fu", "origin"},
+		{"push", "origin", "+main"},
+		{"fetch", "--upload-pack=evil", "origin"},
+		{"log", "--output=/etc/passwd"},
+		{"fetch", "--upload-pac=evil", "origin"},
+		{"fetch", "-u", "evil", "origin"},
+		{"pull", "-uevil"},
+		{"rebase", "-x", "sh", "main"},
+		{"rebase", "--exe=sh", "main"},
+		{"rebase", "-ix", "sh", "main"},
+		{"rebase", "--interactive", "main"},
+		{"rebase", "-fi", "main"},
+		{"rebase", "-mi", "main"},
+		{"grep", "-Oevil", "foo"},
+		{"grep", "-nO", "evil", "foo"},
+		{"grep", "--open-files-in-pager=evil", "foo"},
+		{"grep", "--open=evil", "foo"},
+	}
+	for _, args := range denied {
+		if err := p.check(args); !errors.Is(err, ErrExecDenied) {
+			t.Errorf("check(%q) = %v, want ErrExecDenied", args, err)
+		}
+	}
+}
+
+func TestExecPolicyConfigured(t *testing.T) {
+	p := newExecPolicy([]string{"*"}, []string{"rebase", "reset --hard"})
+	if err := p.check([]string{"gc"}); err != nil {
+		t.Errorf("wildcard allow rejected gc: %v", err)
+	}
+	if err := p.check([]string{"rebase", "main"}); !errors.Is(err, ErrExecDenied) {
+		t.Errorf("denied subcommand ran: %v", err)
+	}
+	if err := p.check([]string{"reset", "--hard", "HEAD~1"}); !errors.Is(err, ErrExecDenied) {
+		t.Errorf("denied flag ran: %v", err)
+	}
+	if err := p.check([]string{"reset", "--soft", "HEAD~1"}); err != nil {
+		t.Errorf("reset --soft rejected: %v", err)
+	}
+	// An explicit empty deny list allows force pushes.
+	if err := newExecPolicy(nil, []string{}).check([]string{"push", "-f"}); err != nil {
+		t.Errorf("empty deny list still blocked push -f: %v", err)
+	}
+}
+
+func TestExecDeniedBeforeRunning(t *testing.T) {
+	_, err := New(Options{}).Exec(t.TempDir(), []string{"-C", "/", "status"})
+	if !errors.Is(err, ErrExecDenied) || !strings.Contains(err.Error(), "-C") {
+		t.Errorf("Exec = %v, want ErrExecDenied naming -C", err)
+	}
+}
@@ -0,0 +1,367 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ghTimeout bounds one gh invocation; every call goes to the GitHub API.
+const ghTimeout = 60 * time.Second
+
+// ErrGHUnavailable is returned when the gh CLI is not installed.
+var ErrGHUnavailable = errors.New("gh CLI not found; install GitHub CLI and run `gh auth login`")
+
+// ghPRFields are the `gh pr --json` fields decoded into PullRequest.
+const ghPRFields = "number,title,state,isDraft,url,headRefName,baseRefName,author,updatedAt"
+
+type PullRequest struct {
+	Number    int    `json:"number"`
+	Title     string `json:"title"`
+	State     string `json:"state"`
+	IsDraft   bool   `json:"isDraft"`
+	URL       string `json:"url"`
+	Head      string `json:"headRefName"`
+	Base      string `json:"baseRefName"`
+	Author    string `json:"author"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+// ghPR mirrors gh's JSON, where author is an object.
+type ghPR struct {
+	PullRequest
+	Author struct {
+		Login string `json:"login"`
+	} `json:"author"`
+}
+
+func (p ghPR) toPullRequest() PullRequest {
+	pr := p.PullRequest
+	pr.Author = p.Author.Login
+	return pr
+}
+
+// PullRequests lists the open pull requests of the repository
+// containing workDir.
+func (m *Manager) PullRequests(ctx context.Context, workDir string) ([]PullRequest, error) {
+	out, err := m.runGH(ctx, workDir, "pr", "list", "--state", "open", "--limit", "100", "--json", ghPRFields)
+	if err != nil {
+		return nil, err
+	}
+	var raw []ghPR
+	if err := json.Unmarshal(out, &raw); err != nil {
+		return nil, fmt.Errorf("parse gh output: %w", err)
+	}
+	prs := make([]PullRequest, len(raw))
+	for i, p := range raw {
+		prs[i] = p.toPullRequest()
+	}
+	return prs, nil
+}
+
+// CreatePROptions describes a pull request to open from the current
+// branch.
+type CreatePROptions struct {
+	Title string
+	Body  string
+	// Base is the target branch; empty uses the repository default.
+	Base  string
+	Draft bool
+}
+
+// CreatePullRequest pushes the current branch (setting its upstream on
+// origin when it has none) and opens a pull request for it. An empty
+// Title fills title and body from the branch's commits.
+func (m *Manager) CreatePullRequest(ctx context.Context, workDir string, opts CreatePROptions) (*PullRequest, error) {
+	if workDir == "" {
+		return nil, errors.New("workDir is required")
+	}
+	if opts.Base != "" {
+		if err := m.checkBranchName(workDir, opts.Base); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := exec.LookPath("gh"); err != nil {
+		return nil, ErrGHUnavailable
+	}
+	branch, err := m.run(workDir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("not a git repository: %w", err)
+	}
+	if strings.TrimSpace(branch) == "HEAD" {
+		return nil, fmt.Errorf("%w: HEAD is detached; check out a branch first", ErrInvalidBranch)
+	}
+	// gh refuses to create a PR for an unpushed branch when it can't
+	// prompt, so push first.
+	if _, err := m.run(workDir, "rev-parse", "--abbrev-ref", "@{upstream}"); err != nil {
+		_, err = m.run(workDir, "push", "-u", "origin", "HEAD")
+		if err != nil {
+			return nil, err
+		}
+	} else if _, err := m.run(workDir, "push"); err != nil {
+		return nil, err
+	}
+
+	args := []string{"pr", "create"}
+	if opts.Title == "" {
+		args = append(args, "--fill")
+	} else {
+		args = append(args, "--title", opts.Title, "--body", opts.Body)
+	}
+	if opts.Base != "" {
+		args = append(args, "--base", opts.Base)
+	}
+	if opts.Draft {
+		args = append(args, "--draft")
+	}
+	out, err := m.runGH(ctx, workDir, args...)
+	if err != nil {
+		return nil, err
+	}
+	// gh prints the new PR's URL as the last line.
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	url := strings.TrimSpace(lines[len(lines)-1])
+	return m.pullRequest(ctx, workDir, url)
+}
+
+func (m *Manager) pullRequest(ctx context.Context, workDir, selector string) (*PullRequest, error) {
+	out, err := m.runGH(ctx, workDir, "pr", "view", selector, "--json", ghPRFields)
+	if err != nil {
+		return nil, err
+	}
+	var raw ghPR
+	if err := json.Unmarshal(out, &raw); err != nil {
+		return nil, fmt.Errorf("parse gh output: %w", err)
+	}
+	pr := raw.toPullRequest()
+	return &pr, nil
+}
+
+// ghIssueFields are the `gh issue view --json` fields decoded into
+// Issue; pull requests add their branch.
+const ghIssueFields = "number,title,body,state,url,author,comments"
+
+// ghRepoRe matches gh's --repo form, [HOST/]OWNER/REPO.
+var ghRepoRe = regexp.MustCompile(`^[A-Za-z0-9][\w.-]*(/[\w.-]+){1,2}$`)
+
+// Issue is a GitHub issue or pull request with its conversation.
+type Issue struct {
+	Number      int    `json:"number"`
+	Title       string `json:"title"`
+	Body        string `json:"body"`
+	State       string `json:"state"`
+	URL         string `json:"url"`
+	Author      string `json:"author"`
+	PullRequest bool   `json:"pullRequest,omitempty"`
+	// Head is a pull request's branch.
+	Head     string         `json:"head,omitempty"`
+	Comments []IssueComment `json:"comments"`
+}
+
+type IssueComment struct {
+	Author    string `json:"author"`
+	Body      string `json:"body"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// Issue fetches issue number, or pull request number when pr is set,
+// with its comments. repo is [HOST/]OWNER/REPO; empty means the
+// repository containing workDir.
+func (m *Manager) Issue(ctx context.Context, workDir, repo string, number int, pr bool) (*Issue, error) {
+	if number <= 0 {
+		return nil, fmt.Errorf("invalid issue number: %d", number)
+	}
+	args := []string{"issue", "view", strconv.Itoa(number), "--json", ghIssueFields}
+	if pr {
+		args = []string{"pr", "view", strconv.Itoa(number), "--json", ghIssueFields + ",headRefName"}
+	}
+	if repo != "" {
+		if !ghRepoRe.MatchString(repo) {
+			return nil, fmt.Errorf("invalid repository %q: want OWNER/REPO", repo)
+		}
+		args = append(args, "--repo", repo)
+	}
+	out, err := m.runGH(ctx, workDir, args...)
+	if err != nil {
+		return nil, err
+	}
+	iss, err := parseIssue(out)
+	if err != nil {
+		return nil, err
+	}
+	iss.PullRequest = pr
+	return iss, nil
+}
+
+// parseIssue decodes gh's JSON, where authors are objects.
+func parseIssue(data []byte) (*Issue, error) {
+	type ghAuthor struct {
+		Login string `json:"login"`
+	}
+	var raw struct {
+		Number   int      `json:"number"`
+		Title    string   `json:"title"`
+		Body     string   `json:"body"`
+		State    string   `json:"state"`
+		URL      string   `json:"url"`
+		Author   ghAuthor `json:"author"`
+		Head     string   `json:"headRefName"`
+		Comments []struct {
+			Author    ghAuthor `json:"author"`
+			Body      string   `json:"body"`
+			CreatedAt string   `json:"createdAt"`
+		} `json:"comments"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse gh output: %w", err)
+	}
+	iss := &Issue{
+		Number: raw.Number, Title: raw.Title, Body: raw.Body, State: raw.State, URL: raw.URL,
+		Author: raw.Author.Login, Head: raw.Head, Comments: make([]IssueComment, len(raw.Comments)),
+	}
+	for i, c := range raw.Comments {
+		iss.Comments[i] = IssueComment{Author: c.Author.Login, Body: c.Body, CreatedAt: c.CreatedAt}
+	}
+	return iss, nil
+}
+
+// Check states reported in PRCheck.State.
+const (
+	CheckPending   = "pending"
+	CheckSuccess   = "success"
+	CheckFailure   = "failure"
+	CheckSkipped   = "skipped"
+	CheckNeutral   = "neutral"
+	CheckCancelled = "cancelled"
+)
+
+type PRCheck struct {
+	Name     string `json:"name"`
+	Workflow string `json:"workflow,omitempty"`
+	State    string `json:"state"`
+	URL      string `json:"url,omitempty"`
+}
+
+type PRChecksResult struct {
+	Number int       `json:"number"`
+	URL    string    `json:"url"`
+	Checks []PRCheck `json:"checks"`
+	// State summarizes the checks: failure if any failed, else
+	// pending if any are still running, else success.
+	State string `json:"state"`
+}
+
+// PullRequestChecks returns the CI checks of pull request number.
+func (m *Manager) PullRequestChecks(ctx context.Context, workDir string, number int) (*PRChecksResult, error) {
+	if number <= 0 {
+		return nil, fmt.Errorf("invalid pull request number: %d", number)
+	}
+	out, err := m.runGH(ctx, workDir, "pr", "view", strconv.Itoa(number), "--json", "number,url,statusCheckRollup")
+	if err != nil {
+		return nil, err
+	}
+	return parsePRChecks(out)
+}
+
+// ghRollupItem is one statusCheckRollup entry: a CheckRun (GitHub
+// Actions and apps) or a StatusContext (commit status API).
+type ghRollupItem struct {
+	Typename     string `json:"__typename"`
+	Name         string `json:"name"`
+	WorkflowName string `json:"workflowName"`
+	Status       string `json:"status"`
+	Conclusion   string `json:"conclusion"`
+	DetailsURL   string `json:"detailsUrl"`
+	Context      string `json:"context"`
+	State        string `json:"state"`
+	TargetURL    string `json:"targetUrl"`
+}
+
+func parsePRChecks(data []byte) (*PRChecksResult, error) {
+	var raw struct {
+		Number int            `json:"number"`
+		URL    string         `json:"url"`
+		Rollup []ghRollupItem `json:"statusCheckRollup"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse gh output: %w", err)
+	}
+	res := &PRChecksResult{Number: raw.Number, URL: raw.URL, Checks: []PRCheck{}, State: CheckSuccess}
+	pending := false
+	for _, it := range raw.Rollup {
+		var c PRCheck
+		if it.Typename == "StatusContext" {
+			c = PRCheck{Name: it.Context, URL: it.TargetURL, State: statusContextState(it.State)}
+		} else {
+			c = PRCheck{Name: it.Name, Workflow: it.WorkflowName, URL: it.DetailsURL, State: checkRunState(it.Status, it.Conclusion)}
+		}
+		switch c.State {
+		case CheckFailure:
+			res.State = CheckFailure
+		case CheckPending:
+			pending = true
+		}
+		res.Checks = append(res.Checks, c)
+	}
+	if pending && res.State != CheckFailure {
+		res.State = CheckPending
+	}
+	return res, nil
+}
+
+func checkRunState(status, conclusion string) string {
+	if status != "COMPLETED" {
+		return CheckPending
+	}
+	switch conclusion {
+	case "SUCCESS":
+		return CheckSuccess
+	case "SKIPPED":
+		return CheckSkipped
+	case "NEUTRAL":
+		return CheckNeutral
+	case "CANCELLED":
+		return CheckCancelled
+	}
+	return CheckFailure // FAILURE, TIMED_OUT, ACTION_REQUIRED, STARTUP_FAILURE, STALE
+}
+
+func statusContextState(state string) string {
+	switch state {
+	case "SUCCESS":
+		return CheckSuccess
+	case "PENDING", "EXPECTED":
+		return CheckPending
+	}
+	return CheckFailure // FAILURE, ERROR
+}
+
+// runGH runs gh in workDir and returns its stdout. gh's stderr carries
+// the useful failure text (auth, no remote, …) and goes into the error.
+func (m *Manager) runGH(ctx context.Context, workDir string, args ...string) ([]byte, error) {
+	if workDir == "" {
+		return nil, errors.New("workDir is required")
+	}
+	if _, err := exec.LookPath("gh"); err != nil {
+		return nil, ErrGHUnavailable
+	}
+	ctx, cancel := context.WithTimeout(ctx, ghTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "gh", args...)
+	cmd.Dir = workDir
+	// Never block on an interactive prompt.
+	cmd.Env = append(cmd.Environ(), "GH_PROMPT_DISABLED=1", "NO_COLOR=1")
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("gh %s: %w: %s", strings.Join(args[:min(2, len(args))], " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
@@ -0,0 +1,69 @@
+package git
+
+import "testing"
+
+func TestParsePRChecks(t *testing.T) {
+	data := []byte(`{"number":7,"url":"https://github.com/o/r/pull/7","statusCheckRollup":[
+		{"__typename":"CheckRun","name":"test","workflowName":"CI","status":"COMPLETED","conclusion":"SUCCESS","detailsUrl":"https://x/1"},
+		{"__typename":"CheckRun","name":"lint","workflowName":"CI","status":"IN_PROGRESS","conclusion":""},
+		{"__typename":"StatusContext","context":"ci/legacy","state":"SUCCESS","targetUrl":"https://x/2"}
+	]}`)
+	res, err := parsePRChecks(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Number != 7 || len(res.Checks) != 3 || res.State != CheckPending {
+		t.Fatalf("result = %+v", res)
+	}
+	if c := res.Checks[0]; c.Name != "test" || c.Workflow != "CI" || c.State != CheckSuccess || c.URL != "https://x/1" {
+		t.Errorf("check run = %+v", c)
+	}
+	if c := res.Checks[2]; c.Name != "ci/legacy" || c.State != CheckSuccess {
+		t.Errorf("status context = %+v", c)
+	}
+
+	failed, err := parsePRChecks([]byte(`{"number":1,"statusCheckRollup":[
+		{"__typename":"CheckRun","name":"a","status":"IN_PROGRESS"},
+		{"__typename":"CheckRun","name":"b","status":"COMPLETED","conclusion":"TIMED_OUT"}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed.State != CheckFailure || failed.Checks[1].State != CheckFailure {
+		t.Errorf("failed = %+v", failed)
+	}
+
+	empty, err := parsePRChecks([]byte(`{"number":2,"statusCheckRollup":[]}`))
+	if err != nil || empty.State != CheckSuccess || len(empty.Checks) != 0 {
+		t.Errorf("no checks = %+v, %v", empty, err)
+	}
+}
+
+func TestParseIssue(t *testing.T) {
+	iss, err := parseIssue([]byte(`{"number":12,"title":"Crash on save","body":"Steps…","state":"OPEN",
+		"url":"https://github.com/o/r/issues/12","author":{"login":"ann"},
+		"comments":[{"author":{"login":"bob"},"body":"Same here","createdAt":"2026-01-02T03:04:05Z"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if iss.Number != 12 || iss.Author != "ann" || iss.State != "OPEN" || iss.Head != "" {
+		t.Errorf("issue = %+v", iss)
+	}
+	if len(iss.Comments) != 1 || iss.Comments[0] != (IssueComment{Author: "bob", Body: "Same here", CreatedAt: "2026-01-02T03:04:05Z"}) {
+		t.Errorf("comments = %+v", iss.Comments)
+	}
+
+	pr, err := parseIssue([]byte(`{"number":3,"headRefName":"fix-it","author":{"login":"cy"},"comments":[]}`))
+	if err != nil || pr.Head != "fix-it" || len(pr.Comments) != 0 {
+		t.Errorf("pull request = %+v, %v", pr, err)
+	}
+}
+
+func TestIssueRejectsBadRepo(t *testing.T) {
+	m := &Manager{}
+	for _, repo := range []string{"--web", "o", "o/r/x/y", "o/r;rm"} {
+		if _, err := m.Issue(t.Context(), t.TempDir(), repo, 1, false); err == nil {
+			t.Errorf("repo %q accepted", repo)
+		}
+	}
+}
@@ -6,12 +6,37 @@ import (
 	"os/exec"
 	"strconv"
 	"strings"
+	"sync/atomic"
 )
 
-type Manager struct{}
+type Manager struct {
+	exec    atomic.Pointer[execPolicy]
+	repos   repoCache
+	fetches fetchTracker
+}
+
+// Options configures a Manager.
+type Options struct {
+	// ExecAllow lists the subcommands Exec may run; "*" allows any.
+	// Empty uses DefaultExecAllow.
+	ExecAllow []string
+	// ExecDeny lists rules Exec rejects even when allowed: a bare
+	// subcommand ("rebase") or a subcommand plus flag ("push --force").
+	// Nil uses DefaultExecDeny; pass an empty slice to deny nothing.
+	ExecDeny []string
+}
 
-func New() *Manager {
-	return &Manager{}
+func New(opts Options) *Manager {
+	m := &Manager{}
+	m.SetExecPolicy(opts.ExecAllow, opts.ExecDeny)
+	return m
+}
+
+// SetExecPolicy replaces Exec's allow/deny rules, with the same
+// defaults as Options. Calls already past the check are unaffected.
+func (m *Manager) SetExecPolicy(allow, deny []string) {
+	p := newExecPolicy(allow, deny)
+	m.exec.Store(&p)
 }
 
 type StatusResult struct {
@@ -23,6 +48,16 @@ type StatusResult struct {
 	Untracked []string `json:"untracked"`
 }
 
+// Branch returns the branch checked out in workDir, "HEAD" when
+// detached.
+func (m *Manager) Branch(workDir string) (string, error) {
+	branch, err := m.run(workDir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("not a git repository: %w", err)
+	}
+	return strings.TrimSpace(branch), nil
+}
+
 func (m *Manager) Status(workDir string) (*StatusResult, error) {
 	if workDir == "" {
 		return nil, errors.New("workDir is required")
@@ -183,6 +218,9 @@ type ExecResult struct {
 	Stderr   string `json:"stderr"`
 }
 
+// Exec runs `git <args>` in workDir after checking args against the
+// Manager's allow/deny policy (ErrExecDenied on rejection). A non-zero
+// git exit is reported in ExecResult, not as an error.
 func (m *Manager) Exec(workDir string, args []string) (*ExecResult, error) {
 	if workDir == "" {
 		return nil, errors.New("workDir is required")
@@ -190,6 +228,9 @@ func (m *Manager) Exec(workDir string, args []string) (*ExecResult, error) {
 	if len(args) == 0 {
 		return nil, errors.New("args is required")
 	}
+	if err := m.exec.Load().check(args); err != nil {
+		return nil, err
+	}
 
 	cmd := exec.Command("git", args...)
 	cmd.Dir = workDir
@@ -0,0 +1,223 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// remotePollInterval is how often WatchRemote re-reads the local
+	// ahead/behind counts. Commits, checkouts and pulls made in a
+	// terminal show up within this delay even when fetching is off.
+	remotePollInterval = 15 * time.Second
+	// MinFetchInterval is the floor for WatchRemote's fetch interval;
+	// shorter requests are raised to it so a page left open can't
+	// hammer the remote.
+	MinFetchInterval = 30 * time.Second
+	// fetchTimeout bounds one background `git fetch`.
+	fetchTimeout = 2 * time.Minute
+)
+
+// RemoteStatus is the branch/upstream half of StatusResult, pushed by
+// WatchRemote whenever it changes.
+type RemoteStatus struct {
+	Branch string `json:"branch"`
+	// Upstream is the tracking ref ("origin/main"); "" when the branch
+	// has none, in which case the counts are zero and nothing is fetched.
+	Upstream     string `json:"upstream"`
+	UpstreamHead string `json:"upstreamHead"`
+	Ahead        int    `json:"ahead"`
+	Behind       int    `json:"behind"`
+	// FetchedAt is when the repo was last fetched by kojo (RFC 3339);
+	// empty until the first background fetch.
+	FetchedAt  string `json:"fetchedAt,omitempty"`
+	FetchError string `json:"fetchError,omitempty"`
+}
+
+// fetchState serialises background fetches of one repository and
+// remembers the last one, so several watchers on the same repo share a
+// single fetch per interval.
+type fetchState struct {
+	running sync.Mutex // held for the duration of a fetch
+
+	mu   sync.Mutex // guards last and err
+	last time.Time
+	err  error
+}
+
+func (f *fetchState) result() (time.Time, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.last, f.err
+}
+
+type fetchTracker struct {
+	mu    sync.Mutex
+	repos map[string]*fetchState
+}
+
+func (t *fetchTracker) get(workDir string) *fetchState {
+	key := workDir
+	if abs, err := filepath.Abs(workDir); err == nil {
+		key = abs
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.repos == nil {
+		t.repos = make(map[string]*fetchState)
+	}
+	st, ok := t.repos[key]
+	if !ok {
+		st = &fetchState{}
+		t.repos[key] = st
+	}
+	return st
+}
+
+// WatchRemote calls fn with the repository's RemoteStatus once, then
+// polls every remotePollInterval and calls fn again with the new status
+// when it changed, or with nil as a heartbeat when it did not. With a
+// non-zero fetchEvery (raised to MinFetchInterval) it also runs `git
+// fetch` in the background at that interval, so Behind reflects the
+// remote rather than the last manual fetch. It returns when ctx is done
+// or fn returns an error; validation failures are returned before fn is
+// first called.
+func (m *Manager) WatchRemote(ctx context.Context, workDir string, fetchEvery time.Duration, fn func(*RemoteStatus) error) error {
+	if workDir == "" {
+		return errors.New("workDir is required")
+	}
+	if fetchEvery > 0 && fetchEvery < MinFetchInterval {
+		fetchEvery = MinFetchInterval
+	}
+
+	last, err := m.remoteStatus(workDir)
+	if err != nil {
+		return err
+	}
+	if fetchEvery > 0 && last.Upstream != "" {
+		// Report the stale view immediately; the first fetch can take
+		// a while on a slow remote.
+		if err := fn(&last); err != nil {
+			return err
+		}
+		m.fetchRemote(ctx, workDir, fetchEvery)
+		cur, err := m.remoteStatus(workDir)
+		if err != nil {
+			return err
+		}
+		if cur != last {
+			last = cur
+			if err := fn(&last); err != nil {
+				return err
+			}
+		}
+	} else if err := fn(&last); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(remotePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		if fetchEvery > 0 && last.Upstream != "" {
+			m.fetchRemote(ctx, workDir, fetchEvery)
+		}
+		cur, err := m.remoteStatus(workDir)
+		if err != nil {
+			// The repo may be mid-rebase or briefly locked; keep the
+			// last good status and try again next tick.
+			cur = last
+		}
+		if cur == last {
+			err = fn(nil)
+		} else {
+			last = cur
+			err = fn(&cur)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// remoteStatus reads branch, upstream and ahead/behind without touching
+// the working tree, so polling stays cheap on large checkouts.
+func (m *Manager) remoteStatus(workDir string) (RemoteStatus, error) {
+	var st RemoteStatus
+	branch, err := m.run(workDir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return st, fmt.Errorf("not a git repository: %w", err)
+	}
+	st.Branch = strings.TrimSpace(branch)
+
+	fetched, fetchErr := m.fetches.get(workDir).result()
+	if !fetched.IsZero() {
+		st.FetchedAt = fetched.UTC().Format(time.RFC3339)
+	}
+	if fetchErr != nil {
+		st.FetchError = fetchErr.Error()
+	}
+
+	// rev-parse applies --abbrev-ref only to the arguments after it.
+	up, err := m.run(workDir, "rev-parse", "@{upstream}", "--abbrev-ref", "@{upstream}")
+	if err != nil {
+		return st, nil // no upstream configured
+	}
+	if fields := strings.Fields(up); len(fields) == 2 {
+		st.UpstreamHead, st.Upstream = fields[0], fields[1]
+	}
+	ab, err := m.run(workDir, "rev-list", "--left-right", "--count", "HEAD...@{upstream}")
+	if err == nil {
+		if parts := strings.Fields(ab); len(parts) == 2 {
+			st.Ahead, _ = strconv.Atoi(parts[0])
+			st.Behind, _ = strconv.Atoi(parts[1])
+		}
+	}
+	return st, nil
+}
+
+// fetchRemote runs `git fetch` in workDir unless another watcher did so
+// within maxAge. Concurrent callers for the same repo wait for the one
+// in flight instead of starting their own. The outcome is recorded for
+// remoteStatus rather than returned: a failing fetch (offline, expired
+// credentials) must not end the watch.
+func (m *Manager) fetchRemote(ctx context.Context, workDir string, maxAge time.Duration) {
+	fs := m.fetches.get(workDir)
+	fs.running.Lock()
+	defer fs.running.Unlock()
+	if last, _ := fs.result(); !last.IsZero() && time.Since(last) < maxAge {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", "fetch", "--quiet", "--prune")
+	cmd.Dir = workDir
+	// Never block on an interactive credential prompt; there is no
+	// terminal to answer it. fetchTimeout covers ssh passphrase prompts.
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	out, err := cmd.CombinedOutput()
+	if errors.Is(ctx.Err(), context.Canceled) {
+		// The watcher went away mid-fetch; leave the record as it was
+		// so the next watcher retries promptly.
+		return
+	}
+	if err != nil {
+		err = fmt.Errorf("git fetch: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	fs.mu.Lock()
+	fs.last, fs.err = time.Now(), err
+	fs.mu.Unlock()
+}
@@ -0,0 +1,71 @@
+package git
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRemoteStatusAfterFetch(t *testing.T) {
+	origin := newTestRepo(t)
+	origin.write("a.txt", "a\n")
+	origin.git("add", ".")
+	origin.git("commit", "-qm", "init")
+
+	clone := &testRepo{t: t, dir: filepath.Join(t.TempDir(), "clone")}
+	if out, err := exec.Command("git", "clone", "-q", origin.dir, clone.dir).CombinedOutput(); err != nil {
+		t.Fatalf("clone: %v\n%s", err, out)
+	}
+
+	m := New(Options{})
+	st, err := m.remoteStatus(clone.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Upstream != "origin/"+st.Branch {
+		t.Fatalf("Upstream = %q, want origin/%s", st.Upstream, st.Branch)
+	}
+	if st.Behind != 0 || st.FetchedAt != "" {
+		t.Fatalf("before fetch: %+v", st)
+	}
+
+	origin.write("b.txt", "b\n")
+	origin.git("add", ".")
+	origin.git("commit", "-qm", "second")
+
+	m.fetchRemote(context.Background(), clone.dir, time.Minute)
+	st, err = m.remoteStatus(clone.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head := origin.git("rev-parse", "HEAD")
+	if st.Behind != 1 || st.Ahead != 0 || st.UpstreamHead+"\n" != head {
+		t.Errorf("after fetch: %+v, want behind 1 at %s", st, head)
+	}
+	if st.FetchedAt == "" || st.FetchError != "" {
+		t.Errorf("fetch not recorded: %+v", st)
+	}
+
+	// A second fetch within maxAge is skipped.
+	fetched := st.FetchedAt
+	m.fetchRemote(context.Background(), clone.dir, time.Hour)
+	if st, _ = m.remoteStatus(clone.dir); st.FetchedAt != fetched {
+		t.Errorf("FetchedAt moved from %s to %s inside maxAge", fetched, st.FetchedAt)
+	}
+}
+
+func TestRemoteStatusWithoutUpstream(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("a.txt", "a\n")
+	r.git("add", ".")
+	r.git("commit", "-qm", "init")
+	st, err := New(Options{}).remoteStatus(r.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Branch == "" || st.Upstream != "" || st.UpstreamHead != "" {
+		t.Errorf("remoteStatus = %+v, want branch only", st)
+	}
+}
@@ -0,0 +1,159 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// repoScanDepth is how many directory levels below each root the
+	// repository scan descends. Repos usually sit at ~/src/<name> or
+	// ~/src/<org>/<name>; deeper layouts are reachable by adding a
+	// file root closer to them.
+	repoScanDepth = 3
+	// repoScanMaxDirs bounds one scan so a huge home directory can't
+	// turn a page load into a full disk walk.
+	repoScanMaxDirs = 20000
+	// repoCacheTTL is how long a scan result is reused.
+	repoCacheTTL = 2 * time.Minute
+	// repoStatusWorkers caps concurrent `git status` calls.
+	repoStatusWorkers = 8
+)
+
+// repoScanSkip are directory names never descended into: dependency
+// trees and OS-managed folders with thousands of entries and no repos
+// worth offering.
+var repoScanSkip = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	"Library":      true,
+	"Applications": true,
+	"AppData":      true,
+}
+
+type RepoInfo struct {
+	Path   string `json:"path"`
+	Branch string `json:"branch"` // "" when HEAD is detached
+	// Dirty is set when tracked files have uncommitted changes.
+	// Untracked files are not considered (too slow on big trees).
+	Dirty bool `json:"dirty"`
+}
+
+type repoCache struct {
+	mu      sync.Mutex
+	key     string
+	scanned time.Time
+	repos   []RepoInfo
+}
+
+// Repos finds git repositories at most repoScanDepth levels below the
+// given roots and reports each one's branch and dirty state. Results
+// are cached per root set for repoCacheTTL; refresh forces a rescan.
+func (m *Manager) Repos(roots []string, refresh bool) []RepoInfo {
+	key := strings.Join(roots, "\x00")
+	m.repos.mu.Lock()
+	defer m.repos.mu.Unlock()
+	if !refresh && m.repos.key == key && time.Since(m.repos.scanned) < repoCacheTTL {
+		return m.repos.repos
+	}
+
+	paths := findRepos(roots)
+	repos := make([]RepoInfo, len(paths))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, repoStatusWorkers)
+	for i, p := range paths {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			repos[i] = m.repoInfo(p)
+		}()
+	}
+	wg.Wait()
+
+	m.repos.key, m.repos.scanned, m.repos.repos = key, time.Now(), repos
+	return repos
+}
+
+// findRepos walks each root breadth-first and returns the sorted,
+// de-duplicated directories that contain a .git entry. It does not
+// descend into a repository (submodules and nested checkouts are not
+// listed) or into hidden directories.
+func findRepos(roots []string) []string {
+	seen := map[string]bool{}
+	var found []string
+	visited := 0
+	for _, root := range roots {
+		level := []string{root}
+		for depth := 0; depth <= repoScanDepth && len(level) > 0; depth++ {
+			var next []string
+			for _, dir := range level {
+				if visited++; visited > repoScanMaxDirs {
+					break
+				}
+				if _, err := os.Lstat(filepath.Join(dir, ".git")); err == nil {
+					if !seen[dir] {
+						seen[dir] = true
+						found = append(found, dir)
+					}
+					continue
+				}
+				entries, err := os.ReadDir(dir)
+				if err != nil {
+					continue
+				}
+				for _, e := range entries {
+					name := e.Name()
+					if !e.IsDir() || strings.HasPrefix(name, ".") || repoScanSkip[name] {
+						continue
+					}
+					next = append(next, filepath.Join(dir, name))
+				}
+			}
+			level = next
+		}
+	}
+	sort.Strings(found)
+	return found
+}
+
+func (m *Manager) repoInfo(path string) RepoInfo {
+	info := RepoInfo{Path: path}
+	out, err := m.run(path, "status", "--porcelain=v1", "--branch", "--untracked-files=no")
+	if err != nil {
+		return info
+	}
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "## ") {
+		info.Branch = parseStatusBranch(strings.TrimPrefix(lines[0], "## "))
+		lines = lines[1:]
+	}
+	info.Dirty = len(lines) > 0 && lines[0] != ""
+	return info
+}
+
+// parseStatusBranch extracts the branch from a porcelain "## " header:
+// "main...origin/main [ahead 1]", "No commits yet on main", or
+// "HEAD (no branch)".
+func parseStatusBranch(h string) string {
+	if strings.HasPrefix(h, "HEAD (no branch)") {
+		return ""
+	}
+	if rest, ok := strings.CutPrefix(h, "No commits yet on "); ok {
+		return rest
+	}
+	if rest, ok := strings.CutPrefix(h, "Initial commit on "); ok {
+		return rest
+	}
+	if i := strings.Index(h, "..."); i >= 0 {
+		return h[:i]
+	}
+	if i := strings.IndexByte(h, ' '); i >= 0 {
+		return h[:i]
+	}
+	return h
+}
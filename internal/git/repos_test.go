@@ -0,0 +1,77 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFindRepos(t *testing.T) {
+	root := t.TempDir()
+	for _, d := range []string{
+		"a/.git",
+		"src/org/b/.git",
+		"src/org/b/nested/.git", // inside a repo: not listed
+		"x/y/z/too-deep/.git",
+		".hidden/c/.git",
+		"node_modules/d/.git",
+	} {
+		if err := os.MkdirAll(filepath.Join(root, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := findRepos([]string{root, root})
+	want := []string{filepath.Join(root, "a"), filepath.Join(root, "src", "org", "b")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findRepos = %v, want %v", got, want)
+	}
+}
+
+func TestParseStatusBranch(t *testing.T) {
+	for h, want := range map[string]string{
+		"main...origin/main [ahead 1]": "main",
+		"feature":                      "feature",
+		"No commits yet on trunk":      "trunk",
+		"HEAD (no branch)":             "",
+	} {
+		if got := parseStatusBranch(h); got != want {
+			t.Errorf("parseStatusBranch(%q) = %q, want %q", h, got, want)
+		}
+	}
+}
+
+func TestReposReportsBranchAndDirty(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	r := newTestRepo(t)
+	r.write("a.txt", "a\n")
+	r.git("add", ".")
+	r.git("commit", "-qm", "init")
+	r.git("checkout", "-qb", "work")
+
+	m := New(Options{})
+	root := filepath.Dir(r.dir)
+	find := func(repos []RepoInfo) *RepoInfo {
+		for i := range repos {
+			if repos[i].Path == r.dir {
+				return &repos[i]
+			}
+		}
+		t.Fatalf("repo %s not found in %+v", r.dir, repos)
+		return nil
+	}
+	if info := find(m.Repos([]string{root}, false)); info.Branch != "work" || info.Dirty {
+		t.Errorf("clean repo = %+v", info)
+	}
+
+	r.write("a.txt", "changed\n")
+	if info := find(m.Repos([]string{root}, false)); info.Dirty {
+		t.Error("cached result should not see the new change yet")
+	}
+	if info := find(m.Repos([]string{root}, true)); !info.Dirty {
+		t.Error("refresh did not pick up the dirty tree")
+	}
+}
@@ -0,0 +1,139 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// maxRevisionFileSize caps FileAt content, matching the file browser's
+// text view limit.
+const maxRevisionFileSize = 1024 * 1024
+
+var (
+	ErrUnknownRevision = errors.New("unknown revision")
+	ErrPathNotFound    = errors.New("path not found at revision")
+	ErrFileTooLarge    = errors.New("file too large")
+)
+
+type CommitDetail struct {
+	Hash           string   `json:"hash"`
+	Parents        []string `json:"parents"`
+	Author         string   `json:"author"`
+	AuthorEmail    string   `json:"authorEmail"`
+	Date           string   `json:"date"` // author date, ISO 8601
+	Committer      string   `json:"committer"`
+	CommitterEmail string   `json:"committerEmail"`
+	CommitDate     string   `json:"commitDate"`
+	Subject        string   `json:"subject"`
+	Body           string   `json:"body,omitempty"`
+	// Diff is the commit's change against its first parent.
+	Diff *StructuredDiffResult `json:"diff"`
+}
+
+// Show returns metadata and the structured diff for the commit ref
+// resolves to. ref may be a hash, branch, tag or an expression like
+// HEAD~2.
+func (m *Manager) Show(workDir, ref string) (*CommitDetail, error) {
+	hash, err := m.resolveCommit(workDir, ref)
+	if err != nil {
+		return nil, err
+	}
+	out, err := m.run(workDir, "show", "-s", "--format=%H%x00%P%x00%an%x00%ae%x00%aI%x00%cn%x00%ce%x00%cI%x00%s%x00%b", hash)
+	if err != nil {
+		return nil, err
+	}
+	f := strings.SplitN(out, "\x00", 10)
+	if len(f) != 10 {
+		return nil, fmt.Errorf("unexpected git show output for %s", hash)
+	}
+	detail := &CommitDetail{
+		Hash:           f[0],
+		Parents:        strings.Fields(f[1]),
+		Author:         f[2],
+		AuthorEmail:    f[3],
+		Date:           f[4],
+		Committer:      f[5],
+		CommitterEmail: f[6],
+		CommitDate:     f[7],
+		Subject:        f[8],
+		Body:           strings.TrimSpace(f[9]),
+	}
+	if detail.Diff, err = m.StructuredDiff(workDir, DiffOptions{Ref: hash}); err != nil {
+		return nil, err
+	}
+	return detail, nil
+}
+
+type RevisionFile struct {
+	Ref  string `json:"ref"` // resolved commit hash
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	// Binary files are reported without Content.
+	Binary  bool   `json:"binary,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+// FileAt returns the content of path (relative to workDir) as of the
+// commit ref resolves to.
+func (m *Manager) FileAt(workDir, ref, path string) (*RevisionFile, error) {
+	paths, err := cleanPaths(workDir, []string{path})
+	if err != nil {
+		return nil, err
+	}
+	hash, err := m.resolveCommit(workDir, ref)
+	if err != nil {
+		return nil, err
+	}
+	// "<rev>:./<path>" resolves path relative to workDir rather than
+	// the repository root, so a session in a subdirectory works.
+	object := hash + ":./" + paths[0]
+	if _, err := m.run(workDir, "cat-file", "-e", object); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrPathNotFound, path)
+	}
+	if kind, _ := m.run(workDir, "cat-file", "-t", object); strings.TrimSpace(kind) != "blob" {
+		return nil, fmt.Errorf("%w: %s is not a file", ErrPathNotFound, path)
+	}
+	sizeOut, err := m.run(workDir, "cat-file", "-s", object)
+	if err != nil {
+		return nil, err
+	}
+	size, _ := strconv.ParseInt(strings.TrimSpace(sizeOut), 10, 64)
+	if size > maxRevisionFileSize {
+		return nil, fmt.Errorf("%w: %d bytes (max %d)", ErrFileTooLarge, size, maxRevisionFileSize)
+	}
+
+	// Output only: run() mixes in stderr, which would corrupt content.
+	cmd := exec.Command("git", "cat-file", "blob", object)
+	cmd.Dir = workDir
+	content, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git cat-file: %w", err)
+	}
+	result := &RevisionFile{Ref: hash, Path: paths[0], Size: size}
+	if bytes.IndexByte(content[:min(len(content), 8000)], 0) >= 0 {
+		result.Binary = true
+	} else {
+		result.Content = string(content)
+	}
+	return result, nil
+}
+
+// resolveCommit turns ref into a full commit hash, rejecting anything
+// git could read as an option.
+func (m *Manager) resolveCommit(workDir, ref string) (string, error) {
+	if workDir == "" {
+		return "", errors.New("workDir is required")
+	}
+	if ref == "" || strings.HasPrefix(ref, "-") || strings.ContainsAny(ref, " \t\n\x00") {
+		return "", fmt.Errorf("invalid ref: %q", ref)
+	}
+	out, err := m.run(workDir, "rev-parse", "-q", "--verify", ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrUnknownRevision, ref)
+	}
+	return strings.TrimSpace(out), nil
+}
@@ -0,0 +1,62 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestShowAndFileAt(t *testing.T) {
+	r := newTestRepo(t)
+	if err := os.Mkdir(filepath.Join(r.dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	r.write("sub/a.txt", "v1\n")
+	r.git("add", ".")
+	r.git("commit", "-qm", "first")
+	r.write("sub/a.txt", "v2\n")
+	r.write("bin.dat", "x\x00y")
+	r.git("add", ".")
+	r.git("commit", "-qm", "second\n\nlonger body")
+	m := New(Options{})
+
+	d, err := m.Show(r.dir, "HEAD")
+	if err != nil {
+		t.Fatalf("Show: %v", err)
+	}
+	if d.Subject != "second" || d.Body != "longer body" || len(d.Parents) != 1 || len(d.Hash) != 40 {
+		t.Errorf("detail = %+v", d)
+	}
+	if d.Diff == nil || len(d.Diff.Files) != 2 {
+		t.Fatalf("diff = %+v", d.Diff)
+	}
+
+	f, err := m.FileAt(r.dir, "HEAD~1", "sub/a.txt")
+	if err != nil {
+		t.Fatalf("FileAt: %v", err)
+	}
+	if f.Content != "v1\n" || f.Size != 3 {
+		t.Errorf("file = %+v", f)
+	}
+	// Paths are relative to workDir, which may be a subdirectory.
+	if f, err := m.FileAt(filepath.Join(r.dir, "sub"), "HEAD", "a.txt"); err != nil || f.Content != "v2\n" {
+		t.Errorf("FileAt from subdir = %+v, %v", f, err)
+	}
+	if f, err := m.FileAt(r.dir, "HEAD", "bin.dat"); err != nil || !f.Binary || f.Content != "" {
+		t.Errorf("binary file = %+v, %v", f, err)
+	}
+
+	if _, err := m.FileAt(r.dir, "HEAD~1", "bin.dat"); !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("missing path: %v, want ErrPathNotFound", err)
+	}
+	if _, err := m.FileAt(r.dir, "HEAD", "sub"); !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("directory path: %v, want ErrPathNotFound", err)
+	}
+	if _, err := m.Show(r.dir, "nosuchbranch"); !errors.Is(err, ErrUnknownRevision) {
+		t.Errorf("unknown ref: %v, want ErrUnknownRevision", err)
+	}
+	if _, err := m.Show(r.dir, "--all"); err == nil {
+		t.Error("option-like ref accepted")
+	}
+}
@@ -0,0 +1,231 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrInvalidBranch    = errors.New("invalid branch name")
+	ErrWorktreeNotFound = errors.New("worktree not found")
+)
+
+type Worktree struct {
+	Path   string `json:"path"`
+	Head   string `json:"head,omitempty"`
+	Branch string `json:"branch,omitempty"` // short name; "" when detached
+	// Main marks the repository's primary working tree, which cannot
+	// be removed.
+	Main     bool `json:"main,omitempty"`
+	Bare     bool `json:"bare,omitempty"`
+	Detached bool `json:"detached,omitempty"`
+	Locked   bool `json:"locked,omitempty"`
+	Prunable bool `json:"prunable,omitempty"`
+}
+
+// Worktrees lists the working trees of the repository containing
+// workDir, main tree first.
+func (m *Manager) Worktrees(workDir string) ([]Worktree, error) {
+	if workDir == "" {
+		return nil, errors.New("workDir is required")
+	}
+	out, err := m.run(workDir, "worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+	return parseWorktrees(out), nil
+}
+
+// parseWorktrees parses `git worktree list --porcelain`: one block of
+// "key value" lines per worktree, separated by blank lines.
+func parseWorktrees(out string) []Worktree {
+	trees := []Worktree{}
+	var cur *Worktree
+	for _, line := range strings.Split(out, "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "worktree":
+			trees = append(trees, Worktree{Path: value, Main: len(trees) == 0})
+			cur = &trees[len(trees)-1]
+		case "HEAD":
+			if cur != nil {
+				cur.Head = value
+			}
+		case "branch":
+			if cur != nil {
+				cur.Branch = strings.TrimPrefix(value, "refs/heads/")
+			}
+		case "bare":
+			if cur != nil {
+				cur.Bare = true
+			}
+		case "detached":
+			if cur != nil {
+				cur.Detached = true
+			}
+		case "locked":
+			if cur != nil {
+				cur.Locked = true
+			}
+		case "prunable":
+			if cur != nil {
+				cur.Prunable = true
+			}
+		}
+	}
+	return trees
+}
+
+// AddWorktreeOptions describes a new working tree.
+type AddWorktreeOptions struct {
+	// Branch is checked out in the new tree. It is created (from Base,
+	// or HEAD) when it doesn't exist yet.
+	Branch string
+	// Path is where the tree is created. Empty uses DefaultWorktreePath.
+	Path string
+	// Base is the start point for a newly created branch.
+	Base string
+}
+
+// AddWorktree creates a working tree for the repository containing
+// workDir and returns it.
+func (m *Manager) AddWorktree(workDir string, opts AddWorktreeOptions) (*Worktree, error) {
+	if workDir == "" {
+		return nil, errors.New("workDir is required")
+	}
+	if err := m.checkBranchName(workDir, opts.Branch); err != nil {
+		return nil, err
+	}
+	if opts.Base != "" && strings.HasPrefix(opts.Base, "-") {
+		return nil, fmt.Errorf("invalid ref: %s", opts.Base)
+	}
+	root, err := m.mainWorktree(workDir)
+	if err != nil {
+		return nil, err
+	}
+	path := opts.Path
+	if path == "" {
+		path = DefaultWorktreePath(root, opts.Branch)
+	} else if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	path = filepath.Clean(path)
+
+	args := []string{"worktree", "add"}
+	if m.branchExists(workDir, opts.Branch) {
+		args = append(args, "--", path, opts.Branch)
+	} else {
+		args = append(args, "-b", opts.Branch, "--", path)
+		if opts.Base != "" {
+			args = append(args, opts.Base)
+		}
+	}
+	if _, err := m.run(workDir, args...); err != nil {
+		return nil, err
+	}
+	return m.findWorktree(workDir, path)
+}
+
+// RemoveWorktree deletes the working tree at path. Without force git
+// refuses when the tree has uncommitted changes. The main working tree
+// cannot be removed.
+func (m *Manager) RemoveWorktree(workDir, path string, force bool) error {
+	if workDir == "" {
+		return errors.New("workDir is required")
+	}
+	if path == "" || !filepath.IsAbs(path) {
+		return fmt.Errorf("%w: worktree path must be absolute", ErrInvalidPath)
+	}
+	wt, err := m.findWorktree(workDir, path)
+	if err != nil {
+		return err
+	}
+	if wt.Main {
+		return fmt.Errorf("%w: the main working tree cannot be removed", ErrInvalidPath)
+	}
+	args := []string{"worktree", "remove"}
+	if force {
+		args = append(args, "--force")
+	}
+	_, err = m.run(workDir, append(args, "--", wt.Path)...)
+	return err
+}
+
+// EnsureWorktree returns the path of a working tree that has branch
+// checked out, creating one at DefaultWorktreePath when none exists.
+func (m *Manager) EnsureWorktree(workDir, branch string) (string, error) {
+	trees, err := m.Worktrees(workDir)
+	if err != nil {
+		return "", err
+	}
+	for _, wt := range trees {
+		if wt.Branch == branch && !wt.Bare && !wt.Prunable {
+			return wt.Path, nil
+		}
+	}
+	wt, err := m.AddWorktree(workDir, AddWorktreeOptions{Branch: branch})
+	if err != nil {
+		return "", err
+	}
+	return wt.Path, nil
+}
+
+// DefaultWorktreePath places worktrees in a sibling directory of the
+// main tree, <parent>/<repo>.worktrees/<branch>, so they stay out of
+// the repository's own file listing and git status. Slashes in the
+// branch name become dashes.
+func DefaultWorktreePath(mainTree, branch string) string {
+	name := strings.ReplaceAll(branch, "/", "-")
+	return filepath.Join(filepath.Dir(mainTree), filepath.Base(mainTree)+".worktrees", name)
+}
+
+func (m *Manager) mainWorktree(workDir string) (string, error) {
+	trees, err := m.Worktrees(workDir)
+	if err != nil {
+		return "", err
+	}
+	if len(trees) == 0 {
+		return "", fmt.Errorf("not a git repository: %s", workDir)
+	}
+	return trees[0].Path, nil
+}
+
+// findWorktree looks path up in the worktree list, comparing
+// symlink-resolved paths (macOS /var → /private/var and friends).
+func (m *Manager) findWorktree(workDir, path string) (*Worktree, error) {
+	trees, err := m.Worktrees(workDir)
+	if err != nil {
+		return nil, err
+	}
+	want := resolvePath(path)
+	for i := range trees {
+		if resolvePath(trees[i].Path) == want {
+			return &trees[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrWorktreeNotFound, path)
+}
+
+func resolvePath(p string) string {
+	if r, err := filepath.EvalSymlinks(p); err == nil {
+		return r
+	}
+	return filepath.Clean(p)
+}
+
+func (m *Manager) checkBranchName(workDir, branch string) error {
+	if branch == "" || strings.HasPrefix(branch, "-") {
+		return fmt.Errorf("%w: %q", ErrInvalidBranch, branch)
+	}
+	if _, err := m.run(workDir, "check-ref-format", "--branch", branch); err != nil {
+		return fmt.Errorf("%w: %q", ErrInvalidBranch, branch)
+	}
+	return nil
+}
+
+func (m *Manager) branchExists(workDir, branch string) bool {
+	_, err := m.run(workDir, "rev-parse", "-q", "--verify", "refs/heads/"+branch)
+	return err == nil
+}
@@ -0,0 +1,71 @@
+package git
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseWorktrees(t *testing.T) {
+	out := "worktree /repo\nHEAD abc\nbranch refs/heads/main\n\n" +
+		"worktree /repo.worktrees/feat-x\nHEAD def\nbranch refs/heads/feat/x\nlocked\n\n" +
+		"worktree /tmp/detached\nHEAD 123\ndetached\nprunable gitdir file points to non-existent location\n\n"
+	trees := parseWorktrees(out)
+	if len(trees) != 3 {
+		t.Fatalf("trees = %d, want 3", len(trees))
+	}
+	if !trees[0].Main || trees[0].Branch != "main" {
+		t.Errorf("main = %+v", trees[0])
+	}
+	if trees[1].Main || trees[1].Branch != "feat/x" || !trees[1].Locked {
+		t.Errorf("feature = %+v", trees[1])
+	}
+	if !trees[2].Detached || !trees[2].Prunable || trees[2].Branch != "" {
+		t.Errorf("detached = %+v", trees[2])
+	}
+}
+
+func TestWorktreeLifecycle(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("a.txt", "a\n")
+	r.git("add", ".")
+	r.git("commit", "-qm", "init")
+	m := New(Options{})
+
+	if _, err := m.AddWorktree(r.dir, AddWorktreeOptions{Branch: "bad..name"}); !errors.Is(err, ErrInvalidBranch) {
+		t.Fatalf("bad branch: %v, want ErrInvalidBranch", err)
+	}
+
+	path, err := m.EnsureWorktree(r.dir, "feat/x")
+	if err != nil {
+		t.Fatalf("EnsureWorktree: %v", err)
+	}
+	if want := DefaultWorktreePath(resolvePath(r.dir), "feat/x"); resolvePath(path) != resolvePath(want) {
+		t.Errorf("worktree path = %s, want %s", path, want)
+	}
+	again, err := m.EnsureWorktree(r.dir, "feat/x")
+	if err != nil || again != path {
+		t.Errorf("second EnsureWorktree = %q, %v; want reuse of %q", again, err, path)
+	}
+
+	trees, err := m.Worktrees(r.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trees) != 2 || trees[1].Branch != "feat/x" {
+		t.Fatalf("worktrees = %+v", trees)
+	}
+
+	if err := m.RemoveWorktree(r.dir, trees[0].Path, false); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("removing main tree: %v, want ErrInvalidPath", err)
+	}
+	if err := m.RemoveWorktree(r.dir, filepath.Join(r.dir, "nope"), false); !errors.Is(err, ErrWorktreeNotFound) {
+		t.Errorf("removing unknown tree: %v, want ErrWorktreeNotFound", err)
+	}
+	if err := m.RemoveWorktree(r.dir, path, false); err != nil {
+		t.Fatalf("RemoveWorktree: %v", err)
+	}
+	if trees, _ := m.Worktrees(r.dir); len(trees) != 1 {
+		t.Errorf("worktrees after remove = %+v", trees)
+	}
+}
@@ -0,0 +1,111 @@
+// Package logfile is the --log-file writer: an append-only file that
+// is rotated by size, keeping a few numbered backups (kojo.log.1 is
+// the newest), so a daemon under launchd or systemd can log for months
+// without filling the disk.
+package logfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Writer appends to a log file and rotates it once a write would take
+// it past the size limit. It is safe for concurrent use; slog handlers
+// write one record per call, so records never straddle two files.
+type Writer struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	backups  int
+	f        *os.File
+	size     int64
+}
+
+// Open opens path for appending, creating it and its directory as
+// needed. The file is rotated when it would grow past maxBytes, and
+// at most backups rotated files are kept; 0 keeps none.
+func Open(path string, maxBytes int64, backups int) (*Writer, error) {
+	if maxBytes <= 0 {
+		return nil, fmt.Errorf("log file size limit must be positive, got %d", maxBytes)
+	}
+	w := &Writer{path: path, maxBytes: maxBytes, backups: max(backups, 0)}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Writer) open() error {
+	f, err := os.OpenFile(w.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.f, w.size = f, fi.Size()
+	return nil
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return 0, os.ErrClosed
+	}
+	if w.size > 0 && w.size+int64(len(p)) > w.maxBytes {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// rotate shifts path.N-1 to path.N and so on down to path to path.1,
+// dropping the oldest, and starts a new file. Caller holds mu.
+func (w *Writer) rotate() error {
+	if err := w.f.Close(); err != nil {
+		return err
+	}
+	w.f = nil
+	if w.backups == 0 {
+		if err := os.Remove(w.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return w.open()
+	}
+	for i := w.backups - 1; i >= 1; i-- {
+		if err := os.Rename(backupName(w.path, i), backupName(w.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(w.path, backupName(w.path, 1)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return w.open()
+}
+
+func backupName(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+// Close closes the file; later writes fail.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return nil
+	}
+	err := w.f.Close()
+	w.f = nil
+	return err
+}
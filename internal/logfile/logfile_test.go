@@ -0,0 +1,62 @@
+package logfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "kojo.log")
+	w, err := Open(path, 20, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// Each line is 10 bytes, so every third one starts a new file.
+	for _, line := range []string{"aaaaaaaaa\n", "bbbbbbbbb\n", "ccccccccc\n", "ddddddddd\n", "eeeeeeeee\n", "fffffffff\n", "ggggggggg\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, want := range map[string]string{
+		path:        "ggggggggg\n",
+		path + ".1": "eeeeeeeee\nfffffffff\n",
+		path + ".2": "ccccccccc\nddddddddd\n",
+	} {
+		got, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", filepath.Base(name), got, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("kept more than 2 backups: %v", err)
+	}
+}
+
+func TestOpenAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kojo.log")
+	if err := os.WriteFile(path, []byte(strings.Repeat("x", 15)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	w, err := Open(path, 20, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The existing 15 bytes count toward the limit.
+	if _, err := w.Write([]byte("0123456789")); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	if got, _ := os.ReadFile(path + ".1"); len(got) != 15 {
+		t.Errorf("backup has %d bytes, want 15", len(got))
+	}
+	if _, err := w.Write([]byte("late")); err == nil {
+		t.Error("write after Close succeeded")
+	}
+}
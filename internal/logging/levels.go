@@ -0,0 +1,116 @@
+// Package logging gives the chattier parts of kojo their own log
+// level on top of the global one, so e.g. session handling can be
+// debugged without also turning on tsnet's debug output. Levels can be
+// changed at runtime through /api/v1/admin/log-levels.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// Components are the parts of kojo whose level can be set on its own.
+var Components = []string{"server", "session", "tmux", "notify", "tsnet"}
+
+// Levels hands out loggers that share one handler but are each leveled
+// by their component's override, or the global level without one.
+type Levels struct {
+	base slog.Handler
+	def  *slog.LevelVar
+
+	mu        sync.RWMutex
+	overrides map[string]slog.Level
+}
+
+// NewLevels wraps base, which must let every record through (its own
+// level at most debug); leveling happens here. def is the global
+// level, which the caller may change in place.
+func NewLevels(base slog.Handler, def *slog.LevelVar) *Levels {
+	return &Levels{base: base, def: def, overrides: map[string]slog.Level{}}
+}
+
+// Logger returns the logger for component, whose records carry a
+// "component" attribute. "" is the global logger for everything else.
+func (l *Levels) Logger(component string) *slog.Logger {
+	var h slog.Handler = &handler{Handler: l.base, levels: l, component: component}
+	if component != "" {
+		h = h.WithAttrs([]slog.Attr{slog.String("component", component)})
+	}
+	return slog.New(h)
+}
+
+// Level returns component's effective level.
+func (l *Levels) Level(component string) slog.Level {
+	l.mu.RLock()
+	lvl, ok := l.overrides[component]
+	l.mu.RUnlock()
+	if ok {
+		return lvl
+	}
+	return l.def.Level()
+}
+
+// Default returns the global level.
+func (l *Levels) Default() slog.Level { return l.def.Level() }
+
+// Overrides returns the components that have their own level.
+func (l *Levels) Overrides() map[string]slog.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return maps.Clone(l.overrides)
+}
+
+// Set gives component its own level, or with nil returns it to the
+// global one.
+func (l *Levels) Set(component string, lvl *slog.Level) error {
+	if !slices.Contains(Components, component) {
+		return fmt.Errorf("unknown log component %q (want one of %v)", component, Components)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if lvl == nil {
+		delete(l.overrides, component)
+	} else {
+		l.overrides[component] = *lvl
+	}
+	return nil
+}
+
+// Replace swaps all overrides for levels, as a config reload does.
+func (l *Levels) Replace(levels map[string]slog.Level) error {
+	for c := range levels {
+		if !slices.Contains(Components, c) {
+			return fmt.Errorf("unknown log component %q (want one of %v)", c, Components)
+		}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.overrides = maps.Clone(levels)
+	if l.overrides == nil {
+		l.overrides = map[string]slog.Level{}
+	}
+	return nil
+}
+
+// handler levels records by its component before passing them on.
+type handler struct {
+	slog.Handler
+	levels    *Levels
+	component string
+}
+
+func (h *handler) Enabled(_ context.Context, lvl slog.Level) bool {
+	return lvl >= h.levels.Level(h.component)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &handler{Handler: h.Handler.WithAttrs(attrs), levels: h.levels, component: h.component}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{Handler: h.Handler.WithGroup(name), levels: h.levels, component: h.component}
+}
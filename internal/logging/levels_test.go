@@ -0,0 +1,58 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLevels(t *testing.T) {
+	var buf bytes.Buffer
+	def := new(slog.LevelVar)
+	l := NewLevels(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}), def)
+
+	debug, warn := slog.LevelDebug, slog.LevelWarn
+	if err := l.Set("session", &debug); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Set("tsnet", &warn); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Set("agent", &debug); err == nil {
+		t.Error("Set accepted an unknown component")
+	}
+
+	l.Logger("").Debug("global debug")
+	l.Logger("").Info("global info")
+	l.Logger("session").With("id", "s1").Debug("session debug")
+	l.Logger("tsnet").Info("tsnet info")
+	l.Logger("tsnet").Warn("tsnet warn")
+	out := buf.String()
+	for _, want := range []string{"global info", `msg="session debug" component=session id=s1`, `msg="tsnet warn" component=tsnet`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log lacks %q:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"global debug", "tsnet info"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("log has %q:\n%s", unwanted, out)
+		}
+	}
+
+	// Clearing an override falls back to the global level, which can
+	// change under it.
+	if err := l.Set("session", nil); err != nil {
+		t.Fatal(err)
+	}
+	def.Set(slog.LevelError)
+	if got := l.Level("session"); got != slog.LevelError {
+		t.Errorf("session level = %v, want ERROR", got)
+	}
+	if err := l.Replace(map[string]slog.Level{"tmux": debug}); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.Overrides(); len(got) != 1 || got["tmux"] != debug {
+		t.Errorf("overrides after Replace = %v", got)
+	}
+}
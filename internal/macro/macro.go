@@ -0,0 +1,287 @@
+// Package macro keeps named key sequences ("Escape twice, then
+// /compact and Enter") that can be played into a session with one tap
+// instead of typed on a phone keyboard. They are persisted as
+// macros.json in the config directory.
+package macro
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/loppo-llc/kojo/internal/atomicfile"
+	"github.com/loppo-llc/kojo/internal/configdir"
+)
+
+const (
+	fileName = "macros.json"
+	// Limits on what the API accepts.
+	maxMacros  = 200
+	maxNameLen = 100
+	maxSteps   = 64
+	maxTextLen = 4 << 10
+	// maxTotalDelay bounds the pauses in one macro, since playing it
+	// holds up the client's other input.
+	maxTotalDelay = 10 * time.Second
+)
+
+var (
+	ErrNotFound = errors.New("macro not found")
+	ErrInvalid  = errors.New("invalid macro")
+)
+
+// Step is one part of a macro: exactly one of a named key (see Keys),
+// literal text, or a pause.
+type Step struct {
+	Key     string `json:"key,omitempty"`
+	Text    string `json:"text,omitempty"`
+	DelayMS int    `json:"delayMs,omitempty"`
+}
+
+// Macro is a named key sequence.
+type Macro struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Steps     []Step    `json:"steps"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// keys maps the key names a Step may use to what a terminal sends.
+// Ctrl-A through Ctrl-Z are handled in keyBytes.
+var keys = map[string]string{
+	"Escape":    "\x1b",
+	"Enter":     "\r",
+	"Tab":       "\t",
+	"ShiftTab":  "\x1b[Z",
+	"Backspace": "\x7f",
+	"Delete":    "\x1b[3~",
+	"Space":     " ",
+	"Up":        "\x1b[A",
+	"Down":      "\x1b[B",
+	"Right":     "\x1b[C",
+	"Left":      "\x1b[D",
+	"Home":      "\x1b[H",
+	"End":       "\x1b[F",
+	"PageUp":    "\x1b[5~",
+	"PageDown":  "\x1b[6~",
+}
+
+// Keys lists the key names a Step may use, besides Ctrl-A to Ctrl-Z.
+func Keys() []string {
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// keyBytes returns what pressing the named key sends.
+func keyBytes(name string) (string, bool) {
+	if b, ok := keys[name]; ok {
+		return b, true
+	}
+	if len(name) == 6 && strings.HasPrefix(name, "Ctrl-") {
+		if c := name[5] | 0x20; c >= 'a' && c <= 'z' {
+			return string(rune(c & 0x1f)), true
+		}
+	}
+	return "", false
+}
+
+// Play sends m's steps to write in order, sleeping for the pauses. It
+// stops at the first write error.
+func Play(m Macro, write func([]byte) error) error {
+	for _, st := range m.Steps {
+		switch {
+		case st.DelayMS > 0:
+			time.Sleep(time.Duration(st.DelayMS) * time.Millisecond)
+		case st.Key != "":
+			b, _ := keyBytes(st.Key)
+			if err := write([]byte(b)); err != nil {
+				return err
+			}
+		default:
+			if err := write([]byte(st.Text)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validate(name string, steps []Step) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	case utf8.RuneCountInString(name) > maxNameLen:
+		return fmt.Errorf("%w: name longer than %d characters", ErrInvalid, maxNameLen)
+	case len(steps) == 0:
+		return fmt.Errorf("%w: at least one step is required", ErrInvalid)
+	case len(steps) > maxSteps:
+		return fmt.Errorf("%w: at most %d steps", ErrInvalid, maxSteps)
+	}
+	var delay time.Duration
+	for i, st := range steps {
+		set := 0
+		for _, ok := range []bool{st.Key != "", st.Text != "", st.DelayMS != 0} {
+			if ok {
+				set++
+			}
+		}
+		if set != 1 {
+			return fmt.Errorf("%w: step %d needs exactly one of key, text or delayMs", ErrInvalid, i+1)
+		}
+		if st.Key != "" {
+			if _, ok := keyBytes(st.Key); !ok {
+				return fmt.Errorf("%w: step %d: unknown key %q", ErrInvalid, i+1, st.Key)
+			}
+		}
+		if len(st.Text) > maxTextLen {
+			return fmt.Errorf("%w: step %d: text larger than %d bytes", ErrInvalid, i+1, maxTextLen)
+		}
+		if st.DelayMS < 0 {
+			return fmt.Errorf("%w: step %d: negative delay", ErrInvalid, i+1)
+		}
+		delay += time.Duration(st.DelayMS) * time.Millisecond
+	}
+	if delay > maxTotalDelay {
+		return fmt.Errorf("%w: pauses add up to more than %s", ErrInvalid, maxTotalDelay)
+	}
+	return nil
+}
+
+// Store holds the macros, sorted by name, and writes every change
+// through to disk.
+type Store struct {
+	mu     sync.Mutex
+	path   string
+	macros []Macro
+	logger *slog.Logger
+}
+
+// NewStore loads the macros saved in the config directory. A missing
+// or unreadable file is an empty set; the latter is logged and only
+// replaced by the next change.
+func NewStore(logger *slog.Logger) *Store {
+	return newStore(logger, filepath.Join(configdir.Path(), fileName))
+}
+
+func newStore(logger *slog.Logger, path string) *Store {
+	st := &Store{path: path, logger: logger}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warn("failed to read macros", "err", err)
+		}
+		return st
+	}
+	if err := json.Unmarshal(data, &st.macros); err != nil {
+		logger.Warn("invalid macros file, ignoring", "path", path, "err", err)
+		st.macros = nil
+	}
+	return st
+}
+
+// List returns every macro, sorted by name.
+func (st *Store) List() []Macro {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return slices.Clone(st.macros)
+}
+
+// Get returns the macro with id.
+func (st *Store) Get(id string) (Macro, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	i := st.index(id)
+	if i < 0 {
+		return Macro{}, ErrNotFound
+	}
+	return st.macros[i], nil
+}
+
+// Create saves a new macro.
+func (st *Store) Create(name string, steps []Step) (Macro, error) {
+	name = strings.TrimSpace(name)
+	if err := validate(name, steps); err != nil {
+		return Macro{}, err
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if len(st.macros) >= maxMacros {
+		return Macro{}, fmt.Errorf("%w: at most %d macros", ErrInvalid, maxMacros)
+	}
+	now := time.Now().UTC()
+	m := Macro{ID: newID(), Name: name, Steps: steps, CreatedAt: now, UpdatedAt: now}
+	st.macros = append(st.macros, m)
+	return m, st.saveLocked()
+}
+
+// Update changes the name and/or steps of the macro with id; nil
+// leaves a field as it is.
+func (st *Store) Update(id string, name *string, steps []Step) (Macro, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	i := st.index(id)
+	if i < 0 {
+		return Macro{}, ErrNotFound
+	}
+	m := st.macros[i]
+	if name != nil {
+		m.Name = strings.TrimSpace(*name)
+	}
+	if steps != nil {
+		m.Steps = steps
+	}
+	if err := validate(m.Name, m.Steps); err != nil {
+		return Macro{}, err
+	}
+	m.UpdatedAt = time.Now().UTC()
+	st.macros[i] = m
+	return m, st.saveLocked()
+}
+
+// Delete removes the macro with id.
+func (st *Store) Delete(id string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	i := st.index(id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	st.macros = slices.Delete(st.macros, i, i+1)
+	return st.saveLocked()
+}
+
+func (st *Store) index(id string) int {
+	return slices.IndexFunc(st.macros, func(m Macro) bool { return m.ID == id })
+}
+
+// saveLocked sorts the macros and writes them out. Caller holds mu.
+func (st *Store) saveLocked() error {
+	slices.SortStableFunc(st.macros, func(a, b Macro) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+	if err := os.MkdirAll(filepath.Dir(st.path), 0o755); err != nil {
+		return err
+	}
+	return atomicfile.WriteJSON(st.path, st.macros, 0o600)
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return "mc_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,61 @@
+package macro
+
+import (
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlay(t *testing.T) {
+	m := Macro{Steps: []Step{{Key: "Escape"}, {Key: "Escape"}, {DelayMS: 1}, {Text: "/compact"}, {Key: "Enter"}, {Key: "Ctrl-C"}}}
+	if err := validate("compact", m.Steps); err != nil {
+		t.Fatal(err)
+	}
+	var got []byte
+	if err := Play(m, func(b []byte) error { got = append(got, b...); return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if want := "\x1b\x1b/compact\r\x03"; string(got) != want {
+		t.Errorf("played %q, want %q", got, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, steps := range [][]Step{
+		nil,
+		{{Key: "Hyper"}},
+		{{Key: "Ctrl-1"}},
+		{{Key: "Enter", Text: "x"}},
+		{{}},
+		{{DelayMS: -1}},
+		{{DelayMS: 6000}, {DelayMS: 6000}},
+	} {
+		if err := validate("m", steps); !errors.Is(err, ErrInvalid) {
+			t.Errorf("validate(%+v) = %v, want ErrInvalid", steps, err)
+		}
+	}
+}
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), fileName)
+	st := newStore(slog.Default(), path)
+	m, err := st.Create("compact", []Step{{Key: "Escape"}, {Text: "/compact"}, {Key: "Enter"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := "Compact"
+	if _, err := st.Update(m.ID, &name, nil); err != nil {
+		t.Fatal(err)
+	}
+	got, err := newStore(slog.Default(), path).Get(m.ID)
+	if err != nil || got.Name != "Compact" || len(got.Steps) != 3 {
+		t.Fatalf("reloaded = %+v, %v", got, err)
+	}
+	if err := st.Delete(m.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Delete(m.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second delete: err = %v", err)
+	}
+}
@@ -0,0 +1,200 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/loppo-llc/kojo/internal/atomicfile"
+	"github.com/loppo-llc/kojo/internal/configdir"
+)
+
+const preferencesFile = "push_preferences.json"
+
+// Event kinds a notification is filed under, for Preferences.Events.
+const (
+	// EventExit: a session exited cleanly.
+	EventExit = "exit"
+	// EventError: a session exited with a non-zero code.
+	EventError = "error"
+	// EventDone: an agent finished its turn.
+	EventDone = "done"
+	// EventNeedsInput: an agent asked a question or a session rang
+	// the bell.
+	EventNeedsInput = "needs-input"
+	// EventYoloApprove: yolo mode auto-approved a prompt.
+	EventYoloApprove = "yolo-approve"
+)
+
+// EventKinds lists every kind, in the order the UI shows them.
+var EventKinds = []string{EventExit, EventError, EventDone, EventNeedsInput, EventYoloApprove}
+
+// DefaultEvents are the kinds sent when Preferences.Events is empty.
+// Yolo approvals happen on every prompt of a yolo session, so they are
+// opt-in.
+var DefaultEvents = []string{EventExit, EventError, EventDone, EventNeedsInput}
+
+// ErrUnknownSubscription is returned for an endpoint no subscription has.
+var ErrUnknownSubscription = errors.New("unknown push subscription")
+
+// Event says what a notification is about so preferences can filter it.
+type Event struct {
+	Kind string
+	// Tool is the session's tool (claude, codex, ...) or the agent's.
+	Tool string
+	// Source is the session or agent ID.
+	Source string
+}
+
+// Preferences filter notifications, globally and per subscription; a
+// notification goes out to a subscription only if both allow it. Empty
+// lists don't filter (Events falls back to DefaultEvents).
+type Preferences struct {
+	Events []string `json:"events,omitempty"`
+	Tools  []string `json:"tools,omitempty"`
+	// Sources are session or agent IDs.
+	Sources    []string    `json:"sources,omitempty"`
+	QuietHours *QuietHours `json:"quietHours,omitempty"`
+}
+
+// QuietHours silences notifications from Start to End, "HH:MM" in the
+// server's local time. A range past midnight ("22:00" to "07:00") wraps.
+type QuietHours struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// Validate rejects unknown event kinds and malformed quiet hours.
+func (p Preferences) Validate() error {
+	for _, k := range p.Events {
+		if !slices.Contains(EventKinds, k) {
+			return fmt.Errorf("unknown event kind %q (want one of %v)", k, EventKinds)
+		}
+	}
+	if q := p.QuietHours; q != nil {
+		if _, err := time.Parse("15:04", q.Start); err != nil {
+			return fmt.Errorf("quietHours.start %q: want HH:MM", q.Start)
+		}
+		if _, err := time.Parse("15:04", q.End); err != nil {
+			return fmt.Errorf("quietHours.end %q: want HH:MM", q.End)
+		}
+	}
+	return nil
+}
+
+// Allows reports whether ev may be sent at now.
+func (p Preferences) Allows(ev Event, now time.Time) bool {
+	events := p.Events
+	if len(events) == 0 {
+		events = DefaultEvents
+	}
+	if !slices.Contains(events, ev.Kind) {
+		return false
+	}
+	if len(p.Tools) > 0 && !slices.Contains(p.Tools, ev.Tool) {
+		return false
+	}
+	if len(p.Sources) > 0 && !slices.Contains(p.Sources, ev.Source) {
+		return false
+	}
+	return p.QuietHours == nil || !p.QuietHours.contains(now)
+}
+
+func (q *QuietHours) contains(now time.Time) bool {
+	start, err1 := time.Parse("15:04", q.Start)
+	end, err2 := time.Parse("15:04", q.End)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	minute := func(t time.Time) int { return t.Hour()*60 + t.Minute() }
+	m, s, e := minute(now), minute(start), minute(end)
+	if s <= e {
+		return m >= s && m < e
+	}
+	return m >= s || m < e
+}
+
+// GlobalPreferences returns the preferences every notification passes.
+func (m *Manager) GlobalPreferences() Preferences {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.global
+}
+
+// SetGlobalPreferences validates and persists p.
+func (m *Manager) SetGlobalPreferences(p Preferences) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.global = p
+	m.mu.Unlock()
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := configdir.Path()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return atomicfile.WriteBytes(filepath.Join(dir, preferencesFile), data, 0o600)
+}
+
+// SubscriptionPreferences returns the preferences of the subscription
+// with endpoint.
+func (m *Manager) SubscriptionPreferences(endpoint string) (Preferences, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, sub := range m.subscriptions {
+		if sub.Endpoint == endpoint {
+			return sub.Preferences, nil
+		}
+	}
+	return Preferences{}, ErrUnknownSubscription
+}
+
+// SetSubscriptionPreferences validates p and stores it on the
+// subscription with endpoint.
+func (m *Manager) SetSubscriptionPreferences(endpoint string, p Preferences) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	found := false
+	for _, sub := range m.subscriptions {
+		if sub.Endpoint == endpoint {
+			sub.Preferences = p
+			found = true
+			break
+		}
+	}
+	m.mu.Unlock()
+	if !found {
+		return ErrUnknownSubscription
+	}
+	m.persistSubscriptions()
+	return nil
+}
+
+func (m *Manager) loadPreferences() {
+	path := filepath.Join(configdir.Path(), preferencesFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			m.logger.Warn("failed to read push preferences", "err", err)
+		}
+		return
+	}
+	var p Preferences
+	if err := json.Unmarshal(data, &p); err != nil || p.Validate() != nil {
+		m.logger.Warn("invalid push preferences file, ignoring", "path", path, "err", err)
+		return
+	}
+	m.mu.Lock()
+	m.global = p
+	m.mu.Unlock()
+}
@@ -0,0 +1,49 @@
+package notify
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPreferencesAllows(t *testing.T) {
+	at := func(hhmm string) time.Time {
+		tm, _ := time.Parse("15:04", hhmm)
+		return tm
+	}
+	exit := Event{Kind: EventExit, Tool: "claude", Source: "s_1"}
+	yolo := Event{Kind: EventYoloApprove, Tool: "claude", Source: "s_1"}
+	cases := []struct {
+		name string
+		p    Preferences
+		ev   Event
+		now  time.Time
+		want bool
+	}{
+		{"defaults send exits", Preferences{}, exit, at("12:00"), true},
+		{"defaults skip yolo", Preferences{}, yolo, at("12:00"), false},
+		{"opted into yolo", Preferences{Events: []string{EventYoloApprove}}, yolo, at("12:00"), true},
+		{"event filtered out", Preferences{Events: []string{EventError}}, exit, at("12:00"), false},
+		{"other tool", Preferences{Tools: []string{"codex"}}, exit, at("12:00"), false},
+		{"other source", Preferences{Sources: []string{"s_2"}}, exit, at("12:00"), false},
+		{"quiet overnight", Preferences{QuietHours: &QuietHours{Start: "22:00", End: "07:00"}}, exit, at("23:30"), false},
+		{"quiet ended", Preferences{QuietHours: &QuietHours{Start: "22:00", End: "07:00"}}, exit, at("07:00"), true},
+		{"quiet daytime", Preferences{QuietHours: &QuietHours{Start: "09:00", End: "17:00"}}, exit, at("08:59"), true},
+	}
+	for _, c := range cases {
+		if got := c.p.Allows(c.ev, c.now); got != c.want {
+			t.Errorf("%s: Allows = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestPreferencesValidate(t *testing.T) {
+	for _, p := range []Preferences{
+		{Events: []string{"exit", "crash"}},
+		{QuietHours: &QuietHours{Start: "25:00", End: "07:00"}},
+		{QuietHours: &QuietHours{Start: "22:00"}},
+	} {
+		if err := p.Validate(); err == nil {
+			t.Errorf("Validate(%+v) = nil, want error", p)
+		}
+	}
+}
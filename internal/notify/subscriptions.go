@@ -0,0 +1,123 @@
+package notify
+
+import (
+	"time"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+)
+
+// maxLabelLen caps a device label.
+const maxLabelLen = 64
+
+// subscription is a browser push subscription with its label and
+// preferences, as kept in the subscriptions file. The delivery fields
+// are diagnostics for the management endpoint and live in memory only.
+type subscription struct {
+	webpush.Subscription
+	// Label names the device ("iPhone", "work laptop").
+	Label       string      `json:"label,omitempty"`
+	Preferences Preferences `json:"preferences"`
+	CreatedAt   time.Time   `json:"createdAt,omitzero"`
+
+	lastSentAt time.Time
+	lastStatus int
+	lastError  string
+}
+
+// SubscriptionInfo describes a subscription for GET
+// /api/v1/push/subscriptions. LastStatus is the push service's HTTP
+// status for the latest delivery (0 when it couldn't be reached, see
+// LastError).
+type SubscriptionInfo struct {
+	Endpoint    string      `json:"endpoint"`
+	Label       string      `json:"label,omitempty"`
+	Preferences Preferences `json:"preferences"`
+	CreatedAt   *time.Time  `json:"createdAt,omitempty"`
+	LastSentAt  *time.Time  `json:"lastSentAt,omitempty"`
+	LastStatus  int         `json:"lastStatus,omitempty"`
+	LastError   string      `json:"lastError,omitempty"`
+}
+
+// Subscriptions lists the subscriptions, oldest first.
+func (m *Manager) Subscriptions() []SubscriptionInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]SubscriptionInfo, len(m.subscriptions))
+	for i, sub := range m.subscriptions {
+		out[i] = SubscriptionInfo{
+			Endpoint:    sub.Endpoint,
+			Label:       sub.Label,
+			Preferences: sub.Preferences,
+			LastStatus:  sub.lastStatus,
+			LastError:   sub.lastError,
+		}
+		if !sub.CreatedAt.IsZero() {
+			t := sub.CreatedAt
+			out[i].CreatedAt = &t
+		}
+		if !sub.lastSentAt.IsZero() {
+			t := sub.lastSentAt
+			out[i].LastSentAt = &t
+		}
+	}
+	return out
+}
+
+// SetLabel names the device behind endpoint. Labels are trimmed to
+// maxLabelLen bytes.
+func (m *Manager) SetLabel(endpoint, label string) error {
+	if len(label) > maxLabelLen {
+		label = label[:maxLabelLen]
+	}
+	m.mu.Lock()
+	found := false
+	for _, sub := range m.subscriptions {
+		if sub.Endpoint == endpoint {
+			sub.Label = label
+			found = true
+			break
+		}
+	}
+	m.mu.Unlock()
+	if !found {
+		return ErrUnknownSubscription
+	}
+	m.persistSubscriptions()
+	return nil
+}
+
+// SendTo pushes payload to one subscription regardless of preferences
+// and returns the push service's HTTP status; a subscription it reports
+// expired is removed. The error is ErrUnknownSubscription or a failure
+// to reach the push service.
+func (m *Manager) SendTo(endpoint string, payload []byte) (int, error) {
+	m.mu.Lock()
+	var target *webpush.Subscription
+	for _, sub := range m.subscriptions {
+		if sub.Endpoint == endpoint {
+			s := sub.Subscription
+			target = &s
+			break
+		}
+	}
+	m.mu.Unlock()
+	if target == nil {
+		return 0, ErrUnknownSubscription
+	}
+	status, err := m.deliver(target, payload)
+	if err == nil && isExpiredStatus(status) {
+		m.removeEndpoints([]string{endpoint})
+	}
+	return status, err
+}
+
+func (m *Manager) recordDelivery(endpoint string, status int, detail string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, sub := range m.subscriptions {
+		if sub.Endpoint == endpoint {
+			sub.lastSentAt, sub.lastStatus, sub.lastError = time.Now(), status, detail
+			return
+		}
+	}
+}
@@ -26,7 +26,9 @@ type Manager struct {
 	logger        *slog.Logger
 	vapidPrivate  string
 	vapidPublic   string
-	subscriptions []*webpush.Subscription
+	subscriptions []*subscription
+	// global filters every notification; see Preferences.
+	global Preferences
 	// persistMu serializes writes to the subscriptions file so concurrent
 	// Subscribe / Unsubscribe / Send-driven persists cannot race on the
 	// shared .tmp filename or commit out-of-order snapshots.
@@ -74,13 +76,14 @@ func NewManager(logger *slog.Logger) (*Manager, error) {
 func newManager(logger *slog.Logger, store VAPIDStore) (*Manager, error) {
 	m := &Manager{
 		logger:        logger,
-		subscriptions: make([]*webpush.Subscription, 0),
+		subscriptions: make([]*subscription, 0),
 		vapidStore:    store,
 	}
 	if err := m.loadOrGenerateVAPID(); err != nil {
 		return nil, err
 	}
 	m.loadSubscriptions()
+	m.loadPreferences()
 	return m, nil
 }
 
@@ -88,17 +91,19 @@ func (m *Manager) VAPIDPublicKey() string {
 	return m.vapidPublic
 }
 
+// Subscribe adds sub, or refreshes the keys of a known endpoint while
+// keeping its preferences.
 func (m *Manager) Subscribe(sub *webpush.Subscription) {
 	m.mu.Lock()
-	for i, existing := range m.subscriptions {
+	for _, existing := range m.subscriptions {
 		if existing.Endpoint == sub.Endpoint {
-			m.subscriptions[i] = sub
+			existing.Subscription = *sub
 			m.mu.Unlock()
 			m.persistSubscriptions()
 			return
 		}
 	}
-	m.subscriptions = append(m.subscriptions, sub)
+	m.subscriptions = append(m.subscriptions, &subscription{Subscription: *sub, CreatedAt: time.Now()})
 	m.mu.Unlock()
 	ep := sub.Endpoint
 	if len(ep) > 50 {
@@ -124,65 +129,99 @@ func (m *Manager) Unsubscribe(endpoint string) {
 	}
 }
 
-func (m *Manager) Send(payload []byte) {
+// Send pushes payload to every subscription whose preferences, and the
+// global ones, allow ev.
+func (m *Manager) Send(ev Event, payload []byte) {
+	now := time.Now()
 	m.mu.Lock()
-	subs := make([]*webpush.Subscription, len(m.subscriptions))
-	copy(subs, m.subscriptions)
+	var subs []webpush.Subscription
+	if m.global.Allows(ev, now) {
+		for _, sub := range m.subscriptions {
+			if sub.Preferences.Allows(ev, now) {
+				subs = append(subs, sub.Subscription)
+			}
+		}
+	}
 	m.mu.Unlock()
 
 	var expired []string
-
 	for _, sub := range subs {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		resp, err := webpush.SendNotificationWithContext(ctx, payload, sub, &webpush.Options{
-			VAPIDPublicKey:  m.vapidPublic,
-			VAPIDPrivateKey: m.vapidPrivate,
-			Subscriber:      "kojo@localhost",
-			TTL:             86400, // 24 hours
-			Urgency:         webpush.UrgencyHigh,
-			// webpush-go pads the encrypted record up to RecordSize bytes.
-			// Default (4096) yields a request body that Mozilla autopush rejects with 413.
-			// 2048 still gives plenty of length-hiding padding while staying well below
-			// every push provider's documented payload cap.
-			RecordSize: 2048,
-		})
-		cancel()
-		if err != nil {
-			m.logger.Warn("push send failed", "err", err)
-			continue
-		}
-		resp.Body.Close()
-
-		if resp.StatusCode == 410 || resp.StatusCode == 404 {
+		if status, err := m.deliver(&sub, payload); err == nil && isExpiredStatus(status) {
 			expired = append(expired, sub.Endpoint)
-			m.logger.Info("push subscription expired, removing", "status", resp.StatusCode)
-		} else if resp.StatusCode >= 400 {
-			ep := sub.Endpoint
-			if len(ep) > 50 {
-				ep = ep[:50] + "..."
-			}
-			m.logger.Warn("push send error", "status", resp.StatusCode, "endpoint", ep)
 		}
 	}
 
-	// remove expired subscriptions in one pass to avoid N persistence writes
-	if len(expired) > 0 {
-		expiredSet := make(map[string]struct{}, len(expired))
-		for _, ep := range expired {
-			expiredSet[ep] = struct{}{}
+	m.removeEndpoints(expired)
+}
+
+// removeEndpoints drops the subscriptions with the given endpoints in
+// one pass, to avoid N persistence writes.
+func (m *Manager) removeEndpoints(endpoints []string) {
+	if len(endpoints) == 0 {
+		return
+	}
+	drop := make(map[string]struct{}, len(endpoints))
+	for _, ep := range endpoints {
+		drop[ep] = struct{}{}
+	}
+	m.mu.Lock()
+	kept := m.subscriptions[:0]
+	for _, s := range m.subscriptions {
+		if _, ok := drop[s.Endpoint]; ok {
+			continue
 		}
-		m.mu.Lock()
-		kept := m.subscriptions[:0]
-		for _, s := range m.subscriptions {
-			if _, drop := expiredSet[s.Endpoint]; drop {
-				continue
-			}
-			kept = append(kept, s)
+		kept = append(kept, s)
+	}
+	m.subscriptions = kept
+	m.mu.Unlock()
+	m.persistSubscriptions()
+}
+
+// deliver sends payload to one subscription and records the outcome
+// on it. A non-nil error means the push service wasn't reached; its
+// answer is in status.
+func (m *Manager) deliver(sub *webpush.Subscription, payload []byte) (status int, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	resp, err := webpush.SendNotificationWithContext(ctx, payload, sub, &webpush.Options{
+		VAPIDPublicKey:  m.vapidPublic,
+		VAPIDPrivateKey: m.vapidPrivate,
+		Subscriber:      "kojo@localhost",
+		TTL:             86400, // 24 hours
+		Urgency:         webpush.UrgencyHigh,
+		// webpush-go pads the encrypted record up to RecordSize bytes.
+		// Default (4096) yields a request body that Mozilla autopush rejects with 413.
+		// 2048 still gives plenty of length-hiding padding while staying well below
+		// every push provider's documented payload cap.
+		RecordSize: 2048,
+	})
+	cancel()
+	if err != nil {
+		m.logger.Warn("push send failed", "err", err)
+		m.recordDelivery(sub.Endpoint, 0, err.Error())
+		return 0, err
+	}
+	resp.Body.Close()
+
+	detail := ""
+	if isExpiredStatus(resp.StatusCode) {
+		m.logger.Info("push subscription expired, removing", "status", resp.StatusCode)
+		detail = "subscription expired"
+	} else if resp.StatusCode >= 400 {
+		ep := sub.Endpoint
+		if len(ep) > 50 {
+			ep = ep[:50] + "..."
 		}
-		m.subscriptions = kept
-		m.mu.Unlock()
-		m.persistSubscriptions()
+		m.logger.Warn("push send error", "status", resp.StatusCode, "endpoint", ep)
+		detail = resp.Status
 	}
+	m.recordDelivery(sub.Endpoint, resp.StatusCode, detail)
+	return resp.StatusCode, nil
+}
+
+// isExpiredStatus reports a push service answer meaning the
+// subscription is gone for good.
+func isExpiredStatus(status int) bool {
+	return status == 410 || status == 404
 }
 
 func (m *Manager) loadOrGenerateVAPID() error {
@@ -288,7 +327,7 @@ func (m *Manager) loadSubscriptions() {
 		}
 		return
 	}
-	var subs []*webpush.Subscription
+	var subs []*subscription
 	if err := json.Unmarshal(data, &subs); err != nil {
 		m.logger.Warn("corrupted push subscriptions file, ignoring", "path", path, "err", err)
 		return
@@ -309,6 +348,39 @@ func (m *Manager) loadSubscriptions() {
 	}
 }
 
+// SeedSubscriptions adopts subs when no subscriptions file has been
+// written yet — the first boot after the v0→v1 migration, which moves
+// v0's subscriptions into the database rather than the v1 config dir.
+// Once a file exists it is authoritative, so endpoints pruned since are
+// not brought back. Entries missing required fields or already known
+// are skipped. Returns how many were added.
+func (m *Manager) SeedSubscriptions(subs []*webpush.Subscription) int {
+	path := filepath.Join(configdir.Path(), subscriptionsFile)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return 0
+	}
+	m.mu.Lock()
+	known := make(map[string]bool, len(m.subscriptions))
+	for _, s := range m.subscriptions {
+		known[s.Endpoint] = true
+	}
+	added := 0
+	for _, s := range subs {
+		if s == nil || s.Endpoint == "" || s.Keys.Auth == "" || s.Keys.P256dh == "" || known[s.Endpoint] {
+			continue
+		}
+		known[s.Endpoint] = true
+		m.subscriptions = append(m.subscriptions, &subscription{Subscription: *s, CreatedAt: time.Now()})
+		added++
+	}
+	m.mu.Unlock()
+	if added > 0 {
+		m.logger.Info("restored push subscriptions from store", "count", added)
+		m.persistSubscriptions()
+	}
+	return added
+}
+
 // persistSubscriptions writes the current subscription list to disk. Best
 // effort: failures are logged but never returned, since a missed save just
 // means a subscription has to re-register on next page load.
@@ -324,8 +396,10 @@ func (m *Manager) persistSubscriptions() {
 	path := filepath.Join(dir, subscriptionsFile)
 
 	m.mu.Lock()
-	snapshot := make([]*webpush.Subscription, len(m.subscriptions))
-	copy(snapshot, m.subscriptions)
+	snapshot := make([]subscription, len(m.subscriptions))
+	for i, sub := range m.subscriptions {
+		snapshot[i] = *sub
+	}
 	m.mu.Unlock()
 
 	if err := os.MkdirAll(dir, 0o755); err != nil {
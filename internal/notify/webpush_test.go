@@ -0,0 +1,127 @@
+package notify
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+
+	"github.com/loppo-llc/kojo/internal/configdir"
+)
+
+func TestSeedSubscriptionsOnlyBeforeFirstPersist(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("APPDATA", t.TempDir())
+	m := &Manager{logger: slog.Default()}
+	sub := func(ep string) *webpush.Subscription {
+		return &webpush.Subscription{Endpoint: ep, Keys: webpush.Keys{Auth: "a", P256dh: "p"}}
+	}
+
+	if n := m.SeedSubscriptions([]*webpush.Subscription{sub("https://push/1"), sub("https://push/1"), {Endpoint: "https://push/bad"}}); n != 1 {
+		t.Fatalf("seeded %d, want 1", n)
+	}
+	if _, err := os.Stat(filepath.Join(configdir.Path(), subscriptionsFile)); err != nil {
+		t.Fatalf("seed not persisted: %v", err)
+	}
+
+	// The file now exists, so a pruned endpoint isn't brought back.
+	m.Unsubscribe("https://push/1")
+	if n := m.SeedSubscriptions([]*webpush.Subscription{sub("https://push/1")}); n != 0 {
+		t.Fatalf("seeded %d after the file was written, want 0", n)
+	}
+
+	reloaded := &Manager{logger: slog.Default()}
+	reloaded.loadSubscriptions()
+	if len(reloaded.subscriptions) != 0 {
+		t.Fatalf("reloaded %d subscriptions, want 0", len(reloaded.subscriptions))
+	}
+}
+
+func TestSubscribeKeepsPreferences(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("APPDATA", t.TempDir())
+	m := &Manager{logger: slog.Default()}
+	sub := &webpush.Subscription{Endpoint: "https://push/1", Keys: webpush.Keys{Auth: "a", P256dh: "p"}}
+	m.Subscribe(sub)
+	prefs := Preferences{Events: []string{EventNeedsInput}}
+	if err := m.SetSubscriptionPreferences(sub.Endpoint, prefs); err != nil {
+		t.Fatal(err)
+	}
+	// Browsers re-register on every page load, often with new keys.
+	m.Subscribe(&webpush.Subscription{Endpoint: sub.Endpoint, Keys: webpush.Keys{Auth: "b", P256dh: "q"}})
+
+	reloaded := &Manager{logger: slog.Default()}
+	reloaded.loadSubscriptions()
+	got, err := reloaded.SubscriptionPreferences(sub.Endpoint)
+	if err != nil || len(got.Events) != 1 || got.Events[0] != EventNeedsInput {
+		t.Fatalf("preferences after resubscribe = %+v, %v", got, err)
+	}
+	if reloaded.subscriptions[0].Keys.Auth != "b" {
+		t.Fatalf("keys not refreshed: %+v", reloaded.subscriptions[0].Keys)
+	}
+	if err := m.SetSubscriptionPreferences("https://push/none", prefs); err != ErrUnknownSubscription {
+		t.Fatalf("unknown endpoint: err = %v", err)
+	}
+}
+
+func TestSendToRecordsDeliveryAndDropsExpired(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("APPDATA", t.TempDir())
+	status := http.StatusCreated
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	priv, pub, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &Manager{logger: slog.Default(), vapidPrivate: priv, vapidPublic: pub}
+	m.Subscribe(testSubscription(t, srv.URL+"/sub"))
+	if err := m.SetLabel(srv.URL+"/sub", "iPhone"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := m.SendTo(srv.URL+"/sub", []byte(`{"type":"test"}`)); err != nil || got != http.StatusCreated {
+		t.Fatalf("SendTo = %d, %v", got, err)
+	}
+	infos := m.Subscriptions()
+	if len(infos) != 1 || infos[0].Label != "iPhone" || infos[0].LastStatus != http.StatusCreated || infos[0].LastSentAt == nil {
+		t.Fatalf("Subscriptions = %+v", infos)
+	}
+
+	status = http.StatusGone
+	if got, _ := m.SendTo(srv.URL+"/sub", []byte(`{"type":"test"}`)); got != http.StatusGone {
+		t.Fatalf("SendTo = %d, want 410", got)
+	}
+	if n := len(m.Subscriptions()); n != 0 {
+		t.Fatalf("%d subscriptions after 410, want 0", n)
+	}
+	if _, err := m.SendTo(srv.URL+"/sub", nil); err != ErrUnknownSubscription {
+		t.Fatalf("SendTo removed endpoint: err = %v", err)
+	}
+}
+
+// testSubscription returns a subscription with real browser-side keys
+// so webpush can encrypt to it.
+func testSubscription(t *testing.T, endpoint string) *webpush.Subscription {
+	t.Helper()
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := make([]byte, 16)
+	rand.Read(auth)
+	return &webpush.Subscription{Endpoint: endpoint, Keys: webpush.Keys{
+		P256dh: base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()),
+		Auth:   base64.RawURLEncoding.EncodeToString(auth),
+	}}
+}
@@ -0,0 +1,150 @@
+// Package prefs keeps each user's Web UI preferences (theme, font
+// size, defaults for new sessions) on the server, so they follow the
+// user from one device to the next instead of living in one browser's
+// localStorage. They are persisted as preferences.json in the config
+// directory.
+package prefs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/loppo-llc/kojo/internal/atomicfile"
+	"github.com/loppo-llc/kojo/internal/configdir"
+)
+
+const (
+	fileName = "preferences.json"
+	// Limits on what the API accepts.
+	minFontSize   = 8
+	maxFontSize   = 32
+	maxWorkDirLen = 4096
+)
+
+var ErrInvalid = errors.New("invalid preferences")
+
+var (
+	themes = []string{"system", "light", "dark"}
+	bells  = []string{"off", "sound", "visual"}
+)
+
+// Prefs are one user's preferences. A zero field is unset and leaves
+// the Web UI's own default in place.
+type Prefs struct {
+	Theme          string    `json:"theme,omitempty"`
+	FontSize       int       `json:"fontSize,omitempty"`
+	DefaultTool    string    `json:"defaultTool,omitempty"`
+	DefaultWorkDir string    `json:"defaultWorkDir,omitempty"`
+	TerminalBell   string    `json:"terminalBell,omitempty"`
+	UpdatedAt      time.Time `json:"updatedAt,omitzero"`
+}
+
+// Patch is a partial update: nil leaves a field as it is, and an empty
+// string or 0 unsets it.
+type Patch struct {
+	Theme          *string `json:"theme"`
+	FontSize       *int    `json:"fontSize"`
+	DefaultTool    *string `json:"defaultTool"`
+	DefaultWorkDir *string `json:"defaultWorkDir"`
+	TerminalBell   *string `json:"terminalBell"`
+}
+
+func (pt Patch) apply(p Prefs) Prefs {
+	if pt.Theme != nil {
+		p.Theme = *pt.Theme
+	}
+	if pt.FontSize != nil {
+		p.FontSize = *pt.FontSize
+	}
+	if pt.DefaultTool != nil {
+		p.DefaultTool = *pt.DefaultTool
+	}
+	if pt.DefaultWorkDir != nil {
+		p.DefaultWorkDir = *pt.DefaultWorkDir
+	}
+	if pt.TerminalBell != nil {
+		p.TerminalBell = *pt.TerminalBell
+	}
+	return p
+}
+
+// validate checks p's fields. The tool is left to the caller, which
+// knows the tools sessions accept.
+func validate(p Prefs) error {
+	switch {
+	case p.Theme != "" && !slices.Contains(themes, p.Theme):
+		return fmt.Errorf("%w: theme must be one of %v", ErrInvalid, themes)
+	case p.FontSize != 0 && (p.FontSize < minFontSize || p.FontSize > maxFontSize):
+		return fmt.Errorf("%w: fontSize must be between %d and %d", ErrInvalid, minFontSize, maxFontSize)
+	case len(p.DefaultWorkDir) > maxWorkDirLen:
+		return fmt.Errorf("%w: defaultWorkDir longer than %d bytes", ErrInvalid, maxWorkDirLen)
+	case p.DefaultWorkDir != "" && !filepath.IsAbs(p.DefaultWorkDir):
+		return fmt.Errorf("%w: defaultWorkDir must be an absolute path", ErrInvalid)
+	case p.TerminalBell != "" && !slices.Contains(bells, p.TerminalBell):
+		return fmt.Errorf("%w: terminalBell must be one of %v", ErrInvalid, bells)
+	}
+	return nil
+}
+
+// Store holds every user's preferences, keyed by user, and writes
+// every change through to disk.
+type Store struct {
+	mu     sync.Mutex
+	path   string
+	prefs  map[string]Prefs
+	logger *slog.Logger
+}
+
+// NewStore loads the preferences saved in the config directory. A
+// missing or unreadable file leaves everyone on the defaults; the
+// latter is logged and only replaced by the next change.
+func NewStore(logger *slog.Logger) *Store {
+	return newStore(logger, filepath.Join(configdir.Path(), fileName))
+}
+
+func newStore(logger *slog.Logger, path string) *Store {
+	st := &Store{path: path, prefs: map[string]Prefs{}, logger: logger}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warn("failed to read preferences", "err", err)
+		}
+		return st
+	}
+	if err := json.Unmarshal(data, &st.prefs); err != nil || st.prefs == nil {
+		logger.Warn("invalid preferences file, ignoring", "path", path, "err", err)
+		st.prefs = map[string]Prefs{}
+	}
+	return st
+}
+
+// Get returns user's preferences; a user who never saved any gets the
+// zero Prefs.
+func (st *Store) Get(user string) Prefs {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.prefs[user]
+}
+
+// Update applies pt to user's preferences and returns the result.
+func (st *Store) Update(user string, pt Patch) (Prefs, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	p := pt.apply(st.prefs[user])
+	if err := validate(p); err != nil {
+		return Prefs{}, err
+	}
+	p.UpdatedAt = time.Now().UTC()
+	st.prefs[user] = p
+	if err := os.MkdirAll(filepath.Dir(st.path), 0o755); err != nil {
+		return Prefs{}, err
+	}
+	return p, atomicfile.WriteJSON(st.path, st.prefs, 0o600)
+}
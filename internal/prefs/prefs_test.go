@@ -0,0 +1,54 @@
+package prefs
+
+import (
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"testing"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, fileName)
+	st := newStore(slog.Default(), path)
+
+	if got := st.Get("owner"); got != (Prefs{}) {
+		t.Fatalf("unsaved prefs = %+v", got)
+	}
+	if _, err := st.Update("owner", Patch{Theme: ptr("dark"), FontSize: ptr(14), TerminalBell: ptr("visual")}); err != nil {
+		t.Fatal(err)
+	}
+	// A patch keeps the fields it leaves out and unsets the empty ones.
+	p, err := st.Update("owner", Patch{FontSize: ptr(16), TerminalBell: ptr("")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Theme != "dark" || p.FontSize != 16 || p.TerminalBell != "" || p.UpdatedAt.IsZero() {
+		t.Errorf("patched = %+v", p)
+	}
+	if _, err := st.Update("agent:ag_1", Patch{DefaultWorkDir: ptr(dir)}); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, pt := range map[string]Patch{
+		"theme":    {Theme: ptr("pink")},
+		"fontSize": {FontSize: ptr(100)},
+		"workDir":  {DefaultWorkDir: ptr("relative/dir")},
+		"bell":     {TerminalBell: ptr("loud")},
+	} {
+		if _, err := st.Update("owner", pt); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+
+	// A reload sees each user's own preferences.
+	re := newStore(slog.Default(), path)
+	if got := re.Get("owner"); got.Theme != "dark" || got.FontSize != 16 || got.DefaultWorkDir != "" {
+		t.Errorf("reloaded owner = %+v", got)
+	}
+	if got := re.Get("agent:ag_1"); got.DefaultWorkDir != dir || got.Theme != "" {
+		t.Errorf("reloaded agent = %+v", got)
+	}
+}
@@ -0,0 +1,84 @@
+// Package qrcode renders QR codes of kojo's access URL: as text for
+// the terminal at startup, and as a PNG for GET /api/v1/qr, so a new
+// phone can be pointed at kojo by scanning instead of typing a ts.net
+// hostname.
+package qrcode
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+// quietZone is the light border, in modules, scanners need around the
+// code.
+const quietZone = 2
+
+// encode returns text's QR code, one bit per module, quiet zone
+// included.
+func encode(text string) (*gozxing.BitMatrix, error) {
+	return qrcode.NewQRCodeWriter().Encode(text, gozxing.BarcodeFormat_QR_CODE, 0, 0,
+		map[gozxing.EncodeHintType]any{
+			gozxing.EncodeHintType_ERROR_CORRECTION: "M",
+			gozxing.EncodeHintType_MARGIN:           quietZone,
+		})
+}
+
+// Terminal returns text's QR code drawn with half-block characters,
+// two modules per character row. Colors are set explicitly, light on
+// black, so the code scans on light and dark terminal themes alike.
+func Terminal(text string) (string, error) {
+	code, err := encode(text)
+	if err != nil {
+		return "", err
+	}
+	size := code.GetWidth()
+	var b strings.Builder
+	for y := 0; y < size; y += 2 {
+		b.WriteString("\x1b[40;97m")
+		for x := 0; x < size; x++ {
+			top, bottom := !code.Get(x, y), y+1 < size && !code.Get(x, y+1)
+			switch {
+			case top && bottom:
+				b.WriteString("█")
+			case top:
+				b.WriteString("▀")
+			case bottom:
+				b.WriteString("▄")
+			default:
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString("\x1b[0m\n")
+	}
+	return b.String(), nil
+}
+
+// PNG returns text's QR code as a PNG image, scale pixels per module.
+func PNG(text string, scale int) ([]byte, error) {
+	code, err := encode(text)
+	if err != nil {
+		return nil, err
+	}
+	size := code.GetWidth()
+	img := image.NewGray(image.Rect(0, 0, size*scale, size*scale))
+	for py := range size * scale {
+		for px := range size * scale {
+			c := color.White
+			if code.Get(px/scale, py/scale) {
+				c = color.Black
+			}
+			img.Set(px, py, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
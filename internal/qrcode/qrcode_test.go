@@ -0,0 +1,51 @@
+package qrcode
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+const testURL = "https://kojo.example-tailnet.ts.net:8080"
+
+func TestPNGDecodes(t *testing.T) {
+	data, err := PNG(testURL, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bmp, err := gozxing.NewBinaryBitmapFromImage(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := qrcode.NewQRCodeReader().Decode(bmp, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.GetText() != testURL {
+		t.Errorf("decoded %q, want %q", res.GetText(), testURL)
+	}
+}
+
+func TestTerminal(t *testing.T) {
+	out, err := Terminal(testURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := encode(testURL)
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if want := (code.GetWidth() + 1) / 2; len(lines) != want {
+		t.Errorf("%d lines, want %d", len(lines), want)
+	}
+	// The first row is quiet zone: all light.
+	if row := strings.TrimSuffix(strings.TrimPrefix(lines[0], "\x1b[40;97m"), "\x1b[0m"); row != strings.Repeat("█", code.GetWidth()) {
+		t.Errorf("first row = %q", row)
+	}
+}
@@ -0,0 +1,279 @@
+// Package schedule starts sessions at set times: each entry is a
+// 5-field cron expression plus what to start (tool, directory, args),
+// e.g. a nightly codex run that triages new issues. Entries are
+// managed over the API and persisted as schedules.json in the config
+// directory; the session's exit notification carries the result.
+package schedule
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/loppo-llc/kojo/internal/atomicfile"
+	"github.com/loppo-llc/kojo/internal/configdir"
+)
+
+const (
+	fileName = "schedules.json"
+	// Limits on what the API accepts.
+	maxEntries = 100
+	maxNameLen = 100
+	maxArgs    = 64
+	maxArgLen  = 8 << 10
+)
+
+var (
+	ErrNotFound = errors.New("schedule not found")
+	ErrInvalid  = errors.New("invalid schedule")
+)
+
+// cronParser takes the standard 5-field form only (minute, hour, day
+// of month, month, day of week), in the server's local time.
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+
+// Spec is what an entry starts and when.
+type Spec struct {
+	Name     string   `json:"name"`
+	Cron     string   `json:"cron"`
+	Tool     string   `json:"tool"`
+	WorkDir  string   `json:"workDir"`
+	Args     []string `json:"args,omitempty"`
+	YoloMode bool     `json:"yoloMode,omitempty"`
+	Enabled  bool     `json:"enabled"`
+}
+
+// Entry is a saved schedule and the outcome of its last run.
+type Entry struct {
+	ID string `json:"id"`
+	Spec
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	// LastRun is when the entry last fired; LastSessionID the session
+	// it started, or LastError why it started none.
+	LastRun       time.Time `json:"lastRun,omitzero"`
+	LastSessionID string    `json:"lastSessionId,omitempty"`
+	LastError     string    `json:"lastError,omitempty"`
+}
+
+// Next returns when e fires next after t, or zero for a disabled or
+// unparsable entry.
+func (e Entry) Next(t time.Time) time.Time {
+	if !e.Enabled {
+		return time.Time{}
+	}
+	sched, err := parseCron(e.Cron)
+	if err != nil {
+		return time.Time{}
+	}
+	return sched.Next(t)
+}
+
+func parseCron(expr string) (cron.Schedule, error) {
+	// The parser would take a leading TZ= descriptor as an extra
+	// field; insist on exactly five.
+	if n := len(strings.Fields(expr)); n != 5 {
+		return nil, fmt.Errorf("%w: cron needs 5 fields (minute hour day month weekday), got %d", ErrInvalid, n)
+	}
+	sched, err := cronParser.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: cron: %v", ErrInvalid, err)
+	}
+	return sched, nil
+}
+
+// validate checks sp; checkTool reports whether a tool name can be
+// started, and is skipped when nil.
+func validate(sp Spec, checkTool func(string) bool) error {
+	switch {
+	case sp.Name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	case utf8.RuneCountInString(sp.Name) > maxNameLen:
+		return fmt.Errorf("%w: name longer than %d characters", ErrInvalid, maxNameLen)
+	case sp.Tool == "":
+		return fmt.Errorf("%w: tool is required", ErrInvalid)
+	case checkTool != nil && !checkTool(sp.Tool):
+		return fmt.Errorf("%w: unknown tool %q", ErrInvalid, sp.Tool)
+	case sp.WorkDir == "" || !filepath.IsAbs(sp.WorkDir):
+		return fmt.Errorf("%w: workDir must be an absolute path", ErrInvalid)
+	case len(sp.Args) > maxArgs:
+		return fmt.Errorf("%w: at most %d args", ErrInvalid, maxArgs)
+	}
+	for i, a := range sp.Args {
+		if len(a) > maxArgLen {
+			return fmt.Errorf("%w: arg %d larger than %d bytes", ErrInvalid, i+1, maxArgLen)
+		}
+	}
+	_, err := parseCron(sp.Cron)
+	return err
+}
+
+// Store holds the schedules, sorted by name, and writes every change
+// through to disk.
+type Store struct {
+	mu        sync.Mutex
+	path      string
+	entries   []Entry
+	checkTool func(string) bool
+	logger    *slog.Logger
+}
+
+// NewStore loads the schedules saved in the config directory. A
+// missing or unreadable file is an empty set; the latter is logged and
+// only replaced by the next change. checkTool, if not nil, limits the
+// tools an entry may name.
+func NewStore(logger *slog.Logger, checkTool func(string) bool) *Store {
+	return newStore(logger, filepath.Join(configdir.Path(), fileName), checkTool)
+}
+
+func newStore(logger *slog.Logger, path string, checkTool func(string) bool) *Store {
+	st := &Store{path: path, checkTool: checkTool, logger: logger}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warn("failed to read schedules", "err", err)
+		}
+		return st
+	}
+	if err := json.Unmarshal(data, &st.entries); err != nil {
+		logger.Warn("invalid schedules file, ignoring", "path", path, "err", err)
+		st.entries = nil
+	}
+	return st
+}
+
+// List returns every schedule, sorted by name.
+func (st *Store) List() []Entry {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return slices.Clone(st.entries)
+}
+
+// Get returns the schedule with id.
+func (st *Store) Get(id string) (Entry, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	i := st.index(id)
+	if i < 0 {
+		return Entry{}, ErrNotFound
+	}
+	return st.entries[i], nil
+}
+
+// BySession returns the schedule whose last run started sessionID.
+func (st *Store) BySession(sessionID string) (Entry, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for _, e := range st.entries {
+		if e.LastSessionID == sessionID && sessionID != "" {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Create saves a new schedule.
+func (st *Store) Create(sp Spec) (Entry, error) {
+	sp.Name = strings.TrimSpace(sp.Name)
+	sp.Cron = strings.TrimSpace(sp.Cron)
+	if err := validate(sp, st.checkTool); err != nil {
+		return Entry{}, err
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if len(st.entries) >= maxEntries {
+		return Entry{}, fmt.Errorf("%w: at most %d schedules", ErrInvalid, maxEntries)
+	}
+	now := time.Now().UTC()
+	e := Entry{ID: newID(), Spec: sp, CreatedAt: now, UpdatedAt: now}
+	st.entries = append(st.entries, e)
+	return e, st.saveLocked()
+}
+
+// Update replaces the spec of the schedule with id after passing the
+// current one to change.
+func (st *Store) Update(id string, change func(*Spec)) (Entry, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	i := st.index(id)
+	if i < 0 {
+		return Entry{}, ErrNotFound
+	}
+	e := st.entries[i]
+	sp := e.Spec
+	sp.Args = slices.Clone(sp.Args)
+	change(&sp)
+	sp.Name = strings.TrimSpace(sp.Name)
+	sp.Cron = strings.TrimSpace(sp.Cron)
+	if err := validate(sp, st.checkTool); err != nil {
+		return Entry{}, err
+	}
+	e.Spec = sp
+	e.UpdatedAt = time.Now().UTC()
+	st.entries[i] = e
+	return e, st.saveLocked()
+}
+
+// Delete removes the schedule with id.
+func (st *Store) Delete(id string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	i := st.index(id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	st.entries = slices.Delete(st.entries, i, i+1)
+	return st.saveLocked()
+}
+
+// recordRun notes that the schedule with id fired at t, starting
+// sessionID or failing with runErr.
+func (st *Store) recordRun(id string, t time.Time, sessionID string, runErr error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	i := st.index(id)
+	if i < 0 {
+		return // deleted while it ran
+	}
+	e := &st.entries[i]
+	e.LastRun, e.LastSessionID, e.LastError = t.UTC(), sessionID, ""
+	if runErr != nil {
+		e.LastError = runErr.Error()
+	}
+	if err := st.saveLocked(); err != nil {
+		st.logger.Warn("failed to save schedules", "err", err)
+	}
+}
+
+func (st *Store) index(id string) int {
+	return slices.IndexFunc(st.entries, func(e Entry) bool { return e.ID == id })
+}
+
+// saveLocked sorts the schedules and writes them out. Caller holds mu.
+func (st *Store) saveLocked() error {
+	slices.SortStableFunc(st.entries, func(a, b Entry) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+	if err := os.MkdirAll(filepath.Dir(st.path), 0o755); err != nil {
+		return err
+	}
+	return atomicfile.WriteJSON(st.path, st.entries, 0o600)
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return "sc_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,99 @@
+package schedule
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStoreValidates(t *testing.T) {
+	st := newStore(slog.New(slog.NewTextHandler(io.Discard, nil)), filepath.Join(t.TempDir(), fileName),
+		func(tool string) bool { return tool == "codex" })
+	good := Spec{Name: "triage", Cron: "0 3 * * *", Tool: "codex", WorkDir: t.TempDir(), Enabled: true}
+	for name, change := range map[string]func(*Spec){
+		"no name":       func(sp *Spec) { sp.Name = " " },
+		"unknown tool":  func(sp *Spec) { sp.Tool = "vim" },
+		"relative dir":  func(sp *Spec) { sp.WorkDir = "repo" },
+		"six fields":    func(sp *Spec) { sp.Cron = "0 0 3 * * *" },
+		"bad cron":      func(sp *Spec) { sp.Cron = "61 * * * *" },
+		"tz descriptor": func(sp *Spec) { sp.Cron = "TZ=UTC 0 3 * *" },
+	} {
+		sp := good
+		change(&sp)
+		if _, err := st.Create(sp); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: err = %v, want ErrInvalid", name, err)
+		}
+	}
+	e, err := st.Create(good)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Update(e.ID, func(sp *Spec) { sp.Cron = "nope" }); !errors.Is(err, ErrInvalid) {
+		t.Errorf("bad update: err = %v", err)
+	}
+	if got, _ := st.Get(e.ID); got.Cron != good.Cron {
+		t.Errorf("a rejected update changed the entry: %q", got.Cron)
+	}
+
+	// Reloads from disk.
+	again := newStore(st.logger, st.path, nil)
+	if got, err := again.Get(e.ID); err != nil || got.Name != "triage" {
+		t.Errorf("reloaded entry = %+v, %v", got, err)
+	}
+}
+
+func TestSchedulerTick(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	st := newStore(logger, filepath.Join(t.TempDir(), fileName), nil)
+	e, err := st.Create(Spec{Name: "nightly", Cron: "0 3 * * *", Tool: "codex", WorkDir: t.TempDir(), Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	off, err := st.Create(Spec{Name: "off", Cron: "* * * * *", Tool: "codex", WorkDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ran []string
+	sc := NewScheduler(st, func(e Entry) (string, error) {
+		ran = append(ran, e.ID)
+		if len(ran) > 1 {
+			return "", errors.New("boom")
+		}
+		return "s_1", nil
+	}, logger)
+
+	day := time.Now().AddDate(0, 0, 1)
+	at := func(h, m int) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), h, m, 0, 0, time.Local)
+	}
+	sc.last = at(2, 59)
+	sc.tick(at(2, 59).Add(30 * time.Second))
+	if len(ran) != 0 {
+		t.Fatalf("fired early: %v", ran)
+	}
+	sc.tick(at(3, 0).Add(10 * time.Second))
+	if len(ran) != 1 || ran[0] != e.ID {
+		t.Fatalf("ran = %v, want only %s", ran, e.ID)
+	}
+	if got, _ := st.Get(e.ID); got.LastSessionID != "s_1" || got.LastRun.IsZero() {
+		t.Errorf("run not recorded: %+v", got)
+	}
+	if got, ok := st.BySession("s_1"); !ok || got.ID != e.ID {
+		t.Errorf("BySession = %+v, %v", got, ok)
+	}
+	sc.tick(at(3, 1))
+	if len(ran) != 1 {
+		t.Fatalf("fired twice: %v", ran)
+	}
+
+	// A run that failed keeps its error.
+	if _, err := sc.RunNow(off.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := st.Get(off.ID); got.LastError != "boom" || got.LastSessionID != "" {
+		t.Errorf("failed run recorded as %+v", got)
+	}
+}
@@ -0,0 +1,116 @@
+package schedule
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// checkInterval is how often the scheduler looks for due entries; a
+// run starts at most this long after its minute begins.
+const checkInterval = 20 * time.Second
+
+// Runner starts the session for e and returns its ID.
+type Runner func(e Entry) (sessionID string, err error)
+
+// Scheduler fires the store's enabled entries when they come due.
+//
+// It compares wall-clock times on every check rather than arming
+// timers, so a run that came due while the machine slept fires once on
+// wake. Runs that came due while kojo wasn't running are not made up.
+type Scheduler struct {
+	store  *Store
+	run    Runner
+	logger *slog.Logger
+
+	mu   sync.Mutex // serializes runs
+	last time.Time  // entries are due from here on
+
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// NewScheduler returns a scheduler for store's entries that starts
+// them with run. Call Start to begin.
+func NewScheduler(store *Store, run Runner, logger *slog.Logger) *Scheduler {
+	return &Scheduler{
+		store:   store,
+		run:     run,
+		logger:  logger,
+		last:    time.Now(),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// Start runs the scheduler until Stop.
+func (sc *Scheduler) Start() {
+	go func() {
+		defer close(sc.stopped)
+		t := time.NewTicker(checkInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-sc.done:
+				return
+			case now := <-t.C:
+				sc.tick(now)
+			}
+		}
+	}()
+}
+
+// Stop ends the scheduler and waits for a run in progress.
+func (sc *Scheduler) Stop() {
+	sc.once.Do(func() { close(sc.done) })
+	<-sc.stopped
+}
+
+// RunNow fires the entry with id at once, enabled or not, and returns
+// it with the outcome recorded.
+func (sc *Scheduler) RunNow(id string) (Entry, error) {
+	e, err := sc.store.Get(id)
+	if err != nil {
+		return Entry{}, err
+	}
+	sc.mu.Lock()
+	sc.fire(e, time.Now())
+	sc.mu.Unlock()
+	return sc.store.Get(id)
+}
+
+// tick fires every entry that came due since the last check. An entry
+// changed since then counts from its change, so editing one doesn't
+// fire it for a time it was not yet set for.
+func (sc *Scheduler) tick(now time.Time) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	from := sc.last
+	sc.last = now
+	for _, e := range sc.store.List() {
+		next := e.Next(later(from, e.UpdatedAt))
+		if next.IsZero() || next.After(now) {
+			continue
+		}
+		sc.fire(e, now)
+	}
+}
+
+// fire starts e's session and records the outcome. Caller holds mu.
+func (sc *Scheduler) fire(e Entry, now time.Time) {
+	id, err := sc.run(e)
+	if err != nil {
+		sc.logger.Warn("scheduled session failed to start", "schedule", e.ID, "name", e.Name, "err", err)
+	} else {
+		sc.logger.Info("scheduled session started", "schedule", e.ID, "name", e.Name, "session", id)
+	}
+	sc.store.recordRun(e.ID, now, id, err)
+}
+
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/loppo-llc/kojo/internal/auth"
+)
+
+// accessEntryKey carries the in-flight *accessEntry so
+// accessPrincipalMiddleware, deeper in the chain, can report who the
+// auth middlewares decided the caller is.
+type accessEntryKey struct{}
+
+type accessEntry struct {
+	principal auth.Principal
+	resolved  bool
+}
+
+// accessLogMiddleware logs one line per request — method, path,
+// status, bytes, duration, remote address and principal — to the main
+// logger at Debug and, when s.accessLog is set, to the access log at
+// Info. The query string is never logged: the UI bootstraps with
+// ?token=.
+//
+// It sits outermost on each listener's chain so auth rejections and
+// base-path redirects are timed and logged too. WebSocket upgrades log
+// once, after the connection closes, with status 101.
+func (s *Server) accessLogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		debug := s.logger.Enabled(r.Context(), slog.LevelDebug)
+		if !debug && s.accessLog == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		entry := &accessEntry{}
+		rw := &accessRecorder{ResponseWriter: w}
+		path := r.URL.Path
+		start := time.Now()
+		next.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), accessEntryKey{}, entry)))
+		status := rw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", path),
+			slog.Int("status", status),
+			slog.Int64("bytes", rw.bytes),
+			slog.Duration("dur", time.Since(start)),
+			slog.String("remote", r.RemoteAddr),
+		}
+		if entry.resolved {
+			attrs = append(attrs, slog.String("principal", principalLabel(entry.principal)))
+		}
+		if debug {
+			s.logger.LogAttrs(r.Context(), slog.LevelDebug, "http request", attrs...)
+		}
+		if s.accessLog != nil {
+			s.accessLog.LogAttrs(r.Context(), slog.LevelInfo, "request", attrs...)
+		}
+	})
+}
+
+// accessPrincipalMiddleware records the request's principal for
+// accessLogMiddleware. It wraps EnforceMiddleware, the first point on
+// every chain where the principal is final.
+func accessPrincipalMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if entry, ok := r.Context().Value(accessEntryKey{}).(*accessEntry); ok {
+			entry.principal, entry.resolved = auth.FromContext(r.Context()), true
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// accessRecorder captures status and body size. Unlike statusRecorder
+// it must also pass Hijack through, since it wraps the WebSocket
+// routes.
+type accessRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *accessRecorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *accessRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+func (r *accessRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *accessRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("hijack not supported")
+	}
+	if r.status == 0 {
+		r.status = http.StatusSwitchingProtocols
+	}
+	return h.Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *accessRecorder) Unwrap() http.ResponseWriter { return r.ResponseWriter }
+
+// appendFile is an io.Writer that appends each Write to path, opening
+// the file per call like gitExecAudit so the log can be rotated away
+// under a running daemon. slog handlers write one record per call.
+type appendFile struct {
+	path string
+	mu   sync.Mutex
+}
+
+func (a *appendFile) Write(p []byte) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(a.path), 0o700); err != nil {
+		return 0, err
+	}
+	f, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return f.Write(p)
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/loppo-llc/kojo/internal/auth"
+)
+
+func TestAccessLogMiddleware(t *testing.T) {
+	var debugBuf bytes.Buffer
+	path := filepath.Join(t.TempDir(), "logs", "access.jsonl")
+	s := &Server{
+		logger:    slog.New(slog.NewTextHandler(&debugBuf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		accessLog: slog.New(slog.NewJSONHandler(&appendFile{path: path}, nil)),
+	}
+	// Stand-in for the auth chain: stamp a principal, then record it.
+	h := s.accessLogMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(auth.WithPrincipal(r.Context(), auth.Principal{Role: auth.RoleAgent, AgentID: "ag_1"}))
+		accessPrincipalMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+			w.Write([]byte("hello"))
+		})).ServeHTTP(w, r)
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/sessions?token=secret", nil))
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var line struct {
+		Method    string `json:"method"`
+		Path      string `json:"path"`
+		Status    int    `json:"status"`
+		Bytes     int    `json:"bytes"`
+		Principal string `json:"principal"`
+	}
+	if err := json.Unmarshal(data, &line); err != nil {
+		t.Fatalf("access log line %q: %v", data, err)
+	}
+	if line.Method != "GET" || line.Path != "/api/v1/sessions" || line.Status != http.StatusTeapot ||
+		line.Bytes != 5 || line.Principal != "agent:ag_1" {
+		t.Errorf("access log line = %+v", line)
+	}
+	for name, out := range map[string]string{"access log": string(data), "debug log": debugBuf.String()} {
+		if strings.Contains(out, "secret") {
+			t.Errorf("%s leaked the query string: %s", name, out)
+		}
+	}
+	if !strings.Contains(debugBuf.String(), "status=418") {
+		t.Errorf("debug log = %q", debugBuf.String())
+	}
+}
+
+func TestAccessLogMiddlewareSkipsWhenDisabled(t *testing.T) {
+	var buf bytes.Buffer
+	s := &Server{logger: slog.New(slog.NewTextHandler(&buf, nil))}
+	h := s.accessLogMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(*accessRecorder); ok {
+			t.Error("response wrapped with logging disabled")
+		}
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if buf.Len() != 0 {
+		t.Errorf("logged at info level: %q", buf.String())
+	}
+}
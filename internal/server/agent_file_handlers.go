@@ -90,7 +90,6 @@ func (s *Server) handleListAgentFiles(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	rel := r.URL.Query().Get("path")
-	hidden := r.URL.Query().Get("hidden") == "true"
 
 	_, abs, err := resolveAgentPath(id, rel)
 	if err != nil {
@@ -98,9 +97,9 @@ func (s *Server) handleListAgentFiles(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := s.files.List(abs, hidden)
+	result, err := s.files.List(abs, parseListOptions(r))
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		writeFileError(w, err)
 		return
 	}
 	// Return both the relative sub-path (for UI state) and the absolute
@@ -110,6 +109,8 @@ func (s *Server) handleListAgentFiles(w http.ResponseWriter, r *http.Request) {
 		"path":    rel,
 		"absPath": abs,
 		"entries": result.Entries,
+		"total":   result.Total,
+		"hasMore": result.HasMore,
 	})
 }
 
@@ -127,7 +128,7 @@ func (s *Server) handleViewAgentFile(w http.ResponseWriter, r *http.Request) {
 	}
 	view, err := s.files.View(abs)
 	if err != nil {
-		writeFileViewError(w, err)
+		writeFileError(w, err)
 		return
 	}
 	// Replace absolute paths with relative ones in the response.
@@ -146,6 +147,8 @@ func (s *Server) handleViewAgentFile(w http.ResponseWriter, r *http.Request) {
 		"mime":     view.Mime,
 		"size":     view.Size,
 		"url":      view.URL,
+		"notebook": view.Notebook,
+		"table":    view.Table,
 		"absPath":  abs,
 	})
 }
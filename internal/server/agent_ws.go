@@ -65,7 +65,8 @@ func (s *Server) handleAgentWebSocket(w http.ResponseWriter, r *http.Request) {
 	}
 
 	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
-		OriginPatterns: wsOriginPatterns,
+		OriginPatterns:  wsOriginPatterns,
+		CompressionMode: wsCompression,
 	})
 	if err != nil {
 		s.logger.Error("agent websocket accept failed", "err", err)
@@ -154,6 +155,7 @@ func (s *Server) handleAgentWebSocket(w http.ResponseWriter, r *http.Request) {
 	}()
 
 	// Read goroutine: continuously reads from client, decoupled from write
+	readOnly := s.readOnlyFor(r)
 	go func() {
 		defer cancel()
 		for {
@@ -199,6 +201,9 @@ func (s *Server) handleAgentWebSocket(w http.ResponseWriter, r *http.Request) {
 				s.logger.Debug("invalid agent ws message", "err", err)
 				continue
 			}
+			if readOnly {
+				continue // message, steer and abort all act on the agent
+			}
 
 			select {
 			case clientMsgs <- msg:
@@ -0,0 +1,246 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loppo-llc/kojo/internal/auth"
+)
+
+// auditEntry is one line of the API audit log.
+type auditEntry struct {
+	Time      time.Time  `json:"time"`
+	Principal string     `json:"principal"`
+	Remote    string     `json:"remote"`
+	Method    string     `json:"method"`
+	Path      string     `json:"path"`
+	Query     url.Values `json:"query,omitempty"`
+	// Params is the JSON request body with secret-looking fields
+	// redacted. Omitted for non-JSON bodies and bodies over
+	// maxAuditBodyBytes; BodyBytes still records the size.
+	Params     json.RawMessage `json:"params,omitempty"`
+	BodyBytes  int64           `json:"bodyBytes,omitempty"`
+	Status     int             `json:"status"`
+	DurationMs int64           `json:"durationMs"`
+}
+
+// maxAuditBodyBytes caps how much of a request body is kept for
+// Params. Uploads and blob PUTs are far bigger and aren't JSON anyway.
+const maxAuditBodyBytes = 16 << 10
+
+// auditMiddleware appends every POST, PUT, PATCH and DELETE to the
+// audit log: who, what, when, the parameters and the resulting status.
+// It wraps EnforceMiddleware, so requests refused by policy are on
+// record too. The body is captured as the handler reads it, never
+// buffered ahead, so streaming uploads are unaffected.
+func (s *Server) auditMiddleware(next http.Handler) http.Handler {
+	if s.auditLog == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+		body := &auditBody{ReadCloser: r.Body}
+		r.Body = body
+		rw := &accessRecorder{ResponseWriter: w}
+		start := time.Now()
+		next.ServeHTTP(rw, r)
+
+		e := auditEntry{
+			Time:       start.UTC(),
+			Principal:  principalLabel(auth.FromContext(r.Context())),
+			Remote:     r.RemoteAddr,
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Query:      redactQuery(r.URL.Query()),
+			BodyBytes:  body.n,
+			Status:     rw.status,
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		if e.Status == 0 {
+			e.Status = http.StatusOK
+		}
+		if body.n > 0 && body.n <= maxAuditBodyBytes {
+			e.Params = redactJSON(body.buf.Bytes())
+		}
+		line, err := json.Marshal(e)
+		if err != nil {
+			return
+		}
+		if _, err := s.auditLog.Write(append(line, '\n')); err != nil {
+			s.logger.Warn("audit log: write failed", "path", s.auditLog.path, "err", err)
+		}
+	})
+}
+
+// auditBody keeps the first maxAuditBodyBytes of a request body as the
+// handler reads it and counts the rest.
+type auditBody struct {
+	io.ReadCloser
+	buf bytes.Buffer
+	n   int64
+}
+
+func (b *auditBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if room := maxAuditBodyBytes - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(n, room)])
+	}
+	b.n += int64(n)
+	return n, err
+}
+
+// isSecretKey reports whether a JSON field or query parameter name
+// looks like it carries a credential. False positives only cost the
+// audit trail a value.
+func isSecretKey(k string) bool {
+	k = strings.ToLower(k)
+	for _, s := range []string{"token", "secret", "password", "passphrase", "credential", "cookie", "apikey", "api_key", "authkey", "privatekey", "private_key"} {
+		if strings.Contains(k, s) {
+			return true
+		}
+	}
+	return k == "key"
+}
+
+func redactQuery(q url.Values) url.Values {
+	if len(q) == 0 {
+		return nil
+	}
+	for k := range q {
+		if isSecretKey(k) {
+			q[k] = []string{"[redacted]"}
+		}
+	}
+	return q
+}
+
+// redactJSON returns body with secret-looking fields replaced, or nil
+// when it isn't JSON.
+func redactJSON(body []byte) json.RawMessage {
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return nil
+	}
+	out, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return nil
+	}
+	return out
+}
+
+func redactValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, val := range v {
+			if isSecretKey(k) {
+				v[k] = "[redacted]"
+			} else {
+				v[k] = redactValue(val)
+			}
+		}
+	case []any:
+		for i := range v {
+			v[i] = redactValue(v[i])
+		}
+	}
+	return v
+}
+
+// handleAuditLog GET /api/v1/audit
+//
+// Returns the most recent audit entries, newest first. Query: limit
+// (default 100, max 1000), since (RFC 3339), principal (exact match,
+// e.g. "owner" or "agent:ag_x"), path (prefix), method. Owner only.
+func (s *Server) handleAuditLog(w http.ResponseWriter, r *http.Request) {
+	if !auth.FromContext(r.Context()).IsOwner() {
+		writeError(w, http.StatusForbidden, "forbidden", "audit log requires Owner")
+		return
+	}
+	if s.auditLog == nil {
+		writeError(w, http.StatusNotImplemented, "unsupported", "audit log is disabled")
+		return
+	}
+	q := r.URL.Query()
+	limit := 100
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "bad_request", "limit must be a positive integer")
+			return
+		}
+		limit = min(n, 1000)
+	}
+	var since time.Time
+	if v := q.Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "bad_request", "since must be RFC 3339")
+			return
+		}
+		since = t
+	}
+	match := func(e *auditEntry) bool {
+		return (since.IsZero() || !e.Time.Before(since)) &&
+			(q.Get("principal") == "" || e.Principal == q.Get("principal")) &&
+			strings.HasPrefix(e.Path, q.Get("path")) &&
+			(q.Get("method") == "" || strings.EqualFold(e.Method, q.Get("method")))
+	}
+	entries, err := readAuditTail(s.auditLog.path, limit, match)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]any{"entries": entries})
+}
+
+// readAuditTail scans the audit file and keeps the last limit entries
+// that match, returned newest first. Unparseable lines (a torn write
+// from a crash) are skipped.
+func readAuditTail(path string, limit int, match func(*auditEntry) bool) ([]auditEntry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []auditEntry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ring := make([]auditEntry, 0, limit)
+	next := 0
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	for sc.Scan() {
+		var e auditEntry
+		if json.Unmarshal(sc.Bytes(), &e) != nil || !match(&e) {
+			continue
+		}
+		if len(ring) < limit {
+			ring = append(ring, e)
+		} else {
+			ring[next] = e
+		}
+		next = (next + 1) % limit
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	out := make([]auditEntry, 0, len(ring))
+	for i := range ring {
+		out = append(out, ring[(next-1-i+2*len(ring))%len(ring)])
+	}
+	return out, nil
+}
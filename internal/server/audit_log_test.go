@@ -0,0 +1,72 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/loppo-llc/kojo/internal/auth"
+)
+
+func TestAuditMiddleware(t *testing.T) {
+	srv := &Server{logger: slog.Default(), auditLog: &appendFile{path: filepath.Join(t.TempDir(), "audit.jsonl")}}
+	h := srv.auditMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	send := func(method, target, body string, p auth.Principal) {
+		r := authedRequest(httptest.NewRequest(method, target, strings.NewReader(body)), p)
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	owner := auth.Principal{Role: auth.RoleOwner}
+	send(http.MethodPost, "/api/v1/sessions?token=tok", `{"tool":"claude","apiKey":"sk-1","env":{"GH_TOKEN":"x"}}`, owner)
+	send(http.MethodGet, "/api/v1/sessions", "", owner)
+	send(http.MethodDelete, "/api/v1/sessions/s1", "", auth.Principal{Role: auth.RoleAgent, AgentID: "ag_x"})
+
+	list := func(query string) []auditEntry {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		srv.handleAuditLog(rr, authedRequest(httptest.NewRequest(http.MethodGet, "/api/v1/audit"+query, nil), owner))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("GET audit%s: status = %d %s", query, rr.Code, rr.Body)
+		}
+		var resp struct {
+			Entries []auditEntry `json:"entries"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.Entries
+	}
+
+	entries := list("")
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2 (GET is not audited): %+v", len(entries), entries)
+	}
+	if entries[0].Method != http.MethodDelete || entries[0].Principal != "agent:ag_x" {
+		t.Errorf("newest entry = %+v, want the agent's DELETE", entries[0])
+	}
+	create := entries[1]
+	if create.Status != http.StatusCreated || create.Principal != "owner" || create.Path != "/api/v1/sessions" {
+		t.Errorf("create entry = %+v", create)
+	}
+	params := string(create.Params)
+	if strings.Contains(params, "sk-1") || strings.Contains(params, `"x"`) || !strings.Contains(params, `"tool":"claude"`) {
+		t.Errorf("params not redacted: %s", params)
+	}
+	if got := create.Query.Get("token"); got != "[redacted]" {
+		t.Errorf("query token = %q", got)
+	}
+
+	if got := list("?principal=owner"); len(got) != 1 || got[0].Method != http.MethodPost {
+		t.Errorf("principal filter = %+v", got)
+	}
+	if got := list("?limit=1"); len(got) != 1 || got[0].Method != http.MethodDelete {
+		t.Errorf("limit=1 = %+v", got)
+	}
+}
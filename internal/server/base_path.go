@@ -0,0 +1,83 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// NormalizeBasePath turns a --base-path value into "/seg[/seg...]"
+// with no trailing slash; "" and "/" mean no prefix.
+func NormalizeBasePath(p string) string {
+	p = strings.Trim(strings.TrimSpace(p), "/")
+	if p == "" {
+		return ""
+	}
+	return "/" + p
+}
+
+// basePathMiddleware strips base from request paths so kojo can sit
+// behind a reverse proxy at https://host/<base>/ that forwards the
+// prefix unchanged. Paths without the prefix are served as they are:
+// agents and the CLI talk to kojo directly, not through the proxy.
+func basePathMiddleware(base string, h http.Handler) http.Handler {
+	if base == "" {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest, ok := strings.CutPrefix(r.URL.Path, base)
+		if !ok || (rest != "" && rest[0] != '/') {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if rest == "" {
+			// /kojo → /kojo/ so relative asset URLs resolve inside
+			// the prefix.
+			http.Redirect(w, r, base+"/", http.StatusMovedPermanently)
+			return
+		}
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = rest
+		r2.URL.RawPath = ""
+		h.ServeHTTP(w, r2)
+	})
+}
+
+// The base-path shim runs before the SPA bundle. The bundle is built for "/"
+// and requests /api/... by absolute path, so the shim prefixes
+// same-origin absolute URLs on fetch, XMLHttpRequest, WebSocket and
+// EventSource. The base is also exposed as window.__KOJO_BASE__. It is
+// split around the JSON-quoted base.
+const (
+	basePathShimHead = `<script>(function(){var b=`
+	basePathShimTail = `;window.__KOJO_BASE__=b;` +
+		`function p(u){if(typeof u==="string"){if(u.charAt(0)==="/"&&u.charAt(1)!=="/"&&u.indexOf(b+"/")!==0)return b+u;` +
+		`try{var x=new URL(u);if(x.host===location.host&&x.pathname.indexOf(b+"/")!==0){x.pathname=b+x.pathname;return x.toString()}}catch(e){}}return u}` +
+		`var f=window.fetch;window.fetch=function(i,o){if(typeof i==="string"||i instanceof URL)i=p(String(i));else if(i&&i.url)i=new Request(p(i.url),i);return f.call(this,i,o)};` +
+		`var X=XMLHttpRequest.prototype.open;XMLHttpRequest.prototype.open=function(m,u){arguments[1]=p(String(u));return X.apply(this,arguments)};` +
+		`var W=window.WebSocket;window.WebSocket=function(u,q){return q===undefined?new W(p(String(u))):new W(p(String(u)),q)};window.WebSocket.prototype=W.prototype;` +
+		`["CONNECTING","OPEN","CLOSING","CLOSED"].forEach(function(k){window.WebSocket[k]=W[k]});` +
+		`if(window.EventSource){var E=window.EventSource;window.EventSource=function(u,o){return new E(p(String(u)),o)};window.EventSource.prototype=E.prototype}` +
+		`})();</script>`
+)
+
+// absoluteAssetRef matches src="/x" and href="/x" but not
+// protocol-relative "//host" URLs.
+var absoluteAssetRef = regexp.MustCompile(`\b(src|href)="/([^/"])`)
+
+// rewriteIndexHTML points index.html's absolute asset URLs at base and
+// injects the shim ahead of the bundle.
+func rewriteIndexHTML(html []byte, base string) []byte {
+	repl := `$1="` + strings.ReplaceAll(base, "$", "$$") + `/$2`
+	html = absoluteAssetRef.ReplaceAll(html, []byte(repl))
+	quoted, _ := json.Marshal(base)
+	shim := slices.Concat([]byte(basePathShimHead), quoted, []byte(basePathShimTail))
+	if i := bytes.Index(html, []byte("<head>")); i >= 0 {
+		i += len("<head>")
+		return slices.Concat(html[:i], shim, html[i:])
+	}
+	return slices.Concat(shim, html)
+}
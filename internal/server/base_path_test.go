@@ -0,0 +1,64 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeBasePath(t *testing.T) {
+	for in, want := range map[string]string{
+		"":         "",
+		"/":        "",
+		"kojo":     "/kojo",
+		"/kojo/":   "/kojo",
+		" /a/b/ ":  "/a/b",
+		"/tools/k": "/tools/k",
+	} {
+		if got := NormalizeBasePath(in); got != want {
+			t.Errorf("NormalizeBasePath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestBasePathMiddleware(t *testing.T) {
+	var seen string
+	h := basePathMiddleware("/kojo", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.URL.Path
+	}))
+	for _, tc := range []struct{ path, want string }{
+		{"/kojo/api/v1/info", "/api/v1/info"},
+		{"/kojo/", "/"},
+		{"/api/v1/info", "/api/v1/info"}, // direct access keeps working
+		{"/kojobar/x", "/kojobar/x"},
+	} {
+		seen = ""
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if seen != tc.want {
+			t.Errorf("%s reached handler as %q, want %q", tc.path, seen, tc.want)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/kojo", nil))
+	if rr.Code != http.StatusMovedPermanently || rr.Header().Get("Location") != "/kojo/" {
+		t.Errorf("/kojo = %d %q, want redirect to /kojo/", rr.Code, rr.Header().Get("Location"))
+	}
+}
+
+func TestRewriteIndexHTML(t *testing.T) {
+	in := `<html><head><link href="/assets/a.css"><script src="//cdn.example/x.js"></script></head>` +
+		`<body><script type="module" src="/assets/index.js"></script></body></html>`
+	out := string(rewriteIndexHTML([]byte(in), "/kojo"))
+	for _, want := range []string{
+		`href="/kojo/assets/a.css"`,
+		`src="/kojo/assets/index.js"`,
+		`src="//cdn.example/x.js"`,
+		`<head><script>(function(){var b="/kojo";`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("rewritten index missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// buildMeta is what the binary knows about its own build.
+type buildMeta struct {
+	GoVersion string `json:"goVersion"`
+	// Commit, CommitTime and Modified come from the VCS stamp go build
+	// embeds when built from a git checkout; empty otherwise.
+	Commit     string `json:"commit,omitempty"`
+	CommitTime string `json:"commitTime,omitempty"`
+	Modified   bool   `json:"modified,omitempty"`
+}
+
+var readBuildMeta = sync.OnceValue(func() buildMeta {
+	m := buildMeta{GoVersion: runtime.Version()}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return m
+	}
+	for _, kv := range bi.Settings {
+		switch kv.Key {
+		case "vcs.revision":
+			m.Commit = kv.Value
+		case "vcs.time":
+			m.CommitTime = kv.Value
+		case "vcs.modified":
+			m.Modified = kv.Value == "true"
+		}
+	}
+	return m
+})
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/loppo-llc/kojo/internal/auth"
+	"github.com/loppo-llc/kojo/internal/filebrowser"
+)
+
+func TestInfoIncludesUptimeAndBuild(t *testing.T) {
+	srv := &Server{
+		logger:    slog.Default(),
+		version:   "v0.1.0",
+		files:     filebrowser.New(slog.Default(), filebrowser.Options{}),
+		startedAt: time.Now().Add(-90 * time.Second),
+	}
+	srv.SetTailscaleName("kojo.example.ts.net")
+	rr := httptest.NewRecorder()
+	srv.handleInfo(rr, newUpdateRequest(http.MethodGet, "/api/v1/info", nil, auth.Principal{Role: auth.RoleOwner}))
+	var body map[string]any
+	readJSONResponse(t, rr, &body)
+
+	if up, _ := body["uptimeSeconds"].(float64); up < 90 {
+		t.Errorf("uptimeSeconds = %v", body["uptimeSeconds"])
+	}
+	if build, _ := body["build"].(map[string]any); build["goVersion"] != runtime.Version() {
+		t.Errorf("build = %v", body["build"])
+	}
+	if body["tailscaleName"] != "kojo.example.ts.net" || body["pushConfigured"] != false || body["authRequired"] != true {
+		t.Errorf("info = %v", body)
+	}
+}
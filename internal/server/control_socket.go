@@ -0,0 +1,36 @@
+package server
+
+import (
+	"log/slog"
+	"net"
+	"os"
+)
+
+// ownerListener passes on only the connections made by processes
+// running as kojo's own user, read from the socket's peer credentials.
+// With the socket file's 0600 mode this is the control socket's auth:
+// a connection it lets through is the Owner.
+type ownerListener struct {
+	net.Listener
+	logger *slog.Logger
+}
+
+func (l ownerListener) Accept() (net.Conn, error) {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		uc, ok := c.(*net.UnixConn)
+		if !ok {
+			_ = c.Close()
+			continue
+		}
+		uid, err := peerUID(uc)
+		if err == nil && uid == os.Getuid() {
+			return c, nil
+		}
+		l.logger.Warn("control socket: refused connection", "uid", uid, "err", err)
+		_ = uc.Close()
+	}
+}
@@ -0,0 +1,35 @@
+//go:build linux || darwin
+
+package server
+
+import (
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOwnerListenerAcceptsOwnUser(t *testing.T) {
+	ln, err := net.Listen("unix", filepath.Join(t.TempDir(), "kojo.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	ol := ownerListener{Listener: ln, logger: slog.New(slog.DiscardHandler)}
+
+	c, err := net.Dial("unix", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	got, err := ol.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer got.Close()
+	uid, err := peerUID(got.(*net.UnixConn))
+	if err != nil || uid != os.Getuid() {
+		t.Errorf("peerUID = %d, %v; want %d", uid, err, os.Getuid())
+	}
+}
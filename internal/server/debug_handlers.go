@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+
+	"github.com/loppo-llc/kojo/internal/auth"
+	"github.com/loppo-llc/kojo/internal/session"
+)
+
+// registerDebugRoutes wires the --debug-endpoints surface. pprof lives
+// at its conventional /debug/pprof/ so `go tool pprof` works as is;
+// being outside /api/v1 it isn't covered by EnforceMiddleware, so the
+// Owner check is done here. On the auth listener pass the token as
+// ?token=.
+func (s *Server) registerDebugRoutes(mux *http.ServeMux) {
+	pp := http.NewServeMux()
+	pp.HandleFunc("/debug/pprof/", pprof.Index)
+	pp.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	pp.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	pp.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	pp.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/pprof/", func(w http.ResponseWriter, r *http.Request) {
+		if !auth.FromContext(r.Context()).IsOwner() {
+			writeError(w, http.StatusForbidden, "forbidden", "debug endpoints require Owner")
+			return
+		}
+		pp.ServeHTTP(w, r)
+	})
+	mux.HandleFunc("GET /api/v1/debug/goroutines", s.handleDebugGoroutines)
+}
+
+// handleDebugGoroutines GET /api/v1/debug/goroutines
+//
+// Summarizes goroutine use: the process total and each session's
+// readLoop / drainLoop / waitLoop counts and output subscribers, plus
+// per loop kind when it started and how many double starts its
+// supervisor refused. An exited session with a loop still running is
+// a leak. Owner only.
+func (s *Server) handleDebugGoroutines(w http.ResponseWriter, r *http.Request) {
+	if !auth.FromContext(r.Context()).IsOwner() {
+		writeError(w, http.StatusForbidden, "forbidden", "debug endpoints require Owner")
+		return
+	}
+	sessions := []session.LoopStats{}
+	if s.sessions != nil {
+		sessions = s.sessions.LoopStats()
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]any{
+		"goroutines": runtime.NumGoroutine(),
+		"sessions":   sessions,
+	})
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/loppo-llc/kojo/internal/auth"
+)
+
+func TestDebugRoutesOwnerOnly(t *testing.T) {
+	srv := &Server{logger: slog.Default()}
+	mux := http.NewServeMux()
+	srv.registerDebugRoutes(mux)
+	get := func(path string, p auth.Principal) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, authedRequest(httptest.NewRequest(http.MethodGet, path, nil), p))
+		return rr
+	}
+	agent := auth.Principal{Role: auth.RoleAgent, AgentID: "ag_x"}
+	owner := auth.Principal{Role: auth.RoleOwner}
+
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/goroutine", "/api/v1/debug/goroutines"} {
+		if rr := get(path, agent); rr.Code != http.StatusForbidden {
+			t.Errorf("agent GET %s: status = %d, want 403", path, rr.Code)
+		}
+	}
+	if rr := get("/debug/pprof/goroutine?debug=1", owner); rr.Code != http.StatusOK {
+		t.Errorf("owner pprof: status = %d, want 200", rr.Code)
+	}
+	rr := get("/api/v1/debug/goroutines", owner)
+	var body struct {
+		Goroutines int   `json:"goroutines"`
+		Sessions   []any `json:"sessions"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body %q: %v", rr.Body, err)
+	}
+	if body.Goroutines == 0 || body.Sessions == nil {
+		t.Errorf("summary = %s", rr.Body)
+	}
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// DefaultViteURL is where --dev finds the Vite dev server unless
+// Config.ViteURL says otherwise.
+const DefaultViteURL = "http://localhost:5173"
+
+// devProxyWait is how long a request waits for the Vite dev server to
+// come up before it gets 502.
+const devProxyWait = 30 * time.Second
+
+// newDevProxy forwards the web UI routes to the Vite dev server at
+// target, WebSocket upgrades (Vite's HMR socket) included. The Host
+// header is rewritten to target's, since Vite refuses hosts it doesn't
+// know; the original goes in X-Forwarded-Host.
+func newDevProxy(target *url.URL, logger *slog.Logger) http.Handler {
+	return &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(target)
+			r.SetXForwarded()
+		},
+		Transport: &devProxyTransport{next: http.DefaultTransport, wait: devProxyWait},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			logger.Warn("dev proxy failed", "url", r.URL.String(), "err", err)
+			http.Error(w, "Vite dev server at "+target.String()+" is not reachable; run `make dev-web`", http.StatusBadGateway)
+		},
+	}
+}
+
+// devProxyTransport retries requests Vite refused to connect, backing
+// off for up to wait, so pages opened while kojo and Vite start
+// together load instead of failing with 502. Requests with a body are
+// not retried.
+type devProxyTransport struct {
+	next http.RoundTripper
+	wait time.Duration
+}
+
+func (t *devProxyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	deadline := time.Now().Add(t.wait)
+	backoff := 100 * time.Millisecond
+	for {
+		resp, err := t.next.RoundTrip(req)
+		var opErr *net.OpError
+		if err == nil || !errors.As(err, &opErr) || opErr.Op != "dial" ||
+			(req.Body != nil && req.Body != http.NoBody) || time.Now().Add(backoff).After(deadline) {
+			return resp, err
+		}
+		select {
+		case <-req.Context().Done():
+			return nil, err
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, 2*time.Second)
+	}
+}
+
+// viteTarget parses Config.ViteURL, DefaultViteURL when empty.
+func viteTarget(raw string) (*url.URL, error) {
+	u, err := url.Parse(cmp.Or(raw, DefaultViteURL))
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("vite url %q: want http(s)://host:port", raw)
+	}
+	return u, nil
+}
+
+// startVite supervises `npm run dev` in cfg.ViteDir (default "web")
+// until Shutdown.
+func (s *Server) startVite(cfg Config) {
+	target, err := viteTarget(cfg.ViteURL)
+	if err == nil && target.Port() == "" {
+		err = fmt.Errorf("vite url %q has no port", target)
+	}
+	if err != nil {
+		s.logger.Error("not starting vite", "err", err)
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.viteStop, s.viteDone = cancel, make(chan struct{})
+	go s.runVite(ctx, cmp.Or(cfg.ViteDir, "web"), target, s.viteDone)
+}
+
+// runVite runs `npm run dev` in dir with Vite on target's port until
+// ctx is done, restarting it when it exits; done is closed on return.
+func (s *Server) runVite(ctx context.Context, dir string, target *url.URL, done chan<- struct{}) {
+	defer close(done)
+	backoff := time.Second
+	for {
+		cmd := exec.CommandContext(ctx, "npm", "run", "dev", "--",
+			"--host", target.Hostname(), "--port", target.Port(), "--strictPort")
+		cmd.Dir = dir
+		cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+		// Stop Vite along with npm (unix only).
+		setupBuildProcessGroup(cmd)
+		started := time.Now()
+		s.logger.Info("starting vite dev server", "dir", dir, "url", target.String())
+		err := cmd.Run()
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > time.Minute {
+			backoff = time.Second
+		}
+		s.logger.Warn("vite dev server exited; restarting", "err", err, "in", backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, 30*time.Second)
+	}
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+func TestDevProxy(t *testing.T) {
+	var gotHost string
+	vite := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHost = r.Host
+		if !isWebSocketUpgrade(r) {
+			w.Write([]byte("<html>"))
+			return
+		}
+		conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{Subprotocols: []string{"vite-hmr"}})
+		if err != nil {
+			return
+		}
+		defer conn.CloseNow()
+		typ, msg, err := conn.Read(r.Context())
+		if err != nil {
+			return
+		}
+		conn.Write(r.Context(), typ, msg)
+	}))
+	defer vite.Close()
+	target, _ := url.Parse(vite.URL)
+	proxy := newDevProxy(target, slog.Default())
+	proxy.(*httputil.ReverseProxy).Transport.(*devProxyTransport).wait = 300 * time.Millisecond
+	front := httptest.NewServer(proxy)
+	defer front.Close()
+
+	resp, err := http.Get(front.URL + "/src/main.tsx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || gotHost != target.Host {
+		t.Errorf("status = %d, Vite saw Host %q, want %q", resp.StatusCode, gotHost, target.Host)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(front.URL, "http")+"/?token=x",
+		&websocket.DialOptions{Subprotocols: []string{"vite-hmr"}})
+	if err != nil {
+		t.Fatalf("HMR WebSocket through the proxy: %v", err)
+	}
+	defer conn.CloseNow()
+	if conn.Subprotocol() != "vite-hmr" {
+		t.Errorf("subprotocol = %q", conn.Subprotocol())
+	}
+	if err := conn.Write(ctx, websocket.MessageText, []byte(`{"type":"ping"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, msg, err := conn.Read(ctx); err != nil || string(msg) != `{"type":"ping"}` {
+		t.Errorf("echo = %q, %v", msg, err)
+	}
+
+	vite.Close()
+	resp, err = http.Get(front.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("status with Vite down = %d, want 502", resp.StatusCode)
+	}
+}
+
+func TestDevProxyWaitsForVite(t *testing.T) {
+	// Reserve a port, then free it for Vite to come up on late.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	front := httptest.NewServer(newDevProxy(&url.URL{Scheme: "http", Host: addr}, slog.Default()))
+	defer front.Close()
+
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return
+		}
+		srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("<html>"))
+		})}
+		t.Cleanup(func() { srv.Close() })
+		srv.Serve(ln)
+	}()
+	resp, err := http.Get(front.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200 once Vite is up", resp.StatusCode)
+	}
+}
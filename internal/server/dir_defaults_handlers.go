@@ -0,0 +1,99 @@
+package server
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/loppo-llc/kojo/internal/dirdefaults"
+	"github.com/loppo-llc/kojo/internal/session"
+)
+
+// writeDirDefaultsError maps a dirdefaults.Store error to its response.
+func writeDirDefaultsError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, dirdefaults.ErrNotFound):
+		writeError(w, http.StatusNotFound, "not_found", err.Error())
+	case errors.Is(err, dirdefaults.ErrInvalid):
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+	}
+}
+
+// handleListDirDefaults GET /api/v1/dir-defaults
+//
+// With ?dir= returns just the defaults a session there gets (404 when
+// none apply), for prefilling the new session form.
+func (s *Server) handleListDirDefaults(w http.ResponseWriter, r *http.Request) {
+	if dir := r.URL.Query().Get("dir"); dir != "" {
+		d, ok := s.dirDefaults.Lookup(dir)
+		if !ok {
+			writeError(w, http.StatusNotFound, "not_found", "no defaults for "+dir)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, d)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]any{"dirDefaults": s.dirDefaults.List()})
+}
+
+// handleSetDirDefaults PUT /api/v1/dir-defaults
+//
+// Body: {"dir":"~/src/app","tool":"claude","args":[...],"yoloMode":false,
+// "sandbox":"...","timeLimitMinutes":0,"tokenBudget":0,"checkpointMinutes":0}.
+// Replaces the defaults for dir; fields left out set nothing.
+func (s *Server) handleSetDirDefaults(w http.ResponseWriter, r *http.Request) {
+	var req dirdefaults.Defaults
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid request body")
+		return
+	}
+	d, err := s.dirDefaults.Set(req)
+	if err != nil {
+		writeDirDefaultsError(w, err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, d)
+}
+
+// handleDeleteDirDefaults DELETE /api/v1/dir-defaults?dir=
+func (s *Server) handleDeleteDirDefaults(w http.ResponseWriter, r *http.Request) {
+	if err := s.dirDefaults.Delete(r.URL.Query().Get("dir")); err != nil {
+		writeDirDefaultsError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// lookupDirDefaults returns the defaults for a local session in
+// workDir.
+func (s *Server) lookupDirDefaults(workDir string) (dirdefaults.Defaults, bool) {
+	if s.dirDefaults == nil {
+		return dirdefaults.Defaults{}, false
+	}
+	return s.dirDefaults.Lookup(workDir)
+}
+
+// sessionDefaults returns what a session in workDir starts with when
+// a request names at most its tool ("" for the directory's, then
+// claude) and yolo mode (nil for the directory's). The directory's
+// args and token budget only go to the tool they were set for.
+func (s *Server) sessionDefaults(workDir, tool string, yolo *bool) (string, []string, bool, session.CreateOptions) {
+	d, _ := s.lookupDirDefaults(workDir)
+	tool = cmp.Or(tool, d.Tool, "claude")
+	var args []string
+	var budget int64
+	if d.Tool == "" || d.Tool == tool {
+		args, budget = d.Args, d.TokenBudget
+	}
+	yolo = cmp.Or(yolo, d.YoloMode)
+	return tool, args, yolo != nil && *yolo, session.CreateOptions{
+		Sandbox:            d.Sandbox,
+		TimeLimit:          time.Duration(d.TimeLimitMinutes) * time.Minute,
+		TokenBudget:        budget,
+		CheckpointInterval: time.Duration(d.CheckpointMinutes) * time.Minute,
+	}
+}
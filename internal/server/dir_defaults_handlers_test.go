@@ -0,0 +1,40 @@
+package server
+
+import (
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/loppo-llc/kojo/internal/dirdefaults"
+)
+
+func TestSessionDefaults(t *testing.T) {
+	if tool, args, yolo, _ := (&Server{}).sessionDefaults("/src/app", "", nil); tool != "claude" || args != nil || yolo {
+		t.Errorf("no store: %q %q %v", tool, args, yolo)
+	}
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("APPDATA", t.TempDir())
+	st := dirdefaults.NewStore(slog.Default(), nil)
+	repo := filepath.Join(t.TempDir(), "app")
+	yes := true
+	if _, err := st.Set(dirdefaults.Defaults{Dir: repo, Tool: "codex", Args: []string{"--model", "o3"}, YoloMode: &yes,
+		TimeLimitMinutes: 30, TokenBudget: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{dirDefaults: st}
+
+	tool, args, yolo, opts := s.sessionDefaults(filepath.Join(repo, "web"), "", nil)
+	if tool != "codex" || !slices.Equal(args, []string{"--model", "o3"}) || !yolo ||
+		opts.TimeLimit != 30*time.Minute || opts.TokenBudget != 1000 {
+		t.Errorf("defaults: %q %q %v %+v", tool, args, yolo, opts)
+	}
+	// Another tool keeps the directory's limits but not codex's args.
+	no := false
+	tool, args, yolo, opts = s.sessionDefaults(repo, "claude", &no)
+	if tool != "claude" || args != nil || yolo || opts.TimeLimit != 30*time.Minute || opts.TokenBudget != 0 {
+		t.Errorf("override: %q %q %v %+v", tool, args, yolo, opts)
+	}
+}
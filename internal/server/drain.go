@@ -0,0 +1,159 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/loppo-llc/kojo/internal/auth"
+	"github.com/loppo-llc/kojo/internal/session"
+)
+
+// Drain mode prepares the daemon for an upgrade: new sessions and new
+// WebSocket connections are refused with 503 while existing ones keep
+// running, and open terminal WebSockets get a "draining" message so the
+// UI (or `kojo attach`) can warn the user. The operator polls GET
+// /api/v1/admin/drain until the counts reach zero, or restarts once
+// they're low enough. Drain is in-memory; a restart ends it.
+
+// WSDrainingMsg tells a terminal WebSocket client the server is
+// draining. The connection stays up until the client or a shutdown
+// closes it.
+type WSDrainingMsg struct {
+	Type  string    `json:"type"`
+	Since time.Time `json:"since"`
+}
+
+// draining reports whether drain mode is on and, if so, since when.
+func (s *Server) draining() (time.Time, bool) {
+	s.drainMu.Lock()
+	defer s.drainMu.Unlock()
+	return s.drainSince, !s.drainSince.IsZero()
+}
+
+// drainSignal returns a channel closed when drain mode starts. Read it
+// once per connection; after a cancelled drain a later one closes a new
+// channel.
+func (s *Server) drainSignal() <-chan struct{} {
+	s.drainMu.Lock()
+	defer s.drainMu.Unlock()
+	return s.drainCh
+}
+
+// drainMiddleware refuses what drain mode stops accepting and counts
+// the WebSockets it lets through, holding them to s.wsLimits. It sits
+// innermost, on the mux, so it sees every listener's traffic after
+// auth.
+func (s *Server) drainMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws := isWebSocketUpgrade(r)
+		if _, on := s.draining(); on && (ws || createsSession(r)) {
+			w.Header().Set("Retry-After", "60")
+			writeError(w, http.StatusServiceUnavailable, "draining",
+				"server is draining for a restart; not accepting new sessions or connections")
+			return
+		}
+		if ws {
+			s.wsConns.Add(1)
+			defer s.wsConns.Add(-1)
+			s.serveWS(w, r, next)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// createsSession matches POST /api/v1/sessions, /sessions/from-issue
+// and /sessions/{id}/restart and /clone, and the /new deep link's form,
+// which all spawn a process.
+func createsSession(r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		return false
+	}
+	switch r.URL.Path {
+	case "/api/v1/sessions", "/api/v1/sessions/from-issue", "/new":
+		return true
+	}
+	rest, ok := strings.CutPrefix(r.URL.Path, "/api/v1/sessions/")
+	return ok && strings.Count(rest, "/") == 1 &&
+		(strings.HasSuffix(rest, "/restart") || strings.HasSuffix(rest, "/clone"))
+}
+
+// drainStatus is the body of every /api/v1/admin/drain response.
+func (s *Server) drainStatus() map[string]any {
+	since, on := s.draining()
+	running := 0
+	if s.sessions != nil {
+		for _, sess := range s.sessions.List() {
+			if st := sess.Info().Status; st == session.StatusRunning || st == session.StatusPaused {
+				running++
+			}
+		}
+	}
+	resp := map[string]any{
+		"draining":        on,
+		"websockets":      s.wsConns.Load(),
+		"runningSessions": running,
+	}
+	if on {
+		resp["since"] = since
+	}
+	return resp
+}
+
+// handleDrainStatus GET /api/v1/admin/drain
+//
+// Owner only. Reports whether drain mode is on and how many
+// WebSockets and running sessions are left.
+func (s *Server) handleDrainStatus(w http.ResponseWriter, r *http.Request) {
+	if !auth.FromContext(r.Context()).IsOwner() {
+		writeError(w, http.StatusForbidden, "forbidden", "drain requires Owner")
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, s.drainStatus())
+}
+
+// handleDrainStart POST /api/v1/admin/drain
+//
+// Owner only. Turns drain mode on; repeating it is a no-op that keeps
+// the original start time.
+func (s *Server) handleDrainStart(w http.ResponseWriter, r *http.Request) {
+	if !auth.FromContext(r.Context()).IsOwner() {
+		writeError(w, http.StatusForbidden, "forbidden", "drain requires Owner")
+		return
+	}
+	s.drainMu.Lock()
+	started := s.drainSince.IsZero()
+	if started {
+		s.drainSince = time.Now()
+		close(s.drainCh)
+	}
+	s.drainMu.Unlock()
+	if started {
+		s.logger.Info("drain mode on: refusing new sessions and WebSocket connections",
+			"websockets", s.wsConns.Load())
+	}
+	writeJSONResponse(w, http.StatusOK, s.drainStatus())
+}
+
+// handleDrainCancel DELETE /api/v1/admin/drain
+//
+// Owner only. Turns drain mode off and accepts new work again.
+func (s *Server) handleDrainCancel(w http.ResponseWriter, r *http.Request) {
+	if !auth.FromContext(r.Context()).IsOwner() {
+		writeError(w, http.StatusForbidden, "forbidden", "drain requires Owner")
+		return
+	}
+	s.drainMu.Lock()
+	if !s.drainSince.IsZero() {
+		s.drainSince = time.Time{}
+		s.drainCh = make(chan struct{})
+		s.logger.Info("drain mode off")
+	}
+	s.drainMu.Unlock()
+	writeJSONResponse(w, http.StatusOK, s.drainStatus())
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/loppo-llc/kojo/internal/auth"
+)
+
+func TestAdminDrainMode(t *testing.T) {
+	srv := &Server{logger: slog.Default(), drainCh: make(chan struct{})}
+	h := srv.drainMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := srv.wsConns.Load(); isWebSocketUpgrade(r) && got != 1 {
+			t.Errorf("websockets during upgrade = %d, want 1", got)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	do := func(method, path string, ws bool) int {
+		r := httptest.NewRequest(method, path, nil)
+		if ws {
+			r.Header.Set("Upgrade", "websocket")
+		}
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, r)
+		return rr.Code
+	}
+	admin := func(method string) {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		r := authedRequest(httptest.NewRequest(method, "/api/v1/admin/drain", nil), auth.Principal{Role: auth.RoleOwner})
+		switch method {
+		case http.MethodPost:
+			srv.handleDrainStart(rr, r)
+		case http.MethodDelete:
+			srv.handleDrainCancel(rr, r)
+		}
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s drain: status = %d", method, rr.Code)
+		}
+	}
+
+	if code := do(http.MethodPost, "/api/v1/sessions", false); code != http.StatusNoContent {
+		t.Fatalf("create before drain: status = %d", code)
+	}
+	signal := srv.drainSignal()
+	admin(http.MethodPost)
+	select {
+	case <-signal:
+	default:
+		t.Fatal("drain signal not closed")
+	}
+	for _, c := range []struct {
+		method, path string
+		ws           bool
+		want         int
+	}{
+		{http.MethodPost, "/api/v1/sessions", false, http.StatusServiceUnavailable},
+		{http.MethodPost, "/api/v1/sessions/s1/restart", false, http.StatusServiceUnavailable},
+		{http.MethodPost, "/api/v1/sessions/s1/clone", false, http.StatusServiceUnavailable},
+		{http.MethodGet, "/api/v1/ws", true, http.StatusServiceUnavailable},
+		{http.MethodGet, "/api/v1/sessions", false, http.StatusNoContent},
+		{http.MethodDelete, "/api/v1/sessions/s1", false, http.StatusNoContent},
+		{http.MethodPost, "/api/v1/sessions/s1/tmux", false, http.StatusNoContent},
+	} {
+		if code := do(c.method, c.path, c.ws); code != c.want {
+			t.Errorf("draining %s %s (ws=%v): status = %d, want %d", c.method, c.path, c.ws, code, c.want)
+		}
+	}
+
+	admin(http.MethodDelete)
+	if code := do(http.MethodGet, "/api/v1/ws", true); code != http.StatusNoContent {
+		t.Errorf("ws after cancel: status = %d", code)
+	}
+	if got := srv.wsConns.Load(); got != 0 {
+		t.Errorf("websockets after close = %d, want 0", got)
+	}
+}
+
+func TestAdminDrainForbiddenForAgent(t *testing.T) {
+	srv := &Server{logger: slog.Default(), drainCh: make(chan struct{})}
+	rr := httptest.NewRecorder()
+	srv.handleDrainStart(rr, authedRequest(httptest.NewRequest(http.MethodPost, "/api/v1/admin/drain", nil),
+		auth.Principal{Role: auth.RoleAgent, AgentID: "ag_x"}))
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403", rr.Code)
+	}
+	if _, on := srv.draining(); on {
+		t.Fatal("agent turned drain mode on")
+	}
+}
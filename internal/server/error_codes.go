@@ -0,0 +1,184 @@
+package server
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+
+	"github.com/loppo-llc/kojo/internal/filebrowser"
+	gitpkg "github.com/loppo-llc/kojo/internal/git"
+	"github.com/loppo-llc/kojo/internal/session"
+)
+
+// Every error response has the body {"error":{"code":"...","message":"..."}}
+// (see writeError). Clients branch on the code; the message is for
+// people and may change. errorCatalog lists the codes shared across
+// the API and the ones for sessions, git and the file browser, which
+// come from the typed errors of those packages through the tables
+// below. Endpoints with codes of their own document them on the
+// handler.
+
+type errorCode struct {
+	Code        string `json:"code"`
+	Status      int    `json:"status"`
+	Description string `json:"description"`
+}
+
+var errorCatalog = []errorCode{
+	// Shared
+	{"bad_request", http.StatusBadRequest, "The request is malformed or a field is invalid."},
+	{"forbidden", http.StatusForbidden, "The caller may not do this, or the path is outside the allowed roots."},
+	{"not_found", http.StatusNotFound, "The session, file, revision or other resource does not exist."},
+	{"conflict", http.StatusConflict, "The resource is not in a state that allows this."},
+	{"payload_too_large", http.StatusRequestEntityTooLarge, "The body or the file is over its size limit."},
+	{"unsupported_media_type", http.StatusUnsupportedMediaType, "The file type can't be shown."},
+	{"rate_limited", http.StatusTooManyRequests, "Too many requests; try again later."},
+	{"internal_error", http.StatusInternalServerError, "The server failed; the message says how."},
+	{"unsupported", http.StatusNotImplemented, "Not available on this platform or without an optional tool."},
+	{"unavailable", http.StatusServiceUnavailable, "A component this needs is not configured or is draining."},
+
+	// Sessions
+	{"unsupported_tool", http.StatusBadRequest, "The tool is not one kojo runs."},
+	{"tool_not_found", http.StatusBadRequest, "The tool is not installed."},
+	{"work_dir_not_found", http.StatusBadRequest, "The working directory does not exist."},
+	{"protected_path", http.StatusForbidden, "The working directory is protected: no sessions there, or no yolo mode."},
+	{"unknown_host", http.StatusBadRequest, "No remote host by that name is configured."},
+	{"sandbox_unavailable", http.StatusBadRequest, "The sandbox is unknown or can't run here."},
+	{"token_budget_unsupported", http.StatusBadRequest, "Token budgets need a local claude session."},
+	{"prompt_unsupported", http.StatusBadRequest, "The tool can't start on a prompt."},
+	{"invalid_notify_pattern", http.StatusBadRequest, "A notify pattern is not a valid regular expression."},
+	{"invalid_size_policy", http.StatusBadRequest, "The size policy or fixed size is invalid."},
+	{"unsupported_signal", http.StatusBadRequest, "The signal is unknown or not available on this platform."},
+	{"unknown_action", http.StatusNotFound, "The session's tool has no housekeeping action by that name."},
+	{"invalid_control", http.StatusBadRequest, "The tool has no such model or permission mode, or none to switch."},
+	{"control_unverified", http.StatusConflict, "The screen doesn't show the tool's permission mode; retry at its prompt."},
+	{"checkpoint_unavailable", http.StatusBadRequest, "Checkpoints need a local session in a git repository."},
+	{"unknown_checkpoint", http.StatusNotFound, "The commit is not one of the session's checkpoints."},
+	{"untracked_files", http.StatusConflict, "Rolling back would delete untracked files; the message lists them."},
+	{"invalid_webhook", http.StatusBadRequest, "The webhook URL, events or pattern is invalid, or the session has too many."},
+	{"unknown_webhook", http.StatusNotFound, "The session has no webhook by that ID."},
+	{"no_base_commit", http.StatusConflict, "The session was not started in a git repository, or runs on a remote host."},
+	{"session_running", http.StatusConflict, "The session is still running."},
+	{"session_not_running", http.StatusConflict, "The session has exited."},
+	{"has_running_children", http.StatusConflict, "The session has running child sessions."},
+	{"not_terminal", http.StatusConflict, "The session is not a terminal session."},
+	{"cannot_resume", http.StatusConflict, "The session has no conversation to resume."},
+	{"invalid_pin", http.StatusBadRequest, "The input lock PIN is not 4 to 32 characters."},
+	{"input_locked", http.StatusConflict, "The session's input is already locked."},
+	{"not_input_locked", http.StatusConflict, "The session's input is not locked."},
+	{"wrong_pin", http.StatusForbidden, "The input lock PIN is wrong."},
+	{"pin_lockout", http.StatusTooManyRequests, "Too many wrong PINs; try again later."},
+
+	// Git
+	{"invalid_path", http.StatusBadRequest, "The path is malformed or escapes the repository."},
+	{"invalid_branch", http.StatusBadRequest, "The branch name is invalid."},
+	{"empty_message", http.StatusBadRequest, "The commit message is empty."},
+	{"nothing_to_commit", http.StatusConflict, "Nothing is staged."},
+	{"exec_denied", http.StatusForbidden, "The git command is not allowed."},
+
+	// File browser
+	{"is_directory", http.StatusBadRequest, "The path is a directory, not a file."},
+	{"not_directory", http.StatusBadRequest, "The path is a file, not a directory."},
+}
+
+// errorMapping turns errors matching err (errors.Is) into a response.
+type errorMapping struct {
+	err    error
+	status int
+	code   string
+}
+
+var sessionErrors = []errorMapping{
+	{session.ErrSessionNotFound, http.StatusNotFound, "not_found"},
+	{session.ErrUnsupportedTool, http.StatusBadRequest, "unsupported_tool"},
+	{session.ErrToolNotFound, http.StatusBadRequest, "tool_not_found"},
+	{session.ErrWorkDirNotFound, http.StatusBadRequest, "work_dir_not_found"},
+	{session.ErrProtectedPath, http.StatusForbidden, "protected_path"},
+	{session.ErrUnknownHost, http.StatusBadRequest, "unknown_host"},
+	{session.ErrSandbox, http.StatusBadRequest, "sandbox_unavailable"},
+	{session.ErrTokenBudget, http.StatusBadRequest, "token_budget_unsupported"},
+	{session.ErrPromptUnsupported, http.StatusBadRequest, "prompt_unsupported"},
+	{session.ErrInvalidNotifyPattern, http.StatusBadRequest, "invalid_notify_pattern"},
+	{session.ErrInvalidSizePolicy, http.StatusBadRequest, "invalid_size_policy"},
+	{session.ErrUnsupportedSignal, http.StatusBadRequest, "unsupported_signal"},
+	{session.ErrUnknownAction, http.StatusNotFound, "unknown_action"},
+	{session.ErrInvalidControl, http.StatusBadRequest, "invalid_control"},
+	{session.ErrControlUnverified, http.StatusConflict, "control_unverified"},
+	{session.ErrCheckpoint, http.StatusBadRequest, "checkpoint_unavailable"},
+	{session.ErrUnknownCheckpoint, http.StatusNotFound, "unknown_checkpoint"},
+	{session.ErrUntrackedFiles, http.StatusConflict, "untracked_files"},
+	{session.ErrInvalidWebhook, http.StatusBadRequest, "invalid_webhook"},
+	{session.ErrUnknownWebhook, http.StatusNotFound, "unknown_webhook"},
+	{session.ErrSessionRunning, http.StatusConflict, "session_running"},
+	{session.ErrSessionNotRunning, http.StatusConflict, "session_not_running"},
+	{session.ErrHasRunningChildren, http.StatusConflict, "has_running_children"},
+	{session.ErrNotTerminal, http.StatusConflict, "not_terminal"},
+	{session.ErrNoTmuxID, http.StatusConflict, "not_terminal"},
+	{session.ErrCannotResume, http.StatusConflict, "cannot_resume"},
+	{session.ErrInvalidPIN, http.StatusBadRequest, "invalid_pin"},
+	{session.ErrInputLocked, http.StatusConflict, "input_locked"},
+	{session.ErrNotInputLocked, http.StatusConflict, "not_input_locked"},
+	{session.ErrWrongPIN, http.StatusForbidden, "wrong_pin"},
+	{session.ErrPINLockout, http.StatusTooManyRequests, "pin_lockout"},
+}
+
+var gitErrors = []errorMapping{
+	{gitpkg.ErrInvalidPath, http.StatusBadRequest, "invalid_path"},
+	{gitpkg.ErrEmptyMessage, http.StatusBadRequest, "empty_message"},
+	{gitpkg.ErrNothingToCommit, http.StatusConflict, "nothing_to_commit"},
+	{gitpkg.ErrInvalidBranch, http.StatusBadRequest, "invalid_branch"},
+	{gitpkg.ErrWorktreeNotFound, http.StatusNotFound, "not_found"},
+	{gitpkg.ErrUnknownRevision, http.StatusNotFound, "not_found"},
+	{gitpkg.ErrPathNotFound, http.StatusNotFound, "not_found"},
+	{gitpkg.ErrGHUnavailable, http.StatusNotImplemented, "unsupported"},
+	{gitpkg.ErrFileTooLarge, http.StatusRequestEntityTooLarge, "payload_too_large"},
+	{gitpkg.ErrExecDenied, http.StatusForbidden, "exec_denied"},
+}
+
+var fileErrors = []errorMapping{
+	{filebrowser.ErrAccessDenied, http.StatusForbidden, "forbidden"},
+	{filebrowser.ErrInvalidPath, http.StatusBadRequest, "invalid_path"},
+	{filebrowser.ErrNotFound, http.StatusNotFound, "not_found"},
+	{fs.ErrNotExist, http.StatusNotFound, "not_found"},
+	{filebrowser.ErrIsDirectory, http.StatusBadRequest, "is_directory"},
+	{filebrowser.ErrNotDirectory, http.StatusBadRequest, "not_directory"},
+	{filebrowser.ErrUnsupportedFile, http.StatusUnsupportedMediaType, "unsupported_media_type"},
+	{filebrowser.ErrFileTooLarge, http.StatusRequestEntityTooLarge, "payload_too_large"},
+}
+
+// writeMappedError writes err with the first mapping it matches, or
+// with fallback and its generic code when none does.
+func writeMappedError(w http.ResponseWriter, err error, mappings []errorMapping, fallback int) {
+	for _, m := range mappings {
+		if errors.Is(err, m.err) {
+			writeError(w, m.status, m.code, err.Error())
+			return
+		}
+	}
+	writeError(w, fallback, codeForStatus(fallback), err.Error())
+}
+
+// writeSessionError writes an error from the session package; fallback
+// is the status for errors it has no code for.
+func writeSessionError(w http.ResponseWriter, err error, fallback int) {
+	writeMappedError(w, err, sessionErrors, fallback)
+}
+
+// writeGitError writes an error from the git package. Anything else is
+// a failed git invocation and keeps the 400 the other git routes use,
+// with git's stderr in the message.
+func writeGitError(w http.ResponseWriter, err error) {
+	writeMappedError(w, err, gitErrors, http.StatusBadRequest)
+}
+
+// writeFileError writes an error from the file browser; others are 400.
+func writeFileError(w http.ResponseWriter, err error) {
+	writeMappedError(w, err, fileErrors, http.StatusBadRequest)
+}
+
+// handleErrorCodes GET /api/v1/error-codes
+//
+// Lists the error codes in errorCatalog with their HTTP status.
+func (s *Server) handleErrorCodes(w http.ResponseWriter, r *http.Request) {
+	writeJSONResponse(w, http.StatusOK, map[string]any{"codes": errorCatalog})
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/loppo-llc/kojo/internal/filebrowser"
+	"github.com/loppo-llc/kojo/internal/session"
+)
+
+func TestErrorMappingsInCatalog(t *testing.T) {
+	catalog := map[string]int{}
+	for _, c := range errorCatalog {
+		if _, dup := catalog[c.Code]; dup {
+			t.Errorf("code %q listed twice", c.Code)
+		}
+		catalog[c.Code] = c.Status
+	}
+	for name, mappings := range map[string][]errorMapping{"session": sessionErrors, "git": gitErrors, "file": fileErrors} {
+		for _, m := range mappings {
+			status, ok := catalog[m.code]
+			if !ok {
+				t.Errorf("%s error %q maps to %q, which the catalog lacks", name, m.err, m.code)
+			} else if status != m.status {
+				t.Errorf("%s error %q: status %d, catalog says %d", name, m.err, m.status, status)
+			}
+		}
+	}
+}
+
+func TestWriteMappedError(t *testing.T) {
+	for _, c := range []struct {
+		write  func(http.ResponseWriter, error)
+		err    error
+		status int
+		code   string
+	}{
+		{func(w http.ResponseWriter, err error) { writeSessionError(w, err, http.StatusBadRequest) },
+			fmt.Errorf("%w: s1", session.ErrSessionNotRunning), http.StatusConflict, "session_not_running"},
+		{func(w http.ResponseWriter, err error) { writeSessionError(w, err, http.StatusInternalServerError) },
+			fmt.Errorf("kill: permission denied"), http.StatusInternalServerError, "internal_error"},
+		{writeFileError, fmt.Errorf("%w: path must be under /x", filebrowser.ErrAccessDenied), http.StatusForbidden, "forbidden"},
+		{writeFileError, filebrowser.ErrIsDirectory, http.StatusBadRequest, "is_directory"},
+	} {
+		rec := httptest.NewRecorder()
+		c.write(rec, c.err)
+		var body struct {
+			Error struct{ Code, Message string }
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Code != c.status || body.Error.Code != c.code || body.Error.Message != c.err.Error() {
+			t.Errorf("%v: got %d %q %q, want %d %q", c.err, rec.Code, body.Error.Code, body.Error.Message, c.status, c.code)
+		}
+	}
+}
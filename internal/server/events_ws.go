@@ -62,7 +62,8 @@ func (s *Server) handleEventsWS(w http.ResponseWriter, r *http.Request) {
 	defer sub.Cancel()
 
 	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
-		OriginPatterns: wsOriginPatterns,
+		OriginPatterns:  wsOriginPatterns,
+		CompressionMode: wsCompression,
 	})
 	if err != nil {
 		s.logger.Error("events websocket accept failed", "err", err)
@@ -76,6 +77,7 @@ func (s *Server) handleEventsWS(w http.ResponseWriter, r *http.Request) {
 	// (it's a pure server-push channel) but a tiny limit defends against
 	// a buggy client streaming garbage.
 	conn.SetReadLimit(4 * 1024)
+	wsAttach(ctx, conn)
 
 	// Drain any client frames in the background so the read pump notices
 	// disconnects promptly and the websocket library can deliver Pongs.
@@ -86,6 +88,7 @@ func (s *Server) handleEventsWS(w http.ResponseWriter, r *http.Request) {
 				cancel()
 				return
 			}
+			wsTouch(ctx)
 		}
 	}()
 
@@ -135,6 +138,7 @@ func (s *Server) handleEventsWS(w http.ResponseWriter, r *http.Request) {
 				}
 				return
 			}
+			wsTouch(ctx)
 		}
 	}
 }
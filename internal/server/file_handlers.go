@@ -4,13 +4,18 @@ import (
 	"errors"
 	"fmt"
 	"mime"
+	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/loppo-llc/kojo/internal/filebrowser"
+	"github.com/loppo-llc/kojo/internal/session"
 	"github.com/loppo-llc/kojo/internal/thumbnail"
 	"github.com/loppo-llc/kojo/internal/uploadpath"
 )
@@ -25,6 +30,8 @@ func codeForStatus(status int) string {
 		return "forbidden"
 	case http.StatusNotFound:
 		return "not_found"
+	case http.StatusConflict:
+		return "conflict"
 	case http.StatusRequestEntityTooLarge:
 		return "too_large"
 	case http.StatusUnsupportedMediaType:
@@ -48,48 +55,93 @@ func writeServeErr(w http.ResponseWriter, err error) {
 
 // --- File Browser Handlers ---
 
+// fileBrowserFor returns the browser a global file request runs against.
+// A request naming a session (?session=<id>, sent by a session's file
+// tab) gets a browser scoped to that session's workDir, so the tab can't
+// wander outside the project it was opened for. Writes the error
+// response and returns ok=false when the session is unknown or its
+// workDir is itself outside the allowed roots.
+func (s *Server) fileBrowserFor(w http.ResponseWriter, r *http.Request) (*filebrowser.Browser, bool) {
+	id := r.URL.Query().Get("session")
+	if id == "" {
+		return s.files, true
+	}
+	sess, ok := s.sessions.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "session not found: "+id)
+		return nil, false
+	}
+	scoped, err := s.files.Scoped(sess.Info().WorkDir)
+	if err != nil {
+		writeFileError(w, err)
+		return nil, false
+	}
+	return scoped, true
+}
+
+// parseListOptions reads the directory-listing query parameters shared by
+// the global and agent-scoped list endpoints: hidden=true, sort=name|mtime|size,
+// order=asc|desc, offset and limit. Unknown or malformed values fall back
+// to the defaults rather than failing the listing.
+func parseListOptions(r *http.Request) filebrowser.ListOptions {
+	q := r.URL.Query()
+	opts := filebrowser.ListOptions{
+		Hidden: q.Get("hidden") == "true",
+		Sort:   q.Get("sort"),
+		Desc:   q.Get("order") == "desc",
+	}
+	if n, err := strconv.Atoi(q.Get("offset")); err == nil && n > 0 {
+		opts.Offset = n
+	}
+	if n, err := strconv.Atoi(q.Get("limit")); err == nil && n > 0 {
+		opts.Limit = n
+	}
+	return opts
+}
+
 func (s *Server) handleListFiles(w http.ResponseWriter, r *http.Request) {
+	files, ok := s.fileBrowserFor(w, r)
+	if !ok {
+		return
+	}
 	dir := r.URL.Query().Get("path")
-	hidden := r.URL.Query().Get("hidden") == "true"
 
-	result, err := s.files.List(dir, hidden)
+	result, err := files.List(dir, parseListOptions(r))
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		writeFileError(w, err)
 		return
 	}
 	writeJSONResponse(w, http.StatusOK, result)
 }
 
-// writeFileViewError maps a filebrowser.View error onto the status
-// ladder shared by the global and agent-scoped file-view endpoints:
-// unsupported file type → 415, size cap → 413, anything else → 400.
-// The body carries err.Error() verbatim in every branch.
-func writeFileViewError(w http.ResponseWriter, err error) {
-	if errors.Is(err, filebrowser.ErrUnsupportedFile) {
-		writeError(w, http.StatusUnsupportedMediaType, "unsupported_media_type", err.Error())
-	} else if errors.Is(err, filebrowser.ErrFileTooLarge) {
-		writeError(w, http.StatusRequestEntityTooLarge, "payload_too_large", err.Error())
-	} else {
-		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
-	}
-}
-
 func (s *Server) handleViewFile(w http.ResponseWriter, r *http.Request) {
+	files, ok := s.fileBrowserFor(w, r)
+	if !ok {
+		return
+	}
 	path := r.URL.Query().Get("path")
-	result, err := s.files.View(path)
+	result, err := files.View(path)
 	if err != nil {
-		writeFileViewError(w, err)
+		writeFileError(w, err)
 		return
 	}
+	// Keep the raw viewer URL inside the same session scope.
+	if id := r.URL.Query().Get("session"); id != "" && result.URL != "" {
+		result.URL += "&session=" + url.QueryEscape(id)
+	}
 	writeJSONResponse(w, http.StatusOK, result)
 }
 
 func (s *Server) handleRawFile(w http.ResponseWriter, r *http.Request) {
+	files, ok := s.fileBrowserFor(w, r)
+	if !ok {
+		return
+	}
 	path := r.URL.Query().Get("path")
 	if r.URL.Query().Get("download") == "1" {
 		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filepath.Base(path)}))
 	}
-	if err := s.files.ServeRaw(w, r, path); err != nil {
+	if err := files.ServeRaw(w, r, path); err != nil {
 		writeServeErr(w, err)
 	}
 }
@@ -99,13 +151,83 @@ func (s *Server) handleRawFile(w http.ResponseWriter, r *http.Request) {
 // 5-MB screenshot doesn't have to ship in full just to render a 150-px
 // tile.
 func (s *Server) handleThumbFile(w http.ResponseWriter, r *http.Request) {
+	files, ok := s.fileBrowserFor(w, r)
+	if !ok {
+		return
+	}
 	path := r.URL.Query().Get("path")
 	size, _ := strconv.Atoi(r.URL.Query().Get("size"))
-	if err := s.files.ServeThumb(w, r, path, size); err != nil {
+	if err := files.ServeThumb(w, r, path, size); err != nil {
 		writeServeErr(w, err)
 	}
 }
 
+// handleTailFile returns the last `lines` lines of a text file. With
+// follow=true the response is a Server-Sent Events stream: one `lines`
+// event per batch of appended lines (JSON filebrowser.TailEvent) and a
+// comment line as keepalive while the file is idle. The stream ends
+// when the client disconnects. Without follow the backlog is returned
+// as a single JSON TailEvent.
+func (s *Server) handleTailFile(w http.ResponseWriter, r *http.Request) {
+	files, ok := s.fileBrowserFor(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	path := q.Get("path")
+	lines, _ := strconv.Atoi(q.Get("lines"))
+	follow := q.Get("follow") == "true"
+
+	if !follow {
+		err := files.Tail(r.Context(), path, lines, false, func(ev filebrowser.TailEvent) error {
+			writeJSONResponse(w, http.StatusOK, ev)
+			return nil
+		})
+		if err != nil {
+			writeFileError(w, err)
+		}
+		return
+	}
+
+	sse := newSSEWriter(w)
+	err := files.Tail(r.Context(), path, lines, true, func(ev filebrowser.TailEvent) error {
+		if sse.started && len(ev.Lines) == 0 && !ev.Reset {
+			return sse.keepalive()
+		}
+		return sse.event("lines", ev)
+	})
+	if err != nil && !sse.started {
+		writeFileError(w, err)
+	}
+}
+
+// handleWatchFiles streams debounced created/modified/deleted changes
+// under a directory (recursively) as Server-Sent Events: one `changes`
+// event per batch ({"changes":[{"path","op"}]}, paths relative to the
+// watched directory) and a keepalive comment while idle. An op of
+// "rescan" means changes were dropped and the client should reload.
+// Accepts ?session= like the other file routes.
+func (s *Server) handleWatchFiles(w http.ResponseWriter, r *http.Request) {
+	files, ok := s.fileBrowserFor(w, r)
+	if !ok {
+		return
+	}
+	path := r.URL.Query().Get("path")
+
+	sse := newSSEWriter(w)
+	// Watch calls back with an empty batch as soon as it is established,
+	// which opens the stream before the first change arrives.
+	err := files.Watch(r.Context(), path, func(changes []filebrowser.FileChange) error {
+		if len(changes) == 0 {
+			return sse.keepalive()
+		}
+		return sse.event("changes", map[string]any{"changes": changes})
+	})
+	if err != nil && !sse.started {
+		writeFileError(w, err)
+	}
+}
+
 // --- Upload Handler ---
 
 var uploadDir = uploadpath.Dir()
@@ -122,6 +244,19 @@ const maxUploadSize = 10 << 30 // 10 GiB
 // when the cap above grows.
 const maxUploadInMemory = 32 << 20 // 32 MiB
 
+// handleUpload POST /api/v1/upload
+//
+// Multipart form with one or more "file" parts, saved under uploadDir.
+// The response lists them in "files"; "path", "name", "size" and "mime"
+// repeat the first one for single-file callers. 507 quota_exceeded when
+// the files would take uploadDir past the upload quota.
+//
+// With "sessionId" and "insert=true" form fields the saved paths are
+// also pasted into that session's input, the way a file dragged onto a
+// terminal is, so the agent picks the attachment up right away. The
+// session must be running (404 / 409 otherwise, before anything is
+// saved), and a "pin" field must unlock its input when that is locked;
+// "inserted" in the response reports whether the paste went through.
 func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
 	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
 	if err := r.ParseMultipartForm(maxUploadInMemory); err != nil {
@@ -139,46 +274,135 @@ func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
-	file, header, err := r.FormFile("file")
-	if err != nil {
+	headers := r.MultipartForm.File["file"]
+	if len(headers) == 0 {
 		writeError(w, http.StatusBadRequest, "bad_request", "missing file field")
 		return
 	}
-	defer file.Close()
+	var insertInto *session.Session
+	if id := r.FormValue("sessionId"); id != "" && r.FormValue("insert") == "true" {
+		sess, ok := s.sessions.Get(id)
+		if !ok {
+			writeError(w, http.StatusNotFound, "not_found", "session not found: "+id)
+			return
+		}
+		if err := sess.CheckInputPIN(r.FormValue("pin")); err != nil && !errors.Is(err, session.ErrNotInputLocked) {
+			writeSessionError(w, err, http.StatusInternalServerError)
+			return
+		}
+		if sess.Info().Status != session.StatusRunning {
+			writeError(w, http.StatusConflict, "conflict", "session not running: "+id)
+			return
+		}
+		insertInto = sess
+	}
+	var total int64
+	for _, h := range headers {
+		total += h.Size
+	}
+	release, err := s.uploads.reserve(total)
+	if errors.Is(err, errUploadQuota) {
+		writeError(w, http.StatusInsufficientStorage, "quota_exceeded", err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	defer release()
 
 	if err := os.MkdirAll(uploadDir, 0o755); err != nil {
 		writeError(w, http.StatusInternalServerError, "internal_error", "failed to create upload directory")
 		return
 	}
 
+	files := make([]uploadedFile, 0, len(headers))
+	for _, h := range headers {
+		f, err := saveUpload(h)
+		if err != nil {
+			// All or nothing: don't leave half a batch behind.
+			for _, done := range files {
+				os.Remove(done.Path)
+			}
+			writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+		files = append(files, f)
+	}
+
+	resp := map[string]any{
+		"path":  files[0].Path,
+		"name":  files[0].Name,
+		"size":  files[0].Size,
+		"mime":  files[0].MIME,
+		"files": files,
+	}
+	if insertInto != nil {
+		paths := make([]string, len(files))
+		for i, f := range files {
+			paths[i] = insertablePath(f.Path)
+		}
+		// The trailing space lets the user keep typing after the path.
+		err := insertInto.Paste(strings.Join(paths, " ") + " ")
+		if err != nil {
+			s.logger.Warn("upload: insert into session failed", "session", insertInto.ID, "err", err)
+		}
+		resp["inserted"] = err == nil
+	}
+	writeJSONResponse(w, http.StatusOK, resp)
+}
+
+// insertablePath returns p as it would be typed at a shell prompt:
+// unchanged when it has no spaces or shell metacharacters, otherwise
+// single-quoted. Agent CLIs recognize both forms as a file reference.
+func insertablePath(p string) string {
+	if !strings.ContainsFunc(p, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune("\"'`$&|;<>()[]{}*?!#", r)
+	}) {
+		return p
+	}
+	return "'" + strings.ReplaceAll(p, "'", `'\''`) + "'"
+}
+
+// uploadedFile describes a file saved by handleUpload.
+type uploadedFile struct {
+	Path string `json:"path"`
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	MIME string `json:"mime"`
+}
+
+// saveUpload copies one multipart file into uploadDir as
+// "{unixnano}_{sanitized name}".
+func saveUpload(header *multipart.FileHeader) (uploadedFile, error) {
+	file, err := header.Open()
+	if err != nil {
+		return uploadedFile{}, fmt.Errorf("failed to read %s", header.Filename)
+	}
+	defer file.Close()
+
 	safeName := uploadpath.SanitizeName(header.Filename)
 	filename := fmt.Sprintf("%d_%s", time.Now().UnixNano(), safeName)
 	destPath := filepath.Join(uploadDir, filename)
 
 	dst, err := os.Create(destPath)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "internal_error", "failed to create file")
-		return
+		return uploadedFile{}, errors.New("failed to create file")
 	}
-	defer dst.Close()
-
 	written, err := dst.ReadFrom(file)
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "internal_error", "failed to write file")
-		return
+		os.Remove(destPath)
+		return uploadedFile{}, errors.New("failed to write file")
 	}
 
 	mime := header.Header.Get("Content-Type")
 	if mime == "" {
 		mime = "application/octet-stream"
 	}
-
-	writeJSONResponse(w, http.StatusOK, map[string]any{
-		"path": destPath,
-		"name": header.Filename,
-		"size": written,
-		"mime": mime,
-	})
+	return uploadedFile{Path: destPath, Name: header.Filename, Size: written, MIME: mime}, nil
 }
 
 func cleanupUploads() {
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/loppo-llc/kojo/internal/auth"
+)
+
+// gitExecAuditEntry is one line of the /git/exec audit log.
+type gitExecAuditEntry struct {
+	Time       time.Time `json:"time"`
+	Principal  string    `json:"principal"`
+	WorkDir    string    `json:"workDir"`
+	Args       []string  `json:"args"`
+	Allowed    bool      `json:"allowed"`
+	Error      string    `json:"error,omitempty"`
+	ExitCode   int       `json:"exitCode"`
+	DurationMs int64     `json:"durationMs"`
+}
+
+// gitExecAudit appends every /git/exec call to a JSON-lines file. The
+// file is opened per write so an operator can rotate it away at any
+// time; exec is interactive-rate, so the open cost doesn't matter.
+// Write failures are logged, never surfaced to the caller.
+type gitExecAudit struct {
+	path   string
+	logger *slog.Logger
+	mu     sync.Mutex
+}
+
+func newGitExecAudit(path string, logger *slog.Logger) *gitExecAudit {
+	if path == "" {
+		return nil
+	}
+	return &gitExecAudit{path: path, logger: logger}
+}
+
+func (a *gitExecAudit) record(e gitExecAuditEntry) {
+	// The daemon log always gets the call, so there is a trail even
+	// when the audit file is disabled or unwritable.
+	logger := slog.Default()
+	if a != nil {
+		logger = a.logger
+	}
+	logger.Info("git exec", "principal", e.Principal, "workDir", e.WorkDir,
+		"args", e.Args, "allowed", e.Allowed, "exitCode", e.ExitCode, "err", e.Error)
+	if a == nil {
+		return
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(a.path), 0o700); err != nil {
+		a.logger.Warn("git exec audit: create dir failed", "path", a.path, "err", err)
+		return
+	}
+	f, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		a.logger.Warn("git exec audit: open failed", "path", a.path, "err", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		a.logger.Warn("git exec audit: write failed", "path", a.path, "err", err)
+	}
+}
+
+// principalLabel renders p for audit records: "owner", "peer:<id>",
+// "agent:<id>" or "guest".
+func principalLabel(p auth.Principal) string {
+	switch {
+	case p.IsOwner():
+		return "owner"
+	case p.IsPeer():
+		return "peer:" + p.PeerID
+	case p.IsAgent():
+		return "agent:" + p.AgentID
+	}
+	return "guest"
+}
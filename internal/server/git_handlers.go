@@ -2,8 +2,15 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/loppo-llc/kojo/internal/auth"
+	gitpkg "github.com/loppo-llc/kojo/internal/git"
+	"github.com/loppo-llc/kojo/internal/session"
 )
 
 // --- Git Handlers ---
@@ -12,7 +19,7 @@ func (s *Server) handleGitStatus(w http.ResponseWriter, r *http.Request) {
 	workDir := r.URL.Query().Get("workDir")
 	result, err := s.git.Status(workDir)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		writeGitError(w, err)
 		return
 	}
 	writeJSONResponse(w, http.StatusOK, result)
@@ -37,23 +44,210 @@ func (s *Server) handleGitLog(w http.ResponseWriter, r *http.Request) {
 	}
 	result, err := s.git.Log(workDir, limit, skip)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		writeGitError(w, err)
 		return
 	}
 	writeJSONResponse(w, http.StatusOK, result)
 }
 
+// handleGitDiff returns the raw diff text by default. With
+// ?format=structured it returns parsed files/hunks/lines instead, and
+// also honours ?staged=1 and repeated ?path= filters; ref must then be
+// a commit hash.
 func (s *Server) handleGitDiff(w http.ResponseWriter, r *http.Request) {
-	workDir := r.URL.Query().Get("workDir")
-	ref := r.URL.Query().Get("ref")
+	q := r.URL.Query()
+	workDir := q.Get("workDir")
+	ref := q.Get("ref")
+	if q.Get("format") == "structured" {
+		result, err := s.git.StructuredDiff(workDir, gitpkg.DiffOptions{
+			Ref:    ref,
+			Staged: q.Get("staged") == "1" || q.Get("staged") == "true",
+			Paths:  q["path"],
+		})
+		if err != nil {
+			writeGitError(w, err)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, result)
+		return
+	}
 	result, err := s.git.Diff(workDir, ref)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		writeGitError(w, err)
 		return
 	}
 	writeJSONResponse(w, http.StatusOK, result)
 }
 
+// handleSessionChanges GET /api/v1/sessions/{id}/changes
+//
+// Diffs the session's working directory, untracked files included,
+// against the commit it had checked out when the session was created:
+// the work done in the session, as structured diff plus "base".
+// Honours repeated ?path= filters.
+func (s *Server) handleSessionChanges(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	sess, ok := s.sessions.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "session not found: "+id)
+		return
+	}
+	info := sess.Info()
+	if info.BaseCommit == "" {
+		writeError(w, http.StatusConflict, "no_base_commit", "session has no base commit: "+id)
+		return
+	}
+	result, err := s.git.StructuredDiff(info.WorkDir, gitpkg.DiffOptions{
+		Base:  info.BaseCommit,
+		Paths: r.URL.Query()["path"],
+	})
+	if err != nil {
+		writeGitError(w, err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, struct {
+		Base string `json:"base"`
+		*gitpkg.StructuredDiffResult
+	}{info.BaseCommit, result})
+}
+
+// handleListCheckpoints GET /api/v1/sessions/{id}/checkpoints
+//
+// Lists the session's checkpoints, newest first, for
+// POST /api/v1/sessions/{id}/rollback.
+func (s *Server) handleListCheckpoints(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	sess, ok := s.sessions.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "session not found: "+id)
+		return
+	}
+	list, err := s.sessions.Checkpoints(sess)
+	if err != nil {
+		writeSessionError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]any{"ref": session.CheckpointRef(id), "checkpoints": list})
+}
+
+// handleCreateCheckpoint POST /api/v1/sessions/{id}/checkpoints
+//
+// Checkpoints the session's working tree now, whether or not it has a
+// checkpoint interval. Answers with the checkpoint, or null in
+// "checkpoint" when nothing changed since the last one.
+func (s *Server) handleCreateCheckpoint(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	sess, ok := s.sessions.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "session not found: "+id)
+		return
+	}
+	cp, err := s.sessions.Checkpoint(sess, "manual")
+	if err != nil {
+		writeSessionError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]any{"checkpoint": cp})
+}
+
+// handleRollbackSession POST /api/v1/sessions/{id}/rollback
+//
+// Body: {"commit":"<checkpoint>","force":false}. Resets the paused or
+// exited session's working tree to one of its checkpoints, after
+// checkpointing it as it is. Untracked files the checkpoint lacks are
+// only deleted with force.
+func (s *Server) handleRollbackSession(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	sess, ok := s.sessions.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "session not found: "+id)
+		return
+	}
+	var req struct {
+		Commit string `json:"commit"`
+		Force  bool   `json:"force"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid request body")
+		return
+	}
+	if req.Commit == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "commit is required")
+		return
+	}
+	result, err := s.sessions.Rollback(sess, req.Commit, req.Force)
+	if err != nil {
+		writeSessionError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, result)
+}
+
+// handleGitRepos lists git repositories found under the file browser's
+// roots, for the new-session screen's repo picker. Scans are cached
+// briefly; ?refresh=1 forces a rescan.
+func (s *Server) handleGitRepos(w http.ResponseWriter, r *http.Request) {
+	refresh := r.URL.Query().Get("refresh") == "1"
+	repos := s.git.Repos(s.files.Roots(), refresh)
+	writeJSONResponse(w, http.StatusOK, map[string]any{"repos": repos})
+}
+
+// handleGitWatch streams the branch's ahead/behind counts and upstream
+// head as Server-Sent Events: a `remote` event with a git.RemoteStatus
+// whenever it changes, keepalive comments otherwise. Background `git
+// fetch` is opt-in per watch via ?fetchInterval=<seconds> (floored at
+// git.MinFetchInterval); without it only local changes are reported.
+func (s *Server) handleGitWatch(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var fetchEvery time.Duration
+	if v := q.Get("fetchInterval"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "bad_request", "fetchInterval must be a non-negative number of seconds")
+			return
+		}
+		fetchEvery = time.Duration(n) * time.Second
+	}
+
+	sse := newSSEWriter(w)
+	err := s.git.WatchRemote(r.Context(), q.Get("workDir"), fetchEvery, func(st *gitpkg.RemoteStatus) error {
+		if st == nil {
+			return sse.keepalive()
+		}
+		return sse.event("remote", st)
+	})
+	if err != nil && !sse.started {
+		writeGitError(w, err)
+	}
+}
+
+// handleGitShow returns a commit's metadata and structured diff.
+func (s *Server) handleGitShow(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	result, err := s.git.Show(q.Get("workDir"), q.Get("ref"))
+	if err != nil {
+		writeGitError(w, err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, result)
+}
+
+// handleGitFile returns a file's content as of ?ref=, for before/after
+// views of a commit.
+func (s *Server) handleGitFile(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	result, err := s.git.FileAt(q.Get("workDir"), q.Get("ref"), q.Get("path"))
+	if err != nil {
+		writeGitError(w, err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, result)
+}
+
+// handleGitExec runs a git command for the Git tab's free-form flows.
+// The git.Manager policy rejects subcommands outside the allowlist,
+// denied rules like `push --force`, and injection flags (403). Every
+// call, allowed or not, is written to the exec audit log.
 func (s *Server) handleGitExec(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		WorkDir string   `json:"workDir"`
@@ -63,9 +257,166 @@ func (s *Server) handleGitExec(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "bad_request", "invalid request body")
 		return
 	}
+	start := time.Now()
 	result, err := s.git.Exec(req.WorkDir, req.Args)
+	entry := gitExecAuditEntry{
+		Time:       start,
+		Principal:  principalLabel(auth.FromContext(r.Context())),
+		WorkDir:    req.WorkDir,
+		Args:       req.Args,
+		Allowed:    !errors.Is(err, gitpkg.ErrExecDenied),
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	} else {
+		entry.ExitCode = result.ExitCode
+	}
+	s.gitAudit.record(entry)
+
+	if err != nil {
+		writeGitError(w, err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, result)
+}
+
+type gitPathsRequest struct {
+	WorkDir string   `json:"workDir"`
+	Paths   []string `json:"paths"`
+}
+
+// handleGitPaths serves the stage / unstage / discard routes, which all
+// take {workDir, paths} and answer with the refreshed status.
+func (s *Server) handleGitPaths(op func(workDir string, paths []string) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req gitPathsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "bad_request", "invalid request body")
+			return
+		}
+		if err := op(req.WorkDir, req.Paths); err != nil {
+			writeGitError(w, err)
+			return
+		}
+		status, err := s.git.Status(req.WorkDir)
+		if err != nil {
+			writeGitError(w, err)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, status)
+	}
+}
+
+func (s *Server) handleGitCommit(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		WorkDir string `json:"workDir"`
+		Message string `json:"message"`
+		Amend   bool   `json:"amend"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid request body")
+		return
+	}
+	result, err := s.git.Commit(req.WorkDir, req.Message, req.Amend)
+	if err != nil {
+		writeGitError(w, err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, result)
+}
+
+func (s *Server) handleGitWorktrees(w http.ResponseWriter, r *http.Request) {
+	trees, err := s.git.Worktrees(r.URL.Query().Get("workDir"))
+	if err != nil {
+		writeGitError(w, err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]any{"worktrees": trees})
+}
+
+func (s *Server) handleGitAddWorktree(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		WorkDir string `json:"workDir"`
+		Branch  string `json:"branch"`
+		Path    string `json:"path"`
+		Base    string `json:"base"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid request body")
+		return
+	}
+	wt, err := s.git.AddWorktree(req.WorkDir, gitpkg.AddWorktreeOptions{
+		Branch: req.Branch,
+		Path:   req.Path,
+		Base:   req.Base,
+	})
+	if err != nil {
+		writeGitError(w, err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, wt)
+}
+
+// handleGitRemoveWorktree deletes a worktree. ?force=1 discards its
+// uncommitted changes; without it git refuses a dirty tree.
+func (s *Server) handleGitRemoveWorktree(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	force := q.Get("force") == "1" || q.Get("force") == "true"
+	if err := s.git.RemoveWorktree(q.Get("workDir"), q.Get("path"), force); err != nil {
+		writeGitError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleGitPullRequests(w http.ResponseWriter, r *http.Request) {
+	prs, err := s.git.PullRequests(r.Context(), r.URL.Query().Get("workDir"))
+	if err != nil {
+		writeGitError(w, err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]any{"pullRequests": prs})
+}
+
+// handleGitCreatePullRequest pushes the current branch and opens a PR
+// for it via gh. An empty title lets gh fill title and body from the
+// commits.
+func (s *Server) handleGitCreatePullRequest(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		WorkDir string `json:"workDir"`
+		Title   string `json:"title"`
+		Body    string `json:"body"`
+		Base    string `json:"base"`
+		Draft   bool   `json:"draft"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid request body")
+		return
+	}
+	pr, err := s.git.CreatePullRequest(r.Context(), req.WorkDir, gitpkg.CreatePROptions{
+		Title: req.Title,
+		Body:  req.Body,
+		Base:  req.Base,
+		Draft: req.Draft,
+	})
+	if err != nil {
+		writeGitError(w, err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, pr)
+}
+
+func (s *Server) handleGitPullRequestChecks(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	number, err := strconv.Atoi(q.Get("number"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "number is required")
+		return
+	}
+	result, err := s.git.PullRequestChecks(r.Context(), q.Get("workDir"), number)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		writeGitError(w, err)
 		return
 	}
 	writeJSONResponse(w, http.StatusOK, result)
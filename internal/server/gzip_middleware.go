@@ -0,0 +1,159 @@
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// gzipMinSize is the smallest response gzipMiddleware compresses;
+// below it the gzip framing costs about what it saves.
+const gzipMinSize = 1024
+
+var gzipWriters = sync.Pool{New: func() any { return gzip.NewWriter(io.Discard) }}
+
+// gzipMiddleware gzips text responses (JSON, HTML, JS, CSS, SVG) of
+// gzipMinSize or more for clients that accept it: over a cellular link
+// big diffs, file listings and the UI bundle are most of the wait.
+// Responses that already have a Content-Encoding, ranges, HEAD
+// requests and WebSocket upgrades pass through untouched.
+func gzipMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead || isWebSocketUpgrade(r) || !acceptsEncoding(r, "gzip") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Accept-Encoding")
+		gw := &gzipResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		defer gw.close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// acceptsEncoding reports whether r's Accept-Encoding allows the
+// content coding enc.
+func acceptsEncoding(r *http.Request, enc string) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(part, ";")
+		if name = strings.TrimSpace(name); name != enc && name != "*" {
+			continue
+		}
+		q := strings.ReplaceAll(params, " ", "")
+		return q != "q=0" && q != "q=0.0" && q != "q=0.00" && q != "q=0.000"
+	}
+	return false
+}
+
+// gzipResponseWriter holds back the first gzipMinSize bytes of a
+// response to decide whether to compress it.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	status  int
+	buf     []byte
+	decided bool
+	gz      *gzip.Writer
+}
+
+func (g *gzipResponseWriter) WriteHeader(code int) {
+	if g.decided {
+		return
+	}
+	g.status = code
+	// Informational and bodiless responses go out as they are.
+	if code < http.StatusOK || code == http.StatusNoContent || code == http.StatusNotModified {
+		g.decided = true
+		g.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (g *gzipResponseWriter) Write(p []byte) (int, error) {
+	if !g.decided {
+		g.buf = append(g.buf, p...)
+		if len(g.buf) < gzipMinSize {
+			return len(p), nil
+		}
+		if err := g.decide(); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if g.gz != nil {
+		return g.gz.Write(p)
+	}
+	return g.ResponseWriter.Write(p)
+}
+
+// decide sends the header, compressing if the response qualifies, and
+// the bytes held back so far.
+func (g *gzipResponseWriter) decide() error {
+	g.decided = true
+	h := g.Header()
+	if h.Get("Content-Type") == "" && len(g.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(g.buf))
+	}
+	if len(g.buf) >= gzipMinSize && g.status != http.StatusPartialContent &&
+		h.Get("Content-Encoding") == "" && h.Get("Content-Range") == "" && compressible(h.Get("Content-Type")) {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", "gzip")
+		// The gzipped bytes differ from what the ETag names.
+		if etag := h.Get("ETag"); strings.HasPrefix(etag, `"`) {
+			h.Set("ETag", "W/"+etag)
+		}
+		g.gz = gzipWriters.Get().(*gzip.Writer)
+		g.gz.Reset(g.ResponseWriter)
+	}
+	g.ResponseWriter.WriteHeader(g.status)
+	buf := g.buf
+	g.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if g.gz != nil {
+		_, err = g.gz.Write(buf)
+	} else {
+		_, err = g.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// Flush sends what is held back, so streamed responses keep streaming.
+func (g *gzipResponseWriter) Flush() {
+	if !g.decided {
+		_ = g.decide()
+	}
+	if g.gz != nil {
+		_ = g.gz.Flush()
+	}
+	_ = http.NewResponseController(g.ResponseWriter).Flush()
+}
+
+func (g *gzipResponseWriter) Unwrap() http.ResponseWriter { return g.ResponseWriter }
+
+func (g *gzipResponseWriter) close() {
+	if !g.decided {
+		_ = g.decide()
+	}
+	if g.gz != nil {
+		_ = g.gz.Close()
+		gzipWriters.Put(g.gz)
+		g.gz = nil
+	}
+}
+
+// compressible reports whether content of type ct shrinks under gzip.
+func compressible(ct string) bool {
+	ct, _, _ = strings.Cut(ct, ";")
+	ct = strings.TrimSpace(strings.ToLower(ct))
+	switch {
+	case ct == "text/event-stream":
+		return false // streamed; each event is small
+	case strings.HasPrefix(ct, "text/"),
+		strings.HasSuffix(ct, "json"), strings.HasSuffix(ct, "+xml"),
+		ct == "application/javascript", ct == "application/xml", ct == "image/svg+xml":
+		return true
+	}
+	return false
+}
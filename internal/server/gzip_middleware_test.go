@@ -0,0 +1,71 @@
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGzipMiddleware(t *testing.T) {
+	large := `{"diff":"` + strings.Repeat("+ line\\n", 400) + `"}`
+	cases := []struct {
+		name     string
+		accept   string
+		ctype    string
+		body     string
+		wantGzip bool
+	}{
+		{"large json", "gzip, deflate, br", "application/json", large, true},
+		{"small json", "gzip", "application/json", `{"ok":true}`, false},
+		{"client without gzip", "", "application/json", large, false},
+		{"gzip refused", "gzip;q=0", "application/json", large, false},
+		{"event stream", "gzip", "text/event-stream", large, false},
+		{"image", "gzip", "image/png", large, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := gzipMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tc.ctype)
+				w.WriteHeader(http.StatusCreated)
+				// Written in pieces, so the decision spans writes.
+				io.WriteString(w, tc.body[:len(tc.body)/2])
+				io.WriteString(w, tc.body[len(tc.body)/2:])
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/sessions/s1/diff", nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept-Encoding", tc.accept)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusCreated {
+				t.Errorf("status = %d", rec.Code)
+			}
+			gotGzip := rec.Header().Get("Content-Encoding") == "gzip"
+			if gotGzip != tc.wantGzip {
+				t.Fatalf("Content-Encoding = %q, want gzip %v", rec.Header().Get("Content-Encoding"), tc.wantGzip)
+			}
+			body := rec.Body.String()
+			if gotGzip {
+				if rec.Body.Len() >= len(tc.body) {
+					t.Errorf("compressed %d bytes to %d", len(tc.body), rec.Body.Len())
+				}
+				zr, err := gzip.NewReader(rec.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				b, err := io.ReadAll(zr)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body = string(b)
+			}
+			if body != tc.body {
+				t.Errorf("body differs: got %d bytes, want %d", len(body), len(tc.body))
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/loppo-llc/kojo/internal/auth"
+	"github.com/loppo-llc/kojo/internal/logging"
+)
+
+// logLevelsResponse is the body of both log-levels endpoints: the
+// global level, each component's effective level, and which of those
+// are overrides rather than the global level.
+func (s *Server) logLevelsResponse() map[string]any {
+	levels := map[string]string{}
+	for _, c := range logging.Components {
+		levels[c] = levelName(s.logLevels.Level(c))
+	}
+	overrides := map[string]string{}
+	for c, lvl := range s.logLevels.Overrides() {
+		overrides[c] = levelName(lvl)
+	}
+	return map[string]any{
+		"default":    levelName(s.logLevels.Default()),
+		"components": levels,
+		"overrides":  overrides,
+	}
+}
+
+func levelName(l slog.Level) string { return strings.ToLower(l.String()) }
+
+// handleGetLogLevels GET /api/v1/admin/log-levels
+//
+// Owner only.
+func (s *Server) handleGetLogLevels(w http.ResponseWriter, r *http.Request) {
+	if !auth.FromContext(r.Context()).IsOwner() {
+		writeError(w, http.StatusForbidden, "forbidden", "log levels require Owner")
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, s.logLevelsResponse())
+}
+
+// handleSetLogLevels PUT /api/v1/admin/log-levels
+//
+// Owner only. Body: {"session":"debug","tsnet":"warn","tmux":""}; an
+// empty level returns the component to the global one, and components
+// not named are left alone. Changes last until the next restart or
+// config reload.
+func (s *Server) handleSetLogLevels(w http.ResponseWriter, r *http.Request) {
+	if !auth.FromContext(r.Context()).IsOwner() {
+		writeError(w, http.StatusForbidden, "forbidden", "log levels require Owner")
+		return
+	}
+	var req map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid request body")
+		return
+	}
+	// Check everything before changing anything.
+	set := map[string]*slog.Level{}
+	for c, name := range req {
+		if !slices.Contains(logging.Components, c) {
+			writeError(w, http.StatusBadRequest, "bad_request", "unknown log component: "+c)
+			return
+		}
+		if name == "" {
+			set[c] = nil
+			continue
+		}
+		var lvl slog.Level
+		if err := lvl.UnmarshalText([]byte(name)); err != nil {
+			writeError(w, http.StatusBadRequest, "bad_request", "invalid level for "+c+": "+name)
+			return
+		}
+		set[c] = &lvl
+	}
+	for c, lvl := range set {
+		_ = s.logLevels.Set(c, lvl)
+	}
+	s.logger.Info("log levels changed", "levels", req)
+	writeJSONResponse(w, http.StatusOK, s.logLevelsResponse())
+}
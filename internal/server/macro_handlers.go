@@ -0,0 +1,132 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/loppo-llc/kojo/internal/macro"
+	"github.com/loppo-llc/kojo/internal/session"
+)
+
+// writeMacroError maps a macro.Store error to its response.
+func writeMacroError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, macro.ErrNotFound):
+		writeError(w, http.StatusNotFound, "not_found", err.Error())
+	case errors.Is(err, macro.ErrInvalid):
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+	}
+}
+
+// handleListMacros GET /api/v1/macros
+//
+// Returns the macros and the key names their steps may use ("keys",
+// plus Ctrl-A to Ctrl-Z).
+func (s *Server) handleListMacros(w http.ResponseWriter, r *http.Request) {
+	writeJSONResponse(w, http.StatusOK, map[string]any{
+		"macros": s.macros.List(),
+		"keys":   macro.Keys(),
+	})
+}
+
+// handleCreateMacro POST /api/v1/macros
+//
+// Body: {"name":"compact","steps":[{"key":"Escape"},{"key":"Escape"},
+// {"delayMs":200},{"text":"/compact"},{"key":"Enter"}]}.
+func (s *Server) handleCreateMacro(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name  string       `json:"name"`
+		Steps []macro.Step `json:"steps"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid request body")
+		return
+	}
+	m, err := s.macros.Create(req.Name, req.Steps)
+	if err != nil {
+		writeMacroError(w, err)
+		return
+	}
+	writeJSONResponse(w, http.StatusCreated, m)
+}
+
+// handleUpdateMacro PATCH /api/v1/macros/{id}
+//
+// Body: {"name":...} and/or {"steps":[...]}.
+func (s *Server) handleUpdateMacro(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name  *string      `json:"name"`
+		Steps []macro.Step `json:"steps"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid request body")
+		return
+	}
+	m, err := s.macros.Update(r.PathValue("id"), req.Name, req.Steps)
+	if err != nil {
+		writeMacroError(w, err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, m)
+}
+
+// handleDeleteMacro DELETE /api/v1/macros/{id}
+func (s *Server) handleDeleteMacro(w http.ResponseWriter, r *http.Request) {
+	if err := s.macros.Delete(r.PathValue("id")); err != nil {
+		writeMacroError(w, err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+// handleRunMacro POST /api/v1/sessions/{id}/macro
+//
+// Body: {"macro":"mc_...","pin":"..."}. Plays the macro into the
+// session and returns once it has been sent. The PIN is needed when
+// the session's input is locked. The "macro" WebSocket message does
+// the same from an open terminal.
+func (s *Server) handleRunMacro(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	sess, ok := s.sessions.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "session not found: "+id)
+		return
+	}
+	var req struct {
+		Macro string `json:"macro"`
+		PIN   string `json:"pin"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid request body")
+		return
+	}
+	m, err := s.macros.Get(req.Macro)
+	if err != nil {
+		writeMacroError(w, err)
+		return
+	}
+	if err := sess.CheckInputPIN(req.PIN); err != nil && !errors.Is(err, session.ErrNotInputLocked) {
+		writeSessionError(w, err, http.StatusInternalServerError)
+		return
+	}
+	if sess.Info().Status != session.StatusRunning {
+		writeError(w, http.StatusConflict, "conflict", "session not running: "+id)
+		return
+	}
+	if err := s.playMacro(sess, m); err != nil {
+		writeError(w, http.StatusConflict, "conflict", err.Error())
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+// playMacro writes m's keys to sess.
+func (s *Server) playMacro(sess *session.Session, m macro.Macro) error {
+	return macro.Play(m, func(b []byte) error {
+		_, err := sess.Write(b)
+		return err
+	})
+}
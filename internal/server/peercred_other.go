@@ -0,0 +1,14 @@
+//go:build !linux && !darwin
+
+package server
+
+import (
+	"errors"
+	"net"
+)
+
+// peerUID can't tell who is connected here, so the control socket
+// refuses every connection.
+func peerUID(*net.UnixConn) (int, error) {
+	return 0, errors.New("peer credentials not supported on this platform")
+}
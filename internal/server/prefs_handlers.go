@@ -0,0 +1,66 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/loppo-llc/kojo/internal/auth"
+	"github.com/loppo-llc/kojo/internal/prefs"
+	"github.com/loppo-llc/kojo/internal/session"
+)
+
+// prefsUser is the key r's principal's preferences are kept under,
+// "" for a principal without any (guests and peers).
+func prefsUser(r *http.Request) string {
+	p := auth.FromContext(r.Context())
+	switch {
+	case p.IsOwner():
+		return "owner"
+	case p.IsAgent() && p.AgentID != "":
+		return "agent:" + p.AgentID
+	}
+	return ""
+}
+
+// handleGetPrefs GET /api/v1/preferences
+func (s *Server) handleGetPrefs(w http.ResponseWriter, r *http.Request) {
+	user := prefsUser(r)
+	if user == "" {
+		writeError(w, http.StatusForbidden, "forbidden", "no preferences for this caller")
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, s.prefs.Get(user))
+}
+
+// handleUpdatePrefs PATCH /api/v1/preferences
+//
+// Body: any of {"theme","fontSize","defaultTool","defaultWorkDir",
+// "terminalBell"}. Omitted fields are kept; "" or 0 unsets one.
+// Returns the preferences as saved.
+func (s *Server) handleUpdatePrefs(w http.ResponseWriter, r *http.Request) {
+	user := prefsUser(r)
+	if user == "" {
+		writeError(w, http.StatusForbidden, "forbidden", "no preferences for this caller")
+		return
+	}
+	var req prefs.Patch
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid request body")
+		return
+	}
+	if req.DefaultTool != nil && *req.DefaultTool != "" && !session.IsUserTool(*req.DefaultTool) {
+		writeError(w, http.StatusBadRequest, "bad_request", "unknown defaultTool: "+*req.DefaultTool)
+		return
+	}
+	p, err := s.prefs.Update(user, req)
+	if err != nil {
+		if errors.Is(err, prefs.ErrInvalid) {
+			writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, p)
+}
@@ -2,9 +2,12 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	webpush "github.com/SherClockHolmes/webpush-go"
+
+	"github.com/loppo-llc/kojo/internal/notify"
 )
 
 // --- Web Push Handlers ---
@@ -24,12 +27,34 @@ func (s *Server) handlePushSubscribe(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusServiceUnavailable, "unavailable", "push notifications not configured")
 		return
 	}
-	var sub webpush.Subscription
-	if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+	var req struct {
+		webpush.Subscription
+		Label       string              `json:"label"`
+		Preferences *notify.Preferences `json:"preferences"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(w, http.StatusBadRequest, "bad_request", "invalid subscription")
 		return
 	}
-	s.notify.Subscribe(&sub)
+	if req.Preferences != nil {
+		if err := req.Preferences.Validate(); err != nil {
+			writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+			return
+		}
+	}
+	s.notify.Subscribe(&req.Subscription)
+	if req.Label != "" {
+		if err := s.notify.SetLabel(req.Endpoint, req.Label); err != nil {
+			writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+	}
+	if req.Preferences != nil {
+		if err := s.notify.SetSubscriptionPreferences(req.Endpoint, *req.Preferences); err != nil {
+			writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+	}
 	writeJSONResponse(w, http.StatusOK, map[string]bool{"ok": true})
 }
 
@@ -48,3 +73,138 @@ func (s *Server) handlePushUnsubscribe(w http.ResponseWriter, r *http.Request) {
 	s.notify.Unsubscribe(req.Endpoint)
 	writeJSONResponse(w, http.StatusOK, map[string]bool{"ok": true})
 }
+
+// handleGetPushPreferences GET /api/v1/push/preferences
+//
+// Returns the global notification preferences, or with ?endpoint= the
+// preferences of that subscription. See notify.Preferences; the event
+// kinds are listed in "events".
+func (s *Server) handleGetPushPreferences(w http.ResponseWriter, r *http.Request) {
+	if s.notify == nil {
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "push notifications not configured")
+		return
+	}
+	prefs := s.notify.GlobalPreferences()
+	if endpoint := r.URL.Query().Get("endpoint"); endpoint != "" {
+		var err error
+		if prefs, err = s.notify.SubscriptionPreferences(endpoint); err != nil {
+			writeError(w, http.StatusNotFound, "not_found", err.Error())
+			return
+		}
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]any{
+		"preferences": prefs,
+		"events":      notify.EventKinds,
+	})
+}
+
+// handlePutPushPreferences PUT /api/v1/push/preferences
+//
+// Body: {"preferences":{...}} replaces the global preferences;
+// {"endpoint":"...","preferences":{...}} those of one subscription.
+// A notification has to pass both.
+func (s *Server) handlePutPushPreferences(w http.ResponseWriter, r *http.Request) {
+	if s.notify == nil {
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "push notifications not configured")
+		return
+	}
+	var req struct {
+		Endpoint    string             `json:"endpoint"`
+		Preferences notify.Preferences `json:"preferences"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid request")
+		return
+	}
+	if err := req.Preferences.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+	var err error
+	if req.Endpoint != "" {
+		err = s.notify.SetSubscriptionPreferences(req.Endpoint, req.Preferences)
+	} else {
+		err = s.notify.SetGlobalPreferences(req.Preferences)
+	}
+	switch {
+	case errors.Is(err, notify.ErrUnknownSubscription):
+		writeError(w, http.StatusNotFound, "not_found", err.Error())
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+	default:
+		writeJSONResponse(w, http.StatusOK, map[string]bool{"ok": true})
+	}
+}
+
+// handleListPushSubscriptions GET /api/v1/push/subscriptions
+//
+// Lists the push subscriptions with their device labels, preferences
+// and the outcome of the latest delivery to each.
+func (s *Server) handleListPushSubscriptions(w http.ResponseWriter, r *http.Request) {
+	if s.notify == nil {
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "push notifications not configured")
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]any{"subscriptions": s.notify.Subscriptions()})
+}
+
+// handlePatchPushSubscription PATCH /api/v1/push/subscriptions
+//
+// Body: {"endpoint":"...","label":"iPhone"}. Names the device behind a
+// subscription; an empty label clears it.
+func (s *Server) handlePatchPushSubscription(w http.ResponseWriter, r *http.Request) {
+	if s.notify == nil {
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "push notifications not configured")
+		return
+	}
+	var req struct {
+		Endpoint string `json:"endpoint"`
+		Label    string `json:"label"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Endpoint == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "endpoint is required")
+		return
+	}
+	if err := s.notify.SetLabel(req.Endpoint, req.Label); err != nil {
+		writeError(w, http.StatusNotFound, "not_found", err.Error())
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+// handlePushTest POST /api/v1/push/test
+//
+// Body: {"endpoint":"..."}. Sends a test notification to that
+// subscription right away, bypassing preferences and quiet hours, and
+// reports the push service's answer: "status" is its HTTP status and
+// "ok" whether it accepted the message. 502 when the push service
+// couldn't be reached; a subscription it reports expired is removed.
+func (s *Server) handlePushTest(w http.ResponseWriter, r *http.Request) {
+	if s.notify == nil {
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "push notifications not configured")
+		return
+	}
+	var req struct {
+		Endpoint string `json:"endpoint"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Endpoint == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "endpoint is required")
+		return
+	}
+	payload, _ := json.Marshal(map[string]any{
+		"type": "test",
+		"body": "Test notification from kojo",
+	})
+	status, err := s.notify.SendTo(req.Endpoint, payload)
+	switch {
+	case errors.Is(err, notify.ErrUnknownSubscription):
+		writeError(w, http.StatusNotFound, "not_found", err.Error())
+	case err != nil:
+		writeError(w, http.StatusBadGateway, "push_failed", err.Error())
+	default:
+		writeJSONResponse(w, http.StatusOK, map[string]any{
+			"ok":     status < 400,
+			"status": status,
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/loppo-llc/kojo/internal/qrcode"
+)
+
+// qrScale is the PNG's pixels per QR module; a URL-sized code comes
+// out around 300px square.
+const qrScale = 8
+
+// handleAccessQR GET /api/v1/qr
+//
+// Returns a PNG QR code of this kojo's tailnet URL, the one printed at
+// startup, for pairing a new phone from a device already signed in.
+// 404 outside tsnet mode or before tailscale has reported the name.
+func (s *Server) handleAccessQR(w http.ResponseWriter, r *http.Request) {
+	u := s.currentAccessURL()
+	if u == "" {
+		writeError(w, http.StatusNotFound, "not_found", "no tailnet URL known")
+		return
+	}
+	img, err := qrcode.PNG(u, qrScale)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	_, _ = w.Write(img)
+}
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/loppo-llc/kojo/internal/auth"
+	"golang.org/x/time/rate"
+)
+
+// RateLimits caps expensive requests per client, where a client is a
+// principal at a remote address: every agent on the loopback listener
+// is its own client, and so is each tailnet device. Rates are requests
+// per minute with bursts of a quarter of that; 0 disables a limit.
+type RateLimits struct {
+	SessionCreate       int
+	GitExec             int
+	Upload              int
+	WebSocketsPerClient int
+}
+
+// limitClass names a rate-limited route group.
+type limitClass string
+
+const (
+	limitSessionCreate limitClass = "session create"
+	limitGitExec       limitClass = "git exec"
+	limitUpload        limitClass = "upload"
+)
+
+// limiterIdle is how long an unused per-client bucket is kept. After
+// that the client starts again with a full burst.
+const limiterIdle = 10 * time.Minute
+
+type limiterKey struct {
+	class  limitClass
+	client string
+}
+
+type limiterEntry struct {
+	lim  *rate.Limiter
+	used time.Time
+}
+
+// rateLimiter holds the per-client token buckets and WebSocket counts.
+type rateLimiter struct {
+	mu      sync.Mutex
+	limits  RateLimits
+	buckets map[limiterKey]*limiterEntry
+	ws      map[string]int
+	swept   time.Time
+}
+
+func newRateLimiter(l RateLimits) *rateLimiter {
+	return &rateLimiter{limits: l, buckets: map[limiterKey]*limiterEntry{}, ws: map[string]int{}}
+}
+
+// set swaps in reloaded limits. Buckets restart full; open WebSockets
+// stay counted.
+func (rl *rateLimiter) set(l RateLimits) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if l != rl.limits {
+		rl.limits = l
+		clear(rl.buckets)
+	}
+}
+
+// allow takes a token from client's bucket for class, or reports how
+// long until one is available.
+func (rl *rateLimiter) allow(class limitClass, client string, now time.Time) (bool, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	var perMin int
+	switch class {
+	case limitSessionCreate:
+		perMin = rl.limits.SessionCreate
+	case limitGitExec:
+		perMin = rl.limits.GitExec
+	case limitUpload:
+		perMin = rl.limits.Upload
+	}
+	if perMin <= 0 {
+		return true, 0
+	}
+	if now.Sub(rl.swept) > limiterIdle {
+		for k, e := range rl.buckets {
+			if now.Sub(e.used) > limiterIdle {
+				delete(rl.buckets, k)
+			}
+		}
+		rl.swept = now
+	}
+	key := limiterKey{class, client}
+	e := rl.buckets[key]
+	if e == nil {
+		e = &limiterEntry{lim: rate.NewLimiter(rate.Limit(float64(perMin)/60), max(1, perMin/4))}
+		rl.buckets[key] = e
+	}
+	e.used = now
+	r := e.lim.ReserveN(now, 1)
+	if d := r.DelayFrom(now); d > 0 {
+		r.CancelAt(now)
+		return false, d
+	}
+	return true, 0
+}
+
+// acquireWS counts a new WebSocket for client, refusing it at the cap.
+// The caller must releaseWS after an accepted connection closes.
+func (rl *rateLimiter) acquireWS(client string) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if n := rl.limits.WebSocketsPerClient; n > 0 && rl.ws[client] >= n {
+		return false
+	}
+	rl.ws[client]++
+	return true
+}
+
+func (rl *rateLimiter) releaseWS(client string) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if rl.ws[client]--; rl.ws[client] <= 0 {
+		delete(rl.ws, client)
+	}
+}
+
+// limitClassOf maps a request onto the rate-limited route groups.
+func limitClassOf(r *http.Request) (limitClass, bool) {
+	switch {
+	case createsSession(r):
+		return limitSessionCreate, true
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v1/git/exec":
+		return limitGitExec, true
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v1/upload":
+		return limitUpload, true
+	}
+	return "", false
+}
+
+// clientKey identifies the caller for rate limiting.
+func clientKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return principalLabel(auth.FromContext(r.Context())) + "@" + host
+}
+
+// rateLimitMiddleware applies s.limits. It sits just inside
+// EnforceMiddleware so requests refused by policy don't spend a token,
+// and answers 429 with Retry-After.
+func (s *Server) rateLimitMiddleware(next http.Handler) http.Handler {
+	if s.limits == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if class, ok := limitClassOf(r); ok {
+			if ok, wait := s.limits.allow(class, clientKey(r), time.Now()); !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+				writeError(w, http.StatusTooManyRequests, "rate_limited",
+					"too many "+string(class)+" requests; retry later")
+				return
+			}
+		}
+		if isWebSocketUpgrade(r) {
+			client := clientKey(r)
+			if !s.limits.acquireWS(client) {
+				writeError(w, http.StatusTooManyRequests, "too_many_connections",
+					"too many open WebSocket connections from this client")
+				return
+			}
+			defer s.limits.releaseWS(client)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/loppo-llc/kojo/internal/auth"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	rl := newRateLimiter(RateLimits{SessionCreate: 8})
+	now := time.Now()
+	for i := range 2 { // burst is a quarter of the per-minute rate
+		if ok, _ := rl.allow(limitSessionCreate, "owner@a", now); !ok {
+			t.Fatalf("request %d refused within the burst", i)
+		}
+	}
+	ok, wait := rl.allow(limitSessionCreate, "owner@a", now)
+	if ok || wait <= 0 || wait > 8*time.Second {
+		t.Fatalf("third request: ok=%v wait=%v, want refused for ~7.5s", ok, wait)
+	}
+	if ok, _ := rl.allow(limitSessionCreate, "owner@b", now); !ok {
+		t.Error("another client shares the bucket")
+	}
+	if ok, _ := rl.allow(limitSessionCreate, "owner@a", now.Add(wait)); !ok {
+		t.Error("refused after waiting the reported delay")
+	}
+	if ok, _ := rl.allow(limitGitExec, "owner@a", now); !ok {
+		t.Error("a 0 rate should not limit")
+	}
+	rl.set(RateLimits{})
+	if ok, _ := rl.allow(limitSessionCreate, "owner@a", now); !ok {
+		t.Error("limit still applied after reload to 0")
+	}
+}
+
+func TestRateLimitMiddlewareWebSocketCap(t *testing.T) {
+	srv := &Server{limits: newRateLimiter(RateLimits{WebSocketsPerClient: 1})}
+	entered, release := make(chan struct{}), make(chan struct{})
+	h := srv.rateLimitMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+	}))
+	upgrade := func(agentID string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/ws", nil)
+		r.Header.Set("Upgrade", "websocket")
+		return authedRequest(r, auth.Principal{Role: auth.RoleAgent, AgentID: agentID})
+	}
+
+	done := make(chan struct{})
+	go func() {
+		h.ServeHTTP(httptest.NewRecorder(), upgrade("ag_a"))
+		close(done)
+	}()
+	<-entered
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, upgrade("ag_a"))
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("second WebSocket: status = %d, want 429", rr.Code)
+	}
+	go h.ServeHTTP(httptest.NewRecorder(), upgrade("ag_b"))
+	<-entered // a different agent has its own allowance
+	release <- struct{}{}
+	release <- struct{}{}
+	<-done
+
+	go h.ServeHTTP(httptest.NewRecorder(), upgrade("ag_a"))
+	select {
+	case <-entered:
+		release <- struct{}{}
+	case <-time.After(time.Second):
+		t.Fatal("WebSocket refused after the first one closed")
+	}
+}
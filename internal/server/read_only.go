@@ -0,0 +1,42 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/loppo-llc/kojo/internal/auth"
+)
+
+// Read-only mode (--read-only) serves kojo for watching: sessions,
+// agents, files and git can be viewed and notifications still arrive,
+// but nothing can be started, typed into or changed. It covers the
+// people looking at the UI (Owner and guests); agents and peers keep
+// their own API access so the agents being watched keep running.
+
+// readOnlyFor reports whether r's caller is limited by read-only mode.
+func (s *Server) readOnlyFor(r *http.Request) bool {
+	if !s.readOnly {
+		return false
+	}
+	p := auth.FromContext(r.Context())
+	return !p.IsAgent() && !p.IsPeer()
+}
+
+// readOnlyMiddleware refuses every write from a read-only caller
+// except push subscription and preference changes, which only affect
+// what notifications they get. WebSocket input is dropped in the
+// socket handlers.
+func (s *Server) readOnlyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		if s.readOnlyFor(r) && !strings.HasPrefix(r.URL.Path, "/api/v1/push/") {
+			writeError(w, http.StatusForbidden, "read_only", "server is in read-only mode")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/loppo-llc/kojo/internal/auth"
+)
+
+func TestReadOnlyMiddleware(t *testing.T) {
+	srv := &Server{readOnly: true}
+	h := srv.readOnlyMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	owner := auth.Principal{Role: auth.RoleOwner}
+	agent := auth.Principal{Role: auth.RoleAgent, AgentID: "ag_1"}
+	for _, c := range []struct {
+		method, path string
+		p            auth.Principal
+		want         int
+	}{
+		{http.MethodGet, "/api/v1/sessions", owner, http.StatusNoContent},
+		{http.MethodPost, "/api/v1/sessions", owner, http.StatusForbidden},
+		{http.MethodDelete, "/api/v1/sessions/s1", owner, http.StatusForbidden},
+		{http.MethodPut, "/api/v1/files", owner, http.StatusForbidden},
+		{http.MethodPost, "/api/v1/push/subscribe", owner, http.StatusNoContent},
+		{http.MethodPost, "/api/v1/agents/ag_1/memory", agent, http.StatusNoContent},
+	} {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, authedRequest(httptest.NewRequest(c.method, c.path, nil), c.p))
+		if rr.Code != c.want {
+			t.Errorf("%s %s as %v: status = %d, want %d", c.method, c.path, c.p.Role, rr.Code, c.want)
+		}
+	}
+
+	srv.readOnly = false
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, authedRequest(httptest.NewRequest(http.MethodPost, "/api/v1/sessions", nil), owner))
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("writable server: status = %d", rr.Code)
+	}
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/loppo-llc/kojo/internal/notify"
+	"github.com/loppo-llc/kojo/internal/schedule"
+	"github.com/loppo-llc/kojo/internal/session"
+)
+
+// writeScheduleError maps a schedule.Store error to its response.
+func writeScheduleError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, schedule.ErrNotFound):
+		writeError(w, http.StatusNotFound, "not_found", err.Error())
+	case errors.Is(err, schedule.ErrInvalid):
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+	}
+}
+
+// scheduleView is a schedule as the API shows it, with its next run.
+type scheduleView struct {
+	schedule.Entry
+	NextRun time.Time `json:"nextRun,omitzero"`
+}
+
+func viewSchedule(e schedule.Entry) scheduleView {
+	return scheduleView{Entry: e, NextRun: e.Next(time.Now())}
+}
+
+// runSchedule is the scheduler's Runner: it starts e's session unless
+// its previous run is still going, and sends an error notification
+// when it can't. The session's own exit notification reports the
+// result.
+func (s *Server) runSchedule(e schedule.Entry) (string, error) {
+	var err error
+	if prev, ok := s.sessions.Get(e.LastSessionID); ok && prev.Info().Status != session.StatusExited {
+		err = fmt.Errorf("previous run %s is still running", e.LastSessionID)
+	} else if _, on := s.draining(); on {
+		err = errors.New("server is draining")
+	} else {
+		var sess *session.Session
+		sess, err = s.sessions.CreateWithOptions(e.Tool, e.WorkDir, slices.Clone(e.Args), e.YoloMode, "", session.CreateOptions{})
+		if err == nil {
+			return sess.ID, nil
+		}
+	}
+	if s.notify != nil {
+		payload, _ := json.Marshal(map[string]any{
+			"type":       "schedule_failed",
+			"scheduleId": e.ID,
+			"schedule":   truncateUTF8(e.Name, 80),
+			"tool":       e.Tool,
+			"error":      truncateUTF8(err.Error(), 200),
+		})
+		s.notify.Send(notify.Event{Kind: notify.EventError, Tool: e.Tool, Source: e.ID}, payload)
+	}
+	return "", err
+}
+
+// handleListSchedules GET /api/v1/schedules
+func (s *Server) handleListSchedules(w http.ResponseWriter, r *http.Request) {
+	entries := s.schedules.List()
+	views := make([]scheduleView, len(entries))
+	for i, e := range entries {
+		views[i] = viewSchedule(e)
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]any{"schedules": views})
+}
+
+// handleCreateSchedule POST /api/v1/schedules
+//
+// Body: {"name":"triage","cron":"0 3 * * *","tool":"codex",
+// "workDir":"/path/to/repo","args":["..."],"yoloMode":true,
+// "enabled":true}. cron is the standard 5-field form in the server's
+// local time.
+func (s *Server) handleCreateSchedule(w http.ResponseWriter, r *http.Request) {
+	var req schedule.Spec
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid request body")
+		return
+	}
+	e, err := s.schedules.Create(req)
+	if err != nil {
+		writeScheduleError(w, err)
+		return
+	}
+	writeJSONResponse(w, http.StatusCreated, viewSchedule(e))
+}
+
+// handleUpdateSchedule PATCH /api/v1/schedules/{id}
+//
+// Body: any of the fields POST takes; omitted ones are kept.
+func (s *Server) handleUpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name     *string   `json:"name"`
+		Cron     *string   `json:"cron"`
+		Tool     *string   `json:"tool"`
+		WorkDir  *string   `json:"workDir"`
+		Args     *[]string `json:"args"`
+		YoloMode *bool     `json:"yoloMode"`
+		Enabled  *bool     `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid request body")
+		return
+	}
+	e, err := s.schedules.Update(r.PathValue("id"), func(sp *schedule.Spec) {
+		setIf(&sp.Name, req.Name)
+		setIf(&sp.Cron, req.Cron)
+		setIf(&sp.Tool, req.Tool)
+		setIf(&sp.WorkDir, req.WorkDir)
+		setIf(&sp.Args, req.Args)
+		setIf(&sp.YoloMode, req.YoloMode)
+		setIf(&sp.Enabled, req.Enabled)
+	})
+	if err != nil {
+		writeScheduleError(w, err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, viewSchedule(e))
+}
+
+// setIf sets *dst to *v when v is not nil.
+func setIf[T any](dst *T, v *T) {
+	if v != nil {
+		*dst = *v
+	}
+}
+
+// handleDeleteSchedule DELETE /api/v1/schedules/{id}
+func (s *Server) handleDeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	if err := s.schedules.Delete(r.PathValue("id")); err != nil {
+		writeScheduleError(w, err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+// handleRunSchedule POST /api/v1/schedules/{id}/run
+//
+// Fires the schedule now, enabled or not, and returns it with the
+// outcome: lastSessionId on success, lastError otherwise.
+func (s *Server) handleRunSchedule(w http.ResponseWriter, r *http.Request) {
+	e, err := s.scheduler.RunNow(r.PathValue("id"))
+	if err != nil {
+		writeScheduleError(w, err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, viewSchedule(e))
+}
@@ -13,7 +13,6 @@ import (
 	"mime"
 	"net"
 	"net/http"
-	"net/http/httputil"
 	"net/url"
 	"os"
 	"strings"
@@ -24,14 +23,20 @@ import (
 	"github.com/loppo-llc/kojo/internal/agent"
 	"github.com/loppo-llc/kojo/internal/auth"
 	"github.com/loppo-llc/kojo/internal/blob"
+	"github.com/loppo-llc/kojo/internal/dirdefaults"
 	"github.com/loppo-llc/kojo/internal/eventbus"
 	"github.com/loppo-llc/kojo/internal/filebrowser"
 	gitpkg "github.com/loppo-llc/kojo/internal/git"
+	"github.com/loppo-llc/kojo/internal/logging"
+	"github.com/loppo-llc/kojo/internal/macro"
 	"github.com/loppo-llc/kojo/internal/notify"
 	"github.com/loppo-llc/kojo/internal/peer"
+	"github.com/loppo-llc/kojo/internal/prefs"
+	"github.com/loppo-llc/kojo/internal/schedule"
 	"github.com/loppo-llc/kojo/internal/selfupdate"
 	"github.com/loppo-llc/kojo/internal/session"
 	"github.com/loppo-llc/kojo/internal/slackbot"
+	"github.com/loppo-llc/kojo/internal/snippet"
 	"github.com/loppo-llc/kojo/internal/store"
 	"github.com/loppo-llc/kojo/internal/store/secretcrypto"
 	"github.com/loppo-llc/kojo/internal/thumbnail"
@@ -94,7 +99,15 @@ type Server struct {
 	slackHub        *slackbot.Hub
 	files           *filebrowser.Browser
 	git             *gitpkg.Manager
+	gitAudit        *gitExecAudit
 	notify          *notify.Manager
+	snippets        *snippet.Store     // nil disables /api/v1/snippets
+	macros          *macro.Store       // nil disables /api/v1/macros
+	prefs           *prefs.Store       // nil disables /api/v1/preferences
+	dirDefaults     *dirdefaults.Store // nil disables /api/v1/dir-defaults
+	schedules       *schedule.Store
+	scheduler       *schedule.Scheduler // nil disables /api/v1/schedules
+	logLevels       *logging.Levels
 	blob            *blob.Store    // native blob API (Phase 3); nil disables /api/v1/blob/...
 	blobMaxPutBytes int64          // per-PUT body cap; 0 = defaultBlobMaxPutBytes
 	events          *eventbus.Bus  // invalidation broadcast (Phase 4); nil disables /api/v1/events
@@ -223,8 +236,13 @@ type Server struct {
 	httpSrv        *http.Server // public (Owner-trusted) listener
 	authSrv        *http.Server // agent-facing auth-required listener (lazy, loopback)
 	authTsnetSrv   *http.Server // peer-mode primary listener (lazy, tsnet+auth+tailnet identity)
+	unixSrv        *http.Server // local control socket (lazy, Owner by filesystem permission)
 	authMu         sync.Mutex
 	devMode        bool
+	basePath       string
+	accessLog      *slog.Logger // nil unless Config.AccessLog is set
+	auditLog       *appendFile  // nil unless Config.AuditLog is set
+	limits         *rateLimiter
 	version        string
 	idempSweepOnce sync.Once // guards StartIdempotencySweep
 	// nodeKeyResolver maps an HTTP request's RemoteAddr to the
@@ -239,10 +257,24 @@ type Server struct {
 	// reach == Owner"). Empty disables the promotion. Settable via
 	// SetSelfNodeKey for the same reason as nodeKeyResolver.
 	selfNodeKey string
+	// tailscaleName is this machine's MagicDNS name, shown by
+	// /api/v1/info. Set via SetTailscaleName once tailscale reports
+	// it; empty until then or without tailscale.
+	tailscaleName string
+	// accessURL is the https URL phones open to reach this kojo,
+	// served as a QR code by GET /api/v1/qr. Set via SetAccessURL in
+	// tsnet mode; empty otherwise.
+	accessURL string
+	// listenPort is the port the main listener got, which may differ
+	// from --port after a fallback; reported by /api/v1/info. 0 until
+	// SetListenPort.
+	listenPort atomic.Int32
 	// unsafePeer collapses the tsnet identity check. Every caller
 	// becomes RolePeer (on a peer daemon) or RoleOwner (on the Hub
 	// when the listener is the public one). Wired from --unsafe.
 	unsafePeer bool
+	// startedAt is when New ran, for the uptime in /api/v1/info.
+	startedAt time.Time
 	// identityMu guards nodeKeyResolver + selfNodeKey, both of
 	// which can be (re)wired after server construction.
 	identityMu sync.RWMutex
@@ -266,6 +298,11 @@ type Server struct {
 	// chunkedSyncSweepDone is closed on Shutdown to stop the sweeper.
 	chunkedSyncSweepDone chan struct{}
 
+	// uploads holds the upload TTL and quota; uploadSweepDone is
+	// closed on Shutdown to stop the expired-upload sweeper.
+	uploads         uploadStore
+	uploadSweepDone chan struct{}
+
 	// mirrorRefreshDone is closed on Shutdown to stop the background
 	// remote_message_mirror push-refresher (mirror_refresher.go).
 	// mirrorRefreshStopped is closed by the refresher goroutine on
@@ -275,6 +312,11 @@ type Server struct {
 	mirrorRefreshDone    chan struct{}
 	mirrorRefreshStopped chan struct{}
 
+	// viteStop stops the Vite dev server Config.SpawnVite started;
+	// viteDone is closed once it has exited. nil otherwise.
+	viteStop context.CancelFunc
+	viteDone chan struct{}
+
 	// restartTrigger, when set via SetRestartTrigger, is invoked by
 	// POST /api/v1/system/restart after every agent chat has drained.
 	// cmd/kojo wires it to "mark restart intent + cancel the signal
@@ -294,6 +336,24 @@ type Server struct {
 	// blocker-bearing error after a drain timeout. Guarded by restartMu.
 	restartLastOutcome string
 	restartLastError   string
+	// reloadTrigger, when set via SetReloadTrigger, re-reads the
+	// config for POST /api/v1/system/reload. Guarded by reloadMu.
+	// nil (tests) → the endpoint returns 501.
+	reloadTrigger func() error
+	reloadMu      sync.Mutex
+	// drainSince is when POST /api/v1/admin/drain took effect; zero
+	// when not draining. drainCh is closed at that moment so open
+	// terminal WebSockets can warn their clients. Guarded by drainMu.
+	drainSince time.Time
+	drainCh    chan struct{}
+	drainMu    sync.Mutex
+	// readOnly refuses writes and terminal input from the UI; see
+	// readOnlyMiddleware.
+	readOnly bool
+	// wsConns counts open WebSocket connections on every listener.
+	wsConns atomic.Int64
+	// wsLimits closes WebSockets past Config.WSLimits.
+	wsLimits *wsTracker
 	// repoDir is the source checkout POST /api/v1/system/rebuild runs
 	// `make build` in. Empty disables the rebuild endpoint (409).
 	// Wired from Config.RepoDir ($KOJO_REPO_DIR).
@@ -324,12 +384,43 @@ type Server struct {
 }
 
 type Config struct {
-	Addr           string
-	DevMode        bool
-	Logger         *slog.Logger
-	StaticFS       fs.FS // embedded web/dist files for production
-	Version        string
-	NotifyManager  *notify.Manager
+	Addr    string
+	DevMode bool
+	// ViteURL is the Vite dev server DevMode proxies the web UI to;
+	// empty means DefaultViteURL.
+	ViteURL string
+	// SpawnVite makes DevMode run `npm run dev` in ViteDir (default
+	// "web") itself, restarting it if it exits, so one command brings
+	// up the whole dev environment.
+	SpawnVite bool
+	ViteDir   string
+	// BasePath is the URL prefix kojo is served under behind a
+	// reverse proxy ("/kojo"), already normalized by
+	// NormalizeBasePath. Empty serves at the root.
+	BasePath string
+	// AccessLog is a JSON-lines file that gets one line per request
+	// on every listener (see accessLogMiddleware). Empty logs requests
+	// to Logger at Debug only.
+	AccessLog     string
+	Logger        *slog.Logger
+	StaticFS      fs.FS // embedded web/dist files for production
+	Version       string
+	NotifyManager *notify.Manager
+	// Snippets is the prompt snippet library; nil leaves the
+	// /api/v1/snippets routes unregistered.
+	Snippets *snippet.Store
+	// Macros are the saved key sequences; nil leaves /api/v1/macros
+	// unregistered and ignores "macro" WebSocket messages.
+	Macros *macro.Store
+	// Prefs holds the per-user Web UI preferences; nil leaves
+	// /api/v1/preferences unregistered.
+	Prefs *prefs.Store
+	// DirDefaults are the per-directory session defaults; nil leaves
+	// /api/v1/dir-defaults unregistered and applies none.
+	DirDefaults *dirdefaults.Store
+	// Schedules are the timed session starts; nil leaves
+	// /api/v1/schedules unregistered and starts nothing.
+	Schedules      *schedule.Store
 	AgentManager   *agent.Manager
 	GroupDMManager *agent.GroupDMManager
 	// BlobStore is optional; when nil, /api/v1/blob/... routes are not
@@ -425,11 +516,70 @@ type Config struct {
 	// (returns 409). cmd/kojo reads $KOJO_REPO_DIR to set this.
 	RepoDir string
 
+	// FileRoots lists extra directories the file browser may read on
+	// top of $HOME and the temp dir (e.g. a mounted work disk).
+	// cmd/kojo reads $KOJO_FILE_ROOTS (os.PathListSeparator-separated)
+	// to set this.
+	FileRoots []string
+
+	// GitExecAllow / GitExecDeny configure which commands
+	// POST /api/v1/git/exec runs; see git.Options for the rule syntax
+	// and defaults. cmd/kojo reads $KOJO_GIT_EXEC_ALLOW and
+	// $KOJO_GIT_EXEC_DENY (comma-separated) to set these.
+	GitExecAllow []string
+	GitExecDeny  []string
+	// GitAuditLog is the JSON-lines file every /git/exec call is
+	// appended to. Empty records calls in the daemon log only.
+	GitAuditLog string
+	// AuditLog is the JSON-lines file every mutating API call is
+	// appended to (see auditMiddleware) and GET /api/v1/audit reads.
+	// Empty disables both.
+	AuditLog string
+	// RateLimits caps session creation, git exec, uploads and open
+	// WebSockets per client. The zero value imposes no limits.
+	RateLimits RateLimits
+	// WSLimits bound the lifetime, idle time and number of open
+	// WebSockets. The zero value imposes no limits.
+	WSLimits WSLimits
+	// UploadTTL is how long an uploaded file is kept before the
+	// sweeper deletes it; UploadQuota caps the total bytes kept.
+	// Zero disables either.
+	UploadTTL   time.Duration
+	UploadQuota int64
+	// SessionBackend picks tmux or a direct PTY for user tool
+	// sessions on Unix; see session.ManagerOptions.Backend.
+	SessionBackend string
+	// RemoteHosts are machines sessions can be started on over SSH
+	// (POST /api/v1/sessions with "host").
+	RemoteHosts []session.RemoteHost
+	// Sandboxes and YoloSandbox feed the matching
+	// session.ManagerOptions.
+	Sandboxes   []session.Sandbox
+	YoloSandbox string
+	// NoRedact and RedactPatterns configure secret redaction of
+	// session output; see session.ManagerOptions.
+	NoRedact       bool
+	RedactPatterns []string
+	// ProtectedPaths and NoYoloPaths are where sessions can't start and
+	// where yolo mode is off; see session.ManagerOptions.
+	ProtectedPaths []string
+	NoYoloPaths    []string
+
 	// UpdateChecker is the process-wide GitHub Releases checker used by
 	// GET/POST /api/v1/system/update. Nil disables the endpoints
 	// (GET returns supported:false; POST returns 501). cmd/kojo always
 	// wires one so the API answers even when the periodic loop is off.
 	UpdateChecker *selfupdate.Checker
+	// DebugEndpoints registers /debug/pprof/ and
+	// /api/v1/debug/goroutines (Owner only). cmd/kojo sets it from
+	// --debug-endpoints.
+	DebugEndpoints bool
+	// ReadOnly serves kojo for viewing only (--read-only); see
+	// readOnlyMiddleware.
+	ReadOnly bool
+	// LogLevels, when set, supplies the server, session and tmux
+	// loggers in place of Logger and backs /api/v1/admin/log-levels.
+	LogLevels *logging.Levels
 }
 
 func New(cfg Config) *Server {
@@ -437,6 +587,12 @@ func New(cfg Config) *Server {
 	if logger == nil {
 		logger = slog.Default()
 	}
+	sessLogger, tmuxLogger := logger, logger
+	if cfg.LogLevels != nil {
+		logger = cfg.LogLevels.Logger("server")
+		sessLogger = cfg.LogLevels.Logger("session")
+		tmuxLogger = cfg.LogLevels.Logger("tmux")
+	}
 
 	// session.Manager runs on Hub AND on --peer. The §3.7 device
 	// switch hands agent runtime ownership to the target peer at
@@ -453,8 +609,19 @@ func New(cfg Config) *Server {
 	// peer wipe out the Hub's live PTYs. That configuration is
 	// unsupported (run one kojo per host); the regular cross-
 	// machine peer setup is unaffected.
-	sessMgr := session.NewManager(logger, cfg.Store, session.ManagerOptions{
+	sessMgr := session.NewManager(sessLogger, cfg.Store, session.ManagerOptions{
 		V0LegacyDir: cfg.V0LegacyDir,
+		Backend:     cfg.SessionBackend,
+		RemoteHosts: cfg.RemoteHosts,
+		Sandboxes:   cfg.Sandboxes,
+		YoloSandbox: cfg.YoloSandbox,
+
+		NoRedact:       cfg.NoRedact,
+		RedactPatterns: cfg.RedactPatterns,
+		TmuxLogger:     tmuxLogger,
+
+		ProtectedPaths: cfg.ProtectedPaths,
+		NoYoloPaths:    cfg.NoYoloPaths,
 	})
 	if baseURL := os.Getenv("CUSTOM_API_BASE_URL"); baseURL != "" {
 		sessMgr.SetCustomBaseURL(baseURL)
@@ -464,9 +631,16 @@ func New(cfg Config) *Server {
 		sessions:             sessMgr,
 		agents:               cfg.AgentManager,
 		groupdms:             cfg.GroupDMManager,
-		files:                filebrowser.New(logger),
-		git:                  gitpkg.New(),
+		files:                filebrowser.New(logger, filebrowser.Options{Roots: cfg.FileRoots}),
+		git:                  gitpkg.New(gitpkg.Options{ExecAllow: cfg.GitExecAllow, ExecDeny: cfg.GitExecDeny}),
+		gitAudit:             newGitExecAudit(cfg.GitAuditLog, logger),
 		notify:               cfg.NotifyManager,
+		snippets:             cfg.Snippets,
+		macros:               cfg.Macros,
+		prefs:                cfg.Prefs,
+		dirDefaults:          cfg.DirDefaults,
+		schedules:            cfg.Schedules,
+		logLevels:            cfg.LogLevels,
 		blob:                 cfg.BlobStore,
 		blobMaxPutBytes:      cfg.MaxBlobPutBytes,
 		events:               cfg.EventBus,
@@ -478,16 +652,35 @@ func New(cfg Config) *Server {
 		pendingSyncDB:        cfg.Store,
 		logger:               logger,
 		devMode:              cfg.DevMode,
+		basePath:             cfg.BasePath,
 		version:              cfg.Version,
 		repoDir:              cfg.RepoDir,
+		readOnly:             cfg.ReadOnly,
+		startedAt:            time.Now(),
 		updateChecker:        cfg.UpdateChecker,
 		unsafePeer:           cfg.Unsafe,
 		thumbPurgeDone:       make(chan struct{}),
 		ttsSweepDone:         make(chan struct{}),
 		chunkedAgentSyncs:    make(map[string]*chunkedSyncEntry),
 		chunkedSyncSweepDone: make(chan struct{}),
+		uploadSweepDone:      make(chan struct{}),
+		drainCh:              make(chan struct{}),
+		limits:               newRateLimiter(cfg.RateLimits),
+		wsLimits:             newWSTracker(cfg.WSLimits),
+	}
+	if cfg.AuditLog != "" {
+		s.auditLog = &appendFile{path: cfg.AuditLog}
+	}
+	if cfg.AccessLog != "" {
+		s.accessLog = slog.New(slog.NewJSONHandler(&appendFile{path: cfg.AccessLog}, nil))
+	}
+	s.uploads.set(cfg.UploadTTL, cfg.UploadQuota)
+	if s.schedules != nil && s.sessions != nil {
+		s.scheduler = schedule.NewScheduler(s.schedules, s.runSchedule, s.logger)
+		s.scheduler.Start()
 	}
 	go s.runChunkedSyncSweeper()
+	go s.runUploadSweeper()
 	// Queue-and-forward: drain anything left queued across a
 	// restart. A holder that was already online when the hub came
 	// back would otherwise never trigger delivery (its
@@ -502,6 +695,9 @@ func New(cfg Config) *Server {
 		s.mirrorRefreshStopped = make(chan struct{})
 		go s.runMirrorRefresher()
 	}
+	if cfg.DevMode && cfg.SpawnVite && !cfg.PeerOnly {
+		s.startVite(cfg)
+	}
 	// Sweep aged thumbnail-cache entries in the background. The cache is
 	// keyed by (path, mtime, size, dimensions) so an edited image
 	// produces a new entry — without periodic purge the cache would grow
@@ -544,7 +740,7 @@ func New(cfg Config) *Server {
 				"name":    truncateUTF8(ag.Name, 80),
 				"preview": truncateUTF8(msg.Content, 200),
 			})
-			s.notify.Send(payload)
+			s.notify.Send(notify.Event{Kind: notify.EventDone, Tool: ag.Tool, Source: ag.ID}, payload)
 		}
 	}
 
@@ -555,16 +751,116 @@ func New(cfg Config) *Server {
 	// to avoid a duplicate push if that ever changes.
 	if s.notify != nil && s.agents != nil && !cfg.PeerOnly {
 		s.agents.OnQuestionRaised = func(agentID string) {
-			name := agentID
+			name, tool := agentID, ""
 			if ag, ok := s.agents.Get(agentID); ok {
-				name = ag.Name
+				name, tool = ag.Name, ag.Tool
 			}
 			payload, _ := json.Marshal(map[string]any{
 				"type":    "agent_awaiting_input",
 				"agentId": agentID,
 				"name":    truncateUTF8(name, 80),
 			})
-			s.notify.Send(payload)
+			s.notify.Send(notify.Event{Kind: notify.EventNeedsInput, Tool: tool, Source: agentID}, payload)
+		}
+	}
+
+	// POST session webhooks (exit, pattern match) to CI and the like.
+	if s.sessions != nil {
+		s.sessions.OnWebhook = s.deliverWebhook
+	}
+
+	// send push notifications for session events: a bell from a session
+	// that opted in (PATCH notifyOnBell), which tools use to ask for
+	// attention and the manager rate-limits per session; exits; yolo
+	// auto-approvals; and output matching the session's patterns.
+	if s.notify != nil && s.sessions != nil {
+		s.sessions.OnSessionBell = func(sess *session.Session) {
+			info := sess.Info()
+			payload, _ := json.Marshal(map[string]any{
+				"type":      "session_bell",
+				"sessionId": info.ID,
+				"tool":      info.Tool,
+				"title":     truncateUTF8(info.Title, 120),
+				"url":       s.sessionURL(info.ID),
+			})
+			s.notify.Send(notify.Event{Kind: notify.EventNeedsInput, Tool: info.Tool, Source: info.ID}, payload)
+		}
+		// Exits are filed as "exit" or, with a non-zero code, "error";
+		// subscribers pick which they want (see notify.Preferences).
+		// The payload carries the last lines the session printed, so the
+		// notification says why without opening the terminal.
+		s.sessions.OnSessionExit = func(sess *session.Session) {
+			info := sess.Info()
+			kind := notify.EventExit
+			if info.ExitCode != nil && *info.ExitCode != 0 {
+				kind = notify.EventError
+			}
+			msg := map[string]any{
+				"type":      "session_exit",
+				"sessionId": info.ID,
+				"tool":      info.Tool,
+				"workDir":   truncateUTF8(info.WorkDir, 120),
+				"exitCode":  info.ExitCode,
+				"title":     truncateUTF8(info.Title, 120),
+				"output":    exitSnippet(sess.TailLines(exitSnippetLines)),
+				"url":       s.sessionURL(info.ID),
+			}
+			// A scheduled run's exit is its result; say which schedule.
+			if s.schedules != nil {
+				if e, ok := s.schedules.BySession(info.ID); ok {
+					msg["schedule"] = truncateUTF8(e.Name, 80)
+				}
+			}
+			payload, _ := json.Marshal(msg)
+			s.notify.Send(notify.Event{Kind: kind, Tool: info.Tool, Source: info.ID}, payload)
+		}
+		s.sessions.OnYoloApprove = func(sess *session.Session, matched string) {
+			info := sess.Info()
+			payload, _ := json.Marshal(map[string]any{
+				"type":      "session_yolo_approve",
+				"sessionId": info.ID,
+				"tool":      info.Tool,
+				"matched":   truncateUTF8(matched, 120),
+				"url":       s.sessionURL(info.ID),
+			})
+			s.notify.Send(notify.Event{Kind: notify.EventYoloApprove, Tool: info.Tool, Source: info.ID}, payload)
+		}
+		// A line matching the session's notifyPatterns (PATCH) counts
+		// as an error: the usual case is a panic or traceback the agent
+		// printed before stalling.
+		s.sessions.OnOutputMatch = func(sess *session.Session, line string) {
+			info := sess.Info()
+			payload, _ := json.Marshal(map[string]any{
+				"type":      "session_output_match",
+				"sessionId": info.ID,
+				"tool":      info.Tool,
+				"line":      truncateUTF8(line, 200),
+				"url":       s.sessionURL(info.ID),
+			})
+			s.notify.Send(notify.Event{Kind: notify.EventError, Tool: info.Tool, Source: info.ID}, payload)
+		}
+		// A session nearing its time limit or token budget needs
+		// attention while it can still be extended; one stopped at it
+		// is an error, followed by the usual exit notification.
+		s.sessions.OnLimit = func(sess *session.Session, ev session.LimitEvent) {
+			info := sess.Info()
+			kind := notify.EventNeedsInput
+			if ev.Exceeded {
+				kind = notify.EventError
+			}
+			payload, _ := json.Marshal(map[string]any{
+				"type":        "session_limit",
+				"sessionId":   info.ID,
+				"tool":        info.Tool,
+				"limit":       ev.Limit,
+				"exceeded":    ev.Exceeded,
+				"deadline":    info.Deadline,
+				"tokenBudget": info.TokenBudget,
+				"tokensUsed":  info.TokensUsed,
+				"title":       truncateUTF8(info.Title, 120),
+				"url":         s.sessionURL(info.ID),
+			})
+			s.notify.Send(notify.Event{Kind: kind, Tool: info.Tool, Source: info.ID}, payload)
 		}
 	}
 
@@ -614,13 +910,18 @@ func New(cfg Config) *Server {
 	if s.agents != nil {
 		st = s.agents.Store()
 	}
-	publicHandler := s.idempotencyMiddleware(mux)
+	publicHandler := s.readOnlyMiddleware(s.drainMiddleware(mux))
+	publicHandler = s.idempotencyMiddleware(publicHandler)
 	publicHandler = s.remoteAgentProxyMiddleware(publicHandler)
 	if s.peerID != nil && st != nil {
 		publicHandler = s.sessionPeerProxyMiddleware(publicHandler)
 	}
+	publicHandler = s.rateLimitMiddleware(publicHandler)
 	publicHandler = auth.EnforceMiddleware(publicHandler)
+	publicHandler = s.auditMiddleware(publicHandler)
+	publicHandler = accessPrincipalMiddleware(publicHandler)
 	publicHandler = apiNoStoreDefaultMiddleware(publicHandler)
+	publicHandler = gzipMiddleware(publicHandler)
 	publicHandler = auth.TailnetIdentityMiddleware(auth.TailnetIdentityConfig{
 		Resolver:        s.resolveNodeKey,
 		SelfNodeKeyFunc: s.currentSelfNodeKey,
@@ -637,6 +938,8 @@ func New(cfg Config) *Server {
 		UnsafeAsHub:                  !cfg.PeerOnly,
 		Logger:                       logger,
 	})(publicHandler)
+	publicHandler = basePathMiddleware(s.basePath, publicHandler)
+	publicHandler = s.accessLogMiddleware(publicHandler)
 	s.httpSrv = &http.Server{
 		Addr:              cfg.Addr,
 		Handler:           publicHandler,
@@ -668,21 +971,100 @@ func (s *Server) registerRoutes(mux *http.ServeMux, cfg Config) {
 
 	// Session routes
 	mux.HandleFunc("GET /api/v1/info", s.handleInfo)
+	mux.HandleFunc("GET /api/v1/error-codes", s.handleErrorCodes)
+	mux.HandleFunc("GET /api/v1/system", s.handleSystemStats)
 	mux.HandleFunc("POST /api/v1/system/restart", s.handleSystemRestart)
 	mux.HandleFunc("GET /api/v1/system/restart", s.handleSystemRestartStatus)
 	mux.HandleFunc("POST /api/v1/system/rebuild", s.handleSystemRebuild)
+	mux.HandleFunc("POST /api/v1/system/reload", s.handleSystemReload)
 	mux.HandleFunc("GET /api/v1/system/update", s.handleSystemUpdateStatus)
 	mux.HandleFunc("POST /api/v1/system/update", s.handleSystemUpdate)
+	mux.HandleFunc("GET /api/v1/audit", s.handleAuditLog)
+	mux.HandleFunc("GET /api/v1/admin/drain", s.handleDrainStatus)
+	mux.HandleFunc("POST /api/v1/admin/drain", s.handleDrainStart)
+	mux.HandleFunc("DELETE /api/v1/admin/drain", s.handleDrainCancel)
+	mux.HandleFunc("GET /api/v1/admin/ws-clients", s.handleListWSClients)
+	if s.logLevels != nil {
+		mux.HandleFunc("GET /api/v1/admin/log-levels", s.handleGetLogLevels)
+		mux.HandleFunc("PUT /api/v1/admin/log-levels", s.handleSetLogLevels)
+	}
+	if cfg.DebugEndpoints {
+		s.registerDebugRoutes(mux)
+	}
 	mux.HandleFunc("GET /api/v1/sessions", s.handleListSessions)
 	mux.HandleFunc("POST /api/v1/sessions", s.handleCreateSession)
+	mux.HandleFunc("POST /api/v1/sessions/from-issue", s.handleCreateIssueSession)
+	mux.HandleFunc("DELETE /api/v1/sessions", s.handleClearExitedSessions)
+	mux.HandleFunc("GET /api/v1/sessions/events", s.handleSessionEventsWS)
 	mux.HandleFunc("GET /api/v1/sessions/{id}", s.handleGetSession)
 	mux.HandleFunc("DELETE /api/v1/sessions/{id}", s.handleDeleteSession)
 	mux.HandleFunc("PATCH /api/v1/sessions/{id}", s.handlePatchSession)
 	mux.HandleFunc("POST /api/v1/sessions/{id}/restart", s.handleRestartSession)
+	mux.HandleFunc("POST /api/v1/sessions/{id}/clone", s.handleCloneSession)
 	mux.HandleFunc("GET /api/v1/sessions/{id}/terminal", s.handleTerminalSession)
 	mux.HandleFunc("POST /api/v1/sessions/{id}/tmux", s.handleTmuxAction)
+	mux.HandleFunc("POST /api/v1/sessions/{id}/signal", s.handleSignalSession)
+	mux.HandleFunc("GET /api/v1/sessions/{id}/actions", s.handleListSessionActions)
+	mux.HandleFunc("POST /api/v1/sessions/{id}/actions/{action}", s.handleRunSessionAction)
+	mux.HandleFunc("GET /api/v1/sessions/{id}/changes", s.handleSessionChanges)
+	mux.HandleFunc("GET /api/v1/sessions/{id}/checkpoints", s.handleListCheckpoints)
+	mux.HandleFunc("POST /api/v1/sessions/{id}/checkpoints", s.handleCreateCheckpoint)
+	mux.HandleFunc("POST /api/v1/sessions/{id}/rollback", s.handleRollbackSession)
+	mux.HandleFunc("POST /api/v1/sessions/{id}/pause", s.handlePauseSession)
+	mux.HandleFunc("POST /api/v1/sessions/{id}/resume", s.handleResumeSession)
+	mux.HandleFunc("POST /api/v1/sessions/{id}/clipboard", s.handleSessionClipboard)
+	mux.HandleFunc("POST /api/v1/sessions/{id}/lock", s.handleLockSessionInput)
+	mux.HandleFunc("DELETE /api/v1/sessions/{id}/lock", s.handleUnlockSessionInput)
 	mux.HandleFunc("GET /api/v1/sessions/{id}/attachments", s.handleListAttachments)
 	mux.HandleFunc("DELETE /api/v1/sessions/{id}/attachments", s.handleDeleteAttachment)
+	mux.HandleFunc("GET /api/v1/sessions/{id}/images/{image}", s.handleSessionImage)
+	mux.HandleFunc("GET /api/v1/sessions/{id}/export", s.handleExportSession)
+	mux.HandleFunc("GET /api/v1/sessions/{id}/webhooks", s.handleListWebhooks)
+	mux.HandleFunc("POST /api/v1/sessions/{id}/webhooks", s.handleCreateWebhook)
+	mux.HandleFunc("DELETE /api/v1/sessions/{id}/webhooks/{webhookId}", s.handleDeleteWebhook)
+
+	// Prompt snippets
+	if s.snippets != nil {
+		mux.HandleFunc("GET /api/v1/snippets", s.handleListSnippets)
+		mux.HandleFunc("POST /api/v1/snippets", s.handleCreateSnippet)
+		mux.HandleFunc("PATCH /api/v1/snippets/{id}", s.handleUpdateSnippet)
+		mux.HandleFunc("DELETE /api/v1/snippets/{id}", s.handleDeleteSnippet)
+		mux.HandleFunc("POST /api/v1/snippets/{id}/render", s.handleRenderSnippet)
+	}
+
+	// Key macros
+	if s.macros != nil {
+		mux.HandleFunc("GET /api/v1/macros", s.handleListMacros)
+		mux.HandleFunc("POST /api/v1/macros", s.handleCreateMacro)
+		mux.HandleFunc("PATCH /api/v1/macros/{id}", s.handleUpdateMacro)
+		mux.HandleFunc("DELETE /api/v1/macros/{id}", s.handleDeleteMacro)
+		mux.HandleFunc("POST /api/v1/sessions/{id}/macro", s.handleRunMacro)
+	}
+
+	mux.HandleFunc("GET /api/v1/qr", s.handleAccessQR)
+
+	// Scheduled sessions
+	if s.scheduler != nil {
+		mux.HandleFunc("GET /api/v1/schedules", s.handleListSchedules)
+		mux.HandleFunc("POST /api/v1/schedules", s.handleCreateSchedule)
+		mux.HandleFunc("PATCH /api/v1/schedules/{id}", s.handleUpdateSchedule)
+		mux.HandleFunc("DELETE /api/v1/schedules/{id}", s.handleDeleteSchedule)
+		mux.HandleFunc("POST /api/v1/schedules/{id}/run", s.handleRunSchedule)
+	}
+
+	// Web UI preferences
+	if s.prefs != nil {
+		mux.HandleFunc("GET /api/v1/preferences", s.handleGetPrefs)
+		mux.HandleFunc("PATCH /api/v1/preferences", s.handleUpdatePrefs)
+	}
+
+	// Per-directory session defaults
+	if s.dirDefaults != nil {
+		mux.HandleFunc("GET /api/v1/dir-defaults", s.handleListDirDefaults)
+		mux.HandleFunc("PUT /api/v1/dir-defaults", s.handleSetDirDefaults)
+		mux.HandleFunc("DELETE /api/v1/dir-defaults", s.handleDeleteDirDefaults)
+	}
+
 	mux.HandleFunc("GET /api/v1/ws", s.handleWebSocket)
 
 	// Directory suggestions
@@ -696,15 +1078,33 @@ func (s *Server) registerRoutes(mux *http.ServeMux, cfg Config) {
 	mux.HandleFunc("GET /api/v1/files/view", s.handleViewFile)
 	mux.HandleFunc("GET /api/v1/files/raw", s.handleRawFile)
 	mux.HandleFunc("GET /api/v1/files/thumb", s.handleThumbFile)
+	mux.HandleFunc("GET /api/v1/files/tail", s.handleTailFile)
+	mux.HandleFunc("GET /api/v1/files/watch", s.handleWatchFiles)
 
 	// File upload
 	mux.HandleFunc("POST /api/v1/upload", s.handleUpload)
+	mux.HandleFunc("GET /api/v1/uploads", s.handleListUploads)
+	mux.HandleFunc("DELETE /api/v1/uploads/{id}", s.handleDeleteUpload)
 
 	// Git
 	mux.HandleFunc("GET /api/v1/git/status", s.handleGitStatus)
+	mux.HandleFunc("GET /api/v1/git/repos", s.handleGitRepos)
+	mux.HandleFunc("GET /api/v1/git/watch", s.handleGitWatch)
 	mux.HandleFunc("GET /api/v1/git/log", s.handleGitLog)
 	mux.HandleFunc("GET /api/v1/git/diff", s.handleGitDiff)
+	mux.HandleFunc("GET /api/v1/git/show", s.handleGitShow)
+	mux.HandleFunc("GET /api/v1/git/file", s.handleGitFile)
 	mux.HandleFunc("POST /api/v1/git/exec", s.handleGitExec)
+	mux.HandleFunc("POST /api/v1/git/stage", s.handleGitPaths(s.git.Stage))
+	mux.HandleFunc("POST /api/v1/git/unstage", s.handleGitPaths(s.git.Unstage))
+	mux.HandleFunc("POST /api/v1/git/discard", s.handleGitPaths(s.git.Discard))
+	mux.HandleFunc("POST /api/v1/git/commit", s.handleGitCommit)
+	mux.HandleFunc("GET /api/v1/git/worktrees", s.handleGitWorktrees)
+	mux.HandleFunc("POST /api/v1/git/worktrees", s.handleGitAddWorktree)
+	mux.HandleFunc("DELETE /api/v1/git/worktrees", s.handleGitRemoveWorktree)
+	mux.HandleFunc("GET /api/v1/git/prs", s.handleGitPullRequests)
+	mux.HandleFunc("POST /api/v1/git/prs", s.handleGitCreatePullRequest)
+	mux.HandleFunc("GET /api/v1/git/pr-checks", s.handleGitPullRequestChecks)
 
 	// Web Push notifications
 	// kv (config / non-secret blob store). Owner-only. Secret rows
@@ -729,6 +1129,11 @@ func (s *Server) registerRoutes(mux *http.ServeMux, cfg Config) {
 	mux.HandleFunc("GET /api/v1/push/vapid", s.handleVAPIDKey)
 	mux.HandleFunc("POST /api/v1/push/subscribe", s.handlePushSubscribe)
 	mux.HandleFunc("POST /api/v1/push/unsubscribe", s.handlePushUnsubscribe)
+	mux.HandleFunc("GET /api/v1/push/preferences", s.handleGetPushPreferences)
+	mux.HandleFunc("PUT /api/v1/push/preferences", s.handlePutPushPreferences)
+	mux.HandleFunc("GET /api/v1/push/subscriptions", s.handleListPushSubscriptions)
+	mux.HandleFunc("PATCH /api/v1/push/subscriptions", s.handlePatchPushSubscription)
+	mux.HandleFunc("POST /api/v1/push/test", s.handlePushTest)
 
 	// Agent routes
 	if s.agents != nil {
@@ -879,10 +1284,15 @@ func (s *Server) registerRoutes(mux *http.ServeMux, cfg Config) {
 	// the peer's plain-HTTP listener returns 404 for non-API
 	// paths instead of 200 with an empty index.html.
 	if !cfg.PeerOnly {
+		mux.HandleFunc("GET /new", s.handleNewSessionLink)
+		mux.HandleFunc("POST /new", s.handleNewSessionLink)
 		if cfg.DevMode {
-			viteURL, _ := url.Parse("http://localhost:5173")
-			proxy := httputil.NewSingleHostReverseProxy(viteURL)
-			mux.Handle("/", proxy)
+			viteURL, err := viteTarget(cfg.ViteURL)
+			if err != nil {
+				s.logger.Warn("invalid vite url; using the default", "err", err)
+				viteURL, _ = viteTarget("")
+			}
+			mux.Handle("/", newDevProxy(viteURL, s.logger))
 		} else if cfg.StaticFS != nil {
 			s.registerStaticFiles(mux, cfg.StaticFS)
 		}
@@ -1075,31 +1485,48 @@ func (s *Server) registerAgentRoutes(mux *http.ServeMux) {
 }
 
 func (s *Server) registerStaticFiles(mux *http.ServeMux, staticFS fs.FS) {
-	fileServer := http.FileServer(http.FS(staticFS))
+	assets := newStaticAssets(staticFS)
+	// Behind a base path, index.html (the root and every SPA fallback)
+	// is served rewritten so its asset and API URLs carry the prefix.
+	var index []byte
+	var indexETag string
+	if s.basePath != "" {
+		if raw, err := fs.ReadFile(staticFS, "index.html"); err == nil {
+			index = rewriteIndexHTML(raw, s.basePath)
+			indexETag = contentETag(index)
+		}
+	}
+	serveIndex := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache")
+		if index == nil {
+			assets.serve(w, r, "index.html")
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		serveBytes(w, r, "index.html", indexETag, index)
+	}
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		path := r.URL.Path
-		if path == "/" {
-			path = "index.html"
-		} else {
-			path = strings.TrimPrefix(path, "/")
+		if path == "/" || path == "/index.html" {
+			serveIndex(w, r)
+			return
 		}
+		path = strings.TrimPrefix(path, "/")
 
-		if _, err := fs.Stat(staticFS, path); err == nil {
+		if assets.isFile(path) {
 			if strings.HasPrefix(r.URL.Path, "/assets/") {
 				w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
 			} else {
 				w.Header().Set("Cache-Control", "no-cache")
 			}
-			fileServer.ServeHTTP(w, r)
+			assets.serve(w, r, path)
 			return
 		}
 		if strings.HasPrefix(r.URL.Path, "/assets/") {
 			http.NotFound(w, r)
 			return
 		}
-		w.Header().Set("Cache-Control", "no-cache")
-		r.URL.Path = "/"
-		fileServer.ServeHTTP(w, r)
+		serveIndex(w, r)
 	})
 }
 
@@ -1160,6 +1587,9 @@ func (s *Server) buildAuthHandler(resolver *auth.Resolver) http.Handler {
 	// Auth listener middleware order (innermost first):
 	//
 	//   mux
+	//     ← drainMiddleware         (refuse new sessions and
+	//       WebSockets while draining; count open WebSockets and
+	//       hold them to WSLimits)
 	//     ← AgentFencingMiddleware  (refuse agent-runtime mutations
 	//       when agent_locks.holder_peer ≠ this peer; §3.7)
 	//     ← idempotencyMiddleware   (dedup write retries — sandwiched
@@ -1170,8 +1600,12 @@ func (s *Server) buildAuthHandler(resolver *auth.Resolver) http.Handler {
 	//       remote agents to the holder peer; proxied requests bypass
 	//       local idempotency + fencing since the target runs its own)
 	//     ← sessionPeerProxyMiddleware
+	//     ← rateLimitMiddleware     (per-client caps on session
+	//       create, git exec, upload and open WebSockets)
 	//     ← EnforceMiddleware       (route-level allowlist for
 	//       non-Owner principals)
+	//     ← auditMiddleware         (record mutating calls, refused
+	//       ones included)
 	//     ← AuthMiddleware          (sets Principal from Bearer;
 	//       skips when an earlier middleware already stamped a
 	//       non-Guest principal — that is how a tsnet-stamped
@@ -1180,7 +1614,7 @@ func (s *Server) buildAuthHandler(resolver *auth.Resolver) http.Handler {
 	// Fencing's 409 responses stamp X-Kojo-No-Idempotency-Cache so
 	// they aren't saved — a retry after the lock comes back must
 	// re-check rather than replay the stale 409.
-	handler := http.Handler(s.mux)
+	handler := s.readOnlyMiddleware(s.drainMiddleware(s.mux))
 	if s.peerID != nil && s.agents != nil && s.agents.Store() != nil {
 		handler = auth.AgentFencingMiddleware(
 			s.agents.Store(), s.peerID.DeviceID, s.logger)(handler)
@@ -1190,9 +1624,15 @@ func (s *Server) buildAuthHandler(resolver *auth.Resolver) http.Handler {
 	if s.peerID != nil && s.agents != nil && s.agents.Store() != nil {
 		handler = s.sessionPeerProxyMiddleware(handler)
 	}
+	handler = s.rateLimitMiddleware(handler)
 	handler = auth.EnforceMiddleware(handler)
+	handler = s.auditMiddleware(handler)
+	handler = accessPrincipalMiddleware(handler)
 	handler = auth.AuthMiddleware(resolver)(handler)
 	handler = apiNoStoreDefaultMiddleware(handler)
+	handler = gzipMiddleware(handler)
+	handler = basePathMiddleware(s.basePath, handler)
+	handler = s.accessLogMiddleware(handler)
 	return handler
 }
 
@@ -1222,6 +1662,34 @@ func (s *Server) ensureAuthServer(resolver *auth.Resolver) *http.Server {
 	return s.authSrv
 }
 
+// ServeUnix serves the full API on a unix domain socket. There is no
+// token check: every connection is stamped Owner by
+// OwnerOnlyMiddleware, and the socket file's permissions (0600, set by
+// the caller) and the peer credentials checked by ownerListener are
+// the auth boundary. The chain is otherwise the auth listener's, so
+// routing, idempotency and fencing behave the same.
+func (s *Server) ServeUnix(ln net.Listener, resolver *auth.Resolver) error {
+	srv := s.ensureUnixServer(resolver)
+	s.logger.Info("control socket started", "path", ln.Addr().String())
+	return srv.Serve(ownerListener{Listener: ln, logger: s.logger})
+}
+
+func (s *Server) ensureUnixServer(resolver *auth.Resolver) *http.Server {
+	s.authMu.Lock()
+	defer s.authMu.Unlock()
+	if s.unixSrv != nil {
+		return s.unixSrv
+	}
+	s.unixSrv = &http.Server{
+		Handler:           auth.OwnerOnlyMiddleware(s.buildAuthHandler(resolver)),
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       120 * time.Second,
+		MaxHeaderBytes:    1 << 20,
+		ErrorLog:          httpServerErrorLog(s.logger),
+	}
+	return s.unixSrv
+}
+
 func (s *Server) ensureAuthTsnetServer(resolver *auth.Resolver) *http.Server {
 	s.authMu.Lock()
 	defer s.authMu.Unlock()
@@ -1566,6 +2034,39 @@ func (s *Server) SetSelfNodeKey(nk string) {
 	s.selfNodeKey = nk
 }
 
+// SetTailscaleName records this machine's MagicDNS name (without the
+// trailing dot) for /api/v1/info.
+func (s *Server) SetTailscaleName(name string) {
+	s.identityMu.Lock()
+	defer s.identityMu.Unlock()
+	s.tailscaleName = name
+}
+
+func (s *Server) tailscaleDNSName() string {
+	s.identityMu.RLock()
+	defer s.identityMu.RUnlock()
+	return s.tailscaleName
+}
+
+// SetListenPort records the port the main listener is bound to.
+func (s *Server) SetListenPort(port int) {
+	s.listenPort.Store(int32(port))
+}
+
+// SetAccessURL records the https URL of this kojo on the tailnet for
+// GET /api/v1/qr and /api/v1/info.
+func (s *Server) SetAccessURL(u string) {
+	s.identityMu.Lock()
+	defer s.identityMu.Unlock()
+	s.accessURL = u
+}
+
+func (s *Server) currentAccessURL() string {
+	s.identityMu.RLock()
+	defer s.identityMu.RUnlock()
+	return s.accessURL
+}
+
 // resolveNodeKey is the late-bound resolver closure the tsnet
 // identity middleware calls per-request. Returns
 // ("", auth.ErrNodeKeyResolverNotReady) when no resolver is wired
@@ -1647,9 +2148,21 @@ func (s *Server) Shutdown(ctx context.Context) error {
 			s.logger.Warn("auth+tsnet listener shutdown error", "err", err)
 		}
 	}
+	if s.unixSrv != nil {
+		if err := s.unixSrv.Shutdown(ctx); err != nil {
+			s.logger.Warn("control socket shutdown error", "err", err)
+		}
+	}
 	if err := s.httpSrv.Shutdown(ctx); err != nil {
 		s.logger.Warn("public listener shutdown error", "err", err)
 	}
+	if s.viteStop != nil {
+		s.viteStop()
+		select {
+		case <-s.viteDone:
+		case <-ctx.Done():
+		}
+	}
 
 	// Now stop background producers / hubs.
 	// Stop the mirror refresher BEFORE agents.Shutdown() so an in-flight
@@ -1700,6 +2213,9 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	if s.slackHub != nil {
 		s.slackHub.Stop()
 	}
+	if s.scheduler != nil {
+		s.scheduler.Stop()
+	}
 	if s.sessions != nil {
 		s.sessions.StopAll()
 		s.sessions.SaveAll()
@@ -1739,6 +2255,13 @@ func (s *Server) Shutdown(ctx context.Context) error {
 			close(s.chunkedSyncSweepDone)
 		}
 	}
+	if s.uploadSweepDone != nil {
+		select {
+		case <-s.uploadSweepDone:
+		default:
+			close(s.uploadSweepDone)
+		}
+	}
 	return nil
 }
 
@@ -1822,6 +2345,30 @@ func writeError(w http.ResponseWriter, status int, code, message string) {
 // maxBytes bytes. If truncation is needed an ellipsis ("...") is appended,
 // and the cut is rolled back to a UTF-8 rune boundary so no multi-byte
 // sequence is split. maxBytes <= 0 returns "".
+// exitSnippetLines is how many trailing output lines a session_exit
+// notification carries. Lines are cut to exitSnippetLineLen bytes so
+// the payload fits the 2 KB record notify encrypts into.
+const (
+	exitSnippetLines   = 5
+	exitSnippetLineLen = 120
+)
+
+// exitSnippet joins the last output lines of a session for a push
+// payload.
+func exitSnippet(lines []string) string {
+	for i, l := range lines {
+		lines[i] = truncateUTF8(l, exitSnippetLineLen)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sessionURL is the path the web UI opens a session's terminal at,
+// under the base path. The service worker resolves it against its own
+// origin when a notification is tapped.
+func (s *Server) sessionURL(id string) string {
+	return s.basePath + "/session/" + url.PathEscape(id)
+}
+
 func truncateUTF8(s string, maxBytes int) string {
 	if maxBytes <= 0 {
 		return ""
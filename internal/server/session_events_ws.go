@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/loppo-llc/kojo/internal/session"
+)
+
+// handleSessionEventsWS GET /api/v1/sessions/events
+//
+// Pushes session list changes so the UI doesn't have to poll
+// GET /api/v1/sessions. The first frame is
+// `{"type":"snapshot","sessions":[...]}`, the same list the GET
+// returns; after that each frame is a session.ListEvent
+// (session_created, session_updated, session_exited, session_removed)
+// carrying the session's new info.
+//
+// As with /api/v1/events, the subscription is taken before the
+// snapshot so no change falls between the two. A client that falls
+// behind is closed with 1008 (PolicyViolation) and should reconnect
+// for a fresh snapshot.
+func (s *Server) handleSessionEventsWS(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	events, unsubscribe := s.sessions.SubscribeList()
+	defer unsubscribe()
+
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+		OriginPatterns:  wsOriginPatterns,
+		CompressionMode: wsCompression,
+	})
+	if err != nil {
+		s.logger.Error("session events websocket accept failed", "err", err)
+		return
+	}
+	defer conn.CloseNow()
+	// Push-only; client frames are read and discarded.
+	conn.SetReadLimit(4 * 1024)
+	wsAttach(ctx, conn)
+
+	go func() {
+		for {
+			if _, _, err := conn.Read(ctx); err != nil {
+				cancel()
+				return
+			}
+			wsTouch(ctx)
+		}
+	}()
+
+	list := s.sessions.List()
+	infos := make([]session.SessionInfo, len(list))
+	for i, sess := range list {
+		infos[i] = sess.Info()
+	}
+	writeCtx, writeCancel := context.WithTimeout(ctx, 10*time.Second)
+	err = wsjson.Write(writeCtx, conn, map[string]any{"type": "snapshot", "sessions": infos})
+	writeCancel()
+	if err != nil {
+		return
+	}
+
+	pingTicker := time.NewTicker(30 * time.Second)
+	defer pingTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			conn.Close(websocket.StatusNormalClosure, "client gone")
+			return
+		case <-pingTicker.C:
+			pingCtx, pingCancel := context.WithTimeout(ctx, 10*time.Second)
+			err := conn.Ping(pingCtx)
+			pingCancel()
+			if err != nil {
+				return
+			}
+		case ev, ok := <-events:
+			if !ok {
+				conn.Close(websocket.StatusPolicyViolation, "subscriber dropped; reconnect for a fresh snapshot")
+				return
+			}
+			writeCtx, writeCancel := context.WithTimeout(ctx, 10*time.Second)
+			err := wsjson.Write(writeCtx, conn, ev)
+			writeCancel()
+			if err != nil {
+				return
+			}
+			wsTouch(ctx)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"bytes"
+	"cmp"
+	"html/template"
+	"mime"
+	"net/http"
+	"time"
+)
+
+// sessionExportPage is the self-contained page GET
+// /api/v1/sessions/{id}/export renders: no scripts, no external
+// resources, so it opens anywhere it is sent.
+var sessionExportPage = template.Must(template.New("export").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { margin: 0; background: #1e1e1e; color: #d4d4d4; font: 13px/1.35 ui-monospace, "IBM Plex Mono", Menlo, Consolas, monospace; }
+header { padding: 12px 16px; border-bottom: 1px solid #333; color: #9a9a9a; }
+header h1 { margin: 0 0 4px; font-size: 15px; color: #e5e5e5; }
+pre { margin: 0; padding: 12px 16px; white-space: pre; overflow-x: auto; font: inherit; }
+</style>
+</head>
+<body>
+<header>
+<h1>{{.Title}}</h1>
+<div>{{.Tool}} in {{.WorkDir}} · {{.Status}} · started {{.CreatedAt}} · exported {{.ExportedAt}}</div>
+</header>
+<pre>{{.Output}}</pre>
+</body>
+</html>
+`))
+
+// handleExportSession GET /api/v1/sessions/{id}/export
+// Renders the session's terminal output (the scrollback, already
+// redacted) as a standalone HTML page, for sharing a run with someone
+// without kojo access.
+func (s *Server) handleExportSession(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	sess, ok := s.sessions.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "session not found: "+id)
+		return
+	}
+	info := sess.Info()
+	cols, rows := int(cmp.Or(info.LastCols, 80)), int(cmp.Or(info.LastRows, 24))
+	screen := renderTerminal(sess.Output(), cols, rows)
+
+	var buf bytes.Buffer
+	err := sessionExportPage.Execute(&buf, map[string]any{
+		"Title":      cmp.Or(info.Title, info.Tool+" session "+info.ID),
+		"Tool":       info.Tool,
+		"WorkDir":    info.WorkDir,
+		"Status":     info.Status,
+		"CreatedAt":  info.CreatedAt,
+		"ExportedAt": time.Now().Format(time.RFC3339),
+		"Output":     template.HTML(screen.HTML()),
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": "kojo-" + id + ".html",
+	}))
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	w.Write(buf.Bytes())
+}
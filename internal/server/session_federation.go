@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/loppo-llc/kojo/internal/auth"
+	"github.com/loppo-llc/kojo/internal/peer"
+	"github.com/loppo-llc/kojo/internal/store"
+)
+
+// federatedListTimeout bounds each peer's part of
+// GET /api/v1/sessions?all=true. A slow peer is reported in the
+// response rather than holding up everyone else's sessions.
+const federatedListTimeout = 3 * time.Second
+
+// peerSessionsStatus is one peer's entry in the "peers" list of an
+// ?all=true response.
+type peerSessionsStatus struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Sessions int    `json:"sessions"`
+	Error    string `json:"error,omitempty"`
+}
+
+// handleListAllSessions serves GET /api/v1/sessions?all=true: this
+// node's sessions plus those of every online peer in the registry,
+// each stamped with the `peer` that owns it so the UI sends its WS and
+// REST calls through `?peer=` (sessionPeerProxyMiddleware). Peers are
+// asked in parallel; one that can't answer is listed in "peers" with
+// its error instead of failing the request.
+func (s *Server) handleListAllSessions(w http.ResponseWriter, r *http.Request) {
+	self := ""
+	if s.peerID != nil {
+		self = s.peerID.DeviceID
+	}
+	sessions := []map[string]any{}
+	for _, sess := range s.sessions.List() {
+		sessions = append(sessions, stampPeer(sess.Info(), self))
+	}
+
+	var peers []*store.PeerRecord
+	if self != "" && s.agents != nil && s.agents.Store() != nil {
+		rows, err := s.agents.Store().ListPeers(r.Context(), store.ListPeersOptions{Status: store.PeerStatusOnline})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal", "list peers: "+err.Error())
+			return
+		}
+		for _, rec := range rows {
+			if rec.DeviceID != self {
+				peers = append(peers, rec)
+			}
+		}
+	}
+
+	statuses := make([]peerSessionsStatus, len(peers))
+	remote := make([][]map[string]any, len(peers))
+	var wg sync.WaitGroup
+	for i, rec := range peers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i] = peerSessionsStatus{ID: rec.DeviceID, Name: rec.Name}
+			list, err := s.fetchPeerSessions(r.Context(), rec)
+			if err != nil {
+				statuses[i].Error = err.Error()
+				return
+			}
+			for _, m := range list {
+				m["peer"] = rec.DeviceID
+			}
+			remote[i] = list
+			statuses[i].Sessions = len(list)
+		}()
+	}
+	wg.Wait()
+	for _, list := range remote {
+		sessions = append(sessions, list...)
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]any{
+		"sessions": sessions,
+		"peers":    statuses,
+	})
+}
+
+// fetchPeerSessions asks rec for its own session list.
+func (s *Server) fetchPeerSessions(ctx context.Context, rec *store.PeerRecord) ([]map[string]any, error) {
+	addr, err := peer.NormalizeAddress(rec.URL)
+	if err != nil {
+		return nil, fmt.Errorf("no usable dial address: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, federatedListTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, addr+"/api/v1/sessions", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := peer.NoKeepAliveHTTPClient(federatedListTimeout).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("peer unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("peer returned %s", resp.Status)
+	}
+	var body struct {
+		Sessions []map[string]any `json:"sessions"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 8<<20)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode peer sessions: %w", err)
+	}
+	return body.Sessions, nil
+}
+
+// stampPeer renders info as a JSON object with its owning peer set.
+func stampPeer(info any, peerID string) map[string]any {
+	out := map[string]any{}
+	full, _ := json.Marshal(info)
+	_ = json.Unmarshal(full, &out)
+	out["peer"] = peerID
+	return out
+}
+
+// wantsAllSessions reports whether a session list request asks for
+// every node's sessions. A peer-signed request never does: the Hub
+// asking a peer for its list must get only that peer's sessions, or
+// two nodes would fan out to each other.
+func wantsAllSessions(r *http.Request) bool {
+	if r.URL.Query().Get("all") != "true" {
+		return false
+	}
+	return !auth.FromContext(r.Context()).IsPeer()
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/loppo-llc/kojo/internal/auth"
+	"github.com/loppo-llc/kojo/internal/store"
+)
+
+func TestWantsAllSessions(t *testing.T) {
+	req := func(q string, role auth.Role) *http.Request {
+		return authedRequest(httptest.NewRequest(http.MethodGet, "/api/v1/sessions"+q, nil), auth.Principal{Role: role})
+	}
+	if !wantsAllSessions(req("?all=true", auth.RoleOwner)) {
+		t.Error("owner ?all=true: want federated list")
+	}
+	if wantsAllSessions(req("", auth.RoleOwner)) {
+		t.Error("owner without all: want local list")
+	}
+	if wantsAllSessions(req("?all=true", auth.RolePeer)) {
+		t.Error("peer ?all=true: want local list (no fan-out loops)")
+	}
+}
+
+func TestFetchPeerSessions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/sessions" || r.URL.RawQuery != "" {
+			t.Errorf("peer got %s", r.URL)
+		}
+		w.Write([]byte(`{"sessions":[{"id":"a","peer":"stale"},{"id":"b"}]}`))
+	}))
+	defer ts.Close()
+	s := &Server{}
+	list, err := s.fetchPeerSessions(context.Background(), &store.PeerRecord{DeviceID: "dev", URL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0]["id"] != "a" {
+		t.Errorf("sessions = %v", list)
+	}
+
+	down := &store.PeerRecord{DeviceID: "dev", URL: "http://127.0.0.1:1"}
+	if _, err := s.fetchPeerSessions(context.Background(), down); err == nil {
+		t.Error("unreachable peer: want error")
+	}
+}
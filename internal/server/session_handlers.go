@@ -2,6 +2,7 @@ package server
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
 	"errors"
@@ -36,7 +37,7 @@ func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
 	// falls through to the version-only reduced view.
 	p := auth.FromContext(r.Context())
 	if !p.IsOwner() && !p.IsPeer() {
-		writeJSONResponse(w, http.StatusOK, map[string]any{"version": s.version})
+		writeJSONResponse(w, http.StatusOK, map[string]any{"version": s.version, "readOnly": s.readOnly})
 		return
 	}
 	hostname, _ := os.Hostname()
@@ -47,10 +48,49 @@ func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
 		"homeDir":   homeDir,
 		"tools":     session.ToolAvailability(),
 		"shellTool": session.ShellToolName(),
+		"fileRoots": s.files.Roots(),
+		"readOnly":  s.readOnly,
+		// Uptime and build metadata, for feature gating and bug reports.
+		"startedAt":      s.startedAt.UTC().Format(time.RFC3339),
+		"uptimeSeconds":  int64(time.Since(s.startedAt).Seconds()),
+		"build":          readBuildMeta(),
+		"tmuxVersion":    session.TmuxVersion(),
+		"tmuxMinVersion": session.MinTmuxVersion,
+		"tmuxSupported":  session.TmuxSupported(),
+		"tailscaleName":  s.tailscaleDNSName(),
+		"accessUrl":      s.currentAccessURL(),
+		"port":           s.listenPort.Load(),
+		"pushConfigured": s.notify != nil,
+		"authRequired":   !s.unsafePeer,
+	}
+	if s.sessions != nil {
+		resp["sessionBackend"] = s.sessions.Backend()
+		hosts := []string{}
+		for _, h := range s.sessions.RemoteHosts() {
+			hosts = append(hosts, h.Name)
+		}
+		resp["remoteHosts"] = hosts
+		sandboxes := []string{}
+		for _, sb := range s.sessions.Sandboxes() {
+			sandboxes = append(sandboxes, sb.Name)
+		}
+		resp["sandboxes"] = sandboxes
 	}
 	if s.agents != nil {
 		resp["agentBackends"] = s.agents.BackendAvailability()
 	}
+	// Last known release check, from cache only so /info never waits
+	// on GitHub. Absent until the checker has run once.
+	if s.updateChecker != nil {
+		if st := s.updateChecker.Status(); !st.CheckedAt.IsZero() {
+			resp["update"] = map[string]any{
+				"latest":          st.Latest,
+				"updateAvailable": st.UpdateAvailable,
+				"notesUrl":        st.NotesURL,
+				"checkedAt":       st.CheckedAt.UTC().Format(time.RFC3339),
+			}
+		}
+	}
 	writeJSONResponse(w, http.StatusOK, resp)
 }
 
@@ -122,7 +162,15 @@ func isLoopback(host string) bool {
 	return ip != nil && ip.IsLoopback()
 }
 
+// handleListSessions GET /api/v1/sessions
+//
+// With ?all=true the list spans every online peer; see
+// handleListAllSessions.
 func (s *Server) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	if wantsAllSessions(r) {
+		s.handleListAllSessions(w, r)
+		return
+	}
 	list := s.sessions.List()
 	infos := make([]session.SessionInfo, len(list))
 	for i, sess := range list {
@@ -136,7 +184,7 @@ func (s *Server) handleCreateSession(w http.ResponseWriter, r *http.Request) {
 		Tool               string   `json:"tool"`
 		WorkDir            string   `json:"workDir"`
 		Args               []string `json:"args"`
-		YoloMode           bool     `json:"yoloMode"`
+		YoloMode           *bool    `json:"yoloMode"`
 		SimpleSystemPrompt bool     `json:"simpleSystemPrompt"`
 		ParentID           string   `json:"parentId"`
 		// PeerID lets the Hub UI target a session on a remote peer
@@ -149,15 +197,38 @@ func (s *Server) handleCreateSession(w http.ResponseWriter, r *http.Request) {
 		// locally — without this guard a misconfigured peerId could
 		// cycle the proxy.
 		PeerID string `json:"peerId,omitempty"`
+		// Worktree names a branch to run the session on. workDir is
+		// then the repository: the session starts in the worktree
+		// that has the branch checked out, created next to the repo
+		// when there is none. Lets several agents work one repo
+		// without sharing a working tree.
+		Worktree string `json:"worktree,omitempty"`
+		// Host names a configured remote host to run the session on
+		// over SSH. workDir is then a path on that host, and empty
+		// means its home directory.
+		Host string `json:"host,omitempty"`
+		// Sandbox names a configured restriction profile to run the
+		// tool in; empty falls back to the yolo sandbox for yolo
+		// sessions.
+		Sandbox string `json:"sandbox,omitempty"`
+		// TimeLimitMinutes and TokenBudget stop the session after
+		// that long or once its tool has used that many tokens, with
+		// a push warning shortly before. Token budgets are for local
+		// claude sessions only.
+		TimeLimitMinutes int   `json:"timeLimitMinutes,omitempty"`
+		TokenBudget      int64 `json:"tokenBudget,omitempty"`
+		// CheckpointMinutes commits the working tree to the session's
+		// checkpoint ref that often, and when it is paused or exits.
+		CheckpointMinutes int `json:"checkpointMinutes,omitempty"`
+		// Prompt is a first prompt the tool starts on, for claude,
+		// custom and codex. It isn't kept, so a restart doesn't
+		// send it again.
+		Prompt string `json:"prompt,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(w, http.StatusBadRequest, "bad_request", "invalid request body")
 		return
 	}
-	if req.Tool == "" {
-		writeError(w, http.StatusBadRequest, "bad_request", "tool is required")
-		return
-	}
 	// Peer-targeted create: forward to the peer's local handler.
 	// Loop prevention: a RolePeer-signed request must NOT re-proxy.
 	if req.PeerID != "" && s.peerID != nil && req.PeerID != s.peerID.DeviceID {
@@ -166,10 +237,44 @@ func (s *Server) handleCreateSession(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
-	if req.WorkDir == "" {
+	if req.Host != "" && req.Worktree != "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "worktree is not supported on a remote host")
+		return
+	}
+	if req.WorkDir == "" && req.Host == "" {
 		home, _ := os.UserHomeDir()
 		req.WorkDir = home
 	}
+	// What the request leaves out comes from the defaults for its
+	// directory (see handleSetDirDefaults); args and token budget only
+	// when the tool is theirs.
+	var defaultsDir string
+	if d, ok := s.lookupDirDefaults(req.WorkDir); ok && req.Host == "" {
+		defaultsDir = d.Dir
+		req.Tool = cmp.Or(req.Tool, d.Tool)
+		if d.Tool == "" || d.Tool == req.Tool {
+			if req.Args == nil {
+				req.Args = d.Args
+			}
+			req.TokenBudget = cmp.Or(req.TokenBudget, d.TokenBudget)
+		}
+		req.YoloMode = cmp.Or(req.YoloMode, d.YoloMode)
+		req.Sandbox = cmp.Or(req.Sandbox, d.Sandbox)
+		req.TimeLimitMinutes = cmp.Or(req.TimeLimitMinutes, d.TimeLimitMinutes)
+		req.CheckpointMinutes = cmp.Or(req.CheckpointMinutes, d.CheckpointMinutes)
+	}
+	if req.Tool == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "tool is required")
+		return
+	}
+	if req.Worktree != "" {
+		dir, err := s.git.EnsureWorktree(req.WorkDir, req.Worktree)
+		if err != nil {
+			writeGitError(w, err)
+			return
+		}
+		req.WorkDir = dir
+	}
 
 	if req.SimpleSystemPrompt && (req.Tool == "claude" || req.Tool == "custom") {
 		hasSystemPrompt := false
@@ -187,9 +292,17 @@ func (s *Server) handleCreateSession(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	sess, err := s.sessions.Create(req.Tool, req.WorkDir, req.Args, req.YoloMode, req.ParentID)
+	sess, err := s.sessions.CreateWithOptions(req.Tool, req.WorkDir, req.Args, req.YoloMode != nil && *req.YoloMode, req.ParentID,
+		session.CreateOptions{
+			Host:               req.Host,
+			Sandbox:            req.Sandbox,
+			TimeLimit:          time.Duration(req.TimeLimitMinutes) * time.Minute,
+			TokenBudget:        req.TokenBudget,
+			CheckpointInterval: time.Duration(req.CheckpointMinutes) * time.Minute,
+			Prompt:             req.Prompt,
+		})
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		writeSessionError(w, err, http.StatusBadRequest)
 		return
 	}
 
@@ -216,6 +329,9 @@ func (s *Server) handleCreateSession(w http.ResponseWriter, r *http.Request) {
 	if s.peerID != nil {
 		out["peer"] = s.peerID.DeviceID
 	}
+	if defaultsDir != "" {
+		out["dirDefaults"] = defaultsDir
+	}
 	writeJSONResponse(w, http.StatusOK, out)
 }
 
@@ -307,6 +423,10 @@ func (s *Server) handleGetSession(w http.ResponseWriter, r *http.Request) {
 	writeJSONResponse(w, http.StatusOK, sess.Info())
 }
 
+// handleDeleteSession DELETE /api/v1/sessions/{id}
+//
+// Stops a running session. Once it has exited, a second DELETE removes
+// its record, persisted state and leftover tmux session for good.
 func (s *Server) handleDeleteSession(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	sess, ok := s.sessions.Get(id)
@@ -315,22 +435,32 @@ func (s *Server) handleDeleteSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var err error
-	if sess.Info().Status == session.StatusRunning {
+	if st := sess.Info().Status; st == session.StatusRunning || st == session.StatusPaused {
 		err = s.sessions.Stop(id)
 	} else {
 		err = s.sessions.Remove(id)
 	}
 	if err != nil {
-		if errors.Is(err, session.ErrSessionNotFound) {
-			writeError(w, http.StatusNotFound, "not_found", err.Error())
-		} else {
-			writeError(w, http.StatusConflict, "conflict", err.Error())
-		}
+		writeSessionError(w, err, http.StatusConflict)
 		return
 	}
 	writeJSONResponse(w, http.StatusOK, map[string]bool{"ok": true})
 }
 
+// handleClearExitedSessions DELETE /api/v1/sessions?status=exited
+//
+// Removes every exited session (and its children) from the list, the
+// persisted store and tmux, as DELETE /api/v1/sessions/{id} does for
+// one. Sessions with a running child are kept. The status filter is
+// required so a bare DELETE can't be mistaken for "stop everything".
+func (s *Server) handleClearExitedSessions(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("status") != string(session.StatusExited) {
+		writeError(w, http.StatusBadRequest, "bad_request", "status=exited is required")
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]any{"removed": s.sessions.RemoveExited()})
+}
+
 func (s *Server) handlePatchSession(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	sess, ok := s.sessions.Get(id)
@@ -340,20 +470,143 @@ func (s *Server) handlePatchSession(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		YoloMode *bool `json:"yoloMode"`
+		YoloMode       *bool     `json:"yoloMode"`
+		NotifyOnBell   *bool     `json:"notifyOnBell"`
+		NotifyPatterns *[]string `json:"notifyPatterns"`
+		// NoRedact shows this session's output unredacted from now on
+		// (what was already masked stays masked).
+		NoRedact *bool `json:"noRedact"`
+		// SizePolicy is "last", "smallest" or "fixed"; the last needs
+		// FixedCols and FixedRows.
+		SizePolicy *string `json:"sizePolicy"`
+		FixedCols  uint16  `json:"fixedCols"`
+		FixedRows  uint16  `json:"fixedRows"`
+		// TimeLimitMinutes restarts the clock: the session now stops
+		// that long from now. TokenBudget replaces the budget. 0
+		// removes either limit.
+		TimeLimitMinutes *int   `json:"timeLimitMinutes"`
+		TokenBudget      *int64 `json:"tokenBudget"`
+		// CheckpointMinutes sets how often the working tree is
+		// checkpointed; 0 turns checkpoints off.
+		CheckpointMinutes *int `json:"checkpointMinutes"`
+		// Model and PermissionMode switch the running tool; see
+		// GET /api/v1/sessions/{id}/actions for what it offers.
+		Model          *string `json:"model"`
+		PermissionMode *string `json:"permissionMode"`
+		// PIN is required with Model or PermissionMode when the
+		// session's input is locked: switching types into the tool.
+		PIN string `json:"pin"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(w, http.StatusBadRequest, "bad_request", "invalid request body")
 		return
 	}
 
+	// The PIN, patterns, the size policy and the limits go first: a
+	// bad one rejects the rest of the patch.
+	if req.Model != nil || req.PermissionMode != nil {
+		if err := sess.CheckInputPIN(req.PIN); err != nil && !errors.Is(err, session.ErrNotInputLocked) {
+			writeSessionError(w, err, http.StatusInternalServerError)
+			return
+		}
+	}
+	if req.SizePolicy != nil {
+		if err := session.CheckSizePolicy(session.SizePolicy(*req.SizePolicy), req.FixedCols, req.FixedRows); err != nil {
+			writeSessionError(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+	if req.TimeLimitMinutes != nil && *req.TimeLimitMinutes < 0 {
+		writeError(w, http.StatusBadRequest, "bad_request", "timeLimitMinutes must not be negative")
+		return
+	}
+	if req.TokenBudget != nil {
+		if err := s.sessions.SetTokenBudget(sess, *req.TokenBudget); err != nil {
+			writeSessionError(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+	if req.CheckpointMinutes != nil {
+		if err := s.sessions.SetCheckpointInterval(sess, time.Duration(*req.CheckpointMinutes)*time.Minute); err != nil {
+			writeSessionError(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+	if req.TimeLimitMinutes != nil {
+		_ = s.sessions.SetTimeLimit(sess, time.Duration(*req.TimeLimitMinutes)*time.Minute)
+	}
+	if req.NotifyPatterns != nil {
+		if err := sess.SetNotifyPatterns(*req.NotifyPatterns); err != nil {
+			writeSessionError(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Model != nil || req.PermissionMode != nil {
+		var model, mode string
+		if req.Model != nil {
+			model = *req.Model
+		}
+		if req.PermissionMode != nil {
+			mode = *req.PermissionMode
+		}
+		if err := s.sessions.SetControls(sess, model, mode); err != nil {
+			writeSessionError(w, err, http.StatusBadRequest)
+			return
+		}
+	}
 	if req.YoloMode != nil {
+		if *req.YoloMode {
+			if err := s.sessions.CheckYoloPath(sess); err != nil {
+				writeSessionError(w, err, http.StatusBadRequest)
+				return
+			}
+			if err := s.sessions.CheckYoloSandbox(sess); err != nil {
+				writeSessionError(w, err, http.StatusBadRequest)
+				return
+			}
+		}
 		sess.SetYoloMode(*req.YoloMode)
 	}
+	if req.NotifyOnBell != nil {
+		sess.SetNotifyOnBell(*req.NotifyOnBell)
+	}
+	if req.NoRedact != nil {
+		sess.SetNoRedact(*req.NoRedact)
+	}
+	if req.SizePolicy != nil {
+		_ = sess.SetSizePolicy(session.SizePolicy(*req.SizePolicy), req.FixedCols, req.FixedRows)
+	}
+	if req.YoloMode != nil || req.NotifyOnBell != nil || req.NotifyPatterns != nil || req.NoRedact != nil || req.SizePolicy != nil {
+		s.sessions.NotifyUpdated(sess)
+	}
 
 	writeJSONResponse(w, http.StatusOK, sess.Info())
 }
 
+// handleCloneSession POST /api/v1/sessions/{id}/clone
+//
+// Starts a second session with the same tool, workDir, args and yolo
+// setting. Body (optional): {"resume":true} to fork the source's claude
+// conversation rather than start fresh.
+func (s *Server) handleCloneSession(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var req struct {
+		Resume bool `json:"resume"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			writeError(w, http.StatusBadRequest, "bad_request", "invalid request body")
+			return
+		}
+	}
+	sess, err := s.sessions.Clone(id, req.Resume)
+	if err != nil {
+		writeSessionError(w, err, http.StatusBadRequest)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, sess.Info())
+}
+
 func (s *Server) handleTerminalSession(w http.ResponseWriter, r *http.Request) {
 	parentID := r.PathValue("id")
 	sess, ok := s.sessions.FindChildSession(parentID, session.ShellToolName())
@@ -368,11 +621,7 @@ func (s *Server) handleRestartSession(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	sess, err := s.sessions.Restart(id)
 	if err != nil {
-		if errors.Is(err, session.ErrSessionNotFound) {
-			writeError(w, http.StatusNotFound, "not_found", err.Error())
-		} else {
-			writeError(w, http.StatusBadRequest, "bad_request", err.Error())
-		}
+		writeSessionError(w, err, http.StatusBadRequest)
 		return
 	}
 	writeJSONResponse(w, http.StatusOK, sess.Info())
@@ -392,19 +641,253 @@ func (s *Server) handleTmuxAction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if err := s.sessions.TmuxAction(id, req.Action); err != nil {
-		switch {
-		case errors.Is(err, session.ErrSessionNotFound):
-			writeError(w, http.StatusNotFound, "not_found", err.Error())
-		case errors.Is(err, session.ErrSessionNotRunning), errors.Is(err, session.ErrNotTerminal):
-			writeError(w, http.StatusConflict, "conflict", err.Error())
-		default:
-			writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		writeSessionError(w, err, http.StatusBadRequest)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+// handleListSessionActions GET /api/v1/sessions/{id}/actions
+//
+// Lists the housekeeping actions of the session's tool (session.ToolAction)
+// and, under "controls", the models and permission modes PATCH can
+// switch it to; controls is null for a tool without them.
+func (s *Server) handleListSessionActions(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	sess, ok := s.sessions.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "session not found: "+id)
+		return
+	}
+	info := sess.Info()
+	actions := session.ToolActions(info.Tool)
+	if actions == nil {
+		actions = []session.ToolAction{}
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]any{"actions": actions, "controls": session.Controls(info.Tool, info.Args)})
+}
+
+// handleRunSessionAction POST /api/v1/sessions/{id}/actions/{action}
+//
+// Body (optional): {"captureMs":3000,"pin":"..."}. Types the action
+// into the session. With captureMs it answers once that much time has
+// passed (30 seconds at most), with the last lines of the output in
+// "output". pin is the input lock PIN, when the session has one.
+func (s *Server) handleRunSessionAction(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	sess, ok := s.sessions.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "session not found: "+id)
+		return
+	}
+	var req struct {
+		CaptureMs int64  `json:"captureMs"`
+		PIN       string `json:"pin"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid request body")
+		return
+	}
+	if req.CaptureMs < 0 || time.Duration(req.CaptureMs)*time.Millisecond > session.MaxActionCapture {
+		writeError(w, http.StatusBadRequest, "bad_request", fmt.Sprintf("captureMs must be 0 to %d", session.MaxActionCapture.Milliseconds()))
+		return
+	}
+	if err := sess.CheckInputPIN(req.PIN); err != nil && !errors.Is(err, session.ErrNotInputLocked) {
+		writeSessionError(w, err, http.StatusInternalServerError)
+		return
+	}
+	output, err := s.sessions.RunAction(id, r.PathValue("action"), time.Duration(req.CaptureMs)*time.Millisecond)
+	if err != nil {
+		writeSessionError(w, err, http.StatusInternalServerError)
+		return
+	}
+	resp := map[string]any{"ok": true}
+	if req.CaptureMs > 0 {
+		if output == nil {
+			output = []string{}
 		}
+		resp["output"] = output
+	}
+	writeJSONResponse(w, http.StatusOK, resp)
+}
+
+// handleSignalSession POST /api/v1/sessions/{id}/signal
+//
+// Body: {"signal":"SIGINT"}. Sends SIGINT, SIGTERM, SIGHUP, SIGQUIT,
+// SIGKILL, SIGSTOP or SIGCONT to the tool running in the session's
+// tmux pane, for a hung tool that ignores keyboard input; the SIG
+// prefix is optional. Only SIGKILL is available on Windows.
+func (s *Server) handleSignalSession(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var req struct {
+		Signal string `json:"signal"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid request body")
+		return
+	}
+	if req.Signal == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "signal is required")
+		return
+	}
+	if err := s.sessions.Signal(id, req.Signal); err != nil {
+		writeSessionError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+// handlePauseSession POST /api/v1/sessions/{id}/pause
+//
+// Stops the session's tool with SIGSTOP; its status reads "paused"
+// until /resume. Not available on Windows.
+func (s *Server) handlePauseSession(w http.ResponseWriter, r *http.Request) {
+	s.setSessionPaused(w, r, true)
+}
+
+// handleResumeSession POST /api/v1/sessions/{id}/resume
+func (s *Server) handleResumeSession(w http.ResponseWriter, r *http.Request) {
+	s.setSessionPaused(w, r, false)
+}
+
+func (s *Server) setSessionPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	id := r.PathValue("id")
+	var err error
+	if paused {
+		err = s.sessions.Pause(id)
+	} else {
+		err = s.sessions.Resume(id)
+	}
+	// Pausing needs SIGSTOP, so an unsupported signal here means the
+	// platform can't pause at all.
+	if errors.Is(err, session.ErrUnsupportedSignal) {
+		writeError(w, http.StatusNotImplemented, "unsupported", err.Error())
+		return
+	}
+	if err != nil {
+		writeSessionError(w, err, http.StatusInternalServerError)
+		return
+	}
+	sess, ok := s.sessions.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "session not found: "+id)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, sess.Info())
+}
+
+// handleSessionClipboard POST /api/v1/sessions/{id}/clipboard
+//
+// Body: {"text":"..."}. Pastes the client's clipboard into the session
+// as a bracketed paste, the counterpart of the "clipboard" WebSocket
+// message that carries OSC 52 copies out.
+func (s *Server) handleSessionClipboard(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	sess, ok := s.sessions.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "session not found: "+id)
+		return
+	}
+	var req struct {
+		Text string `json:"text"`
+		// PIN is required when the session's input is locked.
+		PIN string `json:"pin"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid request body")
+		return
+	}
+	if err := sess.CheckInputPIN(req.PIN); err != nil && !errors.Is(err, session.ErrNotInputLocked) {
+		writeSessionError(w, err, http.StatusInternalServerError)
+		return
+	}
+	if sess.Info().Status != session.StatusRunning {
+		writeError(w, http.StatusConflict, "conflict", "session not running: "+id)
+		return
+	}
+	if err := sess.Paste(req.Text); err != nil {
+		writeError(w, http.StatusConflict, "conflict", err.Error())
 		return
 	}
 	writeJSONResponse(w, http.StatusOK, map[string]bool{"ok": true})
 }
 
+// handleLockSessionInput POST /api/v1/sessions/{id}/lock
+//
+// Body: {"pin":"1234"}. Locks the session's input: WebSocket input and
+// clipboard pastes are refused until the client sends the PIN. 409 if
+// the session is already locked.
+func (s *Server) handleLockSessionInput(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	sess, ok := s.sessions.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "session not found: "+id)
+		return
+	}
+	var req struct {
+		PIN string `json:"pin"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid request body")
+		return
+	}
+	if err := sess.SetInputLock(req.PIN); err != nil {
+		writeSessionError(w, err, http.StatusInternalServerError)
+		return
+	}
+	s.sessions.NotifyUpdated(sess)
+	writeJSONResponse(w, http.StatusOK, sess.Info())
+}
+
+// handleUnlockSessionInput DELETE /api/v1/sessions/{id}/lock
+//
+// Body: {"pin":"1234"}. Removes the input lock. Five wrong PINs in a
+// row refuse further tries for five minutes.
+func (s *Server) handleUnlockSessionInput(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	sess, ok := s.sessions.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "session not found: "+id)
+		return
+	}
+	var req struct {
+		PIN string `json:"pin"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid request body")
+		return
+	}
+	if err := sess.ClearInputLock(req.PIN); err != nil {
+		writeSessionError(w, err, http.StatusInternalServerError)
+		return
+	}
+	s.sessions.NotifyUpdated(sess)
+	writeJSONResponse(w, http.StatusOK, sess.Info())
+}
+
+// handleSessionImage GET /api/v1/sessions/{id}/images/{image}
+//
+// Serves an inline image the tool wrote to the terminal, by the ID its
+// "image" WebSocket event carried. Sixel images come back as the raw
+// DCS sequence (image/x-sixel).
+func (s *Server) handleSessionImage(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	sess, ok := s.sessions.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "session not found: "+id)
+		return
+	}
+	img, ok := sess.InlineImage(r.PathValue("image"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "image not found: "+r.PathValue("image"))
+		return
+	}
+	w.Header().Set("Content-Type", img.MIME)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	w.Write(img.Data())
+}
+
 // --- Attachment Handlers ---
 
 func (s *Server) handleListAttachments(w http.ResponseWriter, r *http.Request) {
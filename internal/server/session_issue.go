@@ -0,0 +1,120 @@
+package server
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	gitpkg "github.com/loppo-llc/kojo/internal/git"
+)
+
+// Limits on the prompt built from an issue. It goes on the tool's
+// command line, which Windows caps at 32K characters.
+const (
+	maxIssuePromptLen  = 24 << 10
+	maxIssueBodyLen    = 12 << 10
+	maxIssueCommentLen = 4 << 10
+)
+
+// handleCreateIssueSession POST /api/v1/sessions/from-issue
+// Starts a session on a GitHub issue or pull request: fetches it and
+// its comments with gh and hands them to the tool as its first prompt,
+// so an agent can be dispatched to triage one from a phone.
+//
+// Body: {"workDir": "...", "number": 123, "pullRequest": false,
+// "repo": "owner/name", "tool": "claude", "instructions": "...",
+// "worktree": "...", "yoloMode": false}. repo defaults to the
+// repository in workDir; tool, yoloMode and the rest of the settings
+// to the directory's defaults, then claude; instructions replace the
+// default request to fix the issue (or address the pull request's
+// review). worktree is as for POST /api/v1/sessions. The issue text is
+// whatever its author wrote, so think twice before yoloMode.
+func (s *Server) handleCreateIssueSession(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		WorkDir      string `json:"workDir"`
+		Number       int    `json:"number"`
+		PullRequest  bool   `json:"pullRequest"`
+		Repo         string `json:"repo"`
+		Tool         string `json:"tool"`
+		Instructions string `json:"instructions"`
+		Worktree     string `json:"worktree"`
+		YoloMode     *bool  `json:"yoloMode"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid request body")
+		return
+	}
+	if req.Number <= 0 {
+		writeError(w, http.StatusBadRequest, "bad_request", "number is required")
+		return
+	}
+	if req.WorkDir == "" {
+		req.WorkDir, _ = os.UserHomeDir()
+	}
+	iss, err := s.git.Issue(r.Context(), req.WorkDir, req.Repo, req.Number, req.PullRequest)
+	if err != nil {
+		writeGitError(w, err)
+		return
+	}
+	tool, args, yolo, opts := s.sessionDefaults(req.WorkDir, req.Tool, req.YoloMode)
+	if req.Worktree != "" {
+		dir, err := s.git.EnsureWorktree(req.WorkDir, req.Worktree)
+		if err != nil {
+			writeGitError(w, err)
+			return
+		}
+		req.WorkDir = dir
+	}
+	opts.Prompt = issuePrompt(iss, req.Instructions)
+	sess, err := s.sessions.CreateWithOptions(tool, req.WorkDir, args, yolo, "", opts)
+	if err != nil {
+		writeSessionError(w, err, http.StatusBadRequest)
+		return
+	}
+	s.logger.Info("session created from issue", "id", sess.ID, "issue", iss.URL)
+	writeJSONResponse(w, http.StatusOK, sess.Info())
+}
+
+// issuePrompt is the first prompt of a session on iss: instructions,
+// then the issue and as many of its latest comments as fit in
+// maxIssuePromptLen.
+func issuePrompt(iss *gitpkg.Issue, instructions string) string {
+	kind := "issue"
+	if iss.PullRequest {
+		kind = "pull request"
+	}
+	if instructions == "" {
+		instructions = "Find the cause of the issue below in this repository and fix it."
+		if iss.PullRequest {
+			instructions = fmt.Sprintf("Check out the branch %s of the pull request below and address its review comments.", iss.Head)
+		}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\nGitHub %s #%d: %s\n%s\n", instructions, kind, iss.Number, iss.Title, iss.URL)
+	fmt.Fprintf(&b, "\n--- @%s (%s) ---\n%s\n", iss.Author, strings.ToLower(iss.State),
+		truncateUTF8(cmp.Or(strings.TrimSpace(iss.Body), "(no description)"), maxIssueBodyLen))
+
+	// The latest comments say most about where things stand.
+	budget := maxIssuePromptLen - b.Len()
+	var kept []string
+	for i := len(iss.Comments) - 1; i >= 0; i-- {
+		c := iss.Comments[i]
+		text := fmt.Sprintf("\n--- @%s, %s ---\n%s\n", c.Author, c.CreatedAt,
+			truncateUTF8(strings.TrimSpace(c.Body), maxIssueCommentLen))
+		if len(text) > budget {
+			break
+		}
+		budget -= len(text)
+		kept = append(kept, text)
+	}
+	if omitted := len(iss.Comments) - len(kept); omitted > 0 {
+		fmt.Fprintf(&b, "\n(%d earlier comments left out; read them at the URL above.)\n", omitted)
+	}
+	for i := len(kept) - 1; i >= 0; i-- {
+		b.WriteString(kept[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	gitpkg "github.com/loppo-llc/kojo/internal/git"
+)
+
+func TestIssuePrompt(t *testing.T) {
+	iss := &gitpkg.Issue{
+		Number: 12, Title: "Crash on save", Body: "Steps to reproduce", State: "OPEN",
+		URL: "https://github.com/o/r/issues/12", Author: "ann",
+		Comments: []gitpkg.IssueComment{
+			{Author: "bob", Body: "Same here", CreatedAt: "2026-01-01T00:00:00Z"},
+			{Author: "cy", Body: "Fixed in main?", CreatedAt: "2026-01-02T00:00:00Z"},
+		},
+	}
+	got := issuePrompt(iss, "")
+	for _, want := range []string{"fix it", "GitHub issue #12: Crash on save", iss.URL, "@ann (open)", "Steps to reproduce", "@bob", "@cy"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("prompt lacks %q:\n%s", want, got)
+		}
+	}
+	if strings.Index(got, "@bob") > strings.Index(got, "@cy") {
+		t.Error("comments out of order")
+	}
+	if got := issuePrompt(&gitpkg.Issue{Number: 3, PullRequest: true, Head: "fix-it"}, "Just review it."); !strings.HasPrefix(got, "Just review it.") ||
+		!strings.Contains(got, "pull request #3") {
+		t.Errorf("pull request prompt:\n%s", got)
+	}
+
+	// Too many comments: the latest are kept and the rest counted.
+	iss.Comments = nil
+	for i := range 20 {
+		iss.Comments = append(iss.Comments, gitpkg.IssueComment{Author: fmt.Sprint("u", i), Body: strings.Repeat("x", 3000)})
+	}
+	got = issuePrompt(iss, "")
+	if len(got) > maxIssuePromptLen || !strings.Contains(got, "@u19,") || strings.Contains(got, "@u0,") ||
+		!strings.Contains(got, "earlier comments left out") {
+		t.Errorf("long thread: %d bytes, latest kept %v, oldest dropped %v",
+			len(got), strings.Contains(got, "@u19,"), !strings.Contains(got, "@u0,"))
+	}
+}
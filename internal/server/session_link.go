@@ -0,0 +1,158 @@
+package server
+
+import (
+	"bytes"
+	"cmp"
+	"html/template"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/loppo-llc/kojo/internal/auth"
+)
+
+// newSessionPage asks before a deep link starts a session, and shows
+// why one failed. Like the export page it has no
+// scripts and loads nothing.
+var newSessionPage = template.Must(template.New("new").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>New session · kojo</title>
+<style>
+body { margin: 0; padding: 24px 16px; background: #1e1e1e; color: #d4d4d4; font: 14px/1.5 system-ui, sans-serif; }
+main { max-width: 640px; margin: 0 auto; }
+h1 { margin: 0 0 16px; font-size: 18px; color: #e5e5e5; }
+dt { color: #9a9a9a; font-size: 12px; }
+dd { margin: 0 0 12px; font-family: ui-monospace, Menlo, Consolas, monospace; white-space: pre-wrap; word-break: break-word; }
+.error { padding: 8px 12px; border-left: 3px solid #cd0000; background: #2a1a1a; }
+button { padding: 8px 20px; border: 0; border-radius: 4px; background: #0e639c; color: #fff; font: inherit; cursor: pointer; }
+</style>
+</head>
+<body>
+<main>
+<h1>Start a {{.Tool}} session?</h1>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<dl>
+<dt>Directory</dt><dd>{{.Dir}}</dd>
+{{if .Prompt}}<dt>Prompt</dt><dd>{{.Prompt}}</dd>{{end}}
+</dl>
+<form method="post" action="{{.Action}}">
+<input type="hidden" name="tool" value="{{.Tool}}">
+<input type="hidden" name="dir" value="{{.Dir}}">
+<input type="hidden" name="prompt" value="{{.Prompt}}">
+<button type="submit">Start session</button>
+</form>
+</main>
+</body>
+</html>
+`))
+
+// newSessionLink is what a /new link asks for, as newSessionPage shows
+// it.
+type newSessionLink struct {
+	Tool, Dir, Prompt string
+	Action            string // the form's URL, under the base path
+	Error             string
+}
+
+// handleNewSessionLink GET /new, POST /new
+// Deep link that starts a session: /new?tool=claude&dir=~/src/app&prompt=...
+// shows what it will start with a button that POSTs it back, which
+// creates the session and redirects to its terminal, so other tools (a
+// bookmarklet in an issue tracker, say) can launch an agent. dir
+// defaults to the home directory, and tool and the other settings to
+// the directory's defaults, then claude; prompt is the first prompt
+// (see session.CreateOptions.Prompt). Links never turn on yolo mode,
+// even where the directory's defaults do.
+//
+// A GET never creates anything: it passes the read-only gate and may
+// come from any page. The POST is accepted from kojo's own origin
+// only, so a page can't start sessions behind the user's back, and is
+// drained and rate limited like POST /api/v1/sessions (see
+// createsSession). Owner only: the route is outside /api/v1/, which
+// the policy gate covers.
+func (s *Server) handleNewSessionLink(w http.ResponseWriter, r *http.Request) {
+	if !auth.FromContext(r.Context()).IsOwner() {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	if r.Method == http.MethodPost {
+		if !sameOriginForm(r) {
+			http.Error(w, "cross-site request refused", http.StatusForbidden)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "invalid form", http.StatusBadRequest)
+			return
+		}
+	}
+	v := r.Form
+	if r.Method == http.MethodGet {
+		v = r.URL.Query()
+	}
+	dir := expandLinkDir(cmp.Or(v.Get("dir"), "~"))
+	tool, args, _, opts := s.sessionDefaults(dir, v.Get("tool"), nil)
+	link := newSessionLink{
+		Tool:   tool,
+		Dir:    cmp.Or(v.Get("dir"), "~"),
+		Prompt: v.Get("prompt"),
+		Action: s.basePath + "/new",
+	}
+	if r.Method == http.MethodGet {
+		s.writeNewSessionPage(w, http.StatusOK, link)
+		return
+	}
+
+	opts.Prompt = link.Prompt
+	sess, err := s.sessions.CreateWithOptions(tool, dir, args, false, "", opts)
+	if err != nil {
+		link.Error = err.Error()
+		s.writeNewSessionPage(w, http.StatusBadRequest, link)
+		return
+	}
+	s.logger.Info("session created from link", "id", sess.ID, "tool", link.Tool)
+	http.Redirect(w, r, s.sessionURL(sess.ID), http.StatusSeeOther)
+}
+
+func (s *Server) writeNewSessionPage(w http.ResponseWriter, status int, link newSessionLink) {
+	var buf bytes.Buffer
+	if err := newSessionPage.Execute(&buf, link); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h := w.Header()
+	h.Set("Content-Type", "text/html; charset=utf-8")
+	h.Set("Cache-Control", "no-store")
+	h.Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; form-action 'self'; frame-ancestors 'none'")
+	w.WriteHeader(status)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// sameOriginForm reports whether a form POST comes from a kojo page,
+// by Sec-Fetch-Site or, from browsers that don't send it, Origin.
+func sameOriginForm(r *http.Request) bool {
+	if site := r.Header.Get("Sec-Fetch-Site"); site != "" {
+		return site == "same-origin"
+	}
+	u, err := url.Parse(r.Header.Get("Origin"))
+	return err == nil && u.Host != "" && u.Host == r.Host
+}
+
+// expandLinkDir expands a leading "~" in a link's directory to the
+// home directory.
+func expandLinkDir(dir string) string {
+	rest, ok := strings.CutPrefix(dir, "~")
+	if !ok || (rest != "" && rest[0] != '/' && rest[0] != filepath.Separator) {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return dir
+	}
+	return filepath.Join(home, rest)
+}
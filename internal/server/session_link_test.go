@@ -0,0 +1,102 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/loppo-llc/kojo/internal/auth"
+)
+
+func TestNewSessionLink(t *testing.T) {
+	s := &Server{basePath: "/kojo"}
+	do := func(method, target, site string, p auth.Principal) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, nil)
+		if method == http.MethodPost {
+			r = httptest.NewRequest(method, target, strings.NewReader(url.Values{"tool": {"claude"}}.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		if site != "" {
+			r.Header.Set("Sec-Fetch-Site", site)
+		}
+		r = r.WithContext(auth.WithPrincipal(r.Context(), p))
+		w := httptest.NewRecorder()
+		s.handleNewSessionLink(w, r)
+		return w
+	}
+	owner := auth.Principal{Role: auth.RoleOwner}
+
+	if w := do(http.MethodGet, "/new?tool=claude", "none", auth.Principal{Role: auth.RoleGuest}); w.Code != http.StatusForbidden {
+		t.Errorf("guest: status %d, want 403", w.Code)
+	}
+
+	// From another site the link only asks.
+	w := do(http.MethodGet, "/new?dir=~/src/app&prompt="+url.QueryEscape(`fix <b>"it"</b>`), "cross-site", owner)
+	body := w.Body.String()
+	if w.Code != http.StatusOK || !strings.Contains(body, `action="/kojo/new"`) ||
+		!strings.Contains(body, "fix &lt;b&gt;&#34;it&#34;&lt;/b&gt;") || !strings.Contains(body, "Start a claude session?") {
+		t.Errorf("cross-site GET: status %d, body:\n%s", w.Code, body)
+	}
+	if csp := w.Header().Get("Content-Security-Policy"); !strings.Contains(csp, "form-action 'self'") {
+		t.Errorf("CSP = %q", csp)
+	}
+	// Typed or bookmarked, it still only asks: a GET never creates.
+	for _, site := range []string{"", "none", "same-origin"} {
+		if w := do(http.MethodGet, "/new", site, owner); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "<form") {
+			t.Errorf("GET with Sec-Fetch-Site %q: status %d, want the confirm page", site, w.Code)
+		}
+	}
+	if !createsSession(httptest.NewRequest(http.MethodPost, "/new", nil)) || createsSession(httptest.NewRequest(http.MethodGet, "/new", nil)) {
+		t.Error("POST /new must be drained and rate limited as a session create, GET /new not")
+	}
+
+	if w := do(http.MethodPost, "/new", "cross-site", owner); w.Code != http.StatusForbidden {
+		t.Errorf("cross-site POST: status %d, want 403", w.Code)
+	}
+}
+
+func TestSameOriginForm(t *testing.T) {
+	for _, tc := range []struct {
+		site, origin string
+		want         bool
+	}{
+		{"same-origin", "", true},
+		{"same-site", "http://example.com", false},
+		{"cross-site", "http://example.com", false},
+		{"", "http://example.com", true},
+		{"", "http://evil.test", false},
+		{"", "", false},
+	} {
+		r := httptest.NewRequest(http.MethodPost, "http://example.com/new", nil)
+		if tc.site != "" {
+			r.Header.Set("Sec-Fetch-Site", tc.site)
+		}
+		if tc.origin != "" {
+			r.Header.Set("Origin", tc.origin)
+		}
+		if got := sameOriginForm(r); got != tc.want {
+			t.Errorf("site %q origin %q: %v, want %v", tc.site, tc.origin, got, tc.want)
+		}
+	}
+}
+
+func TestExpandLinkDir(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip(err)
+	}
+	for in, want := range map[string]string{
+		"~":          home,
+		"~/src/app":  filepath.Join(home, "src/app"),
+		"~bob/src":   "~bob/src",
+		"/srv/~/app": "/srv/~/app",
+	} {
+		if got := expandLinkDir(in); got != want {
+			t.Errorf("expandLinkDir(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
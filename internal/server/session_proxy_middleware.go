@@ -24,7 +24,11 @@ import (
 //     /api/v1/files/raw                              file browser tab
 //   - /api/v1/upload                                 file attach
 //   - /api/v1/git/status, /api/v1/git/log,
-//     /api/v1/git/diff, /api/v1/git/exec             git tab
+//     /api/v1/git/diff, /api/v1/git/show,
+//     /api/v1/git/file, /api/v1/git/exec,
+//     /api/v1/git/{stage,unstage,discard,commit},
+//     /api/v1/git/worktrees, /api/v1/git/prs,
+//     /api/v1/git/pr-checks, /api/v1/git/repos       git tab
 //
 // Loop prevention: a peer-signed inbound request never re-proxies.
 // Missing `?peer=` or `?peer=self` falls through to the local
@@ -59,7 +63,11 @@ func isPeerProxyPath(p string) bool {
 	switch p {
 	case "/api/v1/info", "/api/v1/dirs", "/api/v1/upload",
 		"/api/v1/files", "/api/v1/files/view", "/api/v1/files/raw",
-		"/api/v1/git/status", "/api/v1/git/log", "/api/v1/git/diff", "/api/v1/git/exec":
+		"/api/v1/git/status", "/api/v1/git/log", "/api/v1/git/diff", "/api/v1/git/exec",
+		"/api/v1/git/show", "/api/v1/git/file",
+		"/api/v1/git/stage", "/api/v1/git/unstage", "/api/v1/git/discard", "/api/v1/git/commit",
+		"/api/v1/git/worktrees", "/api/v1/git/prs", "/api/v1/git/pr-checks",
+		"/api/v1/git/repos":
 		return true
 	}
 	return false
@@ -111,14 +119,16 @@ func (s *Server) proxySessionRequest(w http.ResponseWriter, r *http.Request, pee
 	// Response headers preserve every field a browser keys off — raw
 	// file downloads need Content-Disposition + Content-Length to land
 	// as proper saves; the body is streamed, not buffered, so big
-	// downloads aren't silently truncated.
+	// downloads aren't silently truncated. Range / If-Range and the
+	// matching Accept-Ranges / Content-Range let a media player seek
+	// through a file on a remote peer the same as a local one.
 	s.forwardHTTPToPeer(w, r.Context(), peerHTTPForward{
 		method:         r.Method,
 		url:            target,
 		body:           r.Body,
 		contentLength:  r.ContentLength,
 		srcHeader:      r.Header,
-		reqHeaderKeys:  []string{"Content-Type", "If-Match", "Idempotency-Key"},
+		reqHeaderKeys:  []string{"Content-Type", "If-Match", "Idempotency-Key", "Range", "If-Range"},
 		timeout:        0,
 		buildErrStatus: http.StatusBadGateway,
 		buildErrCode:   "proxy_build",
@@ -129,6 +139,8 @@ func (s *Server) proxySessionRequest(w http.ResponseWriter, r *http.Request, pee
 			"Content-Type", "ETag",
 			"Content-Disposition", "Content-Length",
 			"Last-Modified", "Cache-Control",
+			"Accept-Ranges", "Content-Range",
+			"Content-Security-Policy", "X-Content-Type-Options",
 		},
 	})
 }
@@ -0,0 +1,199 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/loppo-llc/kojo/internal/session"
+)
+
+const (
+	// webhookAttempts is how many times a delivery is tried, waiting
+	// webhookRetryDelay, doubled each time, in between.
+	webhookAttempts   = 4
+	webhookRetryDelay = 2 * time.Second
+	// webhookOutputLines is how many trailing output lines a delivery
+	// carries.
+	webhookOutputLines = 20
+)
+
+var webhookHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// handleListWebhooks GET /api/v1/sessions/{id}/webhooks
+func (s *Server) handleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	sess, ok := s.sessions.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "session not found: "+id)
+		return
+	}
+	hooks := sess.Webhooks()
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]any{"webhooks": hooks})
+}
+
+// handleCreateWebhook POST /api/v1/sessions/{id}/webhooks
+//
+// Body: {"url":"https://ci.example/hook","events":["exit","match"],
+// "pattern":"All tests passed","secret":"..."}. The URL is POSTed to
+// when the session exits and, once, when a line of output matches the
+// pattern; with a secret each delivery carries X-Kojo-Signature. An
+// exit webhook added to a session that has already exited fires right
+// away, so a CI job can't miss the exit.
+func (s *Server) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	sess, ok := s.sessions.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "session not found: "+id)
+		return
+	}
+	var req struct {
+		URL     string   `json:"url"`
+		Events  []string `json:"events"`
+		Pattern string   `json:"pattern"`
+		Secret  string   `json:"secret"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid request body")
+		return
+	}
+	h, err := sess.AddWebhook(session.Webhook{
+		URL:     req.URL,
+		Events:  req.Events,
+		Pattern: req.Pattern,
+		Secret:  req.Secret,
+		BaseURL: requestBaseURL(r),
+	})
+	if err != nil {
+		writeSessionError(w, err, http.StatusBadRequest)
+		return
+	}
+	s.sessions.NotifyUpdated(sess)
+	if sess.Info().Status == session.StatusExited {
+		for _, ev := range h.Events {
+			if ev == session.WebhookExit {
+				go s.deliverWebhook(sess, session.WebhookHit{Webhook: h, Event: session.WebhookExit})
+			}
+		}
+	}
+	h.Secret = ""
+	writeJSONResponse(w, http.StatusCreated, h)
+}
+
+// handleDeleteWebhook DELETE /api/v1/sessions/{id}/webhooks/{webhookId}
+func (s *Server) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	sess, ok := s.sessions.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "session not found: "+id)
+		return
+	}
+	if err := sess.RemoveWebhook(r.PathValue("webhookId")); err != nil {
+		writeSessionError(w, err, http.StatusBadRequest)
+		return
+	}
+	s.sessions.NotifyUpdated(sess)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requestBaseURL is the origin r reached kojo at, as the client saw
+// it.
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https") {
+		scheme = "https"
+	}
+	host := r.Host
+	if fwd := r.Header.Get("X-Forwarded-Host"); fwd != "" {
+		host = strings.TrimSpace(strings.Split(fwd, ",")[0])
+	}
+	return scheme + "://" + host
+}
+
+// deliverWebhook POSTs hit to its webhook, retrying failures and 5xx
+// answers with backoff.
+func (s *Server) deliverWebhook(sess *session.Session, hit session.WebhookHit) {
+	info := sess.Info()
+	base := hit.Webhook.BaseURL + s.basePath
+	payload := map[string]any{
+		"event":         hit.Event,
+		"webhookId":     hit.Webhook.ID,
+		"sessionId":     info.ID,
+		"tool":          info.Tool,
+		"workDir":       info.WorkDir,
+		"title":         info.Title,
+		"status":        info.Status,
+		"exitCode":      info.ExitCode,
+		"output":        sess.TailLines(webhookOutputLines),
+		"sessionUrl":    hit.Webhook.BaseURL + s.sessionURL(info.ID),
+		"transcriptUrl": base + "/api/v1/sessions/" + url.PathEscape(info.ID) + "/export",
+		"timestamp":     time.Now().Format(time.RFC3339),
+	}
+	if hit.Event == session.WebhookMatch {
+		payload["line"] = hit.Line
+		payload["pattern"] = hit.Webhook.Pattern
+	}
+	body, _ := json.Marshal(payload)
+
+	delay := webhookRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := postWebhook(hit.Webhook, body)
+		if err == nil {
+			s.logger.Debug("webhook delivered", "session", info.ID, "webhook", hit.Webhook.ID, "event", hit.Event)
+			return
+		}
+		if attempt == webhookAttempts || errors.Is(err, errWebhookRejected) {
+			s.logger.Warn("webhook delivery failed", "session", info.ID, "webhook", hit.Webhook.ID,
+				"event", hit.Event, "attempts", attempt, "err", err)
+			return
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// errWebhookRejected is a 4xx answer other than 429: retrying the same
+// body won't help.
+var errWebhookRejected = errors.New("webhook rejected the delivery")
+
+// postWebhook makes one delivery attempt.
+func postWebhook(h session.Webhook, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookHTTPClient.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "kojo-webhook")
+	req.Header.Set("X-Kojo-Webhook", h.ID)
+	if h.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(h.Secret))
+		mac.Write(body)
+		req.Header.Set("X-Kojo-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := webhookHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	case resp.StatusCode >= 400:
+		return fmt.Errorf("%w: %s", errWebhookRejected, resp.Status)
+	}
+	return nil
+}
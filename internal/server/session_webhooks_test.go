@@ -0,0 +1,46 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/loppo-llc/kojo/internal/session"
+)
+
+func TestPostWebhook(t *testing.T) {
+	status := http.StatusOK
+	var gotSig string
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSig = r.Header.Get("X-Kojo-Signature")
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	h := session.Webhook{ID: "wh_1", URL: srv.URL, Secret: "s3cret"}
+	body := []byte(`{"event":"exit"}`)
+	if err := postWebhook(h, body); err != nil {
+		t.Fatal(err)
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); gotSig != want || string(gotBody) != string(body) {
+		t.Errorf("signature %q, body %q; want %q", gotSig, gotBody, want)
+	}
+
+	status = http.StatusBadGateway
+	if err := postWebhook(h, body); err == nil || errors.Is(err, errWebhookRejected) {
+		t.Errorf("5xx: %v, want a retryable error", err)
+	}
+	status = http.StatusNotFound
+	if err := postWebhook(h, body); !errors.Is(err, errWebhookRejected) {
+		t.Errorf("404: %v, want errWebhookRejected", err)
+	}
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/loppo-llc/kojo/internal/session"
+	"github.com/loppo-llc/kojo/internal/snippet"
+)
+
+// writeSnippetError maps a snippet.Store error to its response.
+func writeSnippetError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, snippet.ErrNotFound):
+		writeError(w, http.StatusNotFound, "not_found", err.Error())
+	case errors.Is(err, snippet.ErrInvalid):
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+	}
+}
+
+// handleListSnippets GET /api/v1/snippets
+func (s *Server) handleListSnippets(w http.ResponseWriter, r *http.Request) {
+	writeJSONResponse(w, http.StatusOK, map[string]any{"snippets": s.snippets.List()})
+}
+
+// handleCreateSnippet POST /api/v1/snippets
+//
+// Body: {"name":"review","text":"Review the diff on {branch}"}.
+func (s *Server) handleCreateSnippet(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid request body")
+		return
+	}
+	sn, err := s.snippets.Create(req.Name, req.Text)
+	if err != nil {
+		writeSnippetError(w, err)
+		return
+	}
+	writeJSONResponse(w, http.StatusCreated, sn)
+}
+
+// handleUpdateSnippet PATCH /api/v1/snippets/{id}
+//
+// Body: {"name":...} and/or {"text":...}.
+func (s *Server) handleUpdateSnippet(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name *string `json:"name"`
+		Text *string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid request body")
+		return
+	}
+	sn, err := s.snippets.Update(r.PathValue("id"), req.Name, req.Text)
+	if err != nil {
+		writeSnippetError(w, err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, sn)
+}
+
+// handleDeleteSnippet DELETE /api/v1/snippets/{id}
+func (s *Server) handleDeleteSnippet(w http.ResponseWriter, r *http.Request) {
+	if err := s.snippets.Delete(r.PathValue("id")); err != nil {
+		writeSnippetError(w, err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+// handleRenderSnippet POST /api/v1/snippets/{id}/render
+//
+// Body: {"sessionId":"s_...","insert":true,"pin":"..."}, all optional.
+// Returns the snippet's text with its template variables filled in for
+// the session ({workDir}, {branch}, {tool}, {sessionId}) and {date}.
+// With insert the text is also pasted into the session, which needs
+// the PIN when its input is locked; "inserted" reports the paste.
+func (s *Server) handleRenderSnippet(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		SessionID string `json:"sessionId"`
+		Insert    bool   `json:"insert"`
+		PIN       string `json:"pin"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "bad_request", "invalid request body")
+			return
+		}
+	}
+	sn, err := s.snippets.Get(r.PathValue("id"))
+	if err != nil {
+		writeSnippetError(w, err)
+		return
+	}
+	if req.SessionID == "" {
+		if req.Insert {
+			writeError(w, http.StatusBadRequest, "bad_request", "insert needs sessionId")
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]any{"text": snippet.Render(sn.Text, snippet.Vars{})})
+		return
+	}
+
+	sess, ok := s.sessions.Get(req.SessionID)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "session not found: "+req.SessionID)
+		return
+	}
+	info := sess.Info()
+	vars := snippet.Vars{WorkDir: info.WorkDir, Tool: info.Tool, SessionID: info.ID}
+	if info.WorkDir != "" && info.Host == "" {
+		vars.Branch, _ = s.git.Branch(info.WorkDir)
+	}
+	text := snippet.Render(sn.Text, vars)
+	if !req.Insert {
+		writeJSONResponse(w, http.StatusOK, map[string]any{"text": text})
+		return
+	}
+	if err := sess.CheckInputPIN(req.PIN); err != nil && !errors.Is(err, session.ErrNotInputLocked) {
+		writeSessionError(w, err, http.StatusInternalServerError)
+		return
+	}
+	if info.Status != session.StatusRunning {
+		writeError(w, http.StatusConflict, "conflict", "session not running: "+info.ID)
+		return
+	}
+	if err := sess.Paste(text); err != nil {
+		writeError(w, http.StatusConflict, "conflict", err.Error())
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]any{"text": text, "inserted": true})
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// sseWriter streams Server-Sent Events. Headers go out lazily with the
+// first event, so a handler can still answer with a plain JSON error
+// when the stream fails before producing anything (started == false).
+type sseWriter struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	started bool
+}
+
+func newSSEWriter(w http.ResponseWriter) *sseWriter {
+	return &sseWriter{w: w, rc: http.NewResponseController(w)}
+}
+
+func (s *sseWriter) start() {
+	if s.started {
+		return
+	}
+	s.started = true
+	s.w.Header().Set("Content-Type", "text/event-stream")
+	s.w.Header().Set("Cache-Control", "no-store")
+	// Stop nginx-style reverse proxies from buffering the stream.
+	s.w.Header().Set("X-Accel-Buffering", "no")
+	s.w.WriteHeader(http.StatusOK)
+}
+
+// event writes one named event with v JSON-encoded as its data.
+func (s *sseWriter) event(name string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s.start()
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", name, data); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// keepalive writes a comment line. It keeps idle proxies from reaping
+// the stream and surfaces a dead client as a write error.
+func (s *sseWriter) keepalive() error {
+	s.start()
+	if _, err := io.WriteString(s.w, ": keepalive\n\n"); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
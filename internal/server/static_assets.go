@@ -0,0 +1,94 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+)
+
+// staticEncodings are the precompressed variants the web build writes
+// next to each compressible asset, in order of preference.
+var staticEncodings = []struct{ name, ext string }{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// staticAssets serves the embedded web UI with ETags, and a file's
+// precompressed .br / .gz variant when the client accepts it. The
+// embedded FS has no modification times, so without an ETag a browser
+// can't revalidate a no-cache file and downloads it again.
+type staticAssets struct {
+	fsys fs.FS
+
+	mu    sync.Mutex
+	etags map[string]string // file name → quoted content hash
+}
+
+func newStaticAssets(fsys fs.FS) *staticAssets {
+	return &staticAssets{fsys: fsys, etags: map[string]string{}}
+}
+
+// isFile reports whether name is a regular file in the FS.
+func (a *staticAssets) isFile(name string) bool {
+	fi, err := fs.Stat(a.fsys, name)
+	return err == nil && !fi.IsDir()
+}
+
+// serve writes the file name, or the best variant of it r accepts.
+func (a *staticAssets) serve(w http.ResponseWriter, r *http.Request, name string) {
+	w.Header().Add("Vary", "Accept-Encoding")
+	file, encoding := name, ""
+	for _, e := range staticEncodings {
+		if acceptsEncoding(r, e.name) && a.isFile(name+e.ext) {
+			file, encoding = name+e.ext, e.name
+			break
+		}
+	}
+	data, err := fs.ReadFile(a.fsys, file)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	h := w.Header()
+	if ct := mime.TypeByExtension(path.Ext(name)); ct != "" {
+		h.Set("Content-Type", ct)
+	} else if encoding != "" {
+		h.Set("Content-Type", "application/octet-stream")
+	}
+	if encoding != "" {
+		h.Set("Content-Encoding", encoding)
+	}
+	h.Set("ETag", a.etag(file, data))
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(data))
+}
+
+// etag returns the ETag of file, hashing data on first use.
+func (a *staticAssets) etag(file string, data []byte) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if tag, ok := a.etags[file]; ok {
+		return tag
+	}
+	tag := contentETag(data)
+	a.etags[file] = tag
+	return tag
+}
+
+// contentETag is a strong ETag for data.
+func contentETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:12]) + `"`
+}
+
+// serveBytes writes data, generated once at startup, as name with an
+// ETag, so it revalidates like a file.
+func serveBytes(w http.ResponseWriter, r *http.Request, name, etag string, data []byte) {
+	w.Header().Set("ETag", etag)
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(data))
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestStaticAssets(t *testing.T) {
+	a := newStaticAssets(fstest.MapFS{
+		"assets/app.js":    {Data: []byte("console.log(1)")},
+		"assets/app.js.br": {Data: []byte("br bytes")},
+		"assets/app.js.gz": {Data: []byte("gz bytes")},
+		"favicon.svg":      {Data: []byte("<svg/>")},
+	})
+	get := func(name, accept, ifNoneMatch string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/"+name, nil)
+		req.Header.Set("Accept-Encoding", accept)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		a.serve(rec, req, name)
+		return rec
+	}
+
+	cases := []struct {
+		accept, wantEncoding, wantBody string
+	}{
+		{"gzip, deflate, br", "br", "br bytes"},
+		{"gzip", "gzip", "gz bytes"},
+		{"br;q=0, gzip", "gzip", "gz bytes"},
+		{"", "", "console.log(1)"},
+	}
+	etags := map[string]bool{}
+	for _, tc := range cases {
+		rec := get("assets/app.js", tc.accept, "")
+		if got := rec.Header().Get("Content-Encoding"); got != tc.wantEncoding {
+			t.Errorf("Accept-Encoding %q: Content-Encoding = %q, want %q", tc.accept, got, tc.wantEncoding)
+		}
+		if rec.Body.String() != tc.wantBody {
+			t.Errorf("Accept-Encoding %q: body = %q", tc.accept, rec.Body.String())
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "text/javascript; charset=utf-8" {
+			t.Errorf("Accept-Encoding %q: Content-Type = %q", tc.accept, ct)
+		}
+		if rec.Header().Get("Vary") != "Accept-Encoding" {
+			t.Errorf("Accept-Encoding %q: Vary = %q", tc.accept, rec.Header().Get("Vary"))
+		}
+		etags[rec.Header().Get("ETag")] = true
+	}
+	if len(etags) != 3 {
+		t.Errorf("variants share ETags: %v", etags)
+	}
+
+	first := get("favicon.svg", "", "")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("status = %d, ETag = %q", first.Code, etag)
+	}
+	if rec := get("favicon.svg", "", etag); rec.Code != http.StatusNotModified {
+		t.Errorf("revalidation status = %d, want 304", rec.Code)
+	}
+	if rec := get("favicon.svg", "", `"stale"`); rec.Code != http.StatusOK {
+		t.Errorf("stale revalidation status = %d, want 200", rec.Code)
+	}
+}
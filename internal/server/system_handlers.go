@@ -174,6 +174,68 @@ func (s *Server) SetRestartTrigger(fn func() bool) {
 	s.restartMu.Unlock()
 }
 
+// SetReloadTrigger wires the config-reload callback behind POST
+// /api/v1/system/reload. cmd/kojo passes the same closure it runs on
+// SIGHUP: re-read the config file and environment, then hand the
+// runtime-changeable settings to ApplySettings. When never set (tests),
+// the handler returns 501.
+func (s *Server) SetReloadTrigger(fn func() error) {
+	s.reloadMu.Lock()
+	s.reloadTrigger = fn
+	s.reloadMu.Unlock()
+}
+
+// Settings are the server options a config reload may change without a
+// restart; live sessions and connections are untouched.
+type Settings struct {
+	FileRoots    []string
+	GitExecAllow []string
+	GitExecDeny  []string
+	RateLimits   RateLimits
+	WSLimits     WSLimits
+	UploadTTL    time.Duration
+	UploadQuota  int64
+}
+
+// ApplySettings swaps in reloaded settings. Requests already in flight
+// finish under the old values.
+func (s *Server) ApplySettings(st Settings) {
+	s.files.SetRoots(st.FileRoots)
+	s.git.SetExecPolicy(st.GitExecAllow, st.GitExecDeny)
+	if s.limits != nil {
+		s.limits.set(st.RateLimits)
+	}
+	if s.wsLimits != nil {
+		s.wsLimits.set(st.WSLimits)
+	}
+	s.uploads.set(st.UploadTTL, st.UploadQuota)
+}
+
+// handleSystemReload POST /api/v1/system/reload
+//
+// Re-reads the config file and KOJO_* environment, like SIGHUP. Owner
+// only. 400 invalid_config when the file fails to parse or validate, in
+// which case nothing is applied.
+func (s *Server) handleSystemReload(w http.ResponseWriter, r *http.Request) {
+	if !auth.FromContext(r.Context()).IsOwner() {
+		writeError(w, http.StatusForbidden, "forbidden", "reload requires Owner")
+		return
+	}
+	s.reloadMu.Lock()
+	trigger := s.reloadTrigger
+	s.reloadMu.Unlock()
+	if trigger == nil {
+		writeError(w, http.StatusNotImplemented, "unsupported",
+			"config reload is not supported in this run mode")
+		return
+	}
+	if err := trigger(); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_config", err.Error())
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]string{"status": "reloaded"})
+}
+
 // handleSystemRestart POST /api/v1/system/restart
 //
 // Gracefully restarts the daemon: quiesce new chats → wait for every
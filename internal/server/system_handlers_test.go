@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
@@ -94,6 +95,34 @@ func TestSystemRestart_UnsupportedWithoutTrigger(t *testing.T) {
 	}
 }
 
+func TestSystemReload(t *testing.T) {
+	srv := &Server{logger: slog.Default()}
+	reload := func(p auth.Principal) int {
+		rr := httptest.NewRecorder()
+		srv.handleSystemReload(rr, authedRequest(
+			httptest.NewRequest(http.MethodPost, "/api/v1/system/reload", nil), p))
+		return rr.Code
+	}
+	owner := auth.Principal{Role: auth.RoleOwner}
+
+	if code := reload(owner); code != http.StatusNotImplemented {
+		t.Errorf("without trigger: status = %d, want 501", code)
+	}
+	var err error
+	calls := 0
+	srv.SetReloadTrigger(func() error { calls++; return err })
+	if code := reload(auth.Principal{Role: auth.RoleAgent, AgentID: "ag_x"}); code != http.StatusForbidden || calls != 0 {
+		t.Errorf("agent: status = %d, calls = %d, want 403 without reloading", code, calls)
+	}
+	if code := reload(owner); code != http.StatusOK || calls != 1 {
+		t.Errorf("owner: status = %d, calls = %d, want 200 after one reload", code, calls)
+	}
+	err = errors.New("parse config.json: bad")
+	if code := reload(owner); code != http.StatusBadRequest {
+		t.Errorf("failing reload: status = %d, want 400", code)
+	}
+}
+
 func TestSystemRestart_PrivAgentTriggersAfterDrain(t *testing.T) {
 	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
 	srv := newChunkedSyncTestServer(t)
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/mitchellh/go-ps"
+
+	"github.com/loppo-llc/kojo/internal/auth"
+	"github.com/loppo-llc/kojo/internal/configdir"
+	"github.com/loppo-llc/kojo/internal/session"
+)
+
+// LoadAvg is the 1, 5 and 15 minute load average.
+type LoadAvg struct {
+	Load1  float64 `json:"load1"`
+	Load5  float64 `json:"load5"`
+	Load15 float64 `json:"load15"`
+}
+
+// MemoryStats is physical memory in bytes. Available is what can be
+// handed to new processes without swapping, cache included.
+type MemoryStats struct {
+	Total     uint64 `json:"total"`
+	Available uint64 `json:"available"`
+}
+
+// DiskStats is the space on the volume holding Path, in bytes. Free
+// is what an unprivileged user can still write.
+type DiskStats struct {
+	Path  string `json:"path"`
+	Total uint64 `json:"total"`
+	Free  uint64 `json:"free"`
+}
+
+// handleSystemStats GET /api/v1/system
+//
+// A quick "is the machine okay" view: load average and CPU count,
+// memory, free space on the volumes kojo works on (home, config dir,
+// temp dir and file browser roots, one entry per volume), and how many
+// processes each supported tool has running on the host next to how
+// many of them are kojo sessions. Load and memory are left out where
+// the platform doesn't offer them. Owner only.
+func (s *Server) handleSystemStats(w http.ResponseWriter, r *http.Request) {
+	if !auth.FromContext(r.Context()).IsOwner() {
+		writeError(w, http.StatusForbidden, "forbidden", "system stats require Owner")
+		return
+	}
+	hostname, _ := os.Hostname()
+	resp := map[string]any{
+		"hostname": hostname,
+		"os":       runtime.GOOS,
+		"cpus":     runtime.NumCPU(),
+		"disks":    s.diskStats(),
+	}
+	if load, err := loadAvg(); err == nil {
+		resp["load"] = load
+	} else {
+		s.logger.Debug("load average unavailable", "err", err)
+	}
+	if mem, err := memoryStats(); err == nil {
+		resp["memory"] = mem
+	} else {
+		s.logger.Debug("memory stats unavailable", "err", err)
+	}
+
+	tools := map[string]bool{}
+	for tool := range session.ToolAvailability() {
+		if tool != "custom" { // runs as claude
+			tools[tool] = true
+		}
+	}
+	procs, err := ps.Processes()
+	if err != nil {
+		s.logger.Debug("process list unavailable", "err", err)
+	} else {
+		resp["processes"] = countToolProcesses(procs, tools)
+	}
+	running := map[string]int{}
+	if s.sessions != nil {
+		for _, sess := range s.sessions.List() {
+			if info := sess.Info(); !info.Internal && (info.Status == session.StatusRunning || info.Status == session.StatusPaused) {
+				running[info.Tool]++
+			}
+		}
+	}
+	resp["sessions"] = running
+	writeJSONResponse(w, http.StatusOK, resp)
+}
+
+// countToolProcesses counts procs by executable name, for the names in
+// tools. Names are compared without case or a .exe suffix.
+func countToolProcesses(procs []ps.Process, tools map[string]bool) map[string]int {
+	counts := make(map[string]int, len(tools))
+	for tool := range tools {
+		counts[tool] = 0
+	}
+	for _, p := range procs {
+		name := strings.TrimSuffix(strings.ToLower(p.Executable()), ".exe")
+		if tools[name] {
+			counts[name]++
+		}
+	}
+	return counts
+}
+
+// diskStats reports the volumes under the paths kojo reads and writes,
+// skipping paths that can't be read and repeats of a volume already
+// listed.
+func (s *Server) diskStats() []DiskStats {
+	paths := []string{configdir.Path(), os.TempDir()}
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append([]string{home}, paths...)
+	}
+	if s.files != nil {
+		paths = append(paths, s.files.Roots()...)
+	}
+	disks := []DiskStats{}
+	var seen []uint64
+	for _, p := range paths {
+		p = filepath.Clean(p)
+		d, vol, err := diskUsage(p)
+		if err != nil {
+			continue
+		}
+		if slices.Contains(seen, vol) {
+			continue
+		}
+		seen = append(seen, vol)
+		disks = append(disks, d)
+	}
+	return disks
+}
@@ -0,0 +1,49 @@
+//go:build darwin
+
+package server
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+func loadAvg() (LoadAvg, error) {
+	// struct loadavg { fixpt_t ldavg[3]; long fscale; }
+	b, err := unix.SysctlRaw("vm.loadavg")
+	if err != nil {
+		return LoadAvg{}, err
+	}
+	if len(b) < 24 {
+		return LoadAvg{}, fmt.Errorf("vm.loadavg: %d bytes", len(b))
+	}
+	scale := float64(binary.LittleEndian.Uint64(b[16:24]))
+	if scale == 0 {
+		return LoadAvg{}, fmt.Errorf("vm.loadavg: zero fscale")
+	}
+	ld := func(i int) float64 { return float64(binary.LittleEndian.Uint32(b[i*4:])) / scale }
+	return LoadAvg{Load1: ld(0), Load5: ld(1), Load15: ld(2)}, nil
+}
+
+func memoryStats() (MemoryStats, error) {
+	total, err := unix.SysctlUint64("hw.memsize")
+	if err != nil {
+		return MemoryStats{}, err
+	}
+	pageSize, err := unix.SysctlUint32("vm.pagesize")
+	if err != nil {
+		return MemoryStats{}, err
+	}
+	// Free plus purgeable and speculative pages, roughly what Activity
+	// Monitor counts as available without the file cache.
+	var avail uint64
+	for _, name := range []string{"vm.page_free_count", "vm.page_purgeable_count", "vm.page_speculative_count"} {
+		n, err := unix.SysctlUint32(name)
+		if err != nil {
+			return MemoryStats{}, err
+		}
+		avail += uint64(n) * uint64(pageSize)
+	}
+	return MemoryStats{Total: total, Available: avail}, nil
+}
@@ -0,0 +1,75 @@
+//go:build linux
+
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func loadAvg() (LoadAvg, error) {
+	b, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return LoadAvg{}, err
+	}
+	return parseLoadAvg(string(b))
+}
+
+// parseLoadAvg reads /proc/loadavg: "0.52 0.58 0.59 1/467 12345".
+func parseLoadAvg(s string) (LoadAvg, error) {
+	f := strings.Fields(s)
+	if len(f) < 3 {
+		return LoadAvg{}, fmt.Errorf("loadavg: unexpected %q", s)
+	}
+	var v [3]float64
+	for i := range v {
+		x, err := strconv.ParseFloat(f[i], 64)
+		if err != nil {
+			return LoadAvg{}, fmt.Errorf("loadavg: %w", err)
+		}
+		v[i] = x
+	}
+	return LoadAvg{Load1: v[0], Load5: v[1], Load15: v[2]}, nil
+}
+
+func memoryStats() (MemoryStats, error) {
+	b, err := os.ReadFile("/proc/meminfo")
+	if err != nil {
+		return MemoryStats{}, err
+	}
+	return parseMemInfo(string(b))
+}
+
+// parseMemInfo reads MemTotal and MemAvailable from /proc/meminfo,
+// whose values are in kB.
+func parseMemInfo(s string) (MemoryStats, error) {
+	var m MemoryStats
+	sc := bufio.NewScanner(strings.NewReader(s))
+	for sc.Scan() {
+		name, rest, ok := strings.Cut(sc.Text(), ":")
+		if !ok {
+			continue
+		}
+		var dst *uint64
+		switch name {
+		case "MemTotal":
+			dst = &m.Total
+		case "MemAvailable":
+			dst = &m.Available
+		default:
+			continue
+		}
+		kb, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimSpace(rest), " kB"), 10, 64)
+		if err != nil {
+			return MemoryStats{}, fmt.Errorf("meminfo %s: %w", name, err)
+		}
+		*dst = kb * 1024
+	}
+	if m.Total == 0 {
+		return MemoryStats{}, fmt.Errorf("meminfo: no MemTotal")
+	}
+	return m, nil
+}
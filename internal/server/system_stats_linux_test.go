@@ -0,0 +1,17 @@
+package server
+
+import "testing"
+
+func TestParseProcStats(t *testing.T) {
+	load, err := parseLoadAvg("0.52 1.58 2.00 1/467 12345\n")
+	if err != nil || load != (LoadAvg{0.52, 1.58, 2}) {
+		t.Errorf("parseLoadAvg = %+v, %v", load, err)
+	}
+	mem, err := parseMemInfo("MemTotal:       16384000 kB\nMemFree:         1000 kB\nMemAvailable:    8192000 kB\n")
+	if err != nil || mem != (MemoryStats{Total: 16384000 * 1024, Available: 8192000 * 1024}) {
+		t.Errorf("parseMemInfo = %+v, %v", mem, err)
+	}
+	if _, err := parseMemInfo("MemFree: 1 kB\n"); err == nil {
+		t.Error("parseMemInfo without MemTotal: want error")
+	}
+}
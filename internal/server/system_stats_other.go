@@ -0,0 +1,16 @@
+//go:build !linux && !darwin && !windows
+
+package server
+
+import (
+	"errors"
+	"runtime"
+)
+
+func loadAvg() (LoadAvg, error) {
+	return LoadAvg{}, errors.New("load average not supported on " + runtime.GOOS)
+}
+
+func memoryStats() (MemoryStats, error) {
+	return MemoryStats{}, errors.New("memory stats not supported on " + runtime.GOOS)
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mitchellh/go-ps"
+
+	"github.com/loppo-llc/kojo/internal/auth"
+)
+
+type fakeProcess string
+
+func (p fakeProcess) Pid() int           { return 1 }
+func (p fakeProcess) PPid() int          { return 0 }
+func (p fakeProcess) Executable() string { return string(p) }
+
+func TestCountToolProcesses(t *testing.T) {
+	procs := []ps.Process{fakeProcess("claude"), fakeProcess("Claude.exe"), fakeProcess("codex"), fakeProcess("bash")}
+	got := countToolProcesses(procs, map[string]bool{"claude": true, "codex": true, "grok": true})
+	want := map[string]int{"claude": 2, "codex": 1, "grok": 0}
+	if !maps.Equal(got, want) {
+		t.Errorf("counts = %v, want %v", got, want)
+	}
+}
+
+func TestHandleSystemStats(t *testing.T) {
+	srv := &Server{logger: slog.Default()}
+	rr := httptest.NewRecorder()
+	srv.handleSystemStats(rr, authedRequest(httptest.NewRequest(http.MethodGet, "/api/v1/system", nil), auth.Principal{Role: auth.RoleAgent, AgentID: "ag_1"}))
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("agent: status = %d, want 403", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	srv.handleSystemStats(rr, authedRequest(httptest.NewRequest(http.MethodGet, "/api/v1/system", nil), auth.Principal{Role: auth.RoleOwner}))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("owner: status = %d: %s", rr.Code, rr.Body)
+	}
+	var resp struct {
+		CPUs  int         `json:"cpus"`
+		Disks []DiskStats `json:"disks"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.CPUs < 1 || len(resp.Disks) == 0 || resp.Disks[0].Total == 0 {
+		t.Errorf("response = %s", rr.Body)
+	}
+}
@@ -0,0 +1,34 @@
+//go:build !windows
+
+package server
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// diskUsage returns the space on path's volume and the volume's device
+// number.
+func diskUsage(path string) (DiskStats, uint64, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return DiskStats{}, 0, err
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return DiskStats{}, 0, err
+	}
+	sys, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return DiskStats{}, 0, fmt.Errorf("stat %s: no device number", path)
+	}
+	bsize := uint64(st.Bsize)
+	return DiskStats{
+		Path:  path,
+		Total: uint64(st.Blocks) * bsize,
+		Free:  uint64(st.Bavail) * bsize,
+	}, uint64(sys.Dev), nil
+}
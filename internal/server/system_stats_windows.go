@@ -0,0 +1,59 @@
+//go:build windows
+
+package server
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// diskUsage returns the space on path's volume and a number naming
+// the volume (its drive letter).
+func diskUsage(path string) (DiskStats, uint64, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return DiskStats{}, 0, err
+	}
+	var free, total, totalFree uint64
+	if err := windows.GetDiskFreeSpaceEx(p, &free, &total, &totalFree); err != nil {
+		return DiskStats{}, 0, err
+	}
+	var vol uint64
+	for _, c := range strings.ToUpper(filepath.VolumeName(path)) {
+		vol = vol*31 + uint64(c)
+	}
+	return DiskStats{Path: path, Total: total, Free: free}, vol, nil
+}
+
+// memoryStatusEx is MEMORYSTATUSEX.
+type memoryStatusEx struct {
+	Length               uint32
+	MemoryLoad           uint32
+	TotalPhys            uint64
+	AvailPhys            uint64
+	TotalPageFile        uint64
+	AvailPageFile        uint64
+	TotalVirtual         uint64
+	AvailVirtual         uint64
+	AvailExtendedVirtual uint64
+}
+
+var procGlobalMemoryStatusEx = windows.NewLazySystemDLL("kernel32.dll").NewProc("GlobalMemoryStatusEx")
+
+func memoryStats() (MemoryStats, error) {
+	ms := memoryStatusEx{}
+	ms.Length = uint32(unsafe.Sizeof(ms))
+	if r, _, err := procGlobalMemoryStatusEx.Call(uintptr(unsafe.Pointer(&ms))); r == 0 {
+		return MemoryStats{}, err
+	}
+	return MemoryStats{Total: ms.TotalPhys, Available: ms.AvailPhys}, nil
+}
+
+// loadAvg is unavailable: Windows has no load average.
+func loadAvg() (LoadAvg, error) {
+	return LoadAvg{}, errors.New("no load average on windows")
+}
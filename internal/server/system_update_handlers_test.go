@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"github.com/loppo-llc/kojo/internal/auth"
+	"github.com/loppo-llc/kojo/internal/filebrowser"
 	"github.com/loppo-llc/kojo/internal/selfupdate"
 )
 
@@ -431,3 +432,38 @@ func buildZip(t *testing.T, member string, payload []byte) []byte {
 	}
 	return buf.Bytes()
 }
+
+func TestInfoIncludesCachedUpdateStatus(t *testing.T) {
+	gh := releaseAPIServerWithAssets(t, "v0.2.0", nil)
+	t.Cleanup(gh.Close)
+
+	client := selfupdate.NewClient("v0.1.0")
+	client.BaseURL = gh.URL
+	client.HTTPClient = gh.Client()
+	checker := selfupdate.NewChecker(client, "v0.1.0", slog.New(slog.DiscardHandler))
+	srv := &Server{
+		logger:        slog.Default(),
+		version:       "v0.1.0",
+		files:         filebrowser.New(slog.Default(), filebrowser.Options{}),
+		updateChecker: checker,
+	}
+
+	info := func() map[string]any {
+		rr := httptest.NewRecorder()
+		srv.handleInfo(rr, newUpdateRequest(http.MethodGet, "/api/v1/info", nil,
+			auth.Principal{Role: auth.RoleOwner}))
+		var body map[string]any
+		readJSONResponse(t, rr, &body)
+		return body
+	}
+	if _, ok := info()["update"]; ok {
+		t.Fatal("update present before the first check")
+	}
+	if _, err := checker.CheckNow(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	update, _ := info()["update"].(map[string]any)
+	if update["latest"] != "v0.2.0" || update["updateAvailable"] != true {
+		t.Errorf("update = %v", update)
+	}
+}
@@ -0,0 +1,363 @@
+package server
+
+import (
+	"cmp"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// termStyle is the SGR state of a terminal cell.
+type termStyle struct {
+	fg, bg                               string // CSS colors, "" for the default
+	bold, dim, italic, underline, invert bool
+}
+
+type termCell struct {
+	r  rune
+	st termStyle
+}
+
+// termScreen replays terminal output into lines of styled cells. It
+// follows what tools do to redraw in place (carriage returns, cursor
+// moves within the screen, erasing lines, the alternate screen) so the
+// result reads like the terminal did, and ignores everything else.
+type termScreen struct {
+	lines      [][]termCell
+	row, col   int
+	top        int // first line of the visible screen
+	cols, rows int
+	st         termStyle
+	saved      *termScreen // main screen while the alternate one is up
+}
+
+// renderTerminal replays out on a cols×rows terminal.
+func renderTerminal(out []byte, cols, rows int) *termScreen {
+	t := &termScreen{cols: max(cols, 20), rows: max(rows, 5)}
+	for i := 0; i < len(out); {
+		b := out[i]
+		switch {
+		case b == 0x1b && i+1 < len(out):
+			i = t.escape(out, i+1)
+			continue
+		case b == '\r':
+			t.col = 0
+		case b == '\n':
+			t.moveRow(t.row + 1)
+		case b == '\b':
+			t.col = max(t.col-1, 0)
+		case b == '\t':
+			t.col = min((t.col/8+1)*8, t.cols-1)
+		case b < 0x20 || b == 0x7f:
+		default:
+			r, n := utf8.DecodeRune(out[i:])
+			t.put(r)
+			i += n
+			continue
+		}
+		i++
+	}
+	return t
+}
+
+// escape handles the sequence after the ESC at out[i-1] and returns the
+// index past it.
+func (t *termScreen) escape(out []byte, i int) int {
+	switch out[i] {
+	case '[':
+		j := i + 1
+		for j < len(out) && (out[j] < 0x40 || out[j] > 0x7e) {
+			j++
+		}
+		if j == len(out) {
+			return j
+		}
+		t.csi(string(out[i+1:j]), out[j])
+		return j + 1
+	case ']', 'P', '_', '^':
+		// OSC, DCS, APC and PM strings run to BEL or ST.
+		for j := i + 1; j < len(out); j++ {
+			if out[j] == 0x07 {
+				return j + 1
+			}
+			if out[j] == 0x1b && j+1 < len(out) && out[j+1] == '\\' {
+				return j + 2
+			}
+		}
+		return len(out)
+	case '(', ')', '*', '+', '#':
+		return min(i+2, len(out))
+	}
+	return i + 1
+}
+
+func (t *termScreen) csi(params string, final byte) {
+	if rest, ok := strings.CutPrefix(params, "?"); ok {
+		if final == 'h' || final == 'l' {
+			for _, p := range strings.Split(rest, ";") {
+				if p == "1049" || p == "1047" || p == "47" {
+					t.altScreen(final == 'h')
+				}
+			}
+		}
+		return
+	}
+	args := strings.Split(params, ";")
+	arg := func(k, def int) int {
+		if k < len(args) {
+			if n, err := strconv.Atoi(args[k]); err == nil && n > 0 {
+				return n
+			}
+		}
+		return def
+	}
+	switch final {
+	case 'm':
+		t.sgr(args)
+	case 'A':
+		t.row = max(t.row-arg(0, 1), t.top)
+	case 'B':
+		t.moveRow(t.row + arg(0, 1))
+	case 'C':
+		t.col = min(t.col+arg(0, 1), t.cols-1)
+	case 'D':
+		t.col = max(t.col-arg(0, 1), 0)
+	case 'E':
+		t.moveRow(t.row + arg(0, 1))
+		t.col = 0
+	case 'F':
+		t.row, t.col = max(t.row-arg(0, 1), t.top), 0
+	case 'G', '`':
+		t.col = min(arg(0, 1), t.cols) - 1
+	case 'd':
+		t.moveRow(t.top + min(arg(0, 1), t.rows) - 1)
+	case 'H', 'f':
+		t.moveRow(t.top + min(arg(0, 1), t.rows) - 1)
+		t.col = min(arg(1, 1), t.cols) - 1
+	case 'K':
+		line := t.line(t.row)
+		switch arg(0, 0) {
+		case 0:
+			*line = (*line)[:min(t.col, len(*line))]
+		case 1:
+			for c := 0; c <= t.col && c < len(*line); c++ {
+				(*line)[c] = termCell{r: ' '}
+			}
+		case 2:
+			*line = nil
+		}
+	case 'J':
+		switch arg(0, 0) {
+		case 0:
+			line := t.line(t.row)
+			*line = (*line)[:min(t.col, len(*line))]
+			t.lines = t.lines[:t.row+1]
+		case 2, 3:
+			// A cleared screen starts below the old one, so the
+			// export keeps the history a terminal would scroll away.
+			t.top = len(t.lines)
+			t.row, t.col = t.top, 0
+		}
+	}
+}
+
+// sgr applies Select Graphic Rendition parameters.
+func (t *termScreen) sgr(args []string) {
+	for k := 0; k < len(args); k++ {
+		n, _ := strconv.Atoi(args[k])
+		switch {
+		case n == 0:
+			t.st = termStyle{}
+		case n == 1:
+			t.st.bold = true
+		case n == 2:
+			t.st.dim = true
+		case n == 3:
+			t.st.italic = true
+		case n == 4:
+			t.st.underline = true
+		case n == 7:
+			t.st.invert = true
+		case n == 22:
+			t.st.bold, t.st.dim = false, false
+		case n == 23:
+			t.st.italic = false
+		case n == 24:
+			t.st.underline = false
+		case n == 27:
+			t.st.invert = false
+		case n >= 30 && n <= 37:
+			t.st.fg = termPalette[n-30]
+		case n >= 90 && n <= 97:
+			t.st.fg = termPalette[n-90+8]
+		case n == 39:
+			t.st.fg = ""
+		case n >= 40 && n <= 47:
+			t.st.bg = termPalette[n-40]
+		case n >= 100 && n <= 107:
+			t.st.bg = termPalette[n-100+8]
+		case n == 49:
+			t.st.bg = ""
+		case n == 38 || n == 48:
+			var color string
+			color, k = extendedColor(args, k+1)
+			if n == 38 {
+				t.st.fg = color
+			} else {
+				t.st.bg = color
+			}
+		}
+	}
+}
+
+// extendedColor parses the 5;n or 2;r;g;b after an SGR 38 or 48 at
+// args[k:], returning the color and the index of its last parameter.
+func extendedColor(args []string, k int) (string, int) {
+	at := func(i int) int {
+		if i < len(args) {
+			n, _ := strconv.Atoi(args[i])
+			return min(max(n, 0), 255)
+		}
+		return 0
+	}
+	switch at(k) {
+	case 5:
+		return color256(at(k + 1)), k + 1
+	case 2:
+		return fmt.Sprintf("#%02x%02x%02x", at(k+1), at(k+2), at(k+3)), k + 3
+	}
+	return "", k
+}
+
+// termPalette is xterm's default 16 colors.
+var termPalette = [16]string{
+	"#000000", "#cd0000", "#00cd00", "#cdcd00", "#0000ee", "#cd00cd", "#00cdcd", "#e5e5e5",
+	"#7f7f7f", "#ff0000", "#00ff00", "#ffff00", "#5c5cff", "#ff00ff", "#00ffff", "#ffffff",
+}
+
+func color256(n int) string {
+	switch {
+	case n < 16:
+		return termPalette[n]
+	case n < 232:
+		n -= 16
+		level := func(v int) int {
+			if v == 0 {
+				return 0
+			}
+			return 55 + v*40
+		}
+		return fmt.Sprintf("#%02x%02x%02x", level(n/36), level(n/6%6), level(n%6))
+	}
+	g := 8 + (n-232)*10
+	return fmt.Sprintf("#%02x%02x%02x", g, g, g)
+}
+
+func (t *termScreen) altScreen(on bool) {
+	switch {
+	case on && t.saved == nil:
+		main := *t
+		t.saved = &main
+		t.lines, t.row, t.col, t.top = nil, 0, 0, 0
+	case !on && t.saved != nil:
+		// What was on the alternate screen isn't part of the history.
+		*t = *t.saved
+	}
+}
+
+// moveRow puts the cursor on row, scrolling the screen down with it.
+func (t *termScreen) moveRow(row int) {
+	t.row = row
+	if t.row-t.top >= t.rows {
+		t.top = t.row - t.rows + 1
+	}
+}
+
+func (t *termScreen) line(row int) *[]termCell {
+	for len(t.lines) <= row {
+		t.lines = append(t.lines, nil)
+	}
+	return &t.lines[row]
+}
+
+func (t *termScreen) put(r rune) {
+	if t.col >= t.cols {
+		t.moveRow(t.row + 1)
+		t.col = 0
+	}
+	line := t.line(t.row)
+	for len(*line) <= t.col {
+		*line = append(*line, termCell{r: ' '})
+	}
+	(*line)[t.col] = termCell{r: r, st: t.st}
+	t.col++
+}
+
+// HTML returns the screen as escaped text in styled spans, for a <pre>.
+func (t *termScreen) HTML() string {
+	var b strings.Builder
+	lines := t.lines
+	for len(lines) > 0 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		for len(line) > 0 && line[len(line)-1] == (termCell{r: ' '}) {
+			line = line[:len(line)-1]
+		}
+		for start := 0; start < len(line); {
+			end := start + 1
+			for end < len(line) && line[end].st == line[start].st {
+				end++
+			}
+			text := make([]rune, 0, end-start)
+			for _, c := range line[start:end] {
+				text = append(text, c.r)
+			}
+			if css := line[start].st.css(); css != "" {
+				fmt.Fprintf(&b, `<span style="%s">%s</span>`, css, html.EscapeString(string(text)))
+			} else {
+				b.WriteString(html.EscapeString(string(text)))
+			}
+			start = end
+		}
+	}
+	return b.String()
+}
+
+// css is the inline style for st, "" for the default.
+func (st termStyle) css() string {
+	fg, bg := st.fg, st.bg
+	if st.invert {
+		fg, bg = cmp.Or(bg, termDefaultBG), cmp.Or(fg, termDefaultFG)
+	}
+	var parts []string
+	if fg != "" {
+		parts = append(parts, "color:"+fg)
+	}
+	if bg != "" {
+		parts = append(parts, "background:"+bg)
+	}
+	if st.bold {
+		parts = append(parts, "font-weight:bold")
+	}
+	if st.dim {
+		parts = append(parts, "opacity:.7")
+	}
+	if st.italic {
+		parts = append(parts, "font-style:italic")
+	}
+	if st.underline {
+		parts = append(parts, "text-decoration:underline")
+	}
+	return strings.Join(parts, ";")
+}
+
+const (
+	termDefaultFG = "#d4d4d4"
+	termDefaultBG = "#1e1e1e"
+)
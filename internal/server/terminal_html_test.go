@@ -0,0 +1,27 @@
+package server
+
+import "testing"
+
+func TestRenderTerminal(t *testing.T) {
+	cases := []struct {
+		name, out, want string
+	}{
+		{"plain", "hello\r\nworld\r\n", "hello\nworld"},
+		{"escaped", "a <b> & c", "a &lt;b&gt; &amp; c"},
+		{"colors", "\x1b[1;31merr\x1b[0m ok \x1b[38;5;196mx\x1b[48;2;1;2;3my\x1b[m",
+			`<span style="color:#cd0000;font-weight:bold">err</span> ok <span style="color:#ff0000">x</span>` +
+				`<span style="color:#ff0000;background:#010203">y</span>`},
+		{"carriage return overwrite", "50%\r100%\n", "100%"},
+		{"spinner redraw", "working |\x1b[1D/\x1b[1D-\r\x1b[2Kdone\n", "done"},
+		{"cursor up redraw", "> prompt\nline 1\nline 2\x1b[2A\r\x1b[0Jnew\n", "new"},
+		{"osc stripped", "\x1b]0;title\x07\x1b]8;;https://x\x1b\\link\x1b]8;;\x1b\\", "link"},
+		{"alternate screen dropped", "before\r\n\x1b[?1049hvim stuff\x1b[?1049lafter", "before\nafter"},
+		{"clear keeps history", "old\r\n\x1b[H\x1b[2Jnew", "old\nnew"},
+		{"wrap", "abcdefghijklmnopqrstuvwxyz", "abcdefghijklmnopqrst\nuvwxyz"},
+	}
+	for _, tc := range cases {
+		if got := renderTerminal([]byte(tc.out), 20, 5).HTML(); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
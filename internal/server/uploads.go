@@ -0,0 +1,214 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loppo-llc/kojo/internal/uploadpath"
+)
+
+// uploadSweepInterval is how often expired uploads are removed.
+const uploadSweepInterval = 10 * time.Minute
+
+// errUploadQuota is returned by uploadStore.reserve when the files
+// would push uploadDir past its quota.
+var errUploadQuota = errors.New("upload quota exceeded")
+
+// uploadStore applies the retention policy to files under uploadDir:
+// a TTL after which the sweeper deletes them and a quota on their total
+// size. Both are reloadable; zero disables either.
+type uploadStore struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	quota int64
+	// reserved counts bytes of uploads still being written, so two
+	// concurrent uploads can't both fit under the quota on paper.
+	reserved int64
+}
+
+func (u *uploadStore) set(ttl time.Duration, quota int64) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.ttl, u.quota = ttl, quota
+}
+
+// reserve claims n bytes of the quota for an upload in flight. The
+// returned release must be called once the files are on disk (or
+// abandoned).
+func (u *uploadStore) reserve(n int64) (release func(), err error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.quota > 0 {
+		used, err := uploadUsage()
+		if err != nil {
+			return nil, err
+		}
+		if used+u.reserved+n > u.quota {
+			return nil, fmt.Errorf("%w: %d of %d bytes in use", errUploadQuota, used+u.reserved, u.quota)
+		}
+	}
+	u.reserved += n
+	return func() {
+		u.mu.Lock()
+		u.reserved -= n
+		u.mu.Unlock()
+	}, nil
+}
+
+// pendingUpload is one file in uploadDir as listed by GET /api/v1/uploads.
+type pendingUpload struct {
+	// ID is the file's name in uploadDir, used to delete it.
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Path      string     `json:"path"`
+	Size      int64      `json:"size"`
+	ModTime   time.Time  `json:"modTime"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// listUploads returns the files in uploadDir, oldest first. A missing
+// directory is an empty list.
+func listUploads() ([]pendingUpload, error) {
+	entries, err := os.ReadDir(uploadDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []pendingUpload
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue // removed since ReadDir
+		}
+		out = append(out, pendingUpload{
+			ID:      e.Name(),
+			Name:    uploadOriginalName(e.Name()),
+			Path:    filepath.Join(uploadDir, e.Name()),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ModTime.Before(out[j].ModTime) })
+	return out, nil
+}
+
+// uploadOriginalName strips the "{unixnano}_" prefix uploads are saved
+// under.
+func uploadOriginalName(id string) string {
+	prefix, name, ok := strings.Cut(id, "_")
+	if !ok || prefix == "" || strings.Trim(prefix, "0123456789") != "" {
+		return id
+	}
+	return name
+}
+
+func uploadUsage() (int64, error) {
+	files, err := listUploads()
+	var total int64
+	for _, f := range files {
+		total += f.Size
+	}
+	return total, err
+}
+
+// runUploadSweeper deletes uploads older than the TTL every
+// uploadSweepInterval until uploadSweepDone is closed. The first sweep
+// runs at startup, catching files left behind by a crash.
+func (s *Server) runUploadSweeper() {
+	t := time.NewTicker(uploadSweepInterval)
+	defer t.Stop()
+	for {
+		s.sweepExpiredUploads(time.Now())
+		select {
+		case <-s.uploadSweepDone:
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (s *Server) sweepExpiredUploads(now time.Time) {
+	s.uploads.mu.Lock()
+	ttl := s.uploads.ttl
+	s.uploads.mu.Unlock()
+	if ttl <= 0 {
+		return
+	}
+	files, err := listUploads()
+	if err != nil {
+		s.logger.Warn("upload sweep: list failed", "err", err)
+		return
+	}
+	for _, f := range files {
+		if now.Sub(f.ModTime) < ttl {
+			continue
+		}
+		if err := os.Remove(f.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			s.logger.Warn("upload sweep: remove failed", "path", f.Path, "err", err)
+		}
+	}
+}
+
+// handleListUploads GET /api/v1/uploads
+//
+// Lists the uploaded files still on disk, oldest first, with when the
+// sweeper will delete each (omitted when uploads don't expire) and the
+// quota in use.
+func (s *Server) handleListUploads(w http.ResponseWriter, r *http.Request) {
+	files, err := listUploads()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	s.uploads.mu.Lock()
+	ttl, quota := s.uploads.ttl, s.uploads.quota
+	s.uploads.mu.Unlock()
+	var used int64
+	for i := range files {
+		used += files[i].Size
+		if ttl > 0 {
+			exp := files[i].ModTime.Add(ttl)
+			files[i].ExpiresAt = &exp
+		}
+	}
+	if files == nil {
+		files = []pendingUpload{}
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]any{
+		"uploads": files,
+		"used":    used,
+		"quota":   quota,
+	})
+}
+
+// handleDeleteUpload DELETE /api/v1/uploads/{id}
+//
+// Removes one uploaded file by the id GET /api/v1/uploads lists.
+func (s *Server) handleDeleteUpload(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id != uploadpath.SanitizeName(id) {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid upload id")
+		return
+	}
+	err := os.Remove(filepath.Join(uploadDir, id))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		writeError(w, http.StatusNotFound, "not_found", "upload not found: "+id)
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+	default:
+		writeJSONResponse(w, http.StatusOK, map[string]bool{"ok": true})
+	}
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// withUploadDir points uploadDir at a fresh directory for the test.
+func withUploadDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	orig := uploadDir
+	uploadDir = dir
+	t.Cleanup(func() { uploadDir = orig })
+	return dir
+}
+
+func multipartUpload(t *testing.T, files map[string]string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for name, content := range files {
+		fw, err := mw.CreateFormFile("file", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(content))
+	}
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestHandleUploadMultipleFiles(t *testing.T) {
+	dir := withUploadDir(t)
+	s := &Server{logger: slog.Default()}
+	rec := httptest.NewRecorder()
+	s.handleUpload(rec, multipartUpload(t, map[string]string{"a.txt": "aaa", "b.txt": "bb"}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Path  string         `json:"path"`
+		Files []uploadedFile `json:"files"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Files) != 2 || resp.Path != resp.Files[0].Path {
+		t.Fatalf("response = %+v", resp)
+	}
+	for _, f := range resp.Files {
+		if filepath.Dir(f.Path) != dir {
+			t.Errorf("%s saved outside the upload dir", f.Path)
+		}
+		if data, err := os.ReadFile(f.Path); err != nil || int64(len(data)) != f.Size {
+			t.Errorf("%s: %d bytes on disk (%v), want %d", f.Name, len(data), err, f.Size)
+		}
+	}
+}
+
+func TestHandleUploadQuota(t *testing.T) {
+	withUploadDir(t)
+	s := &Server{logger: slog.Default()}
+	s.uploads.set(0, 4)
+	rec := httptest.NewRecorder()
+	s.handleUpload(rec, multipartUpload(t, map[string]string{"a.txt": "aaa"}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("first upload: status = %d, body %s", rec.Code, rec.Body)
+	}
+	rec = httptest.NewRecorder()
+	s.handleUpload(rec, multipartUpload(t, map[string]string{"b.txt": "bb"}))
+	if rec.Code != http.StatusInsufficientStorage {
+		t.Fatalf("over quota: status = %d, want 507", rec.Code)
+	}
+}
+
+func TestSweepExpiredUploads(t *testing.T) {
+	dir := withUploadDir(t)
+	s := &Server{logger: slog.Default()}
+	s.uploads.set(time.Hour, 0)
+	old := filepath.Join(dir, "1_old.txt")
+	fresh := filepath.Join(dir, "2_fresh.txt")
+	for _, p := range []string{old, fresh} {
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	if err := os.Chtimes(old, now.Add(-2*time.Hour), now.Add(-2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	s.sweepExpiredUploads(now)
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("expired upload still there (%v)", err)
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Errorf("fresh upload removed: %v", err)
+	}
+}
+
+func TestUploadOriginalName(t *testing.T) {
+	for id, want := range map[string]string{
+		"1712345678_a_b.png": "a_b.png",
+		"notes_v2.txt":       "notes_v2.txt",
+		"_x":                 "_x",
+	} {
+		if got := uploadOriginalName(id); got != want {
+			t.Errorf("uploadOriginalName(%q) = %q, want %q", id, got, want)
+		}
+	}
+}
+
+func TestInsertablePath(t *testing.T) {
+	for p, want := range map[string]string{
+		"/tmp/kojo/upload/1_shot.png":      "/tmp/kojo/upload/1_shot.png",
+		"/tmp/kojo/upload/1_my shot.png":   "'/tmp/kojo/upload/1_my shot.png'",
+		"/tmp/kojo/upload/1_it's $HOME.md": `'/tmp/kojo/upload/1_it'\''s $HOME.md'`,
+	} {
+		if got := insertablePath(p); got != want {
+			t.Errorf("insertablePath(%q) = %q, want %q", p, got, want)
+		}
+	}
+}
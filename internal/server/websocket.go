@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/url"
 	"time"
@@ -13,6 +14,12 @@ import (
 	"github.com/loppo-llc/kojo/internal/session"
 )
 
+// wsCompression is the permessage-deflate mode the UI's WebSockets
+// offer: each message over 512 bytes (scrollback, output bursts,
+// session lists) is deflated on its own, which costs no per-connection
+// window memory.
+const wsCompression = websocket.CompressionNoContextTakeover
+
 // WebSocket message types
 type WSMessage struct {
 	Type string          `json:"type"`
@@ -35,11 +42,92 @@ type WSScrollbackMsg struct {
 	Data string `json:"data"` // base64
 }
 
+// WSDesyncMsg replaces the client's terminal contents after output was
+// dropped because the client fell behind: reset the terminal, then
+// write Data (a fresh scrollback snapshot) as for "scrollback".
+type WSDesyncMsg struct {
+	Type    string `json:"type"`
+	Data    string `json:"data"` // base64
+	Dropped int64  `json:"dropped"`
+}
+
+// WSClipboardMsg carries text a program in the session copied with
+// OSC 52, for the client to put on the system clipboard.
+type WSClipboardMsg struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
 type WSInputMsg struct {
 	Type string `json:"type"`
 	Data string `json:"data"` // base64
 }
 
+// WSPasteMsg is pasted text (base64 UTF-8). The server wraps it in
+// bracketed-paste markers and writes it in order-preserving chunks.
+type WSPasteMsg struct {
+	Type string `json:"type"`
+	Data string `json:"data"` // base64
+}
+
+// WSMacroMsg plays the saved macro with ID into the session.
+type WSMacroMsg struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// WSUnlockMsg unlocks a locked session's input for this connection.
+type WSUnlockMsg struct {
+	Type string `json:"type"`
+	PIN  string `json:"pin"`
+}
+
+// WSInputLockMsg answers an unlock, or input dropped because the
+// session is locked: Locked says whether this connection still can't
+// type, Error why.
+type WSInputLockMsg struct {
+	Type   string `json:"type"`
+	Locked bool   `json:"locked"`
+	Error  string `json:"error,omitempty"`
+}
+
+// WSControlMsg tells a client who has the session's input control
+// (see session.Client). It is sent on connect, whenever control
+// changes hands, and when this client's input was dropped because
+// another one has control. A client takes control with
+// {"type":"takeControl"} and gives it up with {"type":"releaseControl"}.
+type WSControlMsg struct {
+	Type     string `json:"type"`
+	ClientID string `json:"clientId"` // this connection
+	// Owner is the controlling client's ID and OwnerIdentity who it is;
+	// both are empty while nobody has control, when the next client to
+	// type gets it.
+	Owner         string `json:"owner,omitempty"`
+	OwnerIdentity string `json:"ownerIdentity,omitempty"`
+	HasControl    bool   `json:"hasControl"`
+}
+
+// wsControl is one connection's end of the session's input control:
+// its client ID, the session's signal that control changed, and
+// nudge, which asks the write loop to resend the state to this client
+// alone.
+type wsControl struct {
+	clientID string
+	changed  <-chan struct{}
+	nudge    chan struct{}
+}
+
+func (c wsControl) resend() {
+	select {
+	case c.nudge <- struct{}{}:
+	default:
+	}
+}
+
+// wsUnlockIdle is how long an unlocked connection may go without
+// input before it needs the PIN again.
+const wsUnlockIdle = 5 * time.Minute
+
 type WSResizeMsg struct {
 	Type string `json:"type"`
 	Cols int    `json:"cols"`
@@ -83,20 +171,38 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	}
 
 	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
-		OriginPatterns: wsOriginPatterns,
+		OriginPatterns:  wsOriginPatterns,
+		CompressionMode: wsCompression,
 	})
 	if err != nil {
 		s.logger.Error("websocket accept failed", "err", err)
 		return
 	}
 	defer conn.CloseNow()
-	conn.SetReadLimit(64 * 1024) // 64KB max for terminal input
+	// Keystrokes are tiny; the limit is sized for "paste" messages,
+	// ~750KB of text once base64 is accounted for.
+	conn.SetReadLimit(1 << 20)
 
 	ctx, cancel := context.WithCancel(r.Context())
 	defer cancel()
+	wsAttach(ctx, conn)
 
 	s.logger.Info("websocket connected", "session", sessionID)
 
+	readOnly := s.readOnlyFor(r)
+	clientID, changed := sess.AddClient(session.Client{
+		Identity:   wsIdentity(r),
+		RemoteAddr: r.RemoteAddr,
+		ReadOnly:   readOnly,
+	})
+	control := wsControl{clientID: clientID, changed: changed, nudge: make(chan struct{}, 1)}
+	control.resend() // the write loop opens with the current state
+	s.sessions.NotifyUpdated(sess)
+	defer func() {
+		sess.RemoveClient(clientID)
+		s.sessions.NotifyUpdated(sess)
+	}()
+
 	// subscribe to session output
 	ch, scrollback := sess.Subscribe()
 	defer sess.Unsubscribe(ch)
@@ -110,6 +216,12 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	attachCh := sess.SubscribeAttachments()
 	defer sess.UnsubscribeAttachments(attachCh)
 
+	clipCh := sess.SubscribeClipboard()
+	defer sess.UnsubscribeClipboard(clipCh)
+
+	termCh := sess.SubscribeTermEvents()
+	defer sess.UnsubscribeTermEvents(termCh)
+
 	// send scrollback
 	if len(scrollback) > 0 {
 		msg := WSScrollbackMsg{
@@ -150,13 +262,14 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// read from client
-	go s.wsReadLoop(ctx, cancel, conn, sess)
+	lockCh := make(chan WSInputLockMsg, 1)
+	go s.wsReadLoop(ctx, cancel, conn, sess, readOnly, control, lockCh)
 
 	// keepalive: ping every 30s to detect dead connections on mobile
 	go s.wsPingLoop(ctx, cancel, conn)
 
 	// write to client
-	s.wsWriteLoop(ctx, conn, sess, ch, yoloCh, attachCh)
+	s.wsWriteLoop(ctx, conn, sess, ch, yoloCh, attachCh, clipCh, termCh, lockCh, control, s.drainSignal())
 }
 
 func (s *Server) wsPingLoop(ctx context.Context, cancel context.CancelFunc, conn *websocket.Conn) {
@@ -179,22 +292,84 @@ func (s *Server) wsPingLoop(ctx context.Context, cancel context.CancelFunc, conn
 	}
 }
 
-func (s *Server) wsReadLoop(ctx context.Context, cancel context.CancelFunc, conn *websocket.Conn, sess *session.Session) {
+// wsReadLoop applies the client's messages to sess. A readOnly client
+// can only watch: its input, pastes and resizes are dropped. So are
+// the input and pastes of a client while another one has input
+// control, and its resizes count as the session's size policy says
+// (session.SizePolicy). While sess
+// is input locked, input and pastes are dropped until an "unlock"
+// message with the PIN; answers go to lockCh.
+func (s *Server) wsReadLoop(ctx context.Context, cancel context.CancelFunc, conn *websocket.Conn, sess *session.Session, readOnly bool, control wsControl, lockCh chan<- WSInputLockMsg) {
 	defer cancel()
+	var lastInput time.Time // zero until this connection unlocks
+	reply := func(msg WSInputLockMsg) {
+		msg.Type = "inputLock"
+		select {
+		case lockCh <- msg:
+		default: // one pending answer is enough
+		}
+	}
+	// mayType reports whether input from this connection goes through,
+	// telling the client when it doesn't.
+	mayType := func() bool {
+		if !sess.ClaimInput(control.clientID) {
+			control.resend()
+			return false
+		}
+		if !sess.InputLocked() {
+			lastInput = time.Time{} // a later lock needs its own PIN
+			return true
+		}
+		if !lastInput.IsZero() && time.Since(lastInput) < wsUnlockIdle {
+			lastInput = time.Now()
+			return true
+		}
+		lastInput = time.Time{}
+		reply(WSInputLockMsg{Locked: true})
+		return false
+	}
 	for {
 		_, data, err := conn.Read(ctx)
 		if err != nil {
 			return
 		}
+		wsTouch(ctx)
 
 		var msg WSMessage
 		if err := json.Unmarshal(data, &msg); err != nil {
 			s.logger.Debug("invalid ws message", "err", err)
 			continue
 		}
+		if readOnly {
+			continue
+		}
 
 		switch msg.Type {
+		case "takeControl":
+			sess.TakeInput(control.clientID)
+			control.resend() // answered even when nothing changed
+
+		case "releaseControl":
+			sess.ReleaseInput(control.clientID)
+			control.resend()
+
+		case "unlock":
+			var unlock WSUnlockMsg
+			if err := json.Unmarshal(data, &unlock); err != nil {
+				continue
+			}
+			switch err := sess.CheckInputPIN(unlock.PIN); {
+			case err == nil || errors.Is(err, session.ErrNotInputLocked):
+				lastInput = time.Now()
+				reply(WSInputLockMsg{})
+			default:
+				reply(WSInputLockMsg{Locked: true, Error: err.Error()})
+			}
+
 		case "input":
+			if !mayType() {
+				continue
+			}
 			var input WSInputMsg
 			if err := json.Unmarshal(data, &input); err != nil {
 				continue
@@ -207,12 +382,47 @@ func (s *Server) wsReadLoop(ctx context.Context, cancel context.CancelFunc, conn
 				s.logger.Debug("pty write error", "err", err)
 			}
 
+		case "macro":
+			if s.macros == nil || !mayType() {
+				continue
+			}
+			var mm WSMacroMsg
+			if err := json.Unmarshal(data, &mm); err != nil {
+				continue
+			}
+			m, err := s.macros.Get(mm.ID)
+			if err != nil {
+				s.logger.Debug("ws macro", "id", mm.ID, "err", err)
+				continue
+			}
+			// Played inline so input typed after it lands after it.
+			if err := s.playMacro(sess, m); err != nil {
+				s.logger.Debug("pty macro error", "err", err)
+			}
+
+		case "paste":
+			if !mayType() {
+				continue
+			}
+			var paste WSPasteMsg
+			if err := json.Unmarshal(data, &paste); err != nil {
+				continue
+			}
+			decoded, err := base64.StdEncoding.DecodeString(paste.Data)
+			if err != nil {
+				continue
+			}
+			if err := sess.Paste(string(decoded)); err != nil {
+				s.logger.Debug("pty paste error", "err", err)
+			}
+
 		case "resize":
+			// The session's size policy decides what comes of it.
 			var resize WSResizeMsg
 			if err := json.Unmarshal(data, &resize); err != nil {
 				continue
 			}
-			if err := sess.Resize(uint16(resize.Cols), uint16(resize.Rows)); err != nil {
+			if err := sess.ClientResize(control.clientID, uint16(resize.Cols), uint16(resize.Rows)); err != nil {
 				s.logger.Debug("pty resize error", "err", err)
 			}
 
@@ -222,7 +432,15 @@ func (s *Server) wsReadLoop(ctx context.Context, cancel context.CancelFunc, conn
 	}
 }
 
-func (s *Server) wsWriteLoop(ctx context.Context, conn *websocket.Conn, sess *session.Session, ch chan []byte, yoloCh chan string, attachCh chan []*session.Attachment) {
+func (s *Server) wsWriteLoop(ctx context.Context, conn *websocket.Conn, sess *session.Session, ch chan []byte, yoloCh chan string, attachCh chan []*session.Attachment, clipCh chan string, termCh chan session.TermEvent, lockCh <-chan WSInputLockMsg, control wsControl, drainCh <-chan struct{}) {
+	sendControl := func() error {
+		msg := WSControlMsg{Type: "control", ClientID: control.clientID}
+		if owner, ok := sess.InputOwner(); ok {
+			msg.Owner, msg.OwnerIdentity = owner.ID, owner.Identity
+			msg.HasControl = owner.ID == control.clientID
+		}
+		return writeJSON(ctx, conn, msg)
+	}
 	for {
 		select {
 		case <-ctx.Done():
@@ -247,6 +465,18 @@ func (s *Server) wsWriteLoop(ctx context.Context, conn *websocket.Conn, sess *se
 					break drain
 				}
 			}
+			if snap, dropped, ok := sess.Resync(ch); ok {
+				// The batch predates the drop and is already in snap.
+				msg := WSDesyncMsg{
+					Type:    "desync",
+					Data:    base64.StdEncoding.EncodeToString(snap),
+					Dropped: dropped,
+				}
+				if err := writeJSON(ctx, conn, msg); err != nil {
+					return
+				}
+				continue
+			}
 			msg := WSOutputMsg{
 				Type: "output",
 				Data: base64.StdEncoding.EncodeToString(data),
@@ -254,6 +484,7 @@ func (s *Server) wsWriteLoop(ctx context.Context, conn *websocket.Conn, sess *se
 			if err := writeJSON(ctx, conn, msg); err != nil {
 				return
 			}
+			wsTouch(ctx)
 		case tail := <-yoloCh:
 			msg := WSYoloDebugMsg{
 				Type: "yolo_debug",
@@ -270,6 +501,36 @@ func (s *Server) wsWriteLoop(ctx context.Context, conn *websocket.Conn, sess *se
 			if err := writeJSON(ctx, conn, msg); err != nil {
 				return
 			}
+		case text := <-clipCh:
+			if err := writeJSON(ctx, conn, WSClipboardMsg{Type: "clipboard", Text: text}); err != nil {
+				return
+			}
+		case ev := <-termCh:
+			// {"type":"bell"} or {"type":"title","title":...}
+			if err := writeJSON(ctx, conn, ev); err != nil {
+				return
+			}
+		case msg := <-lockCh:
+			if err := writeJSON(ctx, conn, msg); err != nil {
+				return
+			}
+		case <-control.changed:
+			if err := sendControl(); err != nil {
+				return
+			}
+		case <-control.nudge:
+			if err := sendControl(); err != nil {
+				return
+			}
+		case <-drainCh:
+			drainCh = nil // warn once
+			since, on := s.draining()
+			if !on {
+				continue // cancelled again before we got here
+			}
+			if err := writeJSON(ctx, conn, WSDrainingMsg{Type: "draining", Since: since}); err != nil {
+				return
+			}
 		case <-sess.Done():
 			info := sess.Info()
 			exitCode := 0
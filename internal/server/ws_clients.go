@@ -0,0 +1,56 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/loppo-llc/kojo/internal/auth"
+	"github.com/loppo-llc/kojo/internal/session"
+)
+
+// wsIdentity names who authenticated r for session.Client.Identity.
+func wsIdentity(r *http.Request) string {
+	p := auth.FromContext(r.Context())
+	switch {
+	case p.IsOwner():
+		return "owner"
+	case p.IsAgent():
+		return "agent:" + p.AgentID
+	case p.IsPeer():
+		return "peer:" + p.PeerID
+	}
+	return "guest"
+}
+
+// wsClientEntry is a session.Client along with the session it is on.
+type wsClientEntry struct {
+	SessionID string `json:"sessionId"`
+	session.Client
+}
+
+// handleListWSClients GET /api/v1/admin/ws-clients
+//
+// Owner only. Lists the WebSockets attached to this server's session
+// terminals, oldest first within each session; ?session= narrows it to
+// one session.
+func (s *Server) handleListWSClients(w http.ResponseWriter, r *http.Request) {
+	if !auth.FromContext(r.Context()).IsOwner() {
+		writeError(w, http.StatusForbidden, "forbidden", "client list requires Owner")
+		return
+	}
+	sessions := s.sessions.List()
+	if id := r.URL.Query().Get("session"); id != "" {
+		sess, ok := s.sessions.Get(id)
+		if !ok {
+			writeError(w, http.StatusNotFound, "not_found", "session not found: "+id)
+			return
+		}
+		sessions = []*session.Session{sess}
+	}
+	clients := []wsClientEntry{}
+	for _, sess := range sessions {
+		for _, c := range sess.Clients() {
+			clients = append(clients, wsClientEntry{SessionID: sess.ID, Client: c})
+		}
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]any{"clients": clients})
+}
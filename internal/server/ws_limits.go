@@ -0,0 +1,194 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/loppo-llc/kojo/internal/auth"
+)
+
+// WSLimits bound the WebSockets open on this server as a whole, so
+// forgotten tabs don't hold connections (and the session subscriptions
+// behind them) forever. Peer links are exempt. The zero value imposes
+// no limits.
+type WSLimits struct {
+	// MaxLifetime closes a connection this long after it opened; the
+	// client reconnects if it is still there.
+	MaxLifetime time.Duration
+	// IdleTimeout closes a terminal or event WebSocket after this long
+	// with neither a client message nor output sent to it.
+	IdleTimeout time.Duration
+	// MaxConns caps the open WebSockets. At the cap a new one evicts
+	// the connection idle the longest, or with Evict "reject" is
+	// refused with 503.
+	MaxConns int
+	Evict    string
+}
+
+// WSLimits.Evict policies.
+const (
+	WSEvictIdle   = "idle"
+	WSEvictReject = "reject"
+)
+
+// wsSweepInterval is how often a connection checks its lifetime and
+// idle time.
+const wsSweepInterval = 15 * time.Second
+
+// wsConn is one WebSocket counted by wsTracker.
+type wsConn struct {
+	opened time.Time
+	// active is when the connection last saw traffic (UnixNano).
+	active atomic.Int64
+	// idles is set once the handler reports activity (wsAttach), so
+	// connections that don't are never closed for idleness.
+	idles  atomic.Bool
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	conn   *websocket.Conn
+	closed bool
+}
+
+// end closes c for reason: with a close frame once the handler has
+// attached the connection, else by cancelling its request.
+func (c *wsConn) end(reason string) {
+	c.mu.Lock()
+	conn, closed := c.conn, c.closed
+	c.closed = true
+	c.mu.Unlock()
+	if closed {
+		return
+	}
+	if conn != nil {
+		conn.Close(websocket.StatusPolicyViolation, reason)
+	}
+	c.cancel()
+}
+
+// wsTracker holds the open WebSockets and the limits they're under.
+type wsTracker struct {
+	mu     sync.Mutex
+	limits WSLimits
+	conns  map[*wsConn]struct{}
+}
+
+func newWSTracker(l WSLimits) *wsTracker {
+	return &wsTracker{limits: l, conns: map[*wsConn]struct{}{}}
+}
+
+// set swaps in reloaded limits; they apply to open connections too.
+func (t *wsTracker) set(l WSLimits) {
+	t.mu.Lock()
+	t.limits = l
+	t.mu.Unlock()
+}
+
+// add counts a new connection, evicting the longest idle one at the
+// cap; false means it is refused.
+func (t *wsTracker) add(c *wsConn) bool {
+	t.mu.Lock()
+	var evict *wsConn
+	if n := t.limits.MaxConns; n > 0 && len(t.conns) >= n {
+		if t.limits.Evict == WSEvictReject {
+			t.mu.Unlock()
+			return false
+		}
+		for o := range t.conns {
+			if evict == nil || o.active.Load() < evict.active.Load() {
+				evict = o
+			}
+		}
+		delete(t.conns, evict)
+	}
+	t.conns[c] = struct{}{}
+	t.mu.Unlock()
+	if evict != nil {
+		go evict.end("evicted: too many connections")
+	}
+	return true
+}
+
+func (t *wsTracker) remove(c *wsConn) {
+	t.mu.Lock()
+	delete(t.conns, c)
+	t.mu.Unlock()
+}
+
+// expired reports why c should be closed as of now, "" if it shouldn't.
+func (t *wsTracker) expired(c *wsConn, now time.Time) string {
+	t.mu.Lock()
+	l := t.limits
+	t.mu.Unlock()
+	switch {
+	case l.MaxLifetime > 0 && now.Sub(c.opened) >= l.MaxLifetime:
+		return "connection lifetime reached"
+	case l.IdleTimeout > 0 && c.idles.Load() && now.Sub(time.Unix(0, c.active.Load())) >= l.IdleTimeout:
+		return "idle timeout"
+	}
+	return ""
+}
+
+type wsConnKey struct{}
+
+// wsAttach hands the WebSocket serving ctx to its tracker entry, so a
+// limit closes it with a reason, and makes it subject to the idle
+// timeout: the handler then calls wsTouch on activity.
+func wsAttach(ctx context.Context, conn *websocket.Conn) {
+	if c, ok := ctx.Value(wsConnKey{}).(*wsConn); ok {
+		c.mu.Lock()
+		c.conn = conn
+		c.mu.Unlock()
+		c.active.Store(time.Now().UnixNano())
+		c.idles.Store(true)
+	}
+}
+
+// wsTouch restarts the idle clock of the WebSocket serving ctx.
+func wsTouch(ctx context.Context) {
+	if c, ok := ctx.Value(wsConnKey{}).(*wsConn); ok {
+		c.active.Store(time.Now().UnixNano())
+	}
+}
+
+// serveWS serves the WebSocket upgrade r under s.wsLimits, refusing it
+// with 503 at the cap under the reject policy.
+func (s *Server) serveWS(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	if s.wsLimits == nil || auth.FromContext(r.Context()).IsPeer() {
+		next.ServeHTTP(w, r)
+		return
+	}
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	now := time.Now()
+	c := &wsConn{opened: now, cancel: cancel}
+	c.active.Store(now.UnixNano())
+	if !s.wsLimits.add(c) {
+		writeError(w, http.StatusServiceUnavailable, "too_many_connections",
+			"too many open WebSocket connections; close some tabs and retry")
+		return
+	}
+	defer s.wsLimits.remove(c)
+
+	go func() {
+		t := time.NewTicker(wsSweepInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-t.C:
+				if reason := s.wsLimits.expired(c, now); reason != "" {
+					s.logger.Debug("closing websocket", "reason", reason, "remote", r.RemoteAddr)
+					c.end(reason)
+					return
+				}
+			}
+		}
+	}()
+	next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, wsConnKey{}, c)))
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWSTracker(t *testing.T) {
+	now := time.Now()
+	conn := func(active time.Time) (*wsConn, context.Context) {
+		ctx, cancel := context.WithCancel(context.Background())
+		c := &wsConn{opened: now.Add(-time.Hour), cancel: cancel}
+		c.active.Store(active.UnixNano())
+		return c, ctx
+	}
+	tr := newWSTracker(WSLimits{MaxConns: 2, IdleTimeout: 10 * time.Minute, MaxLifetime: 2 * time.Hour})
+	busy, _ := conn(now)
+	quiet, quietCtx := conn(now.Add(-5 * time.Minute))
+	for _, c := range []*wsConn{quiet, busy} {
+		if !tr.add(c) {
+			t.Fatal("connection under the cap refused")
+		}
+	}
+
+	fresh, _ := conn(now)
+	if !tr.add(fresh) {
+		t.Fatal("connection at the cap refused under the idle policy")
+	}
+	select {
+	case <-quietCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the longest idle connection was not evicted")
+	}
+	if _, ok := tr.conns[busy]; !ok || len(tr.conns) != 2 {
+		t.Errorf("open connections = %d, busy kept %v", len(tr.conns), ok)
+	}
+
+	tr.set(WSLimits{MaxConns: 2, Evict: WSEvictReject, IdleTimeout: 10 * time.Minute, MaxLifetime: 2 * time.Hour})
+	if late, _ := conn(now); tr.add(late) {
+		t.Error("connection at the cap accepted under the reject policy")
+	}
+
+	if got := tr.expired(busy, now.Add(20*time.Minute)); got != "" {
+		t.Errorf("connection without wsAttach closed for %q", got)
+	}
+	busy.idles.Store(true)
+	if got := tr.expired(busy, now.Add(20*time.Minute)); got != "idle timeout" {
+		t.Errorf("expired = %q, want idle timeout", got)
+	}
+	if got := tr.expired(fresh, now.Add(time.Hour)); got != "connection lifetime reached" {
+		t.Errorf("expired = %q, want lifetime", got)
+	}
+}
@@ -0,0 +1,369 @@
+package session
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Checkpoints are restore points of a session's work: with a
+// checkpoint interval set, kojo commits the state of the working tree,
+// untracked files included, every interval and when the session is
+// paused or exits. They are commits on refs/kojo/checkpoints/<id>, so
+// neither the branch, the index nor the working tree is touched, and
+// they outlive the session. Each one's first parent is the checkpoint
+// before it and its second the HEAD it was taken on.
+
+// ErrCheckpoint is returned for checkpoints on a session that isn't
+// local or whose working directory isn't in a git repository.
+var ErrCheckpoint = errors.New("checkpoints need a local session in a git repository")
+
+// ErrUnknownCheckpoint is returned for a commit that isn't one of the
+// session's checkpoints.
+var ErrUnknownCheckpoint = errors.New("unknown checkpoint")
+
+// ErrUntrackedFiles is returned for a rollback that would delete
+// untracked files and isn't forced.
+var ErrUntrackedFiles = errors.New("rollback would delete untracked files")
+
+const (
+	// MinCheckpointInterval is the shortest checkpoint interval.
+	MinCheckpointInterval = time.Minute
+	// checkpointPrefix starts the message of every checkpoint commit.
+	checkpointPrefix = "kojo checkpoint: "
+	// maxCheckpoints is how many checkpoints Checkpoints lists.
+	maxCheckpoints = 200
+)
+
+// checkpointEnv is the identity checkpoint commits are made with, so
+// they work without one configured and stand apart from the user's.
+var checkpointEnv = []string{
+	"GIT_AUTHOR_NAME=kojo", "GIT_AUTHOR_EMAIL=kojo@localhost",
+	"GIT_COMMITTER_NAME=kojo", "GIT_COMMITTER_EMAIL=kojo@localhost",
+}
+
+// Checkpoint is one restore point of a session.
+type Checkpoint struct {
+	Commit string `json:"commit"`
+	Time   string `json:"time"`
+	// Reason is what took it: "interval", "paused", "exited",
+	// "manual" or "rollback".
+	Reason string `json:"reason"`
+}
+
+// CheckpointRef is the ref the checkpoints of session id are kept on.
+func CheckpointRef(id string) string { return "refs/kojo/checkpoints/" + id }
+
+// runGit runs git in dir with env added and returns its trimmed
+// output.
+func runGit(dir string, env []string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// checkpointRepo returns the top of the repository s works in.
+func checkpointRepo(s *Session) (string, error) {
+	s.mu.Lock()
+	host, workDir := s.Host, s.WorkDir
+	s.mu.Unlock()
+	if host != "" {
+		return "", fmt.Errorf("%w: %s runs on %s", ErrCheckpoint, s.ID, host)
+	}
+	top, err := runGit(workDir, nil, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrCheckpoint, workDir)
+	}
+	return top, nil
+}
+
+// SetCheckpointInterval has s checkpointed every d, and when it is
+// paused or exits; 0 turns checkpoints off. d under
+// MinCheckpointInterval is rejected.
+func (m *Manager) SetCheckpointInterval(s *Session, d time.Duration) error {
+	if d < 0 || (d > 0 && d < MinCheckpointInterval) {
+		return fmt.Errorf("checkpoint interval must be 0 or at least %s, got %s", MinCheckpointInterval, d)
+	}
+	if d > 0 {
+		if _, err := checkpointRepo(s); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	s.CheckpointInterval = d
+	s.mu.Unlock()
+	select {
+	case s.checkpointWake <- struct{}{}:
+	default:
+	}
+	m.startCheckpointLoop(s)
+	m.save()
+	m.publishList(ListEventUpdated, s.Info())
+	return nil
+}
+
+// startCheckpointLoop starts checkpointLoop for a running session with
+// a checkpoint interval and no loop yet.
+func (m *Manager) startCheckpointLoop(s *Session) {
+	s.mu.Lock()
+	start := s.Status == StatusRunning && s.CheckpointInterval > 0
+	s.mu.Unlock()
+	if start && !s.loops.running(loopCheckpoint) {
+		m.startLoop(s, loopCheckpoint, m.checkpointLoop)
+	}
+}
+
+// checkpointLoop checkpoints s every CheckpointInterval until it exits
+// or the interval is set to 0. SetCheckpointInterval wakes it to pick
+// up a new interval.
+func (m *Manager) checkpointLoop(s *Session) {
+	for {
+		s.mu.Lock()
+		done, d := s.done, s.CheckpointInterval
+		s.mu.Unlock()
+		if d <= 0 {
+			return
+		}
+		t := time.NewTimer(d)
+		select {
+		case <-done:
+			t.Stop()
+			return
+		case <-s.checkpointWake:
+			t.Stop()
+			continue
+		case <-t.C:
+		}
+		m.autoCheckpoint(s, "interval")
+	}
+}
+
+// autoCheckpoint checkpoints s for reason if it has checkpoints on.
+func (m *Manager) autoCheckpoint(s *Session, reason string) {
+	s.mu.Lock()
+	on := s.CheckpointInterval > 0
+	s.mu.Unlock()
+	if !on {
+		return
+	}
+	if _, err := m.Checkpoint(s, reason); err != nil {
+		m.logger.Warn("session checkpoint failed", "id", s.ID, "reason", reason, "err", err)
+	}
+}
+
+// Checkpoint commits the working tree of s onto its checkpoint ref and
+// returns the new checkpoint, or nil when nothing changed since the
+// last one (or since HEAD, before the first).
+func (m *Manager) Checkpoint(s *Session, reason string) (*Checkpoint, error) {
+	top, err := checkpointRepo(s)
+	if err != nil {
+		return nil, err
+	}
+	s.checkpointMu.Lock()
+	defer s.checkpointMu.Unlock()
+	head, tree, err := worktreeTree(top)
+	if err != nil {
+		return nil, err
+	}
+	return m.checkpointLocked(s, top, head, tree, reason)
+}
+
+// worktreeTree writes the working tree of the repository at top, minus
+// ignored files, as a git tree and returns it with HEAD ("" before the
+// first commit). It stages into a scratch index, leaving the real one
+// be.
+func worktreeTree(top string) (head, tree string, err error) {
+	dir, err := os.MkdirTemp("", "kojo-checkpoint-")
+	if err != nil {
+		return "", "", err
+	}
+	defer os.RemoveAll(dir)
+	env := []string{"GIT_INDEX_FILE=" + filepath.Join(dir, "index")}
+	head, _ = runGit(top, nil, "rev-parse", "-q", "--verify", "HEAD")
+	if head != "" {
+		if _, err := runGit(top, env, "read-tree", head); err != nil {
+			return "", "", err
+		}
+	}
+	if _, err := runGit(top, env, "add", "-A"); err != nil {
+		return "", "", err
+	}
+	tree, err = runGit(top, env, "write-tree")
+	return head, tree, err
+}
+
+// checkpointLocked is Checkpoint for tree, written by worktreeTree.
+// Caller holds s.checkpointMu.
+func (m *Manager) checkpointLocked(s *Session, top, head, tree, reason string) (*Checkpoint, error) {
+	ref := CheckpointRef(s.ID)
+	prev, _ := runGit(top, nil, "rev-parse", "-q", "--verify", ref)
+	if last := cmp.Or(prev, head); last != "" {
+		if lastTree, err := runGit(top, nil, "rev-parse", last+"^{tree}"); err == nil && lastTree == tree {
+			return nil, nil
+		}
+	}
+	args := []string{"commit-tree", tree, "-m", checkpointPrefix + reason}
+	if prev != "" {
+		args = append(args, "-p", prev)
+	}
+	if head != "" {
+		args = append(args, "-p", head)
+	}
+	commit, err := runGit(top, checkpointEnv, args...)
+	if err != nil {
+		return nil, err
+	}
+	// prev as the old value keeps a concurrent update from being lost.
+	if _, err := runGit(top, nil, "update-ref", ref, commit, prev); err != nil {
+		return nil, err
+	}
+	m.logger.Info("session checkpoint", "id", s.ID, "reason", reason, "commit", commit)
+	return &Checkpoint{Commit: commit, Time: time.Now().Format(time.RFC3339), Reason: reason}, nil
+}
+
+// Checkpoints lists the checkpoints of s, newest first.
+func (m *Manager) Checkpoints(s *Session) ([]Checkpoint, error) {
+	top, err := checkpointRepo(s)
+	if err != nil {
+		return nil, err
+	}
+	ref := CheckpointRef(s.ID)
+	if _, err := runGit(top, nil, "rev-parse", "-q", "--verify", ref); err != nil {
+		return []Checkpoint{}, nil
+	}
+	out, err := runGit(top, nil, "log", "--first-parent", "-n", strconv.Itoa(maxCheckpoints), "--format=%H%x00%ct%x00%s", ref)
+	if err != nil {
+		return nil, err
+	}
+	list := []Checkpoint{}
+	for _, line := range strings.Split(out, "\n") {
+		f := strings.SplitN(line, "\x00", 3)
+		if len(f) != 3 {
+			continue
+		}
+		reason, ok := strings.CutPrefix(f[2], checkpointPrefix)
+		if !ok {
+			break // past the first checkpoint, into the branch's history
+		}
+		sec, _ := strconv.ParseInt(f[1], 10, 64)
+		list = append(list, Checkpoint{Commit: f[0], Time: time.Unix(sec, 0).Format(time.RFC3339), Reason: reason})
+	}
+	return list, nil
+}
+
+// RollbackResult is what Rollback did.
+type RollbackResult struct {
+	// Checkpoint is the checkpoint the working tree was reset to.
+	Checkpoint Checkpoint `json:"checkpoint"`
+	// Saved is the checkpoint taken of the tree just before, to undo
+	// the rollback with; nil when the last checkpoint already had it.
+	Saved *Checkpoint `json:"saved"`
+	// Removed are the files deleted because the checkpoint lacks them.
+	Removed []string `json:"removed"`
+}
+
+// Rollback resets the working tree of s to its checkpoint commit, which
+// may be abbreviated to 7 digits or more: files are restored and those
+// the checkpoint lacks deleted. HEAD, the index and ignored files are
+// left alone. The tree is checkpointed first, so a rollback can itself
+// be rolled back. s must be paused or exited; untracked files are only
+// deleted with force.
+func (m *Manager) Rollback(s *Session, commit string, force bool) (*RollbackResult, error) {
+	s.mu.Lock()
+	busy := s.Status == StatusRunning && !s.Paused
+	s.mu.Unlock()
+	if busy {
+		return nil, fmt.Errorf("%w: pause or stop %s first", ErrSessionRunning, s.ID)
+	}
+	list, err := m.Checkpoints(s)
+	if err != nil {
+		return nil, err
+	}
+	var target *Checkpoint
+	for i := range list {
+		if len(commit) >= 7 && strings.HasPrefix(list[i].Commit, commit) {
+			target = &list[i]
+			break
+		}
+	}
+	if target == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCheckpoint, commit)
+	}
+	top, err := checkpointRepo(s)
+	if err != nil {
+		return nil, err
+	}
+	s.checkpointMu.Lock()
+	defer s.checkpointMu.Unlock()
+
+	head, tree, err := worktreeTree(top)
+	if err != nil {
+		return nil, err
+	}
+	out, err := runGit(top, nil, "diff", "--name-only", "--no-renames", "--diff-filter=A", "-z", target.Commit, tree)
+	if err != nil {
+		return nil, err
+	}
+	removed := splitNUL(out)
+	if !force {
+		out, err := runGit(top, nil, "ls-files", "--others", "--exclude-standard", "--full-name", "-z")
+		if err != nil {
+			return nil, err
+		}
+		untracked := splitNUL(out)
+		var lost []string
+		for _, p := range removed {
+			if slices.Contains(untracked, p) {
+				lost = append(lost, p)
+			}
+		}
+		if len(lost) > 0 {
+			return nil, fmt.Errorf("%w: %s", ErrUntrackedFiles, strings.Join(lost, ", "))
+		}
+	}
+
+	saved, err := m.checkpointLocked(s, top, head, tree, "rollback")
+	if err != nil {
+		return nil, err
+	}
+	// restore also deletes tracked files the checkpoint lacks.
+	if _, err := runGit(top, nil, "restore", "--source="+target.Commit, "--worktree", "--", ":/"); err != nil {
+		return nil, err
+	}
+	for _, p := range removed {
+		if err := os.Remove(filepath.Join(top, filepath.FromSlash(p))); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	m.logger.Info("session rolled back", "id", s.ID, "checkpoint", target.Commit, "removed", len(removed))
+	return &RollbackResult{Checkpoint: *target, Saved: saved, Removed: removed}, nil
+}
+
+// splitNUL splits git's -z output.
+func splitNUL(s string) []string {
+	list := []string{}
+	for _, p := range strings.Split(s, "\x00") {
+		if p != "" {
+			list = append(list, p)
+		}
+	}
+	return list
+}
@@ -0,0 +1,136 @@
+package session
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckpoint(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		t.Helper()
+		out, err := runGit(dir, checkpointEnv, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	write := func(name, body string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("init", "-q")
+	write("a.txt", "one\n")
+	git("add", ".")
+	git("commit", "-qm", "init")
+
+	m := &Manager{logger: slog.Default()}
+	s := newTestSession(false)
+	s.ID, s.WorkDir = "s1", dir
+	if cp, err := m.Checkpoint(s, "manual"); err != nil || cp != nil {
+		t.Fatalf("checkpoint of a clean tree = %+v, %v", cp, err)
+	}
+
+	write("a.txt", "two\n")
+	write("new.txt", "new\n")
+	first, err := m.Checkpoint(s, "interval")
+	if err != nil || first == nil {
+		t.Fatalf("Checkpoint = %+v, %v", first, err)
+	}
+	if got := git("show", first.Commit+":new.txt"); got != "new" {
+		t.Errorf("checkpoint has new.txt = %q", got)
+	}
+	if got := git("status", "--porcelain"); got != "M a.txt\n?? new.txt" {
+		t.Errorf("status after checkpoint = %q", got)
+	}
+	if cp, _ := m.Checkpoint(s, "interval"); cp != nil {
+		t.Error("checkpointed an unchanged tree")
+	}
+	write("a.txt", "three\n")
+	second, err := m.Checkpoint(s, "paused")
+	if err != nil || second == nil {
+		t.Fatalf("Checkpoint = %+v, %v", second, err)
+	}
+
+	list, err := m.Checkpoints(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].Commit != second.Commit || list[0].Reason != "paused" || list[1].Commit != first.Commit {
+		t.Errorf("Checkpoints = %+v", list)
+	}
+
+	s.WorkDir = t.TempDir()
+	if _, err := m.Checkpoint(s, "manual"); err == nil {
+		t.Error("checkpoint outside a repository succeeded")
+	}
+}
+
+func TestRollback(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	write := func(name, body string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{{"init", "-q"}, {"commit", "-q", "--allow-empty", "-m", "init"}} {
+		if _, err := runGit(dir, checkpointEnv, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := &Manager{logger: slog.Default()}
+	s := newTestSession(false)
+	s.ID, s.WorkDir, s.Status = "s1", dir, StatusRunning
+	write("a.txt", "good\n")
+	cp, err := m.Checkpoint(s, "manual")
+	if err != nil || cp == nil {
+		t.Fatalf("Checkpoint = %+v, %v", cp, err)
+	}
+	write("a.txt", "bad\n")
+	write("junk.txt", "junk\n")
+
+	if _, err := m.Rollback(s, cp.Commit, false); !errors.Is(err, ErrSessionRunning) {
+		t.Fatalf("rollback of a running session: %v", err)
+	}
+	s.Paused = true
+	if _, err := m.Rollback(s, "0000000", false); !errors.Is(err, ErrUnknownCheckpoint) {
+		t.Errorf("rollback to a stranger: %v", err)
+	}
+	if _, err := m.Rollback(s, cp.Commit[:7], false); !errors.Is(err, ErrUntrackedFiles) {
+		t.Fatalf("rollback over an untracked file: %v", err)
+	}
+	res, err := m.Rollback(s, cp.Commit[:7], true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "a.txt")); string(b) != "good\n" {
+		t.Errorf("a.txt = %q", b)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "junk.txt")); !os.IsNotExist(err) {
+		t.Errorf("junk.txt survived: %v", err)
+	}
+	if res.Saved == nil || len(res.Removed) != 1 {
+		t.Fatalf("result = %+v", res)
+	}
+	// The rollback itself can be undone.
+	if _, err := m.Rollback(s, res.Saved.Commit, false); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "junk.txt")); string(b) != "junk\n" {
+		t.Errorf("undone junk.txt = %q", b)
+	}
+}
@@ -0,0 +1,144 @@
+package session
+
+import (
+	"cmp"
+	"crypto/rand"
+	"encoding/hex"
+	"slices"
+	"time"
+)
+
+// Client is a WebSocket attached to a session's terminal, so the UI can
+// show who else is watching, or typing, before joining in.
+//
+// At most one client has input control at a time. A client with none
+// only watches: its keystrokes, pastes and resizes are dropped. The
+// first client to type while nobody has control gets it, keeping it
+// until it lets go, disconnects, or another client takes it over
+// explicitly with TakeInput.
+type Client struct {
+	ID string `json:"id"`
+	// Identity is who authenticated the socket: "owner",
+	// "agent:<id>", "peer:<device>" or "guest".
+	Identity    string    `json:"identity"`
+	RemoteAddr  string    `json:"remoteAddr,omitempty"`
+	ConnectedAt time.Time `json:"connectedAt"`
+	// ReadOnly clients get output but their input is dropped, and they
+	// can't take input control.
+	ReadOnly bool `json:"readOnly"`
+	// Control is set on the client holding input control.
+	Control bool `json:"control"`
+	// Cols and Rows are the client's terminal size, once it has sent
+	// one; see SizePolicy.
+	Cols uint16 `json:"cols,omitempty"`
+	Rows uint16 `json:"rows,omitempty"`
+}
+
+// AddClient registers c, filling in its ID and ConnectedAt. It returns
+// the ID for the other client methods and a channel that receives
+// whenever input control changes hands.
+func (s *Session) AddClient(c Client) (string, <-chan struct{}) {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	c.ID = "c_" + hex.EncodeToString(b)
+	c.ConnectedAt = time.Now()
+	c.Control = false
+	ch := make(chan struct{}, 1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.clients == nil {
+		s.clients = make(map[string]Client)
+		s.clientSignals = make(map[string]chan struct{})
+	}
+	s.clients[c.ID] = c
+	s.clientSignals[c.ID] = ch
+	return c.ID, ch
+}
+
+// RemoveClient drops the client AddClient returned id for, freeing
+// input control if it held it. Under SizeSmallest the terminal then
+// grows to fit the clients left.
+func (s *Session) RemoveClient(id string) {
+	s.mu.Lock()
+	delete(s.clients, id)
+	delete(s.clientSignals, id)
+	if s.inputOwner == id {
+		s.setInputOwnerLocked("")
+	}
+	s.mu.Unlock()
+	_ = s.applySize("")
+}
+
+// Clients returns the attached clients, oldest first.
+func (s *Session) Clients() []Client {
+	s.mu.Lock()
+	out := make([]Client, 0, len(s.clients))
+	for _, c := range s.clients {
+		c.Control = c.ID == s.inputOwner
+		out = append(out, c)
+	}
+	s.mu.Unlock()
+	slices.SortFunc(out, func(a, b Client) int {
+		return cmp.Or(a.ConnectedAt.Compare(b.ConnectedAt), cmp.Compare(a.ID, b.ID))
+	})
+	return out
+}
+
+// InputOwner returns the client holding input control, if any.
+func (s *Session) InputOwner() (Client, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.clients[s.inputOwner]
+	c.Control = ok
+	return c, ok
+}
+
+// ClaimInput reports whether client id may send input, giving it
+// control first if nobody has it.
+func (s *Session) ClaimInput(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inputOwner == "" {
+		if c, ok := s.clients[id]; !ok || c.ReadOnly {
+			return false
+		}
+		s.setInputOwnerLocked(id)
+	}
+	return s.inputOwner == id
+}
+
+// TakeInput hands input control to client id, away from whoever had
+// it. It reports false for an unknown or read-only client.
+func (s *Session) TakeInput(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.clients[id]; !ok || c.ReadOnly {
+		return false
+	}
+	s.setInputOwnerLocked(id)
+	return true
+}
+
+// ReleaseInput gives up input control if client id holds it.
+func (s *Session) ReleaseInput(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inputOwner == id {
+		s.setInputOwnerLocked("")
+	}
+}
+
+// setInputOwnerLocked changes the input owner and tells every client.
+// Caller holds s.mu.
+func (s *Session) setInputOwnerLocked(id string) {
+	if s.inputOwner == id {
+		return
+	}
+	s.inputOwner = id
+	for _, ch := range s.clientSignals {
+		select {
+		case ch <- struct{}{}:
+		default: // one pending change is enough; receivers re-read the owner
+		}
+	}
+}
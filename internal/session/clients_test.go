@@ -0,0 +1,75 @@
+package session
+
+import "testing"
+
+func TestClients(t *testing.T) {
+	s := newTestSession(false)
+	a, _ := s.AddClient(Client{Identity: "owner"})
+	b, _ := s.AddClient(Client{Identity: "guest", ReadOnly: true})
+	if info := s.Info(); info.Viewers != 2 || info.Writers != 1 {
+		t.Errorf("viewers/writers = %d/%d, want 2/1", info.Viewers, info.Writers)
+	}
+	ids := map[string]bool{}
+	for _, c := range s.Clients() {
+		ids[c.ID] = true
+		if c.ConnectedAt.IsZero() {
+			t.Errorf("client %s has no connect time", c.ID)
+		}
+	}
+	if len(ids) != 2 || !ids[a] || !ids[b] {
+		t.Errorf("client IDs = %v, want %s and %s", ids, a, b)
+	}
+	s.RemoveClient(a)
+	if info := s.Info(); info.Viewers != 1 || info.Writers != 0 {
+		t.Errorf("after remove: viewers/writers = %d/%d, want 1/0", info.Viewers, info.Writers)
+	}
+	if s.InfoForSave().Viewers != 0 {
+		t.Error("viewer count should not be persisted")
+	}
+}
+
+func TestInputControl(t *testing.T) {
+	s := newTestSession(false)
+	a, aChanged := s.AddClient(Client{Identity: "owner"})
+	b, bChanged := s.AddClient(Client{Identity: "agent:x"})
+	viewer, _ := s.AddClient(Client{Identity: "guest", ReadOnly: true})
+
+	if s.ClaimInput(viewer) || s.TakeInput(viewer) {
+		t.Error("read-only client got control")
+	}
+	// The first to type gets control; the other is locked out.
+	if !s.ClaimInput(a) {
+		t.Fatal("first claim refused")
+	}
+	if s.ClaimInput(b) {
+		t.Error("second client claimed control from the first")
+	}
+	for name, ch := range map[string]<-chan struct{}{"a": aChanged, "b": bChanged} {
+		select {
+		case <-ch:
+		default:
+			t.Errorf("%s not told control changed", name)
+		}
+	}
+	if owner, ok := s.InputOwner(); !ok || owner.ID != a || !owner.Control {
+		t.Errorf("owner = %+v, %v; want %s", owner, ok, a)
+	}
+
+	// An explicit takeover moves it.
+	if !s.TakeInput(b) || s.ClaimInput(a) || !s.ClaimInput(b) {
+		t.Error("takeover did not move control to b")
+	}
+	s.ReleaseInput(a) // not a's to release
+	if owner, _ := s.InputOwner(); owner.ID != b {
+		t.Errorf("owner after a's release = %q, want %s", owner.ID, b)
+	}
+
+	// Leaving frees it for the next one to type.
+	s.RemoveClient(b)
+	if _, ok := s.InputOwner(); ok {
+		t.Error("control kept by a disconnected client")
+	}
+	if !s.ClaimInput(a) {
+		t.Error("claim after the owner left refused")
+	}
+}
@@ -0,0 +1,173 @@
+package session
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// OSC 52 is the terminal "set clipboard" sequence:
+// ESC ] 52 ; <selection> ; <base64 text> (BEL | ESC \).
+// Tools like vim, tmux and the agent CLIs use it to copy; kojo hands
+// the text to the browser, which owns the real clipboard.
+var osc52Prefix = []byte("\x1b]52;")
+
+// maxClipboardSeq caps a pending OSC 52 sequence. A longer one is
+// dropped rather than buffered without bound.
+const maxClipboardSeq = 1 << 20
+
+// CheckClipboard scans output for OSC 52 copy sequences, including
+// ones split across reads, and returns the copied texts. Queries
+// ("?" payload) and undecodable payloads are ignored.
+func (s *Session) CheckClipboard(data []byte) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	buf := data
+	if len(s.clipPending) > 0 {
+		buf = append(s.clipPending, data...)
+		s.clipPending = nil
+	}
+	var texts []string
+	for {
+		i := bytes.Index(buf, osc52Prefix)
+		if i < 0 {
+			s.clipPending = partialPrefix(buf, osc52Prefix)
+			return texts
+		}
+		seq := buf[i+len(osc52Prefix):]
+		end, termLen := oscEnd(seq)
+		if end < 0 {
+			if len(buf)-i <= maxClipboardSeq {
+				s.clipPending = bytes.Clone(buf[i:])
+			}
+			return texts
+		}
+		if text, ok := decodeOSC52(seq[:end]); ok {
+			texts = append(texts, text)
+		}
+		buf = seq[end+termLen:]
+	}
+}
+
+// oscEnd finds the terminator of an OSC body: BEL or ST (ESC \).
+// It returns the body length and the terminator length, or -1.
+func oscEnd(b []byte) (int, int) {
+	for i, c := range b {
+		switch c {
+		case '\a':
+			return i, 1
+		case '\x1b':
+			if i+1 < len(b) && b[i+1] == '\\' {
+				return i, 2
+			}
+			if i+1 == len(b) {
+				return -1, 0 // may be the first half of ST
+			}
+		}
+	}
+	return -1, 0
+}
+
+// decodeOSC52 parses "<selection>;<base64>".
+func decodeOSC52(body []byte) (string, bool) {
+	_, payload, ok := bytes.Cut(body, []byte(";"))
+	if !ok || len(payload) == 0 || string(payload) == "?" {
+		return "", false
+	}
+	raw, err := base64.StdEncoding.DecodeString(string(payload))
+	if err != nil {
+		raw, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(string(payload), "="))
+		if err != nil {
+			return "", false
+		}
+	}
+	return string(raw), true
+}
+
+// partialPrefix returns the longest tail of b that prefix starts with,
+// so a sequence split right after its first bytes is still found.
+func partialPrefix(b, prefix []byte) []byte {
+	for n := min(len(b), len(prefix)-1); n > 0; n-- {
+		if bytes.HasPrefix(prefix, b[len(b)-n:]) {
+			return bytes.Clone(b[len(b)-n:])
+		}
+	}
+	return nil
+}
+
+func (s *Session) SubscribeClipboard() chan string {
+	ch := make(chan string, 16)
+	s.subMu.Lock()
+	if s.clipSubs == nil {
+		s.clipSubs = make(map[chan string]struct{})
+	}
+	s.clipSubs[ch] = struct{}{}
+	s.subMu.Unlock()
+	return ch
+}
+
+func (s *Session) UnsubscribeClipboard(ch chan string) {
+	s.subMu.Lock()
+	delete(s.clipSubs, ch)
+	s.subMu.Unlock()
+	close(ch)
+}
+
+func (s *Session) BroadcastClipboard(text string) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	for ch := range s.clipSubs {
+		select {
+		case ch <- text:
+		default:
+		}
+	}
+}
+
+// Bracketed paste markers. A program that enabled bracketed paste
+// treats everything between them as typed-in text rather than keys.
+const (
+	pasteStart = "\x1b[200~"
+	pasteEnd   = "\x1b[201~"
+)
+
+// Large pastes are written in pasteChunk pieces pasteChunkGap apart:
+// one huge write can overrun the tty input queue of the tool (or the
+// tmux client in front of it) and arrive garbled.
+const (
+	pasteChunk    = 4096
+	pasteChunkGap = 2 * time.Millisecond
+)
+
+// Paste types text into the session as a bracketed paste. An end
+// marker inside text is removed so pasted content can't break out of
+// the paste and be run as keystrokes. It returns once the last chunk
+// is written, so input sent after it stays in order.
+func (s *Session) Paste(text string) error {
+	data := pasteStart + strings.ReplaceAll(text, pasteEnd, "") + pasteEnd
+	for len(data) > 0 {
+		n := min(len(data), pasteChunk)
+		// Don't split a UTF-8 character across writes.
+		for n < len(data) && n > 0 && !utf8.RuneStart(data[n]) {
+			n--
+		}
+		if n == 0 {
+			n = min(len(data), pasteChunk)
+		}
+		if _, err := s.Write([]byte(data[:n])); err != nil {
+			return err
+		}
+		data = data[n:]
+		if len(data) > 0 {
+			select {
+			case <-time.After(pasteChunkGap):
+			case <-s.done:
+				return os.ErrClosed
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package session
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestCheckClipboard(t *testing.T) {
+	s := newTestSession(false)
+	// "hello" and "world", one BEL- and one ST-terminated, the second
+	// split across three reads; plus a query that must be ignored.
+	reads := []string{
+		"pre\x1b]52;c;aGVsbG8=\amid\x1b",
+		"]52;c;d29y",
+		"bGQ=\x1b\\post\x1b]52;c;?\a",
+	}
+	var got []string
+	for _, r := range reads {
+		got = append(got, s.CheckClipboard([]byte(r))...)
+	}
+	if want := []string{"hello", "world"}; !slices.Equal(got, want) {
+		t.Fatalf("clipboard = %q, want %q", got, want)
+	}
+	if len(s.clipPending) != 0 {
+		t.Fatalf("pending = %q after complete sequences", s.clipPending)
+	}
+}
+
+func TestPasteStripsEndMarker(t *testing.T) {
+	pty := &recordingPTY{}
+	s := newTestSession(false)
+	s.PTY = pty
+	if err := s.Paste("ls\x1b[201~rm -rf ~\r"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := pty.String(), "\x1b[200~lsrm -rf ~\r\x1b[201~"; got != want {
+		t.Fatalf("wrote %q, want %q", got, want)
+	}
+}
+
+func TestPasteChunksOnRuneBoundaries(t *testing.T) {
+	pty := &recordingPTY{}
+	s := newTestSession(false)
+	s.PTY = pty
+	text := strings.Repeat("é", 3*pasteChunk/2)
+	if err := s.Paste(text); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := pty.String(), pasteStart+text+pasteEnd; got != want {
+		t.Fatalf("pasted %d bytes, want %d", len(got), len(want))
+	}
+	if len(pty.chunks) < 2 {
+		t.Fatalf("%d writes, want the paste chunked", len(pty.chunks))
+	}
+	for i, c := range pty.chunks {
+		if len(c) > pasteChunk || !utf8.Valid(c) {
+			t.Errorf("chunk %d: %d bytes, valid UTF-8 %v", i, len(c), utf8.Valid(c))
+		}
+	}
+}
+
+// recordingPTY stands in for a session's PTY and keeps what's written.
+type recordingPTY struct {
+	bytes.Buffer
+	chunks [][]byte
+}
+
+func (p *recordingPTY) Write(b []byte) (int, error) {
+	p.chunks = append(p.chunks, bytes.Clone(b))
+	return p.Buffer.Write(b)
+}
+
+func (p *recordingPTY) Close() error { return nil }
@@ -0,0 +1,85 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// codexRolloutSlack is how long before a session's recorded start a
+// rollout may begin and still be the session's: CreatedAt is persisted
+// to the second.
+const codexRolloutSlack = 2 * time.Second
+
+// codexMetaLineMax bounds the first line of a rollout file, which
+// carries codex's instructions along with the session metadata.
+const codexMetaLineMax = 1 << 20
+
+// FindSessionID finds the rollout codex keeps under
+// $CODEX_HOME/sessions/YYYY/MM/DD for each conversation: the earliest
+// one begun in workDir since started that no other session has
+// claimed.
+func (codexAdapter) FindSessionID(workDir string, started time.Time, taken func(string) bool) string {
+	dir := os.Getenv("CODEX_HOME")
+	if dir == "" {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".codex")
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "sessions", "*", "*", "*", "rollout-*.jsonl"))
+	dirs := []string{filepath.Clean(workDir)}
+	if real, err := filepath.EvalSymlinks(workDir); err == nil && real != dirs[0] {
+		dirs = append(dirs, real)
+	}
+	var bestID string
+	var bestAt time.Time
+	for _, path := range matches {
+		// A rollout last written before the session started isn't its.
+		if fi, err := os.Stat(path); err != nil || fi.ModTime().Before(started) {
+			continue
+		}
+		id, cwd, at, ok := readCodexSessionMeta(path)
+		if !ok || at.Before(started.Add(-codexRolloutSlack)) || taken(id) {
+			continue
+		}
+		if cwd = filepath.Clean(cwd); cwd != dirs[0] && (len(dirs) == 1 || cwd != dirs[1]) {
+			continue
+		}
+		if bestID == "" || at.Before(bestAt) {
+			bestID, bestAt = id, at
+		}
+	}
+	return bestID
+}
+
+// readCodexSessionMeta reads the session_meta record that opens a
+// codex rollout file.
+func readCodexSessionMeta(path string) (id, cwd string, started time.Time, ok bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", "", time.Time{}, false
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, codexMetaLineMax)
+	if !sc.Scan() {
+		return "", "", time.Time{}, false
+	}
+	var line struct {
+		Type    string `json:"type"`
+		Payload struct {
+			ID        string `json:"id"`
+			Timestamp string `json:"timestamp"`
+			Cwd       string `json:"cwd"`
+		} `json:"payload"`
+	}
+	if json.Unmarshal(sc.Bytes(), &line) != nil || line.Type != "session_meta" || line.Payload.ID == "" || line.Payload.Cwd == "" {
+		return "", "", time.Time{}, false
+	}
+	started, err = time.Parse(time.RFC3339Nano, line.Payload.Timestamp)
+	if err != nil {
+		return "", "", time.Time{}, false
+	}
+	return line.Payload.ID, line.Payload.Cwd, started, true
+}
@@ -12,4 +12,20 @@ var (
 	ErrHasRunningChildren = errors.New("cannot remove session with running children")
 	ErrNotTerminal        = errors.New("not a terminal session")
 	ErrNoTmuxID           = errors.New("session has no tmux ID")
+	ErrUnsupportedSignal  = errors.New("unsupported signal")
+	ErrCannotResume       = errors.New("session has no conversation to resume")
+	ErrUnknownHost        = errors.New("unknown remote host")
+	ErrWorkDirNotFound    = errors.New("working directory does not exist")
+	// ErrPromptUnsupported is returned for a first prompt to a tool
+	// that can't take one at launch; see Prompter.
+	ErrPromptUnsupported = errors.New("tool can't start on a prompt")
+	// ErrSandbox is returned for an unknown sandbox or one this host
+	// can't enforce.
+	ErrSandbox = errors.New("sandbox unavailable")
+	// Input lock errors; see SetInputLock.
+	ErrInputLocked    = errors.New("session input is locked")
+	ErrNotInputLocked = errors.New("session input is not locked")
+	ErrInvalidPIN     = errors.New("PIN must be 4 to 32 characters")
+	ErrWrongPIN       = errors.New("wrong PIN")
+	ErrPINLockout     = errors.New("too many wrong PINs; try again later")
 )
@@ -0,0 +1,78 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// geminiChatSlack is how long before a session's recorded start a
+// gemini chat may begin and still be the session's, as for codex.
+const geminiChatSlack = 2 * time.Second
+
+// FindSessionID finds the chat file gemini keeps for each conversation
+// under ~/.gemini/tmp/<project>/chats ($GEMINI_CLI_HOME in place of the
+// home directory when set): the earliest one begun in workDir since
+// started that no other session has claimed. A chat names its project
+// by the SHA-256 of the directory gemini ran in.
+func (geminiAdapter) FindSessionID(workDir string, started time.Time, taken func(string) bool) string {
+	home := os.Getenv("GEMINI_CLI_HOME")
+	if home == "" {
+		home, _ = os.UserHomeDir()
+	}
+	matches, _ := filepath.Glob(filepath.Join(home, ".gemini", "tmp", "*", "chats", "session-*.json"))
+	hashes := []string{geminiProjectHash(filepath.Clean(workDir))}
+	if real, err := filepath.EvalSymlinks(workDir); err == nil && real != filepath.Clean(workDir) {
+		hashes = append(hashes, geminiProjectHash(real))
+	}
+	var bestID string
+	var bestAt time.Time
+	for _, path := range matches {
+		// A chat last written before the session started isn't its.
+		if fi, err := os.Stat(path); err != nil || fi.ModTime().Before(started) {
+			continue
+		}
+		id, project, at, ok := readGeminiChatMeta(path)
+		if !ok || at.Before(started.Add(-geminiChatSlack)) || taken(id) {
+			continue
+		}
+		if project != hashes[0] && (len(hashes) == 1 || project != hashes[1]) {
+			continue
+		}
+		if bestID == "" || at.Before(bestAt) {
+			bestID, bestAt = id, at
+		}
+	}
+	return bestID
+}
+
+// geminiProjectHash is how gemini names the project in dir.
+func geminiProjectHash(dir string) string {
+	sum := sha256.Sum256([]byte(dir))
+	return hex.EncodeToString(sum[:])
+}
+
+// readGeminiChatMeta reads the conversation ID, project hash and start
+// time of a gemini chat file.
+func readGeminiChatMeta(path string) (id, project string, started time.Time, ok bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", time.Time{}, false
+	}
+	var chat struct {
+		SessionID   string `json:"sessionId"`
+		ProjectHash string `json:"projectHash"`
+		StartTime   string `json:"startTime"`
+	}
+	if json.Unmarshal(data, &chat) != nil || chat.SessionID == "" || chat.ProjectHash == "" {
+		return "", "", time.Time{}, false
+	}
+	started, err = time.Parse(time.RFC3339Nano, chat.StartTime)
+	if err != nil {
+		return "", "", time.Time{}, false
+	}
+	return chat.SessionID, chat.ProjectHash, started, true
+}
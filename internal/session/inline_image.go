@@ -0,0 +1,198 @@
+package session
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Inline images arrive as iTerm2 OSC 1337 File= sequences or as Sixel
+// DCS strings. Both pass through the output stream untouched, so a
+// terminal with image support renders them live; but they are large,
+// and a scrollback replay that starts partway through one (the ring
+// buffer wrapped, or a desync resnapshot) leaves only base64 noise.
+// So complete images are also kept per session and announced as
+// "image" terminal events, and the client can fetch them by ID.
+
+// InlineImage is an image a tool wrote to the terminal.
+type InlineImage struct {
+	ID      string    `json:"id"`
+	Format  string    `json:"format"` // "iterm2" or "sixel"
+	MIME    string    `json:"mime"`
+	Name    string    `json:"name,omitempty"`
+	Size    int       `json:"size"`
+	Created time.Time `json:"created"`
+	data    []byte
+}
+
+// Data returns the image bytes: the decoded file for iTerm2, the whole
+// DCS sequence for Sixel (which browsers can't show natively but
+// terminal emulators replay).
+func (img *InlineImage) Data() []byte { return img.data }
+
+const (
+	// maxInlineImage caps one image sequence; bigger ones are let
+	// through to the stream but not kept.
+	maxInlineImage = 8 << 20
+	// maxInlineImages is how many recent images a session keeps.
+	maxInlineImages = 16
+)
+
+var iterm2ImagePrefix = []byte("\x1b]1337;File=")
+
+// CheckInlineImages finds complete inline image sequences in output,
+// including ones split across reads, keeps them on the session and
+// returns them.
+func (s *Session) CheckInlineImages(data []byte) []*InlineImage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	buf := data
+	// A pending sequence is known to be unterminated up to its last
+	// byte (which may be half an ESC \), so the terminator search
+	// resumes there instead of rescanning megabytes on every read.
+	scanFrom, owned := 0, false
+	if len(s.imgPending) > 0 {
+		scanFrom = len(s.imgPending) - 1
+		buf = append(s.imgPending, data...)
+		s.imgPending, owned = nil, true
+	}
+	var found []*InlineImage
+	for len(buf) > 0 {
+		start, bodyAt, format := findImageStart(buf)
+		if start < 0 {
+			s.imgPending = imagePrefixTail(buf)
+			break
+		}
+		body := buf[bodyAt:]
+		skip := max(0, scanFrom-bodyAt)
+		end, termLen := oscEnd(body[skip:])
+		if format == "sixel" {
+			end, termLen = stEnd(body[skip:])
+		}
+		if end >= 0 {
+			end += skip
+		}
+		scanFrom = 0
+		if end < 0 {
+			if len(buf)-start <= maxInlineImage {
+				if owned && start == 0 {
+					s.imgPending = buf
+				} else {
+					s.imgPending = bytes.Clone(buf[start:])
+				}
+			}
+			break
+		}
+		if img := parseInlineImage(format, buf[start:bodyAt+end+termLen], body[:end]); img != nil {
+			s.imgSeq++
+			img.ID = fmt.Sprintf("img_%d", s.imgSeq)
+			img.Created = time.Now()
+			s.images = append(s.images, img)
+			if len(s.images) > maxInlineImages {
+				s.images = s.images[len(s.images)-maxInlineImages:]
+			}
+			found = append(found, img)
+		}
+		buf = body[end+termLen:]
+	}
+	return found
+}
+
+// InlineImage returns a kept image by ID.
+func (s *Session) InlineImage(id string) (*InlineImage, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, img := range s.images {
+		if img.ID == id {
+			return img, true
+		}
+	}
+	return nil, false
+}
+
+// findImageStart locates the first image sequence in b. bodyAt is
+// where its payload starts.
+func findImageStart(b []byte) (start, bodyAt int, format string) {
+	start = -1
+	sixelArea := b
+	if i := bytes.Index(b, iterm2ImagePrefix); i >= 0 {
+		start, bodyAt, format = i, i+len(iterm2ImagePrefix), "iterm2"
+		sixelArea = b[:i]
+	}
+	// Sixel: ESC P, numeric parameters, 'q'.
+	for off := 0; ; {
+		i := bytes.Index(sixelArea[off:], []byte("\x1bP"))
+		if i < 0 {
+			break
+		}
+		i += off
+		j := i + 2
+		for j < len(b) && (b[j] == ';' || (b[j] >= '0' && b[j] <= '9')) {
+			j++
+		}
+		if j < len(b) && b[j] == 'q' {
+			return i, j + 1, "sixel"
+		}
+		off = i + 2
+	}
+	return start, bodyAt, format
+}
+
+// imagePrefixTail keeps the end of b when it could be the start of an
+// image sequence cut off by the read.
+func imagePrefixTail(b []byte) []byte {
+	tail := partialPrefix(b, iterm2ImagePrefix)
+	if i := bytes.LastIndex(b, []byte("\x1bP")); i >= 0 && len(b)-i < 32 && len(b)-i > len(tail) &&
+		len(bytes.Trim(b[i+2:], "0123456789;")) == 0 {
+		tail = bytes.Clone(b[i:])
+	}
+	return tail
+}
+
+// stEnd finds a String Terminator (ESC \).
+func stEnd(b []byte) (int, int) {
+	if i := bytes.Index(b, []byte("\x1b\\")); i >= 0 {
+		return i, 2
+	}
+	return -1, 0
+}
+
+// parseInlineImage builds an InlineImage from a complete sequence, or
+// returns nil for one that isn't an inline image (an iTerm2 download,
+// a corrupt payload).
+func parseInlineImage(format string, seq, body []byte) *InlineImage {
+	if format == "sixel" {
+		return &InlineImage{Format: format, MIME: "image/x-sixel", Size: len(seq), data: bytes.Clone(seq)}
+	}
+	args, payload, ok := bytes.Cut(body, []byte(":"))
+	if !ok {
+		return nil
+	}
+	img := &InlineImage{Format: format}
+	inline := false
+	for _, kv := range strings.Split(string(args), ";") {
+		k, v, _ := strings.Cut(kv, "=")
+		switch k {
+		case "inline":
+			inline = v == "1"
+		case "name":
+			if name, err := base64.StdEncoding.DecodeString(v); err == nil {
+				img.Name = strings.ToValidUTF8(string(name), "")
+			}
+		}
+	}
+	if !inline {
+		return nil
+	}
+	data, err := base64.StdEncoding.DecodeString(string(payload))
+	if err != nil {
+		return nil
+	}
+	img.data = data
+	img.Size = len(data)
+	img.MIME = http.DetectContentType(data)
+	return img
+}
@@ -0,0 +1,65 @@
+package session
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestCheckInlineImagesITerm2(t *testing.T) {
+	s := newTestSession(false)
+	png := "\x89PNG\r\n\x1a\n" + strings.Repeat("x", 64)
+	seq := "\x1b]1337;File=name=" + base64.StdEncoding.EncodeToString([]byte("a.png")) +
+		";inline=1:" + base64.StdEncoding.EncodeToString([]byte(png)) + "\a"
+	// Split inside the prefix and inside the payload.
+	reads := []string{"out\x1b]13", seq[4:40], seq[40:] + "after"}
+	var got []*InlineImage
+	for _, r := range reads {
+		got = append(got, s.CheckInlineImages([]byte(r))...)
+	}
+	if len(got) != 1 {
+		t.Fatalf("found %d images, want 1", len(got))
+	}
+	img := got[0]
+	if img.Name != "a.png" || img.MIME != "image/png" || string(img.Data()) != png {
+		t.Fatalf("image = %+v, data %q", img, img.Data())
+	}
+	if kept, ok := s.InlineImage(img.ID); !ok || kept != img {
+		t.Fatalf("InlineImage(%q) = %v, %v", img.ID, kept, ok)
+	}
+	if len(s.imgPending) != 0 {
+		t.Fatalf("pending = %q after a complete sequence", s.imgPending)
+	}
+}
+
+func TestCheckInlineImagesSixel(t *testing.T) {
+	s := newTestSession(false)
+	seq := "\x1bP0;1;0q\"1;1;2;2#0;2;0;0;0#0~~\x1b\\"
+	s.CheckInlineImages([]byte("x" + seq[:12]))
+	got := s.CheckInlineImages([]byte(seq[12:]))
+	if len(got) != 1 || got[0].Format != "sixel" || string(got[0].Data()) != seq {
+		t.Fatalf("images = %+v", got)
+	}
+}
+
+func TestCheckInlineImagesSkipsDownloads(t *testing.T) {
+	s := newTestSession(false)
+	seq := "\x1b]1337;File=name=YS50eHQ=:" + base64.StdEncoding.EncodeToString([]byte("hi")) + "\a"
+	if got := s.CheckInlineImages([]byte(seq)); len(got) != 0 {
+		t.Fatalf("images = %+v, want none for a non-inline file", got)
+	}
+}
+
+func TestCheckInlineImagesKeepsRecent(t *testing.T) {
+	s := newTestSession(false)
+	seq := "\x1b]1337;File=inline=1:" + base64.StdEncoding.EncodeToString([]byte("GIF89a")) + "\a"
+	for range maxInlineImages + 4 {
+		s.CheckInlineImages([]byte(seq))
+	}
+	if len(s.images) != maxInlineImages {
+		t.Fatalf("kept %d images, want %d", len(s.images), maxInlineImages)
+	}
+	if _, ok := s.InlineImage("img_1"); ok {
+		t.Fatal("oldest image still kept")
+	}
+}
@@ -0,0 +1,93 @@
+package session
+
+import (
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// An input lock makes a session refuse keystrokes and pastes until the
+// client proves it knows the session's PIN, so a phone left unlocked
+// can watch an agent but not type into it. The PIN is kept as a bcrypt
+// hash. Unlocking is per client: a WebSocket that sent the right PIN
+// may type, while the session stays locked for every other client.
+
+const (
+	minPINLen = 4
+	maxPINLen = 32
+	// maxPINFailures wrong PINs in a row shut out PIN checks for
+	// pinLockout, so a 4-digit PIN can't be walked through.
+	maxPINFailures = 5
+	pinLockout     = 5 * time.Minute
+)
+
+// SetInputLock locks the session's input behind pin. A session that is
+// already locked has to be unlocked (ClearInputLock) first.
+func (s *Session) SetInputLock(pin string) error {
+	if len(pin) < minPINLen || len(pin) > maxPINLen {
+		return ErrInvalidPIN
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(pin), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inputLock != "" {
+		return ErrInputLocked
+	}
+	s.inputLock = string(hash)
+	s.pinFailures = 0
+	return nil
+}
+
+// ClearInputLock removes the input lock if pin is right.
+func (s *Session) ClearInputLock(pin string) error {
+	if err := s.CheckInputPIN(pin); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inputLock = ""
+	return nil
+}
+
+// InputLocked reports whether the session's input needs its PIN.
+func (s *Session) InputLocked() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inputLock != ""
+}
+
+// CheckInputPIN returns nil if pin unlocks the session's input. It
+// returns ErrNotInputLocked for a session without a lock, and
+// ErrPINLockout without checking after too many wrong PINs.
+func (s *Session) CheckInputPIN(pin string) error {
+	s.mu.Lock()
+	hash := s.inputLock
+	if hash == "" {
+		s.mu.Unlock()
+		return ErrNotInputLocked
+	}
+	if s.pinFailures >= maxPINFailures && time.Since(s.lastPINFailure) < pinLockout {
+		s.mu.Unlock()
+		return ErrPINLockout
+	}
+	s.mu.Unlock()
+
+	// bcrypt is slow on purpose; compare outside s.mu.
+	ok := bcrypt.CompareHashAndPassword([]byte(hash), []byte(pin)) == nil
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ok {
+		s.pinFailures = 0
+		return nil
+	}
+	if s.pinFailures >= maxPINFailures {
+		s.pinFailures = 0 // the lockout has passed; start counting again
+	}
+	s.pinFailures++
+	s.lastPINFailure = time.Now()
+	return ErrWrongPIN
+}
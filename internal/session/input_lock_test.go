@@ -0,0 +1,47 @@
+package session
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestInputLock(t *testing.T) {
+	s := newTestSession(false)
+	if err := s.CheckInputPIN("1234"); !errors.Is(err, ErrNotInputLocked) {
+		t.Fatalf("unlocked session: err = %v", err)
+	}
+	if err := s.SetInputLock("12"); !errors.Is(err, ErrInvalidPIN) {
+		t.Errorf("short PIN: err = %v", err)
+	}
+	if err := s.SetInputLock("1234"); err != nil {
+		t.Fatal(err)
+	}
+	if !s.InputLocked() || !s.Info().InputLocked {
+		t.Error("session not reported locked")
+	}
+	if s.Info().InputLockHash != "" || s.InfoForSave().InputLockHash == "" {
+		t.Error("PIN hash should be persisted but not shown")
+	}
+	if err := s.SetInputLock("5678"); !errors.Is(err, ErrInputLocked) {
+		t.Errorf("relock: err = %v", err)
+	}
+	if err := s.CheckInputPIN("1234"); err != nil {
+		t.Errorf("right PIN: err = %v", err)
+	}
+
+	for range maxPINFailures {
+		if err := s.CheckInputPIN("0000"); !errors.Is(err, ErrWrongPIN) {
+			t.Fatalf("wrong PIN: err = %v", err)
+		}
+	}
+	if err := s.ClearInputLock("1234"); !errors.Is(err, ErrPINLockout) {
+		t.Fatalf("after %d failures: err = %v, want ErrPINLockout", maxPINFailures, err)
+	}
+	s.lastPINFailure = s.lastPINFailure.Add(-pinLockout)
+	if err := s.ClearInputLock("1234"); err != nil {
+		t.Fatalf("after lockout: err = %v", err)
+	}
+	if s.InputLocked() {
+		t.Error("still locked after ClearInputLock")
+	}
+}
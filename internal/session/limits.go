@@ -0,0 +1,255 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// A session can carry a wall-clock limit, a token budget or both: a
+// hard ceiling for a yolo session left to run unattended. Once 90% of
+// a limit is used kojo warns through OnLimit; past it, it says so again
+// and stops the session.
+
+// limitCheckInterval is how often limitLoop looks at a session's limits.
+const limitCheckInterval = 15 * time.Second
+
+// ErrTokenBudget is returned for a token budget on a session whose
+// token use kojo can't see. Only local claude (and custom) sessions
+// leave a transcript to count tokens in.
+var ErrTokenBudget = errors.New("token budgets need a local claude session")
+
+// LimitEvent is what OnLimit is told: which limit ("time" or
+// "tokens"), and whether it was reached, stopping the session, or is
+// only close.
+type LimitEvent struct {
+	Limit    string
+	Exceeded bool
+}
+
+// checkTokenBudget reports whether a session of tool on host can have
+// a token budget of n.
+func checkTokenBudget(tool, host string, n int64) error {
+	switch {
+	case n < 0:
+		return fmt.Errorf("token budget must not be negative, got %d", n)
+	case n > 0 && ((tool != "claude" && tool != "custom") || host != ""):
+		return ErrTokenBudget
+	}
+	return nil
+}
+
+// setTimeLimitLocked makes the session stop d from now; 0 removes the
+// limit. Caller holds s.mu.
+func (s *Session) setTimeLimitLocked(d time.Duration, now time.Time) {
+	s.TimeLimit, s.Deadline, s.timeWarned = d, time.Time{}, false
+	if d > 0 {
+		s.Deadline = now.Add(d)
+	}
+}
+
+func (s *Session) hasLimitsLocked() bool {
+	return !s.Deadline.IsZero() || s.TokenBudget > 0
+}
+
+// SetTimeLimit gives s d from now to run before it is stopped; 0
+// removes the limit. Setting it again extends (or shortens) it.
+func (m *Manager) SetTimeLimit(s *Session, d time.Duration) error {
+	if d < 0 {
+		return fmt.Errorf("time limit must not be negative, got %s", d)
+	}
+	s.mu.Lock()
+	s.setTimeLimitLocked(d, time.Now())
+	s.mu.Unlock()
+	m.limitsChanged(s)
+	return nil
+}
+
+// SetTokenBudget stops s once its tool has used n tokens; 0 removes
+// the budget. Tokens already used count toward the new budget.
+func (m *Manager) SetTokenBudget(s *Session, n int64) error {
+	s.mu.Lock()
+	err := checkTokenBudget(s.Tool, s.Host, n)
+	if err == nil {
+		s.TokenBudget, s.tokensWarned = n, false
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	m.limitsChanged(s)
+	return nil
+}
+
+func (m *Manager) limitsChanged(s *Session) {
+	m.startLimitLoop(s)
+	m.save()
+	m.publishList(ListEventUpdated, s.Info())
+}
+
+// startLimitLoop starts limitLoop for a running session that has a
+// limit and no loop yet. The loop lasts until the session exits, so
+// limits set later are picked up by the same loop.
+func (m *Manager) startLimitLoop(s *Session) {
+	s.mu.Lock()
+	start := s.Status == StatusRunning && s.hasLimitsLocked()
+	s.mu.Unlock()
+	if start && !s.loops.running(loopLimit) {
+		m.startLoop(s, loopLimit, m.limitLoop)
+	}
+}
+
+func (m *Manager) limitLoop(s *Session) {
+	s.mu.Lock()
+	done := s.done
+	s.mu.Unlock()
+	t := time.NewTicker(limitCheckInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-t.C:
+		}
+		if !m.checkLimits(s, time.Now()) {
+			return
+		}
+	}
+}
+
+// checkLimits warns about or enforces s's limits as of now. It returns
+// false once it has stopped the session.
+func (m *Manager) checkLimits(s *Session, now time.Time) bool {
+	s.mu.Lock()
+	toolSessionID, budget := s.ToolSessionID, s.TokenBudget
+	s.mu.Unlock()
+	var used int64
+	if budget > 0 && toolSessionID != "" {
+		if err := s.tokens.update(claudeTranscript(toolSessionID)); err != nil {
+			m.logger.Debug("reading session transcript failed", "id", s.ID, "err", err)
+		}
+		used = s.tokens.total()
+	}
+
+	var ev *LimitEvent
+	s.mu.Lock()
+	s.tokensUsed = used
+	switch {
+	case s.Status != StatusRunning:
+	case !s.Deadline.IsZero() && !now.Before(s.Deadline):
+		ev = &LimitEvent{Limit: "time", Exceeded: true}
+	case s.TokenBudget > 0 && used >= s.TokenBudget:
+		ev = &LimitEvent{Limit: "tokens", Exceeded: true}
+	case !s.Deadline.IsZero() && !s.timeWarned && !now.Before(s.Deadline.Add(-s.TimeLimit/10)):
+		s.timeWarned = true
+		ev = &LimitEvent{Limit: "time"}
+	case s.TokenBudget > 0 && !s.tokensWarned && used >= s.TokenBudget-s.TokenBudget/10:
+		s.tokensWarned = true
+		ev = &LimitEvent{Limit: "tokens"}
+	}
+	s.mu.Unlock()
+	if ev == nil {
+		return true
+	}
+
+	m.logger.Info("session limit", "id", s.ID, "limit", ev.Limit, "exceeded", ev.Exceeded, "tokensUsed", used)
+	if m.OnLimit != nil {
+		go m.OnLimit(s, *ev)
+	}
+	if !ev.Exceeded {
+		return true
+	}
+	if err := m.Stop(s.ID); err != nil {
+		m.logger.Warn("stopping session over its limit failed", "id", s.ID, "err", err)
+		return true
+	}
+	return false
+}
+
+// claudeTranscript returns the transcript claude keeps for the
+// conversation toolSessionID, or "" if there is none yet. Claude files
+// it under a directory named after the working directory; the
+// conversation ID alone is enough to find it.
+func claudeTranscript(toolSessionID string) string {
+	dir := os.Getenv("CLAUDE_CONFIG_DIR")
+	if dir == "" {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".claude")
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "projects", "*", toolSessionID+".jsonl"))
+	if len(matches) == 0 {
+		return ""
+	}
+	return matches[0]
+}
+
+// tokenTally counts the tokens in a claude transcript, reading only
+// what was appended since the last update. Only limitLoop uses it.
+type tokenTally struct {
+	path   string
+	offset int64
+	// base is what earlier transcripts used, when a restart moved the
+	// session to a new one.
+	base int64
+	used int64
+	// seen is what each message has been counted at: claude writes a
+	// message once per content block, repeating its usage.
+	seen map[string]int64
+}
+
+func (t *tokenTally) total() int64 { return t.base + t.used }
+
+func (t *tokenTally) update(path string) error {
+	if path == "" {
+		return nil
+	}
+	if path != t.path {
+		t.base += t.used
+		t.path, t.offset, t.used, t.seen = path, 0, 0, map[string]int64{}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Seek(t.offset, io.SeekStart); err != nil {
+		return err
+	}
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			// A partial last line is still being written; read it
+			// whole next time.
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		t.offset += int64(len(line))
+		var entry struct {
+			Message struct {
+				ID    string `json:"id"`
+				Usage struct {
+					InputTokens              int64 `json:"input_tokens"`
+					OutputTokens             int64 `json:"output_tokens"`
+					CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+				} `json:"usage"`
+			} `json:"message"`
+		}
+		if json.Unmarshal(line, &entry) != nil || entry.Message.ID == "" {
+			continue
+		}
+		u := entry.Message.Usage
+		n := u.InputTokens + u.OutputTokens + u.CacheCreationInputTokens
+		if prev := t.seen[entry.Message.ID]; n > prev {
+			t.used += n - prev
+			t.seen[entry.Message.ID] = n
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package session
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTokenTally(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "conv.jsonl")
+	lines := `{"type":"user","message":{"role":"user","content":"hi"}}
+{"type":"assistant","message":{"id":"m1","usage":{"input_tokens":10,"output_tokens":5,"cache_creation_input_tokens":100,"cache_read_input_tokens":9999}}}
+{"type":"assistant","message":{"id":"m1","usage":{"input_tokens":10,"output_tokens":20,"cache_creation_input_tokens":100}}}
+{"type":"assistant","message":{"id":"m2","usage":{"input_tokens":1,"output_tokens":2`
+	if err := os.WriteFile(path, []byte(lines), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var tally tokenTally
+	if err := tally.update(path); err != nil {
+		t.Fatal(err)
+	}
+	// m1 counts once, at its last usage; cache reads don't count and
+	// the unfinished m2 line waits for the rest.
+	if got := tally.total(); got != 130 {
+		t.Fatalf("total = %d, want 130", got)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`}}}` + "\n")
+	f.Close()
+	if err := tally.update(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := tally.total(); got != 133 {
+		t.Errorf("total after append = %d, want 133", got)
+	}
+}
+
+func TestCheckLimits(t *testing.T) {
+	if err := checkTokenBudget("codex", "", 1000); err == nil {
+		t.Error("token budget accepted for codex")
+	}
+	if err := checkTokenBudget("claude", "", 1000); err != nil {
+		t.Errorf("token budget for claude: %v", err)
+	}
+
+	events := make(chan LimitEvent, 4)
+	m := &Manager{sessions: map[string]*Session{}, logger: slog.Default()}
+	m.OnLimit = func(_ *Session, ev LimitEvent) { events <- ev }
+	s := newTestSession(true)
+	s.Status = StatusRunning
+	start := time.Now()
+	s.setTimeLimitLocked(time.Hour, start)
+
+	if !m.checkLimits(s, start.Add(30*time.Minute)) {
+		t.Fatal("checkLimits stopped a session within its limit")
+	}
+	// Warned once at 90%, then stopped at the deadline. s isn't
+	// registered with m, so the stop itself fails and the check
+	// carries on.
+	for _, c := range []struct {
+		at   time.Duration
+		want *LimitEvent
+	}{
+		{54 * time.Minute, &LimitEvent{Limit: "time"}},
+		{55 * time.Minute, nil},
+		{time.Hour, &LimitEvent{Limit: "time", Exceeded: true}},
+	} {
+		m.checkLimits(s, start.Add(c.at))
+		select {
+		case ev := <-events:
+			if c.want == nil || ev != *c.want {
+				t.Errorf("at %s: event %+v, want %+v", c.at, ev, c.want)
+			}
+		case <-time.After(100 * time.Millisecond):
+			if c.want != nil {
+				t.Errorf("at %s: no %+v event", c.at, *c.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package session
+
+// ListEventType names a change to the session list.
+type ListEventType string
+
+const (
+	ListEventCreated ListEventType = "session_created"
+	ListEventUpdated ListEventType = "session_updated"
+	ListEventExited  ListEventType = "session_exited"
+	ListEventRemoved ListEventType = "session_removed"
+)
+
+// ListEvent is one change to the session list, carrying the session's
+// state after the change. For session_removed only the ID is
+// meaningful to the client, but the last known info is sent anyway.
+type ListEvent struct {
+	Type    ListEventType `json:"type"`
+	Session SessionInfo   `json:"session"`
+}
+
+// listEventBuffer is each subscriber's channel capacity. A subscriber
+// that falls this far behind is dropped rather than stalling the
+// manager; it resyncs by listing again.
+const listEventBuffer = 64
+
+// SubscribeList registers for session list changes. The returned
+// channel is closed when cancel is called or when the subscriber falls
+// behind and is dropped; either way the caller should re-list before
+// trusting its view again.
+func (m *Manager) SubscribeList() (<-chan ListEvent, func()) {
+	ch := make(chan ListEvent, listEventBuffer)
+	m.listMu.Lock()
+	if m.listSubs == nil {
+		m.listSubs = make(map[chan ListEvent]struct{})
+	}
+	m.listSubs[ch] = struct{}{}
+	m.listMu.Unlock()
+	return ch, func() {
+		m.listMu.Lock()
+		defer m.listMu.Unlock()
+		if _, ok := m.listSubs[ch]; ok {
+			delete(m.listSubs, ch)
+			close(ch)
+		}
+	}
+}
+
+// NotifyUpdated publishes a session_updated event for s. Manager
+// methods publish their own changes; this is for callers that mutate a
+// session directly (e.g. toggling yolo mode).
+func (m *Manager) NotifyUpdated(s *Session) {
+	m.publishList(ListEventUpdated, s.Info())
+}
+
+func (m *Manager) publishList(t ListEventType, info SessionInfo) {
+	m.listMu.Lock()
+	defer m.listMu.Unlock()
+	for ch := range m.listSubs {
+		select {
+		case ch <- ListEvent{Type: t, Session: info}:
+		default:
+			delete(m.listSubs, ch)
+			close(ch)
+			m.logger.Warn("session list subscriber fell behind; dropped")
+		}
+	}
+}
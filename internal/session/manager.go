@@ -8,11 +8,13 @@ import (
 	"log/slog"
 	"os"
 	"os/exec"
+	"regexp"
+	"runtime"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/loppo-llc/kojo/internal/store"
 )
 
@@ -54,19 +56,19 @@ const (
 	maxWriteRetries = 5
 )
 
-var userTools = map[string]bool{
-	"claude": true,
-	"codex":  true,
-	"grok":   true,
-	"custom": true,
-}
-
 // internalTools is populated by platform-specific init() functions.
 // Unix adds "tmux", Windows adds "shell".
 var internalTools = map[string]bool{}
 
 func isAllowedTool(name string) bool {
-	return userTools[name] || internalTools[name]
+	return IsUserTool(name) || internalTools[name]
+}
+
+// IsUserTool reports whether name is a tool a user can start a session
+// with: "claude", "codex", "gemini", "grok", "custom" or one added by
+// RegisterTool.
+func IsUserTool(name string) bool {
+	return toolAdapters[name] != nil
 }
 
 type Manager struct {
@@ -74,14 +76,70 @@ type Manager struct {
 	sessions map[string]*Session
 	logger   *slog.Logger
 	store    *Store
+	// tmuxLogger takes the tmux attach/reattach chatter so it can be
+	// leveled apart from the rest; nil means logger.
+	tmuxLogger *slog.Logger
 
 	shuttingDown bool
 
+	// saves are debounced: save arms saveTimer, flush writes.
+	// removedOutputs are the IDs whose output rows the next flush
+	// deletes. flushMu keeps two flushes from interleaving writes.
+	saveMu         sync.Mutex
+	saveTimer      *time.Timer
+	removedOutputs []string
+	flushMu        sync.Mutex
+
 	// customBaseURL is the base URL for a custom Anthropic Messages API endpoint.
 	customBaseURL string
 
+	// backend is BackendTmux or BackendPTY, resolved from
+	// ManagerOptions.Backend; Windows always uses ConPTY.
+	backend string
+	// remoteHosts are the machines CreateWithOptions can start sessions on;
+	// set once by NewManager.
+	remoteHosts []RemoteHost
+	// sandboxes are the restriction profiles sessions can run in, and
+	// yoloSandbox the one yolo sessions get when they name none; both
+	// set once by NewManager.
+	sandboxes   []Sandbox
+	yoloSandbox string
+	// protectedPaths and noYoloPaths are where sessions can't start
+	// and where yolo mode is off; set once by NewManager.
+	protectedPaths []pathRule
+	noYoloPaths    []pathRule
+	// redactRes mask secrets in session output; nil with redaction
+	// off. Set once by NewManager.
+	redactRes []*regexp.Regexp
+
 	// callback for session events
 	OnSessionExit func(s *Session)
+	// OnSessionBell is called when a session with NotifyOnBell rings
+	// the terminal bell, at most once per bellNotifyInterval.
+	// OnYoloApprove is called when yolo mode auto-approves a prompt.
+	// Both run in their own goroutine so a slow push can't stall the
+	// session's read loop.
+	OnSessionBell func(s *Session)
+	OnYoloApprove func(s *Session, matched string)
+	// OnOutputMatch is called, in its own goroutine, with a line of
+	// output that matched the session's NotifyPatterns, at most once
+	// per outputNotifyInterval.
+	OnOutputMatch func(s *Session, line string)
+	// OnWebhook is called, in its own goroutine, for each session
+	// webhook due to fire: on exit, or once when its pattern matches.
+	OnWebhook func(s *Session, hit WebhookHit)
+	// OnLimit is called, in its own goroutine, when a session nears
+	// or reaches its time limit or token budget; see LimitEvent.
+	OnLimit func(s *Session, ev LimitEvent)
+
+	// session list change subscribers; see SubscribeList
+	listMu   sync.Mutex
+	listSubs map[chan ListEvent]struct{}
+}
+
+// Backend returns the backend user tool sessions are started on.
+func (m *Manager) Backend() string {
+	return m.backend
 }
 
 // SetCustomBaseURL configures the base URL for custom Anthropic API sessions.
@@ -103,8 +161,45 @@ type ManagerOptions struct {
 	// back to. Non-empty enables the v0-side fallback inside
 	// internal/session.Store.Load(): kv miss → v1 dir → v0 dir.
 	V0LegacyDir string
+	// Backend selects how user tool sessions run on Unix: BackendTmux,
+	// BackendPTY, or BackendAuto / empty for tmux when it is installed
+	// and a direct PTY otherwise.
+	Backend string
+	// RemoteHosts are machines sessions can be started on over SSH.
+	RemoteHosts []RemoteHost
+	// Sandboxes are restriction profiles user tool sessions can be
+	// launched in; YoloSandbox names the one for yolo sessions that
+	// don't pick one.
+	Sandboxes   []Sandbox
+	YoloSandbox string
+	// NoRedact turns off secret redaction of session output;
+	// RedactPatterns are regexps masked on top of
+	// DefaultRedactPatterns.
+	NoRedact       bool
+	RedactPatterns []string
+	// ProtectedPaths are where local sessions can't start, and
+	// NoYoloPaths where they start with yolo mode off; see
+	// protected_paths.go for the pattern syntax. nil uses
+	// DefaultProtectedPaths and DefaultNoYoloPaths, an empty list
+	// protects nothing.
+	ProtectedPaths []string
+	NoYoloPaths    []string
+	// TmuxLogger receives the tmux backend's logs; nil uses the
+	// manager's logger.
+	TmuxLogger *slog.Logger
 }
 
+// Session backends for ManagerOptions.Backend.
+const (
+	BackendAuto = "auto"
+	// BackendTmux runs user tools inside tmux, so they outlive kojo
+	// and are reattached when it restarts.
+	BackendTmux = "tmux"
+	// BackendPTY runs user tools on a direct PTY: a degraded mode for
+	// hosts without tmux, where a session ends with kojo.
+	BackendPTY = "pty"
+)
+
 // NewManager constructs a session.Manager. db is the kv-backed
 // persistence layer (Phase 2c-2 slice 28); pass nil to disable
 // persistence (test scaffolding that exercises Manager methods
@@ -113,18 +208,168 @@ type ManagerOptions struct {
 func NewManager(logger *slog.Logger, db *store.Store, opts ManagerOptions) *Manager {
 	st := newStore(logger, db, opts.V0LegacyDir)
 	m := &Manager{
-		sessions: make(map[string]*Session),
-		logger:   logger,
-		store:    st,
+		sessions:   make(map[string]*Session),
+		logger:     logger,
+		tmuxLogger: opts.TmuxLogger,
+		store:      st,
+		backend:    platformResolveBackend(opts.Backend),
+
+		remoteHosts: slices.Clone(opts.RemoteHosts),
+		sandboxes:   slices.Clone(opts.Sandboxes),
+		yoloSandbox: opts.YoloSandbox,
+
+		protectedPaths: compilePathRules(opts.ProtectedPaths, DefaultProtectedPaths),
+		noYoloPaths:    compilePathRules(opts.NoYoloPaths, DefaultNoYoloPaths),
+	}
+	if m.backend == BackendPTY && runtime.GOOS != "windows" {
+		m.logger.Warn("user tool sessions run on a direct PTY; they end when kojo exits", "configured", opts.Backend)
+	}
+	if v := TmuxVersion(); m.backend == BackendTmux && v != "" && !TmuxSupported() {
+		m.logger.Warn("tmux is older than kojo supports; sessions may not resize or capture output",
+			"version", v, "minVersion", MinTmuxVersion)
+	}
+	if !opts.NoRedact {
+		res, err := compileRedactPatterns(opts.RedactPatterns)
+		if err != nil {
+			m.logger.Error("ignoring custom redact patterns", "err", err)
+			res, _ = compileRedactPatterns(nil)
+		}
+		m.redactRes = res
 	}
 	m.platformInit()
 	return m
 }
 
 func (m *Manager) Create(tool, workDir string, args []string, yoloMode bool, parentID string) (*Session, error) {
+	return m.create(tool, workDir, args, yoloMode, parentID, CreateOptions{}, nil)
+}
+
+// CreateOptions say where and how CreateWithOptions runs a session.
+type CreateOptions struct {
+	// Host is a RemoteHost.Name to run on over SSH. workDir is then a
+	// path there, empty for the remote home directory.
+	Host string
+	// Sandbox is a Sandbox.Name to confine the tool in. Empty means
+	// ManagerOptions.YoloSandbox for a yolo session and none otherwise.
+	Sandbox string
+	// TimeLimit and TokenBudget stop the session after that long or
+	// once its tool has used that many tokens; zero for no limit. See
+	// SetTimeLimit and SetTokenBudget.
+	TimeLimit   time.Duration
+	TokenBudget int64
+	// CheckpointInterval turns on checkpoints of the working tree; see
+	// SetCheckpointInterval.
+	CheckpointInterval time.Duration
+	// Prompt is a first prompt the tool starts working on. It is given
+	// on the command line of the first launch only, so a restart
+	// doesn't send it again. Tools that aren't a Prompter fail with
+	// ErrPromptUnsupported.
+	Prompt string
+}
+
+// CreateWithOptions is Create with opts; the zero value is Create.
+func (m *Manager) CreateWithOptions(tool, workDir string, args []string, yoloMode bool, parentID string, opts CreateOptions) (*Session, error) {
+	return m.create(tool, workDir, args, yoloMode, parentID, opts, nil)
+}
+
+// Clone starts a new session with the same tool, working directory,
+// args and yolo setting as id, which may be running or exited. With
+// resume the clone picks up a fork of id's conversation instead of
+// starting fresh; only claude (and custom) can fork a conversation, so
+// for other tools, or before the session ID is known, resume fails
+// with ErrCannotResume. The clone is top-level even if id is a child.
+func (m *Manager) Clone(id string, resume bool) (*Session, error) {
+	src, ok := m.Get(id)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	src.mu.Lock()
+	tool, workDir, yoloMode, toolSessionID := src.Tool, src.WorkDir, src.YoloMode, src.ToolSessionID
+	opts := CreateOptions{Host: src.Host, Sandbox: src.Sandbox}
+	args := slices.Clone(src.Args)
+	src.mu.Unlock()
+
+	claude := tool == "claude" || tool == "custom"
+	var launchArgs []string
+	if claude {
+		// An explicit --session-id would make the clone claim the
+		// source's conversation ID.
+		args = dropFlag(args, "--session-id", true)
+	}
+	if resume {
+		if !claude || toolSessionID == "" {
+			return nil, fmt.Errorf("%w: %s", ErrCannotResume, id)
+		}
+		for _, f := range []string{"--resume", "-r"} {
+			args = dropFlag(args, f, true)
+		}
+		for _, f := range []string{"--continue", "-c"} {
+			args = dropFlag(args, f, false)
+		}
+		launchArgs = []string{"--resume", toolSessionID, "--fork-session"}
+	}
+	s, err := m.create(tool, workDir, args, yoloMode, "", opts, launchArgs)
+	if err != nil {
+		return nil, err
+	}
+	m.logger.Info("session cloned", "id", s.ID, "from", id, "resume", resume)
+	return s, nil
+}
+
+// create starts a session. launchArgs are appended to the command line
+// of this launch only; they are not kept in s.Args, so a later Restart
+// doesn't repeat them.
+func (m *Manager) create(tool, workDir string, args []string, yoloMode bool, parentID string, opts CreateOptions, launchArgs []string) (*Session, error) {
 	if !isAllowedTool(tool) {
 		return nil, fmt.Errorf("%w: %s", ErrUnsupportedTool, tool)
 	}
+	host := opts.Host
+	var remote RemoteHost
+	if host != "" {
+		if opts.Sandbox != "" {
+			return nil, fmt.Errorf("%w: sandboxes apply to local sessions only", ErrSandbox)
+		}
+		var err error
+		if remote, err = m.remoteHost(host, tool); err != nil {
+			return nil, err
+		}
+	}
+	var prompter Prompter
+	if opts.Prompt != "" {
+		var ok bool
+		if prompter, ok = toolAdapters[tool].(Prompter); !ok {
+			return nil, fmt.Errorf("%w: %s", ErrPromptUnsupported, tool)
+		}
+	}
+	if host == "" {
+		workDir = localWorkDir(workDir)
+		var err error
+		if yoloMode, err = m.checkWorkDir(workDir, yoloMode); err != nil {
+			return nil, err
+		}
+		if err := m.checkYoloArgs(tool, workDir, args); err != nil {
+			return nil, err
+		}
+	}
+	if opts.TimeLimit < 0 {
+		return nil, fmt.Errorf("time limit must not be negative, got %s", opts.TimeLimit)
+	}
+	if err := checkTokenBudget(tool, host, opts.TokenBudget); err != nil {
+		return nil, err
+	}
+	if d := opts.CheckpointInterval; d < 0 || (d > 0 && d < MinCheckpointInterval) {
+		return nil, fmt.Errorf("checkpoint interval must be 0 or at least %s, got %s", MinCheckpointInterval, d)
+	}
+	if opts.CheckpointInterval > 0 && host != "" {
+		return nil, fmt.Errorf("%w: %s is a remote host", ErrCheckpoint, host)
+	}
+	var sandbox string
+	if host == "" {
+		var err error
+		if sandbox, err = m.resolveSandbox(opts.Sandbox, tool, yoloMode); err != nil {
+			return nil, err
+		}
+	}
 
 	// Resolve custom → claude with ANTHROPIC_BASE_URL; may modify args to extract --model.
 	customResult := m.resolveCustomAPI(tool, args)
@@ -138,32 +383,56 @@ func (m *Manager) Create(tool, workDir string, args []string, yoloMode bool, par
 	// other tools get no flag and keep the PTY auto-approve machinery instead.
 	args = appendYoloFlag(tool, args, yoloMode)
 
-	toolPath, err := resolveToolPath(tool, actualTool)
-	if err != nil {
-		return nil, err
-	}
-
-	if info, err := os.Stat(workDir); err != nil || !info.IsDir() {
-		return nil, fmt.Errorf("working directory does not exist: %s", workDir)
+	var toolPath, baseCommit string
+	if host != "" {
+		toolPath = remote.toolPath(actualTool)
+	} else {
+		var err error
+		if toolPath, err = resolveToolPath(tool, actualTool); err != nil {
+			return nil, err
+		}
+		go m.warnToolVersion(actualTool, toolPath)
+		if info, err := os.Stat(workDir); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("%w: %s", ErrWorkDirNotFound, workDir)
+		}
+		baseCommit = gitHead(workDir)
+		if opts.CheckpointInterval > 0 {
+			if _, err := runGit(workDir, nil, "rev-parse", "--show-toplevel"); err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrCheckpoint, workDir)
+			}
+		}
 	}
 
 	id := generateID()
+	model, permissionMode := launchControls(tool, args)
 
 	// Assign session ID and build run args based on tool type.
 	var toolSessionID string
 	var runArgs []string
-	if !userTools[tool] {
+	if !IsUserTool(tool) {
 		runArgs, toolSessionID = platformBuildInternalToolArgs(id, tool, workDir, args)
 	} else {
-		toolSessionID, runArgs = assignClaudeSessionID(actualTool, args)
+		toolSessionID, runArgs = assignToolSessionID(tool, args)
+		runArgs = append(slices.Clip(runArgs), launchArgs...)
+		if prompter != nil {
+			runArgs = append(runArgs, prompter.PromptArgs(opts.Prompt)...)
+		}
 	}
 
 	extraEnv := m.buildCustomEnv(customResult)
 
 	var res *startResult
-	if userTools[tool] {
-		res, err = m.platformStartUserTool(id, workDir, toolPath, runArgs, 0, 0, extraEnv)
-	} else {
+	var err error
+	switch {
+	case host != "":
+		res, err = platformStartRemote(remote.sshArgs(workDir, toolPath, runArgs), 0, 0)
+	case IsUserTool(tool):
+		path, wrapped, werr := m.wrapSandbox(sandbox, toolPath, runArgs)
+		if werr != nil {
+			return nil, werr
+		}
+		res, err = m.platformStartUserTool(id, workDir, path, wrapped, 0, 0, extraEnv)
+	default:
 		res, err = m.platformStartInternalTool(id, tool, toolPath, workDir, runArgs, toolSessionID)
 	}
 	if err != nil {
@@ -171,28 +440,38 @@ func (m *Manager) Create(tool, workDir string, args []string, yoloMode bool, par
 	}
 
 	s := &Session{
-		ID:              id,
-		Tool:            tool,
-		WorkDir:         workDir,
-		Args:            args,
-		PTY:             res.pty,
-		Cmd:             res.cmd,
-		CreatedAt:       time.Now(),
-		Status:          StatusRunning,
-		YoloMode:        yoloMode,
-		Internal:        internalTools[tool],
-		ToolSessionID:   toolSessionID,
-		ParentID:        parentID,
-		TmuxSessionName: res.tmuxName,
-		rawPipe:         res.rawPipe,
-		rawPipePath:     res.rawPipePath,
-		scrollback:      NewRingBuffer(defaultRingSize),
-		subscribers:     make(map[chan []byte]struct{}),
-		done:            make(chan struct{}),
-		readDone:        make(chan struct{}),
-		attachments:     make(map[string]*Attachment),
-		logger:          m.logger,
+		ID:                 id,
+		Tool:               tool,
+		WorkDir:            workDir,
+		Args:               args,
+		PTY:                res.pty,
+		Cmd:                res.cmd,
+		CreatedAt:          time.Now(),
+		Status:             StatusRunning,
+		YoloMode:           yoloMode,
+		Internal:           internalTools[tool],
+		ToolSessionID:      toolSessionID,
+		ParentID:           parentID,
+		TmuxSessionName:    res.tmuxName,
+		TmuxServer:         res.tmuxServer,
+		DirectPTY:          IsUserTool(tool) && res.tmuxName == "",
+		Host:               host,
+		Sandbox:            sandbox,
+		TokenBudget:        opts.TokenBudget,
+		Model:              model,
+		PermissionMode:     permissionMode,
+		BaseCommit:         baseCommit,
+		CheckpointInterval: opts.CheckpointInterval,
+		checkpointWake:     make(chan struct{}, 1),
+		rawPipe:            res.rawPipe,
+		rawPipePath:        res.rawPipePath,
+		scrollback:         NewRingBuffer(defaultRingSize),
+		subscribers:        make(map[chan []byte]struct{}),
+		done:               make(chan struct{}),
+		attachments:        make(map[string]*Attachment),
+		logger:             m.logger,
 	}
+	s.setTimeLimitLocked(opts.TimeLimit, s.CreatedAt)
 
 	m.mu.Lock()
 	// Atomic check-and-register: if a duplicate child was created concurrently, discard ours
@@ -214,9 +493,12 @@ func (m *Manager) Create(tool, workDir string, args []string, yoloMode bool, par
 	m.mu.Unlock()
 
 	m.platformStartLoops(s)
+	m.startLimitLoop(s)
+	m.startCheckpointLoop(s)
 
 	m.logger.Info("session created", "id", id, "tool", tool, "workDir", workDir)
 	m.save()
+	m.publishList(ListEventCreated, s.Info())
 	return s, nil
 }
 
@@ -236,8 +518,16 @@ func (m *Manager) Restart(id string) (*Session, error) {
 	workDir := s.WorkDir
 	args := s.Args
 	toolSessionID := s.ToolSessionID
+	host := s.Host
+	sandbox := s.Sandbox
+	createdAt := s.CreatedAt
+	model, permissionMode := s.Model, s.PermissionMode
 	s.mu.Unlock()
 
+	if toolSessionID == "" && host == "" {
+		toolSessionID = m.findToolSessionID(s, tool, workDir, createdAt)
+	}
+
 	clearRestarting := func() {
 		s.mu.Lock()
 		s.restarting = false
@@ -261,7 +551,16 @@ func (m *Manager) Restart(id string) (*Session, error) {
 	customResult := m.resolveCustomAPI(tool, args)
 	actualTool := customResult.actualTool
 
-	toolPath, err := resolveToolPath(tool, actualTool)
+	var remote RemoteHost
+	var toolPath string
+	var err error
+	if host != "" {
+		remote, err = m.remoteHost(host, tool)
+		toolPath = remote.toolPath(actualTool)
+	} else {
+		toolPath, err = resolveToolPath(tool, actualTool)
+		go m.warnToolVersion(actualTool, toolPath)
+	}
 	if err != nil {
 		clearRestarting()
 		return nil, err
@@ -270,17 +569,28 @@ func (m *Manager) Restart(id string) (*Session, error) {
 	// Platform-specific cleanup of old session resources
 	m.platformPrepareRestart(s)
 
-	restartArgs := buildRestartArgs(actualTool, args, toolSessionID)
+	restartArgs := buildRestartArgs(tool, args, toolSessionID)
+	if cp, ok := toolAdapters[tool].(ControlProvider); ok {
+		restartArgs = cp.WithControls(restartArgs, model, permissionMode)
+	}
 
 	extraEnv := m.buildCustomEnv(customResult)
 
+	s.mu.Lock()
+	cols, rows := s.lastCols, s.lastRows
+	s.mu.Unlock()
 	var res *startResult
-	if userTools[tool] {
-		s.mu.Lock()
-		cols, rows := s.lastCols, s.lastRows
-		s.mu.Unlock()
-		res, err = m.platformStartUserTool(id, workDir, toolPath, restartArgs, cols, rows, extraEnv)
-	} else {
+	switch {
+	case host != "":
+		res, err = platformStartRemote(remote.sshArgs(workDir, toolPath, restartArgs), cols, rows)
+	case IsUserTool(tool):
+		path, wrapped, werr := m.wrapSandbox(sandbox, toolPath, restartArgs)
+		if werr != nil {
+			clearRestarting()
+			return nil, werr
+		}
+		res, err = m.platformStartUserTool(id, workDir, path, wrapped, cols, rows, extraEnv)
+	default:
 		res, err = m.platformStartInternalTool(id, tool, toolPath, workDir, restartArgs, toolSessionID)
 	}
 	if err != nil {
@@ -293,23 +603,57 @@ func (m *Manager) Restart(id string) (*Session, error) {
 	s.Cmd = res.cmd
 	s.Args = args // Keep original args (without --resume), not restartArgs
 	s.TmuxSessionName = res.tmuxName
+	s.TmuxServer = res.tmuxServer
+	s.DirectPTY = IsUserTool(tool) && res.tmuxName == ""
 	s.rawPipe = res.rawPipe
 	s.rawPipePath = res.rawPipePath
 	s.Status = StatusRunning
+	s.Paused = false
 	s.ExitCode = nil
 	s.lastOutput = nil
+	s.outputDirty = true
 	s.restarting = false
 	s.done = make(chan struct{})
-	s.readDone = make(chan struct{})
 	s.mu.Unlock()
 
 	m.platformStartLoops(s)
+	m.startLimitLoop(s)
+	m.startCheckpointLoop(s)
 
 	m.logger.Info("session restarted", "id", id, "tool", tool)
 	m.save()
+	m.publishList(ListEventUpdated, s.Info())
 	return s, nil
 }
 
+// findToolSessionID asks tool's SessionIDFinder for the conversation
+// ID of s, which its output never showed, and records it.
+func (m *Manager) findToolSessionID(s *Session, tool, workDir string, started time.Time) string {
+	f, ok := toolAdapters[tool].(SessionIDFinder)
+	if !ok {
+		return ""
+	}
+	taken := map[string]bool{}
+	for _, other := range m.List() {
+		other.mu.Lock()
+		if other != s && other.Tool == tool {
+			taken[other.ToolSessionID] = true
+		}
+		other.mu.Unlock()
+	}
+	id := f.FindSessionID(workDir, started, func(id string) bool { return taken[id] })
+	if id == "" {
+		return ""
+	}
+	s.mu.Lock()
+	if s.ToolSessionID == "" {
+		s.ToolSessionID = id
+	}
+	s.mu.Unlock()
+	m.logger.Info("found tool session ID", "id", s.ID, "tool", tool, "toolSessionId", id)
+	return id
+}
+
 func (m *Manager) Get(id string) (*Session, bool) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -327,6 +671,49 @@ func (m *Manager) List() []*Session {
 	return list
 }
 
+// LoopStats is one session's background goroutine accounting.
+type LoopStats struct {
+	ID          string `json:"id"`
+	Tool        string `json:"tool"`
+	Status      Status `json:"status"`
+	ReadLoop    int32  `json:"readLoop"`
+	DrainLoop   int32  `json:"drainLoop"`
+	WaitLoop    int32  `json:"waitLoop"`
+	Subscribers int    `json:"subscribers"`
+	// Loops has each loop kind's liveness and refused double starts.
+	Loops map[string]LoopState `json:"loops"`
+}
+
+// LoopStats reports the running loops of every session, sorted by ID.
+// A running session normally has a readLoop and a waitLoop (plus a
+// drainLoop and reaper with tmux); an exited one should have none.
+func (m *Manager) LoopStats() []LoopStats {
+	list := m.List()
+	stats := make([]LoopStats, 0, len(list))
+	for _, s := range list {
+		s.mu.Lock()
+		st := LoopStats{ID: s.ID, Tool: s.Tool, Status: s.Status}
+		s.mu.Unlock()
+		st.Loops = s.loops.states()
+		st.ReadLoop = loopCount(st.Loops[loopRead.String()])
+		st.DrainLoop = loopCount(st.Loops[loopDrain.String()])
+		st.WaitLoop = loopCount(st.Loops[loopWait.String()])
+		s.subMu.Lock()
+		st.Subscribers = len(s.subscribers)
+		s.subMu.Unlock()
+		stats = append(stats, st)
+	}
+	slices.SortFunc(stats, func(a, b LoopStats) int { return strings.Compare(a.ID, b.ID) })
+	return stats
+}
+
+func loopCount(st LoopState) int32 {
+	if st.Running {
+		return 1
+	}
+	return 0
+}
+
 // FindChildSession returns a child session of the given parent with the specified tool.
 func (m *Manager) FindChildSession(parentID, tool string) (*Session, bool) {
 	m.mu.Lock()
@@ -380,22 +767,93 @@ func (m *Manager) stopRunningChildren(parentID string) {
 // in the session map, and logs the restore count. Called during platformInit
 // before concurrent access, so no lock is held.
 func (m *Manager) insertRestoredSessions(infos []SessionInfo) {
+	outputs := m.store.LoadOutputs()
 	for _, info := range infos {
+		stored, inRow := outputs[info.ID]
+		delete(outputs, info.ID)
+		inline := info.LastOutput != ""
+		if !inline {
+			info.LastOutput = stored
+		}
 		s := m.restoreSession(info)
+		// Output still inline in a list saved before outputs got
+		// their own rows moves out on the next flush; a row whose
+		// session was reattached (output cleared) goes away.
+		s.mu.Lock()
+		s.outputDirty = (inline && !inRow) || (inRow && len(s.lastOutput) == 0)
+		s.mu.Unlock()
 		m.sessions[info.ID] = s
 	}
+	// The rest belong to sessions that aged out of the list.
+	for id := range outputs {
+		m.store.DeleteOutput(id)
+	}
 	if len(infos) > 0 {
 		m.logger.Info("restored persisted sessions", "count", len(infos))
 	}
 }
 
-// Remove removes an exited session and its internal children from memory and persists the change.
+// Remove removes an exited session and its internal children from
+// memory and the persisted store, and cleans up what they left behind
+// on disk and in tmux.
 func (m *Manager) Remove(id string) error {
 	m.mu.Lock()
+	removed, err := m.removeLocked(id)
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	m.forgetOutputs(removed)
+	m.save()
+	m.finishRemove(removed)
+	return nil
+}
+
+// RemoveExited removes every exited top-level session along with its
+// children, skipping any with a running child. It returns the IDs of
+// the top-level sessions removed.
+func (m *Manager) RemoveExited() []string {
+	m.mu.Lock()
+	var ids []string
+	var removed []*Session
+	for id, s := range m.sessions {
+		if s.ParentID != "" {
+			if _, ok := m.sessions[s.ParentID]; ok {
+				continue // goes with its parent
+			}
+		}
+		rs, err := m.removeLocked(id)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+		removed = append(removed, rs...)
+	}
+	m.mu.Unlock()
+	if len(ids) > 0 {
+		m.forgetOutputs(removed)
+		m.save()
+		m.finishRemove(removed)
+		m.logger.Info("removed exited sessions", "count", len(ids))
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// finishRemove cleans up and announces sessions taken out of m.sessions.
+func (m *Manager) finishRemove(removed []*Session) {
+	for _, rs := range removed {
+		m.platformRemove(rs)
+		m.publishList(ListEventRemoved, rs.Info())
+	}
+}
+
+// removeLocked takes an exited session and its children out of
+// m.sessions and returns them. Caller must hold m.mu.
+func (m *Manager) removeLocked(id string) ([]*Session, error) {
 	s, ok := m.sessions[id]
 	if !ok {
-		m.mu.Unlock()
-		return fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
 	}
 	// Check for running children first to avoid orphans
 	for _, cs := range m.sessions {
@@ -404,27 +862,25 @@ func (m *Manager) Remove(id string) error {
 			cStatus := cs.Status
 			cs.mu.Unlock()
 			if cStatus == StatusRunning {
-				m.mu.Unlock()
-				return fmt.Errorf("%w: %s", ErrHasRunningChildren, id)
+				return nil, fmt.Errorf("%w: %s", ErrHasRunningChildren, id)
 			}
 		}
 	}
 	s.mu.Lock()
 	if s.Status == StatusRunning || s.restarting {
 		s.mu.Unlock()
-		m.mu.Unlock()
-		return fmt.Errorf("%w: %s", ErrSessionRunning, id)
+		return nil, fmt.Errorf("%w: %s", ErrSessionRunning, id)
 	}
 	delete(m.sessions, id)
 	s.mu.Unlock()
+	removed := []*Session{s}
 	for cid, cs := range m.sessions {
 		if cs.ParentID == id {
 			delete(m.sessions, cid)
+			removed = append(removed, cs)
 		}
 	}
-	m.mu.Unlock()
-	m.save()
-	return nil
+	return removed, nil
 }
 
 func (m *Manager) Stop(id string) error {
@@ -438,11 +894,52 @@ func (m *Manager) Stop(id string) error {
 		s.mu.Unlock()
 		return fmt.Errorf("%w: %s", ErrSessionNotRunning, id)
 	}
+	paused := s.Paused
 	s.mu.Unlock()
 
+	// A stopped tool can't act on the hangup or SIGTERM that ends it.
+	if paused {
+		_ = m.Resume(id)
+	}
 	return m.platformStop(s, id)
 }
 
+// Signal sends the named signal ("SIGINT", "KILL", ...) to the tool
+// process of a running session, for a hung tool that ignores the
+// terminal. Unlike Stop it leaves the tmux session alone: after
+// SIGSTOP the session keeps running, and after a fatal signal it exits
+// through the usual wait path.
+func (m *Manager) Signal(id, name string) error {
+	s, ok := m.Get(id)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	sig, ok := parseSignal(name)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnsupportedSignal, name)
+	}
+
+	s.mu.Lock()
+	running := s.Status == StatusRunning && !s.restarting
+	s.mu.Unlock()
+	if !running {
+		return fmt.Errorf("%w: %s", ErrSessionNotRunning, id)
+	}
+
+	m.logger.Info("signalling session", "id", id, "signal", name)
+	if err := m.platformSignal(s, sig); err != nil {
+		return err
+	}
+	// Keep the paused state in step with a STOP or CONT sent by hand.
+	switch strings.TrimPrefix(strings.ToUpper(name), "SIG") {
+	case "STOP":
+		m.markPaused(s, true)
+	case "CONT":
+		m.markPaused(s, false)
+	}
+	return nil
+}
+
 // TmuxAction executes a whitelisted tmux action on a terminal session.
 func (m *Manager) TmuxAction(id, action string) error {
 	s, ok := m.Get(id)
@@ -477,24 +974,82 @@ func (m *Manager) StopAll() {
 	m.platformStopAll()
 }
 
-// SaveAll persists all sessions to disk. Called on shutdown.
+// SaveAll persists all sessions now, including a pending debounced
+// save. Called on shutdown.
 func (m *Manager) SaveAll() {
-	m.save()
+	m.flush()
 }
 
+// saveDebounce is how long save waits for more changes before writing,
+// so a burst of creates and exits costs one write.
+const saveDebounce = 500 * time.Millisecond
+
+// save schedules a flush within saveDebounce.
 func (m *Manager) save() {
+	m.saveMu.Lock()
+	defer m.saveMu.Unlock()
+	if m.saveTimer == nil {
+		m.saveTimer = time.AfterFunc(saveDebounce, m.flush)
+	}
+}
+
+// forgetOutputs queues the stored output of removed sessions for
+// deletion by the next flush.
+func (m *Manager) forgetOutputs(removed []*Session) {
+	m.saveMu.Lock()
+	defer m.saveMu.Unlock()
+	for _, rs := range removed {
+		m.removedOutputs = append(m.removedOutputs, rs.ID)
+	}
+}
+
+// flush writes the session list and the outputs that changed since the
+// last flush. Session state is read outside m.mu, so the store's disk
+// I/O never holds up the manager.
+func (m *Manager) flush() {
+	m.flushMu.Lock()
+	defer m.flushMu.Unlock()
+
+	m.saveMu.Lock()
+	if m.saveTimer != nil {
+		m.saveTimer.Stop()
+		m.saveTimer = nil
+	}
+	removed := m.removedOutputs
+	m.removedOutputs = nil
+	m.saveMu.Unlock()
+
 	m.mu.Lock()
-	infos := make([]SessionInfo, 0, len(m.sessions))
+	sessions := make([]*Session, 0, len(m.sessions))
 	for _, s := range m.sessions {
-		infos = append(infos, s.InfoForSave())
+		sessions = append(sessions, s)
 	}
 	m.mu.Unlock()
+
+	infos := make([]SessionInfo, 0, len(sessions))
+	for _, s := range sessions {
+		if out, ok := s.dirtyOutput(); ok {
+			if err := m.store.SaveOutput(s.ID, out); err != nil {
+				s.outputNotStored()
+			}
+		}
+		infos = append(infos, s.InfoForSave())
+	}
+	for _, id := range removed {
+		m.store.DeleteOutput(id)
+	}
 	m.store.Save(infos)
 }
 
-func (m *Manager) readLoop(s *Session) {
-	defer close(s.readDone)
+// startLoop runs one of s's background loops under its supervisor. A
+// start refused because the loop is still running is logged.
+func (m *Manager) startLoop(s *Session, k loopKind, loop func(*Session)) {
+	if !s.loops.start(k, func() { loop(s) }) {
+		m.logger.Warn("session loop already running, not starting another", "id", s.ID, "loop", k.String())
+	}
+}
 
+func (m *Manager) readLoop(s *Session) {
 	s.mu.Lock()
 	// Prefer raw pipe (pipe-pane FIFO) for complete output capture;
 	// fall back to PTY output.
@@ -516,8 +1071,9 @@ func (m *Manager) readLoop(s *Session) {
 		if n > 0 {
 			data := make([]byte, n)
 			copy(data, buf[:n])
-			s.scrollback.Write(data)
-			s.broadcast(data)
+			out := s.redactOutput(m.redactRes, data)
+			s.scrollback.Write(out)
+			s.broadcast(out)
 
 			// capture tool session ID from output (e.g. codex)
 			s.CaptureToolSessionID(data)
@@ -529,6 +1085,9 @@ func (m *Manager) readLoop(s *Session) {
 			}
 			if approval != nil {
 				m.logger.Info("yolo auto-approve", "id", s.ID, "matched", approval.Matched)
+				if m.OnYoloApprove != nil {
+					go m.OnYoloApprove(s, approval.Matched)
+				}
 				time.AfterFunc(yoloApproveDelay, func() {
 					if !s.IsYoloMode() {
 						return
@@ -539,10 +1098,39 @@ func (m *Manager) readLoop(s *Session) {
 				})
 			}
 
+			// output notify patterns
+			if line, ok := s.CheckNotifyPatterns(out); ok && m.OnOutputMatch != nil {
+				go m.OnOutputMatch(s, line)
+			}
+			// webhook patterns; a fired one is saved as matched
+			if hits := s.CheckWebhookPatterns(out); len(hits) > 0 {
+				m.save()
+				if m.OnWebhook != nil {
+					for _, hit := range hits {
+						go m.OnWebhook(s, hit)
+					}
+				}
+			}
+
 			// attachment detection
 			if newAttachments := s.CheckAttachments(data); len(newAttachments) > 0 {
 				s.BroadcastAttachments(newAttachments)
 			}
+
+			// OSC 52 clipboard copies
+			for _, text := range s.CheckClipboard(data) {
+				s.BroadcastClipboard(text)
+			}
+
+			// bell and window title
+			if events := s.CheckTermEvents(data); len(events) > 0 {
+				m.handleTermEvents(s, events)
+			}
+
+			// inline images (iTerm2 / Sixel)
+			for _, img := range s.CheckInlineImages(data) {
+				s.BroadcastTermEvent(TermEvent{Type: "image", Image: img})
+			}
 		}
 		if err != nil {
 			if err != io.EOF {
@@ -563,7 +1151,7 @@ func (m *Manager) waitLoop(s *Session) {
 	s.mu.Unlock()
 
 	// wait for readLoop to finish draining
-	<-s.readDone
+	<-s.loops.done(loopRead)
 
 	exitCode := 0
 	if err != nil {
@@ -577,9 +1165,7 @@ func (m *Manager) waitLoop(s *Session) {
 
 // awaitReadDone waits for readLoop to finish with a timeout.
 func (m *Manager) awaitReadDone(s *Session) {
-	select {
-	case <-s.readDone:
-	case <-time.After(exitDrainTimeout):
+	if !s.loops.wait(loopRead, exitDrainTimeout) {
 		m.logger.Warn("readLoop did not exit in time, proceeding with session exit", "id", s.ID)
 	}
 }
@@ -587,33 +1173,43 @@ func (m *Manager) awaitReadDone(s *Session) {
 // completeExit captures final output, updates session state, and notifies.
 func (m *Manager) completeExit(s *Session, exitCode int) {
 	// capture last output from scrollback
-	scrollback := s.scrollback.Bytes()
-	if len(scrollback) > maxLastOutput {
-		scrollback = scrollback[len(scrollback)-maxLastOutput:]
-	}
+	scrollback := s.scrollback.Tail(maxLastOutput)
 
 	s.mu.Lock()
 	s.Status = StatusExited
+	s.Paused = false
 	s.lastOutput = scrollback
+	s.outputDirty = true
 	s.ExitCode = &exitCode
 	s.mu.Unlock()
 
 	close(s.done)
 	m.save()
+	m.publishList(ListEventExited, s.Info())
+	go m.autoCheckpoint(s, "exited")
 
 	// Stop child sessions when parent exits
 	m.stopRunningChildren(s.ID)
 
 	m.logger.Info("session exited", "id", s.ID, "exitCode", s.ExitCode)
 
-	if m.OnSessionExit != nil {
+	// Sessions stopped by a kojo shutdown didn't exit on their own.
+	m.mu.Lock()
+	shuttingDown := m.shuttingDown
+	m.mu.Unlock()
+	if m.OnSessionExit != nil && !shuttingDown {
 		m.OnSessionExit(s)
 	}
+	if m.OnWebhook != nil && !shuttingDown {
+		for _, h := range s.ExitWebhooks() {
+			go m.OnWebhook(s, WebhookHit{Webhook: h, Event: WebhookExit})
+		}
+	}
 }
 
 // customAPIResult holds the result of resolving custom API configuration.
 type customAPIResult struct {
-	actualTool string   // the actual tool to execute ("claude" if custom)
+	actualTool string   // the executable to run (ToolAdapter.Binary)
 	args       []string // args (unchanged for non-custom)
 	baseURL    string   // custom API base URL
 }
@@ -621,6 +1217,9 @@ type customAPIResult struct {
 // resolveCustomAPI resolves custom → claude with ANTHROPIC_BASE_URL.
 func (m *Manager) resolveCustomAPI(tool string, args []string) customAPIResult {
 	result := customAPIResult{actualTool: tool, args: args}
+	if a := toolAdapters[tool]; a != nil {
+		result.actualTool = a.Binary()
+	}
 	if tool != "custom" {
 		return result
 	}
@@ -633,7 +1232,6 @@ func (m *Manager) resolveCustomAPI(tool string, args []string) customAPIResult {
 		baseURL = "http://localhost:8080"
 	}
 
-	result.actualTool = "claude"
 	result.baseURL = baseURL
 	return result
 }
@@ -652,7 +1250,7 @@ func (m *Manager) buildCustomEnv(r customAPIResult) []string {
 // resolveToolPath resolves the executable path for a tool.
 // Internal tools (tmux/shell) are resolved by platform functions, not LookPath.
 func resolveToolPath(tool, actualTool string) (string, error) {
-	if !userTools[tool] {
+	if !IsUserTool(tool) {
 		return "", nil
 	}
 	toolPath, err := exec.LookPath(actualTool)
@@ -662,167 +1260,69 @@ func resolveToolPath(tool, actualTool string) (string, error) {
 	return toolPath, nil
 }
 
-// yoloNativeFlag returns the tool-native permission-bypass flag for a tool,
-// or "" when the tool has no native flag (yolo is handled by the PTY
-// auto-approve machinery in that case). Keyed on the user-facing tool name.
-func yoloNativeFlag(tool string) string {
-	switch tool {
-	case "claude":
-		return "--dangerously-skip-permissions"
-	case "codex":
-		return "--dangerously-bypass-approvals-and-sandbox"
-	default:
-		return ""
-	}
+// gitHead returns the commit checked out in workDir, "" outside a git
+// repository or before its first commit.
+func gitHead(workDir string) string {
+	head, _ := runGit(workDir, nil, "rev-parse", "-q", "--verify", "HEAD")
+	return head
 }
 
-// appendYoloFlag appends the tool-native yolo flag to args when yoloMode is
-// enabled and the tool supports one. It is a no-op when yolo is off, the tool
-// has no native flag, or the flag is already present (guards against a user
-// who passed the flag manually in Additional Arguments).
+// appendYoloFlag appends the tool-native yolo flag (ToolAdapter.YoloFlag)
+// to args when yoloMode is enabled and the tool has one. It is a no-op
+// when yolo is off, the tool has no native flag, or the flag is already
+// present (guards against a user who passed the flag manually in
+// Additional Arguments).
 func appendYoloFlag(tool string, args []string, yoloMode bool) []string {
-	if !yoloMode {
+	a := toolAdapters[tool]
+	if !yoloMode || a == nil || a.YoloFlag() == "" {
 		return args
 	}
-	flag := yoloNativeFlag(tool)
-	if flag == "" {
-		return args
-	}
-	for _, a := range args {
-		if a == flag {
+	flag := a.YoloFlag()
+	for _, arg := range args {
+		if arg == flag {
 			return args
 		}
 	}
 	return append(args, flag)
 }
 
-// assignClaudeSessionID assigns a stable session ID for claude sessions
-// and builds the run args. For non-claude tools, returns empty sessionID
-// and the original args.
-func assignClaudeSessionID(actualTool string, args []string) (string, []string) {
-	if actualTool != "claude" {
-		return "", args
+// assignToolSessionID assigns the conversation ID of a new session of
+// tool and builds the run args; see ToolAdapter.AssignSessionID.
+func assignToolSessionID(tool string, args []string) (string, []string) {
+	if a := toolAdapters[tool]; a != nil {
+		return a.AssignSessionID(args)
 	}
+	return "", args
+}
 
-	var toolSessionID string
-	for i, a := range args {
-		if a == "--session-id" {
-			if i+1 < len(args) {
-				toolSessionID = args[i+1]
+// dropFlag removes every occurrence of flag from args, including the
+// "flag=value" form and, when it takes a value, the argument after it.
+func dropFlag(args []string, flag string, takesValue bool) []string {
+	out := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == flag:
+			if takesValue {
+				i++
 			}
-			return toolSessionID, args
-		}
-		if strings.HasPrefix(a, "--session-id=") {
-			toolSessionID = strings.TrimPrefix(a, "--session-id=")
-			return toolSessionID, args
+		case strings.HasPrefix(args[i], flag+"="):
+		default:
+			out = append(out, args[i])
 		}
 	}
-
-	// No --session-id found; generate one and append.
-	toolSessionID = uuid.New().String()
-	runArgs := make([]string, len(args), len(args)+2)
-	copy(runArgs, args)
-	runArgs = append(runArgs, "--session-id", toolSessionID)
-	return toolSessionID, runArgs
+	return out
 }
 
 // buildRestartArgs produces the command arguments for restarting a session.
 func buildRestartArgs(tool string, origArgs []string, toolSessionID string) []string {
-	switch tool {
-	case "claude":
-		args := make([]string, 0, len(origArgs)+2)
-		skipNext := false
-		for _, a := range origArgs {
-			if skipNext {
-				skipNext = false
-				continue
-			}
-			if a == "--resume" || a == "-r" {
-				skipNext = true
-				continue
-			}
-			if a == "--continue" || a == "-c" {
-				continue
-			}
-			args = append(args, a)
-		}
-		if toolSessionID != "" {
-			return append(args, "--resume", toolSessionID)
-		}
-		return append(args, "--continue")
-
-	case "codex":
-		if toolSessionID != "" {
-			return []string{"resume", toolSessionID}
-		}
-		return []string{"resume", "--last"}
-
-	case "grok":
-		// Strip any prior --continue/-c and --resume/-r (including
-		// "--resume=<id>" / "-r=<id>") from origArgs so we can re-
-		// apply a deterministic restart flag. Grok's -r/--resume
-		// takes an OPTIONAL argument: the next positional is its
-		// value only when it doesn't look like another flag.
-		//
-		// preservedResumeID captures an explicit resume target the
-		// user originally passed to `grok`. kojo's PTY layer does
-		// not capture grok session IDs from interactive output, so
-		// toolSessionID is virtually always "" — without preserving
-		// the user's original ID the restart would silently degrade
-		// to --continue and pick whichever session grok last
-		// touched in this cwd, defeating the explicit resume.
-		args := make([]string, 0, len(origArgs)+2)
-		skipNext := false
-		var preservedResumeID string
-		for i, a := range origArgs {
-			if skipNext {
-				skipNext = false
-				continue
-			}
-			if a == "--continue" || a == "-c" {
-				continue
-			}
-			if a == "--resume" || a == "-r" {
-				if i+1 < len(origArgs) && !strings.HasPrefix(origArgs[i+1], "-") {
-					preservedResumeID = origArgs[i+1]
-					skipNext = true
-				}
-				continue
-			}
-			if v, ok := strings.CutPrefix(a, "--resume="); ok {
-				preservedResumeID = v
-				continue
-			}
-			if v, ok := strings.CutPrefix(a, "-r="); ok {
-				preservedResumeID = v
-				continue
-			}
-			args = append(args, a)
-		}
-		// Re-apply the resume target as --resume=<id> (joined form)
-		// rather than --resume <id> (split form). Reason: grok's
-		// --resume takes an OPTIONAL argument, so a split-form value
-		// that happens to start with "-" (e.g. a malformed/stored
-		// "-r=foo") would be parsed as the NEXT flag rather than the
-		// resume target. The joined form is unambiguous.
-		switch {
-		case toolSessionID != "":
-			return append(args, "--resume="+toolSessionID)
-		case preservedResumeID != "":
-			return append(args, "--resume="+preservedResumeID)
-		default:
-			return append(args, "--continue")
-		}
-
-	default:
-		// Internal tools (tmux/shell) use platform-specific restart args
-		if internalTools[tool] {
-			return buildInternalToolRestartArgs(origArgs, toolSessionID)
-		}
-		out := make([]string, len(origArgs))
-		copy(out, origArgs)
-		return out
+	if a := toolAdapters[tool]; a != nil {
+		return a.ResumeArgs(origArgs, toolSessionID)
+	}
+	// Internal tools (tmux/shell) use platform-specific restart args
+	if internalTools[tool] {
+		return buildInternalToolRestartArgs(origArgs, toolSessionID)
 	}
+	return slices.Clone(origArgs)
 }
 
 func generateID() string {
@@ -832,25 +1332,52 @@ func generateID() string {
 }
 
 // ToolAvailability checks which user-facing tools are available on this system.
+// Versions are asked for in parallel and cached; see toolVersion.
 func ToolAvailability() map[string]ToolInfo {
-	result := make(map[string]ToolInfo)
-	for tool := range userTools {
-		if tool == "custom" {
-			// custom requires claude CLI (used as client with ANTHROPIC_BASE_URL).
-			claudePath, err := exec.LookPath("claude")
-			result[tool] = ToolInfo{Available: err == nil, Path: claudePath}
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		result = make(map[string]ToolInfo)
+	)
+	for tool, a := range toolAdapters {
+		// custom requires claude CLI (used as client with ANTHROPIC_BASE_URL).
+		binary := a.Binary()
+		path, err := exec.LookPath(binary)
+		if err != nil {
+			mu.Lock()
+			result[tool] = ToolInfo{Path: path}
+			mu.Unlock()
 			continue
 		}
-		path, err := exec.LookPath(tool)
-		result[tool] = ToolInfo{
-			Available: err == nil,
-			Path:      path,
-		}
+		wg.Go(func() {
+			info := ToolInfo{Available: true, Path: path, Version: toolVersion(path), MinVersion: minToolVersions[binary]}
+			info.Supported = versionSupported(binary, info.Version)
+			mu.Lock()
+			result[tool] = info
+			mu.Unlock()
+		})
 	}
+	wg.Wait()
 	return result
 }
 
+// TmuxVersion is what `tmux -V` printed, e.g. "tmux 3.4", or "" when
+// tmux isn't installed. It runs tmux once per process.
+var TmuxVersion = sync.OnceValue(func() string {
+	out, err := exec.Command("tmux", "-V").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+})
+
 type ToolInfo struct {
 	Available bool   `json:"available"`
 	Path      string `json:"path"`
+	// Version is what `<tool> --version` reported, empty if unknown.
+	// Supported is false when it is older than MinVersion, the oldest
+	// release whose resume flags kojo relies on.
+	Version    string `json:"version,omitempty"`
+	MinVersion string `json:"minVersion,omitempty"`
+	Supported  bool   `json:"supported"`
 }
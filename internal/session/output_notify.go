@@ -0,0 +1,159 @@
+package session
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Output notify patterns are regular expressions matched against each
+// line of session output (ANSI escapes stripped). A match becomes a
+// push notification carrying the line, so an agent that prints a panic
+// and then sits at a prompt still gets noticed.
+
+const (
+	// maxNotifyPatterns and maxNotifyPatternLen bound what PATCH
+	// accepts.
+	maxNotifyPatterns   = 16
+	maxNotifyPatternLen = 256
+	// maxNotifyLine caps the partial line carried between reads;
+	// beyond it the line is matched as is.
+	maxNotifyLine = 4096
+	// outputNotifyInterval spaces out pattern notifications per
+	// session. A stack trace matches many lines at once.
+	outputNotifyInterval = time.Minute
+)
+
+// ErrInvalidNotifyPattern is returned for a pattern that doesn't
+// compile or exceeds the limits.
+var ErrInvalidNotifyPattern = errors.New("invalid notify pattern")
+
+// compileNotifyPatterns joins patterns into one regexp, nil for none.
+func compileNotifyPatterns(patterns []string) (*regexp.Regexp, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	if len(patterns) > maxNotifyPatterns {
+		return nil, fmt.Errorf("%w: at most %d patterns", ErrInvalidNotifyPattern, maxNotifyPatterns)
+	}
+	parts := make([]string, len(patterns))
+	for i, p := range patterns {
+		if p == "" || len(p) > maxNotifyPatternLen {
+			return nil, fmt.Errorf("%w: %q must be 1-%d bytes", ErrInvalidNotifyPattern, p, maxNotifyPatternLen)
+		}
+		if _, err := regexp.Compile(p); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidNotifyPattern, err)
+		}
+		parts[i] = "(?:" + p + ")"
+	}
+	return regexp.Compile(strings.Join(parts, "|"))
+}
+
+// SetNotifyPatterns replaces the session's output notify patterns; an
+// empty list turns them off.
+func (s *Session) SetNotifyPatterns(patterns []string) error {
+	re, err := compileNotifyPatterns(patterns)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.NotifyPatterns = patterns
+	s.notifyRe = re
+	s.notifyLine = nil
+	return nil
+}
+
+// CheckNotifyPatterns feeds output through the notify patterns and
+// returns the first complete line that matched, if a notification is
+// due. Matches inside outputNotifyInterval of the last one are dropped.
+func (s *Session) CheckNotifyPatterns(data []byte) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.notifyRe == nil {
+		return "", false
+	}
+	var hit string
+	found := false
+	feedLines(&s.notifyLine, data, func(line string) {
+		if !found && s.notifyRe.MatchString(line) {
+			hit, found = line, true
+		}
+	})
+	if !found {
+		return "", false
+	}
+	now := time.Now()
+	if now.Sub(s.lastOutputNotify) < outputNotifyInterval {
+		return "", false
+	}
+	s.lastOutputNotify = now
+	return hit, true
+}
+
+// feedLines appends data to the partial line in *buf and calls fn with
+// each line that completes, ANSI escapes stripped. A partial line of
+// maxNotifyLine bytes counts as complete.
+func feedLines(buf *[]byte, data []byte, fn func(line string)) {
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			*buf = append(*buf, data...)
+			if len(*buf) < maxNotifyLine {
+				return
+			}
+			data = nil
+		} else {
+			*buf = append(*buf, data[:i]...)
+			data = data[i+1:]
+		}
+		line := strings.TrimSpace(string(ansiRe.ReplaceAll(*buf, []byte(" "))))
+		*buf = (*buf)[:0]
+		fn(line)
+	}
+}
+
+// maxTailScan is how much of the end of the output TailLines looks at.
+const maxTailScan = 8192
+
+// TailLines returns up to n of the last non-blank lines of output, ANSI
+// escapes stripped, for a notification snippet. A line redrawn with
+// carriage returns (a progress bar) counts as its final state.
+func (s *Session) TailLines(n int) []string {
+	s.mu.Lock()
+	out := s.lastOutput
+	s.mu.Unlock()
+	if len(out) == 0 {
+		out = s.scrollback.Tail(maxTailScan)
+	}
+	if len(out) > maxTailScan {
+		out = out[len(out)-maxTailScan:]
+	}
+	return tailLines(out, n)
+}
+
+func tailLines(out []byte, n int) []string {
+	if n <= 0 {
+		return nil
+	}
+	clean := ansiRe.ReplaceAll(out, nil)
+	var lines []string
+	for rest := clean; len(rest) > 0 && len(lines) < n; {
+		i := bytes.LastIndexByte(rest, '\n')
+		line := rest[i+1:]
+		rest = rest[:max(i, 0)]
+		line = bytes.TrimRight(line, "\r")
+		if j := bytes.LastIndexByte(line, '\r'); j >= 0 {
+			line = line[j+1:]
+		}
+		if s := strings.TrimRight(strings.ToValidUTF8(string(line), ""), " \t"); strings.TrimSpace(s) != "" {
+			lines = append(lines, s)
+		}
+	}
+	slices.Reverse(lines)
+	return lines
+}
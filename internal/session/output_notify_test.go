@@ -0,0 +1,56 @@
+package session
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCheckNotifyPatterns(t *testing.T) {
+	s := newTestSession(false)
+	if err := s.SetNotifyPatterns([]string{`FATAL|panic:`, `Traceback`}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.CheckNotifyPatterns([]byte("building...\r\nok\r\n")); ok {
+		t.Fatal("matched unrelated output")
+	}
+	// The match is split across reads and wrapped in color codes.
+	if _, ok := s.CheckNotifyPatterns([]byte("\x1b[31mpan")); ok {
+		t.Fatal("matched an incomplete line")
+	}
+	line, ok := s.CheckNotifyPatterns([]byte("ic: nil map\x1b[0m\r\n"))
+	if !ok || line != "panic: nil map" {
+		t.Fatalf("CheckNotifyPatterns = %q, %v", line, ok)
+	}
+	if _, ok := s.CheckNotifyPatterns([]byte("Traceback (most recent call last):\n")); ok {
+		t.Fatal("second match inside the throttle interval")
+	}
+	s.lastOutputNotify = time.Now().Add(-outputNotifyInterval)
+	if _, ok := s.CheckNotifyPatterns([]byte("Traceback (most recent call last):\n")); !ok {
+		t.Fatal("no match after the throttle interval")
+	}
+}
+
+func TestSetNotifyPatternsRejectsBadRegexp(t *testing.T) {
+	s := newTestSession(false)
+	if err := s.SetNotifyPatterns([]string{"FATAL("}); !errors.Is(err, ErrInvalidNotifyPattern) {
+		t.Fatalf("err = %v, want ErrInvalidNotifyPattern", err)
+	}
+	if err := s.SetNotifyPatterns(nil); err != nil || s.notifyRe != nil {
+		t.Fatalf("clearing patterns: err %v, re %v", err, s.notifyRe)
+	}
+}
+
+func TestTailLines(t *testing.T) {
+	s := newTestSession(false)
+	s.lastOutput = []byte("one\r\ntwo\r\n\x1b[31merror:\x1b[0m bad input\r\n\r\n  \r\n10%\r50%\r100%\r\n$ ")
+	got := s.TailLines(3)
+	want := []string{"error: bad input", "100%", "$"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("TailLines(3) = %q, want %q", got, want)
+	}
+	if got := s.TailLines(10); len(got) != 5 || got[0] != "one" {
+		t.Fatalf("TailLines(10) = %q", got)
+	}
+}
@@ -0,0 +1,63 @@
+package session
+
+import "fmt"
+
+// Pause freezes a running session's tool with SIGSTOP, sent to the
+// pane's foreground process group, so a runaway agent stops without
+// losing its context. Info reports the session as StatusPaused until
+// Resume. Pausing a paused session does nothing. Windows has no
+// SIGSTOP, so there it fails with ErrUnsupportedSignal.
+func (m *Manager) Pause(id string) error { return m.setPaused(id, true) }
+
+// Resume wakes a paused session's tool with SIGCONT.
+func (m *Manager) Resume(id string) error { return m.setPaused(id, false) }
+
+func (m *Manager) setPaused(id string, paused bool) error {
+	s, ok := m.Get(id)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	name := "CONT"
+	if paused {
+		name = "STOP"
+	}
+	sig, ok := parseSignal(name)
+	if !ok {
+		return fmt.Errorf("%w: SIG%s is not available on this platform", ErrUnsupportedSignal, name)
+	}
+
+	s.mu.Lock()
+	running := s.Status == StatusRunning && !s.restarting
+	same := s.Paused == paused
+	s.mu.Unlock()
+	if !running {
+		return fmt.Errorf("%w: %s", ErrSessionNotRunning, id)
+	}
+	if same {
+		return nil
+	}
+
+	if err := m.platformSignal(s, sig); err != nil {
+		return err
+	}
+	m.markPaused(s, paused)
+	if paused {
+		go m.autoCheckpoint(s, "paused")
+		m.logger.Info("session paused", "id", id)
+	} else {
+		m.logger.Info("session resumed", "id", id)
+	}
+	return nil
+}
+
+// markPaused records s's paused state, saving and announcing a change.
+func (m *Manager) markPaused(s *Session, paused bool) {
+	s.mu.Lock()
+	changed := s.Paused != paused
+	s.Paused = paused
+	s.mu.Unlock()
+	if changed {
+		m.save()
+		m.publishList(ListEventUpdated, s.Info())
+	}
+}
@@ -0,0 +1,60 @@
+//go:build !windows
+
+package session
+
+import (
+	"errors"
+	"log/slog"
+	"os/exec"
+	"testing"
+)
+
+func TestPauseResume(t *testing.T) {
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+	s := newTestSession(false)
+	s.ID, s.Status, s.Cmd = "s1", StatusRunning, cmd
+	m := &Manager{
+		sessions: map[string]*Session{"s1": s},
+		logger:   slog.Default(),
+		store:    newStore(slog.Default(), nil, ""),
+	}
+
+	if err := m.Pause("s1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Info().Status; got != StatusPaused {
+		t.Errorf("status after Pause = %q", got)
+	}
+	if !s.InfoForSave().Paused || s.InfoForSave().Status != StatusRunning {
+		t.Error("saved info should stay running and record the pause")
+	}
+	if err := m.Pause("s1"); err != nil {
+		t.Errorf("second Pause: %v", err)
+	}
+	if err := m.Resume("s1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Info().Status; got != StatusRunning {
+		t.Errorf("status after Resume = %q", got)
+	}
+
+	// A STOP sent by hand counts as a pause too.
+	if err := m.Signal("s1", "SIGSTOP"); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Info().Status; got != StatusPaused {
+		t.Errorf("status after SIGSTOP = %q", got)
+	}
+	_ = m.Resume("s1")
+
+	if err := m.Pause("nope"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Pause of unknown session = %v", err)
+	}
+}
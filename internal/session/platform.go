@@ -13,4 +13,5 @@ type startResult struct {
 	rawPipe     *os.File // Unix: FIFO reader, Windows: nil
 	rawPipePath string   // Unix: FIFO path, Windows: ""
 	tmuxName    string   // Unix: tmux session name, Windows: ""
+	tmuxServer  string   // Unix: tmux -L socket of tmuxName, Windows: ""
 }
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"syscall"
 	"time"
 
 	"github.com/creack/pty/v2"
@@ -23,12 +25,38 @@ func (m *Manager) platformInit() {
 	loadOK := m.loadPersistedSessions()
 	if loadOK {
 		m.cleanupOrphanedTmuxSessions()
+		if m.backend == BackendTmux {
+			m.reapOrphans(nil)
+			go m.reapOrphanLoop()
+		}
+	}
+	if m.backend == BackendTmux {
+		go m.watchLegacyTmuxSessions()
+	}
+}
+
+// platformResolveBackend picks the user tool backend: what was asked
+// for, or with auto, tmux when it is on PATH.
+func platformResolveBackend(backend string) string {
+	switch backend {
+	case BackendTmux, BackendPTY:
+		return backend
+	}
+	if _, err := exec.LookPath("tmux"); err != nil {
+		return BackendPTY
 	}
+	return BackendTmux
 }
 
-// platformStartUserTool starts a user-facing tool inside a tmux session.
+// platformStartUserTool starts a user-facing tool inside a tmux session,
+// or on a direct PTY with BackendPTY.
 func (m *Manager) platformStartUserTool(id, workDir, toolPath string, args []string, cols, rows uint16, envVars []string) (*startResult, error) {
+	if m.backend == BackendPTY {
+		return startDirectPTY(workDir, toolPath, args, cols, rows, envVars)
+	}
 	tmuxName := tmuxSessionName(id)
+	// A restart moves a legacy session onto kojo's server.
+	setTmuxLegacy(tmuxName, false)
 	res, err := m.startTmuxAttach(tmuxName, workDir, toolPath, args, cols, rows, envVars)
 	if err != nil {
 		return nil, err
@@ -39,9 +67,43 @@ func (m *Manager) platformStartUserTool(id, workDir, toolPath string, args []str
 		rawPipe:     res.rawPipe,
 		rawPipePath: res.rawPipePath,
 		tmuxName:    tmuxName,
+		tmuxServer:  tmuxSocket,
 	}, nil
 }
 
+// startDirectPTY runs a user tool on a PTY of its own, wrapped in the
+// same interactive login shell tmux sessions get. Nothing keeps it
+// alive past kojo.
+func startDirectPTY(workDir, toolPath string, args []string, cols, rows uint16, envVars []string) (*startResult, error) {
+	shell := loginShellPath()
+	if _, err := os.Stat(shell); err != nil {
+		shell = "/bin/sh" // minimal containers: no $SHELL, no zsh
+	}
+	argv := loginExecArgv(shell, toolArgv(toolPath, args, envVars))
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Dir = workDir
+	cmd.Env = append(os.Environ(), "TERM=xterm-256color")
+	ws := defaultWinsize(cols, rows)
+	ptmx, err := pty.StartWithSize(cmd, &ws)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start pty: %w", err)
+	}
+	return &startResult{pty: ptmx, cmd: cmd}, nil
+}
+
+// platformStartRemote runs ssh with args on a PTY for a remote host
+// session.
+func platformStartRemote(args []string, cols, rows uint16) (*startResult, error) {
+	cmd := exec.Command("ssh", args...)
+	cmd.Env = append(os.Environ(), "TERM=xterm-256color")
+	ws := defaultWinsize(cols, rows)
+	ptmx, err := pty.StartWithSize(cmd, &ws)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start ssh: %w", err)
+	}
+	return &startResult{pty: ptmx, cmd: cmd}, nil
+}
+
 // platformStartInternalTool starts an internal tool (tmux) with a direct PTY.
 func (m *Manager) platformStartInternalTool(id, tool, toolPath, workDir string, args []string, toolSessionID string) (*startResult, error) {
 	// Internal tools resolve their own executable (toolPath may be empty)
@@ -60,26 +122,25 @@ func (m *Manager) platformStartInternalTool(id, tool, toolPath, workDir string,
 		return nil, fmt.Errorf("failed to start pty: %w", err)
 	}
 	if tool == "tmux" && toolSessionID != "" {
-		tmuxEnableMouse(toolSessionID)
-		tmuxSetLoginShell(toolSessionID)
+		tmuxSetTerminalOptions(toolSessionID)
 	}
 	return &startResult{pty: ptmx, cmd: cmd}, nil
 }
 
 // platformStartLoops starts the background goroutines for a session.
 func (m *Manager) platformStartLoops(s *Session) {
-	go m.readLoop(s)
+	m.startLoop(s, loopRead, m.readLoop)
 	s.mu.Lock()
 	hasRawPipe := s.rawPipe != nil
 	hasTmux := s.TmuxSessionName != ""
 	s.mu.Unlock()
 	if hasRawPipe {
-		go m.drainLoop(s)
+		m.startLoop(s, loopDrain, m.drainLoop)
 	}
 	if hasTmux {
-		go m.tmuxWaitLoop(s)
+		m.startLoop(s, loopWait, m.tmuxWaitLoop)
 	} else {
-		go m.waitLoop(s)
+		m.startLoop(s, loopWait, m.waitLoop)
 	}
 }
 
@@ -99,7 +160,7 @@ func (m *Manager) platformStop(s *Session, id string) error {
 
 	// Kill tmux session for internal tmux tool
 	if tool == "tmux" && toolSessionID != "" {
-		_ = exec.Command("tmux", "kill-session", "-t", toolSessionID).Run()
+		_ = tmuxKillSession(toolSessionID)
 	}
 
 	// Also stop any child sessions (e.g. tmux terminal tab)
@@ -121,6 +182,34 @@ func (m *Manager) platformStop(s *Session, id string) error {
 	return nil
 }
 
+// platformSignal signals the tool in the session's tmux pane (or the
+// internal tmux tool's active pane), falling back to the attached
+// process for sessions without one.
+func (m *Manager) platformSignal(s *Session, sig syscall.Signal) error {
+	s.mu.Lock()
+	cmd := s.Cmd
+	target := s.TmuxSessionName
+	if target == "" && s.Tool == "tmux" {
+		target = s.ToolSessionID
+	}
+	s.mu.Unlock()
+
+	if target != "" {
+		pid, isGroup, err := tmuxPaneForeground(target)
+		if err != nil {
+			return err
+		}
+		if isGroup {
+			pid = -pid
+		}
+		return syscall.Kill(pid, sig)
+	}
+	if cmd == nil || cmd.Process == nil {
+		return fmt.Errorf("%w: %s", ErrSessionNotRunning, s.ID)
+	}
+	return cmd.Process.Signal(sig)
+}
+
 // platformStopAll stops all sessions on shutdown.
 // Tmux-backed sessions are detached (keep alive); non-tmux sessions are killed.
 func (m *Manager) platformStopAll() {
@@ -187,8 +276,8 @@ func (m *Manager) platformCleanupDuplicate(res *startResult) {
 func platformBuildInternalToolArgs(id, tool, workDir string, args []string) (runArgs []string, toolSessionID string) {
 	if tool == "tmux" {
 		toolSessionID = "kojo_" + id
-		loginCmd := tmuxLoginShellCmd()
-		runArgs = []string{"new-session", "-A", "-s", toolSessionID, "-c", workDir, loginCmd}
+		runArgs = append(tmuxServerArgs(), "new-session", "-A", "-s", toolSessionID, "-c", workDir, "--")
+		runArgs = append(runArgs, tmuxLoginShellArgv()...)
 		return
 	}
 	return args, ""
@@ -211,15 +300,39 @@ func (m *Manager) platformPrepareRestart(s *Session) {
 	s.cleanupPipePane()
 	tmuxName := s.TmuxSessionName
 	s.mu.Unlock()
-	if tmuxName != "" && tmuxHasSession(tmuxName) {
+	if tmuxName != "" && tmuxPanes.hasSession(tmuxName) {
 		_ = tmuxKillSession(tmuxName)
 	}
 }
 
+// platformRemove cleans up after a removed session: the FIFO if pipe-pane
+// left one, and the tmux session kept around by remain-on-exit (or, for
+// the tmux tool, one that was detached rather than ended).
+func (m *Manager) platformRemove(s *Session) {
+	s.mu.Lock()
+	s.cleanupPipePane()
+	tmuxName := s.TmuxSessionName
+	names := []string{tmuxName}
+	if s.Tool == "tmux" {
+		names = append(names, s.ToolSessionID)
+	}
+	s.mu.Unlock()
+	for _, name := range names {
+		if name != "" && tmuxPanes.hasSession(name) {
+			_ = tmuxKillSession(name)
+		}
+	}
+	setTmuxLegacy(tmuxName, false)
+	if tmuxName != "" {
+		_ = os.Remove(filepath.Join(os.TempDir(), "kojo", tmuxName+".pipe"))
+	}
+}
+
 // buildInternalToolRestartArgs builds restart arguments for internal tools (tmux).
 func buildInternalToolRestartArgs(origArgs []string, toolSessionID string) []string {
 	if toolSessionID != "" {
-		return []string{"new-session", "-A", "-s", toolSessionID, tmuxLoginShellCmd()}
+		args := append(tmuxServerArgs(), "new-session", "-A", "-s", toolSessionID, "--")
+		return append(args, tmuxLoginShellArgv()...)
 	}
 	return origArgs
 }
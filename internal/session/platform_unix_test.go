@@ -0,0 +1,46 @@
+//go:build !windows
+
+package session
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestStartDirectPTY(t *testing.T) {
+	t.Setenv("SHELL", "/bin/sh")
+	res, err := startDirectPTY(t.TempDir(), "/bin/echo", []string{"hello"}, 80, 24, []string{"KOJO_TEST=1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.pty.Close()
+	var out bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		io.Copy(&out, res.pty) // ends with EIO once the shell exits
+		close(done)
+	}()
+	if err := res.cmd.Wait(); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	<-done
+	if !bytes.Contains(out.Bytes(), []byte("hello")) {
+		t.Errorf("output = %q, want hello", out.String())
+	}
+	if res.tmuxName != "" {
+		t.Errorf("tmuxName = %q, want none", res.tmuxName)
+	}
+}
+
+func TestPlatformResolveBackend(t *testing.T) {
+	for _, b := range []string{BackendTmux, BackendPTY} {
+		if got := platformResolveBackend(b); got != b {
+			t.Errorf("platformResolveBackend(%q) = %q", b, got)
+		}
+	}
+	t.Setenv("PATH", t.TempDir())
+	if got := platformResolveBackend(BackendAuto); got != BackendPTY {
+		t.Errorf("auto without tmux = %q, want %q", got, BackendPTY)
+	}
+}
@@ -5,6 +5,8 @@ package session
 import (
 	"errors"
 	"fmt"
+	"os"
+	"os/exec"
 	"time"
 )
 
@@ -54,6 +56,24 @@ func (m *Manager) platformStartUserTool(id, workDir, toolPath string, args []str
 	}, nil
 }
 
+// platformStartRemote runs ssh (the OpenSSH client Windows ships) with
+// args on a ConPTY for a remote host session.
+func platformStartRemote(args []string, cols, rows uint16) (*startResult, error) {
+	sshPath, err := exec.LookPath("ssh")
+	if err != nil {
+		return nil, fmt.Errorf("%w: ssh", ErrToolNotFound)
+	}
+	home, _ := os.UserHomeDir()
+	rwc, cmd, err := startConPTY(buildCmdLine(sshPath, args), home, cols, rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start ssh: %w", err)
+	}
+	return &startResult{
+		pty: rwc,
+		cmd: cmd,
+	}, nil
+}
+
 // platformStartInternalTool starts an internal tool (shell) via ConPTY.
 func (m *Manager) platformStartInternalTool(id, tool, toolPath, workDir string, args []string, toolSessionID string) (*startResult, error) {
 	shell := defaultShell()
@@ -68,11 +88,14 @@ func (m *Manager) platformStartInternalTool(id, tool, toolPath, workDir string,
 	}, nil
 }
 
+// platformResolveBackend: user tools always run on a direct ConPTY.
+func platformResolveBackend(string) string { return BackendPTY }
+
 // platformStartLoops starts the background goroutines for a session on Windows.
 // No drainLoop or tmuxWaitLoop needed.
 func (m *Manager) platformStartLoops(s *Session) {
-	go m.readLoop(s)
-	go m.waitLoop(s)
+	m.startLoop(s, loopRead, m.readLoop)
+	m.startLoop(s, loopWait, m.waitLoop)
 }
 
 // platformStop stops a session on Windows.
@@ -96,6 +119,22 @@ func (m *Manager) platformStop(s *Session, id string) error {
 	return nil
 }
 
+// platformSignal terminates the session's process; parseSignal only
+// lets KILL through on Windows.
+func (m *Manager) platformSignal(s *Session, _ os.Signal) error {
+	s.mu.Lock()
+	cmd := s.Cmd
+	s.mu.Unlock()
+	if cmd == nil || cmd.Process == nil {
+		return fmt.Errorf("%w: %s", ErrSessionNotRunning, s.ID)
+	}
+	return cmd.Process.Kill()
+}
+
+// platformRemove has nothing to clean up on Windows: ConPTY sessions
+// leave no FIFO or tmux session behind.
+func (m *Manager) platformRemove(s *Session) {}
+
 // platformStopAll kills all running sessions on Windows (no persistence).
 func (m *Manager) platformStopAll() {
 	m.mu.Lock()
@@ -153,6 +192,11 @@ func buildInternalToolRestartArgs(origArgs []string, toolSessionID string) []str
 	return nil // shell sessions restart from scratch
 }
 
+// TmuxLockPath is not available on Windows, which has no tmux backend.
+func TmuxLockPath() (string, error) {
+	return "", errors.New("tmux is not supported on Windows")
+}
+
 // tmuxRunAction is not available on Windows.
 func tmuxRunAction(sessionName, action string) error {
 	return errors.New("tmux actions are not supported on Windows")
@@ -0,0 +1,140 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Protected paths keep a mistyped workDir from handing a tool a
+// directory it shouldn't have: sessions can't start in a protected
+// path, and yolo mode is forced off in a no-yolo path. A pattern is a
+// directory, "~" for the home directory, matching only itself; with a
+// trailing "/**" it also matches everything below it.
+
+// ErrProtectedPath is returned for a session in a protected path.
+var ErrProtectedPath = errors.New("working directory is protected")
+
+// DefaultProtectedPaths are used when ManagerOptions.ProtectedPaths is
+// nil: the filesystem root and the directories holding keys.
+var DefaultProtectedPaths = []string{"/", "~/.ssh/**", "~/.gnupg/**", "~/.aws/**"}
+
+// DefaultNoYoloPaths are used when ManagerOptions.NoYoloPaths is nil:
+// the home directory itself and the folders of personal files.
+var DefaultNoYoloPaths = []string{"~", "~/Documents/**", "~/Desktop/**", "~/Downloads/**"}
+
+// pathRule is a compiled protected path pattern.
+type pathRule struct {
+	dir     string
+	subtree bool
+}
+
+// compilePathRules expands patterns, or defaults when patterns is nil.
+// Relative patterns, and "~" ones without a home directory, are
+// dropped.
+func compilePathRules(patterns, defaults []string) []pathRule {
+	if patterns == nil {
+		patterns = defaults
+	}
+	home, _ := os.UserHomeDir()
+	var rules []pathRule
+	for _, p := range patterns {
+		dir, subtree := strings.CutSuffix(p, "/**")
+		if rest, ok := strings.CutPrefix(dir, "~"); ok && (rest == "" || rest[0] == '/') {
+			if home == "" {
+				continue
+			}
+			dir = home + rest
+		}
+		if dir == "" && subtree {
+			dir = "/"
+		}
+		dir = filepath.Clean(filepath.FromSlash(dir))
+		if !filepath.IsAbs(dir) {
+			continue
+		}
+		rules = append(rules, pathRule{dir, subtree})
+		// Match the directory however the session names it.
+		if real, err := filepath.EvalSymlinks(dir); err == nil && real != dir {
+			rules = append(rules, pathRule{real, subtree})
+		}
+	}
+	return rules
+}
+
+// matchPathRules reports whether dir, or where its symlinks lead,
+// matches one of rules.
+func matchPathRules(rules []pathRule, dir string) bool {
+	if len(rules) == 0 || dir == "" {
+		return false
+	}
+	dirs := []string{filepath.Clean(dir)}
+	if real, err := filepath.EvalSymlinks(dir); err == nil && real != dirs[0] {
+		dirs = append(dirs, real)
+	}
+	for _, d := range dirs {
+		for _, r := range rules {
+			if d == r.dir {
+				return true
+			}
+			if rel, err := filepath.Rel(r.dir, d); r.subtree && err == nil &&
+				rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// localWorkDir is the directory a local session named workDir runs in:
+// a leading "~" expanded and relative paths made absolute against
+// kojo's own directory, as the tool would resolve them. Symlinks are
+// left alone; matchPathRules follows them.
+func localWorkDir(workDir string) string {
+	if rest, ok := strings.CutPrefix(workDir, "~"); ok && (rest == "" || rest[0] == '/' || rest[0] == filepath.Separator) {
+		if home, err := os.UserHomeDir(); err == nil {
+			workDir = home + rest
+		}
+	}
+	if abs, err := filepath.Abs(workDir); err == nil {
+		return abs
+	}
+	return workDir
+}
+
+// checkYoloArgs refuses a local session in a no-yolo path whose args
+// skip the tool's permission prompts, which is yolo mode under another
+// name.
+func (m *Manager) checkYoloArgs(tool, workDir string, args []string) error {
+	if _, mode := launchControls(tool, args); mode != "bypassPermissions" || !matchPathRules(m.noYoloPaths, workDir) {
+		return nil
+	}
+	return fmt.Errorf("%w: permission prompts can't be skipped in %s", ErrProtectedPath, workDir)
+}
+
+// checkWorkDir refuses a local session in a protected path and returns
+// yoloMode, turned off in a no-yolo path. workDir is a localWorkDir.
+func (m *Manager) checkWorkDir(workDir string, yoloMode bool) (bool, error) {
+	if matchPathRules(m.protectedPaths, workDir) {
+		return false, fmt.Errorf("%w: %s", ErrProtectedPath, workDir)
+	}
+	if yoloMode && matchPathRules(m.noYoloPaths, workDir) {
+		m.logger.Warn("yolo mode turned off in a no-yolo path", "workDir", workDir)
+		return false, nil
+	}
+	return yoloMode, nil
+}
+
+// CheckYoloPath returns ErrProtectedPath when s is in a no-yolo path,
+// where yolo mode can't be turned on.
+func (m *Manager) CheckYoloPath(s *Session) error {
+	s.mu.Lock()
+	workDir, host := s.WorkDir, s.Host
+	s.mu.Unlock()
+	if host == "" && matchPathRules(m.noYoloPaths, localWorkDir(workDir)) {
+		return fmt.Errorf("%w: yolo mode is off in %s", ErrProtectedPath, workDir)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package session
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProtectedPaths(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	src := filepath.Join(home, "src")
+	if err := os.MkdirAll(filepath.Join(home, ".ssh", "keys"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(src, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(src, "ssh-link")
+	linked := os.Symlink(filepath.Join(home, ".ssh"), link) == nil
+
+	m := &Manager{
+		logger:         slog.Default(),
+		protectedPaths: compilePathRules([]string{"~/.ssh/**", filepath.ToSlash(src)}, nil),
+		noYoloPaths:    compilePathRules(nil, DefaultNoYoloPaths),
+	}
+	for dir, want := range map[string]bool{
+		filepath.Join(home, ".ssh"):         true,
+		filepath.Join(home, ".ssh", "keys"): true,
+		src:                                 true,
+		filepath.Join(src, "app"):           false, // no /**: just the directory
+		filepath.Join(home, ".sshd"):        false,
+	} {
+		if _, err := m.checkWorkDir(dir, false); errors.Is(err, ErrProtectedPath) != want {
+			t.Errorf("%s: err = %v, want protected %v", dir, err, want)
+		}
+	}
+	if _, err := m.checkWorkDir(link, false); linked && !errors.Is(err, ErrProtectedPath) {
+		t.Errorf("symlink into ~/.ssh: err = %v", err)
+	}
+
+	if yolo, err := m.checkWorkDir(home, true); err != nil || yolo {
+		t.Errorf("home: yolo %v, err %v; want yolo forced off", yolo, err)
+	}
+	if yolo, _ := m.checkWorkDir(filepath.Join(home, "Documents", "notes"), true); yolo {
+		t.Error("yolo left on under ~/Documents")
+	}
+	if yolo, err := m.checkWorkDir(filepath.Join(home, "work"), true); err != nil || !yolo {
+		t.Errorf("~/work: yolo %v, err %v; want yolo kept", yolo, err)
+	}
+
+	s := &Session{WorkDir: home}
+	if err := m.CheckYoloPath(s); !errors.Is(err, ErrProtectedPath) {
+		t.Errorf("CheckYoloPath(home) = %v", err)
+	}
+	s.Host = "build"
+	if err := m.CheckYoloPath(s); err != nil {
+		t.Errorf("remote session: %v", err)
+	}
+
+	t.Chdir(home)
+	for in, want := range map[string]string{
+		"~":           home,
+		"~/Documents": filepath.Join(home, "Documents"),
+		".ssh/keys":   filepath.Join(home, ".ssh", "keys"),
+		"src/../src":  src,
+	} {
+		if got := localWorkDir(in); got != want {
+			t.Errorf("localWorkDir(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if _, err := m.checkWorkDir(localWorkDir(".ssh"), false); !errors.Is(err, ErrProtectedPath) {
+		t.Errorf("relative .ssh: err = %v", err)
+	}
+	for _, args := range [][]string{{"--dangerously-skip-permissions"}, {"--permission-mode", "bypassPermissions"}} {
+		if err := m.checkYoloArgs("claude", home, args); !errors.Is(err, ErrProtectedPath) {
+			t.Errorf("%q in home: err = %v", args, err)
+		}
+		if err := m.checkYoloArgs("claude", filepath.Join(home, "work"), args); err != nil {
+			t.Errorf("%q in ~/work: err = %v", args, err)
+		}
+	}
+
+	if rules := compilePathRules([]string{}, DefaultProtectedPaths); len(rules) != 0 {
+		t.Errorf("empty list compiled to %v", rules)
+	}
+}
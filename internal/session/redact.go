@@ -0,0 +1,118 @@
+package session
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// Redaction masks credentials in session output before it reaches the
+// scrollback, the persisted lastOutput or WebSocket clients, so an
+// agent that cats a .env file doesn't leave its values on disk. Each
+// read is matched together with the tail of the previous one: a secret
+// split across reads has the part in the new read masked, though the
+// part already sent can't be taken back.
+
+const (
+	// redactMask replaces each match.
+	redactMask = "[REDACTED]"
+	// redactOverlap is how much of the previous read is matched again
+	// with the next; longer secrets split across reads keep their
+	// unmasked start.
+	redactOverlap = 256
+)
+
+// DefaultRedactPatterns catch common credentials: AWS access key IDs,
+// GitHub, Slack, OpenAI and Anthropic tokens, private key headers, and
+// the value of a KEY=value line whose name says it is secret. In a
+// pattern with a capture group only the first group is masked.
+var DefaultRedactPatterns = []string{
+	`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,
+	`\bgh[pousr]_[A-Za-z0-9]{36,}`,
+	`\bgithub_pat_[A-Za-z0-9_]{22,}`,
+	`\bxox[abposr]-[A-Za-z0-9-]{10,}`,
+	`\bsk-(?:ant-|proj-)?[A-Za-z0-9_-]{20,}`,
+	`-----BEGIN [A-Z ]*PRIVATE KEY-----`,
+	`(?i)\b[A-Z0-9_]*(?:SECRET|TOKEN|PASSWORD|PASSWD|API_?KEY|ACCESS_KEY|PRIVATE_KEY)[A-Z0-9_]*\s*[=:]\s*["']?([^\s"']{4,})`,
+}
+
+// compileRedactPatterns compiles DefaultRedactPatterns plus extra.
+func compileRedactPatterns(extra []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(DefaultRedactPatterns)+len(extra))
+	for _, p := range slices.Concat(DefaultRedactPatterns, extra) {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("redact pattern %q: %w", p, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// redactOutput masks res's matches in data, a read of session output,
+// returning data itself when nothing matched. It is a no-op with no
+// patterns or with redaction turned off for the session.
+func (s *Session) redactOutput(res []*regexp.Regexp, data []byte) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(res) == 0 || s.NoRedact {
+		return data
+	}
+	buf := append(slices.Clip(s.redactTail), data...)
+	s.redactTail = capTail(s.redactTail, data, redactOverlap)
+	return redactSpans(data, matchSpans(res, buf, len(buf)-len(data)))
+}
+
+// matchSpans returns the parts of buf[off:] res match, in buf[off:]
+// coordinates, sorted and merged.
+func matchSpans(res []*regexp.Regexp, buf []byte, off int) [][2]int {
+	var spans [][2]int
+	for _, re := range res {
+		for _, m := range re.FindAllSubmatchIndex(buf, -1) {
+			start, end := m[0], m[1]
+			if len(m) >= 4 && m[2] >= 0 {
+				start, end = m[2], m[3]
+			}
+			if end <= off || start == end {
+				continue // already sent in an earlier read
+			}
+			spans = append(spans, [2]int{max(start, off) - off, end - off})
+		}
+	}
+	slices.SortFunc(spans, func(a, b [2]int) int { return a[0] - b[0] })
+	merged := spans[:0]
+	for _, sp := range spans {
+		if n := len(merged); n > 0 && sp[0] <= merged[n-1][1] {
+			merged[n-1][1] = max(merged[n-1][1], sp[1])
+			continue
+		}
+		merged = append(merged, sp)
+	}
+	return merged
+}
+
+// redactSpans replaces spans of data with redactMask.
+func redactSpans(data []byte, spans [][2]int) []byte {
+	if len(spans) == 0 {
+		return data
+	}
+	var out bytes.Buffer
+	prev := 0
+	for _, sp := range spans {
+		out.Write(data[prev:sp[0]])
+		out.WriteString(redactMask)
+		prev = sp[1]
+	}
+	out.Write(data[prev:])
+	return out.Bytes()
+}
+
+// SetNoRedact turns output redaction off (or back on) for the session,
+// for when the user needs to see a value the patterns hide.
+func (s *Session) SetNoRedact(off bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.NoRedact = off
+	s.redactTail = nil
+}
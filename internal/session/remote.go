@@ -0,0 +1,97 @@
+package session
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Remote hosts run user tools over SSH: kojo holds the local end of
+// `ssh -tt` on a PTY, so the session streams through the same read
+// loop and WebSocket as a local one. Like a direct PTY it has no crash
+// resilience; the tool ends when kojo or the connection does.
+
+// RemoteHost is another machine sessions can be started on.
+type RemoteHost struct {
+	// Name identifies the host in create requests and SessionInfo.Host.
+	Name string `json:"name"`
+	// Target is the ssh destination: [user@]host, or a Host alias
+	// from ~/.ssh/config.
+	Target string `json:"target"`
+	// ToolDir is the directory on the host holding claude, codex and
+	// grok. Empty looks them up on the remote login shell's PATH.
+	ToolDir string `json:"toolDir,omitempty"`
+	// SSHArgs are extra ssh options, such as ["-p", "2222"].
+	SSHArgs []string `json:"sshArgs,omitempty"`
+}
+
+// toolPath returns where tool lives on h.
+func (h RemoteHost) toolPath(tool string) string {
+	if h.ToolDir == "" {
+		return tool
+	}
+	return path.Join(h.ToolDir, tool)
+}
+
+// sshArgs returns the ssh arguments that run toolPath with args in
+// workDir on h. The command runs under the remote user's login shell
+// so PATH (node for claude, say) matches a terminal there. An empty
+// workDir starts in the remote home directory; a leading "~/" is the
+// remote one too.
+func (h RemoteHost) sshArgs(workDir, toolPath string, args []string) []string {
+	cmd := "exec " + buildShellCommand(toolPath, args)
+	if workDir != "" {
+		cmd = "cd " + shellQuoteHome(workDir) + " && " + cmd
+	}
+	out := []string{"-tt", "-o", "ServerAliveInterval=30"}
+	out = append(out, h.SSHArgs...)
+	return append(out, "--", h.Target, `exec "${SHELL:-/bin/sh}" -lc `+shellQuote(cmd))
+}
+
+// remoteHost looks up the named host and checks tool can run on it.
+func (m *Manager) remoteHost(name, tool string) (RemoteHost, error) {
+	i := slices.IndexFunc(m.remoteHosts, func(h RemoteHost) bool { return h.Name == name })
+	if i < 0 {
+		return RemoteHost{}, fmt.Errorf("%w: %s", ErrUnknownHost, name)
+	}
+	// custom points claude at a URL on this machine, and internal
+	// tools are local terminals.
+	if !IsUserTool(tool) || tool == "custom" {
+		return RemoteHost{}, fmt.Errorf("%w on a remote host: %s", ErrUnsupportedTool, tool)
+	}
+	return m.remoteHosts[i], nil
+}
+
+// RemoteHosts returns the configured remote hosts.
+func (m *Manager) RemoteHosts() []RemoteHost {
+	return slices.Clone(m.remoteHosts)
+}
+
+// shellQuote wraps a string in single quotes, escaping any embedded single quotes.
+// e.g. "it's" → "'it'\”s'"
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellQuoteHome is shellQuote for a path whose leading "~" the shell
+// should expand: it becomes "$HOME", which double quotes leave alone.
+func shellQuoteHome(p string) string {
+	if p == "~" {
+		return `"$HOME"`
+	}
+	if rest, ok := strings.CutPrefix(p, "~/"); ok {
+		return `"$HOME"/` + shellQuote(rest)
+	}
+	return shellQuote(p)
+}
+
+// buildShellCommand constructs a shell-safe command string from a tool path and arguments.
+func buildShellCommand(toolPath string, args []string) string {
+	parts := make([]string, 0, 1+len(args))
+	parts = append(parts, shellQuote(toolPath))
+	for _, a := range args {
+		parts = append(parts, shellQuote(a))
+	}
+	return strings.Join(parts, " ")
+}
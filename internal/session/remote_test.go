@@ -0,0 +1,52 @@
+package session
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestRemoteHostSSHArgs(t *testing.T) {
+	h := RemoteHost{Name: "build", Target: "me@build", ToolDir: "/opt/bin", SSHArgs: []string{"-p", "2222"}}
+	got := h.sshArgs("/src/it's here", h.toolPath("claude"), []string{"--model", "a b"})
+	want := []string{
+		"-tt", "-o", "ServerAliveInterval=30", "-p", "2222", "--", "me@build",
+		`exec "${SHELL:-/bin/sh}" -lc 'cd '\''/src/it'\''\'\'''\''s here'\'' && exec '\''/opt/bin/claude'\'' '\''--model'\'' '\''a b'\'''`,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("sshArgs =\n%q\nwant\n%q", got, want)
+	}
+
+	got = RemoteHost{Target: "vm"}.sshArgs("", "codex", nil)
+	if cmd := got[len(got)-1]; cmd != `exec "${SHELL:-/bin/sh}" -lc 'exec '\''codex'\'''` {
+		t.Errorf("no workDir: command = %q", cmd)
+	}
+
+	got = RemoteHost{Target: "vm"}.sshArgs("~/my proj", "codex", nil)
+	if cmd := got[len(got)-1]; cmd != `exec "${SHELL:-/bin/sh}" -lc 'cd "$HOME"/'\''my proj'\'' && exec '\''codex'\'''` {
+		t.Errorf("~/ workDir: command = %q", cmd)
+	}
+}
+
+func TestShellQuoteHome(t *testing.T) {
+	for in, want := range map[string]string{
+		"~":        `"$HOME"`,
+		"~/src/a":  `"$HOME"/'src/a'`,
+		"~other/a": `'~other/a'`,
+		"/srv/~/a": `'/srv/~/a'`,
+	} {
+		if got := shellQuoteHome(in); got != want {
+			t.Errorf("shellQuoteHome(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestCreateWithOptionsRejectsRemote(t *testing.T) {
+	m := &Manager{remoteHosts: []RemoteHost{{Name: "build", Target: "build"}}}
+	if _, err := m.CreateWithOptions("claude", "", nil, false, "", CreateOptions{Host: "nope"}); !errors.Is(err, ErrUnknownHost) {
+		t.Errorf("unknown host: err = %v, want ErrUnknownHost", err)
+	}
+	if _, err := m.CreateWithOptions("custom", "", nil, false, "", CreateOptions{Host: "build"}); !errors.Is(err, ErrUnsupportedTool) {
+		t.Errorf("custom: err = %v, want ErrUnsupportedTool", err)
+	}
+}
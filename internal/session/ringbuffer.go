@@ -4,6 +4,10 @@ import "sync"
 
 const defaultRingSize = 1024 * 1024 // 1MB
 
+// RingBuffer keeps the last size bytes written to it. Every byte of
+// session output passes through Write, so it copies in at most two
+// segments, and readers that don't need the whole buffer (Tail) or can
+// reuse memory (AppendTo) avoid the full-size allocation Bytes makes.
 type RingBuffer struct {
 	mu   sync.Mutex
 	buf  []byte
@@ -23,28 +27,70 @@ func (r *RingBuffer) Write(p []byte) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	for _, b := range p {
-		r.buf[r.w] = b
-		r.w++
-		if r.w >= r.size {
-			r.w = 0
-			r.full = true
-		}
+	if len(p) >= r.size {
+		// only the last size bytes survive
+		copy(r.buf, p[len(p)-r.size:])
+		r.w = 0
+		r.full = true
+		return
+	}
+	n := copy(r.buf[r.w:], p)
+	if n < len(p) {
+		r.w = copy(r.buf, p[n:])
+		r.full = true
+		return
+	}
+	r.w += n
+	if r.w == r.size {
+		r.w = 0
+		r.full = true
 	}
 }
 
-func (r *RingBuffer) Bytes() []byte {
+// Len returns how many bytes the buffer holds.
+func (r *RingBuffer) Len() int {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	return r.len()
+}
 
-	if !r.full {
-		out := make([]byte, r.w)
-		copy(out, r.buf[:r.w])
-		return out
+func (r *RingBuffer) len() int {
+	if r.full {
+		return r.size
 	}
+	return r.w
+}
+
+// Bytes returns a copy of the buffer's contents, oldest byte first.
+func (r *RingBuffer) Bytes() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.appendLast(make([]byte, 0, r.len()), r.len())
+}
 
-	out := make([]byte, r.size)
-	n := copy(out, r.buf[r.w:])
-	copy(out[n:], r.buf[:r.w])
-	return out
+// AppendTo appends the buffer's contents to dst and returns the
+// extended slice; it doesn't allocate when dst has room.
+func (r *RingBuffer) AppendTo(dst []byte) []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.appendLast(dst, r.len())
+}
+
+// Tail returns a copy of the last n bytes (all of them if the buffer
+// holds fewer).
+func (r *RingBuffer) Tail(n int) []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n = min(max(n, 0), r.len())
+	return r.appendLast(make([]byte, 0, n), n)
+}
+
+// appendLast appends the last n bytes, n <= r.len(). The caller holds mu.
+func (r *RingBuffer) appendLast(dst []byte, n int) []byte {
+	start := r.w - n
+	if start >= 0 {
+		return append(dst, r.buf[start:r.w]...)
+	}
+	dst = append(dst, r.buf[r.size+start:]...)
+	return append(dst, r.buf[:r.w]...)
 }
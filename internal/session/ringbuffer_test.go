@@ -0,0 +1,50 @@
+package session
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestRingBufferMatchesTail(t *testing.T) {
+	const size = 64
+	r := NewRingBuffer(size)
+	var all []byte
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := 0; i < 500; i++ {
+		p := make([]byte, rng.IntN(size*3/2))
+		for j := range p {
+			p[j] = byte(rng.IntN(256))
+		}
+		r.Write(p)
+		all = append(all, p...)
+		want := all[max(0, len(all)-size):]
+		if got := r.Bytes(); !bytes.Equal(got, want) {
+			t.Fatalf("write %d: Bytes = %x, want %x", i, got, want)
+		}
+		if r.Len() != len(want) {
+			t.Fatalf("write %d: Len = %d, want %d", i, r.Len(), len(want))
+		}
+		n := rng.IntN(size + 8)
+		if got, w := r.Tail(n), want[max(0, len(want)-n):]; !bytes.Equal(got, w) {
+			t.Fatalf("write %d: Tail(%d) = %x, want %x", i, n, got, w)
+		}
+	}
+}
+
+func TestRingBufferAppendTo(t *testing.T) {
+	r := NewRingBuffer(4)
+	r.Write([]byte("abcdef"))
+	r.Write([]byte("g"))
+	buf := make([]byte, 0, 16)
+	got := r.AppendTo(append(buf, '>'))
+	if string(got) != ">defg" {
+		t.Fatalf("AppendTo = %q", got)
+	}
+	if &got[0] != &buf[:1][0] {
+		t.Fatal("AppendTo reallocated a buffer with room")
+	}
+	if allocs := testing.AllocsPerRun(10, func() { r.AppendTo(buf[:0]) }); allocs != 0 {
+		t.Fatalf("AppendTo allocated %v times", allocs)
+	}
+}
@@ -0,0 +1,149 @@
+package session
+
+import (
+	"fmt"
+	"runtime"
+	"slices"
+	"strconv"
+)
+
+// A sandbox confines a user tool by prefixing its command line with
+// wrappers: systemd-run for cgroup limits (Linux), sudo for a separate
+// user, unshare (Linux) or sandbox-exec (macOS) for a network-less or
+// custom profile. Whatever a profile asks for that the platform can't
+// enforce fails the launch rather than running the tool unconfined.
+
+// Sandbox is a named restriction profile for user tool sessions.
+type Sandbox struct {
+	Name string `json:"name"`
+	// User runs the tool as this account via `sudo -n -u`; it needs
+	// a NOPASSWD sudoers rule and read access to the tool.
+	User string `json:"user,omitempty"`
+	// MemoryMB and CPUPercent are cgroup limits (Linux, through a
+	// systemd user scope); 0 leaves either unlimited. CPUPercent is
+	// of one core, so 200 allows two.
+	MemoryMB   int `json:"memoryMB,omitempty"`
+	CPUPercent int `json:"cpuPercent,omitempty"`
+	// NoNetwork gives the tool no network access: an empty network
+	// namespace on Linux, a deny-network profile on macOS.
+	NoNetwork bool `json:"noNetwork,omitempty"`
+	// MacProfile is a sandbox-exec profile file to run the tool
+	// under on macOS.
+	MacProfile string `json:"macProfile,omitempty"`
+}
+
+// macNoNetworkProfile is the sandbox-exec profile for NoNetwork.
+const macNoNetworkProfile = "(version 1)(allow default)(deny network*)"
+
+// wrap returns the command line that runs toolPath with args inside sb
+// on goos.
+func (sb Sandbox) wrap(goos, toolPath string, args []string) (string, []string, error) {
+	var prefix []string
+	if sb.MemoryMB > 0 || sb.CPUPercent > 0 {
+		if goos != "linux" {
+			return "", nil, fmt.Errorf("%w: %s: memory and CPU limits need Linux cgroups", ErrSandbox, sb.Name)
+		}
+		prefix = append(prefix, "systemd-run", "--user", "--scope", "--quiet", "--collect")
+		if sb.MemoryMB > 0 {
+			prefix = append(prefix, "-p", "MemoryMax="+strconv.Itoa(sb.MemoryMB)+"M")
+		}
+		if sb.CPUPercent > 0 {
+			prefix = append(prefix, "-p", "CPUQuota="+strconv.Itoa(sb.CPUPercent)+"%")
+		}
+		prefix = append(prefix, "--")
+	}
+	if sb.User != "" {
+		if goos == "windows" {
+			return "", nil, fmt.Errorf("%w: %s: running as another user needs sudo", ErrSandbox, sb.Name)
+		}
+		// sudo before unshare: sudo refuses to run inside a user
+		// namespace.
+		prefix = append(prefix, "sudo", "-n", "-H", "-u", sb.User, "--")
+	}
+	switch goos {
+	case "linux":
+		if sb.MacProfile != "" {
+			return "", nil, fmt.Errorf("%w: %s: macProfile needs macOS", ErrSandbox, sb.Name)
+		}
+		if sb.NoNetwork {
+			prefix = append(prefix, "unshare", "--net", "--map-current-user", "--")
+		}
+	case "darwin":
+		switch {
+		case sb.MacProfile != "" && sb.NoNetwork:
+			return "", nil, fmt.Errorf("%w: %s: with macProfile, deny network in the profile instead of noNetwork", ErrSandbox, sb.Name)
+		case sb.MacProfile != "":
+			prefix = append(prefix, "sandbox-exec", "-f", sb.MacProfile)
+		case sb.NoNetwork:
+			prefix = append(prefix, "sandbox-exec", "-p", macNoNetworkProfile)
+		}
+	default:
+		if sb.NoNetwork || sb.MacProfile != "" {
+			return "", nil, fmt.Errorf("%w: %s: not supported on %s", ErrSandbox, sb.Name, goos)
+		}
+	}
+	if len(prefix) == 0 {
+		return toolPath, args, nil
+	}
+	out := append(prefix[1:], toolPath)
+	return prefix[0], append(out, args...), nil
+}
+
+// resolveSandbox returns the sandbox a session of tool runs in: name,
+// or with none given the yolo sandbox for a yolo session; empty for an
+// unconfined one.
+func (m *Manager) resolveSandbox(name, tool string, yoloMode bool) (string, error) {
+	if name == "" && yoloMode && IsUserTool(tool) {
+		name = m.yoloSandbox
+	}
+	if name == "" {
+		return "", nil
+	}
+	if !IsUserTool(tool) {
+		return "", fmt.Errorf("%w: %s: only user tools can be sandboxed", ErrSandbox, name)
+	}
+	if _, err := m.sandbox(name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// CheckYoloSandbox returns ErrSandbox when yolo mode can't be turned on
+// for the running s: a yolo sandbox is configured and s, a local user
+// tool session, was launched outside it. Moving a running process into
+// a sandbox isn't possible; a new session with yolo mode on gets it.
+func (m *Manager) CheckYoloSandbox(s *Session) error {
+	s.mu.Lock()
+	tool, host, sandbox := s.Tool, s.Host, s.Sandbox
+	s.mu.Unlock()
+	if m.yoloSandbox == "" || host != "" || !IsUserTool(tool) || sandbox == m.yoloSandbox {
+		return nil
+	}
+	return fmt.Errorf("%w: yolo mode needs sandbox %q; start a new yolo session", ErrSandbox, m.yoloSandbox)
+}
+
+func (m *Manager) sandbox(name string) (Sandbox, error) {
+	i := slices.IndexFunc(m.sandboxes, func(sb Sandbox) bool { return sb.Name == name })
+	if i < 0 {
+		return Sandbox{}, fmt.Errorf("%w: unknown sandbox %q", ErrSandbox, name)
+	}
+	return m.sandboxes[i], nil
+}
+
+// Sandboxes returns the configured sandbox profiles.
+func (m *Manager) Sandboxes() []Sandbox {
+	return slices.Clone(m.sandboxes)
+}
+
+// wrapSandbox applies the named sandbox to a local launch, returning
+// the command line unchanged when there is none.
+func (m *Manager) wrapSandbox(name, toolPath string, args []string) (string, []string, error) {
+	if name == "" {
+		return toolPath, args, nil
+	}
+	sb, err := m.sandbox(name)
+	if err != nil {
+		return "", nil, err
+	}
+	return sb.wrap(runtime.GOOS, toolPath, args)
+}
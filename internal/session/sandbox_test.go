@@ -0,0 +1,101 @@
+package session
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestSandboxWrap(t *testing.T) {
+	args := []string{"--model", "x"}
+	for _, tc := range []struct {
+		name string
+		sb   Sandbox
+		goos string
+		want []string // command line, tool first
+	}{
+		{"none", Sandbox{}, "linux", []string{"/bin/claude", "--model", "x"}},
+		{"linux all", Sandbox{User: "agent", MemoryMB: 2048, CPUPercent: 150, NoNetwork: true}, "linux", []string{
+			"systemd-run", "--user", "--scope", "--quiet", "--collect", "-p", "MemoryMax=2048M", "-p", "CPUQuota=150%", "--",
+			"sudo", "-n", "-H", "-u", "agent", "--",
+			"unshare", "--net", "--map-current-user", "--",
+			"/bin/claude", "--model", "x",
+		}},
+		{"darwin no network", Sandbox{NoNetwork: true}, "darwin", []string{
+			"sandbox-exec", "-p", macNoNetworkProfile, "/bin/claude", "--model", "x",
+		}},
+		{"darwin profile", Sandbox{MacProfile: "/etc/agent.sb"}, "darwin", []string{
+			"sandbox-exec", "-f", "/etc/agent.sb", "/bin/claude", "--model", "x",
+		}},
+	} {
+		path, out, err := tc.sb.wrap(tc.goos, "/bin/claude", args)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if got := append([]string{path}, out...); !slices.Equal(got, tc.want) {
+			t.Errorf("%s:\n got %q\nwant %q", tc.name, got, tc.want)
+		}
+	}
+
+	for _, tc := range []struct {
+		sb   Sandbox
+		goos string
+	}{
+		{Sandbox{MemoryMB: 1}, "darwin"},
+		{Sandbox{MacProfile: "p"}, "linux"},
+		{Sandbox{MacProfile: "p", NoNetwork: true}, "darwin"},
+		{Sandbox{User: "agent"}, "windows"},
+		{Sandbox{NoNetwork: true}, "windows"},
+	} {
+		if _, _, err := tc.sb.wrap(tc.goos, "/bin/claude", args); !errors.Is(err, ErrSandbox) {
+			t.Errorf("%+v on %s: err = %v, want ErrSandbox", tc.sb, tc.goos, err)
+		}
+	}
+}
+
+func TestResolveSandbox(t *testing.T) {
+	m := &Manager{sandboxes: []Sandbox{{Name: "strict"}, {Name: "yolo"}}, yoloSandbox: "yolo"}
+	for _, tc := range []struct {
+		name, tool string
+		yolo       bool
+		want       string
+	}{
+		{"", "claude", false, ""},
+		{"", "claude", true, "yolo"},
+		{"strict", "claude", true, "strict"},
+		{"", ShellToolName(), true, ""},
+	} {
+		got, err := m.resolveSandbox(tc.name, tc.tool, tc.yolo)
+		if err != nil || got != tc.want {
+			t.Errorf("resolveSandbox(%q, %q, %v) = %q, %v; want %q", tc.name, tc.tool, tc.yolo, got, err, tc.want)
+		}
+	}
+	if _, err := m.resolveSandbox("nope", "claude", false); !errors.Is(err, ErrSandbox) {
+		t.Errorf("unknown sandbox: err = %v", err)
+	}
+	if _, err := m.resolveSandbox("strict", ShellToolName(), false); !errors.Is(err, ErrSandbox) {
+		t.Errorf("internal tool: err = %v", err)
+	}
+}
+
+func TestCheckYoloSandbox(t *testing.T) {
+	m := &Manager{sandboxes: []Sandbox{{Name: "strict"}, {Name: "yolo"}}, yoloSandbox: "yolo"}
+	for _, tc := range []struct {
+		s  *Session
+		ok bool
+	}{
+		{&Session{Tool: "claude", Sandbox: "yolo"}, true},
+		{&Session{Tool: "claude"}, false},
+		{&Session{Tool: "claude", Sandbox: "strict"}, false},
+		{&Session{Tool: "claude", Host: "remote"}, true},
+		{&Session{Tool: ShellToolName()}, true},
+	} {
+		if err := m.CheckYoloSandbox(tc.s); (err == nil) != tc.ok || err != nil && !errors.Is(err, ErrSandbox) {
+			t.Errorf("tool %q sandbox %q host %q: err = %v", tc.s.Tool, tc.s.Sandbox, tc.s.Host, err)
+		}
+	}
+	if err := (&Manager{}).CheckYoloSandbox(&Session{Tool: "claude"}); err != nil {
+		t.Errorf("no yolo sandbox configured: err = %v", err)
+	}
+}
@@ -17,6 +17,9 @@ type Status string
 const (
 	StatusRunning Status = "running"
 	StatusExited  Status = "exited"
+	// StatusPaused is what Info reports for a running session whose tool
+	// is stopped by Pause. The session itself stays StatusRunning.
+	StatusPaused Status = "paused"
 )
 
 type Session struct {
@@ -31,11 +34,36 @@ type Session struct {
 	Status          Status
 	ExitCode        *int
 	YoloMode        bool
-	Internal        bool   // internal session (e.g. tmux), not user-facing
-	ToolSessionID   string // tool-specific session ID for resume
-	ParentID        string // parent session ID (e.g. tmux child of a CLI session)
-	TmuxSessionName string // tmux session name (kojo_<id>) for tmux-backed sessions
-	restarting      bool   // true while Restart is in progress, prevents concurrent Stop
+	Internal        bool     // internal session (e.g. tmux), not user-facing
+	ToolSessionID   string   // tool-specific session ID for resume
+	ParentID        string   // parent session ID (e.g. tmux child of a CLI session)
+	TmuxSessionName string   // tmux session name (kojo_<id>) for tmux-backed sessions
+	TmuxServer      string   // tmux -L socket it is on; empty for the default server (kojo before -L)
+	DirectPTY       bool     // user tool running without tmux; it won't survive a kojo restart
+	Host            string   // RemoteHost.Name the tool runs on over SSH; empty for local
+	Sandbox         string   // Sandbox.Name the tool is confined in; empty for none
+	Title           string   // last window title the tool set (OSC 0/2)
+	NotifyOnBell    bool     // turn the tool's terminal bell into a push notification
+	NotifyPatterns  []string // output regexps that trigger a push notification; see CheckNotifyPatterns
+	NoRedact        bool     // output redaction turned off for this session; see redactOutput
+	SizePolicy      SizePolicy
+	FixedCols       uint16 // terminal size under SizeFixed
+	FixedRows       uint16
+	inputLock       string        // bcrypt hash of the input lock PIN; empty when unlocked
+	restarting      bool          // true while Restart is in progress, prevents concurrent Stop
+	Paused          bool          // tool stopped with SIGSTOP; see Manager.Pause
+	Deadline        time.Time     // when the session is stopped; zero for no time limit
+	TimeLimit       time.Duration // length of the window ending at Deadline
+	TokenBudget     int64         // tokens the tool may use before it is stopped; 0 for none
+	Model           string        // model the tool was last switched to; see SetControls
+	PermissionMode  string        // permission mode the tool was last switched to
+	BaseCommit      string        // HEAD of WorkDir when the session was created
+	// CheckpointInterval is how often the working tree is committed to
+	// the session's checkpoint ref; 0 for never. See Checkpoint.
+	CheckpointInterval time.Duration
+	checkpointMu       sync.Mutex    // serializes Checkpoint
+	controlsMu         sync.Mutex    // serializes SetControls
+	checkpointWake     chan struct{} // tells checkpointLoop the interval changed
 
 	// pipe-pane: raw pane output captured via FIFO (bypasses tmux screen-diff batching)
 	rawPipe     *os.File // FIFO reader, nil if pipe-pane is not active
@@ -51,12 +79,15 @@ type Session struct {
 	// broadcast channels
 	subscribers map[chan []byte]struct{}
 	subMu       sync.Mutex
+	// bytes dropped per subscriber since it last resynced; see Resync
+	dropped map[chan []byte]int64
 
 	// done signal
 	done chan struct{}
 
-	// codex: trailing buffer for session ID capture across chunk boundaries
-	codexCaptureBuf []byte
+	// trailing buffer for session ID capture across chunk boundaries
+	// (see CaptureToolSessionID)
+	idCaptureBuf []byte
 
 	// yolo: trailing output buffer for pattern detection
 	yoloTail []byte
@@ -69,11 +100,62 @@ type Session struct {
 	attachments map[string]*Attachment
 	attachSubs  map[chan []*Attachment]struct{}
 
-	// last terminal output captured on exit (for persistence)
-	lastOutput []byte
-
-	// readDone is closed when readLoop exits
-	readDone chan struct{}
+	// OSC 52 clipboard: partial sequence carried across reads, and subscribers
+	clipPending []byte
+	clipSubs    map[chan string]struct{}
+
+	// bell / title: escape state carried across reads, subscribers,
+	// last bell notification and the pending title list update
+	term           termScanner
+	termSubs       map[chan TermEvent]struct{}
+	lastBellNotify time.Time
+	titlePublish   *time.Timer
+
+	// inline images: partial sequence carried across reads, recent images
+	imgPending []byte
+	images     []*InlineImage
+	imgSeq     int
+
+	// output notify patterns: compiled NotifyPatterns, the partial
+	// line carried across reads, last notification
+	notifyRe         *regexp.Regexp
+	notifyLine       []byte
+	lastOutputNotify time.Time
+
+	// webhooks are the session's webhooks (see Webhook) and
+	// webhookLine the partial line their patterns are matched on.
+	webhooks    []Webhook
+	webhookLine []byte
+
+	// redaction: raw tail of the previous read, matched again with the
+	// next so a secret split across reads is still caught
+	redactTail []byte
+
+	// input lock: wrong PINs in a row and when the last one was tried
+	pinFailures    int
+	lastPINFailure time.Time
+
+	// limits: tokens counted so far, and which limits were warned about
+	tokens       tokenTally
+	tokensUsed   int64
+	timeWarned   bool
+	tokensWarned bool
+
+	// WebSocket clients attached to the terminal, each one's channel
+	// for input control changes, and the client holding input control;
+	// see AddClient and ClaimInput
+	clients       map[string]Client
+	clientSignals map[string]chan struct{}
+	inputOwner    string
+
+	// last terminal output captured on exit (for persistence);
+	// outputDirty marks it changed since it was last stored
+	lastOutput  []byte
+	outputDirty bool
+
+	// loops runs the session's background goroutines (readLoop,
+	// drainLoop, waitLoop, the attach reaper); see supervisor.
+	loops supervisor
 
 	// logger routes session-scoped diagnostics; nil falls back to slog.Default().
 	logger *slog.Logger
@@ -117,25 +199,45 @@ func newRestoredSession(info SessionInfo) *Session {
 		lastOutput, _ = base64.StdEncoding.DecodeString(info.LastOutput)
 	}
 	s := &Session{
-		ID:              info.ID,
-		Tool:            info.Tool,
-		WorkDir:         info.WorkDir,
-		Args:            info.Args,
-		CreatedAt:       t,
-		Status:          StatusExited,
-		ExitCode:        info.ExitCode,
-		YoloMode:        info.YoloMode,
-		Internal:        info.Internal || internalTools[info.Tool],
-		ToolSessionID:   info.ToolSessionID,
-		ParentID:        info.ParentID,
-		TmuxSessionName: info.TmuxSessionName,
-		lastCols:        info.LastCols,
-		lastRows:        info.LastRows,
-		scrollback:      NewRingBuffer(defaultRingSize),
-		subscribers:     make(map[chan []byte]struct{}),
-		done:            make(chan struct{}),
-		lastOutput:      lastOutput,
-		attachments:     make(map[string]*Attachment, len(info.Attachments)),
+		ID:                 info.ID,
+		Tool:               info.Tool,
+		WorkDir:            info.WorkDir,
+		Args:               info.Args,
+		CreatedAt:          t,
+		Status:             StatusExited,
+		ExitCode:           info.ExitCode,
+		YoloMode:           info.YoloMode,
+		Internal:           info.Internal || internalTools[info.Tool],
+		ToolSessionID:      info.ToolSessionID,
+		ParentID:           info.ParentID,
+		TmuxSessionName:    info.TmuxSessionName,
+		TmuxServer:         info.TmuxServer,
+		DirectPTY:          info.DirectPTY,
+		Host:               info.Host,
+		Sandbox:            info.Sandbox,
+		Title:              info.Title,
+		NotifyOnBell:       info.NotifyOnBell,
+		NoRedact:           info.NoRedact,
+		Paused:             info.Paused,
+		SizePolicy:         SizePolicy(info.SizePolicy),
+		FixedCols:          info.FixedCols,
+		FixedRows:          info.FixedRows,
+		TimeLimit:          time.Duration(info.TimeLimitSec) * time.Second,
+		TokenBudget:        info.TokenBudget,
+		Model:              info.Model,
+		PermissionMode:     info.PermissionMode,
+		BaseCommit:         info.BaseCommit,
+		CheckpointInterval: time.Duration(info.CheckpointIntervalSec) * time.Second,
+		checkpointWake:     make(chan struct{}, 1),
+		inputLock:          info.InputLockHash,
+		NotifyPatterns:     info.NotifyPatterns,
+		lastCols:           info.LastCols,
+		lastRows:           info.LastRows,
+		scrollback:         NewRingBuffer(defaultRingSize),
+		subscribers:        make(map[chan []byte]struct{}),
+		done:               make(chan struct{}),
+		lastOutput:         lastOutput,
+		attachments:        make(map[string]*Attachment, len(info.Attachments)),
 	}
 	for _, att := range info.Attachments {
 		if att == nil || att.Path == "" {
@@ -143,6 +245,9 @@ func newRestoredSession(info SessionInfo) *Session {
 		}
 		s.attachments[att.Path] = att
 	}
+	s.Deadline, _ = time.Parse(time.RFC3339, info.Deadline)
+	s.notifyRe, _ = compileNotifyPatterns(info.NotifyPatterns)
+	s.webhooks = restoreWebhooks(info.Webhooks)
 	return s
 }
 
@@ -158,61 +263,152 @@ const yoloTailSize = 4096
 var ansiRe = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\].*?(?:\x07|\x1b\\)|\x1b[()][0-9A-B]`)
 var multiSpaceRe = regexp.MustCompile(`[ \t]{2,}`)
 
-// "Do you ...? ... 1. Yes" pattern (allow blank lines between question and options)
-var yoloPattern = regexp.MustCompile(`(?i)Do you \S[^\n]*\?[\s\S]{0,200}?1\.\s*Yes`)
-
-// Codex outputs "session id: <UUID>" on startup
-var codexSessionIDRe = regexp.MustCompile(`(?i)session id: ([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})`)
-
 type SessionInfo struct {
-	ID              string        `json:"id"`
-	Tool            string        `json:"tool"`
-	WorkDir         string        `json:"workDir"`
-	Args            []string      `json:"args,omitempty"`
-	Status          Status        `json:"status"`
-	ExitCode        *int          `json:"exitCode,omitempty"`
-	YoloMode        bool          `json:"yoloMode"`
-	Internal        bool          `json:"internal,omitempty"`
-	CreatedAt       string        `json:"createdAt"`
-	ToolSessionID   string        `json:"toolSessionId,omitempty"`
-	ParentID        string        `json:"parentId,omitempty"`
-	TmuxSessionName string        `json:"tmuxSessionName,omitempty"`
-	LastOutput      string        `json:"lastOutput,omitempty"`
-	LastCols        uint16        `json:"lastCols,omitempty"`
-	LastRows        uint16        `json:"lastRows,omitempty"`
-	Attachments     []*Attachment `json:"attachments,omitempty"`
+	ID              string   `json:"id"`
+	Tool            string   `json:"tool"`
+	WorkDir         string   `json:"workDir"`
+	Args            []string `json:"args,omitempty"`
+	Status          Status   `json:"status"`
+	ExitCode        *int     `json:"exitCode,omitempty"`
+	YoloMode        bool     `json:"yoloMode"`
+	Internal        bool     `json:"internal,omitempty"`
+	CreatedAt       string   `json:"createdAt"`
+	ToolSessionID   string   `json:"toolSessionId,omitempty"`
+	ParentID        string   `json:"parentId,omitempty"`
+	TmuxSessionName string   `json:"tmuxSessionName,omitempty"`
+	// TmuxServer is the tmux -L socket TmuxSessionName is on. Sessions
+	// persisted before kojo had its own server lack it: they are on
+	// the user's default server.
+	TmuxServer string `json:"tmuxServer,omitempty"`
+	// DirectPTY flags a user tool session running on a bare PTY
+	// (no tmux, see BackendPTY): it ends if kojo exits.
+	DirectPTY bool `json:"directPty,omitempty"`
+	// Host is the remote host the session runs on (RemoteHost.Name);
+	// empty for this machine.
+	Host string `json:"host,omitempty"`
+	// Sandbox is the restriction profile the tool runs in (Sandbox.Name).
+	Sandbox        string        `json:"sandbox,omitempty"`
+	LastOutput     string        `json:"lastOutput,omitempty"`
+	LastCols       uint16        `json:"lastCols,omitempty"`
+	LastRows       uint16        `json:"lastRows,omitempty"`
+	Attachments    []*Attachment `json:"attachments,omitempty"`
+	Title          string        `json:"title,omitempty"`
+	NotifyOnBell   bool          `json:"notifyOnBell,omitempty"`
+	NotifyPatterns []string      `json:"notifyPatterns,omitempty"`
+	NoRedact       bool          `json:"noRedact,omitempty"`
+	// SizePolicy is how the terminal is sized among several clients
+	// (see SizePolicy); empty is "last". FixedCols and FixedRows are
+	// the size under "fixed".
+	SizePolicy string `json:"sizePolicy,omitempty"`
+	FixedCols  uint16 `json:"fixedCols,omitempty"`
+	FixedRows  uint16 `json:"fixedRows,omitempty"`
+	// InputLocked means typing into the session needs its PIN; see
+	// SetInputLock. InputLockHash is the PIN's hash, persisted only.
+	InputLocked   bool   `json:"inputLocked,omitempty"`
+	InputLockHash string `json:"inputLockHash,omitempty"`
+	// Webhooks are persisted only; the API lists them without secrets.
+	Webhooks []Webhook `json:"webhooks,omitempty"`
+	// Paused is kept so a paused tmux session is still known to be
+	// paused after a kojo restart; Status then reads "paused".
+	Paused bool `json:"paused,omitempty"`
+	// Deadline is when the session is stopped (RFC3339), TimeLimitSec
+	// the length of the window ending there, and TokenBudget the tokens
+	// its tool may use; see SetTimeLimit and SetTokenBudget. TokensUsed
+	// is the count so far, not persisted.
+	Deadline     string `json:"deadline,omitempty"`
+	TimeLimitSec int64  `json:"timeLimitSec,omitempty"`
+	TokenBudget  int64  `json:"tokenBudget,omitempty"`
+	TokensUsed   int64  `json:"tokensUsed,omitempty"`
+	// Model and PermissionMode are the tool's runtime controls (see
+	// Manager.SetControls): what it was launched with or last switched
+	// to. The model is empty for the tool's default.
+	Model          string `json:"model,omitempty"`
+	PermissionMode string `json:"permissionMode,omitempty"`
+	// BaseCommit is the commit WorkDir had checked out when the session
+	// was created, which its changes are diffed against; empty outside
+	// git and for remote sessions.
+	BaseCommit string `json:"baseCommit,omitempty"`
+	// CheckpointIntervalSec is how often the working tree is
+	// checkpointed; see Manager.SetCheckpointInterval.
+	CheckpointIntervalSec int64 `json:"checkpointIntervalSec,omitempty"`
+	// Viewers is how many WebSockets are attached to the terminal and
+	// Writers how many of those may type; neither is persisted.
+	Viewers int `json:"viewers"`
+	Writers int `json:"writers"`
 }
 
 func (s *Session) Info() SessionInfo {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	info := SessionInfo{
-		ID:              s.ID,
-		Tool:            s.Tool,
-		WorkDir:         s.WorkDir,
-		Args:            s.Args,
-		Status:          s.Status,
-		ExitCode:        s.ExitCode,
-		YoloMode:        s.YoloMode,
-		Internal:        s.Internal,
-		CreatedAt:       s.CreatedAt.Local().Format(time.RFC3339),
-		ToolSessionID:   s.ToolSessionID,
-		ParentID:        s.ParentID,
-		TmuxSessionName: s.TmuxSessionName,
-	}
+	info := s.infoLocked()
 	if len(s.lastOutput) > 0 {
 		info.LastOutput = base64.StdEncoding.EncodeToString(s.lastOutput)
 	}
-	info.LastCols = s.lastCols
-	info.LastRows = s.lastRows
+	if info.Paused && info.Status == StatusRunning {
+		info.Status = StatusPaused
+	}
+	info.TokensUsed = s.tokensUsed
+	info.Viewers = len(s.clients)
+	for _, c := range s.clients {
+		if !c.ReadOnly {
+			info.Writers++
+		}
+	}
 	return info
 }
 
-// InfoForSave returns session info including attachment metadata for persistence.
+// infoLocked is Info without LastOutput. Caller holds s.mu.
+func (s *Session) infoLocked() SessionInfo {
+	info := SessionInfo{
+		ID:                    s.ID,
+		Tool:                  s.Tool,
+		WorkDir:               s.WorkDir,
+		Args:                  s.Args,
+		Status:                s.Status,
+		ExitCode:              s.ExitCode,
+		YoloMode:              s.YoloMode,
+		Internal:              s.Internal,
+		CreatedAt:             s.CreatedAt.Local().Format(time.RFC3339),
+		ToolSessionID:         s.ToolSessionID,
+		ParentID:              s.ParentID,
+		TmuxSessionName:       s.TmuxSessionName,
+		TmuxServer:            s.TmuxServer,
+		DirectPTY:             s.DirectPTY,
+		Host:                  s.Host,
+		Sandbox:               s.Sandbox,
+		Title:                 s.Title,
+		NotifyOnBell:          s.NotifyOnBell,
+		NoRedact:              s.NoRedact,
+		Paused:                s.Paused,
+		SizePolicy:            string(s.SizePolicy),
+		FixedCols:             s.FixedCols,
+		FixedRows:             s.FixedRows,
+		InputLocked:           s.inputLock != "",
+		NotifyPatterns:        s.NotifyPatterns,
+		LastCols:              s.lastCols,
+		LastRows:              s.lastRows,
+		Model:                 s.Model,
+		PermissionMode:        s.PermissionMode,
+		BaseCommit:            s.BaseCommit,
+		CheckpointIntervalSec: int64(s.CheckpointInterval / time.Second),
+	}
+	if !s.Deadline.IsZero() {
+		info.Deadline = s.Deadline.Local().Format(time.RFC3339)
+		info.TimeLimitSec = int64(s.TimeLimit / time.Second)
+	}
+	info.TokenBudget = s.TokenBudget
+	return info
+}
+
+// InfoForSave returns session info including attachment metadata for
+// persistence. LastOutput is left out: it is stored in its own row
+// (see dirtyOutput).
 func (s *Session) InfoForSave() SessionInfo {
-	info := s.Info()
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	info := s.infoLocked()
+	info.InputLockHash = s.inputLock
+	info.Webhooks = s.webhooks
 	if len(s.attachments) > 0 {
 		atts := make([]*Attachment, 0, len(s.attachments))
 		for _, att := range s.attachments {
@@ -223,6 +419,27 @@ func (s *Session) InfoForSave() SessionInfo {
 	return info
 }
 
+// dirtyOutput returns the last output if it changed since the last
+// call, and marks it stored; outputNotStored undoes that when the
+// write fails.
+func (s *Session) dirtyOutput() ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.outputDirty {
+		return nil, false
+	}
+	s.outputDirty = false
+	return s.lastOutput, true
+}
+
+// outputNotStored marks the last output changed again after a failed
+// write, so the next flush retries it.
+func (s *Session) outputNotStored() {
+	s.mu.Lock()
+	s.outputDirty = true
+	s.mu.Unlock()
+}
+
 func (s *Session) Subscribe() (chan []byte, []byte) {
 	ch := make(chan []byte, 1024)
 	s.subMu.Lock()
@@ -232,23 +449,85 @@ func (s *Session) Subscribe() (chan []byte, []byte) {
 	return ch, scrollback
 }
 
+// Output returns the session's scrollback or, for a session restored
+// after a restart, the last output saved with it.
+func (s *Session) Output() []byte {
+	if out := s.scrollback.Bytes(); len(out) > 0 {
+		return out
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return bytes.Clone(s.lastOutput)
+}
+
 func (s *Session) Unsubscribe(ch chan []byte) {
 	s.subMu.Lock()
 	delete(s.subscribers, ch)
+	delete(s.dropped, ch)
 	s.subMu.Unlock()
 	close(ch)
 }
 
+// subscriberWait is how long broadcast waits on a full subscriber
+// channel before dropping output for it.
+const subscriberWait = 50 * time.Millisecond
+
+// broadcast fans data out to the output subscribers. A full channel
+// gets subscriberWait to make room; after that the subscriber is
+// marked desynced and receives nothing more until it calls Resync, so
+// it never sees a stream with a hole in it.
 func (s *Session) broadcast(data []byte) {
 	s.subMu.Lock()
 	defer s.subMu.Unlock()
+	var timer *time.Timer
 	for ch := range s.subscribers {
+		if n, ok := s.dropped[ch]; ok {
+			s.dropped[ch] = n + int64(len(data))
+			continue
+		}
 		select {
 		case ch <- data:
+			continue
+		default:
+		}
+		if timer == nil {
+			timer = time.NewTimer(subscriberWait)
+			defer timer.Stop()
+		}
+		select {
+		case ch <- data:
+		case <-timer.C:
+			if s.dropped == nil {
+				s.dropped = make(map[chan []byte]int64)
+			}
+			s.dropped[ch] = int64(len(data))
+			s.log().Warn("output subscriber fell behind; dropping until it resyncs", "id", s.ID)
+		}
+	}
+}
+
+// Resync reports whether output to ch was dropped since it subscribed
+// or last resynced. If so it discards what is still queued on ch and
+// returns a fresh scrollback snapshot to replace the subscriber's
+// view, plus how many bytes were lost; delivery resumes with the
+// output that follows the snapshot.
+func (s *Session) Resync(ch chan []byte) (scrollback []byte, dropped int64, ok bool) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	dropped, ok = s.dropped[ch]
+	if !ok {
+		return nil, 0, false
+	}
+	delete(s.dropped, ch)
+discard:
+	for {
+		select {
+		case <-ch:
 		default:
-			// slow consumer, drop
+			break discard
 		}
 	}
+	return s.scrollback.Bytes(), dropped, true
 }
 
 func (s *Session) SubscribeYoloDebug() chan string {
@@ -335,27 +614,29 @@ func (s *Session) Done() <-chan struct{} {
 	return s.done
 }
 
-// CaptureToolSessionID tries to parse a tool-specific session ID from PTY output.
+// CaptureToolSessionID tries to parse a tool-specific session ID from PTY
+// output with the tool's ToolAdapter.ParseSessionID (e.g. codex).
 // Only captures once (when ToolSessionID is still empty).
 // Accumulates data across chunk boundaries to handle split reads.
 func (s *Session) CaptureToolSessionID(data []byte) {
 	s.mu.Lock()
-	if s.ToolSessionID != "" || s.Tool != "codex" {
+	a := toolAdapters[s.Tool]
+	if s.ToolSessionID != "" || a == nil {
 		s.mu.Unlock()
 		return
 	}
 	// accumulate data, keep last 256 bytes
-	s.codexCaptureBuf = capTail(s.codexCaptureBuf, data, 256)
-	buf := make([]byte, len(s.codexCaptureBuf))
-	copy(buf, s.codexCaptureBuf)
+	s.idCaptureBuf = capTail(s.idCaptureBuf, data, 256)
+	buf := make([]byte, len(s.idCaptureBuf))
+	copy(buf, s.idCaptureBuf)
 	s.mu.Unlock()
 
 	clean := ansiRe.ReplaceAll(buf, []byte(" "))
-	if m := codexSessionIDRe.FindSubmatch(clean); m != nil {
+	if id := a.ParseSessionID(clean); id != "" {
 		s.mu.Lock()
 		if s.ToolSessionID == "" {
-			s.ToolSessionID = string(m[1])
-			s.codexCaptureBuf = nil // done, free buffer
+			s.ToolSessionID = id
+			s.idCaptureBuf = nil // done, free buffer
 		}
 		s.mu.Unlock()
 	}
@@ -382,6 +663,7 @@ func (s *Session) CheckYolo(data []byte) (*YoloApproval, string) {
 		s.mu.Unlock()
 		return nil, ""
 	}
+	prompt := approvalPrompt(s.Tool)
 
 	// append to tail, keep last yoloTailSize bytes
 	s.yoloTail = capTail(s.yoloTail, data, yoloTailSize)
@@ -396,7 +678,7 @@ func (s *Session) CheckYolo(data []byte) (*YoloApproval, string) {
 	clean = multiSpaceRe.ReplaceAll(clean, []byte(" "))
 	cleanStr := string(clean)
 
-	loc := yoloPattern.FindIndex(clean)
+	loc := prompt.FindIndex(clean)
 	if loc == nil {
 		return nil, cleanStr
 	}
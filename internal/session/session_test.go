@@ -1,8 +1,12 @@
 package session
 
 import (
+	"errors"
+	"log/slog"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 )
 
 func newTestSession(yolo bool) *Session {
@@ -198,10 +202,10 @@ func TestAppendYoloFlag(t *testing.T) {
 
 	t.Run("flag survives claude restart args", func(t *testing.T) {
 		// Simulate Create: yolo flag baked into the persisted args, plus the
-		// session-id that assignClaudeSessionID appends. buildRestartArgs
+		// session-id that assignToolSessionID appends. buildRestartArgs
 		// reuses those persisted args, so the yolo flag must flow through.
 		created := appendYoloFlag("claude", nil, true)
-		_, runArgs := assignClaudeSessionID("claude", created)
+		_, runArgs := assignToolSessionID("claude", created)
 		restart := buildRestartArgs("claude", runArgs, "sess-123")
 		if !hasArg(restart, claudeFlag) {
 			t.Fatalf("expected %q to survive restart, got %v", claudeFlag, restart)
@@ -228,3 +232,244 @@ func TestAnsiRe_StripsTildeTerminated(t *testing.T) {
 		t.Fatalf("expected 'visible', got %q", string(clean))
 	}
 }
+
+func TestLoopStats(t *testing.T) {
+	s := newTestSession(false)
+	s.ID, s.Status = "s1", StatusRunning
+	s.subscribers[make(chan []byte)] = struct{}{}
+	m := &Manager{sessions: map[string]*Session{"s1": s}}
+
+	stop := make(chan struct{})
+	block := func() { <-stop }
+	s.loops.start(loopRead, block)
+	s.loops.start(loopWait, block)
+	st := m.LoopStats()
+	if len(st) != 1 || st[0].ReadLoop != 1 || st[0].WaitLoop != 1 || st[0].DrainLoop != 0 || st[0].Subscribers != 1 {
+		t.Fatalf("LoopStats = %+v", st)
+	}
+	if !st[0].Loops["read"].Running || st[0].Loops["reaper"].Running {
+		t.Fatalf("Loops = %+v", st[0].Loops)
+	}
+	close(stop)
+	<-s.loops.done(loopRead)
+	<-s.loops.done(loopWait)
+	if st := m.LoopStats(); st[0].ReadLoop != 0 || st[0].WaitLoop != 0 {
+		t.Fatalf("after exit LoopStats = %+v", st)
+	}
+}
+
+func TestSupervisorRefusesDoubleStart(t *testing.T) {
+	var sv supervisor
+	select {
+	case <-sv.done(loopRead):
+	default:
+		t.Fatal("done blocks for a loop that never ran")
+	}
+	stop := make(chan struct{})
+	if !sv.start(loopRead, func() { <-stop }) {
+		t.Fatal("first start refused")
+	}
+	if sv.start(loopRead, func() { t.Error("second loop ran") }) {
+		t.Fatal("second start accepted while the first runs")
+	}
+	if got := sv.states()["read"].RefusedStarts; got != 1 {
+		t.Fatalf("RefusedStarts = %d", got)
+	}
+	close(stop)
+	if !sv.wait(loopRead, time.Second) {
+		t.Fatal("loop didn't exit")
+	}
+	ran := make(chan struct{})
+	if !sv.start(loopRead, func() { close(ran) }) {
+		t.Fatal("restart after exit refused")
+	}
+	<-ran
+}
+
+func TestSubscribeList(t *testing.T) {
+	exited := newTestSession(false)
+	exited.ID, exited.Status = "s1", StatusExited
+	child := newTestSession(false)
+	child.ID, child.ParentID, child.Status = "s2", "s1", StatusExited
+	m := &Manager{
+		sessions: map[string]*Session{"s1": exited, "s2": child},
+		logger:   slog.Default(),
+		store:    newStore(slog.Default(), nil, ""),
+	}
+
+	events, cancel := m.SubscribeList()
+	if err := m.Remove("s1"); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]ListEventType{}
+	for range 2 {
+		ev := <-events
+		got[ev.Session.ID] = ev.Type
+	}
+	if got["s1"] != ListEventRemoved || got["s2"] != ListEventRemoved {
+		t.Fatalf("events = %v", got)
+	}
+	cancel()
+	if _, ok := <-events; ok {
+		t.Fatal("channel open after cancel")
+	}
+	cancel() // idempotent
+}
+
+func TestSubscribeListDropsSlowSubscriber(t *testing.T) {
+	m := &Manager{logger: slog.Default()}
+	slow, cancel := m.SubscribeList()
+	defer cancel()
+	for range listEventBuffer + 1 {
+		m.publishList(ListEventUpdated, SessionInfo{ID: "s1"})
+	}
+	n := 0
+	for range slow {
+		n++
+	}
+	if n != listEventBuffer {
+		t.Fatalf("received %d events before close, want %d", n, listEventBuffer)
+	}
+}
+
+func TestBroadcastDesyncAndResync(t *testing.T) {
+	s := newTestSession(false)
+	s.scrollback = NewRingBuffer(1024)
+	slow := make(chan []byte, 1)
+	fast := make(chan []byte, 8)
+	s.subscribers[slow] = struct{}{}
+	s.subscribers[fast] = struct{}{}
+
+	for _, chunk := range []string{"a", "bb", "ccc"} {
+		s.scrollback.Write([]byte(chunk))
+		s.broadcast([]byte(chunk))
+	}
+	if len(fast) != 3 {
+		t.Fatalf("fast subscriber got %d chunks, want 3", len(fast))
+	}
+	if _, _, ok := s.Resync(fast); ok {
+		t.Fatal("fast subscriber reported desynced")
+	}
+	snap, dropped, ok := s.Resync(slow)
+	if !ok || dropped != 5 || string(snap) != "abbccc" {
+		t.Fatalf("Resync = %q, %d, %v", snap, dropped, ok)
+	}
+	if len(slow) != 0 {
+		t.Fatal("queued output not discarded on resync")
+	}
+	s.broadcast([]byte("d"))
+	if got := string(<-slow); got != "d" {
+		t.Fatalf("after resync got %q", got)
+	}
+}
+
+func TestSignalRejects(t *testing.T) {
+	exited := newTestSession(false)
+	exited.ID, exited.Status = "s1", StatusExited
+	m := &Manager{sessions: map[string]*Session{"s1": exited}, logger: slog.Default()}
+
+	for _, tc := range []struct {
+		id, sig string
+		want    error
+	}{
+		{"nope", "SIGINT", ErrSessionNotFound},
+		{"s1", "SIGBOGUS", ErrUnsupportedSignal},
+		{"s1", "KILL", ErrSessionNotRunning},
+	} {
+		if err := m.Signal(tc.id, tc.sig); !errors.Is(err, tc.want) {
+			t.Errorf("Signal(%q, %q) = %v, want %v", tc.id, tc.sig, err, tc.want)
+		}
+	}
+}
+
+func TestRemoveExited(t *testing.T) {
+	sess := func(id, parent string, status Status) *Session {
+		s := newTestSession(false)
+		s.ID, s.ParentID, s.Status = id, parent, status
+		return s
+	}
+	m := &Manager{
+		sessions: map[string]*Session{
+			"a":  sess("a", "", StatusExited),
+			"a1": sess("a1", "a", StatusExited),
+			"b":  sess("b", "", StatusExited),
+			"b1": sess("b1", "b", StatusRunning),
+			"c":  sess("c", "", StatusRunning),
+		},
+		logger: slog.Default(),
+		store:  newStore(slog.Default(), nil, ""),
+	}
+	if got := m.RemoveExited(); !slices.Equal(got, []string{"a"}) {
+		t.Fatalf("RemoveExited = %v", got)
+	}
+	var left []string
+	for id := range m.sessions {
+		left = append(left, id)
+	}
+	slices.Sort(left)
+	if !slices.Equal(left, []string{"b", "b1", "c"}) {
+		t.Fatalf("left = %v", left)
+	}
+}
+
+func TestDropFlag(t *testing.T) {
+	args := []string{"--model", "opus", "--session-id", "abc", "--resume=x", "-c", "--verbose"}
+	got := dropFlag(dropFlag(dropFlag(args, "--session-id", true), "--resume", true), "-c", false)
+	if want := []string{"--model", "opus", "--verbose"}; !slices.Equal(got, want) {
+		t.Fatalf("dropFlag = %v, want %v", got, want)
+	}
+}
+
+func TestCloneResumeNeedsClaudeConversation(t *testing.T) {
+	codex := newTestSession(false)
+	codex.ID, codex.Tool, codex.ToolSessionID = "s1", "codex", "t1"
+	fresh := newTestSession(false)
+	fresh.ID, fresh.Tool = "s2", "claude"
+	m := &Manager{sessions: map[string]*Session{"s1": codex, "s2": fresh}, logger: slog.Default()}
+
+	for _, id := range []string{"s1", "s2"} {
+		if _, err := m.Clone(id, true); !errors.Is(err, ErrCannotResume) {
+			t.Errorf("Clone(%s, resume) = %v, want ErrCannotResume", id, err)
+		}
+	}
+	if _, err := m.Clone("nope", false); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Clone(nope) = %v", err)
+	}
+}
+
+func TestSaveDebouncesAndTracksOutputs(t *testing.T) {
+	s := newTestSession(false)
+	s.ID, s.Status = "a", StatusExited
+	s.lastOutput, s.outputDirty = []byte("bye"), true
+	m := &Manager{
+		sessions: map[string]*Session{"a": s},
+		logger:   slog.Default(),
+		store:    newStore(slog.Default(), nil, ""),
+	}
+	m.save()
+	timer := m.saveTimer
+	m.save()
+	if timer == nil || m.saveTimer != timer {
+		t.Fatal("second save armed a new timer")
+	}
+	if info := s.InfoForSave(); info.LastOutput != "" {
+		t.Fatal("InfoForSave carries the output")
+	}
+
+	m.SaveAll()
+	if m.saveTimer != nil {
+		t.Fatal("SaveAll left the timer armed")
+	}
+	if _, dirty := s.dirtyOutput(); dirty {
+		t.Fatal("output still dirty after SaveAll")
+	}
+
+	m.RemoveExited()
+	if !slices.Equal(m.removedOutputs, []string{"a"}) {
+		t.Fatalf("removedOutputs = %v", m.removedOutputs)
+	}
+	m.SaveAll()
+	if m.removedOutputs != nil {
+		t.Fatalf("removedOutputs = %v after flush", m.removedOutputs)
+	}
+}
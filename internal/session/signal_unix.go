@@ -4,6 +4,7 @@ package session
 
 import (
 	"os"
+	"strings"
 	"syscall"
 )
 
@@ -17,3 +18,21 @@ func ShutdownSignals() []os.Signal { return shutdownSignals }
 func sendTermSignal(p *os.Process) error {
 	return p.Signal(syscall.SIGTERM)
 }
+
+// sessionSignals are the signals Manager.Signal accepts, by name
+// without the SIG prefix.
+var sessionSignals = map[string]syscall.Signal{
+	"INT":  syscall.SIGINT,
+	"TERM": syscall.SIGTERM,
+	"HUP":  syscall.SIGHUP,
+	"QUIT": syscall.SIGQUIT,
+	"KILL": syscall.SIGKILL,
+	"STOP": syscall.SIGSTOP,
+	"CONT": syscall.SIGCONT,
+}
+
+// parseSignal maps "SIGINT", "int" or "INT" onto a session signal.
+func parseSignal(name string) (syscall.Signal, bool) {
+	sig, ok := sessionSignals[strings.TrimPrefix(strings.ToUpper(name), "SIG")]
+	return sig, ok
+}
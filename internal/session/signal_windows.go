@@ -2,7 +2,10 @@
 
 package session
 
-import "os"
+import (
+	"os"
+	"strings"
+)
 
 // shutdownSignals are the OS signals that trigger graceful shutdown.
 var shutdownSignals = []os.Signal{os.Interrupt}
@@ -15,3 +18,12 @@ func ShutdownSignals() []os.Signal { return shutdownSignals }
 func sendTermSignal(p *os.Process) error {
 	return p.Kill()
 }
+
+// parseSignal accepts only KILL on Windows, which has no signals to
+// deliver to a console process; it maps onto TerminateProcess.
+func parseSignal(name string) (os.Signal, bool) {
+	if strings.TrimPrefix(strings.ToUpper(name), "SIG") == "KILL" {
+		return os.Kill, true
+	}
+	return nil, false
+}
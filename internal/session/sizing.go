@@ -0,0 +1,125 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SizePolicy decides the terminal size when clients with different
+// screens are attached to one session. kojo is the only client tmux
+// sees, so the choice is made here from each WebSocket's reported
+// size; the result goes to the pane with resize-window, which also
+// pins tmux's window-size to manual so an outside tmux attach doesn't
+// undo it.
+type SizePolicy string
+
+const (
+	// SizeLast follows the last client to resize, or only the client
+	// with input control while one has it. It is the default.
+	SizeLast SizePolicy = "last"
+	// SizeSmallest fits the smallest attached client, so a phone
+	// shrinks the terminal while it is attached and it grows back once
+	// the phone leaves.
+	SizeSmallest SizePolicy = "smallest"
+	// SizeFixed keeps FixedCols x FixedRows whatever the clients say.
+	SizeFixed SizePolicy = "fixed"
+)
+
+// Bounds of a fixed terminal size.
+const (
+	minFixedCols, maxFixedCols = 20, 1000
+	minFixedRows, maxFixedRows = 5, 500
+)
+
+// ErrInvalidSizePolicy is returned for an unknown policy or a fixed
+// size out of bounds.
+var ErrInvalidSizePolicy = errors.New("invalid size policy")
+
+// CheckSizePolicy reports whether SetSizePolicy would take p, cols
+// and rows.
+func CheckSizePolicy(p SizePolicy, cols, rows uint16) error {
+	switch p {
+	case "", SizeLast, SizeSmallest:
+		if cols != 0 || rows != 0 {
+			return fmt.Errorf("%w: a size only goes with %q", ErrInvalidSizePolicy, SizeFixed)
+		}
+	case SizeFixed:
+		if cols < minFixedCols || cols > maxFixedCols || rows < minFixedRows || rows > maxFixedRows {
+			return fmt.Errorf("%w: fixed size must be %d-%d columns by %d-%d rows",
+				ErrInvalidSizePolicy, minFixedCols, maxFixedCols, minFixedRows, maxFixedRows)
+		}
+	default:
+		return fmt.Errorf("%w: %q (want %q, %q or %q)", ErrInvalidSizePolicy, p, SizeLast, SizeSmallest, SizeFixed)
+	}
+	return nil
+}
+
+// SetSizePolicy changes how the session's terminal is sized; cols and
+// rows are the size for SizeFixed and must be 0 otherwise. "" means
+// SizeLast. The new policy is applied at once.
+func (s *Session) SetSizePolicy(p SizePolicy, cols, rows uint16) error {
+	if err := CheckSizePolicy(p, cols, rows); err != nil {
+		return err
+	}
+	if p == SizeLast {
+		p = ""
+	}
+	s.mu.Lock()
+	s.SizePolicy, s.FixedCols, s.FixedRows = p, cols, rows
+	s.mu.Unlock()
+	s.applySize("")
+	return nil
+}
+
+// ClientResize records client id's terminal size and resizes the
+// session as its size policy says.
+func (s *Session) ClientResize(id string, cols, rows uint16) error {
+	s.mu.Lock()
+	if c, ok := s.clients[id]; ok {
+		c.Cols, c.Rows = cols, rows
+		s.clients[id] = c
+	}
+	s.mu.Unlock()
+	return s.applySize(id)
+}
+
+// applySize resizes the session to what its policy makes of the
+// clients' sizes; from is the client that just resized, if any.
+func (s *Session) applySize(from string) error {
+	s.mu.Lock()
+	cols, rows, ok := s.targetSizeLocked(from)
+	s.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return s.Resize(cols, rows)
+}
+
+// targetSizeLocked is the size the policy calls for, or false for no
+// change. Caller holds s.mu.
+func (s *Session) targetSizeLocked(from string) (cols, rows uint16, ok bool) {
+	switch s.SizePolicy {
+	case SizeFixed:
+		return s.FixedCols, s.FixedRows, s.FixedCols > 0 && s.FixedRows > 0
+	case SizeSmallest:
+		for _, c := range s.clients {
+			if c.Cols == 0 || c.Rows == 0 {
+				continue // hasn't reported a size yet
+			}
+			if !ok || c.Cols < cols {
+				cols = c.Cols
+			}
+			if !ok || c.Rows < rows {
+				rows = c.Rows
+			}
+			ok = true
+		}
+		return cols, rows, ok
+	default:
+		if from == "" || (s.inputOwner != "" && s.inputOwner != from) {
+			return 0, 0, false
+		}
+		c := s.clients[from]
+		return c.Cols, c.Rows, c.Cols > 0 && c.Rows > 0
+	}
+}
@@ -0,0 +1,60 @@
+package session
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSizePolicy(t *testing.T) {
+	s := newTestSession(false)
+	desktop, _ := s.AddClient(Client{Identity: "owner"})
+	phone, _ := s.AddClient(Client{Identity: "owner"})
+	// No PTY here, so only the recorded sizes and the target matter.
+	_ = s.ClientResize(desktop, 200, 50)
+	_ = s.ClientResize(phone, 40, 30)
+
+	target := func(from string) (uint16, uint16, bool) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.targetSizeLocked(from)
+	}
+	check := func(name, from string, wantCols, wantRows uint16, wantOK bool) {
+		t.Helper()
+		cols, rows, ok := target(from)
+		if cols != wantCols || rows != wantRows || ok != wantOK {
+			t.Errorf("%s: target = %dx%d %v, want %dx%d %v", name, cols, rows, ok, wantCols, wantRows, wantOK)
+		}
+	}
+
+	check("last", phone, 40, 30, true)
+	s.ClaimInput(desktop)
+	check("last, phone without control", phone, 0, 0, false)
+	check("last, desktop with control", desktop, 200, 50, true)
+
+	if err := s.SetSizePolicy(SizeSmallest, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	check("smallest", desktop, 40, 30, true)
+	s.RemoveClient(phone)
+	check("smallest after the phone left", "", 200, 50, true)
+
+	if err := s.SetSizePolicy(SizeFixed, 120, 40); err != nil {
+		t.Fatal(err)
+	}
+	check("fixed", desktop, 120, 40, true)
+	if info := s.Info(); info.SizePolicy != "fixed" || info.FixedCols != 120 || info.FixedRows != 40 {
+		t.Errorf("info = %q %dx%d", info.SizePolicy, info.FixedCols, info.FixedRows)
+	}
+
+	for _, bad := range []struct {
+		p          SizePolicy
+		cols, rows uint16
+	}{{"biggest", 0, 0}, {SizeFixed, 10, 40}, {SizeFixed, 120, 0}, {SizeSmallest, 80, 24}} {
+		if err := s.SetSizePolicy(bad.p, bad.cols, bad.rows); !errors.Is(err, ErrInvalidSizePolicy) {
+			t.Errorf("SetSizePolicy(%q, %d, %d) = %v", bad.p, bad.cols, bad.rows, err)
+		}
+	}
+	if err := s.SetSizePolicy(SizeLast, 0, 0); err != nil || s.Info().SizePolicy != "" {
+		t.Errorf("back to last: err = %v, policy = %q", err, s.Info().SizePolicy)
+	}
+}
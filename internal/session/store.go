@@ -54,6 +54,7 @@ package session
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"log/slog"
@@ -78,12 +79,18 @@ const (
 	sessionsKVNamespace = "sessions"
 	sessionsKVKey       = "all"
 
+	// sessionOutputKVNamespace holds each exited session's lastOutput
+	// (base64, keyed by session ID) as its own row, so the "all" row
+	// stays small and a save rewrites only the tails that changed.
+	sessionOutputKVNamespace = "session_output"
+
 	// Per-write timeout. Generous because a slow disk on the kv put
 	// is the same posture as the previous atomicfile.WriteJSON.
 	sessionsKVTimeout = 10 * time.Second
 )
 
-// Store persists session metadata as a single JSON-typed kv row.
+// Store persists session metadata as a single JSON-typed kv row, and
+// each exited session's last output as a row of its own.
 //
 // See the package doc comment for the post-slice-28 state: kv is
 // canonical, the legacy <configdir.Path()>/sessions.json file is
@@ -108,6 +115,9 @@ type Store struct {
 	// entirely (e.g. when the runtime opted out via --fresh).
 	v0LegacyPath string
 	logger       *slog.Logger
+	// lastBody is the list JSON last written or loaded; Save skips
+	// a write that wouldn't change the row.
+	lastBody string
 }
 
 // newStore constructs the kv-backed session store. v0LegacyDir is the
@@ -144,6 +154,9 @@ func (st *Store) Save(infos []SessionInfo) {
 		st.logger.Warn("failed to marshal sessions", "err", err)
 		return
 	}
+	if string(body) == st.lastBody {
+		return
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), sessionsKVTimeout)
 	defer cancel()
 	rec := &store.KVRecord{
@@ -155,7 +168,73 @@ func (st *Store) Save(infos []SessionInfo) {
 	}
 	if _, err := st.db.PutKV(ctx, rec, store.KVPutOptions{}); err != nil {
 		st.logger.Warn("failed to save sessions to kv", "err", err)
+		return
+	}
+	st.lastBody = string(body)
+}
+
+// SaveOutput stores a session's last output in its own kv row; empty
+// output deletes the row. Errors are logged, as for Save, and returned
+// so the caller can try again.
+func (st *Store) SaveOutput(id string, output []byte) error {
+	if st.db == nil {
+		return nil
+	}
+	if len(output) == 0 {
+		return st.DeleteOutput(id)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sessionsKVTimeout)
+	defer cancel()
+	rec := &store.KVRecord{
+		Namespace: sessionOutputKVNamespace,
+		Key:       id,
+		Value:     base64.StdEncoding.EncodeToString(output),
+		Type:      store.KVTypeString,
+		Scope:     store.KVScopeLocal,
+	}
+	if _, err := st.db.PutKV(ctx, rec, store.KVPutOptions{}); err != nil {
+		st.logger.Warn("failed to save session output to kv", "id", id, "err", err)
+		return err
+	}
+	return nil
+}
+
+// DeleteOutput removes a session's output row, if any.
+func (st *Store) DeleteOutput(id string) error {
+	if st.db == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sessionsKVTimeout)
+	defer cancel()
+	if err := st.db.DeleteKV(ctx, sessionOutputKVNamespace, id, ""); err != nil {
+		st.logger.Warn("failed to delete session output from kv", "id", id, "err", err)
+		return err
+	}
+	return nil
+}
+
+// LoadOutputs returns every stored session output, base64-encoded as
+// SessionInfo.LastOutput carries it, by session ID. Rows that fail
+// the shape gate are skipped.
+func (st *Store) LoadOutputs() map[string]string {
+	if st.db == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sessionsKVTimeout)
+	defer cancel()
+	recs, err := st.db.ListKV(ctx, sessionOutputKVNamespace)
+	if err != nil {
+		st.logger.Warn("failed to read session outputs from kv", "err", err)
+		return nil
+	}
+	out := make(map[string]string, len(recs))
+	for _, rec := range recs {
+		if rec.Type != store.KVTypeString || rec.Scope != store.KVScopeLocal || rec.Secret {
+			continue
+		}
+		out[rec.Key] = rec.Value
 	}
+	return out
 }
 
 // sessionsKVCollisionTestHook fires inside Load AFTER the kv miss
@@ -191,6 +270,7 @@ var sessionsKVCollisionTestHook func()
 func (st *Store) Load() ([]SessionInfo, error) {
 	st.mu.Lock()
 	defer st.mu.Unlock()
+	st.lastBody = ""
 
 	if st.db == nil {
 		return nil, nil
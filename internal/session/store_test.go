@@ -720,3 +720,65 @@ func TestStoreKV_MalformedRowFallsBackToLegacy(t *testing.T) {
 		t.Errorf("legacy file unlinked despite malformed kv row: %v", err)
 	}
 }
+
+// TestStoreKV_OutputRows verifies session outputs round-trip through
+// their own rows and stay out of the list row.
+func TestStoreKV_OutputRows(t *testing.T) {
+	db := kvTestStore(t)
+	st := newStore(sessionTestLogger(), db, "")
+
+	st.Save([]SessionInfo{sampleInfo("sess_a", time.Now())})
+	st.SaveOutput("sess_a", []byte("bye\r\n"))
+	st.SaveOutput("sess_b", []byte("gone"))
+	st.DeleteOutput("sess_b")
+
+	outputs := st.LoadOutputs()
+	if len(outputs) != 1 || outputs["sess_a"] != "YnllDQo=" {
+		t.Fatalf("LoadOutputs = %v", outputs)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rec, err := db.GetKV(ctx, sessionsKVNamespace, sessionsKVKey)
+	if err != nil {
+		t.Fatalf("GetKV: %v", err)
+	}
+	var infos []SessionInfo
+	if err := json.Unmarshal([]byte(rec.Value), &infos); err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 || infos[0].LastOutput != "" {
+		t.Fatalf("list row = %s", rec.Value)
+	}
+
+	// An unchanged list is not rewritten.
+	st.Save(infos)
+	again, err := db.GetKV(ctx, sessionsKVNamespace, sessionsKVKey)
+	if err != nil {
+		t.Fatalf("GetKV: %v", err)
+	}
+	if again.Version != rec.Version {
+		t.Errorf("unchanged Save bumped version %d -> %d", rec.Version, again.Version)
+	}
+}
+
+// TestFlushRetriesFailedOutputWrite verifies an output whose row write
+// fails stays dirty for the next flush.
+func TestFlushRetriesFailedOutputWrite(t *testing.T) {
+	db := kvTestStore(t)
+	s := newTestSession(false)
+	s.ID = "sess_a"
+	s.lastOutput = []byte("bye")
+	s.outputDirty = true
+	m := &Manager{
+		sessions: map[string]*Session{s.ID: s},
+		logger:   sessionTestLogger(),
+		store:    newStore(sessionTestLogger(), db, ""),
+	}
+
+	_ = db.Close()
+	m.flush()
+	if out, ok := s.dirtyOutput(); !ok || string(out) != "bye" {
+		t.Errorf("dirtyOutput after a failed write = %q, %v; want it still dirty", out, ok)
+	}
+}
@@ -0,0 +1,131 @@
+package session
+
+import (
+	"sync"
+	"time"
+)
+
+// loopKind names a session background goroutine.
+type loopKind int
+
+const (
+	loopRead       loopKind = iota // readLoop
+	loopDrain                      // drainLoop
+	loopWait                       // waitLoop or tmuxWaitLoop
+	loopReaper                     // attach process reaper (tmux)
+	loopLimit                      // limitLoop
+	loopCheckpoint                 // checkpointLoop
+	numLoopKinds
+)
+
+var loopNames = [numLoopKinds]string{"read", "drain", "wait", "reaper", "limit", "checkpoint"}
+
+func (k loopKind) String() string { return loopNames[k] }
+
+// supervisor owns a session's background goroutines. At most one
+// goroutine of each kind runs at a time: start refuses a second while
+// the first is alive, and done hands out the channel closed when the
+// current one exits, so callers wait on the goroutine itself rather
+// than on a channel someone has to remember to recreate.
+type supervisor struct {
+	mu   sync.Mutex
+	runs [numLoopKinds]*loopRun
+	// refused counts start calls turned away because the kind was
+	// already running; non-zero means a double start was attempted.
+	refused [numLoopKinds]int32
+}
+
+type loopRun struct {
+	done    chan struct{}
+	started time.Time
+}
+
+func (r *loopRun) alive() bool {
+	select {
+	case <-r.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// start runs fn in a new goroutine as the session's loop of kind k. It
+// returns false, without running fn, if that loop is still running.
+func (sv *supervisor) start(k loopKind, fn func()) bool {
+	sv.mu.Lock()
+	if r := sv.runs[k]; r != nil && r.alive() {
+		sv.refused[k]++
+		sv.mu.Unlock()
+		return false
+	}
+	r := &loopRun{done: make(chan struct{}), started: time.Now()}
+	sv.runs[k] = r
+	sv.mu.Unlock()
+	go func() {
+		defer close(r.done)
+		fn()
+	}()
+	return true
+}
+
+// closedChan is what done returns for a loop that never ran.
+var closedChan = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
+
+// done returns a channel closed when the current (or last) loop of
+// kind k has exited; already closed if none was started.
+func (sv *supervisor) done(k loopKind) <-chan struct{} {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	if r := sv.runs[k]; r != nil {
+		return r.done
+	}
+	return closedChan
+}
+
+// wait waits up to timeout for the loop of kind k to exit and reports
+// whether it did.
+func (sv *supervisor) wait(k loopKind, timeout time.Duration) bool {
+	select {
+	case <-sv.done(k):
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// running reports whether the loop of kind k is alive.
+func (sv *supervisor) running(k loopKind) bool {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	r := sv.runs[k]
+	return r != nil && r.alive()
+}
+
+// LoopState is the liveness of one session loop kind.
+type LoopState struct {
+	Running bool       `json:"running"`
+	Since   *time.Time `json:"since,omitempty"`
+	// RefusedStarts counts attempts to start the loop while it was
+	// already running.
+	RefusedStarts int32 `json:"refusedStarts,omitempty"`
+}
+
+// states reports every loop kind by name.
+func (sv *supervisor) states() map[string]LoopState {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	out := make(map[string]LoopState, numLoopKinds)
+	for k := range numLoopKinds {
+		st := LoopState{RefusedStarts: sv.refused[k]}
+		if r := sv.runs[k]; r != nil && r.alive() {
+			started := r.started
+			st.Running, st.Since = true, &started
+		}
+		out[k.String()] = st
+	}
+	return out
+}
@@ -0,0 +1,165 @@
+package session
+
+import (
+	"strings"
+	"time"
+)
+
+// TermEvent is a terminal signal from the tool that kojo would
+// otherwise swallow: a bell, or a window title set with OSC 0 / OSC 2.
+// Tools use both to say "I need you". "image" events announce an
+// inline image kept by CheckInlineImages.
+type TermEvent struct {
+	Type  string       `json:"type"` // "bell", "title" or "image"
+	Title string       `json:"title,omitempty"`
+	Image *InlineImage `json:"image,omitempty"`
+}
+
+// maxTitleLen caps an OSC body kept for title parsing; longer ones
+// (an OSC 52 copy, say) are skipped without buffering.
+const maxTitleLen = 1024
+
+// bellNotifyInterval spaces out bell push notifications per session.
+// Tools often ring several times per prompt.
+const bellNotifyInterval = 30 * time.Second
+
+// titlePublishDelay coalesces title changes into session_updated list
+// events. Some tools animate a spinner in the title several times a
+// second; the list only needs to settle on the latest.
+const titlePublishDelay = time.Second
+
+// termScanner is the state CheckTermEvents carries across reads.
+type termScanner struct {
+	inOSC   bool
+	esc     bool // previous byte was ESC
+	body    []byte
+	tooLong bool
+}
+
+// CheckTermEvents scans output for BEL outside an OSC sequence and for
+// OSC 0 / OSC 2 title changes, tracking escape state across reads. The
+// title is recorded on the session; an unchanged title isn't reported.
+func (s *Session) CheckTermEvents(data []byte) []TermEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var events []TermEvent
+	sc := &s.term
+	for _, c := range data {
+		if !sc.inOSC {
+			switch {
+			case sc.esc && c == ']':
+				sc.inOSC, sc.body, sc.tooLong = true, sc.body[:0], false
+			case c == '\a':
+				if n := len(events); n == 0 || events[n-1].Type != "bell" {
+					events = append(events, TermEvent{Type: "bell"})
+				}
+			}
+			sc.esc = c == '\x1b'
+			continue
+		}
+		switch {
+		case c == '\a', sc.esc && c == '\\':
+			if title, ok := oscTitle(sc.body); ok && !sc.tooLong && title != s.Title {
+				s.Title = title
+				events = append(events, TermEvent{Type: "title", Title: title})
+			}
+			sc.inOSC = false
+		case sc.esc:
+			// ESC followed by anything but '\' cancels the sequence.
+			sc.inOSC = false
+		case c != '\x1b':
+			if len(sc.body) < maxTitleLen {
+				sc.body = append(sc.body, c)
+			} else {
+				sc.tooLong = true
+			}
+		}
+		sc.esc = c == '\x1b'
+	}
+	return events
+}
+
+// oscTitle parses an OSC 0 (icon name and title) or OSC 2 (title)
+// body.
+func oscTitle(body []byte) (string, bool) {
+	ps, title, ok := strings.Cut(string(body), ";")
+	if !ok || (ps != "0" && ps != "2") {
+		return "", false
+	}
+	return strings.ToValidUTF8(title, ""), true
+}
+
+// bellNotifyDue reports whether a bell should become a push
+// notification: the session opted in and the last one was long enough
+// ago.
+func (s *Session) bellNotifyDue(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.NotifyOnBell || now.Sub(s.lastBellNotify) < bellNotifyInterval {
+		return false
+	}
+	s.lastBellNotify = now
+	return true
+}
+
+// SetNotifyOnBell turns bell push notifications on or off.
+func (s *Session) SetNotifyOnBell(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.NotifyOnBell = enabled
+}
+
+func (s *Session) SubscribeTermEvents() chan TermEvent {
+	ch := make(chan TermEvent, 16)
+	s.subMu.Lock()
+	if s.termSubs == nil {
+		s.termSubs = make(map[chan TermEvent]struct{})
+	}
+	s.termSubs[ch] = struct{}{}
+	s.subMu.Unlock()
+	return ch
+}
+
+func (s *Session) UnsubscribeTermEvents(ch chan TermEvent) {
+	s.subMu.Lock()
+	delete(s.termSubs, ch)
+	s.subMu.Unlock()
+	close(ch)
+}
+
+func (s *Session) BroadcastTermEvent(ev TermEvent) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	for ch := range s.termSubs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// handleTermEvents fans a read's terminal events out: to terminal
+// WebSockets, to the session list (titles, coalesced), and to
+// OnSessionBell.
+func (m *Manager) handleTermEvents(s *Session, events []TermEvent) {
+	for _, ev := range events {
+		s.BroadcastTermEvent(ev)
+		switch ev.Type {
+		case "title":
+			s.mu.Lock()
+			if s.titlePublish == nil {
+				s.titlePublish = time.AfterFunc(titlePublishDelay, func() {
+					s.mu.Lock()
+					s.titlePublish = nil
+					s.mu.Unlock()
+					m.publishList(ListEventUpdated, s.Info())
+				})
+			}
+			s.mu.Unlock()
+		case "bell":
+			if m.OnSessionBell != nil && s.bellNotifyDue(time.Now()) {
+				go m.OnSessionBell(s)
+			}
+		}
+	}
+}
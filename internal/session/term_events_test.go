@@ -0,0 +1,47 @@
+package session
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCheckTermEvents(t *testing.T) {
+	s := newTestSession(false)
+	reads := []string{
+		"ding\a\a and \x1b]0;first\a",
+		// OSC 2 split mid-sequence and ST-terminated; its BEL-free
+		// body must not ring, and the OSC 52 BEL terminator neither.
+		"\x1b]2;sec", "ond\x1b\\\x1b]52;c;aGk=\a",
+		"\x1b]2;second\a", // unchanged: not reported
+	}
+	var got []TermEvent
+	for _, r := range reads {
+		got = append(got, s.CheckTermEvents([]byte(r))...)
+	}
+	want := []TermEvent{{Type: "bell"}, {Type: "title", Title: "first"}, {Type: "title", Title: "second"}}
+	if !slices.Equal(got, want) {
+		t.Fatalf("events = %+v, want %+v", got, want)
+	}
+	if s.Info().Title != "second" {
+		t.Fatalf("Title = %q", s.Info().Title)
+	}
+}
+
+func TestBellNotifyDue(t *testing.T) {
+	s := newTestSession(false)
+	now := time.Now()
+	if s.bellNotifyDue(now) {
+		t.Fatal("notified without opting in")
+	}
+	s.SetNotifyOnBell(true)
+	if !s.bellNotifyDue(now) {
+		t.Fatal("first bell not notified")
+	}
+	if s.bellNotifyDue(now.Add(time.Second)) {
+		t.Fatal("second bell within the interval notified")
+	}
+	if !s.bellNotifyDue(now.Add(bellNotifyInterval)) {
+		t.Fatal("bell after the interval not notified")
+	}
+}
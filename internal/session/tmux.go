@@ -3,37 +3,210 @@
 package session
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
+
+	"github.com/loppo-llc/kojo/internal/atomicfile"
 )
 
 const tmuxPrefix = "kojo_"
 
-// tmuxEnsureServerConfig ensures the tmux server has terminal-overrides set
-// to disable alternate screen (smcup/rmcup) for the outer terminal.
-//
-// Without this, tmux attach sends \e[?1049h which puts xterm.js into
-// alternate screen mode. In that mode xterm.js has no scrollback and
-// converts mouse wheel to up/down arrow keys — the shell then cycles
-// through command history instead of scrolling.
+// tmuxSocket names kojo's own tmux server (tmux -L). Sessions there are
+// out of reach of the user's `tmux kill-server`, and the server starts
+// from tmuxConfig instead of ~/.tmux.conf.
+const tmuxSocket = "kojo"
+
+// tmuxConfigName is the file in tmuxRuntimeDir tmuxConfigPath writes
+// and the server reads when it starts.
+const tmuxConfigName = "tmux.conf"
+
+// tmuxRuntimeDir returns this user's directory for the tmux backend's
+// files, creating it: kojo-<uid> where tmux keeps the user's sockets,
+// $TMUX_TMPDIR or the temp directory. It must be a directory of the
+// user's own with mode 0700; in a shared one another user could plant
+// the configuration kojo's server runs.
+func tmuxRuntimeDir() (string, error) {
+	dir := filepath.Join(cmp.Or(os.Getenv("TMUX_TMPDIR"), os.TempDir()), "kojo-"+strconv.Itoa(os.Getuid()))
+	if err := os.Mkdir(dir, 0o700); err != nil && !errors.Is(err, fs.ErrExist) {
+		return "", fmt.Errorf("mkdir: %w", err)
+	}
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		return "", err
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !fi.IsDir() || !ok || int(st.Uid) != os.Getuid() || fi.Mode().Perm() != 0o700 {
+		return "", fmt.Errorf("%s is not a directory private to this user", dir)
+	}
+	return dir, nil
+}
+
+// tmuxConfig returns kojo's tmux server configuration. Every session
+// gets it: the tool sessions as they are, the terminal tab with the
+// overrides in tmuxTerminalOptions.
 //
-// This is idempotent: it checks whether the override already exists before
-// appending it. Safe to call before every attach — handles tmux server
-// restarts that would lose the previous setting.
-func tmuxEnsureServerConfig() {
-	out, err := exec.Command("tmux", "show-options", "-s", "terminal-overrides").Output()
+// terminal-overrides disables the alternate screen (smcup/rmcup) for
+// the outer terminal. Without it, tmux attach sends \e[?1049h which puts
+// xterm.js into alternate screen mode. In that mode xterm.js has no
+// scrollback and converts mouse wheel to up/down arrow keys — the shell
+// then cycles through command history instead of scrolling. It is set
+// by index so sourcing the file again doesn't append it twice.
+func tmuxConfig() string {
+	var b strings.Builder
+	b.WriteString("# Generated by kojo for its tmux server (tmux -L " + tmuxSocket + "); rewritten at every start.\n")
+	// A login shell for new windows and panes.
+	b.WriteString("set -g default-command " + tmuxQuote(tmuxLoginShellCmd()) + "\n")
+	b.WriteString("set -g default-terminal xterm-256color\n")
+	b.WriteString("set -s terminal-overrides[99] " + tmuxQuote("xterm-256color:smcup@:rmcup@") + "\n")
+	// Keep the pane after the tool exits, so its exit code can be read.
+	b.WriteString("set -g remain-on-exit on\n")
+	if tmuxAtLeast(tmuxRemainOnExitFormat) {
+		// Keep tmux's "Pane is dead" line out of the tool's last screen;
+		// kojo reports the exit itself. Older releases always write it,
+		// and refuse the unknown option.
+		b.WriteString("set -g remain-on-exit-format \"\"\n")
+	}
+	// Disable prefix keys so Ctrl+B passes through to the CLI tool, hide
+	// the status bar so it doesn't leak into the mobile UI, and disable
+	// mouse mode to avoid interference with xterm.js.
+	b.WriteString("set -g prefix None\nset -g prefix2 None\nset -g status off\nset -g mouse off\n")
+	return b.String()
+}
+
+// tmuxQuote double-quotes s for a tmux configuration file.
+func tmuxQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// TmuxLockPath returns the lock file held by the kojo instance using
+// the tmux backend, in tmuxRuntimeDir. Every config directory's kojo
+// shares this user's one tmux server and its kojo_ session names, and
+// each would take the others' sessions for orphans, so only one may use
+// it at a time; other users have servers, and locks, of their own.
+func TmuxLockPath() (string, error) {
+	dir, err := tmuxRuntimeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tmux.lock"), nil
+}
+
+// tmuxConfigWritten is the config file tmuxConfigPath last wrote.
+var tmuxConfigWritten struct {
+	sync.Mutex
+	path string
+}
+
+// tmuxConfigPath writes tmuxConfig to tmuxConfigName in
+// tmuxRuntimeDir, once per process unless the directory moves or the
+// file goes, and returns its path.
+func tmuxConfigPath() (string, error) {
+	dir, err := tmuxRuntimeDir()
 	if err != nil {
-		return // tmux server not running; will be set when a session is created
+		return "", err
+	}
+	path := filepath.Join(dir, tmuxConfigName)
+	tmuxConfigWritten.Lock()
+	defer tmuxConfigWritten.Unlock()
+	if tmuxConfigWritten.path == path {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	if err := atomicfile.WriteBytes(path, []byte(tmuxConfig()), 0o600); err != nil {
+		return "", fmt.Errorf("write tmux config: %w", err)
+	}
+	tmuxConfigWritten.path = path
+	return path, nil
+}
+
+// tmuxServerArgs are the arguments that put a tmux command on kojo's
+// server, started from kojo's configuration if the command starts it.
+func tmuxServerArgs() []string {
+	path, _ := tmuxConfigPath()
+	return tmuxServerArgsFor(path)
+}
+
+// tmuxServerArgsFor is tmuxServerArgs with the configuration at conf,
+// none when it is "".
+func tmuxServerArgsFor(conf string) []string {
+	args := []string{"-L", tmuxSocket}
+	if conf != "" {
+		args = append(args, "-f", conf)
+	}
+	return args
+}
+
+// tmuxCommand returns an exec.Cmd running tmux args on kojo's server.
+func tmuxCommand(args ...string) *exec.Cmd {
+	return exec.Command("tmux", append(tmuxServerArgs(), args...)...)
+}
+
+// tmuxLegacy holds the names of the restored sessions an older kojo
+// started on the user's default tmux server, before it had its own.
+// They stay there, and every command about them goes there, until a
+// restart starts them afresh on kojo's server.
+var tmuxLegacy = struct {
+	sync.Mutex
+	names map[string]bool
+}{names: map[string]bool{}}
+
+// setTmuxLegacy records whether the named session is on the default
+// tmux server.
+func setTmuxLegacy(name string, legacy bool) {
+	tmuxLegacy.Lock()
+	defer tmuxLegacy.Unlock()
+	if legacy {
+		tmuxLegacy.names[name] = true
+	} else {
+		delete(tmuxLegacy.names, name)
+	}
+}
+
+// isTmuxLegacy reports whether the named session is on the default
+// tmux server; see tmuxLegacy.
+func isTmuxLegacy(name string) bool {
+	tmuxLegacy.Lock()
+	defer tmuxLegacy.Unlock()
+	return tmuxLegacy.names[name]
+}
+
+// hasTmuxLegacy reports whether any session is on the default server.
+func hasTmuxLegacy() bool {
+	tmuxLegacy.Lock()
+	defer tmuxLegacy.Unlock()
+	return len(tmuxLegacy.names) > 0
+}
+
+// tmuxCommandFor is tmuxCommand for a command about the named session,
+// run on the default server instead when the session is there.
+func tmuxCommandFor(name string, args ...string) *exec.Cmd {
+	if isTmuxLegacy(name) {
+		return exec.Command("tmux", args...)
 	}
-	if strings.Contains(string(out), "smcup@:rmcup@") {
-		return // already set
+	return tmuxCommand(args...)
+}
+
+// tmuxEnsureServerConfig loads kojo's configuration into its tmux
+// server. A server started by an older kojo kept the configuration of
+// its time; one started since already has it, and sourcing it again
+// changes nothing. Safe to call before every attach.
+func tmuxEnsureServerConfig() {
+	path, err := tmuxConfigPath()
+	if err != nil {
+		return
 	}
-	_ = exec.Command("tmux", "set-option", "-s", "-a", "terminal-overrides", ",xterm-256color:smcup@:rmcup@").Run()
+	_ = tmuxCommand("source-file", path).Run()
 }
 
 // tmuxSessionName returns the tmux session name for a kojo session ID.
@@ -41,20 +214,33 @@ func tmuxSessionName(id string) string {
 	return tmuxPrefix + id
 }
 
-// shellQuote wraps a string in single quotes, escaping any embedded single quotes.
-// e.g. "it's" → "'it'\”s'"
-func shellQuote(s string) string {
-	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+// toolArgv returns the argv that runs toolPath with args. envVars
+// (KEY=value) are set by env right before it, so they apply after the
+// login shell's profile has run.
+func toolArgv(toolPath string, args, envVars []string) []string {
+	var argv []string
+	if len(envVars) > 0 {
+		argv = append([]string{"env", "--"}, envVars...)
+	}
+	return append(append(argv, toolPath), args...)
 }
 
-// buildShellCommand constructs a shell-safe command string from a tool path and arguments.
-func buildShellCommand(toolPath string, args []string) string {
-	parts := make([]string, 0, 1+len(args))
-	parts = append(parts, shellQuote(toolPath))
-	for _, a := range args {
-		parts = append(parts, shellQuote(a))
+// loginExecArgv returns the argv that runs argv inside an interactive
+// login shell (-lic), so PATH, SSH agent, credential helpers etc. match
+// the user's standard terminal environment. -i is required because
+// ~/.zshrc (where many users add PATH entries) is only sourced for
+// interactive shells. PATH is unset first so the login shell rebuilds
+// it from scratch.
+//
+// The shell gets a fixed script that execs its arguments, so argv
+// reaches the tool as it is, never through a command string.
+func loginExecArgv(shell string, argv []string) []string {
+	out := []string{"/usr/bin/env", "-u", "PATH", shell, "-lic"}
+	if filepath.Base(shell) == "fish" {
+		return append(append(out, "exec $argv"), argv...)
 	}
-	return strings.Join(parts, " ")
+	// "$0" is the shell, for its error messages.
+	return append(append(out, `exec "$@"`, shell), argv...)
 }
 
 // tmuxLoginShellCmd returns a shell command string that launches the user's
@@ -76,82 +262,91 @@ func tmuxLoginShellCmd() string {
 	return "unset PATH; exec " + shellQuote(loginShellPath()) + " -l"
 }
 
-// tmuxSetLoginShell configures the named tmux session to use a login shell
-// for new windows/panes.
-func tmuxSetLoginShell(name string) {
-	cmd := "unset PATH; exec " + shellQuote(loginShellPath()) + " -l"
-	_ = exec.Command("tmux", "set-option", "-t", name, "default-command", cmd).Run()
+// tmuxLoginShellArgv is tmuxLoginShellCmd as an argv, for the terminal
+// tab's new-session.
+func tmuxLoginShellArgv() []string {
+	return []string{"/usr/bin/env", "-u", "PATH", loginShellPath(), "-l"}
+}
+
+// tmuxTerminalOptions give the terminal tab, an interactive tmux, back
+// what kojo's configuration turns off for tools: the prefix key, the
+// status bar and the shell ending with its pane. Mouse mode lets it
+// receive mouse-wheel escape sequences from the web UI for per-pane
+// scrolling.
+var tmuxTerminalOptions = [][2]string{
+	{"prefix", "C-b"},
+	{"status", "on"},
+	{"remain-on-exit", "off"},
+	{"mouse", "on"},
+}
+
+// tmuxSetTerminalOptions applies tmuxTerminalOptions to the named
+// session.
+func tmuxSetTerminalOptions(name string) {
+	_ = tmuxCommandFor(name, tmuxSetOptionsArgs(name, tmuxTerminalOptions)...).Run()
 }
 
-// tmuxNewSession creates a detached tmux session with remain-on-exit enabled.
-// If disablePrefix is true, it also disables prefix keys, status bar, and mouse
-// to make tmux transparent for user-facing tools.
-func tmuxNewSession(name, workDir, shellCmd string, disablePrefix bool) error {
-	// Wrap in interactive login shell (-lic) so PATH, SSH agent, credential
-	// helpers etc. match the user's standard terminal environment.
-	// -i is required because ~/.zshrc (where many users add PATH entries)
-	// is only sourced for interactive shells.
-	// Unset PATH first so the login shell rebuilds it from scratch.
-	shell := loginShellPath()
-	wrappedCmd := "unset PATH; " + shellQuote(shell) + " -lic " + shellQuote(shellCmd)
+// tmuxNewSession creates a detached tmux session on kojo's server
+// running argv in the user's login shell (see loginExecArgv). kojo's
+// configuration makes tmux transparent for the user-facing tool; see
+// tmuxConfig.
+func tmuxNewSession(name, workDir string, argv []string) error {
+	if _, err := tmuxConfigPath(); err != nil {
+		return err
+	}
 
-	// Create a detached session running the shell command
-	args := []string{
+	// Given as several arguments, the command is run with execvp rather
+	// than through sh -c (tmux 3.0 and later).
+	args := append([]string{
 		"new-session", "-d",
 		"-s", name,
 		"-c", workDir,
 		"-x", "120", "-y", "36",
-		wrappedCmd,
-	}
-	if err := exec.Command("tmux", args...).Run(); err != nil {
+		"--",
+	}, loginExecArgv(loginShellPath(), argv)...)
+	if err := tmuxCommand(args...).Run(); err != nil {
 		return fmt.Errorf("tmux new-session: %w", err)
 	}
 
-	// Set remain-on-exit so the pane stays after the process exits
-	if err := exec.Command("tmux", "set-option", "-t", name, "remain-on-exit", "on").Run(); err != nil {
-		return fmt.Errorf("tmux set remain-on-exit: %w", err)
-	}
-
-	// Set TERM for the session
-	if err := exec.Command("tmux", "set-option", "-t", name, "default-terminal", "xterm-256color").Run(); err != nil {
-		return fmt.Errorf("tmux set default-terminal: %w", err)
-	}
-
-	if disablePrefix {
-		// Disable prefix keys so Ctrl+B passes through to the CLI tool
-		_ = exec.Command("tmux", "set-option", "-t", name, "prefix", "None").Run()
-		_ = exec.Command("tmux", "set-option", "-t", name, "prefix2", "None").Run()
-		// Hide status bar to prevent it from leaking into the mobile UI
-		_ = exec.Command("tmux", "set-option", "-t", name, "status", "off").Run()
-		// Disable mouse mode to avoid interference with xterm.js
-		_ = exec.Command("tmux", "set-option", "-t", name, "mouse", "off").Run()
-	}
-
-	// Ensure server-level config is applied (idempotent)
+	// A server left by an earlier kojo may predate the configuration.
 	tmuxEnsureServerConfig()
 
 	return nil
 }
 
+// tmuxSetOptionsArgs builds the arguments of one tmux command line
+// that sets each option on the named session; options go in one tmux
+// invocation, separated by ";" arguments.
+func tmuxSetOptionsArgs(name string, opts [][2]string) []string {
+	var args []string
+	for i, o := range opts {
+		if i > 0 {
+			args = append(args, ";")
+		}
+		args = append(args, "set-option", "-t", name, o[0], o[1])
+	}
+	return args
+}
+
 // tmuxAttachCommand returns an exec.Cmd that attaches to the named tmux session.
 func tmuxAttachCommand(name string) *exec.Cmd {
-	return exec.Command("tmux", "attach-session", "-t", name)
+	return tmuxCommandFor(name, "attach-session", "-t", name)
 }
 
 // tmuxKillSession kills the named tmux session.
 func tmuxKillSession(name string) error {
-	return exec.Command("tmux", "kill-session", "-t", name).Run()
+	return tmuxCommandFor(name, "kill-session", "-t", name).Run()
 }
 
 // tmuxHasSession returns true if the named tmux session exists.
 func tmuxHasSession(name string) bool {
-	return exec.Command("tmux", "has-session", "-t", name).Run() == nil
+	return tmuxCommandFor(name, "has-session", "-t", name).Run() == nil
 }
 
 // tmuxPaneDead checks whether the pane in the named tmux session is dead.
 // Returns dead=true and the exit code if the process has exited.
 func tmuxPaneDead(name string) (dead bool, exitCode int, err error) {
-	out, err := exec.Command("tmux", "display-message", "-t", name, "-p", "#{pane_dead}:#{pane_dead_status}").Output()
+	out, err := tmuxCommandFor(name, "display-message", "-t", name, "-p", "#{pane_dead}:#{pane_dead_status}").Output()
 	if err != nil {
 		return false, 0, fmt.Errorf("tmux display-message: %w", err)
 	}
@@ -169,12 +364,6 @@ func tmuxPaneDead(name string) (dead bool, exitCode int, err error) {
 	return true, code, nil
 }
 
-// tmuxEnableMouse enables mouse mode on the named tmux session so it receives
-// mouse-wheel escape sequences from the web UI for per-pane scrolling.
-func tmuxEnableMouse(name string) {
-	_ = exec.Command("tmux", "set-option", "-t", name, "mouse", "on").Run()
-}
-
 // tmuxActions is the whitelist of tmux actions that can be executed server-side.
 // Each entry maps an action name to a function that returns tmux CLI arguments.
 var tmuxActions = map[string]func(string) []string{
@@ -196,7 +385,7 @@ func tmuxRunAction(sessionName, action string) error {
 	if !ok {
 		return fmt.Errorf("unknown tmux action: %s", action)
 	}
-	out, err := exec.Command("tmux", fn(sessionName)...).CombinedOutput()
+	out, err := tmuxCommandFor(sessionName, fn(sessionName)...).CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("tmux %s: %w (%s)", action, err, strings.TrimSpace(string(out)))
 	}
@@ -204,8 +393,14 @@ func tmuxRunAction(sessionName, action string) error {
 }
 
 // tmuxResizePane resizes the window of the named tmux session.
+// resize-window pins window-size to manual on tmux 3.1 and later;
+// before that window-size is a session option, set here explicitly.
 func tmuxResizePane(name string, cols, rows uint16) error {
-	return exec.Command("tmux", "resize-window", "-t", name, "-x", strconv.Itoa(int(cols)), "-y", strconv.Itoa(int(rows))).Run()
+	args := []string{"resize-window", "-t", name, "-x", strconv.Itoa(int(cols)), "-y", strconv.Itoa(int(rows))}
+	if !tmuxAtLeast(tmuxWindowSizeWindowOption) {
+		args = append(tmuxSetOptionsArgs(name, [][2]string{{"window-size", "manual"}}), append([]string{";"}, args...)...)
+	}
+	return tmuxCommandFor(name, args...).Run()
 }
 
 // tmuxStartPipePane sets up pipe-pane to capture raw pane output via a named FIFO.
@@ -240,20 +435,24 @@ func tmuxStartPipePane(sessionName string) (*os.File, string, error) {
 		os.Remove(fifoPath)
 		return nil, "", fmt.Errorf("open fifo: %w", err)
 	}
-	// Clear O_NONBLOCK so reads block normally until data/EOF
-	if err := syscall.SetNonblock(fd, false); err != nil {
-		syscall.Close(fd)
-		os.Remove(fifoPath)
-		return nil, "", fmt.Errorf("set blocking: %w", err)
-	}
+	// Left non-blocking, so os.NewFile hands the fd to the runtime
+	// poller: Read still waits for data, and Close wakes a pending Read.
+	// With a blocking fd Close can't, and as our own O_RDWR fd keeps a
+	// writer open, no EOF would ever end readLoop either.
 	f := os.NewFile(uintptr(fd), fifoPath)
 
 	// Now start pipe-pane. The writer (cat) can open the FIFO immediately
 	// because our reader fd is already registered.
-	// -o = output only (data written by the program in the pane).
-	// exec cat avoids leaving an extra sh process.
-	if err := exec.Command("tmux", "pipe-pane", "-t", sessionName, "-o",
-		fmt.Sprintf("exec cat > %s", shellQuote(fifoPath))).Run(); err != nil {
+	// -O = output only (data written by the program in the pane),
+	// tmux's default since 2.7 but explicit here. A pipe left from a
+	// previous attach is replaced; -o would toggle it off instead and
+	// open none. exec cat avoids leaving an extra sh process.
+	//
+	// pipe-pane only takes a shell command. The FIFO path reaches it as
+	// the session's @kojo_pipe option, which tmux quotes itself with
+	// #{q:}, so the command is a constant.
+	if err := tmuxCommandFor(sessionName, "set-option", "-t", sessionName, "@kojo_pipe", fifoPath, ";",
+		"pipe-pane", "-t", sessionName, "-O", "exec cat > #{q:@kojo_pipe}").Run(); err != nil {
 		f.Close()
 		os.Remove(fifoPath)
 		return nil, "", fmt.Errorf("pipe-pane: %w", err)
@@ -266,7 +465,7 @@ func tmuxStartPipePane(sessionName string) (*os.File, string, error) {
 func tmuxCleanupPipePane(sessionName string, f *os.File, fifoPath string) {
 	if tmuxHasSession(sessionName) {
 		// Calling pipe-pane without a command stops the active pipe
-		_ = exec.Command("tmux", "pipe-pane", "-t", sessionName).Run()
+		_ = tmuxCommandFor(sessionName, "pipe-pane", "-t", sessionName).Run()
 	}
 	if f != nil {
 		f.Close()
@@ -279,16 +478,49 @@ func tmuxCleanupPipePane(sessionName string, f *os.File, fifoPath string) {
 // tmuxCapturePaneContent captures the current visible pane content (with ANSI escapes)
 // using tmux capture-pane. Returns nil on failure.
 func tmuxCapturePaneContent(name string) []byte {
-	out, err := exec.Command("tmux", "capture-pane", "-t", name, "-p", "-e").Output()
+	out, err := tmuxCommandFor(name, "capture-pane", "-t", name, "-p", "-e").Output()
 	if err != nil {
 		return nil
 	}
 	return out
 }
 
-// tmuxListKojoSessions returns names of all tmux sessions with the kojo_ prefix.
+// tmuxPaneForeground returns the process group in the foreground of the
+// named session's active pane: the tool itself, not the login shell
+// wrapping it, and any children it shares a group with. Falls back to
+// the pane's own pid when ps can't say.
+func tmuxPaneForeground(name string) (pgid int, isGroup bool, err error) {
+	out, err := tmuxCommandFor(name, "display-message", "-t", name, "-p", "#{pane_pid}").Output()
+	if err != nil {
+		return 0, false, fmt.Errorf("tmux display-message: %w", err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	if err != nil || pid <= 0 {
+		return 0, false, fmt.Errorf("unexpected tmux pane pid: %q", out)
+	}
+	out, err = exec.Command("ps", "-o", "tpgid=", "-p", strconv.Itoa(pid)).Output()
+	if err == nil {
+		if tpgid, err := strconv.Atoi(strings.TrimSpace(string(out))); err == nil && tpgid > 0 {
+			return tpgid, true, nil
+		}
+	}
+	return pid, false, nil
+}
+
+// tmuxListKojoSessions returns names of all tmux sessions with the
+// kojo_ prefix on kojo's server.
 func tmuxListKojoSessions() ([]string, error) {
-	out, err := exec.Command("tmux", "list-sessions", "-F", "#{session_name}").Output()
+	return listKojoSessions(tmuxCommand("list-sessions", "-F", "#{session_name}"))
+}
+
+// tmuxListLegacySessions returns names of kojo_ sessions on the user's
+// default tmux server, where kojo ran them before it had its own.
+func tmuxListLegacySessions() ([]string, error) {
+	return listKojoSessions(exec.Command("tmux", "list-sessions", "-F", "#{session_name}"))
+}
+
+func listKojoSessions(cmd *exec.Cmd) ([]string, error) {
+	out, err := cmd.Output()
 	if err != nil {
 		// tmux returns error if no server is running (no sessions)
 		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
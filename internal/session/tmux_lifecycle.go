@@ -4,9 +4,11 @@ package session
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -19,24 +21,43 @@ import (
 func (m *Manager) loadPersistedSessions() bool {
 	infos, err := m.store.Load()
 	if err != nil {
-		m.logger.Error("failed to load persisted sessions, skipping orphan cleanup", "err", err)
+		m.tmuxLog().Error("failed to load persisted sessions, skipping orphan cleanup", "err", err)
 		return false
 	}
 	m.insertRestoredSessions(infos)
 	return true
 }
 
+// tmuxLog is the logger for the tmux backend.
+func (m *Manager) tmuxLog() *slog.Logger {
+	if m.tmuxLogger != nil {
+		return m.tmuxLogger
+	}
+	return m.logger
+}
+
 // restoreSession creates a Session from persisted info, reattaching to a live tmux session if possible.
 func (m *Manager) restoreSession(info SessionInfo) *Session {
 	s := newRestoredSession(info)
 	s.logger = m.logger
 
+	// An older kojo left its sessions on the default tmux server. Keep
+	// talking to them there; the cached pane listing predates the mark.
+	legacy := info.TmuxSessionName != "" && info.TmuxServer == ""
+	if legacy {
+		setTmuxLegacy(info.TmuxSessionName, true)
+		tmuxPanes.refresh()
+	}
+
 	restored := false
-	if info.TmuxSessionName != "" && tmuxHasSession(info.TmuxSessionName) {
+	if info.TmuxSessionName != "" && tmuxPanes.hasSession(info.TmuxSessionName) {
 		restored = m.tryReattachPersistedTmux(s, info)
 	}
 
 	if !restored {
+		if legacy {
+			setTmuxLegacy(info.TmuxSessionName, false)
+		}
 		close(s.done)
 	}
 	return s
@@ -44,23 +65,31 @@ func (m *Manager) restoreSession(info SessionInfo) *Session {
 
 // tryReattachPersistedTmux attempts to reattach to a persisted tmux session.
 func (m *Manager) tryReattachPersistedTmux(s *Session, info SessionInfo) bool {
-	dead, exitCode, err := tmuxPaneDead(info.TmuxSessionName)
+	// Restored sessions share one cached list-panes.
+	pane, ok, err := tmuxPanes.status(info.TmuxSessionName)
+	if err == nil && !ok {
+		err = fmt.Errorf("no pane for %s", info.TmuxSessionName)
+	}
 	if err != nil {
-		m.logger.Warn("failed to check tmux pane state, killing session", "id", info.ID, "tmux", info.TmuxSessionName, "err", err)
+		m.tmuxLog().Warn("failed to check tmux pane state, killing session", "id", info.ID, "tmux", info.TmuxSessionName, "err", err)
 		_ = tmuxKillSession(info.TmuxSessionName)
 		return false
 	}
-	if dead {
+	if pane.dead {
+		exitCode := pane.exitCode
 		s.ExitCode = &exitCode
 		_ = tmuxKillSession(info.TmuxSessionName)
 		return false
 	}
 
-	tmuxEnsureServerConfig()
+	legacy := isTmuxLegacy(info.TmuxSessionName)
+	if !legacy {
+		tmuxEnsureServerConfig()
+	}
 
 	rawPipe, rawPipePath, pipeErr := tmuxStartPipePane(info.TmuxSessionName)
 	if pipeErr != nil {
-		m.logger.Warn("pipe-pane setup failed on restore", "id", info.ID, "err", pipeErr)
+		m.tmuxLog().Warn("pipe-pane setup failed on restore", "id", info.ID, "err", pipeErr)
 	}
 
 	cmd := tmuxAttachCommand(info.TmuxSessionName)
@@ -69,7 +98,7 @@ func (m *Manager) tryReattachPersistedTmux(s *Session, info SessionInfo) bool {
 	ptmx, err := pty.StartWithSize(cmd, &ws)
 	if err != nil {
 		tmuxCleanupPipePane(info.TmuxSessionName, rawPipe, rawPipePath)
-		m.logger.Error("failed to reattach persisted tmux session", "id", info.ID, "err", err)
+		m.tmuxLog().Error("failed to reattach persisted tmux session", "id", info.ID, "err", err)
 		_ = tmuxKillSession(info.TmuxSessionName)
 		return false
 	}
@@ -81,29 +110,79 @@ func (m *Manager) tryReattachPersistedTmux(s *Session, info SessionInfo) bool {
 	s.Status = StatusRunning
 	s.ExitCode = nil
 	s.lastOutput = nil
-	s.readDone = make(chan struct{})
+	s.outputDirty = true
 
 	if rawPipe != nil {
 		if content := tmuxCapturePaneContent(info.TmuxSessionName); len(content) > 0 {
-			s.scrollback.Write(content)
+			s.scrollback.Write(s.redactOutput(m.redactRes, content))
 		}
 	}
 
-	go m.readLoop(s)
+	m.startLoop(s, loopRead, m.readLoop)
 	if rawPipe != nil {
-		go m.drainLoop(s)
+		m.startLoop(s, loopDrain, m.drainLoop)
 	}
-	go m.tmuxWaitLoop(s)
+	m.startLoop(s, loopWait, m.tmuxWaitLoop)
+	m.startLimitLoop(s)
+	m.startCheckpointLoop(s)
 
-	m.logger.Info("reattached to persisted tmux session", "id", info.ID, "tmux", info.TmuxSessionName)
+	m.tmuxLog().Info("reattached to persisted tmux session", "id", info.ID, "tmux", info.TmuxSessionName, "defaultServer", legacy)
 	return true
 }
 
+// legacyTmuxPollInterval is how often watchLegacyTmuxSessions looks at
+// the default tmux server again.
+const legacyTmuxPollInterval = time.Minute
+
+// watchLegacyTmuxSessions logs the kojo_ sessions an older kojo left on
+// the user's default tmux server that no persisted session reattached
+// to, then keeps probing that server and logs each one as it exits,
+// until none is left or the manager shuts down. kojo's own server can't
+// take them over, but the tools in them may still be working, so they
+// are left for the user to attach to or kill.
+func (m *Manager) watchLegacyTmuxSessions() {
+	names, err := untrackedLegacySessions()
+	if err != nil || len(names) == 0 {
+		return
+	}
+	m.tmuxLog().Warn("sessions from an older kojo are still on the default tmux server; attach with `tmux attach -t <name>` or end them with `tmux kill-session -t <name>`",
+		"sessions", names)
+	t := time.NewTicker(legacyTmuxPollInterval)
+	defer t.Stop()
+	for range t.C {
+		m.mu.Lock()
+		done := m.shuttingDown
+		m.mu.Unlock()
+		if done {
+			return
+		}
+		now, err := untrackedLegacySessions()
+		if err != nil {
+			continue
+		}
+		for _, name := range names {
+			if !slices.Contains(now, name) {
+				m.tmuxLog().Info("session from an older kojo on the default tmux server exited", "tmux", name)
+			}
+		}
+		if names = now; len(names) == 0 {
+			return
+		}
+	}
+}
+
+// untrackedLegacySessions is tmuxListLegacySessions without the ones
+// restored sessions are attached to.
+func untrackedLegacySessions() ([]string, error) {
+	names, err := tmuxListLegacySessions()
+	return slices.DeleteFunc(names, isTmuxLegacy), err
+}
+
 // cleanupOrphanedTmuxSessions kills kojo_ tmux sessions that are not tracked.
 func (m *Manager) cleanupOrphanedTmuxSessions() {
 	sessions, err := tmuxListKojoSessions()
 	if err != nil {
-		m.logger.Debug("failed to list tmux sessions for cleanup", "err", err)
+		m.tmuxLog().Debug("failed to list tmux sessions for cleanup", "err", err)
 		return
 	}
 
@@ -120,7 +199,7 @@ func (m *Manager) cleanupOrphanedTmuxSessions() {
 
 	for _, name := range sessions {
 		if !known[name] {
-			m.logger.Info("killing orphaned tmux session", "name", name)
+			m.tmuxLog().Info("killing orphaned tmux session", "name", name)
 			_ = tmuxKillSession(name)
 		}
 	}
@@ -156,20 +235,20 @@ func (m *Manager) drainLoop(s *Session) {
 	}
 }
 
-// tmuxWaitLoop monitors a tmux-backed session by polling pane status
-// and watching the attach process.
+// tmuxWaitLoop monitors a tmux-backed session through the shared pane
+// poller and watches the attach process.
 func (m *Manager) tmuxWaitLoop(s *Session) {
 	attachExited := m.startAttachReaper(s)
 
-	ticker := time.NewTicker(paneStatusPollInterval)
-	defer ticker.Stop()
+	panes := tmuxPanes.subscribe()
+	defer tmuxPanes.unsubscribe(panes)
 
 	consecutiveErrors := 0
 
 	for {
 		select {
-		case <-ticker.C:
-			action := m.handlePanePoll(s, &consecutiveErrors, attachExited)
+		case snap := <-panes:
+			action := m.handlePanePoll(s, snap, &consecutiveErrors, attachExited)
 			switch action {
 			case pollDone:
 				return
@@ -196,8 +275,9 @@ const (
 	pollRetry
 )
 
-// handlePanePoll checks tmux pane status on each tick.
-func (m *Manager) handlePanePoll(s *Session, consecutiveErrors *int, attachExited <-chan struct{}) pollAction {
+// handlePanePoll acts on the session's pane status in each polled
+// snapshot.
+func (m *Manager) handlePanePoll(s *Session, snap paneSnapshot, consecutiveErrors *int, attachExited <-chan struct{}) pollAction {
 	m.mu.Lock()
 	shuttingDown := m.shuttingDown
 	m.mu.Unlock()
@@ -209,16 +289,10 @@ func (m *Manager) handlePanePoll(s *Session, consecutiveErrors *int, attachExite
 	tmuxName := s.TmuxSessionName
 	s.mu.Unlock()
 
-	if !tmuxHasSession(tmuxName) {
-		m.finalizeTmuxSession(s, 1, attachExited)
-		return pollDone
-	}
-
-	dead, exitCode, err := tmuxPaneDead(tmuxName)
-	if err != nil {
+	if snap.err != nil {
 		*consecutiveErrors++
 		if *consecutiveErrors >= maxPaneCheckErrors {
-			m.logger.Error("tmux pane check failed repeatedly, finalizing session", "id", s.ID, "err", err)
+			m.tmuxLog().Error("tmux pane check failed repeatedly, finalizing session", "id", s.ID, "err", snap.err)
 			_ = tmuxKillSession(tmuxName)
 			m.finalizeTmuxSession(s, 1, attachExited)
 			return pollDone
@@ -226,21 +300,25 @@ func (m *Manager) handlePanePoll(s *Session, consecutiveErrors *int, attachExite
 		return pollRetry
 	}
 	*consecutiveErrors = 0
-	if dead {
+	pane, ok := snap.panes[tmuxName]
+	if !ok {
+		m.finalizeTmuxSession(s, 1, attachExited)
+		return pollDone
+	}
+	if pane.dead {
 		_ = tmuxKillSession(tmuxName)
-		m.finalizeTmuxSession(s, exitCode, attachExited)
+		m.finalizeTmuxSession(s, pane.exitCode, attachExited)
 		return pollDone
 	}
 
 	// Check if readLoop exited unexpectedly (FIFO failure).
 	s.mu.Lock()
-	readDone := s.readDone
 	hasRawPipe := s.rawPipe != nil
 	s.mu.Unlock()
 	if hasRawPipe {
 		select {
-		case <-readDone:
-			m.logger.Warn("pipe-pane FIFO lost, forcing reattach", "id", s.ID)
+		case <-s.loops.done(loopRead):
+			m.tmuxLog().Warn("pipe-pane FIFO lost, forcing reattach", "id", s.ID)
 			s.mu.Lock()
 			s.cleanupPipePane()
 			cmd := s.Cmd
@@ -256,7 +334,7 @@ func (m *Manager) handlePanePoll(s *Session, consecutiveErrors *int, attachExite
 }
 
 // handleAttachExit handles the case when the tmux attach process exits.
-func (m *Manager) handleAttachExit(s *Session) (chan struct{}, bool) {
+func (m *Manager) handleAttachExit(s *Session) (<-chan struct{}, bool) {
 	m.mu.Lock()
 	shuttingDown := m.shuttingDown
 	m.mu.Unlock()
@@ -274,7 +352,7 @@ func (m *Manager) handleAttachExit(s *Session) (chan struct{}, bool) {
 		m.awaitReadDone(s)
 	} else {
 		select {
-		case <-s.readDone:
+		case <-s.loops.done(loopRead):
 			s.mu.Lock()
 			s.cleanupPipePane()
 			s.mu.Unlock()
@@ -296,7 +374,7 @@ func (m *Manager) handleAttachExit(s *Session) (chan struct{}, bool) {
 	}
 
 	if err := m.reattachTmux(s); err != nil {
-		m.logger.Error("failed to reattach tmux", "id", s.ID, "err", err)
+		m.tmuxLog().Error("failed to reattach tmux", "id", s.ID, "err", err)
 		m.cleanupPipeAndExit(s, hasRawPipe, 1)
 		return nil, true
 	}
@@ -315,19 +393,20 @@ func (m *Manager) cleanupPipeAndExit(s *Session, hasRawPipe bool, exitCode int)
 	m.completeExit(s, exitCode)
 }
 
-// startAttachReaper starts a goroutine that waits for the attach process to exit.
-func (m *Manager) startAttachReaper(s *Session) chan struct{} {
-	ch := make(chan struct{})
-	go func() {
-		s.mu.Lock()
-		cmd := s.Cmd
-		s.mu.Unlock()
-		if cmd != nil {
-			_ = cmd.Wait()
-		}
-		close(ch)
-	}()
-	return ch
+// startAttachReaper starts the loop that waits for the attach process
+// to exit and returns the channel closed when it has.
+func (m *Manager) startAttachReaper(s *Session) <-chan struct{} {
+	m.startLoop(s, loopReaper, m.reapAttach)
+	return s.loops.done(loopReaper)
+}
+
+func (m *Manager) reapAttach(s *Session) {
+	s.mu.Lock()
+	cmd := s.Cmd
+	s.mu.Unlock()
+	if cmd != nil {
+		_ = cmd.Wait()
+	}
 }
 
 // finalizeTmuxSession handles the case where the tmux pane is dead or gone.
@@ -341,7 +420,7 @@ func (m *Manager) finalizeTmuxSession(s *Session, exitCode int, attachExited <-c
 	select {
 	case <-attachExited:
 	case <-time.After(exitKillTimeout):
-		m.logger.Warn("attach process did not exit in time after kill", "id", s.ID)
+		m.tmuxLog().Warn("attach process did not exit in time after kill", "id", s.ID)
 	}
 
 	s.mu.Lock()
@@ -363,16 +442,7 @@ type tmuxAttachResult struct {
 
 // startTmuxAttach creates a tmux session, sets up pipe-pane, and attaches via PTY.
 func (m *Manager) startTmuxAttach(tmuxName, workDir, toolPath string, args []string, cols, rows uint16, envVars []string) (*tmuxAttachResult, error) {
-	shellCmd := buildShellCommand(toolPath, args)
-	// Prepend environment variable exports to the shell command.
-	if len(envVars) > 0 {
-		var exports string
-		for _, ev := range envVars {
-			exports += "export " + shellQuote(ev) + "; "
-		}
-		shellCmd = exports + shellCmd
-	}
-	if err := tmuxNewSession(tmuxName, workDir, shellCmd, true); err != nil {
+	if err := tmuxNewSession(tmuxName, workDir, toolArgv(toolPath, args, envVars)); err != nil {
 		return nil, fmt.Errorf("failed to create tmux session: %w", err)
 	}
 
@@ -380,7 +450,7 @@ func (m *Manager) startTmuxAttach(tmuxName, workDir, toolPath string, args []str
 	var rawPipePath string
 	rp, rpPath, pipeErr := tmuxStartPipePane(tmuxName)
 	if pipeErr != nil {
-		m.logger.Warn("pipe-pane setup failed", "tmux", tmuxName, "err", pipeErr)
+		m.tmuxLog().Warn("pipe-pane setup failed", "tmux", tmuxName, "err", pipeErr)
 	} else {
 		rawPipe = rp
 		rawPipePath = rpPath
@@ -404,12 +474,11 @@ func (m *Manager) reattachTmux(s *Session) error {
 	s.mu.Lock()
 	tmuxName := s.TmuxSessionName
 	pipeAlreadyActive := s.rawPipe != nil
-	readDone := s.readDone
 	s.mu.Unlock()
 
 	if pipeAlreadyActive {
 		select {
-		case <-readDone:
+		case <-s.loops.done(loopRead):
 			s.mu.Lock()
 			s.cleanupPipePane()
 			s.mu.Unlock()
@@ -425,7 +494,7 @@ func (m *Manager) reattachTmux(s *Session) error {
 	if !pipeAlreadyActive {
 		rp, rpPath, pipeErr := tmuxStartPipePane(tmuxName)
 		if pipeErr != nil {
-			m.logger.Warn("pipe-pane setup failed on reattach", "id", s.ID, "err", pipeErr)
+			m.tmuxLog().Warn("pipe-pane setup failed on reattach", "id", s.ID, "err", pipeErr)
 		} else {
 			rawPipe = rp
 			rawPipePath = rpPath
@@ -451,20 +520,24 @@ func (m *Manager) reattachTmux(s *Session) error {
 	if rawPipe != nil {
 		s.rawPipe = rawPipe
 		s.rawPipePath = rawPipePath
-		s.readDone = make(chan struct{})
 	}
 	s.mu.Unlock()
 
 	if rawPipe != nil {
-		go m.readLoop(s)
+		m.startLoop(s, loopRead, m.readLoop)
 	}
 	s.mu.Lock()
 	hasPipe := s.rawPipe != nil
 	s.mu.Unlock()
 	if hasPipe {
-		go m.drainLoop(s)
+		// The previous drainLoop read the attach PTY closed above;
+		// let it notice before starting the one for the new PTY.
+		if !s.loops.wait(loopDrain, exitDrainTimeout) {
+			m.tmuxLog().Warn("drainLoop did not exit in time", "id", s.ID)
+		}
+		m.startLoop(s, loopDrain, m.drainLoop)
 	}
 
-	m.logger.Info("reattached to tmux session", "id", s.ID, "tmux", tmuxName)
+	m.tmuxLog().Info("reattached to tmux session", "id", s.ID, "tmux", tmuxName)
 	return nil
 }
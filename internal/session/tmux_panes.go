@@ -0,0 +1,211 @@
+//go:build !windows
+
+package session
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Pane status for every tmux-backed session comes from one
+// `tmux list-panes -a` per paneStatusPollInterval, shared by all the
+// tmuxWaitLoops, instead of a has-session and a display-message per
+// session per tick.
+
+// paneCacheTTL is how long a snapshot answers status and hasSession
+// lookups before they run list-panes again.
+const paneCacheTTL = time.Second
+
+// paneStatus is the state of a tmux session's active pane.
+type paneStatus struct {
+	dead     bool
+	exitCode int
+}
+
+// paneSnapshot is every tmux session's pane status at one moment; a
+// session missing from panes doesn't exist. at is when list-panes was
+// started, so anything created after it may be missing.
+type paneSnapshot struct {
+	at    time.Time
+	panes map[string]paneStatus
+	err   error
+}
+
+// tmuxListPanesFormat is the list-panes -F format parseListPanes reads.
+const tmuxListPanesFormat = "#{session_name}\t#{window_active}#{pane_active}\t#{pane_dead}\t#{pane_dead_status}"
+
+// tmuxListPanes runs list-panes across the server and returns the
+// active pane of each session, adding those of the legacy sessions on
+// the default server (see tmuxLegacy). No server running means no
+// sessions.
+func tmuxListPanes() (map[string]paneStatus, error) {
+	panes, err := listPanes(tmuxCommand("list-panes", "-a", "-F", tmuxListPanesFormat))
+	if err != nil || !hasTmuxLegacy() {
+		return panes, err
+	}
+	legacy, err := listPanes(exec.Command("tmux", "list-panes", "-a", "-F", tmuxListPanesFormat))
+	if err != nil {
+		return nil, err
+	}
+	for name, st := range legacy {
+		if isTmuxLegacy(name) {
+			panes[name] = st
+		}
+	}
+	return panes, nil
+}
+
+func listPanes(cmd *exec.Cmd) (map[string]paneStatus, error) {
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return map[string]paneStatus{}, nil
+		}
+		return nil, fmt.Errorf("tmux list-panes: %w", err)
+	}
+	return parseListPanes(string(out))
+}
+
+func parseListPanes(out string) (map[string]paneStatus, error) {
+	panes := make(map[string]paneStatus)
+	active := make(map[string]bool)
+	// Only newlines are trimmed: a live pane's line ends in a tab
+	// before its empty exit status.
+	for _, line := range strings.Split(strings.TrimRight(out, "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		f := strings.Split(line, "\t")
+		if len(f) != 4 {
+			return nil, fmt.Errorf("unexpected tmux list-panes output: %q", line)
+		}
+		name, isActive := f[0], f[1] == "11"
+		if _, seen := panes[name]; seen && (active[name] || !isActive) {
+			continue
+		}
+		st := paneStatus{dead: f[2] == "1"}
+		if st.dead {
+			code, err := strconv.Atoi(f[3])
+			if err != nil {
+				code = 1 // dead but can't parse exit code
+			}
+			st.exitCode = code
+		}
+		panes[name] = st
+		active[name] = isActive
+	}
+	return panes, nil
+}
+
+// panePoller runs list-panes for everything that needs pane status:
+// on a ticker while tmuxWaitLoops are subscribed, and on demand (with
+// caching) for one-off lookups.
+type panePoller struct {
+	mu      sync.Mutex
+	subs    map[chan paneSnapshot]time.Time // subscribed since
+	running bool
+	last    paneSnapshot
+	// list is tmuxListPanes; tests replace it.
+	list func() (map[string]paneStatus, error)
+}
+
+var tmuxPanes = &panePoller{list: tmuxListPanes}
+
+// subscribe returns a channel that receives each polled snapshot taken
+// after the call, starting the poller if it isn't running. A slow
+// receiver only ever sees the latest snapshot.
+func (p *panePoller) subscribe() chan paneSnapshot {
+	ch := make(chan paneSnapshot, 1)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.subs == nil {
+		p.subs = make(map[chan paneSnapshot]time.Time)
+	}
+	p.subs[ch] = time.Now()
+	if !p.running {
+		p.running = true
+		go p.run()
+	}
+	return ch
+}
+
+func (p *panePoller) unsubscribe(ch chan paneSnapshot) {
+	p.mu.Lock()
+	delete(p.subs, ch)
+	p.mu.Unlock()
+}
+
+// run polls until nothing is subscribed.
+func (p *panePoller) run() {
+	t := time.NewTicker(paneStatusPollInterval)
+	defer t.Stop()
+	for range t.C {
+		p.mu.Lock()
+		if len(p.subs) == 0 {
+			p.running = false
+			p.mu.Unlock()
+			return
+		}
+		p.mu.Unlock()
+
+		snap := p.refresh()
+
+		p.mu.Lock()
+		for ch, since := range p.subs {
+			if snap.at.Before(since) {
+				continue // the session may postdate the listing
+			}
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- snap
+		}
+		p.mu.Unlock()
+	}
+}
+
+// refresh runs list-panes and records the result as the latest snapshot.
+func (p *panePoller) refresh() paneSnapshot {
+	snap := paneSnapshot{at: time.Now()}
+	snap.panes, snap.err = p.list()
+	p.mu.Lock()
+	if snap.at.After(p.last.at) {
+		p.last = snap
+	}
+	p.mu.Unlock()
+	return snap
+}
+
+// status returns the pane status of the named session from a snapshot
+// at most paneCacheTTL old, listing again if there is none.
+func (p *panePoller) status(name string) (st paneStatus, ok bool, err error) {
+	p.mu.Lock()
+	snap := p.last
+	p.mu.Unlock()
+	if snap.err != nil || snap.panes == nil || time.Since(snap.at) >= paneCacheTTL {
+		snap = p.refresh()
+	}
+	if snap.err != nil {
+		return paneStatus{}, false, snap.err
+	}
+	st, ok = snap.panes[name]
+	return st, ok, nil
+}
+
+// hasSession is tmuxHasSession answered from the cached snapshot. Only
+// for sessions created well before the call: a session younger than
+// paneCacheTTL can be missing from it.
+func (p *panePoller) hasSession(name string) bool {
+	_, ok, err := p.status(name)
+	if err != nil {
+		return tmuxHasSession(name)
+	}
+	return ok
+}
@@ -0,0 +1,74 @@
+//go:build !windows
+
+package session
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseListPanes(t *testing.T) {
+	out := "kojo_a\t11\t0\t\n" +
+		"kojo_b\t10\t0\t\n" +
+		"kojo_b\t11\t1\t3\n" +
+		"kojo_b\t01\t0\t\n" +
+		"kojo_c\t11\t1\t?\n"
+	panes, err := parseListPanes(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]paneStatus{
+		"kojo_a": {},
+		"kojo_b": {dead: true, exitCode: 3},
+		"kojo_c": {dead: true, exitCode: 1},
+	}
+	if len(panes) != len(want) {
+		t.Fatalf("panes = %v", panes)
+	}
+	for name, st := range want {
+		if panes[name] != st {
+			t.Errorf("%s = %+v, want %+v", name, panes[name], st)
+		}
+	}
+	// A live pane last in the listing keeps its trailing empty field.
+	if panes, err := parseListPanes("kojo_a\t11\t0\t\n"); err != nil || panes["kojo_a"] != (paneStatus{}) {
+		t.Errorf("single live pane = %v, %v", panes, err)
+	}
+	if _, err := parseListPanes("garbage\n"); err == nil {
+		t.Error("malformed line accepted")
+	}
+}
+
+func TestPanePollerSharesListing(t *testing.T) {
+	var calls atomic.Int32
+	p := &panePoller{list: func() (map[string]paneStatus, error) {
+		calls.Add(1)
+		return map[string]paneStatus{"kojo_a": {}}, nil
+	}}
+	a, b := p.subscribe(), p.subscribe()
+	for _, ch := range []chan paneSnapshot{a, b} {
+		select {
+		case snap := <-ch:
+			if _, ok := snap.panes["kojo_a"]; !ok {
+				t.Fatalf("snapshot = %+v", snap)
+			}
+		case <-time.After(5 * paneStatusPollInterval):
+			t.Fatal("no snapshot delivered")
+		}
+	}
+	if n := calls.Load(); n > 2 {
+		t.Errorf("%d listings for two subscribers' first snapshot", n)
+	}
+	p.unsubscribe(a)
+	p.unsubscribe(b)
+
+	// A fresh snapshot answers lookups without listing again.
+	before := calls.Load()
+	if !p.hasSession("kojo_a") || p.hasSession("kojo_b") {
+		t.Fatal("hasSession disagrees with the listing")
+	}
+	if calls.Load() > before+1 {
+		t.Errorf("hasSession listed %d times", calls.Load()-before)
+	}
+}
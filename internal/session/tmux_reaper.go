@@ -0,0 +1,244 @@
+//go:build !windows
+
+package session
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// When kojo crashes, the helpers it leaves with tmux can outlive it:
+// pipe-pane writers (`exec cat > <fifo>`) stuck opening a FIFO nobody
+// reads, and tmux attach clients whose PTY nobody drains. The reaper
+// looks for them at startup and every orphanReapInterval and ends the
+// ones no live session owns.
+
+// orphanReapInterval is how often reapOrphanLoop looks for orphans.
+const orphanReapInterval = 5 * time.Minute
+
+// pipeWriterMarker precedes the FIFO path in a pipe-pane writer's
+// command line until it has opened the FIFO and exec'd cat.
+const pipeWriterMarker = "exec cat > "
+
+// panePipe is a pane with pipe-pane active and the FIFO kojo gave it.
+type panePipe struct {
+	session, fifo string
+}
+
+// helperProc is a process as ps lists it.
+type helperProc struct {
+	pid  int
+	args string
+}
+
+// tmuxClient is a client attached to kojo's tmux server.
+type tmuxClient struct {
+	pid int
+	tty string
+}
+
+// reapOrphanLoop runs reapOrphans every orphanReapInterval until the
+// manager shuts down. Something orphaned is only reaped when the
+// previous pass saw it too, so sessions still starting — with a pipe
+// but not yet in m.sessions — are left alone.
+func (m *Manager) reapOrphanLoop() {
+	t := time.NewTicker(orphanReapInterval)
+	defer t.Stop()
+	suspects := map[string]bool{}
+	for range t.C {
+		m.mu.Lock()
+		done := m.shuttingDown
+		m.mu.Unlock()
+		if done {
+			return
+		}
+		suspects = m.reapOrphans(suspects)
+	}
+}
+
+// reapOrphans ends the kojo helpers no live session owns: active pipes
+// of panes whose FIFO no session reads, pipe-pane writers still waiting
+// on such a FIFO, and attach clients kojo started that no session holds.
+// With prev nil everything orphaned is reaped at once; otherwise only
+// what prev holds, and the rest is returned to be checked next time.
+func (m *Manager) reapOrphans(prev map[string]bool) map[string]bool {
+	fifos, attachPIDs := m.liveHelpers()
+	next := map[string]bool{}
+	due := func(key string) bool {
+		if prev == nil || prev[key] {
+			return true
+		}
+		next[key] = true
+		return false
+	}
+
+	pipes, err := tmuxListPipes()
+	if err != nil {
+		m.tmuxLog().Debug("failed to list tmux pipes for reaping", "err", err)
+	}
+	for _, p := range pipes {
+		if fifos[p.fifo] || !due("pipe:"+p.session+"\t"+p.fifo) {
+			continue
+		}
+		m.tmuxLog().Info("closing orphaned pipe-pane", "tmux", p.session, "fifo", p.fifo)
+		_ = tmuxCommand("pipe-pane", "-t", p.session).Run()
+	}
+
+	procs, err := listHelperProcs()
+	if err != nil {
+		m.tmuxLog().Debug("failed to list processes for reaping", "err", err)
+		return next
+	}
+	fifoDir := filepath.Join(os.TempDir(), "kojo")
+	for _, p := range procs {
+		fifo, ok := pipeWriterFIFO(p.args, fifoDir)
+		if !ok || fifos[fifo] || !due("proc:"+strconv.Itoa(p.pid)+"\t"+p.args) {
+			continue
+		}
+		m.tmuxLog().Info("killing orphaned pipe-pane writer", "pid", p.pid, "fifo", fifo)
+		_ = syscall.Kill(p.pid, syscall.SIGTERM)
+	}
+
+	clients, err := tmuxListClients()
+	if err != nil {
+		m.tmuxLog().Debug("failed to list tmux clients for reaping", "err", err)
+	}
+	cmdlines := make(map[int]string, len(procs))
+	for _, p := range procs {
+		cmdlines[p.pid] = p.args
+	}
+	conf, _ := tmuxConfigPath()
+	for _, c := range clients {
+		// Only one kojo uses the server (TmuxLockPath), so a client
+		// kojo started that none of its sessions holds lost its kojo,
+		// whoever reparented it. A user's own attach isn't kojo's.
+		if attachPIDs[c.pid] || !kojoClient(cmdlines[c.pid], conf) || c.tty == "" ||
+			!due("client:"+strconv.Itoa(c.pid)+"\t"+c.tty) {
+			continue
+		}
+		m.tmuxLog().Info("detaching orphaned tmux client", "pid", c.pid, "tty", c.tty)
+		_ = tmuxCommand("detach-client", "-t", c.tty).Run()
+	}
+	return next
+}
+
+// liveHelpers returns the FIFOs running sessions read and the PIDs of
+// their attach processes.
+func (m *Manager) liveHelpers() (fifos map[string]bool, attachPIDs map[int]bool) {
+	fifos, attachPIDs = map[string]bool{}, map[int]bool{}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range m.sessions {
+		s.mu.Lock()
+		if s.rawPipePath != "" {
+			fifos[s.rawPipePath] = true
+		}
+		if s.Cmd != nil && s.Cmd.Process != nil {
+			attachPIDs[s.Cmd.Process.Pid] = true
+		}
+		s.mu.Unlock()
+	}
+	return fifos, attachPIDs
+}
+
+// tmuxListPipes returns the panes on kojo's server with pipe-pane
+// active. No server running means none.
+func tmuxListPipes() ([]panePipe, error) {
+	out, err := tmuxCommand("list-panes", "-a", "-F", "#{session_name}\t#{pane_pipe}\t#{@kojo_pipe}").Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("tmux list-panes: %w", err)
+	}
+	return parseListPipes(string(out)), nil
+}
+
+// parseListPipes parses tmuxListPipes' list-panes output, keeping kojo
+// sessions' panes with a pipe.
+func parseListPipes(out string) []panePipe {
+	var pipes []panePipe
+	for _, line := range strings.Split(strings.TrimRight(out, "\n"), "\n") {
+		f := strings.SplitN(line, "\t", 3)
+		if len(f) != 3 || f[1] != "1" || !strings.HasPrefix(f[0], tmuxPrefix) {
+			continue
+		}
+		pipes = append(pipes, panePipe{session: f[0], fifo: f[2]})
+	}
+	return pipes
+}
+
+// tmuxListClients returns the clients attached to kojo's server.
+func tmuxListClients() ([]tmuxClient, error) {
+	out, err := tmuxCommand("list-clients", "-F", "#{client_pid}\t#{client_tty}").Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("tmux list-clients: %w", err)
+	}
+	var clients []tmuxClient
+	for _, line := range strings.Split(strings.TrimRight(string(out), "\n"), "\n") {
+		pid, tty, ok := strings.Cut(line, "\t")
+		n, err := strconv.Atoi(pid)
+		if !ok || err != nil {
+			continue
+		}
+		clients = append(clients, tmuxClient{pid: n, tty: tty})
+	}
+	return clients, nil
+}
+
+// kojoClient reports whether a tmux client's command line is one kojo
+// runs: on kojo's server and started from kojo's configuration conf,
+// which a user attaching by hand doesn't name.
+func kojoClient(args, conf string) bool {
+	return conf != "" && strings.Contains(args+" ", " "+strings.Join(tmuxServerArgsFor(conf), " ")+" ")
+}
+
+// listHelperProcs lists the user's processes with ps.
+func listHelperProcs() ([]helperProc, error) {
+	out, err := exec.Command("ps", "-U", strconv.Itoa(os.Getuid()), "-o", "pid=,args=").Output()
+	if err != nil {
+		return nil, fmt.Errorf("ps: %w", err)
+	}
+	return parseHelperProcs(string(out)), nil
+}
+
+func parseHelperProcs(out string) []helperProc {
+	var procs []helperProc
+	for _, line := range strings.Split(out, "\n") {
+		// ps pads the columns with spaces; args keeps its own.
+		pidField, args, _ := strings.Cut(strings.TrimSpace(line), " ")
+		pid, err := strconv.Atoi(pidField)
+		if err != nil {
+			continue
+		}
+		procs = append(procs, helperProc{pid: pid, args: strings.TrimSpace(args)})
+	}
+	return procs
+}
+
+// pipeWriterFIFO returns the FIFO a pipe-pane writer's command line
+// redirects to, when it is one of kojo's in fifoDir. Older kojo
+// releases single-quoted the path.
+func pipeWriterFIFO(args, fifoDir string) (string, bool) {
+	_, fifo, ok := strings.Cut(args, pipeWriterMarker)
+	if !ok {
+		return "", false
+	}
+	fifo = strings.Trim(strings.TrimSpace(fifo), "'")
+	if !strings.HasPrefix(fifo, filepath.Join(fifoDir, tmuxPrefix)) || !strings.HasSuffix(fifo, ".pipe") {
+		return "", false
+	}
+	return fifo, true
+}
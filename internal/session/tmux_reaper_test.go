@@ -0,0 +1,68 @@
+//go:build !windows
+
+package session
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseListPipes(t *testing.T) {
+	out := "kojo_a\t1\t/tmp/kojo/kojo_a.pipe\n" +
+		"kojo_b\t0\t/tmp/kojo/kojo_b.pipe\n" +
+		"work\t1\t\n" +
+		"kojo_c\t1\t\n"
+	want := []panePipe{{"kojo_a", "/tmp/kojo/kojo_a.pipe"}, {"kojo_c", ""}}
+	if got := parseListPipes(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseListPipes = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseHelperProcs(t *testing.T) {
+	out := "  101 sh -c exec cat > /tmp/kojo/kojo_s_1.pipe\n" +
+		"20345 cat\n" +
+		"junk\n"
+	want := []helperProc{
+		{101, "sh -c exec cat > /tmp/kojo/kojo_s_1.pipe"},
+		{20345, "cat"},
+	}
+	if got := parseHelperProcs(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseHelperProcs = %+v, want %+v", got, want)
+	}
+}
+
+func TestKojoClient(t *testing.T) {
+	conf := "/tmp/kojo-501/tmux.conf"
+	for args, want := range map[string]bool{
+		"tmux -L kojo -f /tmp/kojo-501/tmux.conf attach-session -t kojo_s_1": true,
+		"tmux -L kojo -f /tmp/kojo-501/tmux.conf new-session -A -s kojo_t":   true,
+		"tmux -L kojo attach -t kojo_s_1":                                    false, // the user's own
+		"tmux attach -t kojo_s_1":                                            false,
+		"tmux -L kojo -f /tmp/kojo-501/tmux.conf.bak attach":                 false,
+	} {
+		if got := kojoClient(args, conf); got != want {
+			t.Errorf("kojoClient(%q) = %v, want %v", args, got, want)
+		}
+	}
+	if kojoClient("tmux -L kojo attach", "") {
+		t.Error("client matched without a config path")
+	}
+}
+
+func TestPipeWriterFIFO(t *testing.T) {
+	for _, tc := range []struct {
+		args, want string
+		ok         bool
+	}{
+		{"sh -c exec cat > /tmp/kojo/kojo_s_1.pipe", "/tmp/kojo/kojo_s_1.pipe", true},
+		{"sh -c exec cat > '/tmp/kojo/kojo_s_1.pipe'", "/tmp/kojo/kojo_s_1.pipe", true},
+		{"sh -c exec cat > /tmp/other/kojo_s_1.pipe", "", false},
+		{"sh -c exec cat > /tmp/kojo/notes.txt", "", false},
+		{"cat", "", false},
+	} {
+		got, ok := pipeWriterFIFO(tc.args, "/tmp/kojo")
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("pipeWriterFIFO(%q) = %q, %v; want %q, %v", tc.args, got, ok, tc.want, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+//go:build !windows
+
+package session
+
+import (
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTmuxQuote(t *testing.T) {
+	for in, want := range map[string]string{
+		`plain`:                  `"plain"`,
+		`exec '/bin/zsh' -l`:     `"exec '/bin/zsh' -l"`,
+		`a "b" $HOME \x`:         `"a \"b\" \$HOME \\x"`,
+		``:                       `""`,
+		`xterm-256color:smcup@:`: `"xterm-256color:smcup@:"`,
+	} {
+		if got := tmuxQuote(in); got != want {
+			t.Errorf("tmuxQuote(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestTmuxConfig(t *testing.T) {
+	t.Setenv("SHELL", "/bin/my shell")
+	conf := tmuxConfig()
+	for _, line := range []string{
+		`set -g default-command "unset PATH; exec '/bin/my shell' -l"`,
+		`set -s terminal-overrides[99] "xterm-256color:smcup@:rmcup@"`,
+		"set -g remain-on-exit on",
+		"set -g prefix None",
+		"set -g status off",
+	} {
+		if !strings.Contains(conf, line+"\n") {
+			t.Errorf("config lacks %q:\n%s", line, conf)
+		}
+	}
+}
+
+func TestTmuxRuntimeDir(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMUX_TMPDIR", tmp)
+	dir, err := tmuxRuntimeDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(dir); err != nil || fi.Mode().Perm() != 0o700 {
+		t.Fatalf("runtime dir %s: %v, %v", dir, fi, err)
+	}
+	// A directory others may open is refused, not reused.
+	if err := os.Chmod(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tmuxRuntimeDir(); err == nil {
+		t.Error("mode 0755 runtime dir accepted")
+	}
+	// So is a symlink planted in its place.
+	if err := os.Remove(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(t.TempDir(), dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tmuxRuntimeDir(); err == nil {
+		t.Error("symlinked runtime dir accepted")
+	}
+}
+
+func TestTmuxServerArgs(t *testing.T) {
+	args := tmuxServerArgs()
+	if len(args) < 2 || args[0] != "-L" || args[1] != tmuxSocket {
+		t.Fatalf("tmuxServerArgs() = %q, want -L %s first", args, tmuxSocket)
+	}
+	restart := buildInternalToolRestartArgs(nil, "kojo_s_1")
+	if restart[1] != tmuxSocket {
+		t.Errorf("terminal restart args %q are not on kojo's server", restart)
+	}
+}
+
+func TestLoginExecArgv(t *testing.T) {
+	argv := toolArgv("/bin/sh", []string{"-c", `printf '%s|%s' "$K" "$1"`, "sh", "a 'b' $c"}, []string{"K=x y"})
+	// Skip env -u PATH: the test's PATH has to find env.
+	shellArgv := loginExecArgv("/bin/sh", argv)[3:]
+	out, err := exec.Command(shellArgv[0], shellArgv[1:]...).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `x y|a 'b' $c`; string(out) != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+	if got := loginExecArgv("/usr/bin/fish", []string{"tool"}); got[5] != "exec $argv" || got[6] != "tool" {
+		t.Errorf("fish argv = %q", got)
+	}
+}
+
+// A session persisted by a kojo from before its own tmux server is
+// still on the default one, and is reattached, polled and killed there.
+func TestRestoreLegacyTmuxSession(t *testing.T) {
+	if _, err := exec.LookPath("tmux"); err != nil {
+		t.Skip("tmux not installed")
+	}
+	// Both servers' sockets live under TMUX_TMPDIR.
+	t.Setenv("TMUX_TMPDIR", t.TempDir())
+	t.Setenv("TMUX", "")
+	defer func() {
+		_ = exec.Command("tmux", "kill-server").Run()
+		_ = tmuxCommand("kill-server").Run()
+	}()
+	const name = "kojo_s_legacy"
+	if out, err := exec.Command("tmux", "new-session", "-d", "-s", name, "sleep", "60").CombinedOutput(); err != nil {
+		t.Fatalf("new-session: %v: %s", err, out)
+	}
+
+	m := &Manager{
+		sessions: map[string]*Session{},
+		logger:   slog.Default(),
+		store:    newStore(slog.Default(), nil, ""),
+	}
+	s := m.restoreSession(SessionInfo{
+		ID: "s_legacy", Tool: "claude", CreatedAt: time.Now().Format(time.RFC3339),
+		Status: StatusRunning, TmuxSessionName: name,
+	})
+	m.sessions[s.ID] = s
+	if got := s.Info().Status; got != StatusRunning {
+		t.Fatalf("status = %q, want running", got)
+	}
+	if !isTmuxLegacy(name) {
+		t.Error("session not marked as on the default server")
+	}
+	if tmuxCommand("has-session", "-t", name).Run() == nil {
+		t.Error("session created on kojo's server")
+	}
+
+	// The poller watches it over there rather than declaring it gone.
+	time.Sleep(3 * paneStatusPollInterval)
+	if got := s.Info().Status; got != StatusRunning {
+		t.Fatalf("status after polling = %q, want running", got)
+	}
+
+	if err := m.Stop(s.ID); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-s.done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("session did not exit")
+	}
+	if exec.Command("tmux", "has-session", "-t", name).Run() == nil {
+		t.Error("Stop left the session on the default server")
+	}
+	m.platformRemove(s)
+	if isTmuxLegacy(name) {
+		t.Error("legacy mark outlived the session")
+	}
+}
@@ -0,0 +1,43 @@
+package session
+
+// MinTmuxVersion is the oldest tmux release kojo supports: 3.0 runs a
+// session's command given as separate arguments without a shell, and
+// has the #{q:} format pipe-pane relies on.
+const MinTmuxVersion = "3.0"
+
+// tmux releases whose changes the tmux backend adapts to.
+const (
+	// window-size moved from a session option to a window option.
+	tmuxWindowSizeWindowOption = "3.1"
+	// remain-on-exit-format replaced the fixed "Pane is dead" line tmux
+	// writes into a pane whose process exited.
+	tmuxRemainOnExitFormat = "3.3"
+)
+
+// tmuxRelease returns the release in a `tmux -V` line: "3.3" for
+// "tmux 3.3a", "3.5" for "tmux next-3.5". It is "" for builds that
+// name none, such as "tmux master".
+func tmuxRelease(v string) string {
+	return versionRe.FindString(v)
+}
+
+// tmuxVersionAtLeast reports whether the tmux that printed v is release
+// minVersion or newer. A build without a release is taken to be newer
+// than any.
+func tmuxVersionAtLeast(v, minVersion string) bool {
+	r := tmuxRelease(v)
+	return r == "" || compareVersions(r, minVersion) >= 0
+}
+
+// tmuxAtLeast reports whether the installed tmux is release minVersion
+// or newer.
+func tmuxAtLeast(minVersion string) bool {
+	return tmuxVersionAtLeast(TmuxVersion(), minVersion)
+}
+
+// TmuxSupported reports whether tmux is installed and at least
+// MinTmuxVersion.
+func TmuxSupported() bool {
+	v := TmuxVersion()
+	return v != "" && tmuxVersionAtLeast(v, MinTmuxVersion)
+}
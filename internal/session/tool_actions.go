@@ -0,0 +1,123 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Housekeeping actions are a tool's own control commands, such as
+// claude's /compact, typed into the session on request, so a phone
+// doesn't have to type slash commands.
+
+// ErrUnknownAction is returned for an action the session's tool lacks.
+var ErrUnknownAction = errors.New("unknown action")
+
+// ToolAction is one housekeeping action of a tool.
+type ToolAction struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Input is what is typed before Enter.
+	Input string `json:"input"`
+}
+
+// An ActionProvider is a ToolAdapter with housekeeping actions.
+type ActionProvider interface {
+	Actions() []ToolAction
+}
+
+const (
+	// actionSubmitDelay separates an action's input from its Enter,
+	// so TUIs don't take the two as one paste.
+	actionSubmitDelay = 100 * time.Millisecond
+	// MaxActionCapture bounds how long RunAction collects output.
+	MaxActionCapture = 30 * time.Second
+	// maxActionLines is how many lines of captured output RunAction
+	// returns.
+	maxActionLines = 40
+	// maxActionOutput caps the output RunAction collects.
+	maxActionOutput = 64 << 10
+)
+
+func (claudeAdapter) Actions() []ToolAction {
+	return []ToolAction{
+		{"compact", "Summarize the conversation to free up context", "/compact"},
+		{"clear", "Start over with an empty conversation", "/clear"},
+		{"cost", "Show the tokens and cost of this session", "/cost"},
+	}
+}
+
+func (codexAdapter) Actions() []ToolAction {
+	return []ToolAction{
+		{"compact", "Summarize the conversation to free up context", "/compact"},
+		{"new", "Start a new conversation", "/new"},
+		{"status", "Show the session's model, limits and token use", "/status"},
+	}
+}
+
+// ToolActions returns the housekeeping actions of tool, nil if it has
+// none.
+func ToolActions(tool string) []ToolAction {
+	if p, ok := toolAdapters[tool].(ActionProvider); ok {
+		return p.Actions()
+	}
+	return nil
+}
+
+// RunAction types housekeeping action name into session id. With
+// capture > 0 it then collects the session's output for that long (or
+// until it exits) and returns its last lines, escapes stripped.
+func (m *Manager) RunAction(id, name string, capture time.Duration) ([]string, error) {
+	s, ok := m.Get(id)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	s.mu.Lock()
+	tool, status := s.Tool, s.Status
+	s.mu.Unlock()
+	var action *ToolAction
+	for _, a := range ToolActions(tool) {
+		if a.Name == name {
+			action = &a
+			break
+		}
+	}
+	if action == nil {
+		return nil, fmt.Errorf("%w: %s has no action %q", ErrUnknownAction, tool, name)
+	}
+	if status != StatusRunning {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotRunning, id)
+	}
+	capture = min(capture, MaxActionCapture)
+
+	var ch chan []byte
+	if capture > 0 {
+		ch, _ = s.Subscribe()
+		defer s.Unsubscribe(ch)
+	}
+	if _, err := s.Write([]byte(action.Input)); err != nil {
+		return nil, err
+	}
+	time.Sleep(actionSubmitDelay)
+	if _, err := s.Write([]byte("\r")); err != nil {
+		return nil, err
+	}
+	m.logger.Info("session action", "id", id, "action", name)
+	if ch == nil {
+		return nil, nil
+	}
+
+	var out []byte
+	timer := time.NewTimer(capture)
+	defer timer.Stop()
+	for {
+		select {
+		case b := <-ch:
+			out = capTail(out, b, maxActionOutput)
+			continue
+		case <-timer.C:
+		case <-s.Done():
+		}
+		return tailLines(out, maxActionLines), nil
+	}
+}
@@ -0,0 +1,312 @@
+package session
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ToolAdapter is what kojo knows about one user tool's CLI: how to
+// give a new session a conversation ID, how to resume it on restart,
+// what its output says, and how yolo mode gets past its approval
+// prompts. The built-in tools are registered below; RegisterTool adds
+// others.
+type ToolAdapter interface {
+	// Name is the tool's name in the API, e.g. "claude".
+	Name() string
+	// Binary is the executable looked up on PATH; "custom" runs
+	// "claude".
+	Binary() string
+	// AssignSessionID picks the conversation ID of a new session and
+	// returns it with the args that start the tool on it. A tool that
+	// picks its own returns "" and args as they are, and kojo learns
+	// the ID from ParseSessionID.
+	AssignSessionID(args []string) (id string, runArgs []string)
+	// ResumeArgs returns the args that relaunch a session started with
+	// args on conversation id, or on the tool's latest conversation in
+	// the working directory when id is "".
+	ResumeArgs(args []string, id string) []string
+	// ParseSessionID returns the conversation ID announced in output,
+	// which has its escape sequences stripped, or "" when there is
+	// none in it.
+	ParseSessionID(output []byte) string
+	// YoloFlag is the tool's flag for skipping approvals, "" if it has
+	// none. Without one, yolo mode presses Enter on ApprovalPrompt.
+	YoloFlag() string
+	// ApprovalPrompt matches a prompt that Enter approves.
+	ApprovalPrompt() *regexp.Regexp
+}
+
+// A SessionIDFinder is a ToolAdapter that can also find a session's
+// conversation ID in the tool's own files, for when ParseSessionID
+// never saw it. Restart asks it before resuming a local session whose
+// ID is unknown.
+type SessionIDFinder interface {
+	// FindSessionID returns the ID of the conversation the tool
+	// started in workDir at about started, "" when it can't tell.
+	// IDs for which taken reports true belong to other sessions.
+	FindSessionID(workDir string, started time.Time, taken func(id string) bool) string
+}
+
+// A Prompter is a ToolAdapter whose tool takes a first prompt on its
+// command line and starts working on it.
+type Prompter interface {
+	// PromptArgs returns the args that hand the tool prompt at launch.
+	PromptArgs(prompt string) []string
+}
+
+// toolAdapters are the user tools by name. Internal tools (tmux,
+// shell) have no adapter.
+var toolAdapters = map[string]ToolAdapter{}
+
+func init() {
+	for _, a := range []ToolAdapter{claudeAdapter{}, codexAdapter{}, geminiAdapter{}, grokAdapter{}, customAdapter{}} {
+		RegisterTool(a)
+	}
+}
+
+// RegisterTool makes a a user tool sessions can be started with. Call
+// it before the Manager is created, as from an init function; it
+// panics when the name is empty or taken.
+func RegisterTool(a ToolAdapter) {
+	name := a.Name()
+	if name == "" || toolAdapters[name] != nil || internalTools[name] {
+		panic(fmt.Sprintf("session: RegisterTool: bad or duplicate tool name %q", name))
+	}
+	toolAdapters[name] = a
+}
+
+// UserTools returns the names of the user tools, sorted.
+func UserTools() []string {
+	return slices.Sorted(maps.Keys(toolAdapters))
+}
+
+// "Do you ...? ... 1. Yes" pattern (allow blank lines between question and options)
+var yoloPattern = regexp.MustCompile(`(?i)Do you \S[^\n]*\?[\s\S]{0,200}?1\.\s*Yes`)
+
+// approvalPrompt returns the approval prompt of tool, yoloPattern for
+// a tool without an adapter.
+func approvalPrompt(tool string) *regexp.Regexp {
+	if a := toolAdapters[tool]; a != nil {
+		return a.ApprovalPrompt()
+	}
+	return yoloPattern
+}
+
+// stripResumeFlags drops claude's and grok's --resume/-r and
+// --continue/-c from args; resumeValue reports whether --resume takes
+// the next argument.
+func stripResumeFlags(args []string, resumeValue func(next string) bool) []string {
+	out := make([]string, 0, len(args)+2)
+	for i := 0; i < len(args); i++ {
+		switch a := args[i]; a {
+		case "--continue", "-c":
+		case "--resume", "-r":
+			if i+1 < len(args) && resumeValue(args[i+1]) {
+				i++
+			}
+		default:
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// claudeAdapter gives each session its own --session-id, so the
+// conversation is known from the start.
+type claudeAdapter struct{}
+
+func (claudeAdapter) Name() string   { return "claude" }
+func (claudeAdapter) Binary() string { return "claude" }
+
+func (claudeAdapter) AssignSessionID(args []string) (string, []string) {
+	for i, a := range args {
+		if a == "--session-id" {
+			var id string
+			if i+1 < len(args) {
+				id = args[i+1]
+			}
+			return id, args
+		}
+		if id, ok := strings.CutPrefix(a, "--session-id="); ok {
+			return id, args
+		}
+	}
+	// No --session-id found; generate one and append.
+	id := uuid.New().String()
+	return id, append(slices.Clone(args), "--session-id", id)
+}
+
+func (claudeAdapter) ResumeArgs(args []string, id string) []string {
+	out := stripResumeFlags(args, func(string) bool { return true })
+	if id != "" {
+		return append(out, "--resume", id)
+	}
+	return append(out, "--continue")
+}
+
+// PromptArgs passes prompt as claude's positional prompt, after "--"
+// so one starting with "-" isn't read as a flag.
+func (claudeAdapter) PromptArgs(prompt string) []string { return []string{"--", prompt} }
+
+func (claudeAdapter) ParseSessionID([]byte) string   { return "" }
+func (claudeAdapter) YoloFlag() string               { return "--dangerously-skip-permissions" }
+func (claudeAdapter) ApprovalPrompt() *regexp.Regexp { return yoloPattern }
+
+// customAdapter is claude pointed at a custom API (see
+// resolveCustomAPI). It gets no yolo flag: the PTY auto-approve
+// answers its prompts instead.
+type customAdapter struct{ claudeAdapter }
+
+func (customAdapter) Name() string     { return "custom" }
+func (customAdapter) YoloFlag() string { return "" }
+
+// Codex outputs "session id: <UUID>" on startup
+var codexSessionIDRe = regexp.MustCompile(`(?i)session id: ([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})`)
+
+// codexAdapter learns the session ID from codex's startup banner, or
+// failing that from its rollout files (see FindSessionID), and
+// resumes with its resume subcommand, which takes no other args.
+type codexAdapter struct{}
+
+func (codexAdapter) Name() string   { return "codex" }
+func (codexAdapter) Binary() string { return "codex" }
+
+func (codexAdapter) AssignSessionID(args []string) (string, []string) { return "", args }
+
+func (codexAdapter) ResumeArgs(_ []string, id string) []string {
+	if id != "" {
+		return []string{"resume", id}
+	}
+	return []string{"resume", "--last"}
+}
+
+func (codexAdapter) PromptArgs(prompt string) []string { return []string{"--", prompt} }
+
+func (codexAdapter) ParseSessionID(output []byte) string {
+	if m := codexSessionIDRe.FindSubmatch(output); m != nil {
+		return string(m[1])
+	}
+	return ""
+}
+
+func (codexAdapter) YoloFlag() string               { return "--dangerously-bypass-approvals-and-sandbox" }
+func (codexAdapter) ApprovalPrompt() *regexp.Regexp { return yoloPattern }
+
+// Gemini asks "Allow execution of: 'ls'?", "Apply this change?" and
+// the like, then lists "1. Yes, allow once" or "1. Allow once".
+var geminiApprovalRe = regexp.MustCompile(`(?i)(?:Allow execution\b[^\n]*|Apply this change|Do you want to proceed)\?[\s\S]{0,200}?1\.\s*(?:Yes|Allow)`)
+
+// geminiAdapter learns the session ID from its chat files (see
+// FindSessionID), or from the "Session ID" gemini's /stats prints, and
+// resumes with --resume <id>; with --resume latest, gemini's newest
+// conversation in the project, only when the ID is still unknown.
+type geminiAdapter struct{}
+
+func (geminiAdapter) Name() string   { return "gemini" }
+func (geminiAdapter) Binary() string { return "gemini" }
+
+func (geminiAdapter) AssignSessionID(args []string) (string, []string) { return "", args }
+
+func (geminiAdapter) ResumeArgs(args []string, id string) []string {
+	// --resume/-r takes an optional value, "latest" when left out. A
+	// conversation the user resumed by ID stays the one to resume.
+	out := make([]string, 0, len(args)+2)
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		v, joined := strings.CutPrefix(a, "--resume=")
+		if !joined {
+			v, joined = strings.CutPrefix(a, "-r=")
+		}
+		switch {
+		case joined:
+		case a == "--resume" || a == "-r":
+			v = ""
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				i++
+				v = args[i]
+			}
+		default:
+			out = append(out, a)
+			continue
+		}
+		if id == "" && v != "latest" {
+			id = v
+		}
+	}
+	if id == "" {
+		id = "latest"
+	}
+	return append(out, "--resume", id)
+}
+
+// PromptArgs uses --prompt-interactive: a positional prompt makes
+// gemini answer it and exit. The joined form keeps a prompt starting
+// with "-" from being read as a flag.
+func (geminiAdapter) PromptArgs(prompt string) []string {
+	return []string{"--prompt-interactive=" + prompt}
+}
+
+func (geminiAdapter) ParseSessionID(output []byte) string {
+	if m := codexSessionIDRe.FindSubmatch(output); m != nil {
+		return string(m[1])
+	}
+	return ""
+}
+
+func (geminiAdapter) YoloFlag() string               { return "--yolo" }
+func (geminiAdapter) ApprovalPrompt() *regexp.Regexp { return geminiApprovalRe }
+
+// grokAdapter resumes with --resume=<id>. kojo can't read grok's
+// session ID from its output, so an ID the user passed with --resume
+// is kept for restarts.
+type grokAdapter struct{}
+
+func (grokAdapter) Name() string   { return "grok" }
+func (grokAdapter) Binary() string { return "grok" }
+
+func (grokAdapter) AssignSessionID(args []string) (string, []string) { return "", args }
+
+func (grokAdapter) ResumeArgs(args []string, id string) []string {
+	// Grok's -r/--resume takes an OPTIONAL argument: the next
+	// positional is its value only when it doesn't look like another
+	// flag. preservedResumeID captures an explicit resume target the
+	// user originally passed, since toolSessionID is virtually always
+	// "" — without it the restart would silently degrade to --continue
+	// and pick whichever session grok last touched in this cwd.
+	var preservedResumeID string
+	for i, a := range args {
+		if a == "--resume" || a == "-r" {
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				preservedResumeID = args[i+1]
+			}
+		} else if v, ok := strings.CutPrefix(a, "--resume="); ok {
+			preservedResumeID = v
+		} else if v, ok := strings.CutPrefix(a, "-r="); ok {
+			preservedResumeID = v
+		}
+	}
+	out := stripResumeFlags(args, func(next string) bool { return !strings.HasPrefix(next, "-") })
+	out = dropFlag(dropFlag(out, "--resume", false), "-r", false)
+	// Re-apply the resume target as --resume=<id> (joined form)
+	// rather than --resume <id> (split form): with an optional
+	// argument, a split-form value that happens to start with "-"
+	// would be parsed as the NEXT flag rather than the resume target.
+	switch {
+	case id != "":
+		return append(out, "--resume="+id)
+	case preservedResumeID != "":
+		return append(out, "--resume="+preservedResumeID)
+	default:
+		return append(out, "--continue")
+	}
+}
+
+func (grokAdapter) ParseSessionID([]byte) string   { return "" }
+func (grokAdapter) YoloFlag() string               { return "" }
+func (grokAdapter) ApprovalPrompt() *regexp.Regexp { return yoloPattern }
@@ -0,0 +1,237 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBuildRestartArgs(t *testing.T) {
+	for _, c := range []struct {
+		tool string
+		args []string
+		id   string
+		want []string
+	}{
+		{"claude", []string{"--model", "opus", "--resume", "old", "-c"}, "new", []string{"--model", "opus", "--resume", "new"}},
+		{"claude", []string{"--model", "opus"}, "", []string{"--model", "opus", "--continue"}},
+		{"custom", []string{"-r", "old"}, "new", []string{"--resume", "new"}},
+		{"codex", []string{"--model", "o3"}, "abc", []string{"resume", "abc"}},
+		{"codex", nil, "", []string{"resume", "--last"}},
+		{"gemini", []string{"--model", "gemini-2.5-pro", "--resume"}, "", []string{"--model", "gemini-2.5-pro", "--resume", "latest"}},
+		{"gemini", []string{"-r", "latest", "--yolo"}, "abc", []string{"--yolo", "--resume", "abc"}},
+		{"gemini", []string{"--resume=mine", "-m", "flash"}, "", []string{"-m", "flash", "--resume", "mine"}},
+		{"grok", []string{"-r", "mine", "--foo"}, "", []string{"--foo", "--resume=mine"}},
+		{"grok", []string{"--resume=mine", "-c"}, "new", []string{"--resume=new"}},
+		{"grok", []string{"-r", "--foo"}, "", []string{"--foo", "--continue"}},
+	} {
+		if got := buildRestartArgs(c.tool, c.args, c.id); !slices.Equal(got, c.want) {
+			t.Errorf("buildRestartArgs(%s, %q, %q) = %q, want %q", c.tool, c.args, c.id, got, c.want)
+		}
+	}
+}
+
+func TestCodexParseSessionID(t *testing.T) {
+	s := &Session{Tool: "codex"}
+	s.CaptureToolSessionID([]byte("\x1b[1mSession ID: 0199a1b2-c3d4"))
+	s.CaptureToolSessionID([]byte("-e5f6-a7b8-c9d0e1f2a3b4\r\n"))
+	if want := "0199a1b2-c3d4-e5f6-a7b8-c9d0e1f2a3b4"; s.ToolSessionID != want {
+		t.Errorf("ToolSessionID = %q, want %q", s.ToolSessionID, want)
+	}
+}
+
+// echoAdapter is a third-party tool: "echo-tool" announcing
+// "conversation <id>" and asking "Allow? [Enter]".
+type echoAdapter struct{ grokAdapter }
+
+func (echoAdapter) Name() string   { return "echo-tool" }
+func (echoAdapter) Binary() string { return "echo-tool-cli" }
+
+func (echoAdapter) ResumeArgs(_ []string, id string) []string { return []string{"--load", id} }
+
+func (echoAdapter) ParseSessionID(output []byte) string {
+	if m := regexp.MustCompile(`conversation (\w+)`).FindSubmatch(output); m != nil {
+		return string(m[1])
+	}
+	return ""
+}
+
+func (echoAdapter) ApprovalPrompt() *regexp.Regexp { return regexp.MustCompile(`Allow\? \[Enter\]`) }
+
+func TestRegisterTool(t *testing.T) {
+	RegisterTool(echoAdapter{})
+	t.Cleanup(func() { delete(toolAdapters, "echo-tool") })
+
+	if !IsUserTool("echo-tool") {
+		t.Fatal("registered tool is not a user tool")
+	}
+	if got := new(Manager).resolveCustomAPI("echo-tool", nil).actualTool; got != "echo-tool-cli" {
+		t.Errorf("runs %q, want its binary", got)
+	}
+	if got := buildRestartArgs("echo-tool", []string{"-v"}, "x1"); !slices.Equal(got, []string{"--load", "x1"}) {
+		t.Errorf("restart args = %q", got)
+	}
+
+	s := newTestSession(true)
+	s.Tool = "echo-tool"
+	s.CaptureToolSessionID([]byte("started conversation k42\n"))
+	if s.ToolSessionID != "k42" {
+		t.Errorf("ToolSessionID = %q, want k42", s.ToolSessionID)
+	}
+	if approval, _ := s.CheckYolo([]byte("Do you want to proceed? 1. Yes")); approval != nil {
+		t.Error("yolo answered another tool's prompt")
+	}
+	if approval, _ := s.CheckYolo([]byte("Allow? [Enter]")); approval == nil {
+		t.Error("yolo missed the tool's own prompt")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a taken name did not panic")
+		}
+	}()
+	RegisterTool(echoAdapter{})
+}
+
+func TestCodexFindSessionID(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("CODEX_HOME", home)
+	work := t.TempDir()
+	started := time.Now().Add(-time.Minute).Truncate(time.Second)
+	day := filepath.Join(home, "sessions", "2026", "01", "02")
+	if err := os.MkdirAll(day, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for i, r := range []struct {
+		id, cwd string
+		at      time.Time
+	}{
+		{"before", work, started.Add(-time.Hour)},
+		{"elsewhere", "/somewhere/else", started.Add(time.Second)},
+		{"ours", work, started.Add(500 * time.Millisecond)},
+		{"later", work, started.Add(10 * time.Second)},
+		{"claimed", work, started},
+	} {
+		line := fmt.Sprintf(`{"timestamp":%q,"type":"session_meta","payload":{"id":%q,"timestamp":%q,"cwd":%q,"instructions":"..."}}`+"\n",
+			r.at.Format(time.RFC3339Nano), r.id, r.at.Format(time.RFC3339Nano), r.cwd)
+		path := filepath.Join(day, fmt.Sprintf("rollout-%d-%s.jsonl", i, r.id))
+		if err := os.WriteFile(path, []byte(line), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	taken := func(id string) bool { return id == "claimed" }
+	if got := (codexAdapter{}).FindSessionID(work, started, taken); got != "ours" {
+		t.Errorf("FindSessionID = %q, want ours", got)
+	}
+	if got := (codexAdapter{}).FindSessionID(t.TempDir(), started, taken); got != "" {
+		t.Errorf("FindSessionID in an unused dir = %q", got)
+	}
+}
+
+func TestGeminiFindSessionID(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("GEMINI_CLI_HOME", home)
+	work := t.TempDir()
+	started := time.Now().Add(-time.Minute).Truncate(time.Second)
+	for i, r := range []struct {
+		id, dir string
+		at      time.Time
+	}{
+		{"before", work, started.Add(-time.Hour)},
+		{"elsewhere", "/somewhere/else", started.Add(time.Second)},
+		{"ours", work, started.Add(500 * time.Millisecond)},
+		{"later", work, started.Add(10 * time.Second)},
+		{"claimed", work, started},
+	} {
+		chats := filepath.Join(home, ".gemini", "tmp", geminiProjectHash(r.dir), "chats")
+		if err := os.MkdirAll(chats, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		chat := fmt.Sprintf(`{"sessionId":%q,"projectHash":%q,"startTime":%q,"messages":[]}`,
+			r.id, geminiProjectHash(r.dir), r.at.Format(time.RFC3339Nano))
+		path := filepath.Join(chats, fmt.Sprintf("session-%d-%s.json", i, r.id))
+		if err := os.WriteFile(path, []byte(chat), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	taken := func(id string) bool { return id == "claimed" }
+	if got := (geminiAdapter{}).FindSessionID(work, started, taken); got != "ours" {
+		t.Errorf("FindSessionID = %q, want ours", got)
+	}
+	if got := (geminiAdapter{}).FindSessionID(t.TempDir(), started, taken); got != "" {
+		t.Errorf("FindSessionID in an unused dir = %q", got)
+	}
+	if got := buildRestartArgs("gemini", nil, "ours"); !slices.Equal(got, []string{"--resume", "ours"}) {
+		t.Errorf("restart args = %q", got)
+	}
+}
+
+func TestClaudeControls(t *testing.T) {
+	c := claudeAdapter{}
+	if model, mode := c.LaunchControls([]string{"--model=sonnet", "--dangerously-skip-permissions"}); model != "sonnet" || mode != "bypassPermissions" {
+		t.Errorf("LaunchControls = %q, %q", model, mode)
+	}
+	got := c.WithControls([]string{"--model", "sonnet", "--permission-mode", "plan", "--resume", "x"}, "opus", "default")
+	if want := []string{"--resume", "x", "--model", "opus"}; !slices.Equal(got, want) {
+		t.Errorf("WithControls = %q, want %q", got, want)
+	}
+	got = c.WithControls([]string{"--dangerously-skip-permissions"}, "", "acceptEdits")
+	if want := []string{"--dangerously-skip-permissions", "--permission-mode", "acceptEdits"}; !slices.Equal(got, want) {
+		t.Errorf("WithControls = %q, want %q", got, want)
+	}
+	if Controls("codex", nil) != nil || Controls("custom", nil) == nil {
+		t.Error("controls: codex has none, custom is claude's")
+	}
+	for _, tc := range []struct {
+		lines []string
+		mode  string
+		ok    bool
+	}{
+		{[]string{"> ", "  ? for shortcuts"}, "default", true},
+		{[]string{"  ? for shortcuts", "> ", "  ⏸ plan mode on (shift+tab to cycle)"}, "plan", true},
+		{[]string{"  ⏵⏵ accept edits on (shift+tab to cycle)"}, "acceptEdits", true},
+		{[]string{"Thinking…"}, "", false},
+	} {
+		if mode, ok := c.ScreenPermissionMode(tc.lines); mode != tc.mode || ok != tc.ok {
+			t.Errorf("ScreenPermissionMode(%q) = %q, %v", tc.lines, mode, ok)
+		}
+	}
+}
+
+func TestSetControlsRefusals(t *testing.T) {
+	m := &Manager{noYoloPaths: compilePathRules([]string{"/srv/prod/**"}, nil), logger: slog.New(slog.DiscardHandler)}
+	s := &Session{ID: "s1", Tool: "claude", WorkDir: "/srv/prod/app", Args: []string{"--dangerously-skip-permissions"}, Status: StatusRunning, scrollback: NewRingBuffer(1024)}
+	if err := m.SetControls(s, "", "bypassPermissions"); !errors.Is(err, ErrProtectedPath) {
+		t.Errorf("bypassPermissions in a no-yolo path: err = %v", err)
+	}
+	s.WorkDir = "/home/me/app"
+	s.scrollback.Write([]byte("Thinking…\r\n"))
+	if err := m.SetControls(s, "", "plan"); !errors.Is(err, ErrControlUnverified) {
+		t.Errorf("mode not on screen: err = %v", err)
+	}
+}
+
+func TestPromptArgs(t *testing.T) {
+	for _, tool := range []string{"claude", "custom", "codex"} {
+		p, ok := toolAdapters[tool].(Prompter)
+		if !ok {
+			t.Fatalf("%s is not a Prompter", tool)
+		}
+		if got := p.PromptArgs("-fix it"); !slices.Equal(got, []string{"--", "-fix it"}) {
+			t.Errorf("%s PromptArgs = %q", tool, got)
+		}
+	}
+	if got := toolAdapters["gemini"].(Prompter).PromptArgs("-fix it"); !slices.Equal(got, []string{"--prompt-interactive=-fix it"}) {
+		t.Errorf("gemini PromptArgs = %q", got)
+	}
+	m := &Manager{}
+	if _, err := m.CreateWithOptions("grok", "", nil, false, "", CreateOptions{Prompt: "hi"}); !errors.Is(err, ErrPromptUnsupported) {
+		t.Errorf("grok: err = %v, want ErrPromptUnsupported", err)
+	}
+}
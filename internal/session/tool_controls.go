@@ -0,0 +1,256 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Runtime controls are the settings a tool can switch while it runs:
+// its model and its permission mode. kojo switches them by typing the
+// tool's own commands and remembers the result on the session, so the
+// UI can show it and a restart brings the tool back in the same state.
+// A switch the user makes in the terminal itself isn't seen, except
+// that the permission mode is read back from the screen around a
+// switch.
+
+// ErrInvalidControl is returned for a model or permission mode the
+// session's tool can't be switched to, or a tool with no controls.
+var ErrInvalidControl = errors.New("invalid control")
+
+// ErrControlUnverified is returned when the screen doesn't show which
+// permission mode the tool is in, before or after a switch.
+var ErrControlUnverified = errors.New("permission mode not verified")
+
+// controlVerifyTimeout is how long SetControls waits for the screen to
+// show the permission mode it switched to.
+const controlVerifyTimeout = 2 * time.Second
+
+// controlScreenLines is how many of the last output lines SetControls
+// looks for the permission mode in.
+const controlScreenLines = 20
+
+// A ControlProvider is a ToolAdapter with runtime controls.
+type ControlProvider interface {
+	// Models suggests model names; the tool may take others.
+	Models() []string
+	// ModelInput is what to type, before Enter, to switch to model.
+	ModelInput(model string) string
+	// PermissionModes are the modes PermissionModeKey cycles through,
+	// in order, for the tool launched with args.
+	PermissionModes(args []string) []string
+	PermissionModeKey() string
+	// ScreenPermissionMode reads the mode off the last lines of the
+	// tool's screen; ok is false when they don't show one.
+	ScreenPermissionMode(lines []string) (mode string, ok bool)
+	// LaunchControls returns the model and mode args start the tool
+	// in; model is "" for the tool's default.
+	LaunchControls(args []string) (model, mode string)
+	// WithControls returns args changed to launch with model and mode.
+	WithControls(args []string, model, mode string) []string
+}
+
+// ToolControls is what the UI needs to offer a tool's runtime controls.
+type ToolControls struct {
+	Models          []string `json:"models"`
+	PermissionModes []string `json:"permissionModes"`
+}
+
+// modelNameRe is what a model name may look like: "opus",
+// "claude-sonnet-4-5-20250929", "sonnet[1m]".
+var modelNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:\[\]-]{0,99}$`)
+
+// claudeShiftTab is the key claude cycles permission modes with.
+const claudeShiftTab = "\x1b[Z"
+
+func (claudeAdapter) Models() []string { return []string{"default", "opus", "sonnet", "haiku"} }
+
+func (claudeAdapter) ModelInput(model string) string { return "/model " + model }
+
+func (claudeAdapter) PermissionModes(args []string) []string {
+	modes := []string{"default", "acceptEdits", "plan"}
+	if slices.Contains(args, "--dangerously-skip-permissions") {
+		modes = append(modes, "bypassPermissions")
+	}
+	return modes
+}
+
+func (claudeAdapter) PermissionModeKey() string { return claudeShiftTab }
+
+// claudeModeMarkers are what claude's footer shows in each permission
+// mode; the default mode shows only the shortcuts hint.
+var claudeModeMarkers = []struct{ text, mode string }{
+	{"accept edits on", "acceptEdits"},
+	{"plan mode on", "plan"},
+	{"bypass permissions on", "bypassPermissions"},
+	{"? for shortcuts", "default"},
+}
+
+func (claudeAdapter) ScreenPermissionMode(lines []string) (string, bool) {
+	for _, line := range slices.Backward(lines) {
+		for _, m := range claudeModeMarkers {
+			if strings.Contains(line, m.text) {
+				return m.mode, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (claudeAdapter) LaunchControls(args []string) (model, mode string) {
+	mode = "default"
+	if slices.Contains(args, "--dangerously-skip-permissions") {
+		mode = "bypassPermissions"
+	}
+	for i, a := range args {
+		for _, f := range []struct {
+			flag string
+			v    *string
+		}{{"--model", &model}, {"--permission-mode", &mode}} {
+			if a == f.flag && i+1 < len(args) {
+				*f.v = args[i+1]
+			} else if v, ok := strings.CutPrefix(a, f.flag+"="); ok {
+				*f.v = v
+			}
+		}
+	}
+	return model, mode
+}
+
+func (c claudeAdapter) WithControls(args []string, model, mode string) []string {
+	out := dropFlag(dropFlag(args, "--model", true), "--permission-mode", true)
+	if model != "" && model != "default" {
+		out = append(out, "--model", model)
+	}
+	if _, launch := c.LaunchControls(out); mode != "" && mode != launch {
+		out = append(out, "--permission-mode", mode)
+	}
+	return out
+}
+
+// Controls returns the runtime controls of tool, nil if it has none.
+func Controls(tool string, args []string) *ToolControls {
+	cp, ok := toolAdapters[tool].(ControlProvider)
+	if !ok {
+		return nil
+	}
+	return &ToolControls{Models: cp.Models(), PermissionModes: cp.PermissionModes(args)}
+}
+
+// awaitPermissionMode waits up to controlVerifyTimeout for the screen
+// of s to show mode, and returns the mode it last showed — mode, or
+// what the tool ended up in instead, or the one kojo had on record when
+// the screen shows none.
+func (m *Manager) awaitPermissionMode(s *Session, cp ControlProvider, mode string) (string, error) {
+	deadline := time.Now().Add(controlVerifyTimeout)
+	for {
+		shown, ok := cp.ScreenPermissionMode(s.TailLines(controlScreenLines))
+		if ok && shown == mode {
+			return mode, nil
+		}
+		if time.Now().After(deadline) {
+			if !ok {
+				s.mu.Lock()
+				shown = s.PermissionMode
+				s.mu.Unlock()
+				return shown, fmt.Errorf("%w: the screen doesn't show a mode after switching to %s", ErrControlUnverified, mode)
+			}
+			return shown, fmt.Errorf("%w: the tool is in %s, not %s", ErrControlUnverified, shown, mode)
+		}
+		time.Sleep(actionSubmitDelay)
+	}
+}
+
+// launchControls is ControlProvider.LaunchControls, "" for a tool
+// without controls.
+func launchControls(tool string, args []string) (model, mode string) {
+	if cp, ok := toolAdapters[tool].(ControlProvider); ok {
+		return cp.LaunchControls(args)
+	}
+	return "", ""
+}
+
+// SetControls switches the running tool of s to model and permission
+// mode, leaving either alone when it is "". Switches of one session
+// run one at a time. The mode is cycled to from the one the screen
+// shows and must show up there afterwards; ErrControlUnverified when
+// either can't be read. bypassPermissions is yolo mode, so it is
+// refused in no-yolo paths.
+func (m *Manager) SetControls(s *Session, model, mode string) error {
+	s.controlsMu.Lock()
+	defer s.controlsMu.Unlock()
+	s.mu.Lock()
+	tool, args, status := s.Tool, s.Args, s.Status
+	s.mu.Unlock()
+	cp, ok := toolAdapters[tool].(ControlProvider)
+	if !ok {
+		return fmt.Errorf("%w: %s has no runtime controls", ErrInvalidControl, tool)
+	}
+	if model != "" && !modelNameRe.MatchString(model) {
+		return fmt.Errorf("%w: bad model name %q", ErrInvalidControl, model)
+	}
+	var presses int
+	if mode != "" {
+		modes := cp.PermissionModes(args)
+		to := slices.Index(modes, mode)
+		if to < 0 {
+			return fmt.Errorf("%w: permission mode %q is not one of %s", ErrInvalidControl, mode, strings.Join(modes, ", "))
+		}
+		if mode == "bypassPermissions" {
+			if err := m.CheckYoloPath(s); err != nil {
+				return err
+			}
+		}
+		if status != StatusRunning {
+			return fmt.Errorf("%w: %s", ErrSessionNotRunning, s.ID)
+		}
+		cur, ok := cp.ScreenPermissionMode(s.TailLines(controlScreenLines))
+		from := slices.Index(modes, cur)
+		if !ok || from < 0 {
+			return fmt.Errorf("%w: the screen doesn't show the current mode", ErrControlUnverified)
+		}
+		presses = (to - from + len(modes)) % len(modes)
+	}
+	if status != StatusRunning {
+		return fmt.Errorf("%w: %s", ErrSessionNotRunning, s.ID)
+	}
+
+	if model != "" {
+		if _, err := s.Write([]byte(cp.ModelInput(model))); err != nil {
+			return err
+		}
+		time.Sleep(actionSubmitDelay)
+		if _, err := s.Write([]byte("\r")); err != nil {
+			return err
+		}
+		s.mu.Lock()
+		s.Model = model
+		s.mu.Unlock()
+	}
+	for i := 0; i < presses; i++ {
+		if i > 0 || model != "" {
+			time.Sleep(actionSubmitDelay)
+		}
+		if _, err := s.Write([]byte(cp.PermissionModeKey())); err != nil {
+			return err
+		}
+	}
+	if mode != "" {
+		shown, err := m.awaitPermissionMode(s, cp, mode)
+		s.mu.Lock()
+		s.PermissionMode = shown
+		s.mu.Unlock()
+		if err != nil {
+			m.save()
+			m.NotifyUpdated(s)
+			return err
+		}
+	}
+	m.logger.Info("session controls switched", "id", s.ID, "model", model, "permissionMode", mode)
+	m.save()
+	m.NotifyUpdated(s)
+	return nil
+}
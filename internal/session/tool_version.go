@@ -0,0 +1,110 @@
+package session
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// minToolVersions is the oldest release of each tool whose flags kojo
+// relies on: claude's --resume/--fork-session and codex's resume
+// subcommand. Older releases start but can't be resumed or cloned.
+var minToolVersions = map[string]string{
+	"claude": "1.0.0",
+	"codex":  "0.40.0",
+}
+
+// toolVersionTimeout bounds `<tool> --version`; node-based CLIs can
+// take a second or two to start cold.
+const toolVersionTimeout = 5 * time.Second
+
+var versionRe = regexp.MustCompile(`\d+\.\d+(?:\.\d+)?`)
+
+// toolVersionEntry is a cached --version result for one binary.
+type toolVersionEntry struct {
+	modTime time.Time
+	version string
+}
+
+var (
+	toolVersionMu    sync.Mutex
+	toolVersionCache = map[string]toolVersionEntry{}
+)
+
+// toolVersion returns the version `<path> --version` reports, "" when
+// it can't be run or prints no version. Results are cached per binary
+// until its modification time changes, so an auto-updated tool is
+// asked again.
+func toolVersion(path string) string {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	toolVersionMu.Lock()
+	e, ok := toolVersionCache[path]
+	toolVersionMu.Unlock()
+	if ok && e.modTime.Equal(fi.ModTime()) {
+		return e.version
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), toolVersionTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, "--version").Output()
+	version := ""
+	if err == nil {
+		version = versionRe.FindString(string(out))
+	}
+	toolVersionMu.Lock()
+	toolVersionCache[path] = toolVersionEntry{modTime: fi.ModTime(), version: version}
+	toolVersionMu.Unlock()
+	return version
+}
+
+// versionSupported reports whether version meets tool's minimum. An
+// unknown version, or a tool without a minimum, counts as supported.
+func versionSupported(tool, version string) bool {
+	minVersion, ok := minToolVersions[tool]
+	if !ok || version == "" {
+		return true
+	}
+	return compareVersions(version, minVersion) >= 0
+}
+
+// compareVersions compares dotted numeric versions; missing parts
+// count as 0.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := range max(len(as), len(bs)) {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// warnToolVersion logs when the tool at toolPath is older than kojo
+// supports. It runs --version, so callers start it in a goroutine.
+func (m *Manager) warnToolVersion(tool, toolPath string) {
+	if _, ok := minToolVersions[tool]; !ok || toolPath == "" {
+		return
+	}
+	if v := toolVersion(toolPath); !versionSupported(tool, v) {
+		m.logger.Warn("tool is older than kojo supports; resume and clone may fail",
+			"tool", tool, "version", v, "minVersion", minToolVersions[tool])
+	}
+}
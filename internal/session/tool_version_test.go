@@ -0,0 +1,62 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestVersionSupported(t *testing.T) {
+	for _, tc := range []struct {
+		tool, version string
+		want          bool
+	}{
+		{"claude", "2.0.14", true},
+		{"claude", "1.0", true},
+		{"claude", "0.2.125", false},
+		{"codex", "0.39.9", false},
+		{"codex", "0.101.0", true},
+		{"codex", "", true},
+		{"grok", "0.0.1", true},
+	} {
+		if got := versionSupported(tc.tool, tc.version); got != tc.want {
+			t.Errorf("versionSupported(%q, %q) = %v, want %v", tc.tool, tc.version, got, tc.want)
+		}
+	}
+}
+
+func TestToolVersion(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script tool")
+	}
+	path := filepath.Join(t.TempDir(), "claude")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\necho '2.0.14 (Claude Code)'\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if v := toolVersion(path); v != "2.0.14" {
+		t.Errorf("toolVersion = %q, want 2.0.14", v)
+	}
+	if v := toolVersion(filepath.Join(t.TempDir(), "missing")); v != "" {
+		t.Errorf("missing tool: version = %q", v)
+	}
+}
+
+func TestTmuxVersionAtLeast(t *testing.T) {
+	for _, tc := range []struct {
+		v, min string
+		want   bool
+	}{
+		{"tmux 3.4", "3.3", true},
+		{"tmux 3.2a", "3.3", false},
+		{"tmux 3.3a", "3.3", true},
+		{"tmux 2.9", MinTmuxVersion, false},
+		{"tmux next-3.5", "3.3", true},
+		{"tmux master", "3.3", true},
+		{"tmux 3.10", "3.3", true},
+	} {
+		if got := tmuxVersionAtLeast(tc.v, tc.min); got != tc.want {
+			t.Errorf("tmuxVersionAtLeast(%q, %q) = %v, want %v", tc.v, tc.min, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// Session webhooks let CI wait on a session: each is a URL POSTed to
+// when the session exits, or once when a line of its output matches
+// the webhook's pattern. Delivery is the caller's (see
+// Manager.OnWebhook); the session only decides when one fires.
+
+// Webhook events.
+const (
+	WebhookExit  = "exit"
+	WebhookMatch = "match"
+)
+
+// maxWebhooks bounds the webhooks on one session.
+const maxWebhooks = 8
+
+var (
+	// ErrInvalidWebhook is returned for a webhook with a bad URL, event
+	// or pattern, or one too many on the session.
+	ErrInvalidWebhook = errors.New("invalid webhook")
+	// ErrUnknownWebhook is returned for a webhook ID the session
+	// doesn't have.
+	ErrUnknownWebhook = errors.New("unknown webhook")
+)
+
+// Webhook is one registered session webhook.
+type Webhook struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+	// Events are the events it fires on: WebhookExit, WebhookMatch.
+	Events []string `json:"events"`
+	// Pattern is the regexp a WebhookMatch fires on, matched against
+	// each line of output with ANSI escapes stripped.
+	Pattern string `json:"pattern,omitempty"`
+	// Secret signs deliveries (HMAC-SHA256 of the body); persisted,
+	// never returned by the API.
+	Secret string `json:"secret,omitempty"`
+	// BaseURL is where the registering client reached kojo, so the
+	// links in a delivery point back at it.
+	BaseURL   string `json:"baseUrl,omitempty"`
+	CreatedAt string `json:"createdAt"`
+	// Matched is set once the pattern has fired; it doesn't again.
+	Matched bool `json:"matched,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// WebhookHit is a webhook due to fire and the line that set it off.
+type WebhookHit struct {
+	Webhook Webhook
+	Event   string
+	Line    string
+}
+
+// compile checks h and compiles its pattern.
+func (h *Webhook) compile() error {
+	u, err := url.Parse(h.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url %q: want http(s)://host/path", ErrInvalidWebhook, h.URL)
+	}
+	if len(h.Events) == 0 {
+		return fmt.Errorf("%w: no events", ErrInvalidWebhook)
+	}
+	for _, ev := range h.Events {
+		if ev != WebhookExit && ev != WebhookMatch {
+			return fmt.Errorf("%w: event %q: want exit or match", ErrInvalidWebhook, ev)
+		}
+	}
+	switch {
+	case slices.Contains(h.Events, WebhookMatch) && h.Pattern == "":
+		return fmt.Errorf("%w: a match webhook needs a pattern", ErrInvalidWebhook)
+	case len(h.Pattern) > maxNotifyPatternLen:
+		return fmt.Errorf("%w: pattern longer than %d bytes", ErrInvalidWebhook, maxNotifyPatternLen)
+	case h.Pattern != "":
+		if h.re, err = regexp.Compile(h.Pattern); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+		}
+	}
+	return nil
+}
+
+// AddWebhook registers h on the session and returns it with its ID.
+func (s *Session) AddWebhook(h Webhook) (Webhook, error) {
+	if err := h.compile(); err != nil {
+		return Webhook{}, err
+	}
+	var id [6]byte
+	rand.Read(id[:])
+	h.ID = "wh_" + hex.EncodeToString(id[:])
+	h.CreatedAt = time.Now().Format(time.RFC3339)
+	h.Matched = false
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.webhooks) >= maxWebhooks {
+		return Webhook{}, fmt.Errorf("%w: at most %d per session", ErrInvalidWebhook, maxWebhooks)
+	}
+	s.webhooks = append(s.webhooks, h)
+	return h, nil
+}
+
+// Webhooks returns the session's webhooks.
+func (s *Session) Webhooks() []Webhook {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.webhooks)
+}
+
+// RemoveWebhook deletes the webhook id.
+func (s *Session) RemoveWebhook(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.webhooks, func(h Webhook) bool { return h.ID == id })
+	if i < 0 {
+		return ErrUnknownWebhook
+	}
+	s.webhooks = slices.Delete(s.webhooks, i, i+1)
+	return nil
+}
+
+// ExitWebhooks returns the webhooks that fire on exit.
+func (s *Session) ExitWebhooks() []Webhook {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var hooks []Webhook
+	for _, h := range s.webhooks {
+		if slices.Contains(h.Events, WebhookExit) {
+			hooks = append(hooks, h)
+		}
+	}
+	return hooks
+}
+
+// CheckWebhookPatterns feeds output through the match webhooks'
+// patterns and returns those that matched a complete line, marking
+// them matched.
+func (s *Session) CheckWebhookPatterns(data []byte) []WebhookHit {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending := false
+	for _, h := range s.webhooks {
+		pending = pending || (h.re != nil && !h.Matched && slices.Contains(h.Events, WebhookMatch))
+	}
+	if !pending {
+		s.webhookLine = nil
+		return nil
+	}
+	var hits []WebhookHit
+	feedLines(&s.webhookLine, data, func(line string) {
+		for i := range s.webhooks {
+			h := &s.webhooks[i]
+			if h.re == nil || h.Matched || !slices.Contains(h.Events, WebhookMatch) || !h.re.MatchString(line) {
+				continue
+			}
+			h.Matched = true
+			hits = append(hits, WebhookHit{Webhook: *h, Event: WebhookMatch, Line: line})
+		}
+	})
+	return hits
+}
+
+// restoreWebhooks recompiles webhooks loaded from the store, dropping
+// any that no longer compile.
+func restoreWebhooks(hooks []Webhook) []Webhook {
+	var out []Webhook
+	for _, h := range hooks {
+		if h.compile() == nil {
+			out = append(out, h)
+		}
+	}
+	return out
+}
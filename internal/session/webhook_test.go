@@ -0,0 +1,60 @@
+package session
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWebhooks(t *testing.T) {
+	s := newTestSession(false)
+	for _, bad := range []Webhook{
+		{URL: "ftp://ci.example/hook", Events: []string{WebhookExit}},
+		{URL: "https://ci.example/hook"},
+		{URL: "https://ci.example/hook", Events: []string{"start"}},
+		{URL: "https://ci.example/hook", Events: []string{WebhookMatch}},
+		{URL: "https://ci.example/hook", Events: []string{WebhookMatch}, Pattern: "("},
+	} {
+		if _, err := s.AddWebhook(bad); !errors.Is(err, ErrInvalidWebhook) {
+			t.Errorf("AddWebhook(%+v) = %v, want ErrInvalidWebhook", bad, err)
+		}
+	}
+
+	exit, err := s.AddWebhook(Webhook{URL: "https://ci.example/exit", Events: []string{WebhookExit}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	match, err := s.AddWebhook(Webhook{URL: "https://ci.example/done", Events: []string{WebhookMatch, WebhookExit}, Pattern: `tests? passed`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exit.ID == "" || exit.ID == match.ID {
+		t.Fatalf("IDs %q, %q", exit.ID, match.ID)
+	}
+	if got := s.ExitWebhooks(); len(got) != 2 {
+		t.Errorf("ExitWebhooks = %d, want 2", len(got))
+	}
+
+	if hits := s.CheckWebhookPatterns([]byte("running...\r\n\x1b[32m12 tests ")); len(hits) != 0 {
+		t.Fatalf("hits on an incomplete line: %+v", hits)
+	}
+	hits := s.CheckWebhookPatterns([]byte("passed\x1b[0m\r\n"))
+	if len(hits) != 1 || hits[0].Webhook.ID != match.ID || hits[0].Line != "12 tests passed" {
+		t.Fatalf("hits = %+v", hits)
+	}
+	if hits := s.CheckWebhookPatterns([]byte("1 test passed\n")); len(hits) != 0 {
+		t.Errorf("match webhook fired twice: %+v", hits)
+	}
+
+	// Persisted webhooks come back compiled, already fired ones stay so.
+	restored := restoreWebhooks(s.InfoForSave().Webhooks)
+	if len(restored) != 2 || !restored[1].Matched || restored[1].re == nil {
+		t.Errorf("restored = %+v", restored)
+	}
+
+	if err := s.RemoveWebhook(exit.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RemoveWebhook(exit.ID); !errors.Is(err, ErrUnknownWebhook) {
+		t.Errorf("second RemoveWebhook = %v", err)
+	}
+}
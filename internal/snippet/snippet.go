@@ -0,0 +1,211 @@
+// Package snippet keeps the named prompt snippets the Web UI inserts
+// into sessions, persisted as snippets.json in the config directory.
+// A snippet's text may carry template variables such as {workDir} and
+// {branch}, filled in by Render for the session it goes into.
+package snippet
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/loppo-llc/kojo/internal/atomicfile"
+	"github.com/loppo-llc/kojo/internal/configdir"
+)
+
+const (
+	fileName = "snippets.json"
+	// Limits on what the API accepts.
+	maxSnippets = 500
+	maxNameLen  = 100
+	maxTextLen  = 64 << 10
+)
+
+var (
+	ErrNotFound = errors.New("snippet not found")
+	ErrInvalid  = errors.New("invalid snippet")
+)
+
+// Snippet is one saved prompt.
+type Snippet struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Store holds the snippets, sorted by name, and writes every change
+// through to disk.
+type Store struct {
+	mu       sync.Mutex
+	path     string
+	snippets []Snippet
+	logger   *slog.Logger
+}
+
+// NewStore loads the snippets saved in the config directory. A
+// missing file is an empty library; an unreadable one is logged and
+// treated the same, and is only replaced by the next change.
+func NewStore(logger *slog.Logger) *Store {
+	return newStore(logger, filepath.Join(configdir.Path(), fileName))
+}
+
+func newStore(logger *slog.Logger, path string) *Store {
+	st := &Store{path: path, logger: logger}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warn("failed to read snippets", "err", err)
+		}
+		return st
+	}
+	if err := json.Unmarshal(data, &st.snippets); err != nil {
+		logger.Warn("invalid snippets file, ignoring", "path", path, "err", err)
+		st.snippets = nil
+	}
+	return st
+}
+
+// List returns every snippet, sorted by name.
+func (st *Store) List() []Snippet {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return slices.Clone(st.snippets)
+}
+
+// Get returns the snippet with id.
+func (st *Store) Get(id string) (Snippet, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	i := st.index(id)
+	if i < 0 {
+		return Snippet{}, ErrNotFound
+	}
+	return st.snippets[i], nil
+}
+
+// Create saves a new snippet.
+func (st *Store) Create(name, text string) (Snippet, error) {
+	if err := validate(name, text); err != nil {
+		return Snippet{}, err
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if len(st.snippets) >= maxSnippets {
+		return Snippet{}, fmt.Errorf("%w: at most %d snippets", ErrInvalid, maxSnippets)
+	}
+	now := time.Now().UTC()
+	sn := Snippet{ID: newID(), Name: strings.TrimSpace(name), Text: text, CreatedAt: now, UpdatedAt: now}
+	st.snippets = append(st.snippets, sn)
+	return sn, st.saveLocked()
+}
+
+// Update changes the name and/or text of the snippet with id; nil
+// leaves a field as it is.
+func (st *Store) Update(id string, name, text *string) (Snippet, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	i := st.index(id)
+	if i < 0 {
+		return Snippet{}, ErrNotFound
+	}
+	sn := st.snippets[i]
+	if name != nil {
+		sn.Name = strings.TrimSpace(*name)
+	}
+	if text != nil {
+		sn.Text = *text
+	}
+	if err := validate(sn.Name, sn.Text); err != nil {
+		return Snippet{}, err
+	}
+	sn.UpdatedAt = time.Now().UTC()
+	st.snippets[i] = sn
+	return sn, st.saveLocked()
+}
+
+// Delete removes the snippet with id.
+func (st *Store) Delete(id string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	i := st.index(id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	st.snippets = slices.Delete(st.snippets, i, i+1)
+	return st.saveLocked()
+}
+
+func (st *Store) index(id string) int {
+	return slices.IndexFunc(st.snippets, func(sn Snippet) bool { return sn.ID == id })
+}
+
+// saveLocked sorts the snippets and writes them out. Caller holds mu.
+func (st *Store) saveLocked() error {
+	slices.SortStableFunc(st.snippets, func(a, b Snippet) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+	if err := os.MkdirAll(filepath.Dir(st.path), 0o755); err != nil {
+		return err
+	}
+	return atomicfile.WriteJSON(st.path, st.snippets, 0o600)
+}
+
+func validate(name, text string) error {
+	name = strings.TrimSpace(name)
+	switch {
+	case name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	case utf8.RuneCountInString(name) > maxNameLen:
+		return fmt.Errorf("%w: name longer than %d characters", ErrInvalid, maxNameLen)
+	case text == "":
+		return fmt.Errorf("%w: text is required", ErrInvalid)
+	case len(text) > maxTextLen:
+		return fmt.Errorf("%w: text larger than %d bytes", ErrInvalid, maxTextLen)
+	}
+	return nil
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return "sn_" + hex.EncodeToString(b)
+}
+
+// Vars are the values Render fills in. Empty ones are left as written,
+// so a {branch} outside a git repository stays visible.
+type Vars struct {
+	WorkDir   string
+	Branch    string
+	Tool      string
+	SessionID string
+}
+
+// Render fills {workDir}, {branch}, {tool}, {sessionId} and {date}
+// (today, YYYY-MM-DD) into text. Other braces are left alone.
+func Render(text string, v Vars) string {
+	var pairs []string
+	for name, val := range map[string]string{
+		"workDir":   v.WorkDir,
+		"branch":    v.Branch,
+		"tool":      v.Tool,
+		"sessionId": v.SessionID,
+		"date":      time.Now().Format(time.DateOnly),
+	} {
+		if val != "" {
+			pairs = append(pairs, "{"+name+"}", val)
+		}
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
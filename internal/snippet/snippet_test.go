@@ -0,0 +1,59 @@
+package snippet
+
+import (
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), fileName)
+	st := newStore(slog.Default(), path)
+
+	test, err := st.Create("test", "run the tests in {workDir}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	review, err := st.Create(" Review ", "review {branch}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Create("", "x"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("no name: err = %v", err)
+	}
+	if _, err := st.Create("big", strings.Repeat("x", maxTextLen+1)); !errors.Is(err, ErrInvalid) {
+		t.Errorf("big text: err = %v", err)
+	}
+
+	text := "review {branch} carefully"
+	if _, err := st.Update(review.ID, nil, &text); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Update("sn_nope", nil, &text); !errors.Is(err, ErrNotFound) {
+		t.Errorf("update unknown: err = %v", err)
+	}
+
+	// A reload sees the changes, sorted by name.
+	got := newStore(slog.Default(), path).List()
+	if len(got) != 2 || got[0].Name != "Review" || got[0].Text != text || got[1].ID != test.ID {
+		t.Fatalf("reloaded = %+v", got)
+	}
+
+	if err := st.Delete(test.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Get(test.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleted snippet: err = %v", err)
+	}
+}
+
+func TestRender(t *testing.T) {
+	got := Render("cd {workDir} on {branch} ({tool}, {sessionId}) {date} {other}", Vars{WorkDir: "/src", Tool: "claude", SessionID: "s_1"})
+	want := "cd /src on {branch} (claude, s_1) " + time.Now().Format(time.DateOnly) + " {other}"
+	if got != want {
+		t.Errorf("Render =\n%q\nwant\n%q", got, want)
+	}
+}
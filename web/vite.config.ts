@@ -1,5 +1,8 @@
 /// <reference types="vitest" />
-import { defineConfig } from "vite";
+import { readdirSync, readFileSync, writeFileSync } from "node:fs";
+import { join } from "node:path";
+import { brotliCompressSync, constants, gzipSync } from "node:zlib";
+import { defineConfig, type Plugin } from "vite";
 import react from "@vitejs/plugin-react";
 import tailwindcss from "@tailwindcss/vite";
 
@@ -12,17 +15,47 @@ const backend = process.env.KOJO_BACKEND || "localhost:8080";
 // empty in `vite dev` (define resolves to ""), which suppresses the check.
 const kojoVersion = process.env.KOJO_VERSION || "";
 
+// Writes .br and .gz next to each text asset of 1 KiB or more; the Go
+// server sends the variant the browser accepts (internal/server/
+// static_assets.go) instead of compressing on every request.
+function precompress(): Plugin {
+  const compressible = /\.(js|mjs|css|html|svg|json|txt|map|webmanifest)$/;
+  let outDir = "dist";
+  return {
+    name: "kojo-precompress",
+    apply: "build",
+    configResolved(config) {
+      outDir = config.build.outDir;
+    },
+    closeBundle() {
+      for (const entry of readdirSync(outDir, { recursive: true, withFileTypes: true })) {
+        if (!entry.isFile() || !compressible.test(entry.name)) continue;
+        const file = join(entry.parentPath, entry.name);
+        const data = readFileSync(file);
+        if (data.length < 1024) continue;
+        writeFileSync(file + ".gz", gzipSync(data, { level: 9 }));
+        writeFileSync(
+          file + ".br",
+          brotliCompressSync(data, {
+            params: { [constants.BROTLI_PARAM_QUALITY]: constants.BROTLI_MAX_QUALITY },
+          }),
+        );
+      }
+    },
+  };
+}
+
 export default defineConfig({
   define: {
     __KOJO_VERSION__: JSON.stringify(kojoVersion),
   },
-  plugins: [react(), tailwindcss()],
+  plugins: [react(), tailwindcss(), precompress()],
   server: {
     port: 5173,
     proxy: {
-      "/api": `http://${backend}`,
-      "/ws": {
-        target: `ws://${backend}`,
+      // ws: the terminal and event WebSockets live under /api too.
+      "/api": {
+        target: `http://${backend}`,
         ws: true,
       },
     },